@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha5
 
 import (
+	"reflect"
 	"strings"
 
 	conversion "k8s.io/apimachinery/pkg/conversion"
@@ -44,6 +45,9 @@ func (r *OpenStackCluster) ConvertTo(dstRaw ctrlconversion.Hub) error {
 		return err
 	}
 
+	restorev1beta1ClusterSpec(&restored.Spec, &dst.Spec)
+	restorev1beta1ClusterStatus(&restored.Status, &dst.Status)
+
 	return nil
 }
 
@@ -87,6 +91,8 @@ func (r *OpenStackClusterTemplate) ConvertTo(dstRaw ctrlconversion.Hub) error {
 		return err
 	}
 
+	restorev1beta1ClusterSpec(&restored.Spec.Template.Spec, &dst.Spec.Template.Spec)
+
 	return nil
 }
 
@@ -116,6 +122,9 @@ func (r *OpenStackMachine) ConvertTo(dstRaw ctrlconversion.Hub) error {
 		return err
 	}
 
+	restorev1beta1MachineSpec(&restored.Spec, &dst.Spec)
+	restorev1beta1MachineStatus(&restored.Status, &dst.Status)
+
 	return nil
 }
 
@@ -159,6 +168,8 @@ func (r *OpenStackMachineTemplate) ConvertTo(dstRaw ctrlconversion.Hub) error {
 		return err
 	}
 
+	restorev1beta1MachineSpec(&restored.Spec.Template.Spec, &dst.Spec.Template.Spec)
+
 	return nil
 }
 
@@ -187,6 +198,171 @@ func (r *OpenStackMachineTemplateList) ConvertFrom(srcRaw ctrlconversion.Hub) er
 	return Convert_v1beta1_OpenStackMachineTemplateList_To_v1alpha5_OpenStackMachineTemplateList(src, r, nil)
 }
 
+// restorev1beta1ClusterSpec restores the fields which have no equivalent in v1alpha5 from the hub object preserved
+// on down-conversion. The fields which are only partially converted, e.g. filters of which v1alpha5 only has the ID,
+// are only restored if the v1alpha5 object still has the same value.
+func restorev1beta1ClusterSpec(previous *infrav1.OpenStackClusterSpec, dst *infrav1.OpenStackClusterSpec) {
+	dst.Router = previous.Router
+	dst.NetworkMTU = previous.NetworkMTU
+	dst.DisableExternalNetwork = previous.DisableExternalNetwork
+	dst.ControlPlaneOmitAvailabilityZone = previous.ControlPlaneOmitAvailabilityZone
+	dst.APIServerLoadBalancer.Provider = previous.APIServerLoadBalancer.Provider
+	dst.IdentityRef.Region = previous.IdentityRef.Region
+
+	if dst.ExternalNetwork.ID == previous.ExternalNetwork.ID {
+		dst.ExternalNetwork = previous.ExternalNetwork
+	}
+
+	// v1alpha5 only has the first subnet.
+	if len(dst.Subnets) == 1 && len(previous.Subnets) > 1 {
+		dst.Subnets = append(dst.Subnets, previous.Subnets[1:]...)
+	}
+
+	// v1alpha5 only has the CIDR and the DNS nameservers of the first managed subnet.
+	if len(dst.ManagedSubnets) == 1 && len(previous.ManagedSubnets) > 0 && dst.ManagedSubnets[0].CIDR == previous.ManagedSubnets[0].CIDR {
+		dst.ManagedSubnets[0].AllocationPools = previous.ManagedSubnets[0].AllocationPools
+		dst.ManagedSubnets = append(dst.ManagedSubnets, previous.ManagedSubnets[1:]...)
+	}
+
+	if previous.ManagedSecurityGroups != nil && dst.ManagedSecurityGroups != nil {
+		restorev1beta1ManagedSecurityGroups(previous.ManagedSecurityGroups, dst.ManagedSecurityGroups)
+	}
+
+	if previous.Bastion != nil && dst.Bastion != nil {
+		restorev1beta1MachineSpec(&previous.Bastion.Instance, &dst.Bastion.Instance)
+		dst.Bastion.SSHAllowedCIDRs = previous.Bastion.SSHAllowedCIDRs
+		dst.Bastion.Replicas = previous.Bastion.Replicas
+	}
+}
+
+// restorev1beta1AllNodesSecurityGroupRules returns the allNodes rules of the restored managed security groups. The
+// allNodes rules are additive to allowAllInClusterTraffic, so they are restored whatever its value. v1alpha5
+// up-converts to the legacy Calico rules unless all the in-cluster traffic is allowed: when allowAllInClusterTraffic
+// was disabled in v1alpha5, the legacy Calico rules are added to the restored rules, unless they have a rule with
+// the same name.
+func restorev1beta1AllNodesSecurityGroupRules(previous *infrav1.ManagedSecurityGroups, dst *infrav1.ManagedSecurityGroups) []infrav1.SecurityGroupRuleSpec {
+	if !previous.AllowAllInClusterTraffic || dst.AllowAllInClusterTraffic {
+		return previous.AllNodesSecurityGroupRules
+	}
+
+	rules := append([]infrav1.SecurityGroupRuleSpec{}, previous.AllNodesSecurityGroupRules...)
+	names := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		names[rule.Name] = true
+	}
+	for _, rule := range dst.AllNodesSecurityGroupRules {
+		if !names[rule.Name] {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func restorev1beta1ManagedSecurityGroups(previous *infrav1.ManagedSecurityGroups, dst *infrav1.ManagedSecurityGroups) {
+	dst.AllNodesSecurityGroupRules = restorev1beta1AllNodesSecurityGroupRules(previous, dst)
+	dst.AllowLoadBalancerServiceTraffic = previous.AllowLoadBalancerServiceTraffic
+	dst.ShadowRules = previous.ShadowRules
+	dst.SharedNodeGroup = previous.SharedNodeGroup
+	dst.NamePrefix = previous.NamePrefix
+	dst.ProjectID = previous.ProjectID
+	dst.NodePortAllowedCIDRs = previous.NodePortAllowedCIDRs
+	dst.SeparateAllNodesGroup = previous.SeparateAllNodesGroup
+	dst.DisableDefaultRules = previous.DisableDefaultRules
+	dst.DisableWorkerIngressRules = previous.DisableWorkerIngressRules
+	dst.Stateless = previous.Stateless
+	dst.RetainOnDelete = previous.RetainOnDelete
+	dst.ExistingGroups = previous.ExistingGroups
+	dst.DeleteAdoptedGroups = previous.DeleteAdoptedGroups
+	dst.WellKnownPorts = previous.WellKnownPorts
+}
+
+func restorev1beta1ClusterStatus(previous *infrav1.OpenStackClusterStatus, dst *infrav1.OpenStackClusterStatus) {
+	// It's (theoretically) possible in v1beta1 to have Network nil but
+	// Router or APIServerLoadBalancer not nil. In hub-spoke-hub conversion this will
+	// result in Network being a pointer to an empty object.
+	if previous.Network == nil && dst.Network != nil && reflect.ValueOf(*dst.Network).IsZero() {
+		dst.Network = nil
+	}
+
+	// The rules of the security groups are only partially converted, and the other groups have no equivalent in
+	// v1alpha5.
+	dst.ControlPlaneSecurityGroup = previous.ControlPlaneSecurityGroup
+	dst.WorkerSecurityGroup = previous.WorkerSecurityGroup
+	dst.BastionSecurityGroup = previous.BastionSecurityGroup
+	dst.NodeSecurityGroup = previous.NodeSecurityGroup
+	dst.AllNodesSecurityGroup = previous.AllNodesSecurityGroup
+	dst.ShadowSecurityGroup = previous.ShadowSecurityGroup
+	dst.ManagedSecurityGroups = previous.ManagedSecurityGroups
+	dst.ManagedSecurityGroupRules = previous.ManagedSecurityGroupRules
+	dst.AdditionalBastionSecurityGroups = previous.AdditionalBastionSecurityGroups
+	dst.AdditionalBastions = previous.AdditionalBastions
+	dst.SecurityGroupReconcileFailures = previous.SecurityGroupReconcileFailures
+	dst.Conditions = previous.Conditions
+
+	if previous.APIServerLoadBalancer != nil && dst.APIServerLoadBalancer != nil {
+		dst.APIServerLoadBalancer.Tags = previous.APIServerLoadBalancer.Tags
+	}
+
+	if previous.Bastion != nil && dst.Bastion != nil {
+		dst.Bastion.ReferencedResources = previous.Bastion.ReferencedResources
+		dst.Bastion.DependentResources = previous.Bastion.DependentResources
+	}
+}
+
+// restorev1beta1MachineSpec restores the fields which have no equivalent in v1alpha5 from the hub object preserved
+// on down-conversion.
+func restorev1beta1MachineSpec(previous *infrav1.OpenStackMachineSpec, dst *infrav1.OpenStackMachineSpec) {
+	dst.AdditionalBlockDevices = previous.AdditionalBlockDevices
+	dst.AddMachineNameMetadata = previous.AddMachineNameMetadata
+
+	if previous.IdentityRef != nil && dst.IdentityRef != nil {
+		dst.IdentityRef.Region = previous.IdentityRef.Region
+	}
+
+	// v1alpha5 only has the ID of the server group, and the name or ID of the image.
+	var previousServerGroupID string
+	if previous.ServerGroup != nil {
+		previousServerGroupID = previous.ServerGroup.ID
+	}
+	if dst.ServerGroup != nil && dst.ServerGroup.ID == previousServerGroupID {
+		dst.ServerGroup = previous.ServerGroup
+	}
+	if dst.Image.ID == previous.Image.ID && dst.Image.Name == previous.Image.Name {
+		dst.Image = previous.Image
+	}
+
+	// The server metadata is a map in v1alpha5, which loses the order of the list.
+	if serverMetadataEqual(previous.ServerMetadata, dst.ServerMetadata) {
+		dst.ServerMetadata = previous.ServerMetadata
+	}
+
+	// Several fields of the ports have no equivalent in v1alpha5, or are only partially converted. We restore the
+	// whole ports since they are anyway immutable, as long as none was added or removed.
+	if len(dst.Ports) == len(previous.Ports) {
+		dst.Ports = previous.Ports
+	}
+}
+
+// serverMetadataEqual returns true if a and b contain the same keys and values, regardless of their order. The last
+// value of a duplicated key wins, as it does on down-conversion.
+func serverMetadataEqual(a, b []infrav1.ServerMetadata) bool {
+	toMap := func(serverMetadata []infrav1.ServerMetadata) map[string]string {
+		m := make(map[string]string, len(serverMetadata))
+		for i := range serverMetadata {
+			m[serverMetadata[i].Key] = serverMetadata[i].Value
+		}
+		return m
+	}
+
+	return reflect.DeepEqual(toMap(a), toMap(b))
+}
+
+func restorev1beta1MachineStatus(previous *infrav1.OpenStackMachineStatus, dst *infrav1.OpenStackMachineStatus) {
+	dst.ReferencedResources = previous.ReferencedResources
+	dst.DependentResources = previous.DependentResources
+	dst.MachineNameMetadata = previous.MachineNameMetadata
+}
+
 func Convert_v1beta1_OpenStackClusterSpec_To_v1alpha5_OpenStackClusterSpec(in *infrav1.OpenStackClusterSpec, out *OpenStackClusterSpec, s conversion.Scope) error {
 	err := autoConvert_v1beta1_OpenStackClusterSpec_To_v1alpha5_OpenStackClusterSpec(in, out, s)
 	if err != nil {
@@ -197,6 +373,7 @@ func Convert_v1beta1_OpenStackClusterSpec_To_v1alpha5_OpenStackClusterSpec(in *i
 		out.ExternalNetworkID = in.ExternalNetwork.ID
 	}
 
+	// v1alpha5 only has a single subnet. The others are restored from the conversion annotation on up-conversion.
 	if len(in.ManagedSubnets) > 0 {
 		out.NodeCIDR = in.ManagedSubnets[0].CIDR
 		out.DNSNameservers = in.ManagedSubnets[0].DNSNameservers
@@ -210,6 +387,8 @@ func Convert_v1beta1_OpenStackClusterSpec_To_v1alpha5_OpenStackClusterSpec(in *i
 		}
 	}
 
+	// The rest of the managed security groups, e.g. custom allNodes rules, are restored from the conversion annotation
+	// on up-conversion.
 	if in.ManagedSecurityGroups != nil {
 		out.ManagedSecurityGroups = true
 		out.AllowAllInClusterTraffic = in.ManagedSecurityGroups.AllowAllInClusterTraffic
@@ -274,7 +453,8 @@ func Convert_v1beta1_LoadBalancer_To_v1alpha5_LoadBalancer(in *infrav1.LoadBalan
 }
 
 func Convert_v1beta1_PortOpts_To_v1alpha5_PortOpts(in *infrav1.PortOpts, out *PortOpts, s conversion.Scope) error {
-	// value specs and propagate uplink status have been added in v1beta1 but have no equivalent in v1alpha5
+	// value specs, propagate uplink status and exclude managed security groups have been added in v1beta1 but have no equivalent in v1alpha5.
+	// They are restored from the conversion annotation by restorev1beta1MachineSpec.
 	err := autoConvert_v1beta1_PortOpts_To_v1alpha5_PortOpts(in, out, s)
 	if err != nil {
 		return err
@@ -299,11 +479,13 @@ func Convert_v1beta1_PortOpts_To_v1alpha5_PortOpts(in *infrav1.PortOpts, out *Po
 	}
 
 	out.Profile = make(map[string]string)
-	if pointer.BoolDeref(in.Profile.OVSHWOffload, false) {
-		(out.Profile)["capabilities"] = "[\"switchdev\"]"
-	}
-	if pointer.BoolDeref(in.Profile.TrustedVF, false) {
-		(out.Profile)["trusted"] = trueString
+	if in.Profile != nil {
+		if pointer.BoolDeref(in.Profile.OVSHWOffload, false) {
+			(out.Profile)["capabilities"] = "[\"switchdev\"]"
+		}
+		if pointer.BoolDeref(in.Profile.TrustedVF, false) {
+			(out.Profile)["trusted"] = trueString
+		}
 	}
 	return nil
 }
@@ -329,6 +511,23 @@ func Convert_v1alpha5_OpenStackMachineSpec_To_v1beta1_OpenStackMachineSpec(in *O
 	}
 	out.Image = imageFilter
 
+	if len(in.ServerMetadata) > 0 {
+		serverMetadata := make([]infrav1.ServerMetadata, 0, len(in.ServerMetadata))
+		for k, v := range in.ServerMetadata {
+			// Truncate key and value to 255 characters if required, as this
+			// was not validated prior to v1beta1
+			if len(k) > 255 {
+				k = k[:255]
+			}
+			if len(v) > 255 {
+				v = v[:255]
+			}
+
+			serverMetadata = append(serverMetadata, infrav1.ServerMetadata{Key: k, Value: v})
+		}
+		out.ServerMetadata = serverMetadata
+	}
+
 	if in.IdentityRef != nil {
 		out.IdentityRef = &infrav1.OpenStackIdentityReference{Name: in.IdentityRef.Name}
 	}
@@ -376,33 +575,17 @@ func Convert_v1alpha5_PortOpts_To_v1beta1_PortOpts(in *PortOpts, out *infrav1.Po
 		}
 	}
 
-	if len(in.SecurityGroups) > 0 || len(in.SecurityGroupFilters) > 0 {
-		out.SecurityGroups = make([]infrav1.SecurityGroupFilter, 0, len(in.SecurityGroups)+len(in.SecurityGroupFilters))
-		for i := range in.SecurityGroupFilters {
-			sgParam := &in.SecurityGroupFilters[i]
-			switch {
-			case sgParam.UUID != "":
-				out.SecurityGroups = append(out.SecurityGroups, infrav1.SecurityGroupFilter{ID: sgParam.UUID})
-			case sgParam.Name != "":
-				out.SecurityGroups = append(out.SecurityGroups, infrav1.SecurityGroupFilter{Name: sgParam.Name})
-			case sgParam.Filter != (SecurityGroupFilter{}):
-				out.SecurityGroups = append(out.SecurityGroups, infrav1.SecurityGroupFilter{})
-				outSG := &out.SecurityGroups[len(out.SecurityGroups)-1]
-				if err := Convert_v1alpha5_SecurityGroupFilter_To_v1beta1_SecurityGroupFilter(&sgParam.Filter, outSG, s); err != nil {
-					return err
-				}
-			}
-		}
-		for _, id := range in.SecurityGroups {
-			out.SecurityGroups = append(out.SecurityGroups, infrav1.SecurityGroupFilter{ID: id})
-		}
-	}
-
 	// Profile is now a struct in v1beta1.
 	if strings.Contains(in.Profile["capabilities"], "switchdev") {
+		if out.Profile == nil {
+			out.Profile = &infrav1.BindingProfile{}
+		}
 		out.Profile.OVSHWOffload = pointer.Bool(true)
 	}
 	if in.Profile["trusted"] == trueString {
+		if out.Profile == nil {
+			out.Profile = &infrav1.BindingProfile{}
+		}
 		out.Profile.TrustedVF = pointer.Bool(true)
 	}
 	return nil
@@ -614,6 +797,16 @@ func Convert_v1beta1_OpenStackMachineSpec_To_v1alpha5_OpenStackMachineSpec(in *i
 		out.ImageUUID = in.Image.ID
 	}
 
+	if len(in.ServerMetadata) > 0 {
+		serverMetadata := make(map[string]string, len(in.ServerMetadata))
+		for i := range in.ServerMetadata {
+			key := in.ServerMetadata[i].Key
+			value := in.ServerMetadata[i].Value
+			serverMetadata[key] = value
+		}
+		out.ServerMetadata = serverMetadata
+	}
+
 	if in.IdentityRef != nil {
 		out.IdentityRef = &OpenStackIdentityReference{Name: in.IdentityRef.Name}
 		out.CloudName = in.IdentityRef.CloudName
@@ -632,7 +825,7 @@ func Convert_v1beta1_Bastion_To_v1alpha5_Bastion(in *infrav1.Bastion, out *Basti
 	if err != nil {
 		return err
 	}
-	in.FloatingIP = out.Instance.FloatingIP
+	out.Instance.FloatingIP = in.FloatingIP
 	return nil
 }
 
@@ -641,7 +834,7 @@ func Convert_v1alpha5_Bastion_To_v1beta1_Bastion(in *Bastion, out *infrav1.Basti
 	if err != nil {
 		return err
 	}
-	in.Instance.FloatingIP = out.FloatingIP
+	out.FloatingIP = in.Instance.FloatingIP
 	return nil
 }
 
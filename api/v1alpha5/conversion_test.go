@@ -17,16 +17,323 @@ limitations under the License.
 package v1alpha5
 
 import (
+	"strings"
 	"testing"
 
+	fuzz "github.com/google/gofuzz"
+	"github.com/gophercloud/gophercloud"
 	"github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/apitesting/fuzzer"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	runtimeserializer "k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/utils/pointer"
+	utilconversion "sigs.k8s.io/cluster-api/util/conversion"
 	ctrlconversion "sigs.k8s.io/controller-runtime/pkg/conversion"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
 )
 
+// Setting this to false to avoid running tests in parallel. Only for use in development.
+const parallel = true
+
+func runParallel(f func(t *testing.T)) func(t *testing.T) {
+	if parallel {
+		return func(t *testing.T) {
+			t.Helper()
+			t.Parallel()
+			f(t)
+		}
+	}
+	return f
+}
+
+func TestFuzzyConversion(t *testing.T) {
+	// The test already ignores the data annotation added on up-conversion.
+	// Also ignore the data annotation added on down-conversion.
+	ignoreDataAnnotation := func(hub ctrlconversion.Hub) {
+		obj := hub.(metav1.Object)
+		delete(obj.GetAnnotations(), utilconversion.DataAnnotation)
+	}
+
+	filterInvalidTags := func(tags []infrav1.NeutronTag) []infrav1.NeutronTag {
+		var ret []infrav1.NeutronTag
+		for i := range tags {
+			s := string(tags[i])
+			if len(s) > 0 && !strings.Contains(s, ",") {
+				ret = append(ret, tags[i])
+			}
+		}
+		return ret
+	}
+
+	// v1alpha5 filter tags are a comma-separated list, in which empty tags are dropped on up-conversion.
+	normalizeTags := func(tags string) string {
+		var ret []string
+		for _, tag := range strings.Split(tags, ",") {
+			if tag != "" {
+				ret = append(ret, tag)
+			}
+		}
+		return strings.Join(ret, ",")
+	}
+
+	fuzzerFuncs := func(_ runtimeserializer.CodecFactory) []interface{} {
+		return []interface{}{
+			// The following v1alpha5 fields have been removed in v1beta1,
+			// or are only partially converted. They can't survive a
+			// spoke-hub-spoke round trip as the v1alpha5 object isn't
+			// preserved on up-conversion.
+
+			func(spec *OpenStackMachineSpec, c fuzz.Continue) {
+				c.FuzzNoCustom(spec)
+
+				spec.Networks = nil
+				spec.Subnet = ""
+				spec.FloatingIP = ""
+			},
+
+			func(spec *OpenStackClusterSpec, c fuzz.Continue) {
+				c.FuzzNoCustom(spec)
+
+				// AllowAllInClusterTraffic is part of the managed security groups in v1beta1.
+				if !spec.ManagedSecurityGroups {
+					spec.AllowAllInClusterTraffic = false
+				}
+
+				// DNSNameservers are part of the managed subnet in v1beta1, which requires NodeCIDR.
+				if spec.NodeCIDR == "" {
+					spec.DNSNameservers = nil
+				}
+			},
+
+			func(status *OpenStackClusterStatus, c fuzz.Continue) {
+				c.FuzzNoCustom(status)
+
+				if status.Network != nil {
+					status.Network.PortOpts = nil
+				}
+				if status.ExternalNetwork != nil {
+					status.ExternalNetwork.Subnet = nil
+					status.ExternalNetwork.PortOpts = nil
+					status.ExternalNetwork.Router = nil
+					status.ExternalNetwork.APIServerLoadBalancer = nil
+				}
+			},
+
+			func(rule *SecurityGroupRule, c fuzz.Continue) {
+				c.FuzzNoCustom(rule)
+
+				rule.SecurityGroupID = ""
+			},
+
+			func(ref *OpenStackIdentityReference, c fuzz.Continue) {
+				c.FuzzNoCustom(ref)
+
+				ref.Kind = ""
+			},
+
+			func(instance *Instance, c fuzz.Continue) {
+				c.FuzzNoCustom(instance)
+
+				// Only the fields of the v1beta1 BastionStatus are converted.
+				instance.Trunk = false
+				instance.FailureDomain = ""
+				instance.SecurityGroups = nil
+				instance.Networks = nil
+				instance.Subnet = ""
+				instance.Tags = nil
+				instance.Image = ""
+				instance.ImageUUID = ""
+				instance.Flavor = ""
+				instance.UserData = ""
+				instance.Metadata = nil
+				instance.ConfigDrive = nil
+				instance.RootVolume = nil
+			},
+
+			func(param *SecurityGroupParam, c fuzz.Continue) {
+				c.FuzzNoCustom(param)
+
+				// The UUID and the name of the param are merged into the filter.
+				param.Filter.ID = param.UUID
+				param.Filter.Name = param.Name
+			},
+
+			func(param *SubnetParam, c fuzz.Continue) {
+				c.FuzzNoCustom(param)
+
+				// The UUID of the param is merged into the filter.
+				param.Filter.ID = param.UUID
+			},
+
+			func(port *PortOpts, c fuzz.Continue) {
+				c.FuzzNoCustom(port)
+
+				port.TenantID = ""
+				port.ProjectID = ""
+
+				// Security groups are down-converted to filters, by UUID if it is known.
+				port.SecurityGroups = nil
+				var securityGroupFilters []SecurityGroupParam
+				for _, sg := range port.SecurityGroupFilters {
+					switch {
+					case sg.UUID != "":
+						securityGroupFilters = append(securityGroupFilters, SecurityGroupParam{UUID: sg.UUID})
+					case sg.Filter != (SecurityGroupFilter{}) && sg.Filter.ID == "":
+						securityGroupFilters = append(securityGroupFilters, SecurityGroupParam{Filter: sg.Filter})
+					}
+				}
+				port.SecurityGroupFilters = securityGroupFilters
+
+				// Only the binding profile flags known by v1beta1 are converted.
+				profile := map[string]string{}
+				if c.RandBool() {
+					profile["capabilities"] = "[\"switchdev\"]"
+				}
+				if c.RandBool() {
+					profile["trusted"] = trueString
+				}
+				port.Profile = profile
+			},
+
+			func(filter *SecurityGroupFilter, c fuzz.Continue) {
+				c.FuzzNoCustom(filter)
+
+				filter.TenantID = ""
+				filter.Limit = 0
+				filter.Marker = ""
+				filter.SortKey = ""
+				filter.SortDir = ""
+				filter.Tags = normalizeTags(filter.Tags)
+				filter.TagsAny = normalizeTags(filter.TagsAny)
+				filter.NotTags = normalizeTags(filter.NotTags)
+				filter.NotTagsAny = normalizeTags(filter.NotTagsAny)
+			},
+
+			func(filter *NetworkFilter, c fuzz.Continue) {
+				c.FuzzNoCustom(filter)
+
+				filter.Tags = normalizeTags(filter.Tags)
+				filter.TagsAny = normalizeTags(filter.TagsAny)
+				filter.NotTags = normalizeTags(filter.NotTags)
+				filter.NotTagsAny = normalizeTags(filter.NotTagsAny)
+			},
+
+			func(filter *SubnetFilter, c fuzz.Continue) {
+				c.FuzzNoCustom(filter)
+
+				filter.Tags = normalizeTags(filter.Tags)
+				filter.TagsAny = normalizeTags(filter.TagsAny)
+				filter.NotTags = normalizeTags(filter.NotTags)
+				filter.NotTagsAny = normalizeTags(filter.NotTagsAny)
+			},
+
+			func(spec *infrav1.OpenStackClusterSpec, c fuzz.Continue) {
+				c.FuzzNoCustom(spec)
+
+				// The fuzzer only seems to generate Subnets of
+				// length 1, but we need to also test length 2.
+				// Ensure it is occasionally generated.
+				if len(spec.Subnets) == 1 && c.RandBool() {
+					subnet := infrav1.SubnetFilter{}
+					c.FuzzNoCustom(&subnet)
+					spec.Subnets = append(spec.Subnets, subnet)
+				}
+			},
+
+			func(spec *infrav1.SubnetSpec, c fuzz.Continue) {
+				c.FuzzNoCustom(spec)
+
+				// CIDR is required and API validates that it's present, so
+				// we force it to always be set.
+				for spec.CIDR == "" {
+					spec.CIDR = c.RandString()
+				}
+			},
+
+			func(pool *infrav1.AllocationPool, c fuzz.Continue) {
+				c.FuzzNoCustom(pool)
+
+				// Start and End are required properties, let's make sure both are set
+				for pool.Start == "" {
+					pool.Start = c.RandString()
+				}
+
+				for pool.End == "" {
+					pool.End = c.RandString()
+				}
+			},
+
+			// v1beta1 filter tags cannot contain commas and can't be empty.
+
+			func(filter *infrav1.SubnetFilter, c fuzz.Continue) {
+				c.FuzzNoCustom(filter)
+
+				filter.Tags = filterInvalidTags(filter.Tags)
+				filter.TagsAny = filterInvalidTags(filter.TagsAny)
+				filter.NotTags = filterInvalidTags(filter.NotTags)
+				filter.NotTagsAny = filterInvalidTags(filter.NotTagsAny)
+			},
+
+			func(filter *infrav1.NetworkFilter, c fuzz.Continue) {
+				c.FuzzNoCustom(filter)
+
+				filter.Tags = filterInvalidTags(filter.Tags)
+				filter.TagsAny = filterInvalidTags(filter.TagsAny)
+				filter.NotTags = filterInvalidTags(filter.NotTags)
+				filter.NotTagsAny = filterInvalidTags(filter.NotTagsAny)
+			},
+
+			func(filter *infrav1.RouterFilter, c fuzz.Continue) {
+				c.FuzzNoCustom(filter)
+
+				filter.Tags = filterInvalidTags(filter.Tags)
+				filter.TagsAny = filterInvalidTags(filter.TagsAny)
+				filter.NotTags = filterInvalidTags(filter.NotTags)
+				filter.NotTagsAny = filterInvalidTags(filter.NotTagsAny)
+			},
+
+			func(filter *infrav1.SecurityGroupFilter, c fuzz.Continue) {
+				c.FuzzNoCustom(filter)
+
+				filter.Tags = filterInvalidTags(filter.Tags)
+				filter.TagsAny = filterInvalidTags(filter.TagsAny)
+				filter.NotTags = filterInvalidTags(filter.NotTags)
+				filter.NotTagsAny = filterInvalidTags(filter.NotTagsAny)
+			},
+		}
+	}
+
+	t.Run("for OpenStackCluster", runParallel(utilconversion.FuzzTestFunc(utilconversion.FuzzTestFuncInput{
+		Hub:              &infrav1.OpenStackCluster{},
+		Spoke:            &OpenStackCluster{},
+		HubAfterMutation: ignoreDataAnnotation,
+		FuzzerFuncs:      []fuzzer.FuzzerFuncs{fuzzerFuncs},
+	})))
+
+	t.Run("for OpenStackClusterTemplate", runParallel(utilconversion.FuzzTestFunc(utilconversion.FuzzTestFuncInput{
+		Hub:              &infrav1.OpenStackClusterTemplate{},
+		Spoke:            &OpenStackClusterTemplate{},
+		HubAfterMutation: ignoreDataAnnotation,
+		FuzzerFuncs:      []fuzzer.FuzzerFuncs{fuzzerFuncs},
+	})))
+
+	t.Run("for OpenStackMachine", runParallel(utilconversion.FuzzTestFunc(utilconversion.FuzzTestFuncInput{
+		Hub:              &infrav1.OpenStackMachine{},
+		Spoke:            &OpenStackMachine{},
+		HubAfterMutation: ignoreDataAnnotation,
+		FuzzerFuncs:      []fuzzer.FuzzerFuncs{fuzzerFuncs},
+	})))
+
+	t.Run("for OpenStackMachineTemplate", runParallel(utilconversion.FuzzTestFunc(utilconversion.FuzzTestFuncInput{
+		Hub:              &infrav1.OpenStackMachineTemplate{},
+		Spoke:            &OpenStackMachineTemplate{},
+		HubAfterMutation: ignoreDataAnnotation,
+		FuzzerFuncs:      []fuzzer.FuzzerFuncs{fuzzerFuncs},
+	})))
+}
+
 func TestConvertFrom(t *testing.T) {
 	g := gomega.NewWithT(t)
 	scheme := runtime.NewScheme()
@@ -118,6 +425,333 @@ func TestConvertFrom(t *testing.T) {
 	}
 }
 
+func TestConvertToRestoresPortOpts(t *testing.T) {
+	ports := func() []infrav1.PortOpts {
+		return []infrav1.PortOpts{
+			{
+				Description: pointer.String("primary"),
+			},
+			{
+				Description:                  pointer.String("sriov"),
+				ValueSpecs:                   []infrav1.ValueSpec{{Name: "numa", Key: "binding:profile", Value: "{\"numa\": 1}"}},
+				PropagateUplinkStatus:        pointer.Bool(true),
+				ExcludeManagedSecurityGroups: pointer.Bool(true),
+			},
+		}
+	}
+
+	t.Run("OpenStackMachine", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		spoke := &OpenStackMachine{}
+		g.Expect(spoke.ConvertFrom(&infrav1.OpenStackMachine{Spec: infrav1.OpenStackMachineSpec{Ports: ports()}})).To(gomega.Succeed())
+
+		hub := &infrav1.OpenStackMachine{}
+		g.Expect(spoke.ConvertTo(hub)).To(gomega.Succeed())
+		g.Expect(hub.Spec.Ports).To(gomega.Equal(ports()))
+	})
+
+	t.Run("OpenStackMachineTemplate", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		spoke := &OpenStackMachineTemplate{}
+		g.Expect(spoke.ConvertFrom(&infrav1.OpenStackMachineTemplate{Spec: infrav1.OpenStackMachineTemplateSpec{
+			Template: infrav1.OpenStackMachineTemplateResource{Spec: infrav1.OpenStackMachineSpec{Ports: ports()}},
+		}})).To(gomega.Succeed())
+
+		hub := &infrav1.OpenStackMachineTemplate{}
+		g.Expect(spoke.ConvertTo(hub)).To(gomega.Succeed())
+		g.Expect(hub.Spec.Template.Spec.Ports).To(gomega.Equal(ports()))
+	})
+
+	t.Run("OpenStackCluster bastion", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		spoke := &OpenStackCluster{}
+		g.Expect(spoke.ConvertFrom(&infrav1.OpenStackCluster{Spec: infrav1.OpenStackClusterSpec{
+			Bastion: &infrav1.Bastion{Instance: infrav1.OpenStackMachineSpec{Ports: ports()}},
+		}})).To(gomega.Succeed())
+
+		hub := &infrav1.OpenStackCluster{}
+		g.Expect(spoke.ConvertTo(hub)).To(gomega.Succeed())
+		g.Expect(hub.Spec.Bastion.Instance.Ports).To(gomega.Equal(ports()))
+	})
+
+	t.Run("Ports changed in v1alpha5 are not restored", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		spoke := &OpenStackMachine{}
+		g.Expect(spoke.ConvertFrom(&infrav1.OpenStackMachine{Spec: infrav1.OpenStackMachineSpec{Ports: ports()}})).To(gomega.Succeed())
+		spoke.Spec.Ports = spoke.Spec.Ports[:1]
+
+		hub := &infrav1.OpenStackMachine{}
+		g.Expect(spoke.ConvertTo(hub)).To(gomega.Succeed())
+		g.Expect(hub.Spec.Ports).To(gomega.HaveLen(1))
+		g.Expect(hub.Spec.Ports[0].ValueSpecs).To(gomega.BeEmpty())
+	})
+}
+
+func TestConvertPortOptsTrunk(t *testing.T) {
+	hub := func() *infrav1.OpenStackMachine {
+		return &infrav1.OpenStackMachine{
+			Spec: infrav1.OpenStackMachineSpec{
+				Ports: []infrav1.PortOpts{
+					{Description: pointer.String("primary")},
+					{Description: pointer.String("vlans"), Trunk: pointer.Bool(true)},
+					{Description: pointer.String("untrunked"), Trunk: pointer.Bool(false)},
+				},
+			},
+		}
+	}
+
+	g := gomega.NewWithT(t)
+	spoke := &OpenStackMachine{}
+	g.Expect(spoke.ConvertFrom(hub())).To(gomega.Succeed())
+
+	// Trunk exists in v1alpha5, so it is converted rather than restored from the annotation.
+	g.Expect(spoke.Spec.Ports).To(gomega.HaveLen(3))
+	g.Expect(spoke.Spec.Ports[0].Trunk).To(gomega.BeNil())
+	g.Expect(spoke.Spec.Ports[1].Trunk).To(gomega.Equal(pointer.Bool(true)))
+	g.Expect(spoke.Spec.Ports[2].Trunk).To(gomega.Equal(pointer.Bool(false)))
+
+	restored := &infrav1.OpenStackMachine{}
+	g.Expect(spoke.ConvertTo(restored)).To(gomega.Succeed())
+	g.Expect(restored.Spec.Ports).To(gomega.Equal(hub().Spec.Ports))
+
+	delete(spoke.GetAnnotations(), utilconversion.DataAnnotation)
+	restored = &infrav1.OpenStackMachine{}
+	g.Expect(spoke.ConvertTo(restored)).To(gomega.Succeed())
+	g.Expect(restored.Spec.Ports).To(gomega.Equal(hub().Spec.Ports))
+}
+
+func TestConvertToRestoresMachine(t *testing.T) {
+	hub := func() *infrav1.OpenStackMachine {
+		return &infrav1.OpenStackMachine{
+			Spec: infrav1.OpenStackMachineSpec{
+				Flavor:      "m1.medium",
+				Image:       infrav1.ImageFilter{Name: "ubuntu", Tags: []string{"capi"}},
+				ServerGroup: &infrav1.ServerGroupFilter{Name: "workers"},
+				AdditionalBlockDevices: []infrav1.AdditionalBlockDevice{
+					{Name: "etcd", SizeGiB: 10, Storage: infrav1.BlockDeviceStorage{Type: infrav1.LocalBlockDevice}},
+				},
+			},
+			Status: infrav1.OpenStackMachineStatus{
+				ReferencedResources: infrav1.ReferencedMachineResources{ServerGroupID: "sg-id", ImageID: "image-id"},
+			},
+		}
+	}
+
+	t.Run("Unchanged in v1alpha5", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		spoke := &OpenStackMachine{}
+		g.Expect(spoke.ConvertFrom(hub())).To(gomega.Succeed())
+
+		restored := &infrav1.OpenStackMachine{}
+		g.Expect(spoke.ConvertTo(restored)).To(gomega.Succeed())
+		g.Expect(restored.Spec).To(gomega.Equal(hub().Spec))
+		g.Expect(restored.Status.ReferencedResources).To(gomega.Equal(hub().Status.ReferencedResources))
+	})
+
+	t.Run("Changed in v1alpha5", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		spoke := &OpenStackMachine{}
+		g.Expect(spoke.ConvertFrom(hub())).To(gomega.Succeed())
+		spoke.Spec.Image = "debian"
+		spoke.Spec.ServerGroupID = "other-sg-id"
+
+		restored := &infrav1.OpenStackMachine{}
+		g.Expect(spoke.ConvertTo(restored)).To(gomega.Succeed())
+		g.Expect(restored.Spec.Image).To(gomega.Equal(infrav1.ImageFilter{Name: "debian"}))
+		g.Expect(restored.Spec.ServerGroup).To(gomega.Equal(&infrav1.ServerGroupFilter{ID: "other-sg-id"}))
+		// The fields without an equivalent in v1alpha5 are restored regardless.
+		g.Expect(restored.Spec.AdditionalBlockDevices).To(gomega.Equal(hub().Spec.AdditionalBlockDevices))
+	})
+}
+
+func TestConvertToRestoresCluster(t *testing.T) {
+	g := gomega.NewWithT(t)
+	hub := func() *infrav1.OpenStackCluster {
+		return &infrav1.OpenStackCluster{
+			Spec: infrav1.OpenStackClusterSpec{
+				Router:          &infrav1.RouterFilter{Name: "router"},
+				NetworkMTU:      1450,
+				ExternalNetwork: infrav1.NetworkFilter{Name: "public"},
+				Subnets:         []infrav1.SubnetFilter{{Name: "ipv4"}, {Name: "ipv6"}},
+				ManagedSubnets: []infrav1.SubnetSpec{
+					{CIDR: "10.0.0.0/24", AllocationPools: []infrav1.AllocationPool{{Start: "10.0.0.10", End: "10.0.0.100"}}},
+				},
+				ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{
+					AllNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{{Name: "ssh", Direction: "ingress"}},
+					NamePrefix:                 "mgmt",
+				},
+				Bastion: &infrav1.Bastion{
+					Enabled:  true,
+					Instance: infrav1.OpenStackMachineSpec{Image: infrav1.ImageFilter{Name: "bastion", Tags: []string{"capi"}}},
+				},
+				IdentityRef: infrav1.OpenStackIdentityReference{Name: "cloud-config", CloudName: "openstack"},
+			},
+			Status: infrav1.OpenStackClusterStatus{
+				WorkerSecurityGroup: &infrav1.SecurityGroupStatus{
+					ID:    "worker-id",
+					Name:  "k8s-cluster-mycluster-secgroup-worker",
+					Rules: []infrav1.SecurityGroupRuleStatus{{ID: "rule-id", Direction: "ingress", Origin: infrav1.SecurityGroupRuleOriginGeneral}},
+				},
+				NodeSecurityGroup:              &infrav1.SecurityGroupStatus{ID: "node-id"},
+				SecurityGroupReconcileFailures: 2,
+			},
+		}
+	}
+
+	spoke := &OpenStackCluster{}
+	g.Expect(spoke.ConvertFrom(hub())).To(gomega.Succeed())
+
+	restored := &infrav1.OpenStackCluster{}
+	g.Expect(spoke.ConvertTo(restored)).To(gomega.Succeed())
+	g.Expect(restored.Spec).To(gomega.Equal(hub().Spec))
+	g.Expect(restored.Status.WorkerSecurityGroup).To(gomega.Equal(hub().Status.WorkerSecurityGroup))
+	g.Expect(restored.Status.NodeSecurityGroup).To(gomega.Equal(hub().Status.NodeSecurityGroup))
+	g.Expect(restored.Status.SecurityGroupReconcileFailures).To(gomega.Equal(2))
+}
+
+func TestConvertToRestoresSubnets(t *testing.T) {
+	tests := []struct {
+		name string
+		spec infrav1.OpenStackClusterSpec
+	}{
+		{
+			name: "Dual-stack managed subnets",
+			spec: infrav1.OpenStackClusterSpec{
+				ManagedSubnets: []infrav1.SubnetSpec{
+					{CIDR: "10.0.0.0/24", DNSNameservers: []string{"10.0.0.2"}},
+					{CIDR: "2001:db8::/64", DNSNameservers: []string{"2001:db8::2"}},
+				},
+			},
+		},
+		{
+			name: "Multiple subnets",
+			spec: infrav1.OpenStackClusterSpec{
+				Subnets: []infrav1.SubnetFilter{{ID: "ipv4-id"}, {ID: "ipv6-id"}, {Name: "other"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			hub := &infrav1.OpenStackCluster{Spec: *tt.spec.DeepCopy()}
+
+			spoke := &OpenStackCluster{}
+			g.Expect(spoke.ConvertFrom(hub)).To(gomega.Succeed())
+
+			restored := &infrav1.OpenStackCluster{}
+			g.Expect(spoke.ConvertTo(restored)).To(gomega.Succeed())
+			g.Expect(restored.Spec.ManagedSubnets).To(gomega.Equal(tt.spec.ManagedSubnets))
+			g.Expect(restored.Spec.Subnets).To(gomega.Equal(tt.spec.Subnets))
+		})
+	}
+}
+
+func TestConvertToRestoresManagedSecurityGroups(t *testing.T) {
+	customRules := []infrav1.SecurityGroupRuleSpec{
+		{Name: "vxlan", Direction: "ingress", Protocol: pointer.String("udp"), PortRangeMin: pointer.Int(4789), PortRangeMax: pointer.Int(4789), RemoteManagedGroups: []infrav1.ManagedSecurityGroupName{"controlplane", "worker"}},
+	}
+	tests := []struct {
+		name                  string
+		managedSecurityGroups *infrav1.ManagedSecurityGroups
+		changeSpoke           func(*OpenStackCluster)
+		want                  *infrav1.ManagedSecurityGroups
+	}{
+		{
+			name:                  "Custom allNodes rules",
+			managedSecurityGroups: &infrav1.ManagedSecurityGroups{AllNodesSecurityGroupRules: customRules},
+			want:                  &infrav1.ManagedSecurityGroups{AllNodesSecurityGroupRules: customRules},
+		},
+		{
+			name:                  "No allNodes rules",
+			managedSecurityGroups: &infrav1.ManagedSecurityGroups{},
+			want:                  &infrav1.ManagedSecurityGroups{},
+		},
+		{
+			name:                  "Custom allNodes rules with all the in-cluster traffic allowed",
+			managedSecurityGroups: &infrav1.ManagedSecurityGroups{AllNodesSecurityGroupRules: customRules, AllowAllInClusterTraffic: true},
+			want:                  &infrav1.ManagedSecurityGroups{AllNodesSecurityGroupRules: customRules, AllowAllInClusterTraffic: true},
+		},
+		{
+			name:                  "allowAllInClusterTraffic changed in v1alpha5",
+			managedSecurityGroups: &infrav1.ManagedSecurityGroups{AllNodesSecurityGroupRules: customRules, AllowAllInClusterTraffic: true},
+			changeSpoke: func(spoke *OpenStackCluster) {
+				spoke.Spec.AllowAllInClusterTraffic = false
+			},
+			want: &infrav1.ManagedSecurityGroups{AllNodesSecurityGroupRules: append(append([]infrav1.SecurityGroupRuleSpec{}, customRules...), infrav1.LegacyCalicoSecurityGroupRules()...)},
+		},
+		{
+			name:                  "allowAllInClusterTraffic enabled in v1alpha5",
+			managedSecurityGroups: &infrav1.ManagedSecurityGroups{AllNodesSecurityGroupRules: customRules},
+			changeSpoke: func(spoke *OpenStackCluster) {
+				spoke.Spec.AllowAllInClusterTraffic = true
+			},
+			want: &infrav1.ManagedSecurityGroups{AllNodesSecurityGroupRules: customRules, AllowAllInClusterTraffic: true},
+		},
+		{
+			name: "allowAllInClusterTraffic disabled in v1alpha5 with a rule named like a legacy Calico rule",
+			managedSecurityGroups: &infrav1.ManagedSecurityGroups{
+				AllNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{{Name: "BGP (calico)", Direction: "ingress", Protocol: pointer.String("tcp"), PortRangeMin: pointer.Int(179), PortRangeMax: pointer.Int(179)}},
+				AllowAllInClusterTraffic:   true,
+			},
+			changeSpoke: func(spoke *OpenStackCluster) {
+				spoke.Spec.AllowAllInClusterTraffic = false
+			},
+			want: &infrav1.ManagedSecurityGroups{AllNodesSecurityGroupRules: append(
+				[]infrav1.SecurityGroupRuleSpec{{Name: "BGP (calico)", Direction: "ingress", Protocol: pointer.String("tcp"), PortRangeMin: pointer.Int(179), PortRangeMax: pointer.Int(179)}},
+				infrav1.LegacyCalicoSecurityGroupRules()[1:]...,
+			)},
+		},
+		{
+			name: "Managed security groups enabled in v1alpha5",
+			changeSpoke: func(spoke *OpenStackCluster) {
+				spoke.Spec.ManagedSecurityGroups = true
+			},
+			want: &infrav1.ManagedSecurityGroups{AllNodesSecurityGroupRules: infrav1.LegacyCalicoSecurityGroupRules()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			hub := &infrav1.OpenStackCluster{Spec: infrav1.OpenStackClusterSpec{ManagedSecurityGroups: tt.managedSecurityGroups}}
+
+			spoke := &OpenStackCluster{}
+			g.Expect(spoke.ConvertFrom(hub)).To(gomega.Succeed())
+			if tt.changeSpoke != nil {
+				tt.changeSpoke(spoke)
+			}
+
+			restored := &infrav1.OpenStackCluster{}
+			g.Expect(spoke.ConvertTo(restored)).To(gomega.Succeed())
+			g.Expect(restored.Spec.ManagedSecurityGroups).To(gomega.Equal(tt.want))
+		})
+	}
+}
+
+// TestFuzzyIdentityRefConversion checks that the identity reference survives a round-trip through v1alpha5. The
+// region has no equivalent in v1alpha5 and is restored from the conversion annotation.
+func TestFuzzyIdentityRefConversion(t *testing.T) {
+	g := gomega.NewWithT(t)
+	f := fuzz.New().NilChance(0.3)
+
+	for i := 0; i < 1000; i++ {
+		clusterHub := &infrav1.OpenStackCluster{}
+		f.Fuzz(&clusterHub.Spec.IdentityRef)
+		clusterSpoke := &OpenStackCluster{}
+		g.Expect(clusterSpoke.ConvertFrom(clusterHub.DeepCopy())).To(gomega.Succeed())
+		clusterRestored := &infrav1.OpenStackCluster{}
+		g.Expect(clusterSpoke.ConvertTo(clusterRestored)).To(gomega.Succeed())
+		g.Expect(clusterRestored.Spec.IdentityRef).To(gomega.Equal(clusterHub.Spec.IdentityRef))
+
+		machineHub := &infrav1.OpenStackMachine{}
+		f.Fuzz(&machineHub.Spec.IdentityRef)
+		machineSpoke := &OpenStackMachine{}
+		g.Expect(machineSpoke.ConvertFrom(machineHub.DeepCopy())).To(gomega.Succeed())
+		machineRestored := &infrav1.OpenStackMachine{}
+		g.Expect(machineSpoke.ConvertTo(machineRestored)).To(gomega.Succeed())
+		g.Expect(machineRestored.Spec.IdentityRef).To(gomega.Equal(machineHub.Spec.IdentityRef))
+	}
+}
+
 func TestConvert_v1alpha5_OpenStackClusterSpec_To_v1beta1_OpenStackClusterSpec(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -165,3 +799,129 @@ func TestConvert_v1alpha5_OpenStackClusterSpec_To_v1beta1_OpenStackClusterSpec(t
 		})
 	}
 }
+
+func TestConvert_v1alpha5_PortOpts_To_v1beta1_PortOpts(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          *PortOpts
+		expectedOut *infrav1.PortOpts
+	}{
+		{
+			name:        "empty",
+			in:          &PortOpts{},
+			expectedOut: &infrav1.PortOpts{},
+		},
+		{
+			name: "with security groups and security group filters",
+			in: &PortOpts{
+				SecurityGroups: []string{"sg-id-1", "sg-id-2"},
+				SecurityGroupFilters: []SecurityGroupParam{
+					{UUID: "sg-id-3"},
+					{Name: "sg-name"},
+					{Filter: SecurityGroupFilter{Description: "sg-description", Tags: "tag1,tag2"}},
+				},
+			},
+			expectedOut: &infrav1.PortOpts{
+				SecurityGroups: []infrav1.SecurityGroupFilter{
+					{ID: "sg-id-3"},
+					{Name: "sg-name"},
+					{
+						Description: "sg-description",
+						FilterByNeutronTags: infrav1.FilterByNeutronTags{
+							Tags: []infrav1.NeutronTag{"tag1", "tag2"},
+						},
+					},
+					{ID: "sg-id-1"},
+					{ID: "sg-id-2"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+			out := &infrav1.PortOpts{}
+			err := Convert_v1alpha5_PortOpts_To_v1beta1_PortOpts(tt.in, out, nil)
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(out).To(gomega.Equal(tt.expectedOut))
+		})
+	}
+}
+
+func TestConvertBastionFloatingIP(t *testing.T) {
+	t.Run("v1beta1 to v1alpha5", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		in := &infrav1.Bastion{Enabled: true, FloatingIP: "192.0.2.10"}
+		out := &Bastion{}
+		g.Expect(Convert_v1beta1_Bastion_To_v1alpha5_Bastion(in, out, nil)).To(gomega.Succeed())
+		g.Expect(out.Instance.FloatingIP).To(gomega.Equal("192.0.2.10"))
+		g.Expect(in.FloatingIP).To(gomega.Equal("192.0.2.10"))
+	})
+
+	t.Run("v1alpha5 to v1beta1", func(t *testing.T) {
+		g := gomega.NewWithT(t)
+		in := &Bastion{Enabled: true, Instance: OpenStackMachineSpec{FloatingIP: "192.0.2.10"}}
+		out := &infrav1.Bastion{}
+		g.Expect(Convert_v1alpha5_Bastion_To_v1beta1_Bastion(in, out, nil)).To(gomega.Succeed())
+		g.Expect(out.FloatingIP).To(gomega.Equal("192.0.2.10"))
+		g.Expect(in.Instance.FloatingIP).To(gomega.Equal("192.0.2.10"))
+	})
+}
+
+func TestConvertSecurityGroupFilterTagsToListOpts(t *testing.T) {
+	g := gomega.NewWithT(t)
+
+	in := &SecurityGroupFilter{
+		Tags:       "env-a,capo",
+		TagsAny:    "web,,db",
+		NotTags:    "legacy",
+		NotTagsAny: "",
+	}
+	out := &infrav1.SecurityGroupFilter{}
+	g.Expect(Convert_v1alpha5_SecurityGroupFilter_To_v1beta1_SecurityGroupFilter(in, out, nil)).To(gomega.Succeed())
+
+	listOpts := out.ToListOpt()
+	g.Expect(listOpts.Tags).To(gomega.Equal("env-a,capo"))
+	// Empty tags are dropped.
+	g.Expect(listOpts.TagsAny).To(gomega.Equal("web,db"))
+	g.Expect(listOpts.NotTags).To(gomega.Equal("legacy"))
+	g.Expect(listOpts.NotTagsAny).To(gomega.BeEmpty())
+}
+
+func TestConvertSecurityGroupFilterDescriptionToListOpts(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		wantQuery   string
+	}{
+		{
+			name:        "Description is a filter",
+			description: "capo worker group",
+			wantQuery:   "?description=capo+worker+group&name=sg",
+		},
+		{
+			name:      "Empty description is not a filter",
+			wantQuery: "?name=sg",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := gomega.NewWithT(t)
+
+			in := &SecurityGroupFilter{Name: "sg", Description: tt.description}
+			out := &infrav1.SecurityGroupFilter{}
+			g.Expect(Convert_v1alpha5_SecurityGroupFilter_To_v1beta1_SecurityGroupFilter(in, out, nil)).To(gomega.Succeed())
+			g.Expect(out.Description).To(gomega.Equal(tt.description))
+
+			roundTripped := &SecurityGroupFilter{}
+			g.Expect(Convert_v1beta1_SecurityGroupFilter_To_v1alpha5_SecurityGroupFilter(out, roundTripped, nil)).To(gomega.Succeed())
+			g.Expect(roundTripped.Description).To(gomega.Equal(tt.description))
+
+			listOpts := out.ToListOpt()
+			query, err := gophercloud.BuildQueryString(&listOpts)
+			g.Expect(err).NotTo(gomega.HaveOccurred())
+			g.Expect(query.String()).To(gomega.Equal(tt.wantQuery))
+		})
+	}
+}
@@ -465,7 +465,9 @@ func autoConvert_v1beta1_Bastion_To_v1alpha5_Bastion(in *v1beta1.Bastion, out *B
 		return err
 	}
 	out.AvailabilityZone = in.AvailabilityZone
+	// WARNING: in.Replicas requires manual conversion: does not exist in peer-type
 	// WARNING: in.FloatingIP requires manual conversion: does not exist in peer-type
+	// WARNING: in.SSHAllowedCIDRs requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -861,6 +863,12 @@ func autoConvert_v1beta1_OpenStackClusterStatus_To_v1alpha5_OpenStackClusterStat
 	} else {
 		out.BastionSecurityGroup = nil
 	}
+	// WARNING: in.AdditionalBastionSecurityGroups requires manual conversion: does not exist in peer-type
+	// WARNING: in.NodeSecurityGroup requires manual conversion: does not exist in peer-type
+	// WARNING: in.AllNodesSecurityGroup requires manual conversion: does not exist in peer-type
+	// WARNING: in.ShadowSecurityGroup requires manual conversion: does not exist in peer-type
+	// WARNING: in.ManagedSecurityGroups requires manual conversion: does not exist in peer-type
+	// WARNING: in.ManagedSecurityGroupRules requires manual conversion: does not exist in peer-type
 	if in.Bastion != nil {
 		in, out := &in.Bastion, &out.Bastion
 		*out = new(Instance)
@@ -870,8 +878,11 @@ func autoConvert_v1beta1_OpenStackClusterStatus_To_v1alpha5_OpenStackClusterStat
 	} else {
 		out.Bastion = nil
 	}
+	// WARNING: in.AdditionalBastions requires manual conversion: does not exist in peer-type
+	// WARNING: in.SecurityGroupReconcileFailures requires manual conversion: does not exist in peer-type
 	out.FailureReason = (*errors.ClusterStatusError)(unsafe.Pointer(in.FailureReason))
 	out.FailureMessage = (*string)(unsafe.Pointer(in.FailureMessage))
+	// WARNING: in.Conditions requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -1000,6 +1011,7 @@ func autoConvert_v1alpha5_OpenStackIdentityReference_To_v1beta1_OpenStackIdentit
 func autoConvert_v1beta1_OpenStackIdentityReference_To_v1alpha5_OpenStackIdentityReference(in *v1beta1.OpenStackIdentityReference, out *OpenStackIdentityReference, s conversion.Scope) error {
 	out.Name = in.Name
 	// WARNING: in.CloudName requires manual conversion: does not exist in peer-type
+	// WARNING: in.Region requires manual conversion: does not exist in peer-type
 	return nil
 }
 
@@ -1159,6 +1171,7 @@ func autoConvert_v1beta1_OpenStackMachineSpec_To_v1alpha5_OpenStackMachineSpec(i
 	out.Trunk = in.Trunk
 	out.Tags = *(*[]string)(unsafe.Pointer(&in.Tags))
 	// WARNING: in.ServerMetadata requires manual conversion: inconvertible types ([]sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1.ServerMetadata vs map[string]string)
+	// WARNING: in.AddMachineNameMetadata requires manual conversion: does not exist in peer-type
 	out.ConfigDrive = (*bool)(unsafe.Pointer(in.ConfigDrive))
 	out.RootVolume = (*RootVolume)(unsafe.Pointer(in.RootVolume))
 	// WARNING: in.AdditionalBlockDevices requires manual conversion: does not exist in peer-type
@@ -1196,6 +1209,7 @@ func autoConvert_v1beta1_OpenStackMachineStatus_To_v1alpha5_OpenStackMachineStat
 	out.InstanceState = (*InstanceState)(unsafe.Pointer(in.InstanceState))
 	// WARNING: in.ReferencedResources requires manual conversion: does not exist in peer-type
 	// WARNING: in.DependentResources requires manual conversion: does not exist in peer-type
+	// WARNING: in.MachineNameMetadata requires manual conversion: does not exist in peer-type
 	out.FailureReason = (*errors.MachineStatusError)(unsafe.Pointer(in.FailureReason))
 	out.FailureMessage = (*string)(unsafe.Pointer(in.FailureMessage))
 	out.Conditions = *(*apiv1beta1.Conditions)(unsafe.Pointer(&in.Conditions))
@@ -1439,6 +1453,7 @@ func autoConvert_v1beta1_PortOpts_To_v1alpha5_PortOpts(in *v1beta1.PortOpts, out
 	}
 	// WARNING: in.Profile requires manual conversion: inconvertible types (*sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1.BindingProfile vs map[string]string)
 	out.DisablePortSecurity = (*bool)(unsafe.Pointer(in.DisablePortSecurity))
+	// WARNING: in.ExcludeManagedSecurityGroups requires manual conversion: does not exist in peer-type
 	// WARNING: in.PropagateUplinkStatus requires manual conversion: does not exist in peer-type
 	out.Tags = *(*[]string)(unsafe.Pointer(&in.Tags))
 	// WARNING: in.ValueSpecs requires manual conversion: does not exist in peer-type
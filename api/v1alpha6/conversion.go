@@ -159,17 +159,24 @@ func restorev1alpha6ClusterStatus(previous *OpenStackClusterStatus, dst *OpenSta
 }
 
 func restorev1beta1MachineSpec(previous *infrav1.OpenStackMachineSpec, dst *infrav1.OpenStackMachineSpec) {
-	// PropagateUplinkStatus has been added in v1beta1.
+	// PropagateUplinkStatus and ExcludeManagedSecurityGroups have been added in v1beta1.
 	// We restore the whole Ports since they are anyway immutable.
 	dst.Ports = previous.Ports
 	dst.AdditionalBlockDevices = previous.AdditionalBlockDevices
 	dst.ServerGroup = previous.ServerGroup
 	dst.Image = previous.Image
+	dst.AddMachineNameMetadata = previous.AddMachineNameMetadata
+
+	if previous.IdentityRef != nil && dst.IdentityRef != nil {
+		dst.IdentityRef.Region = previous.IdentityRef.Region
+	}
 }
 
 func restorev1beta1Bastion(previous **infrav1.Bastion, dst **infrav1.Bastion) {
 	if *previous != nil && *dst != nil {
 		restorev1beta1MachineSpec(&(*previous).Instance, &(*dst).Instance)
+		(*dst).SSHAllowedCIDRs = (*previous).SSHAllowedCIDRs
+		(*dst).Replicas = (*previous).Replicas
 	}
 }
 
@@ -190,6 +197,15 @@ func restorev1beta1ClusterStatus(previous *infrav1.OpenStackClusterStatus, dst *
 	dst.ControlPlaneSecurityGroup = previous.ControlPlaneSecurityGroup
 	dst.WorkerSecurityGroup = previous.WorkerSecurityGroup
 	dst.BastionSecurityGroup = previous.BastionSecurityGroup
+	dst.SecurityGroupReconcileFailures = previous.SecurityGroupReconcileFailures
+	dst.ShadowSecurityGroup = previous.ShadowSecurityGroup
+	dst.NodeSecurityGroup = previous.NodeSecurityGroup
+	dst.AllNodesSecurityGroup = previous.AllNodesSecurityGroup
+	dst.ManagedSecurityGroups = previous.ManagedSecurityGroups
+	dst.ManagedSecurityGroupRules = previous.ManagedSecurityGroupRules
+	dst.AdditionalBastionSecurityGroups = previous.AdditionalBastionSecurityGroups
+	dst.AdditionalBastions = previous.AdditionalBastions
+	dst.Conditions = previous.Conditions
 
 	if previous.Bastion != nil {
 		dst.Bastion.ReferencedResources = previous.Bastion.ReferencedResources
@@ -283,6 +299,11 @@ var v1beta1OpenStackClusterRestorer = conversion.RestorerFor[*infrav1.OpenStackC
 			return &c.Spec.NetworkMTU
 		},
 	),
+	"identityRefRegion": conversion.UnconditionalFieldRestorer(
+		func(c *infrav1.OpenStackCluster) *string {
+			return &c.Spec.IdentityRef.Region
+		},
+	),
 	"bastion": conversion.HashedFieldRestorer(
 		func(c *infrav1.OpenStackCluster) **infrav1.Bastion {
 			return &c.Spec.Bastion
@@ -361,6 +382,20 @@ var v1alpha6OpenStackClusterTemplateRestorer = conversion.RestorerFor[*OpenStack
 
 func restorev1beta1ManagedSecurityGroups(previous *infrav1.ManagedSecurityGroups, dst *infrav1.ManagedSecurityGroups) {
 	dst.AllNodesSecurityGroupRules = previous.AllNodesSecurityGroupRules
+	dst.AllowLoadBalancerServiceTraffic = previous.AllowLoadBalancerServiceTraffic
+	dst.ShadowRules = previous.ShadowRules
+	dst.SharedNodeGroup = previous.SharedNodeGroup
+	dst.NamePrefix = previous.NamePrefix
+	dst.ProjectID = previous.ProjectID
+	dst.NodePortAllowedCIDRs = previous.NodePortAllowedCIDRs
+	dst.SeparateAllNodesGroup = previous.SeparateAllNodesGroup
+	dst.DisableDefaultRules = previous.DisableDefaultRules
+	dst.DisableWorkerIngressRules = previous.DisableWorkerIngressRules
+	dst.Stateless = previous.Stateless
+	dst.RetainOnDelete = previous.RetainOnDelete
+	dst.ExistingGroups = previous.ExistingGroups
+	dst.DeleteAdoptedGroups = previous.DeleteAdoptedGroups
+	dst.WellKnownPorts = previous.WellKnownPorts
 }
 
 var v1beta1OpenStackClusterTemplateRestorer = conversion.RestorerFor[*infrav1.OpenStackClusterTemplate]{
@@ -384,6 +419,11 @@ var v1beta1OpenStackClusterTemplateRestorer = conversion.RestorerFor[*infrav1.Op
 			return &c.Spec.Template.Spec.NetworkMTU
 		},
 	),
+	"identityRefRegion": conversion.UnconditionalFieldRestorer(
+		func(c *infrav1.OpenStackClusterTemplate) *string {
+			return &c.Spec.Template.Spec.IdentityRef.Region
+		},
+	),
 	"bastion": conversion.HashedFieldRestorer(
 		func(c *infrav1.OpenStackClusterTemplate) **infrav1.Bastion {
 			return &c.Spec.Template.Spec.Bastion
@@ -471,6 +511,11 @@ var v1beta1OpenStackMachineRestorer = conversion.RestorerFor[*infrav1.OpenStackM
 			return &c.Status.ReferencedResources
 		},
 	),
+	"machinenamemetadata": conversion.UnconditionalFieldRestorer(
+		func(c *infrav1.OpenStackMachine) *string {
+			return &c.Status.MachineNameMetadata
+		},
+	),
 }
 
 func (r *OpenStackMachine) ConvertTo(dstRaw ctrlconversion.Hub) error {
@@ -87,8 +87,12 @@ func restorev1beta1SecurityGroupStatus(previous *infrav1.SecurityGroupStatus, ds
 		return
 	}
 
+	dst.RulesHash = previous.RulesHash
+	dst.RulesPendingDeletion = previous.RulesPendingDeletion
+
 	for i := range dst.Rules {
 		dstRule := &dst.Rules[i]
+		dstRule.Origin = previous.Rules[i].Origin
 
 		// Conversion from scalar to *scalar is lossy for zero values. We need to restore only nil values.
 		if dstRule.Description != nil && *dstRule.Description == "" {
@@ -119,6 +123,15 @@ func restorev1beta1ClusterStatus(previous *infrav1.OpenStackClusterStatus, dst *
 	restorev1beta1SecurityGroupStatus(previous.ControlPlaneSecurityGroup, dst.ControlPlaneSecurityGroup)
 	restorev1beta1SecurityGroupStatus(previous.WorkerSecurityGroup, dst.WorkerSecurityGroup)
 	restorev1beta1SecurityGroupStatus(previous.BastionSecurityGroup, dst.BastionSecurityGroup)
+	dst.SecurityGroupReconcileFailures = previous.SecurityGroupReconcileFailures
+	dst.ShadowSecurityGroup = previous.ShadowSecurityGroup
+	dst.NodeSecurityGroup = previous.NodeSecurityGroup
+	dst.AllNodesSecurityGroup = previous.AllNodesSecurityGroup
+	dst.ManagedSecurityGroups = previous.ManagedSecurityGroups
+	dst.ManagedSecurityGroupRules = previous.ManagedSecurityGroupRules
+	dst.AdditionalBastionSecurityGroups = previous.AdditionalBastionSecurityGroups
+	dst.AdditionalBastions = previous.AdditionalBastions
+	dst.Conditions = previous.Conditions
 
 	// ReferencedResources have no equivalent in v1alpha7
 	if previous.Bastion != nil {
@@ -263,6 +276,11 @@ func restorev1alpha7MachineSpec(previous *OpenStackMachineSpec, dst *OpenStackMa
 func restorev1beta1MachineSpec(previous *infrav1.OpenStackMachineSpec, dst *infrav1.OpenStackMachineSpec) {
 	dst.ServerGroup = previous.ServerGroup
 	dst.Image = previous.Image
+	dst.AddMachineNameMetadata = previous.AddMachineNameMetadata
+
+	if previous.IdentityRef != nil && dst.IdentityRef != nil {
+		dst.IdentityRef.Region = previous.IdentityRef.Region
+	}
 
 	if len(dst.Ports) == len(previous.Ports) {
 		for i := range dst.Ports {
@@ -314,6 +332,8 @@ func restorev1beta1Port(previous *infrav1.PortOpts, dst *infrav1.PortOpts) {
 		dst.VNICType = previous.VNICType
 	}
 
+	dst.ExcludeManagedSecurityGroups = previous.ExcludeManagedSecurityGroups
+
 	if dst.Profile == nil && previous.Profile != nil {
 		dst.Profile = &infrav1.BindingProfile{}
 	}
@@ -335,6 +355,8 @@ func restorev1beta1Port(previous *infrav1.PortOpts, dst *infrav1.PortOpts) {
 func restorev1beta1Bastion(previous **infrav1.Bastion, dst **infrav1.Bastion) {
 	if *previous != nil && *dst != nil {
 		restorev1beta1MachineSpec(&(*previous).Instance, &(*dst).Instance)
+		(*dst).SSHAllowedCIDRs = (*previous).SSHAllowedCIDRs
+		(*dst).Replicas = (*previous).Replicas
 	}
 }
 
@@ -392,6 +414,8 @@ func restorev1beta1ClusterSpec(previous *infrav1.OpenStackClusterSpec, dst *infr
 
 	dst.DisableExternalNetwork = previous.DisableExternalNetwork
 
+	dst.IdentityRef.Region = previous.IdentityRef.Region
+
 	if len(previous.Subnets) > 1 {
 		dst.Subnets = append(dst.Subnets, previous.Subnets[1:]...)
 	}
@@ -400,6 +424,20 @@ func restorev1beta1ClusterSpec(previous *infrav1.OpenStackClusterSpec, dst *infr
 
 	if previous.ManagedSecurityGroups != nil {
 		dst.ManagedSecurityGroups.AllNodesSecurityGroupRules = previous.ManagedSecurityGroups.AllNodesSecurityGroupRules
+		dst.ManagedSecurityGroups.AllowLoadBalancerServiceTraffic = previous.ManagedSecurityGroups.AllowLoadBalancerServiceTraffic
+		dst.ManagedSecurityGroups.ShadowRules = previous.ManagedSecurityGroups.ShadowRules
+		dst.ManagedSecurityGroups.WellKnownPorts = previous.ManagedSecurityGroups.WellKnownPorts
+		dst.ManagedSecurityGroups.SharedNodeGroup = previous.ManagedSecurityGroups.SharedNodeGroup
+		dst.ManagedSecurityGroups.NamePrefix = previous.ManagedSecurityGroups.NamePrefix
+		dst.ManagedSecurityGroups.ProjectID = previous.ManagedSecurityGroups.ProjectID
+		dst.ManagedSecurityGroups.NodePortAllowedCIDRs = previous.ManagedSecurityGroups.NodePortAllowedCIDRs
+		dst.ManagedSecurityGroups.SeparateAllNodesGroup = previous.ManagedSecurityGroups.SeparateAllNodesGroup
+		dst.ManagedSecurityGroups.DisableDefaultRules = previous.ManagedSecurityGroups.DisableDefaultRules
+		dst.ManagedSecurityGroups.DisableWorkerIngressRules = previous.ManagedSecurityGroups.DisableWorkerIngressRules
+		dst.ManagedSecurityGroups.Stateless = previous.ManagedSecurityGroups.Stateless
+		dst.ManagedSecurityGroups.RetainOnDelete = previous.ManagedSecurityGroups.RetainOnDelete
+		dst.ManagedSecurityGroups.ExistingGroups = previous.ManagedSecurityGroups.ExistingGroups
+		dst.ManagedSecurityGroups.DeleteAdoptedGroups = previous.ManagedSecurityGroups.DeleteAdoptedGroups
 	}
 }
 
@@ -517,6 +555,11 @@ var v1beta1OpenStackMachineRestorer = conversion.RestorerFor[*infrav1.OpenStackM
 			return &c.Status.ReferencedResources
 		},
 	),
+	"machinenamemetadata": conversion.UnconditionalFieldRestorer(
+		func(c *infrav1.OpenStackMachine) *string {
+			return &c.Status.MachineNameMetadata
+		},
+	),
 }
 
 func (r *OpenStackMachine) ConvertTo(dstRaw ctrlconversion.Hub) error {
@@ -53,3 +53,20 @@ const (
 	// FloatingIPErrorReason used when the floating ip could not be created or attached.
 	FloatingIPErrorReason = "FloatingIPError"
 )
+
+const (
+	// SecurityGroupNotUniqueCondition is set on the OpenStackCluster when several security groups have the name
+	// of a managed security group, and none of them could be picked. It is removed once the security groups are
+	// reconciled.
+	SecurityGroupNotUniqueCondition clusterv1.ConditionType = "SecurityGroupNotUnique"
+
+	// DuplicateSecurityGroupNameReason used when several security groups have the name of a managed security group.
+	DuplicateSecurityGroupNameReason = "DuplicateSecurityGroupName"
+
+	// SecurityGroupStatefulnessMismatchCondition is set on the OpenStackCluster when managed security groups are not
+	// stateless or stateful as set in spec.managedSecurityGroups.stateless. It is removed once the groups match.
+	SecurityGroupStatefulnessMismatchCondition clusterv1.ConditionType = "SecurityGroupStatefulnessMismatch"
+
+	// SecurityGroupRecreationRequiredReason used when a managed security group must be recreated to change its statefulness.
+	SecurityGroupRecreationRequiredReason = "SecurityGroupRecreationRequired"
+)
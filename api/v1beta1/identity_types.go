@@ -28,4 +28,10 @@ type OpenStackIdentityReference struct {
 	// CloudName specifies the name of the entry in the clouds.yaml file to use.
 	// +kubebuilder:validation:Required
 	CloudName string `json:"cloudName"`
+
+	// Region specifies the OpenStack region in which the resources are provisioned.
+	// It overrides the region_name of the clouds.yaml entry. If not specified, the
+	// region_name of the clouds.yaml entry is used.
+	// +optional
+	Region string `json:"region,omitempty"`
 }
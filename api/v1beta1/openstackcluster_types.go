@@ -26,6 +26,23 @@ const (
 	// ClusterFinalizer allows ReconcileOpenStackCluster to clean up OpenStack resources associated with OpenStackCluster before
 	// removing it from the apiserver.
 	ClusterFinalizer = "openstackcluster.infrastructure.cluster.x-k8s.io"
+
+	// SecurityGroupClientTimeoutAnnotation bounds the time the OpenStack API calls made to reconcile the managed
+	// security groups of an OpenStackCluster may take altogether. Its value is a duration, e.g. 2m.
+	SecurityGroupClientTimeoutAnnotation = "infrastructure.cluster.x-k8s.io/security-group-client-timeout"
+
+	// SecurityGroupGoldenRulesAnnotation names a ConfigMap in the namespace of an OpenStackCluster holding the
+	// golden rules of its managed security groups. The generated rules are checked against them at each reconcile.
+	SecurityGroupGoldenRulesAnnotation = "infrastructure.cluster.x-k8s.io/security-group-golden-rules"
+
+	// SecurityGroupRuleProbeTimeoutAnnotation opts an OpenStackCluster in to probing that the SSH rule of its bastion
+	// security group is effective after reconciling the managed security groups. Its value is the timeout of the
+	// probe, a duration, e.g. 5s.
+	SecurityGroupRuleProbeTimeoutAnnotation = "infrastructure.cluster.x-k8s.io/security-group-rule-probe-timeout"
+
+	// SecurityGroupRulesStatusAnnotation reports the desired rules of the managed security groups of an
+	// OpenStackCluster in its status when set to "true". It is unset by default to keep the status small.
+	SecurityGroupRulesStatusAnnotation = "infrastructure.cluster.x-k8s.io/security-group-rules-status"
 )
 
 // OpenStackClusterSpec defines the desired state of OpenStackCluster.
@@ -192,8 +209,56 @@ type OpenStackClusterStatus struct {
 
 	BastionSecurityGroup *SecurityGroupStatus `json:"bastionSecurityGroup,omitempty"`
 
+	// additionalBastionSecurityGroups contains the information about the security groups of the
+	// bastion replicas after the first, in the order of their index. The security group of the
+	// first replica is bastionSecurityGroup.
+	// +optional
+	AdditionalBastionSecurityGroups []SecurityGroupStatus `json:"additionalBastionSecurityGroups,omitempty"`
+
+	// nodeSecurityGroup contains the information about the security group applied to
+	// all the nodes when managedSecurityGroups.sharedNodeGroup is set. The control plane
+	// and worker security groups are not reconciled in that case: controlPlaneSecurityGroup
+	// and workerSecurityGroup report this group too.
+	// +optional
+	NodeSecurityGroup *SecurityGroupStatus `json:"nodeSecurityGroup,omitempty"`
+
+	// allNodesSecurityGroup contains the information about the security group carrying the
+	// allNodesSecurityGroupRules when managedSecurityGroups.separateAllNodesGroup is set.
+	// +optional
+	AllNodesSecurityGroup *SecurityGroupStatus `json:"allNodesSecurityGroup,omitempty"`
+
+	// shadowSecurityGroup contains the information about the shadow security group
+	// reconciled from managedSecurityGroups.shadowRules.
+	// +optional
+	ShadowSecurityGroup *SecurityGroupStatus `json:"shadowSecurityGroup,omitempty"`
+
+	// managedSecurityGroups contains the state of the reconciliation of the managed security groups.
+	// +optional
+	ManagedSecurityGroups *ManagedSecurityGroupsStatus `json:"managedSecurityGroups,omitempty"`
+
+	// managedSecurityGroupRules summarizes the rules the controller intends each managed security
+	// group to have, along with the rules the group doesn't have yet. It is updated at each
+	// reconcile, and only reported when the cluster has the
+	// infrastructure.cluster.x-k8s.io/security-group-rules-status annotation set to "true".
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	ManagedSecurityGroupRules []ManagedSecurityGroupRulesStatus `json:"managedSecurityGroupRules,omitempty"`
+
 	Bastion *BastionStatus `json:"bastion,omitempty"`
 
+	// additionalBastions is the status of the bastion replicas after the first, in the order of
+	// their index. The status of the first replica is bastion.
+	// +optional
+	AdditionalBastions []BastionStatus `json:"additionalBastions,omitempty"`
+
+	// securityGroupReconcileFailures is the number of consecutive failed attempts to
+	// reconcile the security groups. It is reset when the security groups are
+	// reconciled successfully. When it reaches the maximum configured in the
+	// controller, FailureReason and FailureMessage are set.
+	// +optional
+	SecurityGroupReconcileFailures int `json:"securityGroupReconcileFailures,omitempty"`
+
 	// FailureReason will be set in the event that there is a terminal problem
 	// reconciling the OpenStackCluster and will contain a succinct value suitable
 	// for machine interpretation.
@@ -231,6 +296,10 @@ type OpenStackClusterStatus struct {
 	// and/or logged in the controller's output.
 	// +optional
 	FailureMessage *string `json:"failureMessage,omitempty"`
+
+	// conditions defines current service state of the OpenStackCluster.
+	// +optional
+	Conditions clusterv1.Conditions `json:"conditions,omitempty"`
 }
 
 // +genclient
@@ -264,9 +333,21 @@ type OpenStackClusterList struct {
 	Items           []OpenStackCluster `json:"items"`
 }
 
+// GetConditions returns the observations of the operational state of the OpenStackCluster resource.
+func (r *OpenStackCluster) GetConditions() clusterv1.Conditions {
+	return r.Status.Conditions
+}
+
+// SetConditions sets the underlying service state of the OpenStackCluster to the predescribed clusterv1.Conditions.
+func (r *OpenStackCluster) SetConditions(conditions clusterv1.Conditions) {
+	r.Status.Conditions = conditions
+}
+
 // ManagedSecurityGroups defines the desired state of security groups and rules for the cluster.
 type ManagedSecurityGroups struct {
 	// allNodesSecurityGroupRules defines the rules that should be applied to all nodes.
+	// They are added to the rules permitting the in-cluster traffic, whether all of it is
+	// allowed by allowAllInClusterTraffic or not, e.g. for traffic from outside the cluster.
 	// +patchMergeKey=name
 	// +patchStrategy=merge
 	// +listType=map
@@ -275,9 +356,232 @@ type ManagedSecurityGroups struct {
 	AllNodesSecurityGroupRules []SecurityGroupRuleSpec `json:"allNodesSecurityGroupRules" patchStrategy:"merge" patchMergeKey:"name"`
 
 	// AllowAllInClusterTraffic allows all ingress and egress traffic between cluster nodes when set to true.
+	// Otherwise only the traffic of the Kubernetes components is allowed between them. It can be combined
+	// with allNodesSecurityGroupRules, whose rules are added to the rules allowing the in-cluster traffic.
 	// +kubebuilder:default=false
 	// +kubebuilder:validation:Required
 	AllowAllInClusterTraffic bool `json:"allowAllInClusterTraffic"`
+
+	// AllowLoadBalancerServiceTraffic allows the load balancers created by the cloud provider for the
+	// Services of type LoadBalancer of the workload cluster to reach the worker nodes. The node ports of
+	// those Services are opened to the cluster subnets in the worker security group.
+	// +optional
+	AllowLoadBalancerServiceTraffic bool `json:"allowLoadBalancerServiceTraffic,omitempty"`
+
+	// shadowRules is a proposed rule set reconciled into a separate shadow security
+	// group, alongside the control plane and worker groups. The shadow group is not
+	// attached to the ports of the machines: it can be attached to a canary node to
+	// try the rules out before moving them to allNodesSecurityGroupRules. The shadow
+	// group is deleted when shadowRules is emptied.
+	// +patchMergeKey=name
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	ShadowRules []SecurityGroupRuleSpec `json:"shadowRules,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+
+	// wellKnownPorts overrides the ports of the Kubernetes components permitted by the
+	// rules of the managed security groups, for clusters whose components don't listen
+	// on the standard ports. The ports which are not overridden keep their standard value.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	WellKnownPorts []WellKnownPort `json:"wellKnownPorts,omitempty"`
+
+	// sharedNodeGroup reconciles a single node security group, carrying the rules of
+	// both the control plane and the workers, attached to all the nodes, instead of
+	// separate control plane and worker groups. It can't be changed once the cluster
+	// is created.
+	// +optional
+	SharedNodeGroup bool `json:"sharedNodeGroup,omitempty"`
+
+	// namePrefix overrides the k8s prefix of the names of the managed security groups,
+	// <namePrefix>-cluster-<cluster name>-secgroup-<role>, e.g. to tell apart the groups of
+	// several management clusters sharing a project. Its length is capped so that, with
+	// cluster names of at most 63 characters, the names fit in the 255 characters allowed
+	// by Neutron. It can't be changed once the cluster is created.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=161
+	// +optional
+	NamePrefix string `json:"namePrefix,omitempty"`
+
+	// projectID is the ID of the project the managed security groups are created in, e.g. a
+	// project delegated to the network administrators of a multi-project setup. The groups
+	// are looked up in that project only. The credentials of the cluster must be allowed to
+	// manage the security groups of the project, which usually requires the admin role. When
+	// unset, the groups are created in the project of the credentials. It can't be changed
+	// once the cluster is created.
+	// +optional
+	ProjectID string `json:"projectID,omitempty"`
+
+	// nodePortAllowedCIDRs restricts the access to the node port services of the workers
+	// to the given address CIDRs, with a rule per CIDR. The node port services are
+	// reachable from anywhere if unset.
+	// +listType=set
+	// +optional
+	NodePortAllowedCIDRs []string `json:"nodePortAllowedCIDRs,omitempty"`
+
+	// separateAllNodesGroup reconciles the allNodesSecurityGroupRules into a separate allNodes
+	// security group, attached to all the nodes, instead of duplicating them in the control
+	// plane and worker groups. It can be set on existing clusters: the duplicated rules are
+	// kept until all the ports of the control plane and worker groups have the allNodes group,
+	// i.e. until the machines created before have been replaced. It can't be unset.
+	// +optional
+	SeparateAllNodesGroup bool `json:"separateAllNodesGroup,omitempty"`
+
+	// disableDefaultRules removes the rules permitting all egress traffic, over IPv4 and
+	// IPv6, from the control plane, worker and allNodes security groups. The rules
+	// permitting the traffic of the Kubernetes components are kept, but any other traffic,
+	// including the egress traffic of the nodes, must be permitted by the
+	// allNodesSecurityGroupRules. The bastion security group keeps its egress rules.
+	// +optional
+	DisableDefaultRules bool `json:"disableDefaultRules,omitempty"`
+
+	// disableWorkerIngressRules removes the ingress rules generated for the worker security
+	// group: the node ports, the traffic from the control plane and the other workers, and SSH
+	// from the bastion. The workers keep their egress rules, but only permit the ingress traffic
+	// of the allNodesSecurityGroupRules, and of the load balancers of the Services when
+	// allowLoadBalancerServiceTraffic is set. Notably, the NodePort Services and the kubelet API
+	// are unreachable unless permitted by allNodesSecurityGroupRules. It can't be used with
+	// sharedNodeGroup, whose group carries the ingress rules of the control plane.
+	// +optional
+	DisableWorkerIngressRules bool `json:"disableWorkerIngressRules,omitempty"`
+
+	// stateless creates the control plane, worker, node, allNodes and shadow security groups
+	// as stateless groups when true, or as stateful groups when false. Stateless groups don't
+	// track connections, which avoids conntrack becoming a bottleneck, e.g. for high-throughput
+	// NodePort traffic. It requires the stateful-security-group extension of Neutron. The
+	// return traffic of the allNodesSecurityGroupRules is permitted, but not the replies to
+	// the egress traffic permitted by the default rules. When unset, the groups are created
+	// with the default of Neutron. The statefulness of a group can't be changed once it is
+	// created: a group whose statefulness doesn't match is reported, and must be recreated.
+	// The bastion security group is always left to the default of Neutron.
+	// +optional
+	Stateless *bool `json:"stateless,omitempty"`
+
+	// retainOnDelete leaves the managed security groups in place when the cluster is
+	// deleted, e.g. when they have been associated with resources outside of the cluster.
+	// Otherwise the groups are deleted: a group in use is first detached from the ports
+	// created by the cluster, and left in place if other ports still use it.
+	// +optional
+	RetainOnDelete bool `json:"retainOnDelete,omitempty"`
+
+	// existingGroups are pre-existing security groups, e.g. created with Terraform, which
+	// are adopted as the managed security groups of their role instead of creating new
+	// groups. An adopted group is renamed to the name of the managed group of its role,
+	// tagged as adopted, and its rules are then reconciled like those of the other managed
+	// groups: the rules which are not desired are deleted. The role must be one of the
+	// managed security groups of the cluster, which must not exist yet.
+	// +listType=map
+	// +listMapKey=role
+	// +optional
+	ExistingGroups []ExistingSecurityGroup `json:"existingGroups,omitempty"`
+
+	// deleteAdoptedGroups deletes the adopted security groups with the other managed
+	// security groups when the cluster is deleted. Otherwise they are left in place.
+	// +optional
+	DeleteAdoptedGroups bool `json:"deleteAdoptedGroups,omitempty"`
+}
+
+// ExistingSecurityGroupRole is the role of a managed security group a pre-existing security group can be adopted as.
+// +kubebuilder:validation:Enum=controlplane;worker;node;allNodes;bastion
+type ExistingSecurityGroupRole string
+
+// ExistingSecurityGroup is a pre-existing security group adopted as a managed security group.
+type ExistingSecurityGroup struct {
+	// role is the role of the managed security group the group is adopted as.
+	Role ExistingSecurityGroupRole `json:"role"`
+
+	// id is the ID of the security group.
+	// +kubebuilder:validation:MinLength=1
+	ID string `json:"id"`
+}
+
+// WellKnownPortName is the name of a port of a Kubernetes component permitted by the rules
+// of the managed security groups.
+// +kubebuilder:validation:Enum=etcd;kubelet;kube-apiserver;ssh;node-ports
+type WellKnownPortName string
+
+const (
+	// WellKnownPortEtcd is the range of the client and peer ports of etcd, 2379-2380 by default.
+	WellKnownPortEtcd WellKnownPortName = "etcd"
+	// WellKnownPortKubelet is the port of the kubelet API, 10250 by default.
+	WellKnownPortKubelet WellKnownPortName = "kubelet"
+	// WellKnownPortKubeAPIServer is the port of the Kubernetes API server, 6443 by default.
+	WellKnownPortKubeAPIServer WellKnownPortName = "kube-apiserver"
+	// WellKnownPortSSH is the SSH port of the bastion and the nodes, 22 by default.
+	WellKnownPortSSH WellKnownPortName = "ssh"
+	// WellKnownPortNodePorts is the range of the node ports of the Services, 30000-32767 by default.
+	WellKnownPortNodePorts WellKnownPortName = "node-ports"
+)
+
+// WellKnownPort overrides the ports of a Kubernetes component.
+type WellKnownPort struct {
+	// name of the Kubernetes component.
+	Name WellKnownPortName `json:"name"`
+
+	// portRangeMin is the first port of the component.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	PortRangeMin int `json:"portRangeMin"`
+
+	// portRangeMax is the last port of the component. It defaults to portRangeMin.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	PortRangeMax *int `json:"portRangeMax,omitempty"`
+}
+
+// ManagedSecurityGroupsStatus defines the state of the reconciliation of the managed security groups.
+type ManagedSecurityGroupsStatus struct {
+	// lastReconciledTime is the time the managed security groups last converged to the
+	// desired state. It can be monitored to detect security groups which fail to reconcile.
+	// +optional
+	LastReconciledTime *metav1.Time `json:"lastReconciledTime,omitempty"`
+
+	// ruleProbe is the result of the last probe of a managed rule, when the controller
+	// is configured to probe that the rules are effective.
+	// +optional
+	RuleProbe *SecurityGroupRuleProbeStatus `json:"ruleProbe,omitempty"`
+}
+
+// ManagedSecurityGroupRulesStatus summarizes the rules the controller intends a managed security group to have.
+type ManagedSecurityGroupRulesStatus struct {
+	// name is the name of the security group.
+	Name string `json:"name"`
+
+	// desiredRules is the number of rules the controller intends the security group to have.
+	DesiredRules int `json:"desiredRules"`
+
+	// rulesPendingCreation are the desired rules the security group doesn't have yet, e.g. because
+	// they reference a security group which is not listable yet, or their creation failed. A rule
+	// referencing a group which doesn't exist yet has the remote group ID "pending:" followed by the
+	// suffix of that group.
+	// +optional
+	RulesPendingCreation []SecurityGroupRuleStatus `json:"rulesPendingCreation,omitempty"`
+}
+
+// SecurityGroupRuleProbeStatus is the result of a probe checking that the traffic permitted by a
+// rule of a managed security group is effective.
+type SecurityGroupRuleProbeStatus struct {
+	// securityGroupName is the name of the security group of the probed rule.
+	SecurityGroupName string `json:"securityGroupName"`
+
+	// ruleID is the ID of the probed rule.
+	RuleID string `json:"ruleID"`
+
+	// address is the address the probe connected to.
+	Address string `json:"address"`
+
+	// effective is true if the traffic permitted by the rule reached the address.
+	Effective bool `json:"effective"`
+
+	// message explains why the rule is not effective.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// probeTime is the time of the probe.
+	ProbeTime metav1.Time `json:"probeTime"`
 }
 
 func init() {
@@ -17,12 +17,17 @@ limitations under the License.
 package v1beta1
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"reflect"
+	"sort"
+	"strings"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -34,9 +39,297 @@ import (
 // log is for logging in this package.
 var _ = logf.Log.WithName("openstackcluster-resource")
 
-func (r *OpenStackCluster) SetupWebhookWithManager(mgr manager.Manager) error {
+// SecurityGroupRulePolicy is the policy the OpenStackCluster webhook applies to the allNodes security group rules.
+// +kubebuilder:object:generate=false
+type SecurityGroupRulePolicy struct {
+	// DenyOpenIngress rejects the ingress rules open to any address: rules with a remoteIPPrefix of
+	// 0.0.0.0/0 or ::/0, or without any remote.
+	DenyOpenIngress bool
+	// OpenIngressAllowedNamespaces are the namespaces whose clusters are exempted from DenyOpenIngress.
+	OpenIngressAllowedNamespaces []string
+}
+
+// validateSecurityGroupRulePolicy returns the allNodes security group rules of the cluster denied by the
+// security group rule policy.
+func (r *OpenStackCluster) validateSecurityGroupRulePolicy(policy SecurityGroupRulePolicy) field.ErrorList {
+	if !policy.DenyOpenIngress || r.Spec.ManagedSecurityGroups == nil {
+		return nil
+	}
+	for _, namespace := range policy.OpenIngressAllowedNamespaces {
+		if r.Namespace == namespace {
+			return nil
+		}
+	}
+
+	var allErrs field.ErrorList
+	rulesPath := field.NewPath("spec", "managedSecurityGroups", "allNodesSecurityGroupRules")
+	for i, rule := range r.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules {
+		if rule.Direction != "ingress" || rule.RemoteManagedGroups != nil || rule.RemoteGroupID != nil || rule.RemoteSecurityGroupFilter != nil {
+			continue
+		}
+		if rule.RemoteIPPrefix == nil {
+			allErrs = append(allErrs, field.Forbidden(rulesPath.Index(i), "ingress rules must have a remote: ingress from any address is denied by policy"))
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(*rule.RemoteIPPrefix); err == nil {
+			if ones, _ := ipNet.Mask.Size(); ones == 0 {
+				allErrs = append(allErrs, field.Forbidden(rulesPath.Index(i).Child("remoteIPPrefix"), "ingress from any address is denied by policy"))
+			}
+		}
+	}
+	return allErrs
+}
+
+// validateSecurityGroupRuleRemotes checks that each of the rules has at most one kind of remote. Neutron rejects
+// the rules with both a remote group and a remote IP prefix, which would otherwise only fail mid-reconcile.
+func validateSecurityGroupRuleRemotes(rulesPath *field.Path, rules []SecurityGroupRuleSpec) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, rule := range rules {
+		remotes := 0
+		if len(rule.RemoteManagedGroups) > 0 {
+			remotes++
+		}
+		if rule.RemoteGroupID != nil {
+			remotes++
+		}
+		if rule.RemoteIPPrefix != nil {
+			remotes++
+		}
+		if rule.RemoteSecurityGroupFilter != nil {
+			remotes++
+		}
+		if remotes > 1 {
+			allErrs = append(allErrs, field.Forbidden(rulesPath.Index(i), fmt.Sprintf("rule %q: only one of remoteGroupID, remoteIPPrefix, remoteManagedGroups and remoteSecurityGroupFilter can be set", rule.Name)))
+		}
+	}
+	return allErrs
+}
+
+// validateSecurityGroupRuleBastionRemotes checks that the managed rules only reference the bastion security group
+// while the bastion is enabled, as the group is only managed until then.
+func (r *OpenStackCluster) validateSecurityGroupRuleBastionRemotes() field.ErrorList {
+	if r.Spec.ManagedSecurityGroups == nil || (r.Spec.Bastion != nil && r.Spec.Bastion.Enabled) {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	for _, rulesField := range []struct {
+		path  *field.Path
+		rules []SecurityGroupRuleSpec
+	}{
+		{field.NewPath("spec", "managedSecurityGroups", "allNodesSecurityGroupRules"), r.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules},
+		{field.NewPath("spec", "managedSecurityGroups", "shadowRules"), r.Spec.ManagedSecurityGroups.ShadowRules},
+	} {
+		for i, rule := range rulesField.rules {
+			for j, group := range rule.RemoteManagedGroups {
+				if group == "bastion" {
+					allErrs = append(allErrs, field.Invalid(rulesField.path.Index(i).Child("remoteManagedGroups").Index(j), group, "the bastion security group cannot be referenced while the bastion is disabled"))
+				}
+			}
+		}
+	}
+	return allErrs
+}
+
+// validateSecurityGroupRuleICMPTypeCodes checks the ICMP type and code, which Neutron takes from portRangeMin and
+// portRangeMax, of the ICMP rules.
+func validateSecurityGroupRuleICMPTypeCodes(rulesPath *field.Path, rules []SecurityGroupRuleSpec) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, rule := range rules {
+		if !isICMPSecurityGroupRule(rule) {
+			continue
+		}
+		if rule.PortRangeMin != nil && (*rule.PortRangeMin < 0 || *rule.PortRangeMin > 255) {
+			allErrs = append(allErrs, field.Invalid(rulesPath.Index(i).Child("portRangeMin"), *rule.PortRangeMin, "ICMP type must be between 0 and 255"))
+		}
+		if rule.PortRangeMax != nil {
+			if *rule.PortRangeMax < 0 || *rule.PortRangeMax > 255 {
+				allErrs = append(allErrs, field.Invalid(rulesPath.Index(i).Child("portRangeMax"), *rule.PortRangeMax, "ICMP code must be between 0 and 255"))
+			}
+			if rule.PortRangeMin == nil {
+				allErrs = append(allErrs, field.Required(rulesPath.Index(i).Child("portRangeMin"), "ICMP type is required when the ICMP code is set"))
+			}
+		}
+	}
+	return allErrs
+}
+
+// isICMPSecurityGroupRule returns whether the rule is an ICMP rule, whose portRangeMin and portRangeMax are the ICMP
+// type and code rather than a port range.
+func isICMPSecurityGroupRule(rule SecurityGroupRuleSpec) bool {
+	if rule.Protocol == nil {
+		return false
+	}
+	switch strings.ToLower(*rule.Protocol) {
+	case "icmp", "icmpv6", "ipv6-icmp":
+		return true
+	}
+	return false
+}
+
+// validateSecurityGroupRulePortRanges checks that the port ranges of the rules are not inverted, which Neutron
+// rejects mid-reconcile.
+func validateSecurityGroupRulePortRanges(rulesPath *field.Path, rules []SecurityGroupRuleSpec) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, rule := range rules {
+		if isICMPSecurityGroupRule(rule) || rule.PortRangeMin == nil || rule.PortRangeMax == nil {
+			continue
+		}
+		if *rule.PortRangeMax < *rule.PortRangeMin {
+			allErrs = append(allErrs, field.Invalid(rulesPath.Index(i).Child("portRangeMax"), *rule.PortRangeMax, "must not be lower than portRangeMin"))
+		}
+	}
+	return allErrs
+}
+
+// securityGroupRuleOverlapKey returns the direction, ether type, protocol and remote of the rule, which rules must
+// share for their port ranges to overlap.
+func securityGroupRuleOverlapKey(rule SecurityGroupRuleSpec) string {
+	managedGroups := make([]string, 0, len(rule.RemoteManagedGroups))
+	for _, group := range rule.RemoteManagedGroups {
+		managedGroups = append(managedGroups, group.String())
+	}
+	sort.Strings(managedGroups)
+	var remoteSecurityGroupFilter string
+	if rule.RemoteSecurityGroupFilter != nil {
+		remoteSecurityGroupFilter = fmt.Sprintf("%+v", *rule.RemoteSecurityGroupFilter)
+	}
+	return fmt.Sprintf("%s/%s/%s/%s/%s/%s/%s",
+		rule.Direction,
+		strings.ToLower(pointer.StringDeref(rule.EtherType, "")),
+		strings.ToLower(pointer.StringDeref(rule.Protocol, "")),
+		pointer.StringDeref(rule.RemoteGroupID, ""),
+		pointer.StringDeref(rule.RemoteIPPrefix, ""),
+		strings.Join(managedGroups, ","),
+		remoteSecurityGroupFilter)
+}
+
+// overlappingPortRangesWarnings warns when the port ranges of allNodes rules with the same direction, protocol and
+// remote overlap: the rules are redundant. A rule without portRangeMin permits all the ports.
+func (r *OpenStackCluster) overlappingPortRangesWarnings() admission.Warnings {
+	if r.Spec.ManagedSecurityGroups == nil {
+		return nil
+	}
+
+	portRange := func(rule SecurityGroupRuleSpec) (int, int) {
+		if rule.PortRangeMin == nil {
+			return 1, 65535
+		}
+		return *rule.PortRangeMin, pointer.IntDeref(rule.PortRangeMax, *rule.PortRangeMin)
+	}
+
+	var warnings admission.Warnings
+	rules := r.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules
+	for i := range rules {
+		// The rules without a protocol match any protocol, and have no port range.
+		if rules[i].Protocol == nil || isICMPSecurityGroupRule(rules[i]) {
+			continue
+		}
+		minI, maxI := portRange(rules[i])
+		for j := 0; j < i; j++ {
+			if securityGroupRuleOverlapKey(rules[i]) != securityGroupRuleOverlapKey(rules[j]) {
+				continue
+			}
+			minJ, maxJ := portRange(rules[j])
+			if minI <= maxJ && minJ <= maxI {
+				warnings = append(warnings, fmt.Sprintf("spec.managedSecurityGroups.allNodesSecurityGroupRules[%d]: the port range of rule %q overlaps the port range of rule %q, which has the same direction, protocol and remote: the rules are redundant", i, rules[i].Name, rules[j].Name))
+				break
+			}
+		}
+	}
+	return warnings
+}
+
+// defaultRulesWarnings warns when the default rules of the managed security groups are disabled, but no
+// allNodes rule permits egress traffic: the nodes can't then reach anything but the other nodes.
+func (r *OpenStackCluster) defaultRulesWarnings() admission.Warnings {
+	if r.Spec.ManagedSecurityGroups == nil || !r.Spec.ManagedSecurityGroups.DisableDefaultRules {
+		return nil
+	}
+	for _, rule := range r.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules {
+		if rule.Direction == "egress" {
+			return nil
+		}
+	}
+	return admission.Warnings{"spec.managedSecurityGroups.disableDefaultRules is set, but no rule of spec.managedSecurityGroups.allNodesSecurityGroupRules permits egress traffic: the nodes won't be able to reach the API server load balancer, the image registries or the OpenStack APIs"}
+}
+
+// validateWorkerIngressRules checks that the worker ingress rules are only disabled with separate control plane and
+// worker groups: the shared node group carries the ingress rules of the control plane.
+func validateWorkerIngressRules(managedSecurityGroups *ManagedSecurityGroups) field.ErrorList {
+	if !managedSecurityGroups.DisableWorkerIngressRules || !managedSecurityGroups.SharedNodeGroup {
+		return nil
+	}
+	return field.ErrorList{field.Forbidden(field.NewPath("spec", "managedSecurityGroups", "disableWorkerIngressRules"), "cannot be used with sharedNodeGroup")}
+}
+
+// workerIngressRulesWarnings warns when the ingress rules of the workers are disabled, but no allNodes rule permits
+// ingress traffic: the workers can't then be reached, even by the control plane.
+func (r *OpenStackCluster) workerIngressRulesWarnings() admission.Warnings {
+	if r.Spec.ManagedSecurityGroups == nil || !r.Spec.ManagedSecurityGroups.DisableWorkerIngressRules {
+		return nil
+	}
+	for _, rule := range r.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules {
+		if rule.Direction == "ingress" {
+			return nil
+		}
+	}
+	return admission.Warnings{"spec.managedSecurityGroups.disableWorkerIngressRules is set, but no rule of spec.managedSecurityGroups.allNodesSecurityGroupRules permits ingress traffic: the kubelet API and the NodePort Services of the workers won't be reachable"}
+}
+
+// validateExistingSecurityGroups checks that the existing security groups are adopted as managed security groups
+// of the cluster, and that a group is only adopted once.
+func (r *OpenStackCluster) validateExistingSecurityGroups() field.ErrorList {
+	var allErrs field.ErrorList
+	groupsPath := field.NewPath("spec", "managedSecurityGroups", "existingGroups")
+	managedSecurityGroups := r.Spec.ManagedSecurityGroups
+	ids := make(map[string]bool)
+	for i, group := range managedSecurityGroups.ExistingGroups {
+		var managed bool
+		switch group.Role {
+		case "controlplane", "worker":
+			managed = !managedSecurityGroups.SharedNodeGroup
+		case "node":
+			managed = managedSecurityGroups.SharedNodeGroup
+		case "allNodes":
+			managed = managedSecurityGroups.SeparateAllNodesGroup
+		case "bastion":
+			managed = r.Spec.Bastion != nil && r.Spec.Bastion.Enabled
+		}
+		if !managed {
+			allErrs = append(allErrs, field.Invalid(groupsPath.Index(i).Child("role"), group.Role, "is not a managed security group of the cluster"))
+		}
+		if ids[group.ID] {
+			allErrs = append(allErrs, field.Duplicate(groupsPath.Index(i).Child("id"), group.ID))
+		}
+		ids[group.ID] = true
+	}
+	return allErrs
+}
+
+// validateWellKnownPorts checks that the overridden port ranges are not inverted.
+func validateWellKnownPorts(portsPath *field.Path, ports []WellKnownPort) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, port := range ports {
+		if port.PortRangeMax != nil && *port.PortRangeMax < port.PortRangeMin {
+			allErrs = append(allErrs, field.Invalid(portsPath.Index(i).Child("portRangeMax"), *port.PortRangeMax, "must not be lower than portRangeMin"))
+		}
+	}
+	return allErrs
+}
+
+// OpenStackClusterWebhook defaults and validates the OpenStackClusters, applying the security group rule policy.
+// +kubebuilder:object:generate=false
+type OpenStackClusterWebhook struct {
+	// SecurityGroupRulePolicy is applied to the allNodes security group rules.
+	SecurityGroupRulePolicy SecurityGroupRulePolicy
+}
+
+func (w *OpenStackClusterWebhook) SetupWebhookWithManager(mgr manager.Manager) error {
 	return builder.WebhookManagedBy(mgr).
-		For(r).
+		For(&OpenStackCluster{}).
+		WithDefaulter(w).
+		WithValidator(w).
 		Complete()
 }
 
@@ -44,43 +337,148 @@ func (r *OpenStackCluster) SetupWebhookWithManager(mgr manager.Manager) error {
 // +kubebuilder:webhook:verbs=create;update,path=/mutate-infrastructure-cluster-x-k8s-io-v1beta1-openstackcluster,mutating=true,failurePolicy=fail,matchPolicy=Equivalent,groups=infrastructure.cluster.x-k8s.io,resources=openstackclusters,versions=v1beta1,name=default.openstackcluster.infrastructure.cluster.x-k8s.io,sideEffects=None,admissionReviewVersions=v1beta1
 
 var (
-	_ webhook.Defaulter = &OpenStackCluster{}
-	_ webhook.Validator = &OpenStackCluster{}
+	_ webhook.Defaulter       = &OpenStackCluster{}
+	_ webhook.Validator       = &OpenStackCluster{}
+	_ webhook.CustomDefaulter = &OpenStackClusterWebhook{}
+	_ webhook.CustomValidator = &OpenStackClusterWebhook{}
 )
 
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the type.
+func (w *OpenStackClusterWebhook) Default(_ context.Context, obj runtime.Object) error {
+	openStackCluster, ok := obj.(*OpenStackCluster)
+	if !ok {
+		return apierrors.NewBadRequest(fmt.Sprintf("expected an OpenStackCluster but got a %T", obj))
+	}
+	openStackCluster.Default()
+	return nil
+}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type.
+func (w *OpenStackClusterWebhook) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	openStackCluster, ok := obj.(*OpenStackCluster)
+	if !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("expected an OpenStackCluster but got a %T", obj))
+	}
+	return openStackCluster.validateCreate(w.SecurityGroupRulePolicy)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type.
+func (w *OpenStackClusterWebhook) ValidateUpdate(_ context.Context, oldRaw runtime.Object, newRaw runtime.Object) (admission.Warnings, error) {
+	openStackCluster, ok := newRaw.(*OpenStackCluster)
+	if !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("expected an OpenStackCluster but got a %T", newRaw))
+	}
+	return openStackCluster.validateUpdate(oldRaw, w.SecurityGroupRulePolicy)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type.
+func (w *OpenStackClusterWebhook) ValidateDelete(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	openStackCluster, ok := obj.(*OpenStackCluster)
+	if !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("expected an OpenStackCluster but got a %T", obj))
+	}
+	return openStackCluster.ValidateDelete()
+}
+
 // Default satisfies the defaulting webhook interface.
 func (r *OpenStackCluster) Default() {
 }
 
+// validateBastionPorts validates the ports of the bastion, which can be changed as long as the bastion is disabled.
+func (r *OpenStackCluster) validateBastionPorts() field.ErrorList {
+	if r.Spec.Bastion == nil {
+		return nil
+	}
+	return validatePortsExcludeManagedSecurityGroups(field.NewPath("spec", "bastion", "instance", "ports"), r.Spec.Bastion.Instance.Ports)
+}
+
+// validateBastionSSHAllowedCIDRs checks that SSH to the bastion is restricted to valid CIDRs.
+func (r *OpenStackCluster) validateBastionSSHAllowedCIDRs() field.ErrorList {
+	if r.Spec.Bastion == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	for i, cidr := range r.Spec.Bastion.SSHAllowedCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "bastion", "sshAllowedCIDRs").Index(i), cidr, "must be a CIDR"))
+		}
+	}
+	return allErrs
+}
+
+// validateNodePortAllowedCIDRs checks that the node port services are restricted to valid CIDRs.
+func (r *OpenStackCluster) validateNodePortAllowedCIDRs() field.ErrorList {
+	if r.Spec.ManagedSecurityGroups == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	for i, cidr := range r.Spec.ManagedSecurityGroups.NodePortAllowedCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "managedSecurityGroups", "nodePortAllowedCIDRs").Index(i), cidr, "must be a CIDR"))
+		}
+	}
+	return allErrs
+}
+
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
 func (r *OpenStackCluster) ValidateCreate() (admission.Warnings, error) {
+	return r.validateCreate(SecurityGroupRulePolicy{})
+}
+
+// validateCreate validates the creation of the cluster, applying the security group rule policy.
+func (r *OpenStackCluster) validateCreate(policy SecurityGroupRulePolicy) (admission.Warnings, error) {
 	var allErrs field.ErrorList
 
 	if r.Spec.ManagedSecurityGroups != nil {
-		for _, rule := range r.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules {
-			if rule.RemoteManagedGroups != nil && (rule.RemoteGroupID != nil || rule.RemoteIPPrefix != nil) {
-				allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "managedSecurityGroups", "allNodesSecurityGroupRules"), "remoteManagedGroups cannot be used with remoteGroupID or remoteIPPrefix"))
-			}
-			if rule.RemoteGroupID != nil && (rule.RemoteManagedGroups != nil || rule.RemoteIPPrefix != nil) {
-				allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "managedSecurityGroups", "allNodesSecurityGroupRules"), "remoteGroupID cannot be used with remoteManagedGroups or remoteIPPrefix"))
-			}
-			if rule.RemoteIPPrefix != nil && (rule.RemoteManagedGroups != nil || rule.RemoteGroupID != nil) {
-				allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "managedSecurityGroups", "allNodesSecurityGroupRules"), "remoteIPPrefix cannot be used with remoteManagedGroups or remoteGroupID"))
-			}
-		}
+		allErrs = append(allErrs, validateSecurityGroupRuleRemotes(field.NewPath("spec", "managedSecurityGroups", "allNodesSecurityGroupRules"), r.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules)...)
+		allErrs = append(allErrs, validateSecurityGroupRuleRemotes(field.NewPath("spec", "managedSecurityGroups", "shadowRules"), r.Spec.ManagedSecurityGroups.ShadowRules)...)
+		allErrs = append(allErrs, validateSecurityGroupRuleICMPTypeCodes(field.NewPath("spec", "managedSecurityGroups", "allNodesSecurityGroupRules"), r.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules)...)
+		allErrs = append(allErrs, validateSecurityGroupRulePortRanges(field.NewPath("spec", "managedSecurityGroups", "allNodesSecurityGroupRules"), r.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules)...)
+		allErrs = append(allErrs, validateSecurityGroupRuleICMPTypeCodes(field.NewPath("spec", "managedSecurityGroups", "shadowRules"), r.Spec.ManagedSecurityGroups.ShadowRules)...)
+		allErrs = append(allErrs, validateSecurityGroupRulePortRanges(field.NewPath("spec", "managedSecurityGroups", "shadowRules"), r.Spec.ManagedSecurityGroups.ShadowRules)...)
+		allErrs = append(allErrs, validateWellKnownPorts(field.NewPath("spec", "managedSecurityGroups", "wellKnownPorts"), r.Spec.ManagedSecurityGroups.WellKnownPorts)...)
+		allErrs = append(allErrs, validateWorkerIngressRules(r.Spec.ManagedSecurityGroups)...)
+		allErrs = append(allErrs, r.validateExistingSecurityGroups()...)
 	}
 
-	return aggregateObjErrors(r.GroupVersionKind().GroupKind(), r.Name, allErrs)
+	allErrs = append(allErrs, r.validateSecurityGroupRulePolicy(policy)...)
+	allErrs = append(allErrs, r.validateSecurityGroupRuleBastionRemotes()...)
+	allErrs = append(allErrs, r.validateBastionPorts()...)
+	allErrs = append(allErrs, r.validateBastionSSHAllowedCIDRs()...)
+	allErrs = append(allErrs, r.validateNodePortAllowedCIDRs()...)
+
+	warnings := append(r.defaultRulesWarnings(), r.workerIngressRulesWarnings()...)
+	warnings = append(warnings, r.overlappingPortRangesWarnings()...)
+	_, err := aggregateObjErrors(r.GroupVersionKind().GroupKind(), r.Name, allErrs)
+	return warnings, err
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
 func (r *OpenStackCluster) ValidateUpdate(oldRaw runtime.Object) (admission.Warnings, error) {
+	return r.validateUpdate(oldRaw, SecurityGroupRulePolicy{})
+}
+
+// validateUpdate validates the update of the cluster, applying the security group rule policy.
+func (r *OpenStackCluster) validateUpdate(oldRaw runtime.Object, policy SecurityGroupRulePolicy) (admission.Warnings, error) {
 	var allErrs field.ErrorList
 	old, ok := oldRaw.(*OpenStackCluster)
 	if !ok {
 		return nil, apierrors.NewBadRequest(fmt.Sprintf("expected an OpenStackCluster but got a %T", oldRaw))
 	}
 
+	// The allNodes rules can be changed, but must comply with the policy.
+	allErrs = append(allErrs, r.validateSecurityGroupRulePolicy(policy)...)
+	allErrs = append(allErrs, r.validateSecurityGroupRuleBastionRemotes()...)
+	allErrs = append(allErrs, r.validateBastionPorts()...)
+	allErrs = append(allErrs, r.validateBastionSSHAllowedCIDRs()...)
+	allErrs = append(allErrs, r.validateNodePortAllowedCIDRs()...)
+	if r.Spec.ManagedSecurityGroups != nil {
+		allErrs = append(allErrs, r.validateExistingSecurityGroups()...)
+	}
+	// The rules are cleared below to compare the rest of the spec, so the warnings are computed first.
+	warnings := append(r.defaultRulesWarnings(), r.workerIngressRulesWarnings()...)
+	warnings = append(warnings, r.overlappingPortRangesWarnings()...)
+
 	// Allow changes to Spec.IdentityRef
 	old.Spec.IdentityRef = OpenStackIdentityReference{}
 	r.Spec.IdentityRef = OpenStackIdentityReference{}
@@ -112,14 +510,68 @@ func (r *OpenStackCluster) ValidateUpdate(oldRaw runtime.Object) (admission.Warn
 	old.Spec.Bastion = &Bastion{}
 	r.Spec.Bastion = &Bastion{}
 
+	// Allow to remove the managed security groups spec. What happens to the groups is up to the controller.
+	if r.Spec.ManagedSecurityGroups == nil {
+		old.Spec.ManagedSecurityGroups = nil
+	}
+
 	// Allow changes to the managed allNodesSecurityGroupRules.
-	if r.Spec.ManagedSecurityGroups != nil {
+	if r.Spec.ManagedSecurityGroups != nil && old.Spec.ManagedSecurityGroups != nil {
+		allErrs = append(allErrs, validateSecurityGroupRuleRemotes(field.NewPath("spec", "managedSecurityGroups", "allNodesSecurityGroupRules"), r.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules)...)
+		allErrs = append(allErrs, validateSecurityGroupRuleRemotes(field.NewPath("spec", "managedSecurityGroups", "shadowRules"), r.Spec.ManagedSecurityGroups.ShadowRules)...)
+		allErrs = append(allErrs, validateSecurityGroupRuleICMPTypeCodes(field.NewPath("spec", "managedSecurityGroups", "allNodesSecurityGroupRules"), r.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules)...)
+		allErrs = append(allErrs, validateSecurityGroupRulePortRanges(field.NewPath("spec", "managedSecurityGroups", "allNodesSecurityGroupRules"), r.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules)...)
+		allErrs = append(allErrs, validateSecurityGroupRuleICMPTypeCodes(field.NewPath("spec", "managedSecurityGroups", "shadowRules"), r.Spec.ManagedSecurityGroups.ShadowRules)...)
+		allErrs = append(allErrs, validateSecurityGroupRulePortRanges(field.NewPath("spec", "managedSecurityGroups", "shadowRules"), r.Spec.ManagedSecurityGroups.ShadowRules)...)
 		old.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules = []SecurityGroupRuleSpec{}
 		r.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules = []SecurityGroupRuleSpec{}
 
+		// Allow changes to the shadow rules, which are meant to be iterated on.
+		old.Spec.ManagedSecurityGroups.ShadowRules = []SecurityGroupRuleSpec{}
+		r.Spec.ManagedSecurityGroups.ShadowRules = []SecurityGroupRuleSpec{}
+
 		// Allow change to the allowAllInClusterTraffic.
 		old.Spec.ManagedSecurityGroups.AllowAllInClusterTraffic = false
 		r.Spec.ManagedSecurityGroups.AllowAllInClusterTraffic = false
+
+		// Allow change to the disableDefaultRules.
+		old.Spec.ManagedSecurityGroups.DisableDefaultRules = false
+		r.Spec.ManagedSecurityGroups.DisableDefaultRules = false
+
+		// Allow change to the disableWorkerIngressRules, as long as the worker group is separate.
+		allErrs = append(allErrs, validateWorkerIngressRules(r.Spec.ManagedSecurityGroups)...)
+		old.Spec.ManagedSecurityGroups.DisableWorkerIngressRules = false
+		r.Spec.ManagedSecurityGroups.DisableWorkerIngressRules = false
+
+		// Allow change to the statefulness. The existing groups keep theirs until they are recreated, which the
+		// controller reports.
+		old.Spec.ManagedSecurityGroups.Stateless = nil
+		r.Spec.ManagedSecurityGroups.Stateless = nil
+
+		// Allow change to the retainOnDelete, which only applies once the cluster is deleted.
+		old.Spec.ManagedSecurityGroups.RetainOnDelete = false
+		r.Spec.ManagedSecurityGroups.RetainOnDelete = false
+
+		// Allow adopting existing groups, e.g. to migrate the groups of an existing cluster, and changing what
+		// happens to them once the cluster is deleted. They are validated above, with the bastion spec.
+		old.Spec.ManagedSecurityGroups.ExistingGroups = nil
+		r.Spec.ManagedSecurityGroups.ExistingGroups = nil
+		old.Spec.ManagedSecurityGroups.DeleteAdoptedGroups = false
+		r.Spec.ManagedSecurityGroups.DeleteAdoptedGroups = false
+
+		// Allow changes to the well-known ports, e.g. after reconfiguring the kubelet.
+		allErrs = append(allErrs, validateWellKnownPorts(field.NewPath("spec", "managedSecurityGroups", "wellKnownPorts"), r.Spec.ManagedSecurityGroups.WellKnownPorts)...)
+		old.Spec.ManagedSecurityGroups.WellKnownPorts = nil
+		r.Spec.ManagedSecurityGroups.WellKnownPorts = nil
+
+		// Allow changes to the sources of the node port services.
+		old.Spec.ManagedSecurityGroups.NodePortAllowedCIDRs = nil
+		r.Spec.ManagedSecurityGroups.NodePortAllowedCIDRs = nil
+
+		// Allow opting in to the separate allNodes group, but not out of it once it is attached to the machines.
+		if !old.Spec.ManagedSecurityGroups.SeparateAllNodesGroup {
+			r.Spec.ManagedSecurityGroups.SeparateAllNodesGroup = false
+		}
 	}
 
 	// Allow changes on AllowedCIDRs
@@ -147,7 +599,8 @@ func (r *OpenStackCluster) ValidateUpdate(oldRaw runtime.Object) (admission.Warn
 		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec"), "cannot be modified"))
 	}
 
-	return aggregateObjErrors(r.GroupVersionKind().GroupKind(), r.Name, allErrs)
+	_, err := aggregateObjErrors(r.GroupVersionKind().GroupKind(), r.Name, allErrs)
+	return warnings, err
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type.
@@ -17,10 +17,13 @@ limitations under the License.
 package v1beta1
 
 import (
+	"context"
 	"testing"
 
 	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 func TestOpenStackCluster_ValidateUpdate(t *testing.T) {
@@ -72,6 +75,27 @@ func TestOpenStackCluster_ValidateUpdate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Changing OpenStackCluster.Spec.IdentityRef.Region is allowed",
+			oldTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+				},
+			},
+			newTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+						Region:    "RegionTwo",
+					},
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name: "Changing OpenStackCluster.Spec.Bastion is allowed",
 			oldTemplate: &OpenStackCluster{
@@ -156,20 +180,14 @@ func TestOpenStackCluster_ValidateUpdate(t *testing.T) {
 			wantErr: false,
 		},
 		{
-			name: "Changing CIDRs on the OpenStackCluster.Spec.APIServerLoadBalancer.AllowedCIDRs is allowed",
+			name: "Adding OpenStackCluster.Spec.ManagedSecurityGroups.ShadowRules is allowed",
 			oldTemplate: &OpenStackCluster{
 				Spec: OpenStackClusterSpec{
 					IdentityRef: OpenStackIdentityReference{
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					APIServerLoadBalancer: APIServerLoadBalancer{
-						Enabled: true,
-						AllowedCIDRs: []string{
-							"0.0.0.0/0",
-							"192.168.10.0/24",
-						},
-					},
+					ManagedSecurityGroups: &ManagedSecurityGroups{},
 				},
 			},
 			newTemplate: &OpenStackCluster{
@@ -178,12 +196,16 @@ func TestOpenStackCluster_ValidateUpdate(t *testing.T) {
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					APIServerLoadBalancer: APIServerLoadBalancer{
-						Enabled: true,
-						AllowedCIDRs: []string{
-							"0.0.0.0/0",
-							"192.168.10.0/24",
-							"10.6.0.0/16",
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						ShadowRules: []SecurityGroupRuleSpec{
+							{
+								Name:                "foobar",
+								Description:         pointer.String("foobar"),
+								PortRangeMin:        pointer.Int(80),
+								PortRangeMax:        pointer.Int(80),
+								Protocol:            pointer.String("tcp"),
+								RemoteManagedGroups: []ManagedSecurityGroupName{"worker"},
+							},
 						},
 					},
 				},
@@ -191,13 +213,14 @@ func TestOpenStackCluster_ValidateUpdate(t *testing.T) {
 			wantErr: false,
 		},
 		{
-			name: "Adding OpenStackCluster.Spec.ControlPlaneAvailabilityZones is allowed",
+			name: "Removing OpenStackCluster.Spec.ManagedSecurityGroups is allowed",
 			oldTemplate: &OpenStackCluster{
 				Spec: OpenStackClusterSpec{
 					IdentityRef: OpenStackIdentityReference{
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
+					ManagedSecurityGroups: &ManagedSecurityGroups{AllowAllInClusterTraffic: true},
 				},
 			},
 			newTemplate: &OpenStackCluster{
@@ -206,26 +229,40 @@ func TestOpenStackCluster_ValidateUpdate(t *testing.T) {
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					ControlPlaneAvailabilityZones: []string{
-						"alice",
-						"bob",
-					},
 				},
 			},
 			wantErr: false,
 		},
 		{
-			name: "Modifying OpenStackCluster.Spec.ControlPlaneAvailabilityZones is allowed",
+			name: "Adding OpenStackCluster.Spec.ManagedSecurityGroups is not allowed",
 			oldTemplate: &OpenStackCluster{
 				Spec: OpenStackClusterSpec{
 					IdentityRef: OpenStackIdentityReference{
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					ControlPlaneAvailabilityZones: []string{
-						"alice",
-						"bob",
+				},
+			},
+			newTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					ManagedSecurityGroups: &ManagedSecurityGroups{},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Changing OpenStackCluster.Spec.ManagedSecurityGroups.WellKnownPorts is allowed",
+			oldTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
 					},
+					ManagedSecurityGroups: &ManagedSecurityGroups{},
 				},
 			},
 			newTemplate: &OpenStackCluster{
@@ -234,27 +271,22 @@ func TestOpenStackCluster_ValidateUpdate(t *testing.T) {
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					ControlPlaneAvailabilityZones: []string{
-						"alice",
-						"bob",
-						"eve",
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						WellKnownPorts: []WellKnownPort{{Name: WellKnownPortKubelet, PortRangeMin: 10255}},
 					},
 				},
 			},
 			wantErr: false,
 		},
 		{
-			name: "Removing OpenStackCluster.Spec.ControlPlaneAvailabilityZones is allowed",
+			name: "Inverted OpenStackCluster.Spec.ManagedSecurityGroups.WellKnownPorts range is not allowed",
 			oldTemplate: &OpenStackCluster{
 				Spec: OpenStackClusterSpec{
 					IdentityRef: OpenStackIdentityReference{
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					ControlPlaneAvailabilityZones: []string{
-						"alice",
-						"bob",
-					},
+					ManagedSecurityGroups: &ManagedSecurityGroups{},
 				},
 			},
 			newTemplate: &OpenStackCluster{
@@ -263,18 +295,22 @@ func TestOpenStackCluster_ValidateUpdate(t *testing.T) {
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						WellKnownPorts: []WellKnownPort{{Name: WellKnownPortNodePorts, PortRangeMin: 32767, PortRangeMax: pointer.Int(30000)}},
+					},
 				},
 			},
-			wantErr: false,
+			wantErr: true,
 		},
 		{
-			name: "Modifying OpenstackCluster.Spec.ControlPlaneOmitAvailabilityZone is allowed",
+			name: "Changing OpenStackCluster.Spec.ManagedSecurityGroups.SharedNodeGroup is not allowed",
 			oldTemplate: &OpenStackCluster{
 				Spec: OpenStackClusterSpec{
 					IdentityRef: OpenStackIdentityReference{
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
+					ManagedSecurityGroups: &ManagedSecurityGroups{},
 				},
 			},
 			newTemplate: &OpenStackCluster{
@@ -283,20 +319,24 @@ func TestOpenStackCluster_ValidateUpdate(t *testing.T) {
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					ControlPlaneOmitAvailabilityZone: true,
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						SharedNodeGroup: true,
+					},
 				},
 			},
-			wantErr: false,
+			wantErr: true,
 		},
 		{
-			name: "Changing OpenStackCluster.Spec.APIServerFixedIP is allowed when API Server Floating IP is disabled",
+			name: "Setting OpenStackCluster.Spec.ManagedSecurityGroups.SeparateAllNodesGroup is allowed",
 			oldTemplate: &OpenStackCluster{
 				Spec: OpenStackClusterSpec{
 					IdentityRef: OpenStackIdentityReference{
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					DisableAPIServerFloatingIP: true,
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						SeparateAllNodesGroup: false,
+					},
 				},
 			},
 			newTemplate: &OpenStackCluster{
@@ -305,21 +345,24 @@ func TestOpenStackCluster_ValidateUpdate(t *testing.T) {
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					DisableAPIServerFloatingIP: true,
-					APIServerFixedIP:           "20.1.56.1",
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						SeparateAllNodesGroup: true,
+					},
 				},
 			},
 			wantErr: false,
 		},
 		{
-			name: "Changing OpenStackCluster.Spec.APIServerFixedIP is not allowed",
+			name: "Unsetting OpenStackCluster.Spec.ManagedSecurityGroups.SeparateAllNodesGroup is not allowed",
 			oldTemplate: &OpenStackCluster{
 				Spec: OpenStackClusterSpec{
 					IdentityRef: OpenStackIdentityReference{
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					DisableAPIServerFloatingIP: false,
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						SeparateAllNodesGroup: true,
+					},
 				},
 			},
 			newTemplate: &OpenStackCluster{
@@ -328,41 +371,56 @@ func TestOpenStackCluster_ValidateUpdate(t *testing.T) {
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					DisableAPIServerFloatingIP: false,
-					APIServerFixedIP:           "20.1.56.1",
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						SeparateAllNodesGroup: false,
+					},
 				},
 			},
 			wantErr: true,
 		},
-
 		{
-			name: "Changing OpenStackCluster.Spec.APIServerPort is allowed when API Server Floating IP is disabled",
+			name: "Changing OpenStackCluster.Spec.ManagedSecurityGroups.DisableDefaultRules is allowed",
 			oldTemplate: &OpenStackCluster{
 				Spec: OpenStackClusterSpec{
 					IdentityRef: OpenStackIdentityReference{
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					DisableAPIServerFloatingIP: true,
+					ManagedSecurityGroups: &ManagedSecurityGroups{},
 				},
 			},
 			newTemplate: &OpenStackCluster{
 				Spec: OpenStackClusterSpec{
-					DisableAPIServerFloatingIP: true,
-					APIServerPort:              8443,
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						DisableDefaultRules: true,
+						AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{
+							{
+								Name:           "https",
+								Direction:      "egress",
+								Protocol:       pointer.String("tcp"),
+								PortRangeMin:   pointer.Int(443),
+								PortRangeMax:   pointer.Int(443),
+								RemoteIPPrefix: pointer.String("0.0.0.0/0"),
+							},
+						},
+					},
 				},
 			},
 			wantErr: false,
 		},
 		{
-			name: "Changing OpenStackCluster.Spec.APIServerPort is not allowed",
+			name: "Setting both RemoteGroupID and RemoteIPPrefix on OpenStackCluster.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules is not allowed",
 			oldTemplate: &OpenStackCluster{
 				Spec: OpenStackClusterSpec{
 					IdentityRef: OpenStackIdentityReference{
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					DisableAPIServerFloatingIP: false,
+					ManagedSecurityGroups: &ManagedSecurityGroups{},
 				},
 			},
 			newTemplate: &OpenStackCluster{
@@ -371,26 +429,29 @@ func TestOpenStackCluster_ValidateUpdate(t *testing.T) {
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					DisableAPIServerFloatingIP: false,
-					APIServerPort:              8443,
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{
+							{
+								Name:           "foobar",
+								Direction:      "ingress",
+								RemoteGroupID:  pointer.String("foobar"),
+								RemoteIPPrefix: pointer.String("10.0.0.0/24"),
+							},
+						},
+					},
 				},
 			},
 			wantErr: true,
 		},
 		{
-			name: "Changing OpenStackCluster.Spec.APIServerFloatingIP is allowed when it matches the current api server loadbalancer IP",
+			name: "Changing OpenStackCluster.Spec.ManagedSecurityGroups.RetainOnDelete is allowed",
 			oldTemplate: &OpenStackCluster{
 				Spec: OpenStackClusterSpec{
 					IdentityRef: OpenStackIdentityReference{
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					APIServerFloatingIP: "",
-				},
-				Status: OpenStackClusterStatus{
-					APIServerLoadBalancer: &LoadBalancer{
-						IP: "1.2.3.4",
-					},
+					ManagedSecurityGroups: &ManagedSecurityGroups{},
 				},
 			},
 			newTemplate: &OpenStackCluster{
@@ -399,30 +460,22 @@ func TestOpenStackCluster_ValidateUpdate(t *testing.T) {
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					APIServerFloatingIP: "1.2.3.4",
-				},
-				Status: OpenStackClusterStatus{
-					APIServerLoadBalancer: &LoadBalancer{
-						IP: "1.2.3.4",
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						RetainOnDelete: true,
 					},
 				},
 			},
 			wantErr: false,
 		},
 		{
-			name: "Changing OpenStackCluster.Spec.APIServerFloatingIP is not allowed when it doesn't matches the current api server loadbalancer IP",
+			name: "Changing OpenStackCluster.Spec.ManagedSecurityGroups.Stateless is allowed",
 			oldTemplate: &OpenStackCluster{
 				Spec: OpenStackClusterSpec{
 					IdentityRef: OpenStackIdentityReference{
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					APIServerFloatingIP: "",
-				},
-				Status: OpenStackClusterStatus{
-					APIServerLoadBalancer: &LoadBalancer{
-						IP: "1.2.3.4",
-					},
+					ManagedSecurityGroups: &ManagedSecurityGroups{},
 				},
 			},
 			newTemplate: &OpenStackCluster{
@@ -431,29 +484,26 @@ func TestOpenStackCluster_ValidateUpdate(t *testing.T) {
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					APIServerFloatingIP: "5.6.7.8",
-				},
-				Status: OpenStackClusterStatus{
-					APIServerLoadBalancer: &LoadBalancer{
-						IP: "1.2.3.4",
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						Stateless: pointer.Bool(true),
 					},
 				},
 			},
-			wantErr: true,
+			wantErr: false,
 		},
 		{
-			name: "Removing OpenStackCluster.Spec.Bastion when it is enabled is not allowed",
+			name: "Changing CIDRs on the OpenStackCluster.Spec.APIServerLoadBalancer.AllowedCIDRs is allowed",
 			oldTemplate: &OpenStackCluster{
 				Spec: OpenStackClusterSpec{
 					IdentityRef: OpenStackIdentityReference{
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					Bastion: &Bastion{
+					APIServerLoadBalancer: APIServerLoadBalancer{
 						Enabled: true,
-						Instance: OpenStackMachineSpec{
-							Flavor: "m1.small",
-							Image:  ImageFilter{Name: "ubuntu"},
+						AllowedCIDRs: []string{
+							"0.0.0.0/0",
+							"192.168.10.0/24",
 						},
 					},
 				},
@@ -464,25 +514,26 @@ func TestOpenStackCluster_ValidateUpdate(t *testing.T) {
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
+					APIServerLoadBalancer: APIServerLoadBalancer{
+						Enabled: true,
+						AllowedCIDRs: []string{
+							"0.0.0.0/0",
+							"192.168.10.0/24",
+							"10.6.0.0/16",
+						},
+					},
 				},
 			},
-			wantErr: true,
+			wantErr: false,
 		},
 		{
-			name: "Removing OpenStackCluster.Spec.Bastion when it is disabled is allowed",
+			name: "Adding OpenStackCluster.Spec.ControlPlaneAvailabilityZones is allowed",
 			oldTemplate: &OpenStackCluster{
 				Spec: OpenStackClusterSpec{
 					IdentityRef: OpenStackIdentityReference{
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					Bastion: &Bastion{
-						Enabled: false,
-						Instance: OpenStackMachineSpec{
-							Flavor: "m1.small",
-							Image:  ImageFilter{Name: "ubuntu"},
-						},
-					},
 				},
 			},
 			newTemplate: &OpenStackCluster{
@@ -491,88 +542,587 @@ func TestOpenStackCluster_ValidateUpdate(t *testing.T) {
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
+					ControlPlaneAvailabilityZones: []string{
+						"alice",
+						"bob",
+					},
 				},
 			},
 			wantErr: false,
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			warn, err := tt.newTemplate.ValidateUpdate(tt.oldTemplate)
-			if tt.wantErr {
-				g.Expect(err).To(HaveOccurred())
-			} else {
-				g.Expect(err).NotTo(HaveOccurred())
-			}
-			// Nothing emits warnings yet
-			g.Expect(warn).To(BeEmpty())
-		})
-	}
-}
-
-func TestOpenStackCluster_ValidateCreate(t *testing.T) {
-	g := NewWithT(t)
-
-	tests := []struct {
-		name     string
-		template *OpenStackCluster
-		wantErr  bool
-	}{
 		{
-			name: "OpenStackCluster.Spec.IdentityRef with correct spec on create",
-			template: &OpenStackCluster{
+			name: "Modifying OpenStackCluster.Spec.ControlPlaneAvailabilityZones is allowed",
+			oldTemplate: &OpenStackCluster{
 				Spec: OpenStackClusterSpec{
 					IdentityRef: OpenStackIdentityReference{
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
+					ControlPlaneAvailabilityZones: []string{
+						"alice",
+						"bob",
+					},
 				},
 			},
-			wantErr: false,
-		},
-		{
-			name: "OpenStackCluster.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules with correct spec on create",
-			template: &OpenStackCluster{
+			newTemplate: &OpenStackCluster{
 				Spec: OpenStackClusterSpec{
 					IdentityRef: OpenStackIdentityReference{
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					ManagedSecurityGroups: &ManagedSecurityGroups{
-						AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{
-							{
-								Name:         "foobar",
-								Description:  pointer.String("foobar"),
-								PortRangeMin: pointer.Int(80),
-								PortRangeMax: pointer.Int(80),
-								Protocol:     pointer.String("tcp"),
-							},
-						},
+					ControlPlaneAvailabilityZones: []string{
+						"alice",
+						"bob",
+						"eve",
 					},
 				},
 			},
 			wantErr: false,
 		},
 		{
-			name: "OpenStackCluster.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules with mutually exclusive fields on create",
-			template: &OpenStackCluster{
+			name: "Removing OpenStackCluster.Spec.ControlPlaneAvailabilityZones is allowed",
+			oldTemplate: &OpenStackCluster{
 				Spec: OpenStackClusterSpec{
 					IdentityRef: OpenStackIdentityReference{
 						Name:      "foobar",
 						CloudName: "foobar",
 					},
-					ManagedSecurityGroups: &ManagedSecurityGroups{
-						AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{
-							{
-								Name:                "foobar",
-								Description:         pointer.String("foobar"),
-								PortRangeMin:        pointer.Int(80),
-								PortRangeMax:        pointer.Int(80),
-								Protocol:            pointer.String("tcp"),
-								RemoteManagedGroups: []ManagedSecurityGroupName{"controlplane"},
-								RemoteGroupID:       pointer.String("foobar"),
-							},
-						},
+					ControlPlaneAvailabilityZones: []string{
+						"alice",
+						"bob",
+					},
+				},
+			},
+			newTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Modifying OpenstackCluster.Spec.ControlPlaneOmitAvailabilityZone is allowed",
+			oldTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+				},
+			},
+			newTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					ControlPlaneOmitAvailabilityZone: true,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Changing OpenStackCluster.Spec.APIServerFixedIP is allowed when API Server Floating IP is disabled",
+			oldTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					DisableAPIServerFloatingIP: true,
+				},
+			},
+			newTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					DisableAPIServerFloatingIP: true,
+					APIServerFixedIP:           "20.1.56.1",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Changing OpenStackCluster.Spec.APIServerFixedIP is not allowed",
+			oldTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					DisableAPIServerFloatingIP: false,
+				},
+			},
+			newTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					DisableAPIServerFloatingIP: false,
+					APIServerFixedIP:           "20.1.56.1",
+				},
+			},
+			wantErr: true,
+		},
+
+		{
+			name: "Changing OpenStackCluster.Spec.APIServerPort is allowed when API Server Floating IP is disabled",
+			oldTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					DisableAPIServerFloatingIP: true,
+				},
+			},
+			newTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					DisableAPIServerFloatingIP: true,
+					APIServerPort:              8443,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Changing OpenStackCluster.Spec.APIServerPort is not allowed",
+			oldTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					DisableAPIServerFloatingIP: false,
+				},
+			},
+			newTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					DisableAPIServerFloatingIP: false,
+					APIServerPort:              8443,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Changing OpenStackCluster.Spec.APIServerFloatingIP is allowed when it matches the current api server loadbalancer IP",
+			oldTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					APIServerFloatingIP: "",
+				},
+				Status: OpenStackClusterStatus{
+					APIServerLoadBalancer: &LoadBalancer{
+						IP: "1.2.3.4",
+					},
+				},
+			},
+			newTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					APIServerFloatingIP: "1.2.3.4",
+				},
+				Status: OpenStackClusterStatus{
+					APIServerLoadBalancer: &LoadBalancer{
+						IP: "1.2.3.4",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Changing OpenStackCluster.Spec.APIServerFloatingIP is not allowed when it doesn't matches the current api server loadbalancer IP",
+			oldTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					APIServerFloatingIP: "",
+				},
+				Status: OpenStackClusterStatus{
+					APIServerLoadBalancer: &LoadBalancer{
+						IP: "1.2.3.4",
+					},
+				},
+			},
+			newTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					APIServerFloatingIP: "5.6.7.8",
+				},
+				Status: OpenStackClusterStatus{
+					APIServerLoadBalancer: &LoadBalancer{
+						IP: "1.2.3.4",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Removing OpenStackCluster.Spec.Bastion when it is enabled is not allowed",
+			oldTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					Bastion: &Bastion{
+						Enabled: true,
+						Instance: OpenStackMachineSpec{
+							Flavor: "m1.small",
+							Image:  ImageFilter{Name: "ubuntu"},
+						},
+					},
+				},
+			},
+			newTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Removing OpenStackCluster.Spec.Bastion when it is disabled is allowed",
+			oldTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					Bastion: &Bastion{
+						Enabled: false,
+						Instance: OpenStackMachineSpec{
+							Flavor: "m1.small",
+							Image:  ImageFilter{Name: "ubuntu"},
+						},
+					},
+				},
+			},
+			newTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Disabling the bastion while OpenStackCluster.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules reference its group is not allowed",
+			oldTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					Bastion: &Bastion{Enabled: true},
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{
+							{Name: "ssh", Direction: "ingress", RemoteManagedGroups: []ManagedSecurityGroupName{"bastion"}},
+						},
+					},
+				},
+			},
+			newTemplate: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					Bastion: &Bastion{Enabled: false},
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{
+							{Name: "ssh", Direction: "ingress", RemoteManagedGroups: []ManagedSecurityGroupName{"bastion"}},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warn, err := tt.newTemplate.ValidateUpdate(tt.oldTemplate)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			// None of the cases emit warnings
+			g.Expect(warn).To(BeEmpty())
+		})
+	}
+}
+
+func TestOpenStackCluster_ValidateCreate(t *testing.T) {
+	g := NewWithT(t)
+
+	tests := []struct {
+		name     string
+		template *OpenStackCluster
+		wantErr  bool
+	}{
+		{
+			name: "OpenStackCluster.Spec.IdentityRef with correct spec on create",
+			template: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "OpenStackCluster.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules with correct spec on create",
+			template: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{
+							{
+								Name:         "foobar",
+								Description:  pointer.String("foobar"),
+								PortRangeMin: pointer.Int(80),
+								PortRangeMax: pointer.Int(80),
+								Protocol:     pointer.String("tcp"),
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "OpenStackCluster.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules with mutually exclusive fields on create",
+			template: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{
+							{
+								Name:                "foobar",
+								Description:         pointer.String("foobar"),
+								PortRangeMin:        pointer.Int(80),
+								PortRangeMax:        pointer.Int(80),
+								Protocol:            pointer.String("tcp"),
+								RemoteManagedGroups: []ManagedSecurityGroupName{"controlplane"},
+								RemoteGroupID:       pointer.String("foobar"),
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "OpenStackCluster.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules with ICMP type and code on create",
+			template: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{
+							{
+								Name:         "pmtud",
+								Direction:    "ingress",
+								EtherType:    pointer.String("IPv4"),
+								PortRangeMin: pointer.Int(3),
+								PortRangeMax: pointer.Int(4),
+								Protocol:     pointer.String("icmp"),
+							},
+							{
+								Name:         "pmtud6",
+								Direction:    "ingress",
+								EtherType:    pointer.String("IPv6"),
+								PortRangeMin: pointer.Int(2),
+								Protocol:     pointer.String("icmpv6"),
+							},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "OpenStackCluster.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules with ICMP code above 255 on create",
+			template: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{
+							{
+								Name:         "echo",
+								Direction:    "ingress",
+								PortRangeMin: pointer.Int(8),
+								PortRangeMax: pointer.Int(256),
+								Protocol:     pointer.String("icmp"),
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "OpenStackCluster.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules with mixed-case ICMP protocol and type above 255 on create",
+			template: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{
+							{
+								Name:         "echo",
+								Direction:    "ingress",
+								PortRangeMin: pointer.Int(256),
+								Protocol:     pointer.String("IPv6-ICMP"),
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "OpenStackCluster.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules with ICMP code without type on create",
+			template: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{
+							{
+								Name:         "echo",
+								Direction:    "ingress",
+								PortRangeMax: pointer.Int(0),
+								Protocol:     pointer.String("icmp"),
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "OpenStackCluster.Spec.ManagedSecurityGroups.ShadowRules with mutually exclusive fields on create",
+			template: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef: OpenStackIdentityReference{
+						Name:      "foobar",
+						CloudName: "foobar",
+					},
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						ShadowRules: []SecurityGroupRuleSpec{
+							{
+								Name:           "foobar",
+								Description:    pointer.String("foobar"),
+								PortRangeMin:   pointer.Int(80),
+								PortRangeMax:   pointer.Int(80),
+								Protocol:       pointer.String("tcp"),
+								RemoteGroupID:  pointer.String("foobar"),
+								RemoteIPPrefix: pointer.String("10.0.0.0/24"),
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "OpenStackCluster.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules referencing the group of an enabled bastion on create",
+			template: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					Bastion: &Bastion{Enabled: true},
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{
+							{Name: "ssh", Direction: "ingress", RemoteManagedGroups: []ManagedSecurityGroupName{"controlplane", "bastion"}},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "OpenStackCluster.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules referencing the group of a disabled bastion on create",
+			template: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{
+							{Name: "ssh", Direction: "ingress", RemoteManagedGroups: []ManagedSecurityGroupName{"controlplane", "bastion"}},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "OpenStackCluster.Spec.ManagedSecurityGroups.ShadowRules referencing the group of a disabled bastion on create",
+			template: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					Bastion: &Bastion{Enabled: false},
+					ManagedSecurityGroups: &ManagedSecurityGroups{
+						ShadowRules: []SecurityGroupRuleSpec{
+							{Name: "ssh", Direction: "ingress", RemoteManagedGroups: []ManagedSecurityGroupName{"bastion"}},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "OpenStackCluster.Spec.Bastion.Instance.Ports with a secondary port excluded from the managed security groups on create",
+			template: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					Bastion: &Bastion{
+						Instance: OpenStackMachineSpec{
+							Ports: []PortOpts{{}, {ExcludeManagedSecurityGroups: pointer.Bool(true)}},
+						},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "OpenStackCluster.Spec.Bastion.Instance.Ports with the primary port excluded from the managed security groups on create",
+			template: &OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					Bastion: &Bastion{
+						Instance: OpenStackMachineSpec{
+							Ports: []PortOpts{{ExcludeManagedSecurityGroups: pointer.Bool(true)}},
+						},
 					},
 				},
 			},
@@ -587,8 +1137,569 @@ func TestOpenStackCluster_ValidateCreate(t *testing.T) {
 			} else {
 				g.Expect(err).NotTo(HaveOccurred())
 			}
-			// Nothing emits warnings yet
+			// None of the cases emit warnings
 			g.Expect(warn).To(BeEmpty())
 		})
 	}
 }
+
+func TestValidateSecurityGroupRuleRemotes(t *testing.T) {
+	g := NewWithT(t)
+	rulesPath := field.NewPath("spec", "managedSecurityGroups", "allNodesSecurityGroupRules")
+	rules := []SecurityGroupRuleSpec{
+		{Name: "cidr", Direction: "ingress", RemoteIPPrefix: pointer.String("10.0.0.0/24")},
+		{Name: "both", Direction: "ingress", RemoteGroupID: pointer.String("foobar"), RemoteIPPrefix: pointer.String("10.0.0.0/24")},
+		{Name: "managed", Direction: "ingress", RemoteManagedGroups: []ManagedSecurityGroupName{"worker"}, RemoteIPPrefix: pointer.String("10.0.0.0/24")},
+		{Name: "none", Direction: "egress"},
+		{Name: "filter", Direction: "ingress", RemoteSecurityGroupFilter: &SecurityGroupFilter{Name: "monitoring"}},
+		{Name: "filterAndGroup", Direction: "ingress", RemoteSecurityGroupFilter: &SecurityGroupFilter{Name: "monitoring"}, RemoteGroupID: pointer.String("foobar")},
+	}
+
+	// A single error is reported per rule, naming its index.
+	errs := validateSecurityGroupRuleRemotes(rulesPath, rules)
+	g.Expect(errs).To(HaveLen(3))
+	g.Expect(errs[0].Field).To(Equal("spec.managedSecurityGroups.allNodesSecurityGroupRules[1]"))
+	g.Expect(errs[0].Error()).To(ContainSubstring(`rule "both"`))
+	g.Expect(errs[1].Field).To(Equal("spec.managedSecurityGroups.allNodesSecurityGroupRules[2]"))
+	g.Expect(errs[1].Error()).To(ContainSubstring(`rule "managed"`))
+	g.Expect(errs[2].Field).To(Equal("spec.managedSecurityGroups.allNodesSecurityGroupRules[5]"))
+	g.Expect(errs[2].Error()).To(ContainSubstring(`rule "filterAndGroup"`))
+}
+
+func TestOpenStackCluster_SecurityGroupRulePolicy(t *testing.T) {
+	denyOpenIngress := SecurityGroupRulePolicy{
+		DenyOpenIngress:              true,
+		OpenIngressAllowedNamespaces: []string{"platform"},
+	}
+	newCluster := func(namespace string, rule SecurityGroupRuleSpec) *OpenStackCluster {
+		rule.Name = "rule"
+		cluster := &OpenStackCluster{
+			Spec: OpenStackClusterSpec{
+				IdentityRef: OpenStackIdentityReference{
+					Name:      "foobar",
+					CloudName: "foobar",
+				},
+				ManagedSecurityGroups: &ManagedSecurityGroups{
+					AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{rule},
+				},
+			},
+		}
+		cluster.Namespace = namespace
+		return cluster
+	}
+
+	tests := []struct {
+		name      string
+		policy    SecurityGroupRulePolicy
+		namespace string
+		rule      SecurityGroupRuleSpec
+		wantErr   bool
+	}{
+		{
+			name:      "Open IPv4 ingress is accepted without policy",
+			namespace: "tenant",
+			rule:      SecurityGroupRuleSpec{Direction: "ingress", RemoteIPPrefix: pointer.String("0.0.0.0/0")},
+		},
+		{
+			name:      "Open IPv4 ingress is denied",
+			policy:    denyOpenIngress,
+			namespace: "tenant",
+			rule:      SecurityGroupRuleSpec{Direction: "ingress", RemoteIPPrefix: pointer.String("0.0.0.0/0")},
+			wantErr:   true,
+		},
+		{
+			name:      "Open IPv6 ingress is denied",
+			policy:    denyOpenIngress,
+			namespace: "tenant",
+			rule:      SecurityGroupRuleSpec{Direction: "ingress", RemoteIPPrefix: pointer.String("::/0")},
+			wantErr:   true,
+		},
+		{
+			name:      "Ingress without remote is denied",
+			policy:    denyOpenIngress,
+			namespace: "tenant",
+			rule:      SecurityGroupRuleSpec{Direction: "ingress"},
+			wantErr:   true,
+		},
+		{
+			name:      "Ingress from a prefix is accepted",
+			policy:    denyOpenIngress,
+			namespace: "tenant",
+			rule:      SecurityGroupRuleSpec{Direction: "ingress", RemoteIPPrefix: pointer.String("10.0.0.0/8")},
+		},
+		{
+			name:      "Ingress from a managed group is accepted",
+			policy:    denyOpenIngress,
+			namespace: "tenant",
+			rule:      SecurityGroupRuleSpec{Direction: "ingress", RemoteManagedGroups: []ManagedSecurityGroupName{"worker"}},
+		},
+		{
+			name:      "Open egress is accepted",
+			policy:    denyOpenIngress,
+			namespace: "tenant",
+			rule:      SecurityGroupRuleSpec{Direction: "egress", RemoteIPPrefix: pointer.String("0.0.0.0/0")},
+		},
+		{
+			name:      "Open ingress is accepted in an allowlisted namespace",
+			policy:    denyOpenIngress,
+			namespace: "platform",
+			rule:      SecurityGroupRuleSpec{Direction: "ingress", RemoteIPPrefix: pointer.String("0.0.0.0/0")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			w := &OpenStackClusterWebhook{SecurityGroupRulePolicy: tt.policy}
+
+			_, err := w.ValidateCreate(context.TODO(), newCluster(tt.namespace, tt.rule))
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+
+			// The rules can be changed on update, but must still comply with the policy.
+			old := newCluster(tt.namespace, SecurityGroupRuleSpec{Direction: "egress"})
+			_, err = w.ValidateUpdate(context.TODO(), old, newCluster(tt.namespace, tt.rule))
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestOpenStackCluster_DefaultRulesWarnings(t *testing.T) {
+	egressRule := SecurityGroupRuleSpec{
+		Name:           "https",
+		Direction:      "egress",
+		Protocol:       pointer.String("tcp"),
+		PortRangeMin:   pointer.Int(443),
+		PortRangeMax:   pointer.Int(443),
+		RemoteIPPrefix: pointer.String("0.0.0.0/0"),
+	}
+	ingressRule := SecurityGroupRuleSpec{
+		Name:                "cilium",
+		Direction:           "ingress",
+		Protocol:            pointer.String("tcp"),
+		PortRangeMin:        pointer.Int(4240),
+		PortRangeMax:        pointer.Int(4240),
+		RemoteManagedGroups: []ManagedSecurityGroupName{"controlplane", "worker"},
+	}
+
+	tests := []struct {
+		name                  string
+		managedSecurityGroups *ManagedSecurityGroups
+		wantWarning           bool
+	}{
+		{
+			name:                  "Default rules are not disabled",
+			managedSecurityGroups: &ManagedSecurityGroups{AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{ingressRule}},
+		},
+		{
+			name:                  "Default rules are disabled with an egress rule",
+			managedSecurityGroups: &ManagedSecurityGroups{DisableDefaultRules: true, AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{ingressRule, egressRule}},
+		},
+		{
+			name:                  "Default rules are disabled without egress rule",
+			managedSecurityGroups: &ManagedSecurityGroups{DisableDefaultRules: true, AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{ingressRule}},
+			wantWarning:           true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			newCluster := func() *OpenStackCluster {
+				return &OpenStackCluster{
+					Spec: OpenStackClusterSpec{
+						IdentityRef:           OpenStackIdentityReference{Name: "foobar", CloudName: "foobar"},
+						ManagedSecurityGroups: tt.managedSecurityGroups.DeepCopy(),
+					},
+				}
+			}
+
+			createWarnings, err := newCluster().ValidateCreate()
+			g.Expect(err).NotTo(HaveOccurred())
+			updateWarnings, err := newCluster().ValidateUpdate(&OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef:           OpenStackIdentityReference{Name: "foobar", CloudName: "foobar"},
+					ManagedSecurityGroups: &ManagedSecurityGroups{},
+				},
+			})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			for _, warnings := range []admission.Warnings{createWarnings, updateWarnings} {
+				if tt.wantWarning {
+					g.Expect(warnings).To(ConsistOf(ContainSubstring("disableDefaultRules")))
+				} else {
+					g.Expect(warnings).To(BeEmpty())
+				}
+			}
+		})
+	}
+}
+
+func TestOpenStackCluster_WorkerIngressRules(t *testing.T) {
+	ingressRule := SecurityGroupRuleSpec{
+		Name:                "kubelet",
+		Direction:           "ingress",
+		Protocol:            pointer.String("tcp"),
+		PortRangeMin:        pointer.Int(10250),
+		PortRangeMax:        pointer.Int(10250),
+		RemoteManagedGroups: []ManagedSecurityGroupName{"controlplane"},
+	}
+
+	tests := []struct {
+		name                  string
+		managedSecurityGroups *ManagedSecurityGroups
+		wantWarning           bool
+		wantErr               bool
+	}{
+		{
+			name:                  "Worker ingress rules are disabled with an ingress rule",
+			managedSecurityGroups: &ManagedSecurityGroups{DisableWorkerIngressRules: true, AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{ingressRule}},
+		},
+		{
+			name:                  "Worker ingress rules are disabled without ingress rule",
+			managedSecurityGroups: &ManagedSecurityGroups{DisableWorkerIngressRules: true},
+			wantWarning:           true,
+		},
+		{
+			name:                  "Worker ingress rules are disabled with the shared node group",
+			managedSecurityGroups: &ManagedSecurityGroups{DisableWorkerIngressRules: true, SharedNodeGroup: true, AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{ingressRule}},
+			wantErr:               true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			newCluster := func(managedSecurityGroups *ManagedSecurityGroups) *OpenStackCluster {
+				return &OpenStackCluster{
+					Spec: OpenStackClusterSpec{
+						IdentityRef:           OpenStackIdentityReference{Name: "foobar", CloudName: "foobar"},
+						ManagedSecurityGroups: managedSecurityGroups.DeepCopy(),
+					},
+				}
+			}
+
+			createWarnings, createErr := newCluster(tt.managedSecurityGroups).ValidateCreate()
+			// The worker ingress rules can be disabled on an existing cluster.
+			oldManagedSecurityGroups := &ManagedSecurityGroups{SharedNodeGroup: tt.managedSecurityGroups.SharedNodeGroup}
+			updateWarnings, updateErr := newCluster(tt.managedSecurityGroups).ValidateUpdate(newCluster(oldManagedSecurityGroups))
+
+			for _, err := range []error{createErr, updateErr} {
+				if tt.wantErr {
+					g.Expect(err).To(MatchError(ContainSubstring("disableWorkerIngressRules")))
+				} else {
+					g.Expect(err).NotTo(HaveOccurred())
+				}
+			}
+			for _, warnings := range []admission.Warnings{createWarnings, updateWarnings} {
+				if tt.wantWarning {
+					g.Expect(warnings).To(ConsistOf(ContainSubstring("disableWorkerIngressRules")))
+				} else {
+					g.Expect(warnings).To(BeEmpty())
+				}
+			}
+		})
+	}
+}
+
+func TestOpenStackCluster_ExistingSecurityGroups(t *testing.T) {
+	tests := []struct {
+		name                  string
+		managedSecurityGroups *ManagedSecurityGroups
+		bastion               *Bastion
+		wantErr               string
+	}{
+		{
+			name: "Existing groups are adopted as managed groups",
+			managedSecurityGroups: &ManagedSecurityGroups{ExistingGroups: []ExistingSecurityGroup{
+				{Role: "controlplane", ID: "cp-id"},
+				{Role: "worker", ID: "worker-id"},
+				{Role: "bastion", ID: "bastion-id"},
+			}},
+			bastion: &Bastion{Enabled: true},
+		},
+		{
+			name:                  "Existing group is adopted as the node group of the shared node group",
+			managedSecurityGroups: &ManagedSecurityGroups{SharedNodeGroup: true, ExistingGroups: []ExistingSecurityGroup{{Role: "node", ID: "node-id"}}},
+		},
+		{
+			name:                  "Existing group is adopted as the control plane group with the shared node group",
+			managedSecurityGroups: &ManagedSecurityGroups{SharedNodeGroup: true, ExistingGroups: []ExistingSecurityGroup{{Role: "controlplane", ID: "cp-id"}}},
+			wantErr:               "existingGroups[0].role",
+		},
+		{
+			name:                  "Existing group is adopted as the allNodes group without the separate allNodes group",
+			managedSecurityGroups: &ManagedSecurityGroups{ExistingGroups: []ExistingSecurityGroup{{Role: "allNodes", ID: "all-nodes-id"}}},
+			wantErr:               "existingGroups[0].role",
+		},
+		{
+			name:                  "Existing group is adopted as the bastion group without bastion",
+			managedSecurityGroups: &ManagedSecurityGroups{ExistingGroups: []ExistingSecurityGroup{{Role: "bastion", ID: "bastion-id"}}},
+			wantErr:               "existingGroups[0].role",
+		},
+		{
+			name: "Existing group is adopted twice",
+			managedSecurityGroups: &ManagedSecurityGroups{ExistingGroups: []ExistingSecurityGroup{
+				{Role: "controlplane", ID: "group-id"},
+				{Role: "worker", ID: "group-id"},
+			}},
+			wantErr: "existingGroups[1].id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			newCluster := func(managedSecurityGroups *ManagedSecurityGroups) *OpenStackCluster {
+				return &OpenStackCluster{
+					Spec: OpenStackClusterSpec{
+						IdentityRef:           OpenStackIdentityReference{Name: "foobar", CloudName: "foobar"},
+						ManagedSecurityGroups: managedSecurityGroups.DeepCopy(),
+						Bastion:               tt.bastion.DeepCopy(),
+					},
+				}
+			}
+
+			_, createErr := newCluster(tt.managedSecurityGroups).ValidateCreate()
+			// The existing groups can be adopted by an existing cluster.
+			oldManagedSecurityGroups := &ManagedSecurityGroups{SharedNodeGroup: tt.managedSecurityGroups.SharedNodeGroup}
+			_, updateErr := newCluster(tt.managedSecurityGroups).ValidateUpdate(newCluster(oldManagedSecurityGroups))
+
+			for _, err := range []error{createErr, updateErr} {
+				if tt.wantErr != "" {
+					g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				} else {
+					g.Expect(err).NotTo(HaveOccurred())
+				}
+			}
+		})
+	}
+}
+
+func TestOpenStackCluster_BastionSSHAllowedCIDRs(t *testing.T) {
+	tests := []struct {
+		name            string
+		sshAllowedCIDRs []string
+		wantErr         string
+	}{
+		{
+			name: "SSH is permitted from anywhere",
+		},
+		{
+			name:            "SSH is restricted to CIDRs",
+			sshAllowedCIDRs: []string{"192.168.0.0/16", "2001:db8::/32"},
+		},
+		{
+			name:            "SSH is restricted to an address",
+			sshAllowedCIDRs: []string{"192.168.0.0/16", "192.168.1.1"},
+			wantErr:         "sshAllowedCIDRs[1]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			newCluster := func(sshAllowedCIDRs []string) *OpenStackCluster {
+				return &OpenStackCluster{
+					Spec: OpenStackClusterSpec{
+						IdentityRef: OpenStackIdentityReference{Name: "foobar", CloudName: "foobar"},
+						Bastion:     &Bastion{Enabled: true, SSHAllowedCIDRs: sshAllowedCIDRs},
+					},
+				}
+			}
+
+			_, createErr := newCluster(tt.sshAllowedCIDRs).ValidateCreate()
+			// The CIDRs can be changed.
+			_, updateErr := newCluster(tt.sshAllowedCIDRs).ValidateUpdate(newCluster(nil))
+
+			for _, err := range []error{createErr, updateErr} {
+				if tt.wantErr != "" {
+					g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				} else {
+					g.Expect(err).NotTo(HaveOccurred())
+				}
+			}
+		})
+	}
+}
+
+func TestOpenStackCluster_NodePortAllowedCIDRs(t *testing.T) {
+	tests := []struct {
+		name                 string
+		nodePortAllowedCIDRs []string
+		wantErr              string
+	}{
+		{
+			name: "Node ports are permitted from anywhere",
+		},
+		{
+			name:                 "Node ports are restricted to CIDRs",
+			nodePortAllowedCIDRs: []string{"192.168.0.0/16", "2001:db8::/32"},
+		},
+		{
+			name:                 "Node ports are restricted to an address",
+			nodePortAllowedCIDRs: []string{"192.168.0.0/16", "192.168.1.1"},
+			wantErr:              "nodePortAllowedCIDRs[1]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			newCluster := func(nodePortAllowedCIDRs []string) *OpenStackCluster {
+				return &OpenStackCluster{
+					Spec: OpenStackClusterSpec{
+						IdentityRef:           OpenStackIdentityReference{Name: "foobar", CloudName: "foobar"},
+						ManagedSecurityGroups: &ManagedSecurityGroups{NodePortAllowedCIDRs: nodePortAllowedCIDRs},
+					},
+				}
+			}
+
+			_, createErr := newCluster(tt.nodePortAllowedCIDRs).ValidateCreate()
+			// The CIDRs can be changed.
+			_, updateErr := newCluster(tt.nodePortAllowedCIDRs).ValidateUpdate(newCluster(nil))
+
+			for _, err := range []error{createErr, updateErr} {
+				if tt.wantErr != "" {
+					g.Expect(err).To(MatchError(ContainSubstring(tt.wantErr)))
+				} else {
+					g.Expect(err).NotTo(HaveOccurred())
+				}
+			}
+		})
+	}
+}
+
+func TestValidateSecurityGroupRulePortRanges(t *testing.T) {
+	g := NewWithT(t)
+	rulesPath := field.NewPath("spec", "managedSecurityGroups", "allNodesSecurityGroupRules")
+	rules := []SecurityGroupRuleSpec{
+		{Name: "http", Direction: "ingress", Protocol: pointer.String("tcp"), PortRangeMin: pointer.Int(80), PortRangeMax: pointer.Int(443)},
+		{Name: "inverted", Direction: "ingress", Protocol: pointer.String("tcp"), PortRangeMin: pointer.Int(443), PortRangeMax: pointer.Int(80)},
+		// The port range of an ICMP rule is its type and code.
+		{Name: "icmp", Direction: "ingress", Protocol: pointer.String("icmp"), PortRangeMin: pointer.Int(8), PortRangeMax: pointer.Int(0)},
+	}
+
+	errs := validateSecurityGroupRulePortRanges(rulesPath, rules)
+	g.Expect(errs).To(HaveLen(1))
+	g.Expect(errs[0].Field).To(Equal("spec.managedSecurityGroups.allNodesSecurityGroupRules[1].portRangeMax"))
+}
+
+func TestOpenStackCluster_OverlappingPortRangesWarnings(t *testing.T) {
+	rule := func(name string, min, max int) SecurityGroupRuleSpec {
+		return SecurityGroupRuleSpec{
+			Name:           name,
+			Direction:      "ingress",
+			Protocol:       pointer.String("tcp"),
+			PortRangeMin:   pointer.Int(min),
+			PortRangeMax:   pointer.Int(max),
+			RemoteIPPrefix: pointer.String("10.0.0.0/8"),
+		}
+	}
+	otherRemote := rule("https", 443, 8080)
+	otherRemote.RemoteIPPrefix = pointer.String("192.168.0.0/16")
+	otherProtocol := rule("https", 443, 8080)
+	otherProtocol.Protocol = pointer.String("udp")
+	allPorts := rule("all", 0, 0)
+	allPorts.PortRangeMin, allPorts.PortRangeMax = nil, nil
+	managedGroups := func(name string, min, max int, groups ...ManagedSecurityGroupName) SecurityGroupRuleSpec {
+		r := rule(name, min, max)
+		r.RemoteIPPrefix = nil
+		r.RemoteManagedGroups = groups
+		return r
+	}
+
+	tests := []struct {
+		name         string
+		rules        []SecurityGroupRuleSpec
+		wantWarnings []string
+	}{
+		{
+			name:  "Disjoint port ranges",
+			rules: []SecurityGroupRuleSpec{rule("http", 80, 442), rule("https", 443, 8080)},
+		},
+		{
+			name:         "Overlapping port ranges",
+			rules:        []SecurityGroupRuleSpec{rule("http", 80, 443), rule("https", 443, 8080)},
+			wantWarnings: []string{`allNodesSecurityGroupRules[1]: the port range of rule "https" overlaps the port range of rule "http"`},
+		},
+		{
+			name:  "Overlapping port ranges with other remotes",
+			rules: []SecurityGroupRuleSpec{rule("http", 80, 443), otherRemote},
+		},
+		{
+			name:  "Overlapping port ranges with other protocols",
+			rules: []SecurityGroupRuleSpec{rule("http", 80, 443), otherProtocol},
+		},
+		{
+			name:         "Rule permitting all the ports",
+			rules:        []SecurityGroupRuleSpec{allPorts, rule("https", 443, 443)},
+			wantWarnings: []string{`rule "https" overlaps the port range of rule "all"`},
+		},
+		{
+			name:         "Same managed groups in another order",
+			rules:        []SecurityGroupRuleSpec{managedGroups("a", 80, 443, "controlplane", "worker"), managedGroups("b", 443, 443, "worker", "controlplane")},
+			wantWarnings: []string{`rule "b" overlaps the port range of rule "a"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			newCluster := func() *OpenStackCluster {
+				return &OpenStackCluster{
+					Spec: OpenStackClusterSpec{
+						IdentityRef:           OpenStackIdentityReference{Name: "foobar", CloudName: "foobar"},
+						ManagedSecurityGroups: &ManagedSecurityGroups{AllNodesSecurityGroupRules: tt.rules},
+					},
+				}
+			}
+
+			createWarnings, err := newCluster().ValidateCreate()
+			g.Expect(err).NotTo(HaveOccurred())
+			updateWarnings, err := newCluster().ValidateUpdate(&OpenStackCluster{
+				Spec: OpenStackClusterSpec{
+					IdentityRef:           OpenStackIdentityReference{Name: "foobar", CloudName: "foobar"},
+					ManagedSecurityGroups: &ManagedSecurityGroups{},
+				},
+			})
+			g.Expect(err).NotTo(HaveOccurred())
+
+			for _, warnings := range []admission.Warnings{createWarnings, updateWarnings} {
+				g.Expect(warnings).To(HaveLen(len(tt.wantWarnings)))
+				for i, want := range tt.wantWarnings {
+					g.Expect(warnings[i]).To(ContainSubstring(want))
+				}
+			}
+		})
+	}
+}
+
+func TestOpenStackCluster_InvertedPortRange(t *testing.T) {
+	g := NewWithT(t)
+	cluster := &OpenStackCluster{
+		Spec: OpenStackClusterSpec{
+			IdentityRef: OpenStackIdentityReference{Name: "foobar", CloudName: "foobar"},
+			ManagedSecurityGroups: &ManagedSecurityGroups{
+				AllNodesSecurityGroupRules: []SecurityGroupRuleSpec{
+					{Name: "inverted", Direction: "egress", Protocol: pointer.String("tcp"), PortRangeMin: pointer.Int(8080), PortRangeMax: pointer.Int(80)},
+				},
+			},
+		},
+	}
+	_, err := cluster.DeepCopy().ValidateCreate()
+	g.Expect(err).To(MatchError(ContainSubstring("portRangeMax")))
+	_, err = cluster.DeepCopy().ValidateUpdate(&OpenStackCluster{
+		Spec: OpenStackClusterSpec{
+			IdentityRef:           OpenStackIdentityReference{Name: "foobar", CloudName: "foobar"},
+			ManagedSecurityGroups: &ManagedSecurityGroups{},
+		},
+	})
+	g.Expect(err).To(MatchError(ContainSubstring("portRangeMax")))
+}
@@ -28,6 +28,10 @@ const (
 	// MachineFinalizer allows ReconcileOpenStackMachine to clean up OpenStack resources associated with OpenStackMachine before
 	// removing it from the apiserver.
 	MachineFinalizer = "openstackmachine.infrastructure.cluster.x-k8s.io"
+
+	// MachineNameMetadataKey is the key of the server metadata entry holding the namespace/name of the Machine
+	// owning the server, when addMachineNameMetadata is set.
+	MachineNameMetadataKey = "capi-machine"
 )
 
 // OpenStackMachineSpec defines the desired state of OpenStackMachine.
@@ -68,6 +72,13 @@ type OpenStackMachineSpec struct {
 	// +listMapKey=key
 	ServerMetadata []ServerMetadata `json:"serverMetadata,omitempty"`
 
+	// addMachineNameMetadata adds a capi-machine metadata entry with the namespace/name of
+	// the owning Machine to the server when it is created, to correlate the servers with their
+	// Machines. It takes precedence over a serverMetadata entry with the same key. It is
+	// ignored for the bastion, which has no Machine.
+	// +optional
+	AddMachineNameMetadata bool `json:"addMachineNameMetadata,omitempty"`
+
 	// Config Drive support
 	ConfigDrive *bool `json:"configDrive,omitempty"`
 
@@ -120,6 +131,11 @@ type OpenStackMachineStatus struct {
 	// DependentResources contains resolved dependent resources that were created by the machine.
 	DependentResources DependentMachineResources `json:"dependentResources,omitempty"`
 
+	// machineNameMetadata is the namespace/name of the Machine in the capi-machine metadata
+	// entry of the server, if it has one.
+	// +optional
+	MachineNameMetadata string `json:"machineNameMetadata,omitempty"`
+
 	FailureReason *errors.MachineStatusError `json:"failureReason,omitempty"`
 
 	// FailureMessage will be set in the event that there is a terminal problem
@@ -23,6 +23,7 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
@@ -63,9 +64,21 @@ func (r *OpenStackMachine) ValidateCreate() (admission.Warnings, error) {
 		}
 	}
 
+	allErrs = append(allErrs, validatePortsExcludeManagedSecurityGroups(field.NewPath("spec", "ports"), r.Spec.Ports)...)
+
 	return aggregateObjErrors(r.GroupVersionKind().GroupKind(), r.Name, allErrs)
 }
 
+// validatePortsExcludeManagedSecurityGroups forbids excluding the primary port of a machine from the managed
+// security groups.
+func validatePortsExcludeManagedSecurityGroups(fldPath *field.Path, ports []PortOpts) field.ErrorList {
+	var allErrs field.ErrorList
+	if len(ports) > 0 && pointer.BoolDeref(ports[0].ExcludeManagedSecurityGroups, false) {
+		allErrs = append(allErrs, field.Forbidden(fldPath.Index(0).Child("excludeManagedSecurityGroups"), "cannot be set on the primary port"))
+	}
+	return allErrs
+}
+
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
 func (r *OpenStackMachine) ValidateUpdate(old runtime.Object) (admission.Warnings, error) {
 	newOpenStackMachine, err := runtime.DefaultUnstructuredConverter.ToUnstructured(r)
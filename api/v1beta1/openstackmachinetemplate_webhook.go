@@ -61,6 +61,8 @@ func (r *OpenStackMachineTemplateWebhook) ValidateCreate(_ context.Context, obj
 		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "template", "spec", "providerID"), "cannot be set in templates"))
 	}
 
+	allErrs = append(allErrs, validatePortsExcludeManagedSecurityGroups(field.NewPath("spec", "template", "spec", "ports"), openStackMachineTemplate.Spec.Template.Spec.Ports)...)
+
 	return aggregateObjErrors(openStackMachineTemplate.GroupVersionKind().GroupKind(), openStackMachineTemplate.Name, allErrs)
 }
 
@@ -168,3 +168,44 @@ func TestOpenStackMachineTemplate_ValidateUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestOpenStackMachineTemplate_ValidateCreateExcludeManagedSecurityGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		ports   []PortOpts
+		wantErr bool
+	}{
+		{
+			name:  "No port excluded from the managed security groups",
+			ports: []PortOpts{{}, {}},
+		},
+		{
+			name:  "Secondary port excluded from the managed security groups",
+			ports: []PortOpts{{}, {ExcludeManagedSecurityGroups: pointer.Bool(true)}},
+		},
+		{
+			name:    "Primary port excluded from the managed security groups",
+			ports:   []PortOpts{{ExcludeManagedSecurityGroups: pointer.Bool(true)}, {}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			template := &OpenStackMachineTemplate{
+				Spec: OpenStackMachineTemplateSpec{
+					Template: OpenStackMachineTemplateResource{
+						Spec: OpenStackMachineSpec{Ports: tt.ports},
+					},
+				},
+			}
+			webhook := &OpenStackMachineTemplateWebhook{}
+			_, err := webhook.ValidateCreate(context.Background(), template)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
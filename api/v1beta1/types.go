@@ -17,6 +17,8 @@ limitations under the License.
 package v1beta1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/utils/optional"
 )
 
@@ -218,6 +220,13 @@ type PortOpts struct {
 	// +optional
 	DisablePortSecurity *bool `json:"disablePortSecurity,omitempty"`
 
+	// ExcludeManagedSecurityGroups doesn't apply the security groups managed by the OpenStackCluster
+	// to the port, e.g. to a DPDK or SR-IOV port. Unless SecurityGroups is set, the port only
+	// inherits the security groups of the machine spec, or none. It can't be set on the first port,
+	// the primary port of the machine, which always has the managed security groups.
+	// +optional
+	ExcludeManagedSecurityGroups *bool `json:"excludeManagedSecurityGroups,omitempty"`
+
 	// PropageteUplinkStatus enables or disables the propagate uplink status on the port.
 	// +optional
 	PropagateUplinkStatus *bool `json:"propagateUplinkStatus,omitempty"`
@@ -426,9 +435,36 @@ type SecurityGroupStatus struct {
 	// +kubebuilder:validation:Required
 	ID string `json:"id"`
 
-	// list of security group rules
+	// list of security group rules. The rules are sorted in a stable order, by
+	// direction, ether type, protocol, port range, remote IP prefix, remote group ID,
+	// description and ID, so the list only changes when the rules do.
 	// +optional
 	Rules []SecurityGroupRuleStatus `json:"rules,omitempty"`
+
+	// rulesHash is a hash of the desired rules of the security group when
+	// they were last reconciled. It is used to only reconcile the security
+	// groups whose rules changed when the cluster spec is edited.
+	// +optional
+	RulesHash string `json:"rulesHash,omitempty"`
+
+	// rulesPendingDeletion are the rules which are not desired anymore, but whose
+	// deletion is deferred by the rule deletion grace period of the controller so
+	// the rules replacing them are in place before they are deleted.
+	// +listType=map
+	// +listMapKey=id
+	// +optional
+	RulesPendingDeletion []SecurityGroupRulePendingDeletion `json:"rulesPendingDeletion,omitempty"`
+}
+
+// SecurityGroupRulePendingDeletion is a security group rule whose deletion is deferred.
+type SecurityGroupRulePendingDeletion struct {
+	// id of the security group rule.
+	// +kubebuilder:validation:Required
+	ID string `json:"id"`
+
+	// markedTime is the time the rule was first found not to be desired anymore.
+	// +kubebuilder:validation:Required
+	MarkedTime metav1.Time `json:"markedTime"`
 }
 
 // SecurityGroupRuleSpec represent the basic information of the associated OpenStack
@@ -462,33 +498,44 @@ type SecurityGroupRuleSpec struct {
 
 	// portRangeMin is a number in the range that is matched by the security group
 	// rule. If the protocol is TCP or UDP, this value must be less than or equal
-	// to the value of the portRangeMax attribute.
+	// to the value of the portRangeMax attribute. If the protocol is icmp, icmpv6
+	// or ipv6-icmp, it is the ICMP type matched by the rule, between 0 and 255.
 	// +optional
 	PortRangeMin *int `json:"portRangeMin,omitempty"`
 
 	// portRangeMax is a number in the range that is matched by the security group
-	// rule. The portRangeMin attribute constrains the portRangeMax attribute.
+	// rule. The portRangeMin attribute constrains the portRangeMax attribute. If
+	// the protocol is icmp, icmpv6 or ipv6-icmp, it is the ICMP code matched by
+	// the rule, between 0 and 255, and requires the ICMP type in portRangeMin.
 	// +optional
 	PortRangeMax *int `json:"portRangeMax,omitempty"`
 
 	// protocol is the protocol that is matched by the security group rule.
+	// When unset, or set to any, the rule matches any protocol.
 	// +optional
 	Protocol *string `json:"protocol,omitempty"`
 
 	// remoteGroupID is the remote group ID to be associated with this security group rule.
-	// You can specify either remoteGroupID or remoteIPPrefix or remoteManagedGroups.
+	// You can specify either remoteGroupID or remoteIPPrefix or remoteManagedGroups or remoteSecurityGroupFilter.
 	// +optional
 	RemoteGroupID *string `json:"remoteGroupID,omitempty"`
 
 	// remoteIPPrefix is the remote IP prefix to be associated with this security group rule.
-	// You can specify either remoteGroupID or remoteIPPrefix or remoteManagedGroups.
+	// You can specify either remoteGroupID or remoteIPPrefix or remoteManagedGroups or remoteSecurityGroupFilter.
 	// +optional
 	RemoteIPPrefix *string `json:"remoteIPPrefix,omitempty"`
 
 	// remoteManagedGroups is the remote managed groups to be associated with this security group rule.
-	// You can specify either remoteGroupID or remoteIPPrefix or remoteManagedGroups.
+	// You can specify either remoteGroupID or remoteIPPrefix or remoteManagedGroups or remoteSecurityGroupFilter.
 	// +optional
 	RemoteManagedGroups []ManagedSecurityGroupName `json:"remoteManagedGroups,omitempty"`
+
+	// remoteSecurityGroupFilter selects security groups which are not managed by the cluster, e.g. the
+	// group of a monitoring system, to be associated with this security group rule. It is resolved on
+	// each reconcile, to a rule per matching group, and the reconcile fails if no group matches.
+	// You can specify either remoteGroupID or remoteIPPrefix or remoteManagedGroups or remoteSecurityGroupFilter.
+	// +optional
+	RemoteSecurityGroupFilter *SecurityGroupFilter `json:"remoteSecurityGroupFilter,omitempty"`
 }
 
 type SecurityGroupRuleStatus struct {
@@ -526,6 +573,7 @@ type SecurityGroupRuleStatus struct {
 	PortRangeMax *int `json:"portRangeMax,omitempty"`
 
 	// protocol is the protocol that is matched by the security group rule.
+	// When unset, or set to any, the rule matches any protocol.
 	// +optional
 	Protocol *string `json:"protocol,omitempty"`
 
@@ -538,8 +586,30 @@ type SecurityGroupRuleStatus struct {
 	// You can specify either remoteGroupID or remoteIPPrefix or remoteManagedGroups.
 	// +optional
 	RemoteIPPrefix *string `json:"remoteIPPrefix,omitempty"`
+
+	// origin is the source of the rule in a managed security group: default for the
+	// rules every managed group has, general for the rules generated for the cluster
+	// to work, user for the rules defined in the cluster spec and bastion for the rules
+	// permitting access through the bastion.
+	// +optional
+	Origin SecurityGroupRuleOrigin `json:"origin,omitempty"`
 }
 
+// SecurityGroupRuleOrigin is the source of a rule of a managed security group.
+// +kubebuilder:validation:Enum=default;general;user;bastion
+type SecurityGroupRuleOrigin string
+
+const (
+	// SecurityGroupRuleOriginDefault is the origin of the rules every managed security group has.
+	SecurityGroupRuleOriginDefault SecurityGroupRuleOrigin = "default"
+	// SecurityGroupRuleOriginGeneral is the origin of the rules generated for the cluster to work.
+	SecurityGroupRuleOriginGeneral SecurityGroupRuleOrigin = "general"
+	// SecurityGroupRuleOriginUser is the origin of the rules defined in the cluster spec.
+	SecurityGroupRuleOriginUser SecurityGroupRuleOrigin = "user"
+	// SecurityGroupRuleOriginBastion is the origin of the rules permitting access through the bastion.
+	SecurityGroupRuleOriginBastion SecurityGroupRuleOrigin = "bastion"
+)
+
 // +kubebuilder:validation:Enum=bastion;controlplane;worker
 type ManagedSecurityGroupName string
 
@@ -584,10 +654,23 @@ type Bastion struct {
 	//+optional
 	AvailabilityZone string `json:"availabilityZone,omitempty"`
 
-	// FloatingIP which will be associated to the bastion machine.
+	// Replicas is the number of bastion instances, 1 if unset. Each replica has its own instance, managed
+	// security group and floating IP, whose names end with the index of the replica. The first replica keeps
+	// the names of a single bastion.
+	//+optional
+	//+kubebuilder:validation:Minimum=1
+	Replicas *int `json:"replicas,omitempty"`
+
+	// FloatingIP which will be associated to the first bastion replica.
 	// The floating IP should already exist and should not be associated with a port.
 	//+optional
 	FloatingIP string `json:"floatingIP,omitempty"`
+
+	// SSHAllowedCIDRs restricts SSH to the bastion to the given address CIDRs. The managed bastion security group
+	// permits SSH from anywhere if unset.
+	//+optional
+	//+listType=set
+	SSHAllowedCIDRs []string `json:"sshAllowedCIDRs,omitempty"`
 }
 
 type APIServerLoadBalancer struct {
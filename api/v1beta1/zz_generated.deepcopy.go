@@ -107,6 +107,16 @@ func (in *AllocationPool) DeepCopy() *AllocationPool {
 func (in *Bastion) DeepCopyInto(out *Bastion) {
 	*out = *in
 	in.Instance.DeepCopyInto(&out.Instance)
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int)
+		**out = **in
+	}
+	if in.SSHAllowedCIDRs != nil {
+		in, out := &in.SSHAllowedCIDRs, &out.SSHAllowedCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Bastion.
@@ -216,6 +226,21 @@ func (in *DependentMachineResources) DeepCopy() *DependentMachineResources {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExistingSecurityGroup) DeepCopyInto(out *ExistingSecurityGroup) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExistingSecurityGroup.
+func (in *ExistingSecurityGroup) DeepCopy() *ExistingSecurityGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(ExistingSecurityGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExternalRouterIPParam) DeepCopyInto(out *ExternalRouterIPParam) {
 	*out = *in
@@ -337,6 +362,28 @@ func (in *LoadBalancer) DeepCopy() *LoadBalancer {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedSecurityGroupRulesStatus) DeepCopyInto(out *ManagedSecurityGroupRulesStatus) {
+	*out = *in
+	if in.RulesPendingCreation != nil {
+		in, out := &in.RulesPendingCreation, &out.RulesPendingCreation
+		*out = make([]SecurityGroupRuleStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedSecurityGroupRulesStatus.
+func (in *ManagedSecurityGroupRulesStatus) DeepCopy() *ManagedSecurityGroupRulesStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedSecurityGroupRulesStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ManagedSecurityGroups) DeepCopyInto(out *ManagedSecurityGroups) {
 	*out = *in
@@ -347,6 +394,35 @@ func (in *ManagedSecurityGroups) DeepCopyInto(out *ManagedSecurityGroups) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ShadowRules != nil {
+		in, out := &in.ShadowRules, &out.ShadowRules
+		*out = make([]SecurityGroupRuleSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.WellKnownPorts != nil {
+		in, out := &in.WellKnownPorts, &out.WellKnownPorts
+		*out = make([]WellKnownPort, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NodePortAllowedCIDRs != nil {
+		in, out := &in.NodePortAllowedCIDRs, &out.NodePortAllowedCIDRs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Stateless != nil {
+		in, out := &in.Stateless, &out.Stateless
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ExistingGroups != nil {
+		in, out := &in.ExistingGroups, &out.ExistingGroups
+		*out = make([]ExistingSecurityGroup, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedSecurityGroups.
@@ -359,6 +435,30 @@ func (in *ManagedSecurityGroups) DeepCopy() *ManagedSecurityGroups {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedSecurityGroupsStatus) DeepCopyInto(out *ManagedSecurityGroupsStatus) {
+	*out = *in
+	if in.LastReconciledTime != nil {
+		in, out := &in.LastReconciledTime, &out.LastReconciledTime
+		*out = (*in).DeepCopy()
+	}
+	if in.RuleProbe != nil {
+		in, out := &in.RuleProbe, &out.RuleProbe
+		*out = new(SecurityGroupRuleProbeStatus)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedSecurityGroupsStatus.
+func (in *ManagedSecurityGroupsStatus) DeepCopy() *ManagedSecurityGroupsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedSecurityGroupsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NetworkFilter) DeepCopyInto(out *NetworkFilter) {
 	*out = *in
@@ -588,11 +688,52 @@ func (in *OpenStackClusterStatus) DeepCopyInto(out *OpenStackClusterStatus) {
 		*out = new(SecurityGroupStatus)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.AdditionalBastionSecurityGroups != nil {
+		in, out := &in.AdditionalBastionSecurityGroups, &out.AdditionalBastionSecurityGroups
+		*out = make([]SecurityGroupStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NodeSecurityGroup != nil {
+		in, out := &in.NodeSecurityGroup, &out.NodeSecurityGroup
+		*out = new(SecurityGroupStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AllNodesSecurityGroup != nil {
+		in, out := &in.AllNodesSecurityGroup, &out.AllNodesSecurityGroup
+		*out = new(SecurityGroupStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ShadowSecurityGroup != nil {
+		in, out := &in.ShadowSecurityGroup, &out.ShadowSecurityGroup
+		*out = new(SecurityGroupStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ManagedSecurityGroups != nil {
+		in, out := &in.ManagedSecurityGroups, &out.ManagedSecurityGroups
+		*out = new(ManagedSecurityGroupsStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ManagedSecurityGroupRules != nil {
+		in, out := &in.ManagedSecurityGroupRules, &out.ManagedSecurityGroupRules
+		*out = make([]ManagedSecurityGroupRulesStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.Bastion != nil {
 		in, out := &in.Bastion, &out.Bastion
 		*out = new(BastionStatus)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.AdditionalBastions != nil {
+		in, out := &in.AdditionalBastions, &out.AdditionalBastions
+		*out = make([]BastionStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.FailureReason != nil {
 		in, out := &in.FailureReason, &out.FailureReason
 		*out = new(errors.ClusterStatusError)
@@ -603,6 +744,13 @@ func (in *OpenStackClusterStatus) DeepCopyInto(out *OpenStackClusterStatus) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make(apiv1beta1.Conditions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OpenStackClusterStatus.
@@ -1064,6 +1212,11 @@ func (in *PortOpts) DeepCopyInto(out *PortOpts) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.ExcludeManagedSecurityGroups != nil {
+		in, out := &in.ExcludeManagedSecurityGroups, &out.ExcludeManagedSecurityGroups
+		*out = new(bool)
+		**out = **in
+	}
 	if in.PropagateUplinkStatus != nil {
 		in, out := &in.PropagateUplinkStatus, &out.PropagateUplinkStatus
 		*out = new(bool)
@@ -1200,6 +1353,38 @@ func (in *SecurityGroupFilter) DeepCopy() *SecurityGroupFilter {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecurityGroupRulePendingDeletion) DeepCopyInto(out *SecurityGroupRulePendingDeletion) {
+	*out = *in
+	in.MarkedTime.DeepCopyInto(&out.MarkedTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecurityGroupRulePendingDeletion.
+func (in *SecurityGroupRulePendingDeletion) DeepCopy() *SecurityGroupRulePendingDeletion {
+	if in == nil {
+		return nil
+	}
+	out := new(SecurityGroupRulePendingDeletion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecurityGroupRuleProbeStatus) DeepCopyInto(out *SecurityGroupRuleProbeStatus) {
+	*out = *in
+	in.ProbeTime.DeepCopyInto(&out.ProbeTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecurityGroupRuleProbeStatus.
+func (in *SecurityGroupRuleProbeStatus) DeepCopy() *SecurityGroupRuleProbeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SecurityGroupRuleProbeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecurityGroupRuleSpec) DeepCopyInto(out *SecurityGroupRuleSpec) {
 	*out = *in
@@ -1243,6 +1428,11 @@ func (in *SecurityGroupRuleSpec) DeepCopyInto(out *SecurityGroupRuleSpec) {
 		*out = make([]ManagedSecurityGroupName, len(*in))
 		copy(*out, *in)
 	}
+	if in.RemoteSecurityGroupFilter != nil {
+		in, out := &in.RemoteSecurityGroupFilter, &out.RemoteSecurityGroupFilter
+		*out = new(SecurityGroupFilter)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecurityGroupRuleSpec.
@@ -1315,6 +1505,13 @@ func (in *SecurityGroupStatus) DeepCopyInto(out *SecurityGroupStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.RulesPendingDeletion != nil {
+		in, out := &in.RulesPendingDeletion, &out.RulesPendingDeletion
+		*out = make([]SecurityGroupRulePendingDeletion, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecurityGroupStatus.
@@ -1432,3 +1629,23 @@ func (in *ValueSpec) DeepCopy() *ValueSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WellKnownPort) DeepCopyInto(out *WellKnownPort) {
+	*out = *in
+	if in.PortRangeMax != nil {
+		in, out := &in.PortRangeMax, &out.PortRangeMax
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WellKnownPort.
+func (in *WellKnownPort) DeepCopy() *WellKnownPort {
+	if in == nil {
+		return nil
+	}
+	out := new(WellKnownPort)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -31,10 +31,12 @@ import (
 	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/controllers/remote"
 	capierrors "sigs.k8s.io/cluster-api/errors"
 	"sigs.k8s.io/cluster-api/util"
 	"sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/collections"
+	"sigs.k8s.io/cluster-api/util/conditions"
 	"sigs.k8s.io/cluster-api/util/patch"
 	"sigs.k8s.io/cluster-api/util/predicates"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -66,11 +68,18 @@ type OpenStackClusterReconciler struct {
 	WatchFilterValue string
 	ScopeFactory     scope.Factory
 	CaCertificates   []byte // PEM encoded ca certificates.
+	// Tracker caches the clients of the workload clusters and watches their Services of type LoadBalancer.
+	Tracker *remote.ClusterCacheTracker
+	// NetworkingOptions configure the networking services.
+	NetworkingOptions networking.Options
+
+	controller controller.Controller
 }
 
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=openstackclusters,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=infrastructure.cluster.x-k8s.io,resources=openstackclusters/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=cluster.x-k8s.io,resources=clusters;clusters/status,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get
 
 func (r *OpenStackClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, reterr error) {
 	log := ctrl.LoggerFrom(ctx)
@@ -127,7 +136,7 @@ func (r *OpenStackClusterReconciler) Reconcile(ctx context.Context, req ctrl.Req
 		if openStackCluster.Status.Bastion == nil {
 			openStackCluster.Status.Bastion = &infrav1.BastionStatus{}
 		}
-		changed, err := compute.ResolveReferencedMachineResources(scope, openStackCluster, &openStackCluster.Spec.Bastion.Instance, &openStackCluster.Status.Bastion.ReferencedResources)
+		changed, err := compute.ResolveReferencedMachineResources(scope, r.NetworkingOptions, openStackCluster, &openStackCluster.Spec.Bastion.Instance, &openStackCluster.Status.Bastion.ReferencedResources)
 		if err != nil {
 			return reconcile.Result{}, err
 		}
@@ -136,7 +145,7 @@ func (r *OpenStackClusterReconciler) Reconcile(ctx context.Context, req ctrl.Req
 			return reconcile.Result{}, nil
 		}
 
-		changed, err = compute.ResolveDependentBastionResources(scope, openStackCluster, bastionName(cluster.Name))
+		changed, err = compute.ResolveDependentBastionResources(scope, r.NetworkingOptions, openStackCluster, bastionName(cluster.Name))
 		if err != nil {
 			return reconcile.Result{}, err
 		}
@@ -144,6 +153,19 @@ func (r *OpenStackClusterReconciler) Reconcile(ctx context.Context, req ctrl.Req
 			// If the dependent resources have changed, we need to update the OpenStackCluster status now.
 			return reconcile.Result{}, nil
 		}
+
+		// The other bastion replicas use the resources referenced by the first one.
+		for i := 1; i < bastionReplicas(openStackCluster); i++ {
+			bastionStatus := initBastionReplicaStatus(openStackCluster, i)
+			bastionStatus.ReferencedResources = *openStackCluster.Status.Bastion.ReferencedResources.DeepCopy()
+			changed, err = compute.ResolveDependentBastionReplicaResources(scope, r.NetworkingOptions, openStackCluster, bastionStatus, bastionReplicaName(cluster.Name, i))
+			if err != nil {
+				return reconcile.Result{}, err
+			}
+			if changed {
+				return reconcile.Result{}, nil
+			}
+		}
 	}
 
 	// Handle deleted clusters
@@ -152,7 +174,104 @@ func (r *OpenStackClusterReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	}
 
 	// Handle non-deleted clusters
-	return reconcileNormal(scope, cluster, openStackCluster)
+	loadBalancerServices, err := r.getLoadBalancerServices(ctx, cluster, openStackCluster)
+	if err != nil {
+		if errors.Is(err, remote.ErrClusterLocked) {
+			// Another reconcile is creating the client of the workload cluster.
+			log.V(5).Info("Requeuing because another worker has the lock on the ClusterCacheTracker")
+			return reconcile.Result{RequeueAfter: time.Minute}, nil
+		}
+		return reconcile.Result{}, err
+	}
+	goldenRules, err := r.getSecurityGroupGoldenRules(ctx, openStackCluster)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcileNormal(ctx, scope, r.NetworkingOptions, cluster, openStackCluster, loadBalancerServices, goldenRules)
+}
+
+// getLoadBalancerServices returns the Services of type LoadBalancer of the workload cluster when the cluster
+// allows their traffic. The workload cluster is only queried once its control plane is initialized. The Services
+// are then watched, so that the cluster is reconciled again when they change.
+func (r *OpenStackClusterReconciler) getLoadBalancerServices(ctx context.Context, cluster *clusterv1.Cluster, openStackCluster *infrav1.OpenStackCluster) ([]corev1.Service, error) {
+	if openStackCluster.Spec.ManagedSecurityGroups == nil || !openStackCluster.Spec.ManagedSecurityGroups.AllowLoadBalancerServiceTraffic {
+		return nil, nil
+	}
+	if !conditions.IsTrue(cluster, clusterv1.ControlPlaneInitializedCondition) {
+		return nil, nil
+	}
+
+	if err := r.Tracker.Watch(ctx, remote.WatchInput{
+		Name:         "openstackcluster-watchLoadBalancerServices",
+		Cluster:      util.ObjectKey(cluster),
+		Watcher:      r.controller,
+		Kind:         &corev1.Service{},
+		EventHandler: handler.EnqueueRequestsFromMapFunc(loadBalancerServiceToOpenStackCluster(openStackCluster)),
+		Predicates:   []predicate.Predicate{loadBalancerServicePredicate()},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to watch services of workload cluster: %w", err)
+	}
+
+	workloadClient, err := r.Tracker.GetClient(ctx, util.ObjectKey(cluster))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client for workload cluster: %w", err)
+	}
+
+	services := &corev1.ServiceList{}
+	if err := workloadClient.List(ctx, services); err != nil {
+		return nil, fmt.Errorf("failed to list services of workload cluster: %w", err)
+	}
+
+	var loadBalancerServices []corev1.Service
+	for _, service := range services.Items {
+		if service.Spec.Type == corev1.ServiceTypeLoadBalancer {
+			loadBalancerServices = append(loadBalancerServices, service)
+		}
+	}
+	return loadBalancerServices, nil
+}
+
+// loadBalancerServiceToOpenStackCluster maps the Services of the workload cluster of openStackCluster to it.
+func loadBalancerServiceToOpenStackCluster(openStackCluster *infrav1.OpenStackCluster) handler.MapFunc {
+	request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(openStackCluster)}
+	return func(context.Context, client.Object) []reconcile.Request {
+		return []reconcile.Request{request}
+	}
+}
+
+// loadBalancerServicePredicate filters the events of the Services which are, or were, of type LoadBalancer.
+func loadBalancerServicePredicate() predicate.Funcs {
+	isLoadBalancer := func(o client.Object) bool {
+		service, ok := o.(*corev1.Service)
+		return ok && service.Spec.Type == corev1.ServiceTypeLoadBalancer
+	}
+	return predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return isLoadBalancer(e.Object) },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return isLoadBalancer(e.Object) },
+		GenericFunc: func(e event.GenericEvent) bool { return isLoadBalancer(e.Object) },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return isLoadBalancer(e.ObjectOld) || isLoadBalancer(e.ObjectNew)
+		},
+	}
+}
+
+// getSecurityGroupGoldenRules returns the data of the ConfigMap named by the security group golden rules
+// annotation of the cluster, if any. A missing ConfigMap is warned about rather than failing the reconcile.
+func (r *OpenStackClusterReconciler) getSecurityGroupGoldenRules(ctx context.Context, openStackCluster *infrav1.OpenStackCluster) (map[string]string, error) {
+	name, ok := openStackCluster.Annotations[infrav1.SecurityGroupGoldenRulesAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	configMap := &corev1.ConfigMap{}
+	if err := r.Client.Get(ctx, client.ObjectKey{Namespace: openStackCluster.Namespace, Name: name}, configMap); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Recorder.Eventf(openStackCluster, corev1.EventTypeWarning, "SecurityGroupGoldenRulesNotFound", "ConfigMap %s holding the golden security group rules was not found", name)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get security group golden rules: %w", err)
+	}
+	return configMap.Data, nil
 }
 
 func (r *OpenStackClusterReconciler) reconcileDelete(ctx context.Context, scope *scope.WithLogger, cluster *clusterv1.Cluster, openStackCluster *infrav1.OpenStackCluster) (ctrl.Result, error) {
@@ -172,11 +291,11 @@ func (r *OpenStackClusterReconciler) reconcileDelete(ctx context.Context, scope
 		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
 	}
 
-	if err := deleteBastion(scope, cluster, openStackCluster); err != nil {
+	if err := deleteBastion(scope, r.NetworkingOptions, cluster, openStackCluster); err != nil {
 		return reconcile.Result{}, err
 	}
 
-	networkingService, err := networking.NewService(scope)
+	networkingService, err := networking.NewService(scope, r.NetworkingOptions)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
@@ -184,7 +303,7 @@ func (r *OpenStackClusterReconciler) reconcileDelete(ctx context.Context, scope
 	clusterName := fmt.Sprintf("%s-%s", cluster.Namespace, cluster.Name)
 
 	if openStackCluster.Spec.APIServerLoadBalancer.Enabled {
-		loadBalancerService, err := loadbalancer.NewService(scope)
+		loadBalancerService, err := loadbalancer.NewService(scope, r.NetworkingOptions)
 		if err != nil {
 			return reconcile.Result{}, err
 		}
@@ -213,7 +332,7 @@ func (r *OpenStackClusterReconciler) reconcileDelete(ctx context.Context, scope
 		}
 	}
 
-	if err = networkingService.DeleteSecurityGroups(openStackCluster, clusterName); err != nil {
+	if err = networkingService.DeleteSecurityGroups(ctx, openStackCluster, clusterName); err != nil {
 		handleUpdateOSCError(openStackCluster, fmt.Errorf("failed to delete security groups: %w", err))
 		return reconcile.Result{}, fmt.Errorf("failed to delete security groups: %w", err)
 	}
@@ -233,33 +352,52 @@ func contains(arr []string, target string) bool {
 	return false
 }
 
-func deleteBastion(scope *scope.WithLogger, cluster *clusterv1.Cluster, openStackCluster *infrav1.OpenStackCluster) error {
+// deleteBastion deletes all the bastion replicas, starting from the last one.
+func deleteBastion(scope *scope.WithLogger, networkingOptions networking.Options, cluster *clusterv1.Cluster, openStackCluster *infrav1.OpenStackCluster) error {
 	scope.Logger().Info("Deleting Bastion")
 
-	computeService, err := compute.NewService(scope)
+	// The replicas are also looked up by name, in case their status was not saved.
+	replicas := len(openStackCluster.Status.AdditionalBastions) + 1
+	if replicas < bastionReplicas(openStackCluster) {
+		replicas = bastionReplicas(openStackCluster)
+	}
+	for i := replicas - 1; i >= 0; i-- {
+		if err := deleteBastionReplica(scope, networkingOptions, cluster, openStackCluster, i); err != nil {
+			return err
+		}
+	}
+
+	delete(openStackCluster.ObjectMeta.Annotations, BastionInstanceHashAnnotation)
+
+	return nil
+}
+
+func deleteBastionReplica(scope *scope.WithLogger, networkingOptions networking.Options, cluster *clusterv1.Cluster, openStackCluster *infrav1.OpenStackCluster, replica int) error {
+	computeService, err := compute.NewService(scope, networkingOptions)
 	if err != nil {
 		return err
 	}
-	networkingService, err := networking.NewService(scope)
+	networkingService, err := networking.NewService(scope, networkingOptions)
 	if err != nil {
 		return err
 	}
 
-	if openStackCluster.Status.Bastion != nil && openStackCluster.Status.Bastion.FloatingIP != "" {
-		if err = networkingService.DeleteFloatingIP(openStackCluster, openStackCluster.Status.Bastion.FloatingIP); err != nil {
+	bastionStatus := getBastionReplicaStatus(openStackCluster, replica)
+	if bastionStatus != nil && bastionStatus.FloatingIP != "" {
+		if err = networkingService.DeleteFloatingIP(openStackCluster, bastionStatus.FloatingIP); err != nil {
 			handleUpdateOSCError(openStackCluster, fmt.Errorf("failed to delete floating IP: %w", err))
 			return fmt.Errorf("failed to delete floating IP: %w", err)
 		}
 	}
 
 	var instanceStatus *compute.InstanceStatus
-	if openStackCluster.Status.Bastion != nil && openStackCluster.Status.Bastion.ID != "" {
-		instanceStatus, err = computeService.GetInstanceStatus(openStackCluster.Status.Bastion.ID)
+	if bastionStatus != nil && bastionStatus.ID != "" {
+		instanceStatus, err = computeService.GetInstanceStatus(bastionStatus.ID)
 		if err != nil {
 			return err
 		}
 	} else {
-		instanceStatus, err = computeService.GetInstanceStatusByName(openStackCluster, bastionName(cluster.Name))
+		instanceStatus, err = computeService.GetInstanceStatusByName(openStackCluster, bastionReplicaName(cluster.Name, replica))
 		if err != nil {
 			return err
 		}
@@ -282,7 +420,7 @@ func deleteBastion(scope *scope.WithLogger, cluster *clusterv1.Cluster, openStac
 			}
 		}
 
-		instanceSpec, err := bastionToInstanceSpec(openStackCluster, cluster)
+		instanceSpec, err := bastionToInstanceSpec(openStackCluster, cluster, replica)
 		if err != nil {
 			return err
 		}
@@ -292,29 +430,28 @@ func deleteBastion(scope *scope.WithLogger, cluster *clusterv1.Cluster, openStac
 		}
 	}
 
-	if openStackCluster.Status.Bastion != nil && len(openStackCluster.Status.Bastion.DependentResources.PortsStatus) > 0 {
+	if bastionStatus != nil && len(bastionStatus.DependentResources.PortsStatus) > 0 {
 		trunkSupported, err := networkingService.IsTrunkExtSupported()
 		if err != nil {
 			return err
 		}
-		for _, port := range openStackCluster.Status.Bastion.DependentResources.PortsStatus {
+		for _, port := range bastionStatus.DependentResources.PortsStatus {
 			if err := networkingService.DeleteInstanceTrunkAndPort(openStackCluster, port, trunkSupported); err != nil {
 				handleUpdateOSCError(openStackCluster, fmt.Errorf("failed to delete port: %w", err))
 				return fmt.Errorf("failed to delete port: %w", err)
 			}
 		}
-		openStackCluster.Status.Bastion.DependentResources.PortsStatus = nil
+		bastionStatus.DependentResources.PortsStatus = nil
 	}
 
 	scope.Logger().Info("Deleted Bastion for cluster %s", cluster.Name)
 
-	openStackCluster.Status.Bastion = nil
-	delete(openStackCluster.ObjectMeta.Annotations, BastionInstanceHashAnnotation)
+	clearBastionReplicaStatus(openStackCluster, replica)
 
 	return nil
 }
 
-func reconcileNormal(scope *scope.WithLogger, cluster *clusterv1.Cluster, openStackCluster *infrav1.OpenStackCluster) (ctrl.Result, error) { //nolint:unparam
+func reconcileNormal(ctx context.Context, scope *scope.WithLogger, networkingOptions networking.Options, cluster *clusterv1.Cluster, openStackCluster *infrav1.OpenStackCluster, loadBalancerServices []corev1.Service, goldenRules map[string]string) (ctrl.Result, error) { //nolint:unparam
 	scope.Logger().Info("Reconciling Cluster")
 
 	// If the OpenStackCluster doesn't have our finalizer, add it.
@@ -323,17 +460,17 @@ func reconcileNormal(scope *scope.WithLogger, cluster *clusterv1.Cluster, openSt
 		return reconcile.Result{}, nil
 	}
 
-	computeService, err := compute.NewService(scope)
+	computeService, err := compute.NewService(scope, networkingOptions)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
 
-	err = reconcileNetworkComponents(scope, cluster, openStackCluster)
+	err = reconcileNetworkComponents(ctx, scope, networkingOptions, cluster, openStackCluster, loadBalancerServices, goldenRules)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
 
-	result, err := reconcileBastion(scope, cluster, openStackCluster)
+	result, err := reconcileBastion(scope, networkingOptions, cluster, openStackCluster)
 	if err != nil || !reflect.DeepEqual(result, reconcile.Result{}) {
 		return result, err
 	}
@@ -365,11 +502,11 @@ func reconcileNormal(scope *scope.WithLogger, cluster *clusterv1.Cluster, openSt
 	return reconcile.Result{}, nil
 }
 
-func reconcileBastion(scope *scope.WithLogger, cluster *clusterv1.Cluster, openStackCluster *infrav1.OpenStackCluster) (ctrl.Result, error) {
+func reconcileBastion(scope *scope.WithLogger, networkingOptions networking.Options, cluster *clusterv1.Cluster, openStackCluster *infrav1.OpenStackCluster) (ctrl.Result, error) {
 	scope.Logger().Info("Reconciling Bastion")
 
 	if openStackCluster.Spec.Bastion == nil || !openStackCluster.Spec.Bastion.Enabled {
-		return reconcile.Result{}, deleteBastion(scope, cluster, openStackCluster)
+		return reconcile.Result{}, deleteBastion(scope, networkingOptions, cluster, openStackCluster)
 	}
 
 	// If ports options aren't in the status, we'll re-trigger the reconcile to get them
@@ -378,41 +515,71 @@ func reconcileBastion(scope *scope.WithLogger, cluster *clusterv1.Cluster, openS
 		return reconcile.Result{}, nil
 	}
 
-	computeService, err := compute.NewService(scope)
-	if err != nil {
-		return reconcile.Result{}, err
-	}
-
-	networkingService, err := networking.NewService(scope)
-	if err != nil {
-		return reconcile.Result{}, err
+	// The removed replicas are deleted, starting from the last one.
+	for i := len(openStackCluster.Status.AdditionalBastions); i >= 1 && i >= bastionReplicas(openStackCluster); i-- {
+		if err := deleteBastionReplica(scope, networkingOptions, cluster, openStackCluster, i); err != nil {
+			return reconcile.Result{}, err
+		}
 	}
 
-	instanceSpec, err := bastionToInstanceSpec(openStackCluster, cluster)
+	// All the replicas have the instance spec of the first one, but for their name and security group.
+	instanceSpec, err := bastionToInstanceSpec(openStackCluster, cluster, 0)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
-	clusterName := fmt.Sprintf("%s-%s", cluster.Namespace, cluster.Name)
 	bastionHash, err := compute.HashInstanceSpec(instanceSpec)
 	if err != nil {
 		return reconcile.Result{}, fmt.Errorf("failed computing bastion hash from instance spec: %w", err)
 	}
 	if bastionHashHasChanged(bastionHash, openStackCluster.ObjectMeta.Annotations) {
-		if err := deleteBastion(scope, cluster, openStackCluster); err != nil {
+		if err := deleteBastion(scope, networkingOptions, cluster, openStackCluster); err != nil {
 			return ctrl.Result{}, err
 		}
 	}
 
-	err = getOrCreateBastionPorts(scope, cluster, openStackCluster, networkingService, cluster.Name)
+	for i := 0; i < bastionReplicas(openStackCluster); i++ {
+		result, err := reconcileBastionReplica(scope, networkingOptions, cluster, openStackCluster, i, bastionHash)
+		if err != nil || !reflect.DeepEqual(result, reconcile.Result{}) {
+			return result, err
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// reconcileBastionReplica reconciles the instance, the ports and the floating IP of the bastion replica with the
+// given index.
+func reconcileBastionReplica(scope *scope.WithLogger, networkingOptions networking.Options, cluster *clusterv1.Cluster, openStackCluster *infrav1.OpenStackCluster, replica int, bastionHash string) (ctrl.Result, error) {
+	computeService, err := compute.NewService(scope, networkingOptions)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	networkingService, err := networking.NewService(scope, networkingOptions)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	bastionStatus := initBastionReplicaStatus(openStackCluster, replica)
+	if replica > 0 {
+		bastionStatus.ReferencedResources = *openStackCluster.Status.Bastion.ReferencedResources.DeepCopy()
+	}
+
+	instanceSpec, err := bastionToInstanceSpec(openStackCluster, cluster, replica)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	clusterName := fmt.Sprintf("%s-%s", cluster.Namespace, cluster.Name)
+
+	err = getOrCreateBastionPorts(scope, cluster, openStackCluster, networkingService, cluster.Name, replica)
 	if err != nil {
 		handleUpdateOSCError(openStackCluster, fmt.Errorf("failed to get or create ports for bastion: %w", err))
 		return ctrl.Result{}, fmt.Errorf("failed to get or create ports for bastion: %w", err)
 	}
-	bastionPortIDs := GetPortIDs(openStackCluster.Status.Bastion.DependentResources.PortsStatus)
+	bastionPortIDs := GetPortIDs(bastionStatus.DependentResources.PortsStatus)
 
 	var instanceStatus *compute.InstanceStatus
-	if openStackCluster.Status.Bastion != nil && openStackCluster.Status.Bastion.ID != "" {
-		if instanceStatus, err = computeService.GetInstanceStatus(openStackCluster.Status.Bastion.ID); err != nil {
+	if bastionStatus.ID != "" {
+		if instanceStatus, err = computeService.GetInstanceStatus(bastionStatus.ID); err != nil {
 			return reconcile.Result{}, err
 		}
 	}
@@ -430,7 +597,7 @@ func reconcileBastion(scope *scope.WithLogger, cluster *clusterv1.Cluster, openS
 	}
 
 	// Save hash & status as soon as we know we have an instance
-	instanceStatus.UpdateBastionStatus(openStackCluster)
+	instanceStatus.UpdateBastionReplicaStatus(openStackCluster, bastionStatus)
 	annotations.AddAnnotations(openStackCluster, map[string]string{BastionInstanceHashAnnotation: bastionHash})
 
 	// Make sure that bastion instance has a valid state
@@ -442,7 +609,7 @@ func reconcileBastion(scope *scope.WithLogger, cluster *clusterv1.Cluster, openS
 		return ctrl.Result{RequeueAfter: waitForBuildingInstanceToReconcile}, nil
 	case infrav1.InstanceStateDeleted:
 		// This should normally be handled by deleteBastion
-		openStackCluster.Status.Bastion = nil
+		clearBastionReplicaStatus(openStackCluster, replica)
 		return ctrl.Result{}, nil
 	}
 
@@ -459,14 +626,18 @@ func reconcileBastion(scope *scope.WithLogger, cluster *clusterv1.Cluster, openS
 	}
 	if fp != nil {
 		// Floating IP is already attached to bastion, no need to proceed
-		openStackCluster.Status.Bastion.FloatingIP = fp.FloatingIP
+		bastionStatus.FloatingIP = fp.FloatingIP
 		return ctrl.Result{}, nil
 	}
 
-	floatingIP := openStackCluster.Spec.Bastion.FloatingIP
-	if openStackCluster.Status.Bastion.FloatingIP != "" {
+	// The floating IP of the spec is associated to the first replica, the other ones get a new floating IP.
+	var floatingIP string
+	if replica == 0 {
+		floatingIP = openStackCluster.Spec.Bastion.FloatingIP
+	}
+	if bastionStatus.FloatingIP != "" {
 		// Some floating IP has already been created for this bastion, make sure we re-use it
-		floatingIP = openStackCluster.Status.Bastion.FloatingIP
+		floatingIP = bastionStatus.FloatingIP
 	}
 	// Check if there is an existing floating IP attached to bastion, in case where FloatingIP would not yet have been stored in cluster status
 	fp, err = networkingService.GetOrCreateFloatingIP(openStackCluster, openStackCluster, clusterName, floatingIP)
@@ -474,7 +645,7 @@ func reconcileBastion(scope *scope.WithLogger, cluster *clusterv1.Cluster, openS
 		handleUpdateOSCError(openStackCluster, fmt.Errorf("failed to get or create floating IP for bastion: %w", err))
 		return ctrl.Result{}, fmt.Errorf("failed to get or create floating IP for bastion: %w", err)
 	}
-	openStackCluster.Status.Bastion.FloatingIP = fp.FloatingIP
+	bastionStatus.FloatingIP = fp.FloatingIP
 
 	err = networkingService.AssociateFloatingIP(openStackCluster, fp, port.ID)
 	if err != nil {
@@ -485,32 +656,25 @@ func reconcileBastion(scope *scope.WithLogger, cluster *clusterv1.Cluster, openS
 	return ctrl.Result{}, nil
 }
 
-func bastionToInstanceSpec(openStackCluster *infrav1.OpenStackCluster, cluster *clusterv1.Cluster) (*compute.InstanceSpec, error) {
+func bastionToInstanceSpec(openStackCluster *infrav1.OpenStackCluster, cluster *clusterv1.Cluster, replica int) (*compute.InstanceSpec, error) {
 	if openStackCluster.Spec.Bastion == nil {
 		return nil, fmt.Errorf("bastion spec is nil")
 	}
 
-	if openStackCluster.Status.Bastion == nil {
+	bastionStatus := getBastionReplicaStatus(openStackCluster, replica)
+	if bastionStatus == nil {
 		return nil, fmt.Errorf("bastion status is nil")
 	}
 	instanceSpec := &compute.InstanceSpec{
-		Name:          bastionName(cluster.Name),
+		Name:          bastionReplicaName(cluster.Name, replica),
 		Flavor:        openStackCluster.Spec.Bastion.Instance.Flavor,
 		SSHKeyName:    openStackCluster.Spec.Bastion.Instance.SSHKeyName,
-		ImageID:       openStackCluster.Status.Bastion.ReferencedResources.ImageID,
+		ImageID:       bastionStatus.ReferencedResources.ImageID,
 		FailureDomain: openStackCluster.Spec.Bastion.AvailabilityZone,
 		RootVolume:    openStackCluster.Spec.Bastion.Instance.RootVolume,
 	}
 
-	instanceSpec.SecurityGroups = openStackCluster.Spec.Bastion.Instance.SecurityGroups
-	if openStackCluster.Spec.ManagedSecurityGroups != nil {
-		if openStackCluster.Status.BastionSecurityGroup != nil {
-			instanceSpec.SecurityGroups = append(instanceSpec.SecurityGroups, infrav1.SecurityGroupFilter{
-				ID: openStackCluster.Status.BastionSecurityGroup.ID,
-			})
-		}
-	}
-	instanceSpec.SecurityGroups = getBastionSecurityGroups(openStackCluster)
+	instanceSpec.SecurityGroups = getBastionSecurityGroups(openStackCluster, replica)
 
 	instanceSpec.Ports = openStackCluster.Spec.Bastion.Instance.Ports
 
@@ -521,9 +685,69 @@ func bastionName(clusterName string) string {
 	return fmt.Sprintf("%s-bastion", clusterName)
 }
 
+// bastionReplicaName returns the name of the bastion replica with the given index. The first replica has the
+// name of a single bastion.
+func bastionReplicaName(clusterName string, replica int) string {
+	if replica == 0 {
+		return bastionName(clusterName)
+	}
+	return fmt.Sprintf("%s-%d", bastionName(clusterName), replica)
+}
+
+// bastionReplicas returns the number of bastion replicas of the cluster, 0 when the bastion is disabled.
+func bastionReplicas(openStackCluster *infrav1.OpenStackCluster) int {
+	if openStackCluster.Spec.Bastion == nil || !openStackCluster.Spec.Bastion.Enabled {
+		return 0
+	}
+	return pointer.IntDeref(openStackCluster.Spec.Bastion.Replicas, 1)
+}
+
+// getBastionReplicaStatus returns the status of the bastion replica with the given index, nil if it has none.
+func getBastionReplicaStatus(openStackCluster *infrav1.OpenStackCluster, replica int) *infrav1.BastionStatus {
+	if replica == 0 {
+		return openStackCluster.Status.Bastion
+	}
+	if replica > len(openStackCluster.Status.AdditionalBastions) {
+		return nil
+	}
+	return &openStackCluster.Status.AdditionalBastions[replica-1]
+}
+
+// initBastionReplicaStatus returns the status of the bastion replica with the given index, initializing it if it
+// has none. The statuses of the replicas after the first are listed by index, so it may initialize the statuses
+// of the replicas before it too.
+func initBastionReplicaStatus(openStackCluster *infrav1.OpenStackCluster, replica int) *infrav1.BastionStatus {
+	if replica == 0 {
+		if openStackCluster.Status.Bastion == nil {
+			openStackCluster.Status.Bastion = &infrav1.BastionStatus{}
+		}
+		return openStackCluster.Status.Bastion
+	}
+	for len(openStackCluster.Status.AdditionalBastions) < replica {
+		openStackCluster.Status.AdditionalBastions = append(openStackCluster.Status.AdditionalBastions, infrav1.BastionStatus{})
+	}
+	return &openStackCluster.Status.AdditionalBastions[replica-1]
+}
+
+// clearBastionReplicaStatus clears the status of the bastion replica with the given index. The status of the last
+// replica is removed from the list of the statuses of the replicas after the first.
+func clearBastionReplicaStatus(openStackCluster *infrav1.OpenStackCluster, replica int) {
+	switch {
+	case replica == 0:
+		openStackCluster.Status.Bastion = nil
+	case replica == len(openStackCluster.Status.AdditionalBastions):
+		openStackCluster.Status.AdditionalBastions = openStackCluster.Status.AdditionalBastions[:replica-1]
+		if len(openStackCluster.Status.AdditionalBastions) == 0 {
+			openStackCluster.Status.AdditionalBastions = nil
+		}
+	case replica < len(openStackCluster.Status.AdditionalBastions):
+		openStackCluster.Status.AdditionalBastions[replica-1] = infrav1.BastionStatus{}
+	}
+}
+
 // getBastionSecurityGroups returns a combination of openStackCluster.Spec.Bastion.Instance.SecurityGroups
-// and the security group managed by the OpenStackCluster.
-func getBastionSecurityGroups(openStackCluster *infrav1.OpenStackCluster) []infrav1.SecurityGroupFilter {
+// and the security group managed by the OpenStackCluster for the bastion replica with the given index.
+func getBastionSecurityGroups(openStackCluster *infrav1.OpenStackCluster, replica int) []infrav1.SecurityGroupFilter {
 	instanceSpecSecurityGroups := openStackCluster.Spec.Bastion.Instance.SecurityGroups
 
 	if openStackCluster.Spec.ManagedSecurityGroups == nil {
@@ -531,8 +755,12 @@ func getBastionSecurityGroups(openStackCluster *infrav1.OpenStackCluster) []infr
 	}
 
 	var managedSecurityGroup string
-	if openStackCluster.Status.BastionSecurityGroup != nil {
-		managedSecurityGroup = openStackCluster.Status.BastionSecurityGroup.ID
+	if replica == 0 {
+		if openStackCluster.Status.BastionSecurityGroup != nil {
+			managedSecurityGroup = openStackCluster.Status.BastionSecurityGroup.ID
+		}
+	} else if replica <= len(openStackCluster.Status.AdditionalBastionSecurityGroups) {
+		managedSecurityGroup = openStackCluster.Status.AdditionalBastionSecurityGroups[replica-1].ID
 	}
 
 	if managedSecurityGroup != "" {
@@ -543,34 +771,34 @@ func getBastionSecurityGroups(openStackCluster *infrav1.OpenStackCluster) []infr
 	return instanceSpecSecurityGroups
 }
 
-func getOrCreateBastionPorts(scope *scope.WithLogger, cluster *clusterv1.Cluster, openStackCluster *infrav1.OpenStackCluster, networkingService *networking.Service, clusterName string) error {
-	scope.Logger().Info("Reconciling ports for bastion", "bastion", bastionName(openStackCluster.Name))
+func getOrCreateBastionPorts(scope *scope.WithLogger, cluster *clusterv1.Cluster, openStackCluster *infrav1.OpenStackCluster, networkingService *networking.Service, clusterName string, replica int) error {
+	name := bastionReplicaName(cluster.Name, replica)
+	scope.Logger().Info("Reconciling ports for bastion", "bastion", name)
 
-	if openStackCluster.Status.Bastion == nil {
-		openStackCluster.Status.Bastion = &infrav1.BastionStatus{}
-	}
+	bastionStatus := initBastionReplicaStatus(openStackCluster, replica)
 
-	desiredPorts := openStackCluster.Status.Bastion.ReferencedResources.PortsOpts
-	portsToCreate := networking.MissingPorts(openStackCluster.Status.Bastion.DependentResources.PortsStatus, desiredPorts)
+	desiredPorts := bastionStatus.ReferencedResources.PortsOpts
+	portsToCreate := networking.MissingPorts(bastionStatus.DependentResources.PortsStatus, desiredPorts)
 
 	// Sanity check that the number of desired ports is equal to the addition of ports to create and ports that already exist.
-	if len(desiredPorts) != len(portsToCreate)+len(openStackCluster.Status.Bastion.DependentResources.PortsStatus) {
-		return fmt.Errorf("length of desired ports (%d) is not equal to the length of ports to create (%d) + the length of ports that already exist (%d)", len(desiredPorts), len(portsToCreate), len(openStackCluster.Status.Bastion.DependentResources.PortsStatus))
+	if len(desiredPorts) != len(portsToCreate)+len(bastionStatus.DependentResources.PortsStatus) {
+		return fmt.Errorf("length of desired ports (%d) is not equal to the length of ports to create (%d) + the length of ports that already exist (%d)", len(desiredPorts), len(portsToCreate), len(bastionStatus.DependentResources.PortsStatus))
 	}
 
 	if len(portsToCreate) > 0 {
-		securityGroups := getBastionSecurityGroups(openStackCluster)
-		bastionPortsStatus, err := networkingService.CreatePorts(openStackCluster, clusterName, portsToCreate, securityGroups, []string{}, bastionName(cluster.Name))
+		securityGroups := getBastionSecurityGroups(openStackCluster, replica)
+		portsToCreate = networking.ExcludeManagedSecurityGroups(portsToCreate, openStackCluster.Spec.Bastion.Instance.SecurityGroups)
+		bastionPortsStatus, err := networkingService.CreatePorts(openStackCluster, clusterName, portsToCreate, securityGroups, []string{}, name)
 		if err != nil {
-			return fmt.Errorf("failed to create ports for bastion %s: %w", bastionName(openStackCluster.Name), err)
+			return fmt.Errorf("failed to create ports for bastion %s: %w", name, err)
 		}
 
-		openStackCluster.Status.Bastion.DependentResources.PortsStatus = append(openStackCluster.Status.Bastion.DependentResources.PortsStatus, bastionPortsStatus...)
+		bastionStatus.DependentResources.PortsStatus = append(bastionStatus.DependentResources.PortsStatus, bastionPortsStatus...)
 	}
 
 	// Sanity check that the number of ports that have been put into PortsStatus is equal to the number of desired ports now that we have created them all.
-	if len(openStackCluster.Status.Bastion.DependentResources.PortsStatus) != len(desiredPorts) {
-		return fmt.Errorf("length of ports that already exist (%d) is not equal to the length of desired ports (%d)", len(openStackCluster.Status.Bastion.DependentResources.PortsStatus), len(desiredPorts))
+	if len(bastionStatus.DependentResources.PortsStatus) != len(desiredPorts) {
+		return fmt.Errorf("length of ports that already exist (%d) is not equal to the length of desired ports (%d)", len(bastionStatus.DependentResources.PortsStatus), len(desiredPorts))
 	}
 
 	return nil
@@ -585,10 +813,10 @@ func bastionHashHasChanged(computeHash string, clusterAnnotations map[string]str
 	return latestHash != computeHash
 }
 
-func reconcileNetworkComponents(scope *scope.WithLogger, cluster *clusterv1.Cluster, openStackCluster *infrav1.OpenStackCluster) error {
+func reconcileNetworkComponents(ctx context.Context, scope *scope.WithLogger, networkingOptions networking.Options, cluster *clusterv1.Cluster, openStackCluster *infrav1.OpenStackCluster, loadBalancerServices []corev1.Service, goldenRules map[string]string) error {
 	clusterName := fmt.Sprintf("%s-%s", cluster.Namespace, cluster.Name)
 
-	networkingService, err := networking.NewService(scope)
+	networkingService, err := networking.NewService(scope, networkingOptions)
 	if err != nil {
 		return err
 	}
@@ -613,13 +841,18 @@ func reconcileNetworkComponents(scope *scope.WithLogger, cluster *clusterv1.Clus
 		return fmt.Errorf("failed to reconcile network: ManagedSubnets only supports one element, %d provided", len(openStackCluster.Spec.ManagedSubnets))
 	}
 
-	err = networkingService.ReconcileSecurityGroups(openStackCluster, clusterName)
+	networkingService.SetLoadBalancerServices(loadBalancerServices)
+	networkingService.SetGoldenRules(goldenRules)
+	err = networkingService.ReconcileSecurityGroups(ctx, openStackCluster, clusterName)
 	if err != nil {
-		handleUpdateOSCError(openStackCluster, fmt.Errorf("failed to reconcile security groups: %w", err))
+		// The failure is only terminal once the attempts are exhausted, until then it is retried.
+		if errors.Is(err, networking.ErrSecurityGroupReconcileAttemptsExhausted) {
+			handleUpdateOSCError(openStackCluster, fmt.Errorf("failed to reconcile security groups: %w", err))
+		}
 		return fmt.Errorf("failed to reconcile security groups: %w", err)
 	}
 
-	return reconcileControlPlaneEndpoint(scope, networkingService, openStackCluster, clusterName)
+	return reconcileControlPlaneEndpoint(scope, networkingOptions, networkingService, openStackCluster, clusterName)
 }
 
 // reconcilePreExistingNetworkComponents reconciles the cluster network status when the cluster is
@@ -706,7 +939,7 @@ func reconcileProvisionedNetworkComponents(networkingService *networking.Service
 // reconcileControlPlaneEndpoint configures the control plane endpoint for the
 // cluster, creating it if necessary, and updates ControlPlaneEndpoint in the
 // cluster spec.
-func reconcileControlPlaneEndpoint(scope *scope.WithLogger, networkingService *networking.Service, openStackCluster *infrav1.OpenStackCluster, clusterName string) error {
+func reconcileControlPlaneEndpoint(scope *scope.WithLogger, networkingOptions networking.Options, networkingService *networking.Service, openStackCluster *infrav1.OpenStackCluster, clusterName string) error {
 	// Calculate the port that we will use for the API server
 	apiServerPort := getAPIServerPort(openStackCluster)
 
@@ -718,7 +951,7 @@ func reconcileControlPlaneEndpoint(scope *scope.WithLogger, networkingService *n
 	// Note that we reconcile the load balancer even if the control plane
 	// endpoint is already set.
 	case openStackCluster.Spec.APIServerLoadBalancer.Enabled:
-		loadBalancerService, err := loadbalancer.NewService(scope)
+		loadBalancerService, err := loadbalancer.NewService(scope, networkingOptions)
 		if err != nil {
 			return err
 		}
@@ -792,7 +1025,7 @@ func (r *OpenStackClusterReconciler) SetupWithManager(ctx context.Context, mgr c
 	clusterToInfraFn := util.ClusterToInfrastructureMapFunc(ctx, infrav1.GroupVersion.WithKind("OpenStackCluster"), mgr.GetClient(), &infrav1.OpenStackCluster{})
 	log := ctrl.LoggerFrom(ctx)
 
-	return ctrl.NewControllerManagedBy(mgr).
+	c, err := ctrl.NewControllerManagedBy(mgr).
 		WithOptions(options).
 		For(&infrav1.OpenStackCluster{},
 			builder.WithPredicates(
@@ -834,7 +1067,12 @@ func (r *OpenStackClusterReconciler) SetupWithManager(ctx context.Context, mgr c
 		).
 		WithEventFilter(predicates.ResourceNotPausedAndHasFilterLabel(ctrl.LoggerFrom(ctx), r.WatchFilterValue)).
 		WithEventFilter(predicates.ResourceIsNotExternallyManaged(ctrl.LoggerFrom(ctx))).
-		Complete(r)
+		Build(r)
+	if err != nil {
+		return err
+	}
+	r.controller = c
+	return nil
 }
 
 func handleUpdateOSCError(openstackCluster *infrav1.OpenStackCluster, message error) {
@@ -32,17 +32,21 @@ import (
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/pointer"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
 	"sigs.k8s.io/cluster-api/test/framework"
 	"sigs.k8s.io/cluster-api/util/annotations"
 	"sigs.k8s.io/cluster-api/util/patch"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/clients"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/cloud/services/networking"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
 )
 
@@ -214,7 +218,7 @@ var _ = Describe("OpenStackCluster controller", func() {
 		computeClientRecorder := mockScopeFactory.ComputeClient.EXPECT()
 		computeClientRecorder.GetServer("bastion-uuid").Return(nil, gophercloud.ErrResourceNotFound{})
 
-		err = deleteBastion(scope, capiCluster, testCluster)
+		err = deleteBastion(scope, networking.DefaultOptions(), capiCluster, testCluster)
 		Expect(testCluster.Status.Bastion).To(BeNil())
 		Expect(err).To(BeNil())
 	})
@@ -278,7 +282,7 @@ var _ = Describe("OpenStackCluster controller", func() {
 
 		networkClientRecorder.ListFloatingIP(floatingips.ListOpts{PortID: "portID1"}).Return(make([]floatingips.FloatingIP, 1), nil)
 
-		res, err := reconcileBastion(scope, capiCluster, testCluster)
+		res, err := reconcileBastion(scope, networking.DefaultOptions(), capiCluster, testCluster)
 		Expect(testCluster.Status.Bastion).To(Equal(&infrav1.BastionStatus{
 			ID:    "adopted-bastion-uuid",
 			State: "ACTIVE",
@@ -362,7 +366,7 @@ var _ = Describe("OpenStackCluster controller", func() {
 
 		networkClientRecorder.ListFloatingIP(floatingips.ListOpts{PortID: "portID1"}).Return([]floatingips.FloatingIP{{FloatingIP: "1.2.3.4"}}, nil)
 
-		res, err := reconcileBastion(scope, capiCluster, testCluster)
+		res, err := reconcileBastion(scope, networking.DefaultOptions(), capiCluster, testCluster)
 		Expect(testCluster.Status.Bastion).To(Equal(&infrav1.BastionStatus{
 			ID:         "adopted-fip-bastion-uuid",
 			FloatingIP: "1.2.3.4",
@@ -442,7 +446,7 @@ var _ = Describe("OpenStackCluster controller", func() {
 		computeClientRecorder := mockScopeFactory.ComputeClient.EXPECT()
 		computeClientRecorder.GetServer("requeue-bastion-uuid").Return(&server, nil)
 
-		res, err := reconcileBastion(scope, capiCluster, testCluster)
+		res, err := reconcileBastion(scope, networking.DefaultOptions(), capiCluster, testCluster)
 		Expect(testCluster.Status.Bastion).To(Equal(&infrav1.BastionStatus{
 			ID:    "requeue-bastion-uuid",
 			State: "BUILD",
@@ -506,7 +510,7 @@ var _ = Describe("OpenStackCluster controller", func() {
 		computeClientRecorder.DeleteServer("delete-bastion-uuid").Return(nil)
 		computeClientRecorder.GetServer("delete-bastion-uuid").Return(nil, gophercloud.ErrResourceNotFound{})
 
-		err = deleteBastion(scope, capiCluster, testCluster)
+		err = deleteBastion(scope, networking.DefaultOptions(), capiCluster, testCluster)
 		Expect(err).To(BeNil())
 	})
 	It("should implicitly filter cluster subnets by cluster network", func() {
@@ -584,7 +588,7 @@ var _ = Describe("OpenStackCluster controller", func() {
 			},
 		}, nil)
 
-		err = reconcileNetworkComponents(scope, capiCluster, testCluster)
+		err = reconcileNetworkComponents(ctx, scope, networking.DefaultOptions(), capiCluster, testCluster, nil, nil)
 		Expect(err).To(BeNil())
 	})
 
@@ -668,7 +672,7 @@ var _ = Describe("OpenStackCluster controller", func() {
 			CIDR: "2001:db8:2222:5555::/64",
 		}, nil)
 
-		err = reconcileNetworkComponents(scope, capiCluster, testCluster)
+		err = reconcileNetworkComponents(ctx, scope, networking.DefaultOptions(), capiCluster, testCluster, nil, nil)
 		Expect(err).To(BeNil())
 		Expect(len(testCluster.Status.Network.Subnets)).To(Equal(2))
 	})
@@ -710,7 +714,7 @@ var _ = Describe("OpenStackCluster controller", func() {
 			ID: clusterNetworkID,
 		}, nil)
 
-		err = reconcileNetworkComponents(scope, capiCluster, testCluster)
+		err = reconcileNetworkComponents(ctx, scope, networking.DefaultOptions(), capiCluster, testCluster, nil, nil)
 		Expect(err).To(BeNil())
 		Expect(testCluster.Status.Network.ID).To(Equal(clusterNetworkID))
 	})
@@ -819,9 +823,97 @@ func TestGetBastionSecurityGroups(t *testing.T) {
 		},
 	}
 
-	securityGroups := getBastionSecurityGroups(openStackCluster)
+	securityGroups := getBastionSecurityGroups(openStackCluster, 0)
 
 	if !reflect.DeepEqual(securityGroups, expectedSecurityGroups) {
 		t.Errorf("Expected security groups %v, but got %v", expectedSecurityGroups, securityGroups)
 	}
 }
+
+func TestGetBastionReplicaSecurityGroups(t *testing.T) {
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			Bastion: &infrav1.Bastion{
+				Replicas: pointer.Int(3),
+			},
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{},
+		},
+		Status: infrav1.OpenStackClusterStatus{
+			BastionSecurityGroup: &infrav1.SecurityGroupStatus{ID: "sg-bastion"},
+			AdditionalBastionSecurityGroups: []infrav1.SecurityGroupStatus{
+				{ID: "sg-bastion-1"},
+			},
+		},
+	}
+
+	tests := []struct {
+		replica int
+		want    []infrav1.SecurityGroupFilter
+	}{
+		{replica: 0, want: []infrav1.SecurityGroupFilter{{ID: "sg-bastion"}}},
+		{replica: 1, want: []infrav1.SecurityGroupFilter{{ID: "sg-bastion-1"}}},
+		// The group of the last replica hasn't been created yet.
+		{replica: 2, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("replica %d", tt.replica), func(t *testing.T) {
+			if got := getBastionSecurityGroups(openStackCluster, tt.replica); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getBastionSecurityGroups() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBastionReplicaStatus(t *testing.T) {
+	g := NewWithT(t)
+	openStackCluster := &infrav1.OpenStackCluster{}
+
+	g.Expect(bastionReplicaName("mycluster", 0)).To(Equal("mycluster-bastion"))
+	g.Expect(bastionReplicaName("mycluster", 2)).To(Equal("mycluster-bastion-2"))
+
+	g.Expect(getBastionReplicaStatus(openStackCluster, 0)).To(BeNil())
+	g.Expect(getBastionReplicaStatus(openStackCluster, 2)).To(BeNil())
+
+	// Initializing the status of a replica initializes the statuses of the replicas before it.
+	initBastionReplicaStatus(openStackCluster, 0).ID = "id-0"
+	initBastionReplicaStatus(openStackCluster, 2).ID = "id-2"
+	g.Expect(openStackCluster.Status.Bastion.ID).To(Equal("id-0"))
+	g.Expect(openStackCluster.Status.AdditionalBastions).To(HaveLen(2))
+	g.Expect(getBastionReplicaStatus(openStackCluster, 1)).To(Equal(&infrav1.BastionStatus{}))
+	g.Expect(getBastionReplicaStatus(openStackCluster, 2).ID).To(Equal("id-2"))
+
+	// Clearing the status of a replica before the last one keeps the index of the others.
+	initBastionReplicaStatus(openStackCluster, 1).ID = "id-1"
+	clearBastionReplicaStatus(openStackCluster, 1)
+	g.Expect(openStackCluster.Status.AdditionalBastions).To(HaveLen(2))
+	g.Expect(getBastionReplicaStatus(openStackCluster, 1)).To(Equal(&infrav1.BastionStatus{}))
+
+	clearBastionReplicaStatus(openStackCluster, 2)
+	g.Expect(openStackCluster.Status.AdditionalBastions).To(HaveLen(1))
+	clearBastionReplicaStatus(openStackCluster, 1)
+	g.Expect(openStackCluster.Status.AdditionalBastions).To(BeNil())
+	clearBastionReplicaStatus(openStackCluster, 0)
+	g.Expect(openStackCluster.Status.Bastion).To(BeNil())
+}
+
+func TestLoadBalancerServiceEvents(t *testing.T) {
+	g := NewWithT(t)
+
+	loadBalancer := &corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer}}
+	clusterIP := &corev1.Service{Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP}}
+
+	// Only the events of the Services which are, or were, of type LoadBalancer are handled.
+	p := loadBalancerServicePredicate()
+	g.Expect(p.Create(event.CreateEvent{Object: loadBalancer})).To(BeTrue())
+	g.Expect(p.Create(event.CreateEvent{Object: clusterIP})).To(BeFalse())
+	g.Expect(p.Delete(event.DeleteEvent{Object: loadBalancer})).To(BeTrue())
+	g.Expect(p.Delete(event.DeleteEvent{Object: clusterIP})).To(BeFalse())
+	g.Expect(p.Update(event.UpdateEvent{ObjectOld: clusterIP, ObjectNew: loadBalancer})).To(BeTrue())
+	g.Expect(p.Update(event.UpdateEvent{ObjectOld: loadBalancer, ObjectNew: clusterIP})).To(BeTrue())
+	g.Expect(p.Update(event.UpdateEvent{ObjectOld: clusterIP, ObjectNew: clusterIP})).To(BeFalse())
+
+	// The events are mapped to the OpenStackCluster of the workload cluster.
+	openStackCluster := &infrav1.OpenStackCluster{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mycluster"}}
+	requests := loadBalancerServiceToOpenStackCluster(openStackCluster)(context.TODO(), loadBalancer)
+	g.Expect(requests).To(Equal([]reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "mycluster"}}}))
+}
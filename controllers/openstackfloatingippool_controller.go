@@ -67,6 +67,8 @@ type OpenStackFloatingIPPoolReconciler struct {
 	WatchFilterValue string
 	ScopeFactory     scope.Factory
 	CaCertificates   []byte // PEM encoded ca certificates.
+	// NetworkingOptions configure the networking services.
+	NetworkingOptions networking.Options
 
 	Scheme *runtime.Scheme
 }
@@ -218,7 +220,7 @@ func (r *OpenStackFloatingIPPoolReconciler) reconcileDelete(ctx context.Context,
 		return errors.New("waiting for IPAddress to be deleted, until we can delete the OpenStackFloatingIPPool")
 	}
 
-	networkingService, err := networking.NewService(scope)
+	networkingService, err := networking.NewService(scope, r.NetworkingOptions)
 	if err != nil {
 		return err
 	}
@@ -274,7 +276,7 @@ func (r *OpenStackFloatingIPPoolReconciler) reconcileIPAddresses(ctx context.Con
 		return err
 	}
 
-	networkingService, err := networking.NewService(scope)
+	networkingService, err := networking.NewService(scope, r.NetworkingOptions)
 	if err != nil {
 		return err
 	}
@@ -314,7 +316,7 @@ func (r *OpenStackFloatingIPPoolReconciler) getIP(ctx context.Context, scope *sc
 	// There's a potential leak of IPs here, if the reconcile loop fails after we claim an IP but before we create the IPAddress object.
 	var ip string
 
-	networkingService, err := networking.NewService(scope)
+	networkingService, err := networking.NewService(scope, r.NetworkingOptions)
 	if err != nil {
 		scope.Logger().Error(err, "Failed to create networking service")
 		return "", err
@@ -395,7 +397,7 @@ func (r *OpenStackFloatingIPPoolReconciler) reconcileFloatingIPNetwork(scope *sc
 		return nil
 	}
 
-	networkingService, err := networking.NewService(scope)
+	networkingService, err := networking.NewService(scope, r.NetworkingOptions)
 	if err != nil {
 		return err
 	}
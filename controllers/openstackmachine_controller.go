@@ -62,6 +62,8 @@ type OpenStackMachineReconciler struct {
 	WatchFilterValue string
 	ScopeFactory     scope.Factory
 	CaCertificates   []byte // PEM encoded ca certificates.
+	// NetworkingOptions configure the networking services.
+	NetworkingOptions networking.Options
 }
 
 const (
@@ -149,7 +151,7 @@ func (r *OpenStackMachineReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	scope := scope.NewWithLogger(clientScope, log)
 
 	// Resolve and store referenced resources
-	changed, err := compute.ResolveReferencedMachineResources(scope, infraCluster, &openStackMachine.Spec, &openStackMachine.Status.ReferencedResources)
+	changed, err := compute.ResolveReferencedMachineResources(scope, r.NetworkingOptions, infraCluster, &openStackMachine.Spec, &openStackMachine.Status.ReferencedResources)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
@@ -159,7 +161,7 @@ func (r *OpenStackMachineReconciler) Reconcile(ctx context.Context, req ctrl.Req
 	}
 
 	// Resolve and store dependent resources
-	changed, err = compute.ResolveDependentMachineResources(scope, openStackMachine)
+	changed, err = compute.ResolveDependentMachineResources(scope, r.NetworkingOptions, openStackMachine)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
@@ -246,18 +248,18 @@ func (r *OpenStackMachineReconciler) reconcileDelete(scope *scope.WithLogger, cl
 
 	clusterName := fmt.Sprintf("%s-%s", cluster.ObjectMeta.Namespace, cluster.Name)
 
-	computeService, err := compute.NewService(scope)
+	computeService, err := compute.NewService(scope, r.NetworkingOptions)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	networkingService, err := networking.NewService(scope)
+	networkingService, err := networking.NewService(scope, r.NetworkingOptions)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
 	if openStackCluster.Spec.APIServerLoadBalancer.Enabled {
-		loadBalancerService, err := loadbalancer.NewService(scope)
+		loadBalancerService, err := loadbalancer.NewService(scope, r.NetworkingOptions)
 		if err != nil {
 			return ctrl.Result{}, err
 		}
@@ -369,18 +371,26 @@ func (r *OpenStackMachineReconciler) reconcileNormal(ctx context.Context, scope
 
 	clusterName := fmt.Sprintf("%s-%s", cluster.ObjectMeta.Namespace, cluster.Name)
 
-	computeService, err := compute.NewService(scope)
+	computeService, err := compute.NewService(scope, r.NetworkingOptions)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
-	networkingService, err := networking.NewService(scope)
+	networkingService, err := networking.NewService(scope, r.NetworkingOptions)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
 
 	err = getOrCreateMachinePorts(scope, openStackCluster, machine, openStackMachine, networkingService, clusterName)
 	if err != nil {
+		// A security group referenced by the spec which doesn't exist is a configuration error, which retrying
+		// doesn't fix: the machine is failed rather than requeued.
+		var notFoundErr *networking.SecurityGroupNotFoundError
+		if errors.As(err, &notFoundErr) {
+			conditions.MarkFalse(openStackMachine, infrav1.InstanceReadyCondition, infrav1.InvalidMachineSpecReason, clusterv1.ConditionSeverityError, err.Error())
+			openStackMachine.SetFailure(capierrors.InvalidConfigurationMachineError, err)
+			return ctrl.Result{}, nil
+		}
 		return ctrl.Result{}, err
 	}
 	portIDs := GetPortIDs(openStackMachine.Status.DependentResources.PortsStatus)
@@ -398,6 +408,7 @@ func (r *OpenStackMachineReconciler) reconcileNormal(ctx context.Context, scope
 
 	state := instanceStatus.State()
 	openStackMachine.Status.InstanceState = &state
+	openStackMachine.Status.MachineNameMetadata = instanceStatus.Metadata()[infrav1.MachineNameMetadataKey]
 
 	instanceNS, err := instanceStatus.NetworkStatus()
 	if err != nil {
@@ -506,6 +517,7 @@ func getOrCreateMachinePorts(scope *scope.WithLogger, openStackCluster *infrav1.
 	if len(portsToCreate) > 0 {
 		instanceTags := getInstanceTags(openStackMachine, openStackCluster)
 		managedSecurityGroups := getManagedSecurityGroups(openStackCluster, machine, openStackMachine)
+		portsToCreate = networking.ExcludeManagedSecurityGroups(portsToCreate, openStackMachine.Spec.SecurityGroups)
 		machinePortsStatus, err = networkingService.CreatePorts(openStackMachine, clusterName, portsToCreate, managedSecurityGroups, instanceTags, openStackMachine.Name)
 		if err != nil {
 			return fmt.Errorf("create ports: %w", err)
@@ -563,6 +575,9 @@ func machineToInstanceSpec(openStackCluster *infrav1.OpenStackCluster, machine *
 		value := openStackMachine.Spec.ServerMetadata[i].Value
 		serverMetadata[key] = value
 	}
+	if openStackMachine.Spec.AddMachineNameMetadata {
+		serverMetadata[infrav1.MachineNameMetadataKey] = fmt.Sprintf("%s/%s", machine.Namespace, machine.Name)
+	}
 
 	instanceSpec := compute.InstanceSpec{
 		Name:                   openStackMachine.Name,
@@ -620,7 +635,8 @@ func getInstanceTags(openStackMachine *infrav1.OpenStackMachine, openStackCluste
 }
 
 // getManagedSecurityGroups returns a combination of OpenStackMachine.Spec.SecurityGroups
-// and the security group managed by the OpenStackCluster whether it's a control plane or a worker machine.
+// and the security group managed by the OpenStackCluster whether it's a control plane or a worker machine,
+// or the shared node security group when the OpenStackCluster has one, and the allNodes security group if any.
 func getManagedSecurityGroups(openStackCluster *infrav1.OpenStackCluster, machine *clusterv1.Machine, openStackMachine *infrav1.OpenStackMachine) []infrav1.SecurityGroupFilter {
 	machineSpecSecurityGroups := openStackMachine.Spec.SecurityGroups
 
@@ -629,7 +645,11 @@ func getManagedSecurityGroups(openStackCluster *infrav1.OpenStackCluster, machin
 	}
 
 	var managedSecurityGroup string
-	if util.IsControlPlaneMachine(machine) {
+	if openStackCluster.Spec.ManagedSecurityGroups.SharedNodeGroup {
+		if openStackCluster.Status.NodeSecurityGroup != nil {
+			managedSecurityGroup = openStackCluster.Status.NodeSecurityGroup.ID
+		}
+	} else if util.IsControlPlaneMachine(machine) {
 		if openStackCluster.Status.ControlPlaneSecurityGroup != nil {
 			managedSecurityGroup = openStackCluster.Status.ControlPlaneSecurityGroup.ID
 		}
@@ -645,12 +665,18 @@ func getManagedSecurityGroups(openStackCluster *infrav1.OpenStackCluster, machin
 		})
 	}
 
+	if openStackCluster.Status.AllNodesSecurityGroup != nil {
+		machineSpecSecurityGroups = append(machineSpecSecurityGroups, infrav1.SecurityGroupFilter{
+			ID: openStackCluster.Status.AllNodesSecurityGroup.ID,
+		})
+	}
+
 	return machineSpecSecurityGroups
 }
 
 func (r *OpenStackMachineReconciler) reconcileLoadBalancerMember(scope *scope.WithLogger, openStackCluster *infrav1.OpenStackCluster, openStackMachine *infrav1.OpenStackMachine, instanceNS *compute.InstanceNetworkStatus, clusterName string) error {
 	ip := instanceNS.IP(openStackCluster.Status.Network.Name)
-	loadbalancerService, err := loadbalancer.NewService(scope)
+	loadbalancerService, err := loadbalancer.NewService(scope, r.NetworkingOptions)
 	if err != nil {
 		return err
 	}
@@ -35,6 +35,8 @@ const (
 	extraSecurityGroupUUID        = "514bb2d8-3390-4a3b-86a7-7864ba57b329"
 	controlPlaneSecurityGroupUUID = "c9817a91-4821-42db-8367-2301002ab659"
 	workerSecurityGroupUUID       = "9c6c0d28-03c9-436c-815d-58440ac2c1c8"
+	nodeSecurityGroupUUID         = "4a1e5c37-6f0b-4c8e-9d2a-3b7f1e6c8d90"
+	allNodesSecurityGroupUUID     = "e0b7a3d2-91c4-4f58-8a6e-2d5c7b1f9e43"
 	serverGroupUUID               = "7b940d62-68ef-4e42-a76a-1a62e290509c"
 	imageUUID                     = "ce96e584-7ebc-46d6-9e55-987d72e3806c"
 
@@ -252,6 +254,27 @@ func Test_machineToInstanceSpec(t *testing.T) {
 				return i
 			},
 		},
+		{
+			name:             "Machine name metadata",
+			openStackCluster: getDefaultOpenStackCluster,
+			machine: func() *clusterv1.Machine {
+				m := getDefaultMachine()
+				m.Namespace = namespace
+				m.Name = "machine-name"
+				return m
+			},
+			openStackMachine: func() *infrav1.OpenStackMachine {
+				m := getDefaultOpenStackMachine()
+				m.Spec.AddMachineNameMetadata = true
+				m.Spec.ServerMetadata = append(m.Spec.ServerMetadata, infrav1.ServerMetadata{Key: infrav1.MachineNameMetadataKey, Value: "overridden"})
+				return m
+			},
+			wantInstanceSpec: func() *compute.InstanceSpec {
+				i := getDefaultInstanceSpec()
+				i.Metadata[infrav1.MachineNameMetadataKey] = namespace + "/machine-name"
+				return i
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -441,6 +464,56 @@ func Test_getManagedSecurityGroups(t *testing.T) {
 			openStackMachine:   getDefaultOpenStackMachine,
 			wantSecurityGroups: []infrav1.SecurityGroupFilter{},
 		},
+		{
+			name: "Control plane machine with shared node security group",
+			openStackCluster: func() *infrav1.OpenStackCluster {
+				c := getDefaultOpenStackCluster()
+				c.Spec.ManagedSecurityGroups = &infrav1.ManagedSecurityGroups{SharedNodeGroup: true}
+				c.Status.NodeSecurityGroup = &infrav1.SecurityGroupStatus{ID: nodeSecurityGroupUUID}
+				return c
+			},
+			machine: func() *clusterv1.Machine {
+				m := getDefaultMachine()
+				m.Labels = map[string]string{
+					clusterv1.MachineControlPlaneLabel: "true",
+				}
+				return m
+			},
+			openStackMachine: getDefaultOpenStackMachine,
+			wantSecurityGroups: []infrav1.SecurityGroupFilter{
+				{ID: nodeSecurityGroupUUID},
+			},
+		},
+		{
+			name: "Worker machine with shared node security group",
+			openStackCluster: func() *infrav1.OpenStackCluster {
+				c := getDefaultOpenStackCluster()
+				c.Spec.ManagedSecurityGroups = &infrav1.ManagedSecurityGroups{SharedNodeGroup: true}
+				c.Status.NodeSecurityGroup = &infrav1.SecurityGroupStatus{ID: nodeSecurityGroupUUID}
+				return c
+			},
+			machine:          getDefaultMachine,
+			openStackMachine: getDefaultOpenStackMachine,
+			wantSecurityGroups: []infrav1.SecurityGroupFilter{
+				{ID: nodeSecurityGroupUUID},
+			},
+		},
+		{
+			name: "Worker machine with allNodes security group",
+			openStackCluster: func() *infrav1.OpenStackCluster {
+				c := getDefaultOpenStackCluster()
+				c.Spec.ManagedSecurityGroups = &infrav1.ManagedSecurityGroups{SeparateAllNodesGroup: true}
+				c.Status.WorkerSecurityGroup = &infrav1.SecurityGroupStatus{ID: workerSecurityGroupUUID}
+				c.Status.AllNodesSecurityGroup = &infrav1.SecurityGroupStatus{ID: allNodesSecurityGroupUUID}
+				return c
+			},
+			machine:          getDefaultMachine,
+			openStackMachine: getDefaultOpenStackMachine,
+			wantSecurityGroups: []infrav1.SecurityGroupFilter{
+				{ID: workerSecurityGroupUUID},
+				{ID: allNodesSecurityGroupUUID},
+			},
+		},
 		{
 			name: "Machine with additional security groups",
 			openStackCluster: func() *infrav1.OpenStackCluster {
@@ -40,6 +40,7 @@ import (
 	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/clients"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/cloud/services/compute"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/cloud/services/networking"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
 	"sigs.k8s.io/cluster-api-provider-openstack/test/helpers/external"
 )
@@ -141,7 +142,7 @@ var _ = Describe("When calling getOrCreate", func() {
 		reconsiler = OpenStackMachineReconciler{}
 		mockCtrl = gomock.NewController(GinkgoT())
 		mockScopeFactory = scope.NewMockScopeFactory(mockCtrl, "1234")
-		computeService, err = compute.NewService(scope.NewWithLogger(mockScopeFactory, logger))
+		computeService, err = compute.NewService(scope.NewWithLogger(mockScopeFactory, logger), networking.DefaultOptions())
 		Expect(err).NotTo(HaveOccurred())
 	})
 
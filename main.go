@@ -36,6 +36,7 @@ import (
 	_ "k8s.io/component-base/logs/json/register"
 	"k8s.io/klog/v2"
 	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/controllers/remote"
 	ipamv1 "sigs.k8s.io/cluster-api/exp/ipam/api/v1beta1"
 	"sigs.k8s.io/cluster-api/util/flags"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -51,6 +52,8 @@ import (
 	infrav1alpha7 "sigs.k8s.io/cluster-api-provider-openstack/api/v1alpha7"
 	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-openstack/controllers"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/audit"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/cloud/services/networking"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/metrics"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/record"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
@@ -96,6 +99,25 @@ var (
 	caCertsPath                 string
 	showVersion                 bool
 	scopeCacheMaxSize           int
+	auditWebhookURL             string
+	auditQueueSize              int
+	secGroupDescriptionTemplate string
+	secGroupControlReference    string
+	secGroupRulePrefix          string
+	secGroupRuleOwnerTag        string
+	secGroupRuleManagedMarker   bool
+	secGroupNameConflictPolicy  string
+	secGroupDescriptionFormat   string
+	secGroupPropagationTimeout  time.Duration
+	secGroupRulePolicy          infrav1.SecurityGroupRulePolicy
+	secGroupReconcileAttempts   int
+	secGroupMaxRulesPerGroup    int
+	secGroupClientRetryAttempts int
+	foreignSecGroupPolicy       string
+	secGroupRuleDeletionGrace   time.Duration
+	secGroupRemovalPolicy       string
+	floatingIPReuseTag          string
+	secGroupListMaxResults      int
 	logOptions                  = logs.NewOptions()
 )
 
@@ -111,6 +133,7 @@ func init() {
 	// +kubebuilder:scaffold:scheme
 
 	metrics.RegisterAPIPrometheusMetrics()
+	metrics.RegisterSecurityGroupPrometheusMetrics()
 }
 
 // InitFlags initializes the flags.
@@ -172,6 +195,64 @@ func InitFlags(fs *pflag.FlagSet) {
 
 	fs.IntVar(&scopeCacheMaxSize, "scope-cache-max-size", 10, "The maximum credentials count the operator should keep in cache. Setting this value to 0 means no cache.")
 
+	fs.StringVar(&auditWebhookURL, "audit-webhook-url", "", "The URL to which changes of managed security group rules are POSTed as JSON audit records. Audit is disabled if unset.")
+
+	fs.IntVar(&auditQueueSize, "audit-queue-size", 1000, "The number of audit records queued while they are sent to the audit webhook. The records are dropped while the queue is full.")
+
+	fs.StringVar(&secGroupDescriptionTemplate, "security-group-description-template", "Cluster API managed group",
+		"The Go template of the description of the managed security groups. It can reference .Cluster, .Namespace, .Name, .Role and .ControlReference. The description is truncated to the 255 characters allowed by Neutron.")
+
+	fs.StringVar(&secGroupControlReference, "security-group-control-reference", "",
+		"A compliance control reference, e.g. CIS-5.2, available as .ControlReference in the security group description template.")
+
+	fs.StringVar(&secGroupRuleOwnerTag, "security-group-rule-owner-tag", "",
+		"A tag identifying the security group rules owned by this controller, in addition to the tags of the cluster. Only the owned rules are deleted, allowing several controllers or operators to manage rules of the same security group. All the rules are owned if the cluster has no tags and this is unset.")
+
+	fs.StringVar(&secGroupRulePrefix, "security-group-rule-description-prefix", "",
+		"A prefix prepended to the description of the managed security group rules. The untagged rules with this prefix are adopted by tagging them with the owner tag, which must be set.")
+
+	fs.BoolVar(&secGroupRuleManagedMarker, "security-group-rule-managed-marker", false,
+		"Start the description of the managed security group rules with the \""+networking.ManagedRuleDescriptionMarker+"\" marker. The owner tag defaults to \""+networking.ManagedRuleOwnerTag+"\", and the untagged rules with the marker are adopted.")
+
+	fs.StringVar(&secGroupDescriptionFormat, "security-group-description-format", string(networking.DescriptionFormatProse),
+		"The format of the descriptions generated for the managed security groups and rules: prose or structured. The structured format uses space separated key=value pairs and ignores the security group description template.")
+
+	fs.DurationVar(&secGroupPropagationTimeout, "security-group-propagation-timeout", 0,
+		"The maximum time to wait for a created security group to be listable before using it in rules and ports. Use on clouds where created security groups are not immediately usable. No wait is done when set to 0.")
+
+	fs.DurationVar(&secGroupRuleDeletionGrace, "security-group-rule-deletion-grace-period", 0,
+		"The minimum time the security group rules which are not desired anymore are retained before being deleted, so the rules replacing them are in place first. The rules are deleted by the first reconcile after the grace period in which all the desired rules are present. The rules are deleted immediately when set to 0.")
+
+	fs.IntVar(&secGroupReconcileAttempts, "security-group-max-reconcile-attempts", 1,
+		"The number of consecutive failed attempts to reconcile the security groups of a cluster after which the failure is reported as terminal in the failureReason and failureMessage of the OpenStackCluster. Earlier failures are retried.")
+
+	fs.IntVar(&secGroupClientRetryAttempts, "security-group-client-retry-attempts", 1,
+		"The number of times the creation and deletion of security group rules, and the listing of security groups, are attempted when they fail with a transient server error of Neutron, e.g. a 503 during a maintenance of the cloud. The attempts are made with an exponential backoff starting at 500ms. Client errors are never retried.")
+
+	fs.StringVar(&floatingIPReuseTag, "floating-ip-reuse-tag", "",
+		"The tag of the floating IPs reused across clusters, so that recreating clusters doesn't exhaust the floating IP quota. When set, the floating IPs allocated without an explicit address are tagged with it, an unassigned floating IP of the external network with the tag is reused before a new one is allocated, and the floating IPs with the tag are only disassociated, not released, when they aren't used anymore.")
+
+	fs.IntVar(&secGroupListMaxResults, "security-group-list-max-results", 1000,
+		"The maximum number of security groups a listing of security groups may return, e.g. for a securityGroups filter of a machine on tags only. The security groups are then listed by pages of 100, and the listing fails as soon as more groups match rather than loading them all. 0 means unlimited.")
+
+	fs.IntVar(&secGroupMaxRulesPerGroup, "security-group-max-rules-per-group", 0,
+		"The maximum number of rules per security group allowed by the cloud. When set, a security group whose rules would exceed it is reported before any of its rules is changed. 0 means unknown, in which case the limit is only detected from the errors of the cloud.")
+
+	fs.BoolVar(&secGroupRulePolicy.DenyOpenIngress, "deny-open-ingress-security-group-rules", false,
+		"Reject the OpenStackClusters with allNodes security group rules permitting ingress from any address, i.e. with a remoteIPPrefix of 0.0.0.0/0 or ::/0, or without any remote.")
+
+	fs.StringSliceVar(&secGroupRulePolicy.OpenIngressAllowedNamespaces, "open-ingress-security-group-rules-allowed-namespaces", nil,
+		"Comma-separated list of namespaces whose OpenStackClusters may have allNodes security group rules permitting ingress from any address when --deny-open-ingress-security-group-rules is set.")
+
+	fs.StringVar(&secGroupNameConflictPolicy, "security-group-name-conflict-policy", string(networking.SecurityGroupNameConflictError),
+		"The policy applied when several security groups have the name of a managed security group: Error fails the reconcile, Oldest uses the oldest group and Tagged uses the only group with all the tags of the cluster.")
+
+	fs.StringVar(&foreignSecGroupPolicy, "foreign-security-group-policy", string(networking.ForeignSecurityGroupIgnore),
+		"The policy applied to the security groups with the tags of a cluster but not the name of one of its managed security groups, e.g. after the cluster was renamed: Ignore leaves them alone, Warn emits an event for each of them and Adopt renames a group named like a managed group of the same role to the name of the managed group when it doesn't exist yet.")
+
+	fs.StringVar(&secGroupRemovalPolicy, "security-group-removal-policy", string(networking.SecurityGroupRemovalLeave),
+		"The policy applied to the managed security groups of a cluster once managedSecurityGroups is removed from its spec: Leave leaves them in place and Delete deletes each of them once no port uses it anymore.")
+
 	fs.BoolVar(&showVersion, "version", false, "Show current version and exit.")
 
 	fs.StringVar(&tlsOptions.TLSMinVersion, "tls-min-version", TLSVersion12,
@@ -294,10 +375,45 @@ func main() {
 	// Initialize event recorder.
 	record.InitFromRecorder(mgr.GetEventRecorderFor("openstack-controller"))
 
+	networkingOptions := networking.Options{
+		SecurityGroupDescriptionTemplate:     secGroupDescriptionTemplate,
+		SecurityGroupControlReference:        secGroupControlReference,
+		SecurityGroupDescriptionFormat:       networking.DescriptionFormat(secGroupDescriptionFormat),
+		SecurityGroupRuleOwnerTag:            secGroupRuleOwnerTag,
+		SecurityGroupRuleDescriptionPrefix:   secGroupRulePrefix,
+		SecurityGroupRuleManagedMarker:       secGroupRuleManagedMarker,
+		SecurityGroupNameConflictPolicy:      networking.SecurityGroupNameConflictPolicy(secGroupNameConflictPolicy),
+		ForeignSecurityGroupPolicy:           networking.ForeignSecurityGroupPolicy(foreignSecGroupPolicy),
+		SecurityGroupRemovalPolicy:           networking.SecurityGroupRemovalPolicy(secGroupRemovalPolicy),
+		SecurityGroupPropagationTimeout:      secGroupPropagationTimeout,
+		SecurityGroupRuleDeletionGracePeriod: secGroupRuleDeletionGrace,
+		SecurityGroupMaxReconcileAttempts:    secGroupReconcileAttempts,
+		SecurityGroupMaxRulesPerGroup:        secGroupMaxRulesPerGroup,
+		SecurityGroupClientRetryAttempts:     secGroupClientRetryAttempts,
+		SecurityGroupListMaxResults:          secGroupListMaxResults,
+		FloatingIPReuseTag:                   floatingIPReuseTag,
+	}
+
+	// Initialize audit sink.
+	// The records are sent in the background, so that an unavailable webhook doesn't delay the reconciles.
+	if auditWebhookURL != "" {
+		auditSink := audit.NewQueueSink(audit.NewWebhookSink(auditWebhookURL), auditQueueSize, ctrl.Log.WithName("audit"))
+		if err := mgr.Add(auditSink); err != nil {
+			setupLog.Error(err, "unable to add audit sink to manager")
+			os.Exit(1)
+		}
+		networkingOptions.AuditSink = auditSink
+	}
+
+	if err := networkingOptions.Validate(); err != nil {
+		setupLog.Error(err, "invalid networking options")
+		os.Exit(1)
+	}
+
 	scopeFactory := scope.NewFactory(scopeCacheMaxSize)
 
 	setupChecks(mgr)
-	setupReconcilers(ctx, mgr, caCerts, scopeFactory)
+	setupReconcilers(ctx, mgr, caCerts, scopeFactory, networkingOptions)
 	setupWebhooks(mgr)
 	// +kubebuilder:scaffold:builder
 	setupLog.Info("starting manager", "version", version.Get().String())
@@ -319,33 +435,56 @@ func setupChecks(mgr ctrl.Manager) {
 	}
 }
 
-func setupReconcilers(ctx context.Context, mgr ctrl.Manager, caCerts []byte, scopeFactory scope.Factory) {
-	if err := (&controllers.OpenStackClusterReconciler{
+func setupReconcilers(ctx context.Context, mgr ctrl.Manager, caCerts []byte, scopeFactory scope.Factory, networkingOptions networking.Options) {
+	// The tracker caches the clients of the workload clusters, e.g. to watch their Services.
+	trackerLog := ctrl.Log.WithName("remote").WithName("ClusterCacheTracker")
+	tracker, err := remote.NewClusterCacheTracker(mgr, remote.ClusterCacheTrackerOptions{
+		ControllerName: "openstackcluster-controller",
+		Log:            &trackerLog,
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to create cluster cache tracker")
+		os.Exit(1)
+	}
+	if err := (&remote.ClusterCacheReconciler{
 		Client:           mgr.GetClient(),
-		Recorder:         mgr.GetEventRecorderFor("openstackcluster-controller"),
+		Tracker:          tracker,
 		WatchFilterValue: watchFilterValue,
-		ScopeFactory:     scopeFactory,
-		CaCertificates:   caCerts,
+	}).SetupWithManager(ctx, mgr, concurrency(openStackClusterConcurrency)); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ClusterCacheReconciler")
+		os.Exit(1)
+	}
+
+	if err := (&controllers.OpenStackClusterReconciler{
+		Client:            mgr.GetClient(),
+		Recorder:          mgr.GetEventRecorderFor("openstackcluster-controller"),
+		WatchFilterValue:  watchFilterValue,
+		ScopeFactory:      scopeFactory,
+		CaCertificates:    caCerts,
+		Tracker:           tracker,
+		NetworkingOptions: networkingOptions,
 	}).SetupWithManager(ctx, mgr, concurrency(openStackClusterConcurrency)); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "OpenStackCluster")
 		os.Exit(1)
 	}
 	if err := (&controllers.OpenStackMachineReconciler{
-		Client:           mgr.GetClient(),
-		Recorder:         mgr.GetEventRecorderFor("openstackmachine-controller"),
-		WatchFilterValue: watchFilterValue,
-		ScopeFactory:     scopeFactory,
-		CaCertificates:   caCerts,
+		Client:            mgr.GetClient(),
+		Recorder:          mgr.GetEventRecorderFor("openstackmachine-controller"),
+		WatchFilterValue:  watchFilterValue,
+		ScopeFactory:      scopeFactory,
+		CaCertificates:    caCerts,
+		NetworkingOptions: networkingOptions,
 	}).SetupWithManager(ctx, mgr, concurrency(openStackMachineConcurrency)); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "OpenStackMachine")
 		os.Exit(1)
 	}
 	if err := (&controllers.OpenStackFloatingIPPoolReconciler{
-		Client:         mgr.GetClient(),
-		Recorder:       mgr.GetEventRecorderFor("floatingippool-controller"),
-		ScopeFactory:   scopeFactory,
-		Scheme:         mgr.GetScheme(),
-		CaCertificates: caCerts,
+		Client:            mgr.GetClient(),
+		Recorder:          mgr.GetEventRecorderFor("floatingippool-controller"),
+		ScopeFactory:      scopeFactory,
+		Scheme:            mgr.GetScheme(),
+		CaCertificates:    caCerts,
+		NetworkingOptions: networkingOptions,
 	}).SetupWithManager(ctx, mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "FloatingIPPool")
 		os.Exit(1)
@@ -361,7 +500,7 @@ func setupWebhooks(mgr ctrl.Manager) {
 		setupLog.Error(err, "unable to create webhook", "webhook", "OpenStackMachineTemplateList")
 		os.Exit(1)
 	}
-	if err := (&infrav1.OpenStackCluster{}).SetupWebhookWithManager(mgr); err != nil {
+	if err := (&infrav1.OpenStackClusterWebhook{SecurityGroupRulePolicy: secGroupRulePolicy}).SetupWebhookWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create webhook", "webhook", "OpenStackCluster")
 		os.Exit(1)
 	}
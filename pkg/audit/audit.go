@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit mirrors changes made to OpenStack resources to an external audit sink.
+package audit
+
+import (
+	"context"
+	"time"
+)
+
+// Action is the kind of change recorded.
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionDelete Action = "delete"
+)
+
+// Record is a structured record of a change made to an OpenStack resource.
+type Record struct {
+	Time time.Time `json:"time"`
+	// Action is the change made to the resource.
+	Action Action `json:"action"`
+	// ResourceType is the type of the resource, e.g. security-group-rule.
+	ResourceType string `json:"resourceType"`
+	// ID is the ID of the resource.
+	ID string `json:"id"`
+	// Parent is the ID of the resource owning the resource, e.g. the security group of a rule.
+	Parent string `json:"parent,omitempty"`
+	// Resource is the description of the resource.
+	Resource interface{} `json:"resource,omitempty"`
+}
+
+// Sink receives audit records. Send must return once ctx is done.
+type Sink interface {
+	Send(ctx context.Context, record Record) error
+}
+
+type noopSink struct{}
+
+func (noopSink) Send(context.Context, Record) error {
+	return nil
+}
+
+// NewNoopSink returns a sink discarding the records.
+func NewNoopSink() Sink {
+	return noopSink{}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-logr/logr"
+)
+
+// ErrQueueFull is returned by QueueSink.Send when the record is dropped because the queue is full.
+var ErrQueueFull = errors.New("audit queue is full")
+
+// QueueSink queues the records and sends them to another sink in the background, so that a slow or unavailable
+// sink doesn't delay the callers. The queue is bounded: the records sent while it is full are dropped.
+type QueueSink struct {
+	sink    Sink
+	records chan Record
+	logger  logr.Logger
+}
+
+// NewQueueSink returns a sink queueing up to size records for sink. The records are only sent once Start is called.
+func NewQueueSink(sink Sink, size int, logger logr.Logger) *QueueSink {
+	return &QueueSink{
+		sink:    sink,
+		records: make(chan Record, size),
+		logger:  logger,
+	}
+}
+
+// Send queues the record without blocking. It returns ErrQueueFull if the record is dropped.
+func (s *QueueSink) Send(_ context.Context, record Record) error {
+	select {
+	case s.records <- record:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Start sends the queued records until ctx is done. The records which fail to be sent are logged and dropped.
+// It implements the manager.Runnable interface.
+func (s *QueueSink) Start(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case record := <-s.records:
+			if err := s.sink.Send(ctx, record); err != nil {
+				s.logger.Error(err, "Failed to send audit record", "action", record.Action, "resourceType", record.ResourceType, "ID", record.ID)
+			}
+		}
+	}
+}
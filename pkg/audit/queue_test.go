@@ -0,0 +1,62 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/gomega"
+)
+
+type recordingSink struct {
+	records chan Record
+	err     error
+}
+
+func (s *recordingSink) Send(_ context.Context, record Record) error {
+	s.records <- record
+	return s.err
+}
+
+func TestQueueSink(t *testing.T) {
+	g := NewWithT(t)
+
+	sink := &recordingSink{records: make(chan Record, 3), err: errors.New("unavailable")}
+	queue := NewQueueSink(sink, 2, logr.Discard())
+
+	// The records are queued without being sent until the queue is started, and dropped once it is full.
+	g.Expect(queue.Send(context.TODO(), Record{ID: "id1"})).To(Succeed())
+	g.Expect(queue.Send(context.TODO(), Record{ID: "id2"})).To(Succeed())
+	g.Expect(queue.Send(context.TODO(), Record{ID: "id3"})).To(MatchError(ErrQueueFull))
+	g.Expect(sink.records).To(BeEmpty())
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	done := make(chan error)
+	go func() { done <- queue.Start(ctx) }()
+
+	// The failures of the sink don't stop the queue.
+	g.Eventually(sink.records).Should(Receive(Equal(Record{ID: "id1"})))
+	g.Eventually(sink.records).Should(Receive(Equal(Record{ID: "id2"})))
+	g.Expect(queue.Send(context.TODO(), Record{ID: "id3"})).To(Succeed())
+	g.Eventually(sink.records).Should(Receive(Equal(Record{ID: "id3"})))
+
+	cancel()
+	g.Eventually(done).Should(Receive(BeNil()))
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultWebhookTimeout       = 10 * time.Second
+	defaultWebhookRetries       = 3
+	defaultWebhookRetryInterval = time.Second
+)
+
+// WebhookSink POSTs every record as JSON to a URL.
+type WebhookSink struct {
+	URL    string
+	Client *http.Client
+	// Retries is the number of times a failed request is retried.
+	Retries int
+	// RetryInterval is the time waited between two attempts.
+	RetryInterval time.Duration
+}
+
+// NewWebhookSink returns a sink sending records to url.
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{
+		URL:           url,
+		Client:        &http.Client{Timeout: defaultWebhookTimeout},
+		Retries:       defaultWebhookRetries,
+		RetryInterval: defaultWebhookRetryInterval,
+	}
+}
+
+// Send POSTs the record, retrying the failed requests. The requests and the waits between them are aborted once
+// ctx is done.
+func (s *WebhookSink) Send(ctx context.Context, record Record) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshalling audit record: %w", err)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err = s.post(ctx, body)
+		if err == nil || attempt >= s.Retries {
+			return err
+		}
+
+		timer := time.NewTimer(s.RetryInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("sending audit record: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (s *WebhookSink) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("sending audit record: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending audit record: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("sending audit record: unexpected status %s", resp.Status)
+	}
+	return nil
+}
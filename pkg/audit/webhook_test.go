@@ -0,0 +1,120 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestWebhookSink(t *testing.T) {
+	record := Record{
+		Action:       ActionCreate,
+		ResourceType: "security-group-rule",
+		ID:           "idSGRule",
+		Parent:       "idSG",
+	}
+
+	tests := []struct {
+		name         string
+		failures     int
+		retries      int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{
+			name:         "Record is sent",
+			retries:      2,
+			wantAttempts: 1,
+		},
+		{
+			name:         "Failed request is retried",
+			failures:     2,
+			retries:      2,
+			wantAttempts: 3,
+		},
+		{
+			name:         "Error is returned once retries are exhausted",
+			failures:     3,
+			retries:      2,
+			wantAttempts: 3,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				g.Expect(r.Method).To(Equal(http.MethodPost))
+				g.Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+
+				var got Record
+				g.Expect(json.NewDecoder(r.Body).Decode(&got)).To(Succeed())
+				g.Expect(got).To(Equal(record))
+
+				if attempts <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer server.Close()
+
+			sink := NewWebhookSink(server.URL)
+			sink.Retries = tt.retries
+			sink.RetryInterval = 0
+
+			err := sink.Send(context.TODO(), record)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			g.Expect(attempts).To(Equal(tt.wantAttempts))
+		})
+	}
+}
+
+func TestWebhookSinkContextDone(t *testing.T) {
+	g := NewWithT(t)
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL)
+	sink.RetryInterval = time.Hour
+
+	// The wait before the retry is aborted with the context.
+	ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+	defer cancel()
+	err := sink.Send(ctx, Record{Action: ActionDelete, ID: "idSGRule"})
+	g.Expect(err).To(MatchError(context.DeadlineExceeded))
+	g.Expect(attempts).To(Equal(1))
+}
@@ -28,6 +28,7 @@ import (
 	attributestags "github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/attributestags"
 	floatingips "github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/floatingips"
 	routers "github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/routers"
+	quotas "github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/quotas"
 	groups "github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
 	rules "github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
 	trunks "github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/trunks"
@@ -59,6 +60,20 @@ func (m *MockNetworkClient) EXPECT() *MockNetworkClientMockRecorder {
 	return m.recorder
 }
 
+// AddAttributesTag mocks base method.
+func (m *MockNetworkClient) AddAttributesTag(arg0, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddAttributesTag", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddAttributesTag indicates an expected call of AddAttributesTag.
+func (mr *MockNetworkClientMockRecorder) AddAttributesTag(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddAttributesTag", reflect.TypeOf((*MockNetworkClient)(nil).AddAttributesTag), arg0, arg1, arg2)
+}
+
 // AddRouterInterface mocks base method.
 func (m *MockNetworkClient) AddRouterInterface(arg0 string, arg1 routers.AddInterfaceOptsBuilder) (*routers.InterfaceInfo, error) {
 	m.ctrl.T.Helper()
@@ -164,6 +179,21 @@ func (mr *MockNetworkClientMockRecorder) CreateSecGroupRule(arg0 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSecGroupRule", reflect.TypeOf((*MockNetworkClient)(nil).CreateSecGroupRule), arg0)
 }
 
+// CreateSecGroupRules mocks base method.
+func (m *MockNetworkClient) CreateSecGroupRules(arg0 []rules.CreateOpts) ([]rules.SecGroupRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSecGroupRules", arg0)
+	ret0, _ := ret[0].([]rules.SecGroupRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSecGroupRules indicates an expected call of CreateSecGroupRules.
+func (mr *MockNetworkClientMockRecorder) CreateSecGroupRules(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSecGroupRules", reflect.TypeOf((*MockNetworkClient)(nil).CreateSecGroupRules), arg0)
+}
+
 // CreateSubnet mocks base method.
 func (m *MockNetworkClient) CreateSubnet(arg0 subnets.CreateOptsBuilder) (*subnets.Subnet, error) {
 	m.ctrl.T.Helper()
@@ -351,6 +381,21 @@ func (mr *MockNetworkClientMockRecorder) GetPort(arg0 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPort", reflect.TypeOf((*MockNetworkClient)(nil).GetPort), arg0)
 }
 
+// GetQuota mocks base method.
+func (m *MockNetworkClient) GetQuota(arg0 string) (*quotas.Quota, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQuota", arg0)
+	ret0, _ := ret[0].(*quotas.Quota)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetQuota indicates an expected call of GetQuota.
+func (mr *MockNetworkClientMockRecorder) GetQuota(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQuota", reflect.TypeOf((*MockNetworkClient)(nil).GetQuota), arg0)
+}
+
 // GetRouter mocks base method.
 func (m *MockNetworkClient) GetRouter(arg0 string) (*routers.Router, error) {
 	m.ctrl.T.Helper()
@@ -516,6 +561,21 @@ func (mr *MockNetworkClientMockRecorder) ListSecGroupRule(arg0 interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSecGroupRule", reflect.TypeOf((*MockNetworkClient)(nil).ListSecGroupRule), arg0)
 }
 
+// ListSecGroupRuleWithTags mocks base method.
+func (m *MockNetworkClient) ListSecGroupRuleWithTags(arg0 rules.ListOpts, arg1 []string) ([]rules.SecGroupRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSecGroupRuleWithTags", arg0, arg1)
+	ret0, _ := ret[0].([]rules.SecGroupRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSecGroupRuleWithTags indicates an expected call of ListSecGroupRuleWithTags.
+func (mr *MockNetworkClientMockRecorder) ListSecGroupRuleWithTags(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSecGroupRuleWithTags", reflect.TypeOf((*MockNetworkClient)(nil).ListSecGroupRuleWithTags), arg0, arg1)
+}
+
 // ListSubnet mocks base method.
 func (m *MockNetworkClient) ListSubnet(arg0 subnets.ListOptsBuilder) ([]subnets.Subnet, error) {
 	m.ctrl.T.Helper()
@@ -17,7 +17,11 @@ limitations under the License.
 package clients
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack"
@@ -25,17 +29,34 @@ import (
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/attributestags"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/floatingips"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/routers"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/quotas"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/trunks"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/subnets"
+	"github.com/gophercloud/gophercloud/pagination"
 	"github.com/gophercloud/utils/openstack/clientconfig"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/apimachinery/pkg/util/wait"
 
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/metrics"
+	capoerrors "sigs.k8s.io/cluster-api-provider-openstack/pkg/utils/errors"
 )
 
+const (
+	// extensionCacheSize is the maximum number of Neutron endpoints whose extensions are cached.
+	extensionCacheSize = 64
+	// extensionCacheTTL is how long the extensions of a Neutron endpoint are cached for.
+	extensionCacheTTL = 10 * time.Minute
+)
+
+// extensionCache holds the extensions of each Neutron endpoint for the lifetime of the process, so that
+// capability checks don't list the extensions on every reconcile. It is keyed by endpoint, so a change of
+// endpoint always results in the extensions being listed again.
+var extensionCache = cache.NewLRUExpireCache(extensionCacheSize)
+
 type NetworkClient interface {
 	ListFloatingIP(opts floatingips.ListOptsBuilder) ([]floatingips.FloatingIP, error)
 	CreateFloatingIP(opts floatingips.CreateOptsBuilder) (*floatingips.FloatingIP, error)
@@ -68,7 +89,9 @@ type NetworkClient interface {
 	UpdateSecGroup(id string, opts groups.UpdateOptsBuilder) (*groups.SecGroup, error)
 
 	ListSecGroupRule(opts rules.ListOpts) ([]rules.SecGroupRule, error)
+	ListSecGroupRuleWithTags(opts rules.ListOpts, tags []string) ([]rules.SecGroupRule, error)
 	CreateSecGroupRule(opts rules.CreateOptsBuilder) (*rules.SecGroupRule, error)
+	CreateSecGroupRules(opts []rules.CreateOpts) ([]rules.SecGroupRule, error)
 	DeleteSecGroupRule(id string) error
 	GetSecGroupRule(id string) (*rules.SecGroupRule, error)
 
@@ -86,13 +109,24 @@ type NetworkClient interface {
 
 	ListExtensions() ([]extensions.Extension, error)
 
+	GetQuota(projectID string) (*quotas.Quota, error)
+
 	ReplaceAllAttributesTags(resourceType string, resourceID string, opts attributestags.ReplaceAllOptsBuilder) ([]string, error)
+	AddAttributesTag(resourceType string, resourceID string, tag string) error
 }
 
 type networkClient struct {
 	serviceClient *gophercloud.ServiceClient
+	// secGroupListPageSize is the number of security groups requested per page when secGroupListMaxResults is set.
+	secGroupListPageSize int
+	// secGroupListMaxResults is the maximum number of security groups a listing may return, zero if unlimited.
+	secGroupListMaxResults int
 }
 
+// ErrTooManySecGroups is returned when listing security groups matches more groups than the maximum configured with
+// NetworkClientWithSecGroupListLimit.
+var ErrTooManySecGroups = errors.New("too many security groups match")
+
 // NewNetworkClient returns an instance of the networking service.
 func NewNetworkClient(providerClient *gophercloud.ProviderClient, providerClientOpts *clientconfig.ClientOpts) (NetworkClient, error) {
 	serviceClient, err := openstack.NewNetworkV2(providerClient, gophercloud.EndpointOpts{
@@ -103,7 +137,125 @@ func NewNetworkClient(providerClient *gophercloud.ProviderClient, providerClient
 		return nil, fmt.Errorf("failed to create networking service providerClient: %v", err)
 	}
 
-	return networkClient{serviceClient}, nil
+	return networkClient{serviceClient: serviceClient}, nil
+}
+
+// NetworkClientWithContext returns a copy of the network client whose requests are made with ctx, and are aborted
+// once it is done. Clients not created by NewNetworkClient, such as mocks, are returned unchanged.
+func NetworkClientWithContext(ctx context.Context, c NetworkClient) NetworkClient {
+	if rc, ok := c.(retryNetworkClient); ok {
+		rc.NetworkClient = NetworkClientWithContext(ctx, rc.NetworkClient)
+		rc.ctx = ctx
+		return rc
+	}
+	nc, ok := c.(networkClient)
+	if !ok {
+		return c
+	}
+	return nc.withProviderClient(func(providerClient *gophercloud.ProviderClient) {
+		providerClient.Context = ctx
+	})
+}
+
+// NetworkClientWithSecGroupListLimit returns a copy of the network client listing the security groups by pages of
+// pageSize groups, and failing with ErrTooManySecGroups as soon as more than maxResults groups match, rather than
+// loading them all, e.g. for a filter on tags only matching thousands of groups. The client is returned unchanged when
+// maxResults is not positive, or when it was not created by NewNetworkClient, such as mocks. It must be called before
+// NetworkClientWithRetry.
+func NetworkClientWithSecGroupListLimit(c NetworkClient, pageSize, maxResults int) NetworkClient {
+	nc, ok := c.(networkClient)
+	if !ok || maxResults <= 0 {
+		return c
+	}
+	nc.secGroupListPageSize = pageSize
+	nc.secGroupListMaxResults = maxResults
+	return nc
+}
+
+// NetworkClientWithRetry returns a copy of the network client retrying the creation and deletion of security group
+// rules, and the listing of security groups, when they fail with a transient server error. The requests are made up
+// to backoff.Steps times, waiting as configured by backoff in between. Other errors, e.g. 4xx errors, are returned
+// immediately. The client is returned unchanged when backoff doesn't allow more than one request.
+//
+// A retried creation may find the rule created by the failed request: the conflict is returned, as when the rule
+// was created by someone else.
+func NetworkClientWithRetry(c NetworkClient, backoff wait.Backoff) NetworkClient {
+	if backoff.Steps <= 1 {
+		return c
+	}
+	return retryNetworkClient{NetworkClient: c, backoff: backoff, ctx: context.Background()}
+}
+
+// retryNetworkClient is a network client retrying some of its requests on transient server errors.
+type retryNetworkClient struct {
+	NetworkClient
+	backoff wait.Backoff
+	// ctx aborts the retries once it is done.
+	ctx context.Context
+}
+
+// retry calls request until it succeeds, fails with an error which is not retryable, or the backoff is exhausted.
+// The last error of request is returned.
+func (c retryNetworkClient) retry(request func() error) error {
+	var err error
+	waitErr := wait.ExponentialBackoffWithContext(c.ctx, c.backoff, func(context.Context) (bool, error) {
+		err = request()
+		return err == nil || !capoerrors.IsRetryable(err), nil
+	})
+	if err == nil && waitErr != nil {
+		// The context is done before the first request.
+		return waitErr
+	}
+	return err
+}
+
+func (c retryNetworkClient) ListSecGroup(opts groups.ListOpts) ([]groups.SecGroup, error) {
+	var secGroups []groups.SecGroup
+	err := c.retry(func() error {
+		var err error
+		secGroups, err = c.NetworkClient.ListSecGroup(opts)
+		return err
+	})
+	return secGroups, err
+}
+
+func (c retryNetworkClient) CreateSecGroupRule(opts rules.CreateOptsBuilder) (*rules.SecGroupRule, error) {
+	var rule *rules.SecGroupRule
+	err := c.retry(func() error {
+		var err error
+		rule, err = c.NetworkClient.CreateSecGroupRule(opts)
+		return err
+	})
+	return rule, err
+}
+
+func (c retryNetworkClient) DeleteSecGroupRule(id string) error {
+	return c.retry(func() error {
+		return c.NetworkClient.DeleteSecGroupRule(id)
+	})
+}
+
+// withProviderClient returns a copy of the network client with a copy of its provider client modified by modify.
+func (c networkClient) withProviderClient(modify func(*gophercloud.ProviderClient)) NetworkClient {
+	// The provider client is shared by the clients of the cloud, so it is copied rather than modified.
+	original := c.serviceClient.ProviderClient
+	providerClient := *original
+	modify(&providerClient)
+	if original.ReauthFunc != nil {
+		// Reauthenticating sets the new token on the original provider client only.
+		providerClient.ReauthFunc = func() error {
+			if err := original.ReauthFunc(); err != nil {
+				return err
+			}
+			providerClient.CopyTokenFrom(original)
+			return nil
+		}
+	}
+
+	serviceClient := *c.serviceClient
+	serviceClient.ProviderClient = &providerClient
+	c.serviceClient = &serviceClient
+	return c
 }
 
 func (c networkClient) AddRouterInterface(id string, opts routers.AddInterfaceOptsBuilder) (*routers.InterfaceInfo, error) {
@@ -133,6 +285,12 @@ func (c networkClient) ReplaceAllAttributesTags(resourceType string, resourceID
 	return tags, nil
 }
 
+func (c networkClient) AddAttributesTag(resourceType string, resourceID string, tag string) error {
+	mc := metrics.NewMetricPrometheusContext("attributes_tags", "add")
+	err := attributestags.Add(c.serviceClient, resourceType, resourceID, tag).ExtractErr()
+	return mc.ObserveRequest(err)
+}
+
 func (c networkClient) ListRouter(opts routers.ListOpts) ([]routers.Router, error) {
 	mc := metrics.NewMetricPrometheusContext("router", "list")
 	allPages, err := routers.List(c.serviceClient, opts).AllPages()
@@ -280,6 +438,10 @@ func (c networkClient) UpdateRouter(id string, opts routers.UpdateOptsBuilder) (
 }
 
 func (c networkClient) ListSecGroup(opts groups.ListOpts) ([]groups.SecGroup, error) {
+	if c.secGroupListMaxResults > 0 {
+		return c.listSecGroupUpTo(opts)
+	}
+
 	mc := metrics.NewMetricPrometheusContext("group", "list")
 	allPages, err := groups.List(c.serviceClient, opts).AllPages()
 	if mc.ObserveRequest(err) != nil {
@@ -288,6 +450,34 @@ func (c networkClient) ListSecGroup(opts groups.ListOpts) ([]groups.SecGroup, er
 	return groups.ExtractGroups(allPages)
 }
 
+// listSecGroupUpTo lists the security groups page by page, and stops with ErrTooManySecGroups as soon as more than
+// secGroupListMaxResults groups match.
+func (c networkClient) listSecGroupUpTo(opts groups.ListOpts) ([]groups.SecGroup, error) {
+	if opts.Limit == 0 {
+		opts.Limit = c.secGroupListPageSize
+	}
+
+	mc := metrics.NewMetricPrometheusContext("group", "list")
+	var secGroups []groups.SecGroup
+	tooMany := false
+	err := groups.List(c.serviceClient, opts).EachPage(func(page pagination.Page) (bool, error) {
+		pageGroups, err := groups.ExtractGroups(page)
+		if err != nil {
+			return false, err
+		}
+		secGroups = append(secGroups, pageGroups...)
+		tooMany = len(secGroups) > c.secGroupListMaxResults
+		return !tooMany, nil
+	})
+	if mc.ObserveRequest(err) != nil {
+		return nil, err
+	}
+	if tooMany {
+		return nil, fmt.Errorf("%w: more than %d groups match name %q, tags %q and project %q: narrow down the filter", ErrTooManySecGroups, c.secGroupListMaxResults, opts.Name, opts.Tags, opts.ProjectID)
+	}
+	return secGroups, nil
+}
+
 func (c networkClient) CreateSecGroup(opts groups.CreateOptsBuilder) (*groups.SecGroup, error) {
 	mc := metrics.NewMetricPrometheusContext("security_group", "create")
 	group, err := groups.Create(c.serviceClient, opts).Extract()
@@ -329,6 +519,26 @@ func (c networkClient) ListSecGroupRule(opts rules.ListOpts) ([]rules.SecGroupRu
 	return rules.ExtractRules(allPages)
 }
 
+// ListSecGroupRuleWithTags lists the rules matching opts which have all the tags. The rules returned by Gophercloud
+// don't have their tags, and its list options can't filter on them, so the filter is added to the query.
+func (c networkClient) ListSecGroupRuleWithTags(opts rules.ListOpts, tags []string) ([]rules.SecGroupRule, error) {
+	mc := metrics.NewMetricPrometheusContext("security_group_rule", "list")
+	q, err := gophercloud.BuildQueryString(&opts)
+	if err != nil {
+		return nil, err
+	}
+	query := q.Query()
+	query.Set("tags", strings.Join(tags, ","))
+	url := c.serviceClient.ServiceURL("security-group-rules") + "?" + query.Encode()
+	allPages, err := pagination.NewPager(c.serviceClient, url, func(r pagination.PageResult) pagination.Page {
+		return rules.SecGroupRulePage{LinkedPageBase: pagination.LinkedPageBase{PageResult: r}}
+	}).AllPages()
+	if mc.ObserveRequest(err) != nil {
+		return nil, err
+	}
+	return rules.ExtractRules(allPages)
+}
+
 func (c networkClient) CreateSecGroupRule(opts rules.CreateOptsBuilder) (*rules.SecGroupRule, error) {
 	mc := metrics.NewMetricPrometheusContext("security_group_rule", "create")
 	rule, err := rules.Create(c.serviceClient, opts).Extract()
@@ -338,6 +548,38 @@ func (c networkClient) CreateSecGroupRule(opts rules.CreateOptsBuilder) (*rules.
 	return rule, nil
 }
 
+// CreateSecGroupRules creates the rules with a single request. Neutron creates all of them or none.
+func (c networkClient) CreateSecGroupRules(opts []rules.CreateOpts) ([]rules.SecGroupRule, error) {
+	mc := metrics.NewMetricPrometheusContext("security_group_rule", "create_bulk")
+
+	// Gophercloud has no bulk operation for the rules, so the request is built from the bodies of single rules.
+	ruleBodies := make([]interface{}, len(opts))
+	for i := range opts {
+		b, err := opts[i].ToSecGroupRuleCreateMap()
+		if err != nil {
+			return nil, err
+		}
+		ruleBodies[i] = b["security_group_rule"]
+	}
+
+	var r gophercloud.Result
+	resp, err := c.serviceClient.Post(c.serviceClient.ServiceURL("security-group-rules"), map[string]interface{}{"security_group_rules": ruleBodies}, &r.Body, &gophercloud.RequestOpts{
+		OkCodes: []int{201},
+	})
+	_, r.Header, r.Err = gophercloud.ParseResponse(resp, err)
+	if mc.ObserveRequest(r.Err) != nil {
+		return nil, r.Err
+	}
+
+	var created struct {
+		Rules []rules.SecGroupRule `json:"security_group_rules"`
+	}
+	if err := r.ExtractInto(&created); err != nil {
+		return nil, err
+	}
+	return created.Rules, nil
+}
+
 func (c networkClient) DeleteSecGroupRule(id string) error {
 	mc := metrics.NewMetricPrometheusContext("security_group_rule", "delete")
 	return mc.ObserveRequestIgnoreNotFound(rules.Delete(c.serviceClient, id).ExtractErr())
@@ -434,7 +676,36 @@ func (c networkClient) UpdateSubnet(id string, opts subnets.UpdateOptsBuilder) (
 	return subnet, nil
 }
 
+func (c networkClient) GetQuota(projectID string) (*quotas.Quota, error) {
+	mc := metrics.NewMetricPrometheusContext("quota", "get")
+	quota, err := quotas.Get(c.serviceClient, projectID).Extract()
+	if mc.ObserveRequest(err) != nil {
+		return nil, err
+	}
+	return quota, nil
+}
+
 func (c networkClient) ListExtensions() ([]extensions.Extension, error) {
+	return listExtensionsCached(extensionCache, c.serviceClient.Endpoint, c.listExtensions)
+}
+
+// listExtensionsCached returns the extensions of endpoint from extCache, calling list only if they are not
+// cached or have expired. Errors are not cached.
+func listExtensionsCached(extCache *cache.LRUExpireCache, endpoint string, list func() ([]extensions.Extension, error)) ([]extensions.Extension, error) {
+	if exts, found := extCache.Get(endpoint); found {
+		return exts.([]extensions.Extension), nil
+	}
+
+	exts, err := list()
+	if err != nil {
+		return nil, err
+	}
+
+	extCache.Add(endpoint, exts, extensionCacheTTL)
+	return exts, nil
+}
+
+func (c networkClient) listExtensions() ([]extensions.Extension, error) {
 	mc := metrics.NewMetricPrometheusContext("network_extension", "list")
 	allPages, err := extensions.List(c.serviceClient).AllPages()
 	if mc.ObserveRequest(err) != nil {
@@ -0,0 +1,386 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clients
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/apimachinery/pkg/util/wait"
+	testingclock "k8s.io/utils/clock/testing"
+
+	capoerrors "sigs.k8s.io/cluster-api-provider-openstack/pkg/utils/errors"
+)
+
+func TestListExtensionsCached(t *testing.T) {
+	const (
+		endpoint      = "https://neutron.example.com/"
+		otherEndpoint = "https://other-neutron.example.com/"
+	)
+
+	trunkExtension := extensions.Extension{}
+	trunkExtension.Alias = "trunk"
+
+	tests := []struct {
+		name      string
+		calls     func(get func(endpoint string) error, clock *testingclock.FakeClock)
+		wantLists int
+	}{
+		{
+			name: "Extensions are listed once within the TTL",
+			calls: func(get func(string) error, clock *testingclock.FakeClock) {
+				_ = get(endpoint)
+				clock.Step(extensionCacheTTL / 2)
+				_ = get(endpoint)
+				_ = get(endpoint)
+			},
+			wantLists: 1,
+		},
+		{
+			name: "Extensions are listed again after the TTL",
+			calls: func(get func(string) error, clock *testingclock.FakeClock) {
+				_ = get(endpoint)
+				clock.Step(extensionCacheTTL + time.Second)
+				_ = get(endpoint)
+			},
+			wantLists: 2,
+		},
+		{
+			name: "Extensions are listed again when the endpoint changes",
+			calls: func(get func(string) error, clock *testingclock.FakeClock) {
+				_ = get(endpoint)
+				_ = get(otherEndpoint)
+				_ = get(otherEndpoint)
+			},
+			wantLists: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			clock := testingclock.NewFakeClock(time.Now())
+			extCache := cache.NewLRUExpireCacheWithClock(extensionCacheSize, clock)
+
+			lists := 0
+			list := func() ([]extensions.Extension, error) {
+				lists++
+				return []extensions.Extension{trunkExtension}, nil
+			}
+			get := func(endpoint string) error {
+				exts, err := listExtensionsCached(extCache, endpoint, list)
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(exts).To(Equal([]extensions.Extension{trunkExtension}))
+				return err
+			}
+
+			tt.calls(get, clock)
+			g.Expect(lists).To(Equal(tt.wantLists))
+		})
+	}
+}
+
+func TestListExtensionsCachedError(t *testing.T) {
+	g := NewWithT(t)
+
+	extCache := cache.NewLRUExpireCacheWithClock(extensionCacheSize, testingclock.NewFakeClock(time.Now()))
+
+	lists := 0
+	list := func() ([]extensions.Extension, error) {
+		lists++
+		return nil, fmt.Errorf("test error")
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := listExtensionsCached(extCache, "https://neutron.example.com/", list)
+		g.Expect(err).To(HaveOccurred())
+	}
+	g.Expect(lists).To(Equal(2), "errors must not be cached")
+}
+
+func TestListSecGroupRulePagination(t *testing.T) {
+	g := NewWithT(t)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		g.Expect(r.URL.Path).To(Equal("/security-group-rules"))
+		g.Expect(r.URL.Query().Get("security_group_id")).To(Equal("idSG"))
+
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("marker") == "" {
+			fmt.Fprintf(w, `{
+				"security_group_rules": [{"id": "rule1", "security_group_id": "idSG", "direction": "ingress"}],
+				"security_group_rules_links": [{"rel": "next", "href": "%s/security-group-rules?security_group_id=idSG&marker=rule1"}]
+			}`, server.URL)
+			return
+		}
+		g.Expect(r.URL.Query().Get("marker")).To(Equal("rule1"))
+		fmt.Fprint(w, `{
+			"security_group_rules": [{"id": "rule2", "security_group_id": "idSG", "direction": "egress"}],
+			"security_group_rules_links": []
+		}`)
+	}))
+	defer server.Close()
+
+	c := networkClient{serviceClient: &gophercloud.ServiceClient{
+		ProviderClient: &gophercloud.ProviderClient{},
+		Endpoint:       server.URL + "/",
+	}}
+
+	secGroupRules, err := c.ListSecGroupRule(rules.ListOpts{SecGroupID: "idSG"})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(secGroupRules).To(HaveLen(2))
+	g.Expect(secGroupRules[0].ID).To(Equal("rule1"))
+	g.Expect(secGroupRules[1].ID).To(Equal("rule2"))
+}
+
+func TestNetworkClientWithContext(t *testing.T) {
+	g := NewWithT(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"security_groups": []}`)
+	}))
+	defer server.Close()
+
+	providerClient := &gophercloud.ProviderClient{}
+	c := networkClient{serviceClient: &gophercloud.ServiceClient{
+		ProviderClient: providerClient,
+		Endpoint:       server.URL + "/",
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := NetworkClientWithContext(ctx, c).ListSecGroup(groups.ListOpts{})
+	g.Expect(err).To(MatchError(context.DeadlineExceeded))
+
+	// The context only applies to the returned client.
+	g.Expect(providerClient.Context).To(BeNil())
+	_, err = c.ListSecGroup(groups.ListOpts{})
+	g.Expect(err).NotTo(HaveOccurred())
+}
+
+func TestNetworkClientWithRetry(t *testing.T) {
+	backoff := wait.Backoff{Duration: time.Millisecond, Factor: 2, Steps: 3}
+
+	tests := []struct {
+		name         string
+		statusCodes  []int
+		request      func(c NetworkClient) error
+		wantRequests int
+		wantErr      bool
+	}{
+		{
+			name:        "Listing is retried on transient server errors",
+			statusCodes: []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			request: func(c NetworkClient) error {
+				_, err := c.ListSecGroup(groups.ListOpts{})
+				return err
+			},
+			wantRequests: 3,
+		},
+		{
+			name:        "Retries are bounded",
+			statusCodes: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK},
+			request: func(c NetworkClient) error {
+				_, err := c.ListSecGroup(groups.ListOpts{})
+				return err
+			},
+			wantRequests: 3,
+			wantErr:      true,
+		},
+		{
+			name:        "Client errors fail fast",
+			statusCodes: []int{http.StatusBadRequest, http.StatusOK},
+			request: func(c NetworkClient) error {
+				_, err := c.CreateSecGroupRule(rules.CreateOpts{Direction: rules.DirIngress, EtherType: rules.EtherType4, SecGroupID: "idSG"})
+				return err
+			},
+			wantRequests: 1,
+			wantErr:      true,
+		},
+		{
+			name:        "Not implemented is not retried",
+			statusCodes: []int{http.StatusNotImplemented, http.StatusOK},
+			request: func(c NetworkClient) error {
+				return c.DeleteSecGroupRule("idRule")
+			},
+			wantRequests: 1,
+			wantErr:      true,
+		},
+		{
+			// The failed request may have created the rule: the conflict is returned for the rule to be adopted.
+			name:        "Conflict of a retried creation is returned",
+			statusCodes: []int{http.StatusServiceUnavailable, http.StatusConflict},
+			request: func(c NetworkClient) error {
+				_, err := c.CreateSecGroupRule(rules.CreateOpts{Direction: rules.DirIngress, EtherType: rules.EtherType4, SecGroupID: "idSG"})
+				if !capoerrors.IsConflict(err) {
+					return fmt.Errorf("expected a conflict, got %v", err)
+				}
+				return nil
+			},
+			wantRequests: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				statusCode := tt.statusCodes[requests]
+				requests++
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case statusCode != http.StatusOK:
+					w.WriteHeader(statusCode)
+				case r.Method == http.MethodDelete:
+					w.WriteHeader(http.StatusNoContent)
+				default:
+					fmt.Fprint(w, `{"security_groups": []}`)
+				}
+			}))
+			defer server.Close()
+
+			c := NetworkClientWithRetry(networkClient{serviceClient: &gophercloud.ServiceClient{
+				ProviderClient: &gophercloud.ProviderClient{},
+				Endpoint:       server.URL + "/",
+			}}, backoff)
+
+			err := tt.request(c)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			g.Expect(requests).To(Equal(tt.wantRequests))
+		})
+	}
+}
+
+func TestNetworkClientWithRetryAndContext(t *testing.T) {
+	g := NewWithT(t)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := NetworkClientWithRetry(networkClient{serviceClient: &gophercloud.ServiceClient{
+		ProviderClient: &gophercloud.ProviderClient{},
+		Endpoint:       server.URL + "/",
+	}}, wait.Backoff{Duration: time.Hour, Steps: 3})
+
+	// The retries are aborted once the context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := NetworkClientWithContext(ctx, c).ListSecGroup(groups.ListOpts{})
+	g.Expect(capoerrors.IsRetryable(err)).To(BeTrue())
+	g.Expect(requests).To(Equal(1))
+
+	// A single attempt doesn't need a retrying client.
+	g.Expect(NetworkClientWithRetry(c, wait.Backoff{Steps: 1})).To(Equal(c))
+}
+
+func TestNetworkClientWithSecGroupListLimit(t *testing.T) {
+	tests := []struct {
+		name         string
+		groups       int
+		maxResults   int
+		wantGroups   int
+		wantRequests int
+		wantErr      bool
+	}{
+		{
+			name:         "Groups are listed page by page",
+			groups:       5,
+			maxResults:   10,
+			wantGroups:   5,
+			wantRequests: 3,
+		},
+		{
+			name:         "As many groups as the maximum",
+			groups:       4,
+			maxResults:   4,
+			wantGroups:   4,
+			wantRequests: 2,
+		},
+		{
+			name:         "Listing stops once more groups than the maximum match",
+			groups:       9,
+			maxResults:   3,
+			wantRequests: 2,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			const pageSize = 2
+			requests := 0
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				g.Expect(r.URL.Query().Get("limit")).To(Equal(fmt.Sprint(pageSize)))
+				start := 0
+				if marker := r.URL.Query().Get("marker"); marker != "" {
+					fmt.Sscan(marker, &start)
+				}
+				var secGroups []string
+				for i := start; i < start+pageSize && i < tt.groups; i++ {
+					secGroups = append(secGroups, fmt.Sprintf(`{"id": "%d"}`, i))
+				}
+				links := "[]"
+				if start+pageSize < tt.groups {
+					links = fmt.Sprintf(`[{"rel": "next", "href": "%s/security-groups?limit=%d&marker=%d"}]`, server.URL, pageSize, start+pageSize)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprintf(w, `{"security_groups": [%s], "security_groups_links": %s}`, strings.Join(secGroups, ","), links)
+			}))
+			defer server.Close()
+
+			c := NetworkClientWithSecGroupListLimit(networkClient{serviceClient: &gophercloud.ServiceClient{
+				ProviderClient: &gophercloud.ProviderClient{},
+				Endpoint:       server.URL + "/",
+			}}, pageSize, tt.maxResults)
+
+			secGroups, err := c.ListSecGroup(groups.ListOpts{Tags: "env"})
+			if tt.wantErr {
+				g.Expect(err).To(MatchError(ErrTooManySecGroups))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(secGroups).To(HaveLen(tt.wantGroups))
+			}
+			g.Expect(requests).To(Equal(tt.wantRequests))
+		})
+	}
+}
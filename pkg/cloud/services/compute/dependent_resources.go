@@ -22,10 +22,10 @@ import (
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
 )
 
-func ResolveDependentMachineResources(scope *scope.WithLogger, openStackMachine *infrav1.OpenStackMachine) (changed bool, err error) {
+func ResolveDependentMachineResources(scope *scope.WithLogger, networkingOptions networking.Options, openStackMachine *infrav1.OpenStackMachine) (changed bool, err error) {
 	changed = false
 
-	networkingService, err := networking.NewService(scope)
+	networkingService, err := networking.NewService(scope, networkingOptions)
 	if err != nil {
 		return changed, err
 	}
@@ -33,13 +33,24 @@ func ResolveDependentMachineResources(scope *scope.WithLogger, openStackMachine
 	return networkingService.AdoptMachinePorts(scope, openStackMachine, openStackMachine.Status.ReferencedResources.PortsOpts)
 }
 
-func ResolveDependentBastionResources(scope *scope.WithLogger, openStackCluster *infrav1.OpenStackCluster, bastionName string) (changed bool, err error) {
+func ResolveDependentBastionResources(scope *scope.WithLogger, networkingOptions networking.Options, openStackCluster *infrav1.OpenStackCluster, bastionName string) (changed bool, err error) {
 	changed = false
 
-	networkingService, err := networking.NewService(scope)
+	networkingService, err := networking.NewService(scope, networkingOptions)
 	if err != nil {
 		return changed, err
 	}
 
 	return networkingService.AdoptBastionPorts(scope, openStackCluster, bastionName)
 }
+
+// ResolveDependentBastionReplicaResources resolves the dependent resources of a bastion replica like
+// ResolveDependentBastionResources, storing them in the given status of the replica.
+func ResolveDependentBastionReplicaResources(scope *scope.WithLogger, networkingOptions networking.Options, openStackCluster *infrav1.OpenStackCluster, bastionStatus *infrav1.BastionStatus, bastionName string) (changed bool, err error) {
+	networkingService, err := networking.NewService(scope, networkingOptions)
+	if err != nil {
+		return false, err
+	}
+
+	return networkingService.AdoptBastionReplicaPorts(scope, openStackCluster, bastionStatus, bastionName)
+}
@@ -26,6 +26,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/cloud/services/networking"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
 )
 
@@ -104,7 +105,7 @@ func Test_ResolveDependentMachineResources(t *testing.T) {
 				Status: tt.openStackMachineStatus,
 			}
 
-			_, err := ResolveDependentMachineResources(scope.NewWithLogger(mockScopeFactory, log), defaultOpenStackMachine)
+			_, err := ResolveDependentMachineResources(scope.NewWithLogger(mockScopeFactory, log), networking.DefaultOptions(), defaultOpenStackMachine)
 			if tt.wantErr {
 				g.Expect(err).Error()
 				return
@@ -197,7 +198,7 @@ func TestResolveDependentBastionResources(t *testing.T) {
 			mockCtrl := gomock.NewController(t)
 			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
 
-			_, err := ResolveDependentBastionResources(scope.NewWithLogger(mockScopeFactory, log), tt.openStackCluster, bastionName)
+			_, err := ResolveDependentBastionResources(scope.NewWithLogger(mockScopeFactory, log), networking.DefaultOptions(), tt.openStackCluster, bastionName)
 			if tt.wantErr {
 				g.Expect(err).Error()
 				return
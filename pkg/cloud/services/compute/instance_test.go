@@ -37,6 +37,7 @@ import (
 	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/clients"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/clients/mock"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/cloud/services/networking"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
 )
 
@@ -123,7 +124,7 @@ func TestService_getImageID(t *testing.T) {
 			log := testr.New(t)
 			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
 
-			s, err := NewService(scope.NewWithLogger(mockScopeFactory, log))
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, log), networking.DefaultOptions())
 			if err != nil {
 				t.Fatalf("Failed to create service: %v", err)
 			}
@@ -677,7 +678,7 @@ func TestService_ReconcileInstance(t *testing.T) {
 
 			tt.expect(&recorders{computeRecorder, imageRecorder, networkRecorder, volumeRecorder})
 
-			s, err := NewService(scope.NewWithLogger(mockScopeFactory, log))
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, log), networking.DefaultOptions())
 			if err != nil {
 				t.Fatalf("Failed to create service: %v", err)
 			}
@@ -770,7 +771,7 @@ func TestService_DeleteInstance(t *testing.T) {
 
 			tt.expect(&recorders{computeRecorder, networkRecorder, volumeRecorder})
 
-			s, err := NewService(scope.NewWithLogger(mockScopeFactory, log))
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, log), networking.DefaultOptions())
 			if err != nil {
 				t.Fatalf("Failed to create service: %v", err)
 			}
@@ -99,16 +99,25 @@ func (is *InstanceStatus) AvailabilityZone() string {
 	return is.server.AvailabilityZone
 }
 
+func (is *InstanceStatus) Metadata() map[string]string {
+	return is.server.Metadata
+}
+
 // BastionStatus updates BastionStatus in openStackCluster.
 func (is *InstanceStatus) UpdateBastionStatus(openStackCluster *infrav1.OpenStackCluster) {
 	if openStackCluster.Status.Bastion == nil {
 		openStackCluster.Status.Bastion = &infrav1.BastionStatus{}
 	}
 
-	openStackCluster.Status.Bastion.ID = is.ID()
-	openStackCluster.Status.Bastion.Name = is.Name()
-	openStackCluster.Status.Bastion.SSHKeyName = is.SSHKeyName()
-	openStackCluster.Status.Bastion.State = is.State()
+	is.UpdateBastionReplicaStatus(openStackCluster, openStackCluster.Status.Bastion)
+}
+
+// UpdateBastionReplicaStatus updates the given status of a bastion replica with the instance.
+func (is *InstanceStatus) UpdateBastionReplicaStatus(openStackCluster *infrav1.OpenStackCluster, bastionStatus *infrav1.BastionStatus) {
+	bastionStatus.ID = is.ID()
+	bastionStatus.Name = is.Name()
+	bastionStatus.SSHKeyName = is.SSHKeyName()
+	bastionStatus.State = is.State()
 
 	ns, err := is.NetworkStatus()
 	if err != nil {
@@ -117,7 +126,7 @@ func (is *InstanceStatus) UpdateBastionStatus(openStackCluster *infrav1.OpenStac
 	}
 
 	clusterNetwork := openStackCluster.Status.Network.Name
-	openStackCluster.Status.Bastion.IP = ns.IP(clusterNetwork)
+	bastionStatus.IP = ns.IP(clusterNetwork)
 }
 
 // InstanceIdentifier returns an InstanceIdentifier object for an InstanceStatus.
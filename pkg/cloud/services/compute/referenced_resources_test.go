@@ -29,6 +29,7 @@ import (
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/clients/mock"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/cloud/services/networking"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
 )
 
@@ -180,7 +181,7 @@ func Test_ResolveReferencedMachineResources(t *testing.T) {
 			resources := &infrav1.ReferencedMachineResources{}
 
 			scope := scope.NewWithLogger(mockScopeFactory, log)
-			_, err := ResolveReferencedMachineResources(scope, openStackCluster, machineSpec, resources)
+			_, err := ResolveReferencedMachineResources(scope, networking.DefaultOptions(), openStackCluster, machineSpec, resources)
 			if tt.wantErr {
 				g.Expect(err).Error()
 				return
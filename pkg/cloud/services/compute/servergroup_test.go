@@ -26,6 +26,7 @@ import (
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/clients/mock"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/cloud/services/networking"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
 )
 
@@ -110,7 +111,7 @@ func TestService_GetServerGroupID(t *testing.T) {
 			log := testr.New(t)
 			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
 
-			s, err := NewService(scope.NewWithLogger(mockScopeFactory, log))
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, log), networking.DefaultOptions())
 			if err != nil {
 				t.Fatalf("Failed to create service: %v", err)
 			}
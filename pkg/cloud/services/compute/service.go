@@ -30,12 +30,15 @@ type Service struct {
 	_volumeClient      clients.VolumeClient
 	_imageClient       clients.ImageClient
 	_networkingService *networking.Service
+	networkingOptions  networking.Options
 }
 
-// NewService returns an instance of the compute service.
-func NewService(scope *scope.WithLogger) (*Service, error) {
+// NewService returns an instance of the compute service. The networking options configure the networking
+// service used for the ports of the instances.
+func NewService(scope *scope.WithLogger, networkingOptions networking.Options) (*Service, error) {
 	return &Service{
-		scope: scope,
+		scope:             scope,
+		networkingOptions: networkingOptions,
 	}, nil
 }
 
@@ -80,7 +83,7 @@ func (s Service) getImageClient() clients.ImageClient {
 
 func (s Service) getNetworkingService() (*networking.Service, error) {
 	if s._networkingService == nil {
-		networkingService, err := networking.NewService(s.scope)
+		networkingService, err := networking.NewService(s.scope, s.networkingOptions)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create networking service: %v", err)
 		}
@@ -35,6 +35,7 @@ import (
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/clients/mock"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/cloud/services/networking"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
 )
 
@@ -142,7 +143,7 @@ func Test_ReconcileLoadBalancer(t *testing.T) {
 			log := testr.New(t)
 
 			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
-			lbs, err := NewService(scope.NewWithLogger(mockScopeFactory, log))
+			lbs, err := NewService(scope.NewWithLogger(mockScopeFactory, log), networking.DefaultOptions())
 			g.Expect(err).NotTo(HaveOccurred())
 
 			tt.expectNetwork(mockScopeFactory.NetworkClient.EXPECT())
@@ -489,7 +490,7 @@ func Test_getOrCreateAPILoadBalancer(t *testing.T) {
 			log := testr.New(t)
 
 			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
-			lbs, err := NewService(scope.NewWithLogger(mockScopeFactory, log))
+			lbs, err := NewService(scope.NewWithLogger(mockScopeFactory, log), networking.DefaultOptions())
 			g.Expect(err).NotTo(HaveOccurred())
 
 			tt.expectLoadBalancer(mockScopeFactory.LbClient.EXPECT())
@@ -31,14 +31,15 @@ type Service struct {
 	networkingService  *networking.Service
 }
 
-// NewService returns an instance of the loadbalancer service.
-func NewService(scope *scope.WithLogger) (*Service, error) {
+// NewService returns an instance of the loadbalancer service. The networking options configure the networking
+// service used for the floating IPs and ports of the load balancers.
+func NewService(scope *scope.WithLogger, networkingOptions networking.Options) (*Service, error) {
 	loadbalancerClient, err := scope.NewLbClient()
 	if err != nil {
 		return nil, err
 	}
 
-	networkingService, err := networking.NewService(scope)
+	networkingService, err := networking.NewService(scope, networkingOptions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create networking service: %v", err)
 	}
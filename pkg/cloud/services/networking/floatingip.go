@@ -47,6 +47,18 @@ func (s *Service) GetOrCreateFloatingIP(eventObject runtime.Object, openStackClu
 		}
 		// only admin can add ip address
 		fpCreateOpts.FloatingIP = ip
+	} else if s.floatingIPReuseTag != "" {
+		fp, err = s.getUnassignedFloatingIPByTag(openStackCluster.Status.ExternalNetwork.ID, s.floatingIPReuseTag)
+		if err != nil {
+			return nil, err
+		}
+		if fp != nil {
+			if err := s.tagFloatingIP(fp, openStackCluster.Spec.Tags); err != nil {
+				return nil, err
+			}
+			record.Eventf(eventObject, "SuccessfulReuseFloatingIP", "Reused floating IP %s with id %s", fp.FloatingIP, fp.ID)
+			return fp, nil
+		}
 	}
 
 	fpCreateOpts.FloatingNetworkID = openStackCluster.Status.ExternalNetwork.ID
@@ -58,20 +70,47 @@ func (s *Service) GetOrCreateFloatingIP(eventObject runtime.Object, openStackClu
 		return nil, err
 	}
 
-	if len(openStackCluster.Spec.Tags) > 0 {
-		mc := metrics.NewMetricPrometheusContext("floating_ip", "update")
-		_, err = s.client.ReplaceAllAttributesTags("floatingips", fp.ID, attributestags.ReplaceAllOpts{
-			Tags: openStackCluster.Spec.Tags,
-		})
-		if mc.ObserveRequest(err) != nil {
-			return nil, err
-		}
+	if err := s.tagFloatingIP(fp, openStackCluster.Spec.Tags); err != nil {
+		return nil, err
 	}
 
 	record.Eventf(eventObject, "SuccessfulCreateFloatingIP", "Created floating IP %s with id %s", fp.FloatingIP, fp.ID)
 	return fp, nil
 }
 
+// getUnassignedFloatingIPByTag returns a floating IP of the network with the given tag which isn't associated with a
+// port, or nil if there is none.
+func (s *Service) getUnassignedFloatingIPByTag(networkID, tag string) (*floatingips.FloatingIP, error) {
+	fpList, err := s.client.ListFloatingIP(floatingips.ListOpts{FloatingNetworkID: networkID, Tags: tag})
+	if err != nil {
+		return nil, err
+	}
+	for i := range fpList {
+		if fpList[i].PortID == "" {
+			return &fpList[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// tagFloatingIP replaces the tags of a floating IP with the tags of the cluster, and the reuse tag if the floating
+// IPs are reused, so that a reused floating IP doesn't keep the tags of the cluster it was used by before.
+func (s *Service) tagFloatingIP(fp *floatingips.FloatingIP, clusterTags []string) error {
+	tags := clusterTags
+	if s.floatingIPReuseTag != "" {
+		tags = append(append([]string{}, clusterTags...), s.floatingIPReuseTag)
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	mc := metrics.NewMetricPrometheusContext("floating_ip", "update")
+	_, err := s.client.ReplaceAllAttributesTags("floatingips", fp.ID, attributestags.ReplaceAllOpts{
+		Tags: tags,
+	})
+	return mc.ObserveRequest(err)
+}
+
 func (s *Service) CreateFloatingIPForPool(pool *v1alpha1.OpenStackFloatingIPPool) (*floatingips.FloatingIP, error) {
 	var fpCreateOpts floatingips.CreateOpts
 
@@ -147,6 +186,18 @@ func (s *Service) DeleteFloatingIP(eventObject runtime.Object, ip string) error
 		return nil
 	}
 
+	// Floating IPs with the reuse tag are kept, unassigned, for the next cluster.
+	if s.floatingIPReuseTag != "" && hasAllTags(fip.Tags, []string{s.floatingIPReuseTag}) {
+		if fip.PortID != "" {
+			if _, err := s.client.UpdateFloatingIP(fip.ID, &floatingips.UpdateOpts{PortID: nil}); err != nil {
+				record.Warnf(eventObject, "FailedDisassociateFloatingIP", "Failed to disassociate floating IP %s: %v", ip, err)
+				return err
+			}
+		}
+		record.Eventf(eventObject, "SuccessfulReleaseFloatingIP", "Kept floating IP %s with tag %s for reuse", ip, s.floatingIPReuseTag)
+		return nil
+	}
+
 	err = s.client.DeleteFloatingIP(fip.ID)
 	if err != nil {
 		record.Warnf(eventObject, "FailedDeleteFloatingIP", "Failed to delete floating IP %s: %v", ip, err)
@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/attributestags"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/layer3/floatingips"
 	. "github.com/onsi/gomega"
 
@@ -84,3 +85,141 @@ func Test_GetOrCreateFloatingIP(t *testing.T) {
 		})
 	}
 }
+
+func Test_GetOrCreateFloatingIPReuseTag(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const reuseTag = "capo-reuse"
+
+	tests := []struct {
+		name        string
+		ip          string
+		clusterTags []string
+		expect      func(m *mock.MockNetworkClientMockRecorder)
+		want        *floatingips.FloatingIP
+	}{
+		{
+			name:        "reuses an unassigned floating IP with the tag",
+			clusterTags: []string{"cluster-tag"},
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.
+					ListFloatingIP(floatingips.ListOpts{FloatingNetworkID: "ext-net", Tags: reuseTag}).
+					Return([]floatingips.FloatingIP{
+						{ID: "assigned", FloatingIP: "192.168.111.1", PortID: "port"},
+						{ID: "unassigned", FloatingIP: "192.168.111.2"},
+					}, nil)
+				m.
+					ReplaceAllAttributesTags("floatingips", "unassigned", attributestags.ReplaceAllOpts{Tags: []string{"cluster-tag", reuseTag}}).
+					Return([]string{"cluster-tag", reuseTag}, nil)
+			},
+			want: &floatingips.FloatingIP{ID: "unassigned", FloatingIP: "192.168.111.2"},
+		},
+		{
+			name: "allocates and tags a floating IP when none is unassigned",
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.
+					ListFloatingIP(floatingips.ListOpts{FloatingNetworkID: "ext-net", Tags: reuseTag}).
+					Return([]floatingips.FloatingIP{{ID: "assigned", FloatingIP: "192.168.111.1", PortID: "port"}}, nil)
+				m.
+					CreateFloatingIP(floatingips.CreateOpts{
+						FloatingNetworkID: "ext-net",
+						Description:       "Created by cluster-api-provider-openstack cluster test-cluster",
+					}).
+					Return(&floatingips.FloatingIP{ID: "created", FloatingIP: "192.168.111.3"}, nil)
+				m.
+					ReplaceAllAttributesTags("floatingips", "created", attributestags.ReplaceAllOpts{Tags: []string{reuseTag}}).
+					Return([]string{reuseTag}, nil)
+			},
+			want: &floatingips.FloatingIP{ID: "created", FloatingIP: "192.168.111.3"},
+		},
+		{
+			name: "explicit floating IP isn't looked up by tag",
+			ip:   "192.168.111.0",
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.
+					ListFloatingIP(floatingips.ListOpts{FloatingIP: "192.168.111.0"}).
+					Return([]floatingips.FloatingIP{{FloatingIP: "192.168.111.0"}}, nil)
+			},
+			want: &floatingips.FloatingIP{FloatingIP: "192.168.111.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockClient := mock.NewMockNetworkClient(mockCtrl)
+			tt.expect(mockClient.EXPECT())
+			s := Service{
+				client:             mockClient,
+				floatingIPReuseTag: reuseTag,
+			}
+			openStackCluster := &infrav1.OpenStackCluster{
+				Spec: infrav1.OpenStackClusterSpec{Tags: tt.clusterTags},
+				Status: infrav1.OpenStackClusterStatus{
+					ExternalNetwork: &infrav1.NetworkStatus{ID: "ext-net"},
+				},
+			}
+			eventObject := infrav1.OpenStackMachine{}
+			got, err := s.GetOrCreateFloatingIP(&eventObject, openStackCluster, "test-cluster", tt.ip)
+			g.Expect(err).ShouldNot(HaveOccurred())
+			g.Expect(got).To(Equal(tt.want))
+		})
+	}
+}
+
+func Test_DeleteFloatingIPReuseTag(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		reuseTag = "capo-reuse"
+		ip       = "192.168.111.0"
+	)
+
+	tests := []struct {
+		name   string
+		expect func(m *mock.MockNetworkClientMockRecorder)
+	}{
+		{
+			name: "keeps an unassigned floating IP with the tag",
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.
+					ListFloatingIP(floatingips.ListOpts{FloatingIP: ip}).
+					Return([]floatingips.FloatingIP{{ID: "fip", FloatingIP: ip, Tags: []string{reuseTag}}}, nil)
+			},
+		},
+		{
+			name: "disassociates an assigned floating IP with the tag",
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.
+					ListFloatingIP(floatingips.ListOpts{FloatingIP: ip}).
+					Return([]floatingips.FloatingIP{{ID: "fip", FloatingIP: ip, PortID: "port", Tags: []string{reuseTag}}}, nil)
+				m.
+					UpdateFloatingIP("fip", &floatingips.UpdateOpts{PortID: nil}).
+					Return(&floatingips.FloatingIP{ID: "fip", FloatingIP: ip}, nil)
+			},
+		},
+		{
+			name: "releases a floating IP without the tag",
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.
+					ListFloatingIP(floatingips.ListOpts{FloatingIP: ip}).
+					Return([]floatingips.FloatingIP{{ID: "fip", FloatingIP: ip, Tags: []string{"cluster-tag"}}}, nil)
+				m.DeleteFloatingIP("fip").Return(nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockClient := mock.NewMockNetworkClient(mockCtrl)
+			tt.expect(mockClient.EXPECT())
+			s := Service{
+				client:             mockClient,
+				floatingIPReuseTag: reuseTag,
+			}
+			eventObject := infrav1.OpenStackCluster{}
+			g.Expect(s.DeleteFloatingIP(&eventObject, ip)).To(Succeed())
+		})
+	}
+}
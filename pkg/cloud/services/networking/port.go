@@ -80,11 +80,17 @@ func (s *Service) CreatePort(eventObject runtime.Object, clusterName string, por
 		if portOpts.SecurityGroups != nil {
 			securityGroups, err = s.GetSecurityGroups(portOpts.SecurityGroups)
 			if err != nil {
-				return nil, fmt.Errorf("error getting security groups: %v", err)
+				return nil, fmt.Errorf("error getting security groups: %w", err)
 			}
 		}
-		// inherit port security groups from the instance if not explicitly specified
-		if len(securityGroups) == 0 {
+		if pointer.BoolDeref(portOpts.ExcludeManagedSecurityGroups, false) {
+			// A port excluded from the managed security groups doesn't inherit them from the instance. It has no
+			// security group, rather than the default security group, if it has none of its own.
+			if securityGroups == nil {
+				securityGroups = []string{}
+			}
+		} else if len(securityGroups) == 0 {
+			// inherit port security groups from the instance if not explicitly specified
 			securityGroups = instanceSecurityGroups
 		}
 	}
@@ -313,6 +319,20 @@ func GetPortName(instanceName string, opts *infrav1.PortOpts, netIndex int) stri
 	return fmt.Sprintf("%s-%d", instanceName, netIndex)
 }
 
+// ExcludeManagedSecurityGroups returns the ports, with the ports excluded from the managed security groups having
+// the unmanaged security groups of the instance unless they have their own security groups.
+func ExcludeManagedSecurityGroups(ports []infrav1.PortOpts, unmanagedSecurityGroups []infrav1.SecurityGroupFilter) []infrav1.PortOpts {
+	result := make([]infrav1.PortOpts, len(ports))
+	for i := range ports {
+		port := ports[i].DeepCopy()
+		if pointer.BoolDeref(port.ExcludeManagedSecurityGroups, false) && len(port.SecurityGroups) == 0 {
+			port.SecurityGroups = unmanagedSecurityGroups
+		}
+		result[i] = *port
+	}
+	return result
+}
+
 func (s *Service) CreatePorts(eventObject runtime.Object, clusterName string, ports []infrav1.PortOpts, securityGroups []infrav1.SecurityGroupFilter, instanceTags []string, instanceName string) ([]infrav1.PortStatus, error) {
 	return s.createPortsImpl(eventObject, clusterName, ports, securityGroups, instanceTags, instanceName)
 }
@@ -320,7 +340,7 @@ func (s *Service) CreatePorts(eventObject runtime.Object, clusterName string, po
 func (s *Service) createPortsImpl(eventObject runtime.Object, clusterName string, ports []infrav1.PortOpts, securityGroups []infrav1.SecurityGroupFilter, instanceTags []string, instanceName string) ([]infrav1.PortStatus, error) {
 	instanceSecurityGroups, err := s.GetSecurityGroups(securityGroups)
 	if err != nil {
-		return nil, fmt.Errorf("error getting security groups: %v", err)
+		return nil, fmt.Errorf("error getting security groups: %w", err)
 	}
 
 	portsStatus := make([]infrav1.PortStatus, 0, len(ports))
@@ -580,11 +600,9 @@ func (s *Service) AdoptMachinePorts(scope *scope.WithLogger, openStackMachine *i
 // A port is searched by name and network ID and has to be unique.
 // If the port is not found, it'll be ignored because it'll be created after the adoption.
 func (s *Service) AdoptBastionPorts(scope *scope.WithLogger, openStackCluster *infrav1.OpenStackCluster, bastionName string) (changed bool, err error) {
-	changed = false
-
 	if openStackCluster.Status.Network == nil {
 		scope.Logger().V(5).Info("Network status is nil, skipping the adoption of ports")
-		return changed, nil
+		return false, nil
 	}
 
 	if openStackCluster.Status.Bastion == nil {
@@ -592,21 +610,34 @@ func (s *Service) AdoptBastionPorts(scope *scope.WithLogger, openStackCluster *i
 		openStackCluster.Status.Bastion = &infrav1.BastionStatus{}
 	}
 
-	desiredPorts := openStackCluster.Status.Bastion.ReferencedResources.PortsOpts
+	return s.AdoptBastionReplicaPorts(scope, openStackCluster, openStackCluster.Status.Bastion, bastionName)
+}
+
+// AdoptBastionReplicaPorts adopts the ports of a bastion replica like AdoptBastionPorts, adding them to the given
+// status of the replica.
+func (s *Service) AdoptBastionReplicaPorts(scope *scope.WithLogger, openStackCluster *infrav1.OpenStackCluster, bastionStatus *infrav1.BastionStatus, bastionName string) (changed bool, err error) {
+	changed = false
+
+	if openStackCluster.Status.Network == nil {
+		scope.Logger().V(5).Info("Network status is nil, skipping the adoption of ports")
+		return changed, nil
+	}
+
+	desiredPorts := bastionStatus.ReferencedResources.PortsOpts
 
 	// We can skip adoption if the ports are already in the status
-	if len(desiredPorts) == len(openStackCluster.Status.Bastion.DependentResources.PortsStatus) {
+	if len(desiredPorts) == len(bastionStatus.DependentResources.PortsStatus) {
 		return changed, nil
 	}
 
-	scope.Logger().Info("Adopting bastion ports for OpenStackCluster", "name", openStackCluster.Name)
+	scope.Logger().Info("Adopting bastion ports for OpenStackCluster", "name", openStackCluster.Name, "bastion", bastionName)
 
 	// We create ports in order and adopt them in order in PortsStatus.
 	// This means that if port N doesn't exist we know that ports >N don't exist.
 	// We can therefore stop searching for ports once we find one that doesn't exist.
 	for i, port := range desiredPorts {
 		// check if the port is in status first and if it is, skip it
-		if i < len(openStackCluster.Status.Bastion.DependentResources.PortsStatus) {
+		if i < len(bastionStatus.DependentResources.PortsStatus) {
 			scope.Logger().V(5).Info("Port already in status, skipping it", "port index", i)
 			continue
 		}
@@ -632,7 +663,7 @@ func (s *Service) AdoptBastionPorts(scope *scope.WithLogger, openStackCluster *i
 
 		// The desired port was found, so we add it to the status
 		scope.Logger().V(5).Info("Port found, adding it to the status", "port index", i)
-		openStackCluster.Status.Bastion.DependentResources.PortsStatus = append(openStackCluster.Status.Bastion.DependentResources.PortsStatus, infrav1.PortStatus{ID: ports[0].ID})
+		bastionStatus.DependentResources.PortsStatus = append(bastionStatus.DependentResources.PortsStatus, infrav1.PortStatus{ID: ports[0].ID})
 		changed = true
 	}
 
@@ -406,6 +406,91 @@ func Test_CreatePort(t *testing.T) {
 			&ports.Port{ID: portID1, PropagateUplinkStatus: true},
 			false,
 		},
+		{
+			"creates port without security groups nor address pairs when port security is disabled",
+			"foo-port-1",
+			infrav1.PortOpts{
+				Network: &infrav1.NetworkFilter{
+					ID: netID,
+				},
+				SecurityGroups: portSecurityGroupFilters,
+				AllowedAddressPairs: []infrav1.AddressPair{{
+					IPAddress: "10.10.10.10",
+				}},
+				DisablePortSecurity: pointer.Bool(true),
+			},
+			instanceSecurityGroups,
+			[]string{},
+			func(m *mock.MockNetworkClientMockRecorder) {
+				m.
+					CreatePort(portsbinding.CreateOptsExt{
+						CreateOptsBuilder: portsecurity.PortCreateOptsExt{
+							CreateOptsBuilder: ports.CreateOpts{
+								Name:                "foo-port-1",
+								Description:         "Created by cluster-api-provider-openstack cluster test-cluster",
+								NetworkID:           netID,
+								AllowedAddressPairs: []ports.AddressPair{},
+							},
+							PortSecurityEnabled: pointer.Bool(false),
+						},
+					}).Return(&ports.Port{ID: portID1}, nil)
+			},
+			&ports.Port{ID: portID1},
+			false,
+		},
+		{
+			"creates port excluded from the managed security groups without security groups",
+			"foo-port-1",
+			infrav1.PortOpts{
+				Network: &infrav1.NetworkFilter{
+					ID: netID,
+				},
+				ExcludeManagedSecurityGroups: pointer.Bool(true),
+			},
+			instanceSecurityGroups,
+			[]string{},
+			func(m *mock.MockNetworkClientMockRecorder) {
+				m.
+					CreatePort(portsbinding.CreateOptsExt{
+						CreateOptsBuilder: ports.CreateOpts{
+							Name:                "foo-port-1",
+							Description:         "Created by cluster-api-provider-openstack cluster test-cluster",
+							SecurityGroups:      &[]string{},
+							NetworkID:           netID,
+							AllowedAddressPairs: []ports.AddressPair{},
+						},
+					}).Return(&ports.Port{ID: portID1}, nil)
+			},
+			&ports.Port{ID: portID1},
+			false,
+		},
+		{
+			"creates port excluded from the managed security groups with its own security groups",
+			"foo-port-1",
+			infrav1.PortOpts{
+				Network: &infrav1.NetworkFilter{
+					ID: netID,
+				},
+				SecurityGroups:               portSecurityGroupFilters,
+				ExcludeManagedSecurityGroups: pointer.Bool(true),
+			},
+			instanceSecurityGroups,
+			[]string{},
+			func(m *mock.MockNetworkClientMockRecorder) {
+				m.
+					CreatePort(portsbinding.CreateOptsExt{
+						CreateOptsBuilder: ports.CreateOpts{
+							Name:                "foo-port-1",
+							Description:         "Created by cluster-api-provider-openstack cluster test-cluster",
+							SecurityGroups:      &securityGroupUUIDs,
+							NetworkID:           netID,
+							AllowedAddressPairs: []ports.AddressPair{},
+						},
+					}).Return(&ports.Port{ID: portID1}, nil)
+			},
+			&ports.Port{ID: portID1},
+			false,
+		},
 	}
 
 	eventObject := &infrav1.OpenStackMachine{}
@@ -436,6 +521,27 @@ func Test_CreatePort(t *testing.T) {
 	}
 }
 
+func TestExcludeManagedSecurityGroups(t *testing.T) {
+	g := NewWithT(t)
+
+	unmanagedSecurityGroups := []infrav1.SecurityGroupFilter{{Name: "machine-secgroup"}}
+	portSecurityGroups := []infrav1.SecurityGroupFilter{{Name: "port-secgroup"}}
+	portOpts := []infrav1.PortOpts{
+		{NameSuffix: pointer.String("primary")},
+		{NameSuffix: pointer.String("sriov"), ExcludeManagedSecurityGroups: pointer.Bool(true)},
+		{NameSuffix: pointer.String("dpdk"), ExcludeManagedSecurityGroups: pointer.Bool(true), SecurityGroups: portSecurityGroups},
+	}
+
+	got := ExcludeManagedSecurityGroups(portOpts, unmanagedSecurityGroups)
+	g.Expect(got).To(HaveLen(3))
+	// The ports not excluded still inherit the security groups of the instance.
+	g.Expect(got[0].SecurityGroups).To(BeNil())
+	g.Expect(got[1].SecurityGroups).To(Equal(unmanagedSecurityGroups))
+	g.Expect(got[2].SecurityGroups).To(Equal(portSecurityGroups))
+	// The ports passed in are unchanged.
+	g.Expect(portOpts[1].SecurityGroups).To(BeNil())
+}
+
 func TestService_normalizePorts(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -17,86 +17,577 @@ limitations under the License.
 package networking
 
 import (
+	"context"
+	"errors"
 	"fmt"
-
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/attributestags"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
+	"golang.org/x/sync/errgroup"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/audit"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/clients"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/metrics"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/record"
+	capoerrors "sigs.k8s.io/cluster-api-provider-openstack/pkg/utils/errors"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/utils/hash"
+)
+
+// SecurityGroupNameConflictPolicy is the policy applied when several security groups have the name of a
+// managed security group.
+type SecurityGroupNameConflictPolicy string
+
+const (
+	// SecurityGroupNameConflictError fails the reconcile.
+	SecurityGroupNameConflictError SecurityGroupNameConflictPolicy = "Error"
+	// SecurityGroupNameConflictOldest uses the oldest security group.
+	SecurityGroupNameConflictOldest SecurityGroupNameConflictPolicy = "Oldest"
+	// SecurityGroupNameConflictTagged uses the only security group with all the tags of the cluster.
+	SecurityGroupNameConflictTagged SecurityGroupNameConflictPolicy = "Tagged"
 )
 
+// validate returns an error if the policy is unknown.
+func (p SecurityGroupNameConflictPolicy) validate() error {
+	switch p {
+	case SecurityGroupNameConflictError, SecurityGroupNameConflictOldest, SecurityGroupNameConflictTagged:
+		return nil
+	}
+	return fmt.Errorf("invalid security group name conflict policy %q, must be one of %s, %s or %s", p,
+		SecurityGroupNameConflictError, SecurityGroupNameConflictOldest, SecurityGroupNameConflictTagged)
+}
+
+// secGroupClientRetryBackoff returns the backoff of the retries of the security group requests failing with a
+// transient server error, making the given number of attempts.
+func secGroupClientRetryBackoff(attempts int) wait.Backoff {
+	return wait.Backoff{
+		Duration: 500 * time.Millisecond,
+		Factor:   2,
+		Jitter:   0.1,
+		Steps:    attempts,
+	}
+}
+
+// secGroupListPageSize is the number of security groups requested per page when the listings are limited.
+const secGroupListPageSize = 100
+
+const retryIntervalSecGroupPropagation = 2 * time.Second
+
 const (
 	secGroupPrefix     string = "k8s"
 	controlPlaneSuffix string = "controlplane"
 	workerSuffix       string = "worker"
+	nodeSuffix         string = "node"
 	bastionSuffix      string = "bastion"
 	allNodesSuffix     string = "allNodes"
+	shadowSuffix       string = "shadow"
 	remoteGroupIDSelf  string = "self"
 )
 
+// maxSecGroupNameLength is the maximum length of the name of a security group allowed by Neutron.
+const maxSecGroupNameLength = 255
+
+// ErrSecurityGroupReconcileAttemptsExhausted is returned by ReconcileSecurityGroups once the security groups
+// failed to reconcile the maximum number of consecutive attempts.
+var ErrSecurityGroupReconcileAttemptsExhausted = errors.New("maximum security group reconcile attempts reached")
+
+// ErrSecurityGroupRuleLimitExceeded is returned when the desired rules of a security group exceed the maximum
+// number of rules per security group allowed by the cloud.
+var ErrSecurityGroupRuleLimitExceeded = errors.New("maximum number of rules per security group exceeded")
+
+// ErrSecurityGroupRemoteGroupPending is returned when rules were deferred because the managed security group they
+// reference was created but is not listable yet. The rules are created by a later reconcile.
+var ErrSecurityGroupRemoteGroupPending = errors.New("referenced security group is not listable yet")
+
+// ErrSecurityGroupNotUnique is returned when several security groups have the name of a managed security group,
+// and the name conflict policy doesn't pick one of them.
+var ErrSecurityGroupNotUnique = errors.New("more than one security group found")
+
+// SecurityGroupNotFoundError is returned by GetSecurityGroups when a filter matches no security group, e.g.
+// because the spec references a group which doesn't exist. Unlike the errors of the Neutron API, retrying doesn't
+// help until the group is created.
+type SecurityGroupNotFoundError struct {
+	// Filter is the filter which matched no security group.
+	Filter infrav1.SecurityGroupFilter
+}
+
+func (e *SecurityGroupNotFoundError) Error() string {
+	if e.Filter.Name != "" {
+		return fmt.Sprintf("security group %s not found", e.Filter.Name)
+	}
+	// Groups may be selected by their tags only, e.g. all the groups tagged with an environment name.
+	return fmt.Sprintf("no security group found matching filter %+v", e.Filter)
+}
+
+// SecurityGroupProjectNotAccessibleError is returned when the credentials of the cluster are not allowed to manage
+// the security groups of the project set in managedSecurityGroups.projectID.
+type SecurityGroupProjectNotAccessibleError struct {
+	// ProjectID is the ID of the project which is not accessible.
+	ProjectID string
+	// Err is the error returned by Neutron.
+	Err error
+}
+
+func (e *SecurityGroupProjectNotAccessibleError) Error() string {
+	return fmt.Sprintf("the credentials of the cluster are not allowed to manage the security groups of project %s, which usually requires the admin role: %v", e.ProjectID, e.Err)
+}
+
+func (e *SecurityGroupProjectNotAccessibleError) Unwrap() error {
+	return e.Err
+}
+
+// pendingRemoteGroupIDPrefix prefixes the remote group ID of the rules referencing a pending managed group.
+const pendingRemoteGroupIDPrefix = "pending:"
+
+// pendingRemoteGroupID returns the placeholder remote group ID of the rules referencing the pending managed group
+// with the given suffix.
+func pendingRemoteGroupID(suffix string) string {
+	return pendingRemoteGroupIDPrefix + suffix
+}
+
+// deferPendingRemoteGroupRules splits the rules into those which can be created and those referencing a pending
+// managed group.
+func deferPendingRemoteGroupRules(rules []resolvedSecurityGroupRuleSpec) (ready, deferred []resolvedSecurityGroupRuleSpec) {
+	ready = make([]resolvedSecurityGroupRuleSpec, 0, len(rules))
+	for _, rule := range rules {
+		if strings.HasPrefix(rule.RemoteGroupID, pendingRemoteGroupIDPrefix) {
+			deferred = append(deferred, rule)
+			continue
+		}
+		ready = append(ready, rule)
+	}
+	return ready, deferred
+}
+
+// securityGroupRuleLimitPattern matches the errors returned by the backends capping the rules per security group.
+var securityGroupRuleLimitPattern = regexp.MustCompile(`(?i)rules per security group|security ?group ?rules? ?limit`)
+
 // ReconcileSecurityGroups reconcile the security groups.
-func (s *Service) ReconcileSecurityGroups(openStackCluster *infrav1.OpenStackCluster, clusterName string) error {
+// The consecutive failures are counted in the status of the cluster. Once they reach the maximum number of
+// attempts, the returned error wraps ErrSecurityGroupReconcileAttemptsExhausted.
+// The requests to Neutron are made with ctx, and are aborted once it is done.
+func (s *Service) ReconcileSecurityGroups(ctx context.Context, openStackCluster *infrav1.OpenStackCluster, clusterName string) error {
+	ctx, cancel, err := s.withSecurityGroupClientTimeout(ctx, openStackCluster)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+
+	err = s.withClientContext(ctx).reconcileSecurityGroups(ctx, openStackCluster, clusterName)
+	metrics.SetSecurityGroupRules(openStackCluster.Namespace, clusterName, getSecGroupRuleCounts(openStackCluster))
+	if err != nil {
+		// Duplicate groups are fixed by the operator, so they are reported on the cluster rather than only in the logs.
+		if errors.Is(err, ErrSecurityGroupNotUnique) {
+			conditions.Set(openStackCluster, &clusterv1.Condition{
+				Type:    infrav1.SecurityGroupNotUniqueCondition,
+				Status:  corev1.ConditionTrue,
+				Reason:  infrav1.DuplicateSecurityGroupNameReason,
+				Message: err.Error(),
+			})
+		}
+		// Waiting for removed groups to be unused, or for created groups to be listable, is not a failure.
+		if errors.Is(err, ErrSecurityGroupInUse) || errors.Is(err, ErrSecurityGroupRemoteGroupPending) {
+			return err
+		}
+		openStackCluster.Status.SecurityGroupReconcileFailures++
+		if openStackCluster.Status.SecurityGroupReconcileFailures >= s.secGroupMaxReconcileAttempts {
+			return fmt.Errorf("%w after %d attempts: %w", ErrSecurityGroupReconcileAttemptsExhausted, openStackCluster.Status.SecurityGroupReconcileFailures, err)
+		}
+		return err
+	}
+	openStackCluster.Status.SecurityGroupReconcileFailures = 0
+	conditions.Delete(openStackCluster, infrav1.SecurityGroupNotUniqueCondition)
+	return nil
+}
+
+func (s *Service) reconcileSecurityGroups(ctx context.Context, openStackCluster *infrav1.OpenStackCluster, clusterName string) error {
 	s.scope.Logger().Info("Reconciling security groups")
 	if openStackCluster.Spec.ManagedSecurityGroups == nil {
-		s.scope.Logger().V(4).Info("No need to reconcile security groups")
-		return nil
+		openStackCluster.Status.ManagedSecurityGroups = nil
+		openStackCluster.Status.ManagedSecurityGroupRules = nil
+		return s.reconcileRemovedSecurityGroups(openStackCluster)
 	}
 
-	secControlPlaneGroupName := getSecControlPlaneGroupName(clusterName)
-	secWorkerGroupName := getSecWorkerGroupName(clusterName)
-	secGroupNames := map[string]string{
-		controlPlaneSuffix: secControlPlaneGroupName,
-		workerSuffix:       secWorkerGroupName,
+	if err := validateAllNodesRules(openStackCluster.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules); err != nil {
+		return err
+	}
+	if err := validateRules("shadowRules", openStackCluster.Spec.ManagedSecurityGroups.ShadowRules); err != nil {
+		return err
+	}
+	if err := s.validateSecGroupProject(openStackCluster); err != nil {
+		return err
 	}
 
-	if openStackCluster.Spec.Bastion != nil && openStackCluster.Spec.Bastion.Enabled {
-		secBastionGroupName := getSecBastionGroupName(clusterName)
-		secGroupNames[bastionSuffix] = secBastionGroupName
+	secGroupNames, err := getManagedSecGroupNames(openStackCluster, clusterName)
+	if err != nil {
+		return err
+	}
+
+	// The shadow group is deleted once it has no rules anymore.
+	if _, ok := secGroupNames[shadowSuffix]; !ok && openStackCluster.Status.ShadowSecurityGroup != nil {
+		if err := s.deleteSecurityGroup(openStackCluster, clusterName, getSecShadowGroupName(getSecGroupNamePrefix(openStackCluster), clusterName)); err != nil {
+			return err
+		}
+		openStackCluster.Status.ShadowSecurityGroup = nil
+	}
+
+	// The groups of the removed bastion replicas are deleted, starting from the last one.
+	for i := len(openStackCluster.Status.AdditionalBastionSecurityGroups); i >= 1 && i >= getBastionReplicas(openStackCluster); i-- {
+		if err := s.deleteSecurityGroup(openStackCluster, clusterName, getSecBastionReplicaGroupName(getSecGroupNamePrefix(openStackCluster), clusterName, i)); err != nil {
+			return err
+		}
+		openStackCluster.Status.AdditionalBastionSecurityGroups = openStackCluster.Status.AdditionalBastionSecurityGroups[:i-1]
+	}
+
+	if err := s.adoptExistingSecurityGroups(openStackCluster, secGroupNames); err != nil {
+		return err
+	}
+
+	if err := s.reconcileForeignSecurityGroups(openStackCluster, secGroupNames); err != nil {
+		return err
 	}
 
 	// create security groups first, because desired rules use group ids.
-	for _, v := range secGroupNames {
-		if err := s.createSecurityGroupIfNotExists(openStackCluster, v); err != nil {
+	for k, v := range secGroupNames {
+		description, err := s.secGroupDescription.render(openStackCluster, clusterName, k)
+		if err != nil {
+			return err
+		}
+		if err := s.createSecurityGroupIfNotExists(ctx, openStackCluster, v, description, getSecGroupStateless(openStackCluster, k)); err != nil {
 			return err
 		}
 	}
+	if err := s.checkSecGroupStatefulness(openStackCluster, secGroupNames); err != nil {
+		return err
+	}
 	// create desired security groups
 	desiredSecGroups, err := s.generateDesiredSecGroups(openStackCluster, secGroupNames)
 	if err != nil {
 		return err
 	}
+	if _, err := s.checkGoldenRules(openStackCluster, desiredSecGroups, secGroupNames); err != nil {
+		return err
+	}
+
+	previousSecGroups := getPreviousSecGroups(openStackCluster)
+	changedSecGroups, rulesHashes, err := getChangedSecGroups(desiredSecGroups, previousSecGroups)
+	if err != nil {
+		return err
+	}
+	// The warning is repeated on each reconcile, as long as SSH to the bastion isn't restricted.
+	if _, ok := desiredSecGroups[bastionSuffix]; ok && len(openStackCluster.Spec.Bastion.SSHAllowedCIDRs) == 0 {
+		record.Warnf(openStackCluster, "BastionSSHOpen", "The bastion security group %s permits SSH from anywhere: restrict it with spec.bastion.sshAllowedCIDRs", secGroupNames[bastionSuffix])
+	}
 
-	observedSecGroups := make(map[string]*infrav1.SecurityGroupStatus)
+	// The groups all exist by now, so their rules are reconciled concurrently. The groups reconciled by a failed
+	// pass are still all collected, so that their status is reported.
+	var (
+		mu                sync.Mutex
+		eg                errgroup.Group
+		observedSecGroups = make(map[string]*infrav1.SecurityGroupStatus)
+		deferredRules     int
+	)
 	for k, desiredSecGroup := range desiredSecGroups {
-		var err error
-		observedSecGroups[k], err = s.getSecurityGroupByName(desiredSecGroup.Name)
 
-		if err != nil {
-			return err
+		// When the spec was edited, only reconcile the groups it affects.
+		// Otherwise reconcile all of them to correct any drift.
+		if len(changedSecGroups) > 0 && !changedSecGroups[k] {
+			s.scope.Logger().V(4).Info("Security group rules are unchanged, skipping", "name", desiredSecGroup.Name)
+			mu.Lock()
+			observedSecGroups[k] = previousSecGroups[k]
+			mu.Unlock()
+			continue
 		}
 
-		if observedSecGroups[k].ID != "" {
-			observedSecGroup, err := s.reconcileGroupRules(desiredSecGroup, *observedSecGroups[k])
+		k, desiredSecGroup := k, desiredSecGroup
+		eg.Go(func() error {
+			observedSecGroup, deferred, err := s.reconcileSecGroup(ctx, openStackCluster, desiredSecGroup, previousSecGroups[k], rulesHashes[k])
 			if err != nil {
 				return err
 			}
-			observedSecGroups[k] = &observedSecGroup
-			continue
-		}
+			mu.Lock()
+			defer mu.Unlock()
+			observedSecGroups[k] = observedSecGroup
+			deferredRules += deferred
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		setReconciledSecGroupStatuses(openStackCluster, observedSecGroups)
+		s.setManagedSecGroupRulesStatus(openStackCluster, desiredSecGroups, observedSecGroups)
+		return err
 	}
+	s.setManagedSecGroupRulesStatus(openStackCluster, desiredSecGroups, observedSecGroups)
 
 	openStackCluster.Status.ControlPlaneSecurityGroup = observedSecGroups[controlPlaneSuffix]
 	openStackCluster.Status.WorkerSecurityGroup = observedSecGroups[workerSuffix]
+	openStackCluster.Status.NodeSecurityGroup = observedSecGroups[nodeSuffix]
+	setSharedNodeSecGroupStatuses(openStackCluster)
+	openStackCluster.Status.AllNodesSecurityGroup = observedSecGroups[allNodesSuffix]
 	openStackCluster.Status.BastionSecurityGroup = observedSecGroups[bastionSuffix]
+	openStackCluster.Status.ShadowSecurityGroup = observedSecGroups[shadowSuffix]
+	for i := 1; i < getBastionReplicas(openStackCluster); i++ {
+		if status := observedSecGroups[getBastionReplicaSuffix(i)]; status != nil {
+			setAdditionalBastionSecGroupStatus(openStackCluster, i, *status)
+		}
+	}
+
+	// The groups haven't converged until the deferred rules are created.
+	if deferredRules > 0 {
+		return fmt.Errorf("%w: %d rules deferred", ErrSecurityGroupRemoteGroupPending, deferredRules)
+	}
+
+	openStackCluster.Status.ManagedSecurityGroups = &infrav1.ManagedSecurityGroupsStatus{
+		LastReconciledTime: &metav1.Time{Time: s.clock.Now()},
+	}
+	s.probeSecurityGroupRules(ctx, openStackCluster)
 
 	return nil
 }
 
+// reconcileSecGroup reconciles the rules of an existing managed security group. It returns the status of the
+// group, and the number of its rules deferred because they reference groups which are not listable yet.
+func (s *Service) reconcileSecGroup(ctx context.Context, openStackCluster *infrav1.OpenStackCluster, desiredSecGroup securityGroupSpec, previous *infrav1.SecurityGroupStatus, rulesHash string) (*infrav1.SecurityGroupStatus, int, error) {
+	observedSecGroup, err := s.getSecurityGroupByName(desiredSecGroup.Name, getSecGroupProjectID(openStackCluster), openStackCluster.Spec.Tags)
+	if err != nil {
+		return nil, 0, err
+	}
+	if observedSecGroup.ID == "" {
+		return observedSecGroup, 0, nil
+	}
+
+	// The rules embedded in the group are capped by some backends, so list them all separately.
+	observedSecGroup.Rules, err = s.getSecurityGroupRules(observedSecGroup.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	observedSecGroup.Rules, err = s.deleteOrphanedRules(ctx, *observedSecGroup, s.getRuleOwnershipTags(openStackCluster))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if previous != nil {
+		observedSecGroup.RulesPendingDeletion = previous.RulesPendingDeletion
+	}
+
+	var deferred []resolvedSecurityGroupRuleSpec
+	desiredSecGroup.Rules, deferred = deferPendingRemoteGroupRules(desiredSecGroup.Rules)
+	if len(deferred) > 0 {
+		s.scope.Logger().Info("Deferring the rules referencing security groups which are not listable yet", "name", desiredSecGroup.Name, "rules", len(deferred))
+	}
+
+	desiredSecGroup.RuleTags = s.getRuleOwnershipTags(openStackCluster)
+	reconciledSecGroup, err := s.reconcileGroupRules(ctx, desiredSecGroup, *observedSecGroup)
+	if err != nil {
+		if errors.Is(err, ErrSecurityGroupRuleLimitExceeded) {
+			record.Warnf(openStackCluster, "SecurityGroupRuleLimitExceeded", "Failed to reconcile rules of security group %s: %v", desiredSecGroup.Name, err)
+		}
+		return nil, 0, err
+	}
+	reconciledSecGroup.RulesHash = rulesHash
+	return &reconciledSecGroup, len(deferred), nil
+}
+
+// getSecGroupRuleCounts returns the number of rules of the managed security groups in the status of the cluster,
+// keyed by their suffix.
+func getSecGroupRuleCounts(openStackCluster *infrav1.OpenStackCluster) map[string]int {
+	ruleCounts := make(map[string]int)
+	for k, status := range map[string]*infrav1.SecurityGroupStatus{
+		controlPlaneSuffix: openStackCluster.Status.ControlPlaneSecurityGroup,
+		workerSuffix:       openStackCluster.Status.WorkerSecurityGroup,
+		nodeSuffix:         openStackCluster.Status.NodeSecurityGroup,
+		allNodesSuffix:     openStackCluster.Status.AllNodesSecurityGroup,
+		bastionSuffix:      openStackCluster.Status.BastionSecurityGroup,
+		shadowSuffix:       openStackCluster.Status.ShadowSecurityGroup,
+	} {
+		if status == nil || status.ID == "" {
+			continue
+		}
+		// The shared node group is also reported as the control plane and worker groups, but only counted once.
+		if (k == controlPlaneSuffix || k == workerSuffix) && isSharedNodeSecGroupStatus(openStackCluster, status) {
+			continue
+		}
+		ruleCounts[k] = len(status.Rules)
+	}
+	for i, status := range openStackCluster.Status.AdditionalBastionSecurityGroups {
+		if status.ID != "" {
+			ruleCounts[getBastionReplicaSuffix(i+1)] = len(status.Rules)
+		}
+	}
+	return ruleCounts
+}
+
+// setReconciledSecGroupStatuses reports the groups reconciled by a pass which then failed in the status of the
+// cluster, so that the next pass doesn't reconcile them again. The status of the other groups is left unchanged.
+func setReconciledSecGroupStatuses(openStackCluster *infrav1.OpenStackCluster, reconciledSecGroups map[string]*infrav1.SecurityGroupStatus) {
+	for k, status := range reconciledSecGroups {
+		switch k {
+		case controlPlaneSuffix:
+			openStackCluster.Status.ControlPlaneSecurityGroup = status
+		case workerSuffix:
+			openStackCluster.Status.WorkerSecurityGroup = status
+		case nodeSuffix:
+			openStackCluster.Status.NodeSecurityGroup = status
+		case allNodesSuffix:
+			openStackCluster.Status.AllNodesSecurityGroup = status
+		case bastionSuffix:
+			// The bastion group is only reconciled while the bastion is enabled.
+			if openStackCluster.Spec.Bastion != nil && openStackCluster.Spec.Bastion.Enabled {
+				openStackCluster.Status.BastionSecurityGroup = status
+			}
+		case shadowSuffix:
+			openStackCluster.Status.ShadowSecurityGroup = status
+		default:
+			if i, ok := getBastionReplicaIndex(k); ok && status != nil && i < getBastionReplicas(openStackCluster) {
+				setAdditionalBastionSecGroupStatus(openStackCluster, i, *status)
+			}
+		}
+	}
+	if _, ok := reconciledSecGroups[nodeSuffix]; ok {
+		setSharedNodeSecGroupStatuses(openStackCluster)
+	}
+}
+
+// setSharedNodeSecGroupStatuses reports the shared node group as the control plane and worker groups too, for the
+// consumers of the status only aware of them, when the cluster has a shared node group.
+func setSharedNodeSecGroupStatuses(openStackCluster *infrav1.OpenStackCluster) {
+	if openStackCluster.Spec.ManagedSecurityGroups == nil || !openStackCluster.Spec.ManagedSecurityGroups.SharedNodeGroup {
+		return
+	}
+	openStackCluster.Status.ControlPlaneSecurityGroup = openStackCluster.Status.NodeSecurityGroup.DeepCopy()
+	openStackCluster.Status.WorkerSecurityGroup = openStackCluster.Status.NodeSecurityGroup.DeepCopy()
+}
+
+// isSharedNodeSecGroupStatus returns whether the status is the shared node group of the cluster.
+func isSharedNodeSecGroupStatus(openStackCluster *infrav1.OpenStackCluster, status *infrav1.SecurityGroupStatus) bool {
+	node := openStackCluster.Status.NodeSecurityGroup
+	return node != nil && node.ID != "" && status.ID == node.ID
+}
+
+// setAdditionalBastionSecGroupStatus reports the status of the security group of the bastion replica with the
+// given index, after the first, in the status of the cluster.
+func setAdditionalBastionSecGroupStatus(openStackCluster *infrav1.OpenStackCluster, replica int, status infrav1.SecurityGroupStatus) {
+	for len(openStackCluster.Status.AdditionalBastionSecurityGroups) < replica {
+		openStackCluster.Status.AdditionalBastionSecurityGroups = append(openStackCluster.Status.AdditionalBastionSecurityGroups, infrav1.SecurityGroupStatus{})
+	}
+	openStackCluster.Status.AdditionalBastionSecurityGroups[replica-1] = status
+}
+
+// getManagedSecGroupNames returns the names of the managed security groups of a cluster, keyed by their suffix.
+func getManagedSecGroupNames(openStackCluster *infrav1.OpenStackCluster, clusterName string) (map[string]string, error) {
+	namePrefix := getSecGroupNamePrefix(openStackCluster)
+	secGroupNames := make(map[string]string)
+	if openStackCluster.Spec.ManagedSecurityGroups.SharedNodeGroup {
+		secGroupNames[nodeSuffix] = getSecNodeGroupName(namePrefix, clusterName)
+	} else {
+		secGroupNames[controlPlaneSuffix] = getSecControlPlaneGroupName(namePrefix, clusterName)
+		secGroupNames[workerSuffix] = getSecWorkerGroupName(namePrefix, clusterName)
+	}
+
+	for i := 0; i < getBastionReplicas(openStackCluster); i++ {
+		secGroupNames[getBastionReplicaSuffix(i)] = getSecBastionReplicaGroupName(namePrefix, clusterName, i)
+	}
+
+	// The shadow group is reconciled like the other groups, but it is never attached to the ports of the machines.
+	if len(openStackCluster.Spec.ManagedSecurityGroups.ShadowRules) > 0 {
+		secGroupNames[shadowSuffix] = getSecShadowGroupName(namePrefix, clusterName)
+	}
+
+	if openStackCluster.Spec.ManagedSecurityGroups.SeparateAllNodesGroup {
+		secGroupNames[allNodesSuffix] = getSecAllNodesGroupName(namePrefix, clusterName)
+	}
+
+	// The length of the prefix is validated, but the names of the clusters created before their length was capped
+	// may still be too long.
+	for _, name := range secGroupNames {
+		if len(name) > maxSecGroupNameLength {
+			return nil, fmt.Errorf("security group name %s is longer than the %d characters allowed", name, maxSecGroupNameLength)
+		}
+	}
+	return secGroupNames, nil
+}
+
+// getPreviousSecGroups returns the managed security groups reported in the status of a cluster, keyed by their suffix.
+func getPreviousSecGroups(openStackCluster *infrav1.OpenStackCluster) map[string]*infrav1.SecurityGroupStatus {
+	previousSecGroups := map[string]*infrav1.SecurityGroupStatus{
+		controlPlaneSuffix: openStackCluster.Status.ControlPlaneSecurityGroup,
+		workerSuffix:       openStackCluster.Status.WorkerSecurityGroup,
+		nodeSuffix:         openStackCluster.Status.NodeSecurityGroup,
+		allNodesSuffix:     openStackCluster.Status.AllNodesSecurityGroup,
+		bastionSuffix:      openStackCluster.Status.BastionSecurityGroup,
+		shadowSuffix:       openStackCluster.Status.ShadowSecurityGroup,
+	}
+	for i := range openStackCluster.Status.AdditionalBastionSecurityGroups {
+		previousSecGroups[getBastionReplicaSuffix(i+1)] = &openStackCluster.Status.AdditionalBastionSecurityGroups[i]
+	}
+	return previousSecGroups
+}
+
+// getChangedSecGroups returns the suffixes of the desired security groups whose rules changed since they were
+// last reconciled, along with the hash of the rules of every desired security group.
+func getChangedSecGroups(desiredSecGroups map[string]securityGroupSpec, previousSecGroups map[string]*infrav1.SecurityGroupStatus) (map[string]bool, map[string]string, error) {
+	changed := make(map[string]bool)
+	rulesHashes := make(map[string]string, len(desiredSecGroups))
+	for k, desiredSecGroup := range desiredSecGroups {
+		rulesHash, err := hash.ComputeSpewHash(desiredSecGroup)
+		if err != nil {
+			return nil, nil, fmt.Errorf("computing hash of security group %s: %w", desiredSecGroup.Name, err)
+		}
+		rulesHashes[k] = strconv.Itoa(int(rulesHash))
+
+		// The groups with deferred rule deletions are reconciled again to complete them.
+		previous := previousSecGroups[k]
+		if previous == nil || previous.RulesHash != rulesHashes[k] || len(previous.RulesPendingDeletion) > 0 {
+			changed[k] = true
+		}
+	}
+	return changed, rulesHashes, nil
+}
+
 type securityGroupSpec struct {
 	Name  string
 	Rules []resolvedSecurityGroupRuleSpec
+	// Stateless is true if the group doesn't track connections, in which case return traffic must be
+	// permitted by explicit rules.
+	Stateless bool
+	// RuleTags are the tags of the rules created in the group. When set, the rules not carrying all of them are
+	// owned by someone else, and are never deleted.
+	RuleTags []string
+}
+
+// withReturnTrafficRules returns the group with the rules permitting the return traffic of userRules added
+// if the group is stateless. Stateful groups are returned unchanged.
+func withReturnTrafficRules(group securityGroupSpec, userRules []resolvedSecurityGroupRuleSpec) securityGroupSpec {
+	if !group.Stateless {
+		return group
+	}
+	group.Rules = append(group.Rules, withRuleOrigin(getSGReturnTraffic(userRules), infrav1.SecurityGroupRuleOriginGeneral)...)
+	return group
+}
+
+// withRuleOrigin returns a copy of the rules with their origin set.
+func withRuleOrigin(rules []resolvedSecurityGroupRuleSpec, origin infrav1.SecurityGroupRuleOrigin) []resolvedSecurityGroupRuleSpec {
+	result := make([]resolvedSecurityGroupRuleSpec, len(rules))
+	for i, rule := range rules {
+		rule.Origin = origin
+		result[i] = rule
+	}
+	return result
 }
 
 type resolvedSecurityGroupRuleSpec struct {
@@ -108,17 +599,22 @@ type resolvedSecurityGroupRuleSpec struct {
 	Protocol       string `json:"protocol,omitempty"`
 	RemoteGroupID  string `json:"remoteGroupID,omitempty"`
 	RemoteIPPrefix string `json:"remoteIPPrefix,omitempty"`
+	// Origin is the source of the rule. It is not sent to OpenStack, but reported in the status.
+	Origin infrav1.SecurityGroupRuleOrigin `json:"origin,omitempty"`
 }
 
+// Matches returns true if other is the rule r. The unset fields of other, e.g. of the rules created out-of-band,
+// are compared as their zero value. The direction, ether type and protocol are compared the way Neutron normalizes
+// them, so that e.g. a TCP rule matches the tcp rule Neutron reports.
 func (r resolvedSecurityGroupRuleSpec) Matches(other infrav1.SecurityGroupRuleStatus) bool {
-	return r.Description == *other.Description &&
-		r.Direction == other.Direction &&
-		r.EtherType == *other.EtherType &&
-		r.PortRangeMin == *other.PortRangeMin &&
-		r.PortRangeMax == *other.PortRangeMax &&
-		r.Protocol == *other.Protocol &&
-		r.RemoteGroupID == *other.RemoteGroupID &&
-		r.RemoteIPPrefix == *other.RemoteIPPrefix
+	return r.Description == pointer.StringDeref(other.Description, "") &&
+		strings.EqualFold(r.Direction, other.Direction) &&
+		canonicalEtherType(r.EtherType) == canonicalEtherType(pointer.StringDeref(other.EtherType, "")) &&
+		r.PortRangeMin == pointer.IntDeref(other.PortRangeMin, 0) &&
+		r.PortRangeMax == pointer.IntDeref(other.PortRangeMax, 0) &&
+		canonicalProtocol(r.Protocol, r.EtherType) == canonicalProtocol(pointer.StringDeref(other.Protocol, ""), pointer.StringDeref(other.EtherType, "")) &&
+		r.RemoteGroupID == pointer.StringDeref(other.RemoteGroupID, "") &&
+		r.RemoteIPPrefix == pointer.StringDeref(other.RemoteIPPrefix, "")
 }
 
 func (s *Service) generateDesiredSecGroups(openStackCluster *infrav1.OpenStackCluster, secGroupNames map[string]string) (map[string]securityGroupSpec, error) {
@@ -130,7 +626,8 @@ func (s *Service) generateDesiredSecGroups(openStackCluster *infrav1.OpenStackCl
 
 	var secControlPlaneGroupID string
 	var secWorkerGroupID string
-	var secBastionGroupID string
+	var secAllNodesGroupID string
+	secBastionGroupIDs := make([]string, getBastionReplicas(openStackCluster))
 
 	// remoteManagedGroups is a map of suffix to security group ID.
 	// It will be used to fill in the RemoteGroupID field of the security group rules
@@ -139,93 +636,227 @@ func (s *Service) generateDesiredSecGroups(openStackCluster *infrav1.OpenStackCl
 	remoteManagedGroups := make(map[string]string)
 
 	for i, v := range secGroupNames {
-		secGroup, err := s.getSecurityGroupByName(v)
+		secGroup, err := s.getSecurityGroupByName(v, getSecGroupProjectID(openStackCluster), openStackCluster.Spec.Tags)
 		if err != nil {
 			return desiredSecGroups, err
 		}
+		groupID := secGroup.ID
+		if groupID == "" {
+			// The group was just created but isn't listable yet. The rules referencing it would have no remote
+			// group, i.e. permit any remote, so they are deferred until its ID is known.
+			groupID = pendingRemoteGroupID(i)
+		}
 		switch i {
 		case controlPlaneSuffix:
-			secControlPlaneGroupID = secGroup.ID
+			secControlPlaneGroupID = groupID
 			remoteManagedGroups[controlPlaneSuffix] = secControlPlaneGroupID
 		case workerSuffix:
-			secWorkerGroupID = secGroup.ID
+			secWorkerGroupID = groupID
 			remoteManagedGroups[workerSuffix] = secWorkerGroupID
-		case bastionSuffix:
-			secBastionGroupID = secGroup.ID
-			remoteManagedGroups[bastionSuffix] = secBastionGroupID
+		case nodeSuffix:
+			// The rules of the control plane and the workers reference the shared node group instead.
+			secControlPlaneGroupID = groupID
+			secWorkerGroupID = groupID
+			remoteManagedGroups[controlPlaneSuffix] = groupID
+			remoteManagedGroups[workerSuffix] = groupID
+		case allNodesSuffix:
+			secAllNodesGroupID = groupID
+		default:
+			// The bastion group stands for the groups of all the bastion replicas.
+			if replica, ok := getBastionReplicaIndex(i); ok && replica < len(secBastionGroupIDs) {
+				secBastionGroupIDs[replica] = groupID
+				remoteManagedGroups[i] = groupID
+			}
 		}
 	}
 
-	// Start with the default rules
-	controlPlaneRules := append([]resolvedSecurityGroupRuleSpec{}, defaultRules...)
-	workerRules := append([]resolvedSecurityGroupRuleSpec{}, defaultRules...)
+	ports := getWellKnownPorts(openStackCluster)
+	dualStack := isDualStack(openStackCluster)
+
+	// Start with the default rules, unless the user permits the egress traffic of the nodes explicitly.
+	var nodeDefaultRules []resolvedSecurityGroupRuleSpec
+	if !openStackCluster.Spec.ManagedSecurityGroups.DisableDefaultRules {
+		nodeDefaultRules = withRuleOrigin(defaultRules, infrav1.SecurityGroupRuleOriginDefault)
+	}
+	controlPlaneRules := append([]resolvedSecurityGroupRuleSpec{}, nodeDefaultRules...)
+	workerRules := append([]resolvedSecurityGroupRuleSpec{}, nodeDefaultRules...)
+
+	// The workers may only permit the ingress traffic of the allNodes rules, and of the load balancers of the
+	// Services when opted in.
+	workerIngress := !openStackCluster.Spec.ManagedSecurityGroups.DisableWorkerIngressRules
 
-	controlPlaneRules = append(controlPlaneRules, getSGControlPlaneHTTPS()...)
-	workerRules = append(workerRules, getSGWorkerNodePort()...)
+	controlPlaneRules = append(controlPlaneRules, withRuleOrigin(getSGControlPlaneHTTPS(ports, dualStack), infrav1.SecurityGroupRuleOriginGeneral)...)
+	if workerIngress {
+		workerRules = append(workerRules, withRuleOrigin(getSGWorkerNodePort(ports, dualStack, openStackCluster.Spec.ManagedSecurityGroups.NodePortAllowedCIDRs), infrav1.SecurityGroupRuleOriginGeneral)...)
+	}
+
+	// Source CIDRs are derived from the cluster subnets, never from the router, which may be externally managed
+	// and have its gateway on a network unrelated to the cluster.
+	if openStackCluster.Spec.ManagedSecurityGroups.AllowLoadBalancerServiceTraffic {
+		workerRules = append(workerRules, withRuleOrigin(getSGWorkerLoadBalancerServices(openStackCluster, s.loadBalancerServices), infrav1.SecurityGroupRuleOriginGeneral)...)
+	}
 
 	// If we set additional ports to LB, we need create secgroup rules those ports, this apply to controlPlaneRules only
 	if openStackCluster.Spec.APIServerLoadBalancer.Enabled {
-		controlPlaneRules = append(controlPlaneRules, getSGControlPlaneAdditionalPorts(openStackCluster.Spec.APIServerLoadBalancer.AdditionalPorts)...)
+		controlPlaneRules = append(controlPlaneRules, withRuleOrigin(getSGControlPlaneAdditionalPorts(openStackCluster.Spec.APIServerLoadBalancer.AdditionalPorts, dualStack), infrav1.SecurityGroupRuleOriginGeneral)...)
 	}
 
 	if openStackCluster.Spec.ManagedSecurityGroups != nil && openStackCluster.Spec.ManagedSecurityGroups.AllowAllInClusterTraffic {
 		// Permit all ingress from the cluster security groups
-		controlPlaneRules = append(controlPlaneRules, getSGControlPlaneAllowAll(remoteGroupIDSelf, secWorkerGroupID)...)
-		workerRules = append(workerRules, getSGWorkerAllowAll(remoteGroupIDSelf, secControlPlaneGroupID)...)
+		controlPlaneRules = append(controlPlaneRules, withRuleOrigin(getSGControlPlaneAllowAll(remoteGroupIDSelf, secWorkerGroupID, dualStack), infrav1.SecurityGroupRuleOriginGeneral)...)
+		if workerIngress {
+			workerRules = append(workerRules, withRuleOrigin(getSGWorkerAllowAll(remoteGroupIDSelf, secControlPlaneGroupID, dualStack), infrav1.SecurityGroupRuleOriginGeneral)...)
+		}
 	} else {
-		controlPlaneRules = append(controlPlaneRules, getSGControlPlaneGeneral(remoteGroupIDSelf, secWorkerGroupID)...)
-		workerRules = append(workerRules, getSGWorkerGeneral(remoteGroupIDSelf, secControlPlaneGroupID)...)
+		controlPlaneRules = append(controlPlaneRules, withRuleOrigin(getSGControlPlaneGeneral(ports, remoteGroupIDSelf, secWorkerGroupID, dualStack), infrav1.SecurityGroupRuleOriginGeneral)...)
+		if workerIngress {
+			workerRules = append(workerRules, withRuleOrigin(getSGWorkerGeneral(ports, remoteGroupIDSelf, secControlPlaneGroupID, dualStack), infrav1.SecurityGroupRuleOriginGeneral)...)
+		}
 	}
 
-	// For now, we do not create a separate security group for allNodes.
-	// Instead, we append the rules for allNodes to the control plane and worker security groups.
-	allNodesRules, err := getAllNodesRules(remoteManagedGroups, openStackCluster.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules)
+	// Unless they have a separate group attached to all the nodes, the rules for allNodes are appended to the
+	// control plane and worker security groups.
+	// The rules are resolved once, from the IDs listed above, and the same rules are used for both groups.
+	allNodesRules, err := getAllNodesRules("allNodesSecurityGroupRules", remoteManagedGroups, s.GetSecurityGroups, openStackCluster.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules)
 	if err != nil {
 		return desiredSecGroups, err
 	}
-	controlPlaneRules = append(controlPlaneRules, allNodesRules...)
-	workerRules = append(workerRules, allNodesRules...)
+	allNodesRules = withRuleOrigin(allNodesRules, infrav1.SecurityGroupRuleOriginUser)
+	_, separateAllNodes := secGroupNames[allNodesSuffix]
+	duplicateAllNodesRules := true
+	if separateAllNodes {
+		duplicateAllNodesRules, err = s.isAllNodesGroupMissingFromPorts(secAllNodesGroupID, secControlPlaneGroupID, secWorkerGroupID)
+		if err != nil {
+			return desiredSecGroups, err
+		}
+	}
+	if duplicateAllNodesRules {
+		controlPlaneRules = append(controlPlaneRules, allNodesRules...)
+		workerRules = append(workerRules, allNodesRules...)
+	}
 
-	if openStackCluster.Spec.Bastion != nil && openStackCluster.Spec.Bastion.Enabled {
-		controlPlaneRules = append(controlPlaneRules, getSGControlPlaneSSH(secBastionGroupID)...)
-		workerRules = append(workerRules, getSGWorkerSSH(secBastionGroupID)...)
+	// SSH is permitted from every bastion replica, each of them having its own group.
+	for i, secBastionGroupID := range secBastionGroupIDs {
+		controlPlaneRules = append(controlPlaneRules, withRuleOrigin(getSGControlPlaneSSH(ports, secBastionGroupID, dualStack), infrav1.SecurityGroupRuleOriginBastion)...)
+		if workerIngress {
+			workerRules = append(workerRules, withRuleOrigin(getSGWorkerSSH(ports, secBastionGroupID, dualStack), infrav1.SecurityGroupRuleOriginBastion)...)
+		}
 
-		desiredSecGroups[bastionSuffix] = securityGroupSpec{
-			Name: secGroupNames[bastionSuffix],
+		desiredSecGroups[getBastionReplicaSuffix(i)] = securityGroupSpec{
+			Name: secGroupNames[getBastionReplicaSuffix(i)],
 			Rules: append(
-				[]resolvedSecurityGroupRuleSpec{
-					{
-						Description:  "SSH",
-						Direction:    "ingress",
-						EtherType:    "IPv4",
-						PortRangeMin: 22,
-						PortRangeMax: 22,
-						Protocol:     "tcp",
-					},
-				},
-				defaultRules...,
+				withRuleOrigin(getSGBastionSSH(ports, dualStack, openStackCluster.Spec.Bastion.SSHAllowedCIDRs), infrav1.SecurityGroupRuleOriginBastion),
+				withRuleOrigin(defaultRules, infrav1.SecurityGroupRuleOriginDefault)...,
 			),
 		}
 	}
 
 	desiredSecGroups[controlPlaneSuffix] = securityGroupSpec{
-		Name:  secGroupNames[controlPlaneSuffix],
-		Rules: controlPlaneRules,
+		Name:      secGroupNames[controlPlaneSuffix],
+		Rules:     controlPlaneRules,
+		Stateless: pointer.BoolDeref(getSecGroupStateless(openStackCluster, controlPlaneSuffix), false),
 	}
 
 	desiredSecGroups[workerSuffix] = securityGroupSpec{
-		Name:  secGroupNames[workerSuffix],
-		Rules: workerRules,
+		Name:      secGroupNames[workerSuffix],
+		Rules:     workerRules,
+		Stateless: pointer.BoolDeref(getSecGroupStateless(openStackCluster, workerSuffix), false),
+	}
+
+	// The allNodes rules are the ones provided by the user, so stateless groups get their return traffic permitted.
+	if duplicateAllNodesRules {
+		for k, group := range desiredSecGroups {
+			if _, ok := getBastionReplicaIndex(k); ok {
+				continue
+			}
+			desiredSecGroups[k] = withReturnTrafficRules(group, allNodesRules)
+		}
+	}
+
+	if name, ok := secGroupNames[nodeSuffix]; ok {
+		desiredSecGroups[nodeSuffix] = mergeNodeSecGroups(name, secControlPlaneGroupID, desiredSecGroups[controlPlaneSuffix], desiredSecGroups[workerSuffix])
+		delete(desiredSecGroups, controlPlaneSuffix)
+		delete(desiredSecGroups, workerSuffix)
+	}
+
+	if separateAllNodes {
+		desiredSecGroups[allNodesSuffix] = withReturnTrafficRules(securityGroupSpec{
+			Name:      secGroupNames[allNodesSuffix],
+			Rules:     append(append([]resolvedSecurityGroupRuleSpec{}, nodeDefaultRules...), allNodesRules...),
+			Stateless: pointer.BoolDeref(getSecGroupStateless(openStackCluster, allNodesSuffix), false),
+		}, allNodesRules)
+	}
+
+	// The shadow rules stand for the allNodes rules they propose, so they are resolved the same way.
+	if _, ok := secGroupNames[shadowSuffix]; ok {
+		shadowRules, err := getAllNodesRules("shadowRules", remoteManagedGroups, s.GetSecurityGroups, openStackCluster.Spec.ManagedSecurityGroups.ShadowRules)
+		if err != nil {
+			return desiredSecGroups, fmt.Errorf("shadowRules: %w", err)
+		}
+		shadowRules = withRuleOrigin(shadowRules, infrav1.SecurityGroupRuleOriginUser)
+		desiredSecGroups[shadowSuffix] = withReturnTrafficRules(securityGroupSpec{
+			Name:      secGroupNames[shadowSuffix],
+			Rules:     append(append([]resolvedSecurityGroupRuleSpec{}, nodeDefaultRules...), shadowRules...),
+			Stateless: pointer.BoolDeref(getSecGroupStateless(openStackCluster, shadowSuffix), false),
+		}, shadowRules)
 	}
 	return desiredSecGroups, nil
 }
 
-// getAllNodesRules returns the rules for the allNodes security group that should be created.
-func getAllNodesRules(remoteManagedGroups map[string]string, allNodesSecurityGroupRules []infrav1.SecurityGroupRuleSpec) ([]resolvedSecurityGroupRuleSpec, error) {
+// mergeNodeSecGroups returns the shared node security group, carrying the union of the rules of the control plane
+// and worker groups. The rules referencing the node group itself are made self-referencing, so that the rules the
+// control plane and the workers have in common are only created once.
+func mergeNodeSecGroups(name, nodeGroupID string, controlPlane, worker securityGroupSpec) securityGroupSpec {
+	group := securityGroupSpec{Name: name, Stateless: controlPlane.Stateless || worker.Stateless}
+	seen := make(map[resolvedSecurityGroupRuleSpec]bool)
+	for _, rule := range append(append([]resolvedSecurityGroupRuleSpec{}, controlPlane.Rules...), worker.Rules...) {
+		if rule.RemoteGroupID == nodeGroupID {
+			rule.RemoteGroupID = remoteGroupIDSelf
+		}
+		// The same rule may have a different origin in each group, the first one is kept.
+		key := rule
+		key.Origin = ""
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		group.Rules = append(group.Rules, rule)
+	}
+	return group
+}
+
+// isDualStack returns true if the cluster network has both IPv4 and IPv6 subnets.
+func isDualStack(openStackCluster *infrav1.OpenStackCluster) bool {
+	if openStackCluster.Status.Network == nil {
+		return false
+	}
+
+	var hasIPv4, hasIPv6 bool
+	for _, subnet := range openStackCluster.Status.Network.Subnets {
+		ip, _, err := net.ParseCIDR(subnet.CIDR)
+		if err != nil {
+			continue
+		}
+		if ip.To4() != nil {
+			hasIPv4 = true
+		} else {
+			hasIPv6 = true
+		}
+	}
+	return hasIPv4 && hasIPv6
+}
+
+// getAllNodesRules returns the rules for the allNodes security group that should be created, from the rules of the
+// given field of the managed security groups, which must have been checked with validateRules. The
+// remoteSecurityGroupFilter of a rule is resolved with getSecurityGroups, to a rule per matching group.
+func getAllNodesRules(field string, remoteManagedGroups map[string]string, getSecurityGroups func([]infrav1.SecurityGroupFilter) ([]string, error), allNodesSecurityGroupRules []infrav1.SecurityGroupRuleSpec) ([]resolvedSecurityGroupRuleSpec, error) {
 	rules := make([]resolvedSecurityGroupRuleSpec, 0, len(allNodesSecurityGroupRules))
-	for _, rule := range allNodesSecurityGroupRules {
-		if err := validateRemoteManagedGroups(remoteManagedGroups, rule.RemoteManagedGroups); err != nil {
-			return nil, err
+	for i, rule := range allNodesSecurityGroupRules {
+		// A rule may have another kind of remote instead, e.g. the addresses the nodes are permitted to reach.
+		if rule.RemoteIPPrefix == nil && rule.RemoteGroupID == nil && rule.RemoteSecurityGroupFilter == nil {
+			if err := validateRemoteManagedGroups(remoteManagedGroups, rule.RemoteManagedGroups); err != nil {
+				return nil, fmt.Errorf("%s[%d] (%s): %w", field, i, rule.Name, err)
+			}
 		}
 		r := resolvedSecurityGroupRuleSpec{
 			Direction: rule.Direction,
@@ -252,15 +883,40 @@ func getAllNodesRules(remoteManagedGroups map[string]string, allNodesSecurityGro
 			r.RemoteIPPrefix = *rule.RemoteIPPrefix
 		}
 
-		if len(rule.RemoteManagedGroups) > 0 {
-			if rule.RemoteGroupID != nil {
-				return nil, fmt.Errorf("remoteGroupID must not be set when remoteManagedGroups is set")
+		// The groups matched by the filter aren't managed, so they are looked up on each reconcile.
+		if rule.RemoteSecurityGroupFilter != nil {
+			remoteGroupIDs, err := getSecurityGroups([]infrav1.SecurityGroupFilter{*rule.RemoteSecurityGroupFilter})
+			if err != nil {
+				return nil, fmt.Errorf("%s[%d] (%s): remoteSecurityGroupFilter: %w", field, i, rule.Name, err)
+			}
+			for _, remoteGroupID := range remoteGroupIDs {
+				rc := r
+				rc.RemoteGroupID = remoteGroupID
+				rules = append(rules, rc)
 			}
+			continue
+		}
 
+		if len(rule.RemoteManagedGroups) > 0 {
 			for _, rg := range rule.RemoteManagedGroups {
 				rc := r
+				rc.Description = withRemoteManagedGroupDescription(r.Description, rg.String())
 				rc.RemoteGroupID = remoteManagedGroups[rg.String()]
 				rules = append(rules, rc)
+
+				// The rules referencing the bastion group also reference the groups of the other bastion replicas.
+				if rg.String() != bastionSuffix {
+					continue
+				}
+				for replica := 1; ; replica++ {
+					remoteGroupID, ok := remoteManagedGroups[getBastionReplicaSuffix(replica)]
+					if !ok {
+						break
+					}
+					rc.Description = withRemoteManagedGroupDescription(r.Description, getBastionReplicaSuffix(replica))
+					rc.RemoteGroupID = remoteGroupID
+					rules = append(rules, rc)
+				}
 			}
 		} else {
 			rules = append(rules, r)
@@ -269,6 +925,16 @@ func getAllNodesRules(remoteManagedGroups map[string]string, allNodesSecurityGro
 	return rules, nil
 }
 
+// withRemoteManagedGroupDescription returns the description of a rule expanded from remoteManagedGroups, suffixed
+// with the name of the managed group it references, e.g. "Cilium health (worker)", so the rules expanded from the
+// same allNodes rule can be told apart in Neutron.
+func withRemoteManagedGroupDescription(description, remoteManagedGroup string) string {
+	if description == "" {
+		return fmt.Sprintf("(%s)", remoteManagedGroup)
+	}
+	return fmt.Sprintf("%s (%s)", description, remoteManagedGroup)
+}
+
 // validateRemoteManagedGroups validates that the remoteManagedGroups target existing managed security groups.
 func validateRemoteManagedGroups(remoteManagedGroups map[string]string, ruleRemoteManagedGroups []infrav1.ManagedSecurityGroupName) error {
 	if len(ruleRemoteManagedGroups) == 0 {
@@ -277,76 +943,273 @@ func validateRemoteManagedGroups(remoteManagedGroups map[string]string, ruleRemo
 
 	for _, group := range ruleRemoteManagedGroups {
 		if _, ok := remoteManagedGroups[group.String()]; !ok {
+			// The bastion group is only managed while the bastion is enabled.
+			if group.String() == bastionSuffix {
+				return fmt.Errorf("remoteManagedGroups: the bastion security group is referenced, but the bastion is disabled")
+			}
 			return fmt.Errorf("remoteManagedGroups: %s is not a valid remote managed security group", group)
 		}
 	}
 	return nil
 }
 
-func (s *Service) GetSecurityGroups(securityGroupParams []infrav1.SecurityGroupFilter) ([]string, error) {
-	var sgIDs []string
-	for _, sg := range securityGroupParams {
-		// Don't validate an explicit UUID if we were given one
-		if sg.ID != "" {
-			if isDuplicate(sgIDs, sg.ID) {
-				continue
-			}
-			sgIDs = append(sgIDs, sg.ID)
-			continue
-		}
+// validateAllNodesRules validates the allNodes rules which can be checked without calling the OpenStack API.
+func validateAllNodesRules(allNodesSecurityGroupRules []infrav1.SecurityGroupRuleSpec) error {
+	return validateRules("allNodesSecurityGroupRules", allNodesSecurityGroupRules)
+}
 
-		listOpts := sg.ToListOpt()
-		if listOpts.ProjectID == "" {
-			listOpts.ProjectID = s.scope.ProjectID()
+// validateRules checks the direction, the remotes, and the ICMP type and code, of the rules of the given field of the
+// managed security groups.
+func validateRules(field string, securityGroupRules []infrav1.SecurityGroupRuleSpec) error {
+	for i, rule := range securityGroupRules {
+		if err := validateRuleDirection(rule.Direction); err != nil {
+			return fmt.Errorf("%s[%d] (%s): %w", field, i, rule.Name, err)
 		}
-		SGList, err := s.client.ListSecGroup(listOpts)
-		if err != nil {
+		if err := validateRuleRemotes(rule); err != nil {
+			return fmt.Errorf("%s[%d] (%s): %w", field, i, rule.Name, err)
+		}
+		if err := validateRuleICMPTypeCode(rule); err != nil {
+			return fmt.Errorf("%s[%d] (%s): %w", field, i, rule.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateRuleICMPTypeCode checks the ICMP type and code of an ICMP rule, which Neutron takes from portRangeMin and
+// portRangeMax.
+func validateRuleICMPTypeCode(rule infrav1.SecurityGroupRuleSpec) error {
+	if rule.Protocol == nil || !isICMPProtocol(*rule.Protocol) {
+		return nil
+	}
+	if rule.PortRangeMin != nil && (*rule.PortRangeMin < 0 || *rule.PortRangeMin > maxICMPTypeCode) {
+		return fmt.Errorf("ICMP type %d is not valid, must be between 0 and %d", *rule.PortRangeMin, maxICMPTypeCode)
+	}
+	if rule.PortRangeMax != nil {
+		if *rule.PortRangeMax < 0 || *rule.PortRangeMax > maxICMPTypeCode {
+			return fmt.Errorf("ICMP code %d is not valid, must be between 0 and %d", *rule.PortRangeMax, maxICMPTypeCode)
+		}
+		if rule.PortRangeMin == nil {
+			return fmt.Errorf("ICMP code requires an ICMP type")
+		}
+	}
+	return nil
+}
+
+// maxICMPTypeCode is the highest ICMP type, and code.
+const maxICMPTypeCode = 255
+
+// isICMPProtocol returns true if protocol is one of the names, or numbers, Neutron accepts for ICMP or ICMPv6.
+func isICMPProtocol(protocol string) bool {
+	switch canonicalProtocol(protocol, "") {
+	case string(rules.ProtocolICMP), string(rules.ProtocolIPv6ICMP):
+		return true
+	}
+	return false
+}
+
+// protocolNames maps the IP protocol numbers Neutron accepts in place of the names of the protocols to the names.
+var protocolNames = map[string]string{
+	"1":   string(rules.ProtocolICMP),
+	"2":   string(rules.ProtocolIGMP),
+	"4":   string(rules.ProtocolIPIP),
+	"6":   string(rules.ProtocolTCP),
+	"17":  string(rules.ProtocolUDP),
+	"33":  string(rules.ProtocolDCCP),
+	"41":  string(rules.ProtocolIPv6Encap),
+	"46":  string(rules.ProtocolRSVP),
+	"47":  string(rules.ProtocolGRE),
+	"50":  string(rules.ProtocolESP),
+	"51":  string(rules.ProtocolAH),
+	"58":  string(rules.ProtocolIPv6ICMP),
+	"89":  string(rules.ProtocolOSPF),
+	"112": string(rules.ProtocolVRRP),
+	"132": string(rules.ProtocolSCTP),
+	"136": string(rules.ProtocolUDPLite),
+}
+
+// canonicalProtocol returns the name Neutron reports for protocol in a rule of the given ether type. Neutron
+// accepts the names in any case, the numbers of the common protocols, and several names for ICMPv6. The rules
+// matching any protocol have no protocol, which Neutron also accepts as "any".
+func canonicalProtocol(protocol, etherType string) string {
+	protocol = strings.ToLower(protocol)
+	if protocol == "any" {
+		return string(rules.ProtocolAny)
+	}
+	if name, ok := protocolNames[protocol]; ok {
+		protocol = name
+	}
+	if protocol == "icmpv6" || (protocol == string(rules.ProtocolICMP) && canonicalEtherType(etherType) == string(rules.EtherType6)) {
+		return string(rules.ProtocolIPv6ICMP)
+	}
+	return protocol
+}
+
+// canonicalEtherType returns the ether type as Neutron reports it, e.g. IPv6 for ipv6.
+func canonicalEtherType(etherType string) string {
+	switch strings.ToLower(etherType) {
+	case strings.ToLower(string(rules.EtherType4)):
+		return string(rules.EtherType4)
+	case strings.ToLower(string(rules.EtherType6)):
+		return string(rules.EtherType6)
+	}
+	return etherType
+}
+
+// validateRuleRemotes checks that the rule has at most one kind of remote, as Neutron rejects the rules with both a
+// remote group and a remote IP prefix.
+func validateRuleRemotes(rule infrav1.SecurityGroupRuleSpec) error {
+	remotes := 0
+	if len(rule.RemoteManagedGroups) > 0 {
+		remotes++
+	}
+	if rule.RemoteGroupID != nil {
+		remotes++
+	}
+	if rule.RemoteIPPrefix != nil {
+		remotes++
+	}
+	if rule.RemoteSecurityGroupFilter != nil {
+		remotes++
+	}
+	if remotes > 1 {
+		return fmt.Errorf("only one of remoteGroupID, remoteIPPrefix, remoteManagedGroups and remoteSecurityGroupFilter can be set")
+	}
+	return nil
+}
+
+func validateRuleDirection(direction string) error {
+	switch rules.RuleDirection(direction) {
+	case rules.DirIngress, rules.DirEgress:
+		return nil
+	case "":
+		return fmt.Errorf("direction is required")
+	}
+	return fmt.Errorf("direction %q is not valid, must be %q or %q", direction, rules.DirIngress, rules.DirEgress)
+}
+
+func (s *Service) GetSecurityGroups(securityGroupParams []infrav1.SecurityGroupFilter) ([]string, error) {
+	var sgIDs []string
+	// Filters may resolve to overlapping groups, each group is returned once in the order it was first found.
+	seen := make(map[string]bool)
+	for _, sg := range securityGroupParams {
+		// Don't validate an explicit UUID if we were given one
+		if sg.ID != "" {
+			if !seen[sg.ID] {
+				seen[sg.ID] = true
+				sgIDs = append(sgIDs, sg.ID)
+			}
+			continue
+		}
+
+		listOpts := sg.ToListOpt()
+		if listOpts.ProjectID == "" {
+			listOpts.ProjectID = s.scope.ProjectID()
+		}
+		SGList, err := s.client.ListSecGroup(listOpts)
+		if err != nil {
 			return nil, err
 		}
 
 		if len(SGList) == 0 {
-			return nil, fmt.Errorf("security group %s not found", sg.Name)
+			return nil, &SecurityGroupNotFoundError{Filter: sg}
 		}
 
 		for _, group := range SGList {
-			if isDuplicate(sgIDs, group.ID) {
+			if seen[group.ID] {
 				continue
 			}
+			seen[group.ID] = true
 			sgIDs = append(sgIDs, group.ID)
 		}
 	}
 	return sgIDs, nil
 }
 
-func (s *Service) DeleteSecurityGroups(openStackCluster *infrav1.OpenStackCluster, clusterName string) error {
+// DeleteSecurityGroups deletes the managed security groups of the cluster. The requests to Neutron are made with
+// ctx, and are aborted once it is done.
+func (s *Service) DeleteSecurityGroups(ctx context.Context, openStackCluster *infrav1.OpenStackCluster, clusterName string) error {
+	ctx, cancel, err := s.withSecurityGroupClientTimeout(ctx, openStackCluster)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	s = s.withClientContext(ctx)
+
+	if openStackCluster.Spec.ManagedSecurityGroups != nil && openStackCluster.Spec.ManagedSecurityGroups.RetainOnDelete {
+		s.scope.Logger().Info("Retaining the managed security groups of the deleted cluster")
+		record.Eventf(openStackCluster, "RetainedSecurityGroups", "Retained the managed security groups of the cluster")
+		metrics.DeleteSecurityGroupRules(openStackCluster.Namespace, clusterName)
+		return nil
+	}
+
+	namePrefix := getSecGroupNamePrefix(openStackCluster)
 	secGroupNames := []string{
-		getSecControlPlaneGroupName(clusterName),
-		getSecWorkerGroupName(clusterName),
+		getSecControlPlaneGroupName(namePrefix, clusterName),
+		getSecWorkerGroupName(namePrefix, clusterName),
+	}
+
+	if openStackCluster.Status.NodeSecurityGroup != nil || (openStackCluster.Spec.ManagedSecurityGroups != nil && openStackCluster.Spec.ManagedSecurityGroups.SharedNodeGroup) {
+		secGroupNames = append(secGroupNames, getSecNodeGroupName(namePrefix, clusterName))
+	}
+
+	if openStackCluster.Status.AllNodesSecurityGroup != nil || (openStackCluster.Spec.ManagedSecurityGroups != nil && openStackCluster.Spec.ManagedSecurityGroups.SeparateAllNodesGroup) {
+		secGroupNames = append(secGroupNames, getSecAllNodesGroupName(namePrefix, clusterName))
 	}
 
 	if openStackCluster.Spec.Bastion != nil && openStackCluster.Spec.Bastion.Enabled {
-		secGroupNames = append(secGroupNames, getSecBastionGroupName(clusterName))
+		secGroupNames = append(secGroupNames, getSecBastionGroupName(namePrefix, clusterName))
+	}
+	// The groups of the bastion replicas after the first are deleted even if the replicas were removed since.
+	for i := 1; i < getBastionReplicas(openStackCluster) || i <= len(openStackCluster.Status.AdditionalBastionSecurityGroups); i++ {
+		secGroupNames = append(secGroupNames, getSecBastionReplicaGroupName(namePrefix, clusterName, i))
+	}
+
+	if openStackCluster.Status.ShadowSecurityGroup != nil || (openStackCluster.Spec.ManagedSecurityGroups != nil && len(openStackCluster.Spec.ManagedSecurityGroups.ShadowRules) > 0) {
+		secGroupNames = append(secGroupNames, getSecShadowGroupName(namePrefix, clusterName))
 	}
 
 	for _, secGroupName := range secGroupNames {
-		if err := s.deleteSecurityGroup(openStackCluster, secGroupName); err != nil {
+		if err := s.deleteSecurityGroup(openStackCluster, clusterName, secGroupName); err != nil {
 			return err
 		}
 	}
+	metrics.DeleteSecurityGroupRules(openStackCluster.Namespace, clusterName)
 
 	return nil
 }
 
-func (s *Service) deleteSecurityGroup(openStackCluster *infrav1.OpenStackCluster, name string) error {
-	group, err := s.getSecurityGroupByName(name)
+// deleteSecurityGroup deletes the managed security group with the given name. A group Neutron reports in use is
+// detached from the ports created by the cluster before retrying, or left in place if other ports use it. An
+// adopted group is left in place, unless the adopted groups are to be deleted.
+func (s *Service) deleteSecurityGroup(openStackCluster *infrav1.OpenStackCluster, clusterName, name string) error {
+	osGroup, err := s.getOSSecurityGroupByName(name, getSecGroupProjectID(openStackCluster), openStackCluster.Spec.Tags)
 	if err != nil {
 		return err
 	}
-	if group.ID == "" {
+	if osGroup == nil {
 		// nothing to do
 		return nil
 	}
+	if isRetainedAdoptedSecGroup(openStackCluster, osGroup) {
+		s.scope.Logger().Info("Security group was adopted, not deleting it", "name", osGroup.Name, "id", osGroup.ID)
+		record.Eventf(openStackCluster, "RetainedAdoptedSecurityGroup", "Retained adopted security group %s with id %s", osGroup.Name, osGroup.ID)
+		return nil
+	}
+	group := convertOSSecGroupToConfigSecGroup(*osGroup)
 	err = s.client.DeleteSecGroup(group.ID)
+	if capoerrors.IsConflict(err) {
+		var blockingPortIDs []string
+		blockingPortIDs, err = s.detachSecurityGroupFromClusterPorts(openStackCluster, clusterName, group)
+		if err != nil {
+			return err
+		}
+		if len(blockingPortIDs) > 0 {
+			s.scope.Logger().Info("Security group is used by ports not owned by the cluster, not deleting it", "name", group.Name, "id", group.ID, "ports", blockingPortIDs)
+			record.Warnf(openStackCluster, "SecurityGroupInUse", "Security group %s with id %s is used by ports not owned by the cluster, leaving it in place: %s", group.Name, group.ID, strings.Join(blockingPortIDs, ", "))
+			return nil
+		}
+		err = s.client.DeleteSecGroup(group.ID)
+	}
 	if err != nil {
 		record.Warnf(openStackCluster, "FailedDeleteSecurityGroup", "Failed to delete security group %s with id %s: %v", group.Name, group.ID, err)
 		return err
@@ -356,41 +1219,260 @@ func (s *Service) deleteSecurityGroup(openStackCluster *infrav1.OpenStackCluster
 	return nil
 }
 
-// reconcileGroupRules reconciles an already existing observed group by deleting rules not needed anymore and
-// creating rules that are missing.
-func (s *Service) reconcileGroupRules(desired securityGroupSpec, observed infrav1.SecurityGroupStatus) (infrav1.SecurityGroupStatus, error) {
-	var rulesToDelete []string
+// reconcileGroupRules reconciles an already existing observed group by creating rules that are missing, then
+// deleting rules not needed anymore.
+func (s *Service) reconcileGroupRules(ctx context.Context, desired securityGroupSpec, observed infrav1.SecurityGroupStatus) (infrav1.SecurityGroupStatus, error) {
+	diff := s.diffGroupRules(desired, observed)
+	ownedRuleIDs, legacyRuleIDs, err := s.getOwnedRuleIDs(observed, desired.RuleTags)
+	if err != nil {
+		return infrav1.SecurityGroupStatus{}, err
+	}
+	diff.rulesToDelete = filterOwnedRules(diff.rulesToDelete, ownedRuleIDs)
+	desiredRules := diff.desiredRules
+	reconciledRules := diff.reconciledRules
+	rulesToCreate := diff.rulesToCreate
+
+	// Fail before changing the group rather than on the first rule the cloud refuses to create.
+	if s.secGroupMaxRulesPerGroup > 0 {
+		rulesCount := len(desiredRules)
+		for _, observedRule := range observed.Rules {
+			if ownedRuleIDs != nil && !ownedRuleIDs[observedRule.ID] && !containsRule(reconciledRules, observedRule.ID) {
+				rulesCount++
+			}
+		}
+		if rulesCount > s.secGroupMaxRulesPerGroup {
+			return infrav1.SecurityGroupStatus{}, fmt.Errorf("%w: security group %s needs %d rules, the limit is %d", ErrSecurityGroupRuleLimitExceeded, observed.Name, rulesCount, s.secGroupMaxRulesPerGroup)
+		}
+	}
+
+	// The rules found without the tags, e.g. created before the rules were tagged, are adopted by tagging them:
+	// the rules with the rule description prefix, and the desired rules.
+	adoptedRuleIDs := legacyRuleIDs
+	if ownedRuleIDs != nil {
+		for _, rule := range reconciledRules {
+			if !ownedRuleIDs[rule.ID] {
+				adoptedRuleIDs = append(adoptedRuleIDs, rule.ID)
+			}
+		}
+	}
+	for _, ruleID := range adoptedRuleIDs {
+		if err := s.tagRule(ruleID, desired.RuleTags); err != nil {
+			return infrav1.SecurityGroupStatus{}, err
+		}
+	}
+
+	// The rules are created before the rules not desired anymore are deleted, so that a rule replacing another one,
+	// e.g. because its description changed, is in place before the rule it replaces is removed.
+	s.scope.Logger().V(4).Info("Creating new rules needed for group", "name", observed.Name, "amount", len(rulesToCreate))
+	// The fields compared to match the observed rules are logged, to tell why a rule is recreated at every reconcile.
+	for _, rule := range rulesToCreate {
+		s.scope.Logger().V(5).Info("Rule to create", "name", observed.Name, "direction", rule.Direction, "etherType", rule.EtherType,
+			"protocol", rule.Protocol, "portRangeMin", rule.PortRangeMin, "portRangeMax", rule.PortRangeMax,
+			"remoteGroupID", rule.RemoteGroupID, "remoteIPPrefix", rule.RemoteIPPrefix, "description", rule.Description)
+	}
+	// Several rules are created with a single request. Neutron refuses the whole request when one of the rules
+	// can't be created, e.g. because it already exists or the group has too many rules, and older versions don't
+	// support it at all: the rules are then created one at a time, which handles each case.
+	if len(rulesToCreate) > 1 {
+		newRules, err := s.createRules(ctx, observed.ID, rulesToCreate, desired.RuleTags)
+		switch {
+		case err == nil:
+			for _, newRule := range newRules {
+				s.auditRuleChange(ctx, audit.ActionCreate, observed.ID, newRule)
+				reconciledRules = append(reconciledRules, newRule)
+			}
+			rulesToCreate = nil
+		case capoerrors.IsClientError(err):
+			s.scope.Logger().V(4).Info("Failed to create the rules in bulk, creating them one at a time", "name", observed.Name, "error", err.Error())
+		default:
+			return infrav1.SecurityGroupStatus{}, err
+		}
+	}
+	var swaps []ruleSwap
+	for _, rule := range rulesToCreate {
+		newRule, err := s.createRule(ctx, observed.ID, rule, desired.RuleTags)
+		// Some backends refuse the rule with a conflict, which must not be taken for an existing rule.
+		if isSecurityGroupRuleLimitError(err) {
+			return infrav1.SecurityGroupStatus{}, fmt.Errorf("%w: security group %s needs %d rules, the cloud refused to create more: %w", ErrSecurityGroupRuleLimitExceeded, observed.Name, len(desiredRules), err)
+		}
+		if capoerrors.IsConflict(err) {
+			// The rule was created since we listed the rules of the group, e.g. by the previous
+			// leader before a failover. Adopt it.
+			var existingRule *infrav1.SecurityGroupRuleStatus
+			existingRule, err = s.getMatchingRule(observed.ID, rule, err)
+			if err != nil {
+				return infrav1.SecurityGroupStatus{}, err
+			}
+			// Neutron doesn't tell rules apart by their description, so a rule whose description changed can't
+			// be created while the rule it replaces exists. The old rule allows the same traffic: it is swapped
+			// for the new one once the other rules are reconciled.
+			if containsRule(diff.rulesToDelete, existingRule.ID) {
+				swaps = append(swaps, ruleSwap{old: *existingRule, new: rule})
+				continue
+			}
+			existingRule.Origin = rule.Origin
+			if len(desired.RuleTags) > 0 {
+				if err := s.tagRule(existingRule.ID, desired.RuleTags); err != nil {
+					return infrav1.SecurityGroupStatus{}, err
+				}
+			}
+			reconciledRules = append(reconciledRules, *existingRule)
+			continue
+		}
+		if err != nil {
+			return infrav1.SecurityGroupStatus{}, err
+		}
+		s.auditRuleChange(ctx, audit.ActionCreate, observed.ID, newRule)
+		reconciledRules = append(reconciledRules, newRule)
+	}
+
+	// The swapped rules are deleted with their swap, regardless of the rule deletion grace period: their
+	// replacement allows the same traffic.
+	var swappedRules []infrav1.SecurityGroupRuleStatus
+	for _, swap := range swaps {
+		swappedRules = append(swappedRules, swap.old)
+	}
+	rulesToDelete, rulesPendingDeletion := s.deferRuleDeletions(withoutRules(diff.rulesToDelete, swappedRules), observed.RulesPendingDeletion, len(diff.rulesToCreate) == 0)
+
+	// A failed delete doesn't stop the pass, so the other orphaned rules are still removed and the desired
+	// rules created. The error is returned once the pass is done: the status is then left unchanged, so the
+	// group is reconciled again by the next pass, which finds the orphan again as it lists the rules of the group.
+	var deleteErrs []error
+	s.scope.Logger().V(4).Info("Deleting rules not needed anymore for group", "name", observed.Name, "amount", len(rulesToDelete))
+	for _, rule := range rulesToDelete {
+		s.scope.Logger().V(5).Info("Rule to delete", "name", observed.Name, "ID", rule.ID, "direction", rule.Direction, "etherType", pointer.StringDeref(rule.EtherType, ""),
+			"protocol", pointer.StringDeref(rule.Protocol, ""), "portRangeMin", pointer.IntDeref(rule.PortRangeMin, 0), "portRangeMax", pointer.IntDeref(rule.PortRangeMax, 0),
+			"remoteGroupID", pointer.StringDeref(rule.RemoteGroupID, ""), "remoteIPPrefix", pointer.StringDeref(rule.RemoteIPPrefix, ""), "description", pointer.StringDeref(rule.Description, ""))
+	}
+	for _, rule := range rulesToDelete {
+		if err := s.deleteRule(ctx, observed, rule); err != nil {
+			deleteErrs = append(deleteErrs, err)
+		}
+	}
+
+	for _, swap := range swaps {
+		s.scope.Logger().V(6).Info("Replacing rule", "ID", swap.old.ID, "name", observed.Name)
+		if err := s.deleteRule(ctx, observed, swap.old); err != nil {
+			deleteErrs = append(deleteErrs, err)
+			continue
+		}
+		newRule, err := s.createRule(ctx, observed.ID, swap.new, desired.RuleTags)
+		if err != nil {
+			return infrav1.SecurityGroupStatus{}, err
+		}
+		s.auditRuleChange(ctx, audit.ActionCreate, observed.ID, newRule)
+		reconciledRules = append(reconciledRules, newRule)
+	}
+	if len(deleteErrs) > 0 {
+		return infrav1.SecurityGroupStatus{}, errors.Join(deleteErrs...)
+	}
+
+	sortSecurityGroupRules(reconciledRules)
+	observed.Rules = reconciledRules
+	observed.RulesPendingDeletion = rulesPendingDeletion
+
+	if len(reconciledRules) == 0 && len(rulesPendingDeletion) == 0 {
+		return infrav1.SecurityGroupStatus{}, nil
+	}
+
+	return observed, nil
+}
+
+// ruleSwap is an observed rule replaced by a desired rule Neutron can't create while the observed rule exists.
+type ruleSwap struct {
+	old infrav1.SecurityGroupRuleStatus
+	new resolvedSecurityGroupRuleSpec
+}
+
+// deleteRule deletes a rule of the observed group. A rule already deleted isn't an error. Nothing is deleted once
+// ctx is done.
+func (s *Service) deleteRule(ctx context.Context, observed infrav1.SecurityGroupStatus, rule infrav1.SecurityGroupRuleStatus) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.scope.Logger().V(6).Info("Deleting rule", "ID", rule.ID, "name", observed.Name)
+	err := s.client.DeleteSecGroupRule(rule.ID)
+	if capoerrors.IsNotFound(err) {
+		s.scope.Logger().V(6).Info("Rule was already deleted", "ID", rule.ID, "name", observed.Name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("deleting rule %s of security group %s: %w", rule.ID, observed.Name, err)
+	}
+	s.auditRuleChange(ctx, audit.ActionDelete, observed.ID, rule)
+	return nil
+}
+
+// containsRule returns whether the rules contain the rule with the ID.
+func containsRule(rules []infrav1.SecurityGroupRuleStatus, id string) bool {
+	for _, rule := range rules {
+		if rule.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutRules returns the rules, except the excluded ones.
+func withoutRules(rules, excluded []infrav1.SecurityGroupRuleStatus) []infrav1.SecurityGroupRuleStatus {
+	if len(excluded) == 0 {
+		return rules
+	}
+	var kept []infrav1.SecurityGroupRuleStatus
+	for _, rule := range rules {
+		if !containsRule(excluded, rule.ID) {
+			kept = append(kept, rule)
+		}
+	}
+	return kept
+}
+
+// groupRulesDiff is the difference between the desired and the observed rules of a security group.
+type groupRulesDiff struct {
+	// desiredRules are the desired rules, as they are created.
+	desiredRules []resolvedSecurityGroupRuleSpec
+	// reconciledRules are the observed rules matching a desired rule.
+	reconciledRules []infrav1.SecurityGroupRuleStatus
+	rulesToCreate   []resolvedSecurityGroupRuleSpec
+	// rulesToDelete are the observed rules not desired anymore, whose deletion may still be deferred. They include
+	// the rules owned by someone else, which must be filtered out.
+	rulesToDelete []infrav1.SecurityGroupRuleStatus
+}
+
+// diffGroupRules compares the desired rules of a security group with its observed rules. It doesn't change the group.
+func (s *Service) diffGroupRules(desired securityGroupSpec, observed infrav1.SecurityGroupStatus) groupRulesDiff {
+	desiredRules := withRuleDescriptionFormat(canonicalizeRemoteIPPrefixes(desired.Rules), s.secGroupDescription.format)
+	desiredRules = resolveSelfRemoteGroupID(withRuleDescriptionPrefix(desiredRules, s.ruleDescriptionPrefix), observed.ID)
+	desiredRules = dedupeRules(desiredRules)
+	sortResolvedSecurityGroupRules(desiredRules)
+
+	// Neutron has no API to update a security group rule, so a rule whose description changed is replaced:
+	// it is created again as a missing desired rule, and deleted as an observed rule not desired anymore.
+	var rulesToDelete []infrav1.SecurityGroupRuleStatus
 	// fills rulesToDelete by calculating observed - desired
 	for _, observedRule := range observed.Rules {
 		deleteRule := true
-		for _, desiredRule := range desired.Rules {
-			r := desiredRule
-			if r.RemoteGroupID == remoteGroupIDSelf {
-				r.RemoteGroupID = observed.ID
-			}
-			if r.Matches(observedRule) {
+		for _, desiredRule := range desiredRules {
+			if desiredRule.Matches(observedRule) {
 				deleteRule = false
 				break
 			}
 		}
 		if deleteRule {
-			rulesToDelete = append(rulesToDelete, observedRule.ID)
+			rulesToDelete = append(rulesToDelete, observedRule)
 		}
 	}
 
 	rulesToCreate := []resolvedSecurityGroupRuleSpec{}
-	reconciledRules := make([]infrav1.SecurityGroupRuleStatus, 0, len(desired.Rules))
+	reconciledRules := make([]infrav1.SecurityGroupRuleStatus, 0, len(desiredRules))
 	// fills rulesToCreate by calculating desired - observed
 	// also adds rules which are in observed and desired to reconcileGroupRules.
-	for _, desiredRule := range desired.Rules {
-		r := desiredRule
-		if r.RemoteGroupID == remoteGroupIDSelf {
-			r.RemoteGroupID = observed.ID
-		}
+	for _, desiredRule := range desiredRules {
 		createRule := true
 		for _, observedRule := range observed.Rules {
-			if r.Matches(observedRule) {
+			if desiredRule.Matches(observedRule) {
 				// add already existing rules to reconciledRules because we won't touch them anymore
+				observedRule.Origin = desiredRule.Origin
 				reconciledRules = append(reconciledRules, observedRule)
 				createRule = false
 				break
@@ -401,63 +1483,318 @@ func (s *Service) reconcileGroupRules(desired securityGroupSpec, observed infrav
 		}
 	}
 
-	s.scope.Logger().V(4).Info("Deleting rules not needed anymore for group", "name", observed.Name, "amount", len(rulesToDelete))
+	return groupRulesDiff{
+		desiredRules:    desiredRules,
+		reconciledRules: reconciledRules,
+		rulesToCreate:   rulesToCreate,
+		rulesToDelete:   rulesToDelete,
+	}
+}
+
+// deferRuleDeletions splits the rules not desired anymore between the rules to delete now and the rules whose
+// deletion is deferred, when the rule deletion grace period is set. A rule is deleted once a previous pass marked it
+// for deletion at least the grace period ago, and the current pass found all the desired rules present, i.e. the
+// rules replacing it are in place.
+func (s *Service) deferRuleDeletions(rulesToDelete []infrav1.SecurityGroupRuleStatus, pending []infrav1.SecurityGroupRulePendingDeletion, desiredRulesPresent bool) ([]infrav1.SecurityGroupRuleStatus, []infrav1.SecurityGroupRulePendingDeletion) {
+	if s.secGroupRuleDeletionGracePeriod <= 0 {
+		return rulesToDelete, nil
+	}
+
+	markedTimes := make(map[string]metav1.Time, len(pending))
+	for _, p := range pending {
+		markedTimes[p.ID] = p.MarkedTime
+	}
+
+	now := s.clock.Now()
+	var deleteNow []infrav1.SecurityGroupRuleStatus
+	var deferred []infrav1.SecurityGroupRulePendingDeletion
 	for _, rule := range rulesToDelete {
-		s.scope.Logger().V(6).Info("Deleting rule", "ID", rule, "name", observed.Name)
-		err := s.client.DeleteSecGroupRule(rule)
+		markedTime, marked := markedTimes[rule.ID]
+		if marked && desiredRulesPresent && !now.Before(markedTime.Add(s.secGroupRuleDeletionGracePeriod)) {
+			deleteNow = append(deleteNow, rule)
+			continue
+		}
+		if !marked {
+			markedTime = metav1.Time{Time: now}
+		}
+		s.scope.Logger().V(6).Info("Deferring deletion of rule", "ID", rule.ID, "markedTime", markedTime)
+		deferred = append(deferred, infrav1.SecurityGroupRulePendingDeletion{ID: rule.ID, MarkedTime: markedTime})
+	}
+	return deleteNow, deferred
+}
+
+// auditRuleChange mirrors a change of a security group rule to the audit sink.
+// Failing to do so is logged but doesn't fail the reconcile.
+func (s *Service) auditRuleChange(ctx context.Context, action audit.Action, secGroupID string, rule infrav1.SecurityGroupRuleStatus) {
+	err := s.auditSink.Send(ctx, audit.Record{
+		Time:         s.clock.Now(),
+		Action:       action,
+		ResourceType: "security-group-rule",
+		ID:           rule.ID,
+		Parent:       secGroupID,
+		Resource:     rule,
+	})
+	if err != nil {
+		s.scope.Logger().Error(err, "Failed to send audit record", "action", action, "ID", rule.ID)
+	}
+}
+
+// withClientContext returns a copy of the service whose requests to Neutron are made with ctx. The service itself is
+// left unchanged, so that the requests of its other callers aren't aborted with ctx.
+func (s *Service) withClientContext(ctx context.Context) *Service {
+	ctxService := *s
+	ctxService.client = clients.NetworkClientWithContext(ctx, s.client)
+	return &ctxService
+}
+
+// withSecurityGroupClientTimeout returns a context derived from ctx, which is done once the duration set by the
+// security group client timeout annotation of the cluster has elapsed, if any, and the function releasing it.
+func (s *Service) withSecurityGroupClientTimeout(ctx context.Context, openStackCluster *infrav1.OpenStackCluster) (context.Context, context.CancelFunc, error) {
+	value, ok := openStackCluster.Annotations[infrav1.SecurityGroupClientTimeoutAnnotation]
+	if !ok {
+		return ctx, func() {}, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return nil, nil, fmt.Errorf("invalid %s annotation %q: must be a positive duration", infrav1.SecurityGroupClientTimeoutAnnotation, value)
+	}
+
+	s.scope.Logger().V(4).Info("Using security group client timeout", "timeout", timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, nil
+}
+
+// waitForSecurityGroupPropagation waits until the freshly created group with the given ID is listable, as on
+// some clouds a group isn't immediately usable by rules and ports. It doesn't wait when the propagation
+// timeout is not set.
+func (s *Service) waitForSecurityGroupPropagation(ctx context.Context, groupID string) error {
+	if s.secGroupPropagationTimeout <= 0 {
+		return nil
+	}
+
+	s.scope.Logger().V(6).Info("Waiting for security group to be listable", "id", groupID)
+	err := wait.PollUntilContextTimeout(ctx, s.secGroupPropagationInterval, s.secGroupPropagationTimeout, true, func(_ context.Context) (bool, error) {
+		secGroups, err := s.client.ListSecGroup(groups.ListOpts{ID: groupID})
 		if err != nil {
-			return infrav1.SecurityGroupStatus{}, err
+			if capoerrors.IsRetryable(err) {
+				return false, nil
+			}
+			return false, err
 		}
+		return len(secGroups) > 0, nil
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for security group %s to be listable: %w", groupID, err)
 	}
+	return nil
+}
 
-	s.scope.Logger().V(4).Info("Creating new rules needed for group", "name", observed.Name, "amount", len(rulesToCreate))
-	for _, rule := range rulesToCreate {
+// securityGroupRuleKey is the key the rules are sorted by.
+type securityGroupRuleKey struct {
+	direction      string
+	etherType      string
+	protocol       string
+	portRangeMin   int
+	portRangeMax   int
+	remoteIPPrefix string
+	remoteGroupID  string
+	description    string
+	id             string
+}
+
+func (k securityGroupRuleKey) less(other securityGroupRuleKey) bool {
+	switch {
+	case k.direction != other.direction:
+		return k.direction < other.direction
+	case k.etherType != other.etherType:
+		return k.etherType < other.etherType
+	case k.protocol != other.protocol:
+		return k.protocol < other.protocol
+	case k.portRangeMin != other.portRangeMin:
+		return k.portRangeMin < other.portRangeMin
+	case k.portRangeMax != other.portRangeMax:
+		return k.portRangeMax < other.portRangeMax
+	case k.remoteIPPrefix != other.remoteIPPrefix:
+		return k.remoteIPPrefix < other.remoteIPPrefix
+	case k.remoteGroupID != other.remoteGroupID:
+		return k.remoteGroupID < other.remoteGroupID
+	case k.description != other.description:
+		return k.description < other.description
+	}
+	return k.id < other.id
+}
+
+func resolvedSecurityGroupRuleKey(r resolvedSecurityGroupRuleSpec) securityGroupRuleKey {
+	return securityGroupRuleKey{
+		direction:      r.Direction,
+		etherType:      r.EtherType,
+		protocol:       r.Protocol,
+		portRangeMin:   r.PortRangeMin,
+		portRangeMax:   r.PortRangeMax,
+		remoteIPPrefix: r.RemoteIPPrefix,
+		remoteGroupID:  r.RemoteGroupID,
+		description:    r.Description,
+	}
+}
+
+func securityGroupRuleStatusKey(r infrav1.SecurityGroupRuleStatus) securityGroupRuleKey {
+	return securityGroupRuleKey{
+		direction:      r.Direction,
+		etherType:      pointer.StringDeref(r.EtherType, ""),
+		protocol:       pointer.StringDeref(r.Protocol, ""),
+		portRangeMin:   pointer.IntDeref(r.PortRangeMin, 0),
+		portRangeMax:   pointer.IntDeref(r.PortRangeMax, 0),
+		remoteIPPrefix: pointer.StringDeref(r.RemoteIPPrefix, ""),
+		remoteGroupID:  pointer.StringDeref(r.RemoteGroupID, ""),
+		description:    pointer.StringDeref(r.Description, ""),
+		id:             r.ID,
+	}
+}
+
+// sortResolvedSecurityGroupRules sorts the desired rules in the canonical order, so they are diffed and
+// created in a deterministic order.
+func sortResolvedSecurityGroupRules(rules []resolvedSecurityGroupRuleSpec) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return resolvedSecurityGroupRuleKey(rules[i]).less(resolvedSecurityGroupRuleKey(rules[j]))
+	})
+}
+
+// sortSecurityGroupRules sorts the rules in the canonical order they are written to the status in.
+func sortSecurityGroupRules(rules []infrav1.SecurityGroupRuleStatus) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return securityGroupRuleStatusKey(rules[i]).less(securityGroupRuleStatusKey(rules[j]))
+	})
+}
+
+// canonicalizeRemoteIPPrefixes returns the rules with their RemoteIPPrefix in the form Neutron stores it: the
+// host bits of the prefix are cleared, and an address without a prefix length is a host prefix. Otherwise the
+// observed rules never match the desired ones, and the rules are recreated on every reconcile.
+func canonicalizeRemoteIPPrefixes(rules []resolvedSecurityGroupRuleSpec) []resolvedSecurityGroupRuleSpec {
+	canonicalRules := make([]resolvedSecurityGroupRuleSpec, len(rules))
+	for i, rule := range rules {
+		rule.RemoteIPPrefix = canonicalRemoteIPPrefix(rule.RemoteIPPrefix)
+		canonicalRules[i] = rule
+	}
+	return canonicalRules
+}
+
+// canonicalRemoteIPPrefix returns prefix in network address form. Invalid prefixes are returned unchanged,
+// to be rejected by Neutron.
+func canonicalRemoteIPPrefix(prefix string) string {
+	if prefix == "" {
+		return prefix
+	}
+	if strings.Contains(prefix, "/") {
+		_, ipNet, err := net.ParseCIDR(prefix)
+		if err != nil {
+			return prefix
+		}
+		return ipNet.String()
+	}
+	ip := net.ParseIP(prefix)
+	if ip == nil {
+		return prefix
+	}
+	if ip.To4() != nil {
+		return ip.String() + "/32"
+	}
+	return ip.String() + "/128"
+}
+
+// resolveSelfRemoteGroupID returns the rules with the self keyword in RemoteGroupID replaced by the ID of the
+// group.
+func resolveSelfRemoteGroupID(rules []resolvedSecurityGroupRuleSpec, groupID string) []resolvedSecurityGroupRuleSpec {
+	resolvedRules := make([]resolvedSecurityGroupRuleSpec, 0, len(rules))
+	for _, rule := range rules {
 		r := rule
 		if r.RemoteGroupID == remoteGroupIDSelf {
-			r.RemoteGroupID = observed.ID
+			r.RemoteGroupID = groupID
 		}
-		newRule, err := s.createRule(observed.ID, r)
-		if err != nil {
-			return infrav1.SecurityGroupStatus{}, err
+		resolvedRules = append(resolvedRules, r)
+	}
+	return resolvedRules
+}
+
+// dedupeRules returns the rules without the duplicates, i.e. the rules matching the same security group rule, which
+// Neutron would refuse to create. Duplicates arise e.g. from a rule referencing the group by the self keyword and one
+// referencing it by its ID, or from an allNodes rule which is also one of the general rules. The first rule is kept,
+// along with its origin.
+func dedupeRules(rules []resolvedSecurityGroupRuleSpec) []resolvedSecurityGroupRuleSpec {
+	dedupedRules := make([]resolvedSecurityGroupRuleSpec, 0, len(rules))
+	seen := make(map[resolvedSecurityGroupRuleSpec]bool, len(rules))
+	for _, rule := range rules {
+		// The key only has the fields compared by Matches.
+		key := rule
+		key.Origin = ""
+		key.Direction = strings.ToLower(rule.Direction)
+		key.EtherType = canonicalEtherType(rule.EtherType)
+		key.Protocol = canonicalProtocol(rule.Protocol, rule.EtherType)
+		if seen[key] {
+			continue
 		}
-		reconciledRules = append(reconciledRules, newRule)
+		seen[key] = true
+		dedupedRules = append(dedupedRules, rule)
 	}
-	observed.Rules = reconciledRules
+	return dedupedRules
+}
 
-	if len(reconciledRules) == 0 {
-		return infrav1.SecurityGroupStatus{}, nil
+// validateSecGroupProject checks that the credentials of the cluster can manage the security groups of the project
+// set in managedSecurityGroups.projectID, if it isn't their own project. Neutron only lists the groups of the projects
+// the credentials are allowed to see, so the managed groups would otherwise be silently missed, and created again in
+// vain. The quota of the project is only readable with the same rights as its security groups.
+func (s *Service) validateSecGroupProject(openStackCluster *infrav1.OpenStackCluster) error {
+	projectID := getSecGroupProjectID(openStackCluster)
+	if projectID == "" || projectID == s.scope.ProjectID() {
+		return nil
 	}
 
-	return observed, nil
+	if _, err := s.client.GetQuota(projectID); err != nil {
+		if capoerrors.IsForbidden(err) || capoerrors.IsNotFound(err) {
+			err = &SecurityGroupProjectNotAccessibleError{ProjectID: projectID, Err: err}
+			record.Warnf(openStackCluster, "FailedValidateSecurityGroupProject", "%v", err)
+			return err
+		}
+		return fmt.Errorf("checking access to the security groups of project %s: %w", projectID, err)
+	}
+	return nil
 }
 
-func (s *Service) createSecurityGroupIfNotExists(openStackCluster *infrav1.OpenStackCluster, groupName string) error {
-	secGroup, err := s.getSecurityGroupByName(groupName)
+// createSecurityGroupIfNotExists creates the group if there is none with its name. The group is created stateless
+// or stateful as requested, or with the default of Neutron if stateless is nil.
+func (s *Service) createSecurityGroupIfNotExists(ctx context.Context, openStackCluster *infrav1.OpenStackCluster, groupName, description string, stateless *bool) error {
+	secGroup, err := s.getOSSecurityGroupByName(groupName, getSecGroupProjectID(openStackCluster), openStackCluster.Spec.Tags)
 	if err != nil {
 		return err
 	}
-	if secGroup == nil || secGroup.ID == "" {
+	if secGroup == nil {
 		s.scope.Logger().V(6).Info("Group doesn't exist, creating it", "name", groupName)
 
 		createOpts := groups.CreateOpts{
 			Name:        groupName,
-			Description: "Cluster API managed group",
+			Description: description,
+			ProjectID:   getSecGroupProjectID(openStackCluster),
+		}
+		if stateless != nil {
+			createOpts.Stateful = pointer.Bool(!*stateless)
 		}
-		s.scope.Logger().V(6).Info("Creating group", "name", groupName)
+		s.scope.Logger().V(6).Info("Creating group", "name", groupName, "projectID", createOpts.ProjectID)
 
 		group, err := s.client.CreateSecGroup(createOpts)
 		if err != nil {
+			if createOpts.ProjectID != "" && capoerrors.IsForbidden(err) {
+				err = &SecurityGroupProjectNotAccessibleError{ProjectID: createOpts.ProjectID, Err: err}
+			}
 			record.Warnf(openStackCluster, "FailedCreateSecurityGroup", "Failed to create security group %s: %v", groupName, err)
 			return err
 		}
 
-		if len(openStackCluster.Spec.Tags) > 0 {
-			_, err = s.client.ReplaceAllAttributesTags("security-groups", group.ID, attributestags.ReplaceAllOpts{
-				Tags: openStackCluster.Spec.Tags,
-			})
-			if err != nil {
-				return err
-			}
+		if err := s.waitForSecurityGroupPropagation(ctx, group.ID); err != nil {
+			record.Warnf(openStackCluster, "FailedCreateSecurityGroup", "Security group %s with id %s is not listable: %v", groupName, group.ID, err)
+			return err
+		}
+
+		if err := s.addSecurityGroupTags(openStackCluster, group); err != nil {
+			return err
 		}
 
 		record.Eventf(openStackCluster, "SuccessfulCreateSecurityGroup", "Created security group %s with id %s", groupName, group.ID)
@@ -467,36 +1804,323 @@ func (s *Service) createSecurityGroupIfNotExists(openStackCluster *infrav1.OpenS
 	sInfo := fmt.Sprintf("Reuse Existing SecurityGroup %s with %s", groupName, secGroup.ID)
 	s.scope.Logger().V(6).Info(sInfo)
 
+	if err := s.addSecurityGroupTags(openStackCluster, secGroup); err != nil {
+		return err
+	}
+
+	if secGroup.Description != description {
+		s.scope.Logger().V(4).Info("Updating description of security group", "name", groupName, "description", description)
+		if _, err := s.client.UpdateSecGroup(secGroup.ID, groups.UpdateOpts{Description: &description}); err != nil {
+			record.Warnf(openStackCluster, "FailedUpdateSecurityGroup", "Failed to update description of security group %s: %v", groupName, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkSecGroupStatefulness reports the managed security groups whose statefulness doesn't match the spec with
+// the SecurityGroupStatefulnessMismatch condition. Neutron can't change the statefulness of a group, so they
+// have to be recreated by the operator. The groups are only checked when the statefulness is set in the spec, as
+// the groups of a Neutron without the stateful-security-group extension are all reported as stateless.
+func (s *Service) checkSecGroupStatefulness(openStackCluster *infrav1.OpenStackCluster, secGroupNames map[string]string) error {
+	var mismatched []string
+	for k, name := range secGroupNames {
+		stateless := getSecGroupStateless(openStackCluster, k)
+		if stateless == nil {
+			continue
+		}
+		secGroup, err := s.getOSSecurityGroupByName(name, getSecGroupProjectID(openStackCluster), openStackCluster.Spec.Tags)
+		if err != nil {
+			return err
+		}
+		if secGroup == nil || secGroup.Stateful != *stateless {
+			continue
+		}
+		record.Warnf(openStackCluster, "SecurityGroupStatefulnessMismatch", "Security group %s with id %s has stateful=%t, but stateless=%t is desired: the group must be recreated", secGroup.Name, secGroup.ID, secGroup.Stateful, *stateless)
+		mismatched = append(mismatched, secGroup.Name)
+	}
+
+	if len(mismatched) == 0 {
+		conditions.Delete(openStackCluster, infrav1.SecurityGroupStatefulnessMismatchCondition)
+		return nil
+	}
+	sort.Strings(mismatched)
+	conditions.Set(openStackCluster, &clusterv1.Condition{
+		Type:    infrav1.SecurityGroupStatefulnessMismatchCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  infrav1.SecurityGroupRecreationRequiredReason,
+		Message: fmt.Sprintf("The statefulness of security groups %s can't be changed, they must be recreated", strings.Join(mismatched, ", ")),
+	})
 	return nil
 }
 
-func (s *Service) getSecurityGroupByName(name string) (*infrav1.SecurityGroupStatus, error) {
+// getSecGroupStateless returns whether the managed security group with the given suffix should be stateless, or
+// nil if it is left to the default of Neutron. The bastion group is always left to the default.
+func getSecGroupStateless(openStackCluster *infrav1.OpenStackCluster, suffix string) *bool {
+	if _, ok := getBastionReplicaIndex(suffix); ok || openStackCluster.Spec.ManagedSecurityGroups == nil {
+		return nil
+	}
+	return openStackCluster.Spec.ManagedSecurityGroups.Stateless
+}
+
+func (s *Service) getSecurityGroupByName(name, projectID string, tags []string) (*infrav1.SecurityGroupStatus, error) {
+	secGroup, err := s.getOSSecurityGroupByName(name, projectID, tags)
+	if err != nil || secGroup == nil {
+		return &infrav1.SecurityGroupStatus{}, err
+	}
+	return convertOSSecGroupToConfigSecGroup(*secGroup), nil
+}
+
+// addSecurityGroupTags adds the tags of the cluster the group doesn't have yet. Tags are added one at a time rather
+// than replaced all at once, so that concurrent reconciles, or other tools, editing the tags of the group don't
+// clobber each other's tags: the group ends up with the union of the tags they added.
+func (s *Service) addSecurityGroupTags(openStackCluster *infrav1.OpenStackCluster, group *groups.SecGroup) error {
+	for _, tag := range openStackCluster.Spec.Tags {
+		if hasAllTags(group.Tags, []string{tag}) {
+			continue
+		}
+		s.scope.Logger().V(6).Info("Adding tag to security group", "name", group.Name, "id", group.ID, "tag", tag)
+		if err := s.client.AddAttributesTag("security-groups", group.ID, tag); err != nil {
+			record.Warnf(openStackCluster, "FailedTagSecurityGroup", "Failed to add tag %s to security group %s with id %s: %v", tag, group.Name, group.ID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// getOSSecurityGroupByName returns the security group with the given name, or nil if there is none.
+// The group is looked up in the project with projectID if it is not empty, otherwise in all the projects
+// visible to the credentials. If several groups have the name, one is picked according to the name
+// conflict policy, using the tags of the cluster when the policy is Tagged.
+func (s *Service) getOSSecurityGroupByName(name, projectID string, tags []string) (*groups.SecGroup, error) {
 	opts := groups.ListOpts{
-		Name: name,
+		Name:      name,
+		ProjectID: projectID,
 	}
 
 	s.scope.Logger().V(6).Info("Attempting to fetch security group with", "name", name)
 	allGroups, err := s.client.ListSecGroup(opts)
 	if err != nil {
-		return &infrav1.SecurityGroupStatus{}, err
+		return nil, err
 	}
 
 	switch len(allGroups) {
 	case 0:
-		return &infrav1.SecurityGroupStatus{}, nil
+		return nil, nil
 	case 1:
-		return convertOSSecGroupToConfigSecGroup(allGroups[0]), nil
+		return &allGroups[0], nil
 	}
 
-	return &infrav1.SecurityGroupStatus{}, fmt.Errorf("more than one security group found named: %s", name)
+	switch s.secGroupNameConflictPolicy {
+	case SecurityGroupNameConflictOldest:
+		oldest := &allGroups[0]
+		for i := range allGroups[1:] {
+			group := &allGroups[i+1]
+			if group.CreatedAt.Before(oldest.CreatedAt) || (group.CreatedAt.Equal(oldest.CreatedAt) && group.ID < oldest.ID) {
+				oldest = group
+			}
+		}
+		s.scope.Logger().Info("More than one security group found, using the oldest one", "name", name, "id", oldest.ID)
+		return oldest, nil
+	case SecurityGroupNameConflictTagged:
+		var tagged []*groups.SecGroup
+		if len(tags) > 0 {
+			for i := range allGroups {
+				if hasAllTags(allGroups[i].Tags, tags) {
+					tagged = append(tagged, &allGroups[i])
+				}
+			}
+		}
+		if len(tagged) == 1 {
+			s.scope.Logger().Info("More than one security group found, using the one with the cluster tags", "name", name, "id", tagged[0].ID)
+			return tagged[0], nil
+		}
+		return nil, fmt.Errorf("%w named: %s, with IDs: %s, and %d of them have the cluster tags", ErrSecurityGroupNotUnique, name, strings.Join(getSecGroupIDs(allGroups), ", "), len(tagged))
+	}
+
+	return nil, fmt.Errorf("%w named: %s, with IDs: %s", ErrSecurityGroupNotUnique, name, strings.Join(getSecGroupIDs(allGroups), ", "))
+}
+
+// getSecGroupIDs returns the sorted IDs of the groups, so that the errors reporting them don't depend on the order
+// of the groups listed.
+func getSecGroupIDs(secGroups []groups.SecGroup) []string {
+	ids := make([]string, len(secGroups))
+	for i := range secGroups {
+		ids[i] = secGroups[i].ID
+	}
+	sort.Strings(ids)
+	return ids
 }
 
-func (s *Service) createRule(securityGroupID string, r resolvedSecurityGroupRuleSpec) (infrav1.SecurityGroupRuleStatus, error) {
+// hasAllTags returns true if resourceTags contains all of tags.
+func hasAllTags(resourceTags, tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, resourceTag := range resourceTags {
+			if resourceTag == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// getSecurityGroupRules returns all the rules of the security group, fetched with a dedicated paginated list.
+func (s *Service) getSecurityGroupRules(secGroupID string) ([]infrav1.SecurityGroupRuleStatus, error) {
+	s.scope.Logger().V(6).Info("Listing rules of security group", "id", secGroupID)
+	allRules, err := s.client.ListSecGroupRule(rules.ListOpts{
+		SecGroupID: secGroupID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing rules of security group %s: %w", secGroupID, err)
+	}
+
+	securityGroupRules := make([]infrav1.SecurityGroupRuleStatus, len(allRules))
+	for i, rule := range allRules {
+		securityGroupRules[i] = convertOSSecGroupRuleToConfigSecGroupRule(rule)
+	}
+	return securityGroupRules, nil
+}
+
+// createRule creates the rule, with the tags if any. Nothing is created once ctx is done.
+func (s *Service) createRule(ctx context.Context, securityGroupID string, r resolvedSecurityGroupRuleSpec, tags []string) (infrav1.SecurityGroupRuleStatus, error) {
+	if err := ctx.Err(); err != nil {
+		return infrav1.SecurityGroupRuleStatus{}, err
+	}
+	createOpts := s.getRuleCreateOpts(securityGroupID, r)
+	rule, err := s.client.CreateSecGroupRule(createOpts)
+	if err != nil {
+		return infrav1.SecurityGroupRuleStatus{}, err
+	}
+	if len(tags) > 0 {
+		if err := s.tagRule(rule.ID, tags); err != nil {
+			return infrav1.SecurityGroupRuleStatus{}, err
+		}
+	}
+	status := convertOSSecGroupRuleToConfigSecGroupRule(*rule)
+	status.Origin = r.Origin
+	return status, nil
+}
+
+// createRules creates the rules with a single bulk request, then tags them with the tags if any. Neutron creates
+// all of them or none. Nothing is created once ctx is done.
+func (s *Service) createRules(ctx context.Context, securityGroupID string, rs []resolvedSecurityGroupRuleSpec, tags []string) ([]infrav1.SecurityGroupRuleStatus, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	createOpts := make([]rules.CreateOpts, len(rs))
+	for i, r := range rs {
+		createOpts[i] = s.getRuleCreateOpts(securityGroupID, r)
+	}
+	created, err := s.client.CreateSecGroupRules(createOpts)
+	if err != nil {
+		return nil, err
+	}
+	// The rules are returned in the order of the request.
+	if len(created) != len(rs) {
+		return nil, fmt.Errorf("creating %d rules of security group %s returned %d rules", len(rs), securityGroupID, len(created))
+	}
+	statuses := make([]infrav1.SecurityGroupRuleStatus, len(created))
+	for i := range created {
+		if len(tags) > 0 {
+			if err := s.tagRule(created[i].ID, tags); err != nil {
+				return nil, err
+			}
+		}
+		statuses[i] = convertOSSecGroupRuleToConfigSecGroupRule(created[i])
+		statuses[i].Origin = rs[i].Origin
+	}
+	return statuses, nil
+}
+
+// tagRule replaces the tags of the rule with the tags.
+func (s *Service) tagRule(ruleID string, tags []string) error {
+	s.scope.Logger().V(6).Info("Tagging rule", "ID", ruleID, "tags", tags)
+	_, err := s.client.ReplaceAllAttributesTags(secGroupRuleResource, ruleID, attributestags.ReplaceAllOpts{
+		Tags: tags,
+	})
+	if err != nil {
+		return fmt.Errorf("tagging rule %s: %w", ruleID, err)
+	}
+	return nil
+}
+
+// getRuleOwnershipTags returns the tags of the rules owned by this manager in the managed security groups of the
+// cluster: the tags of the cluster and the rule owner tag.
+func (s *Service) getRuleOwnershipTags(openStackCluster *infrav1.OpenStackCluster) []string {
+	tags := openStackCluster.Spec.Tags
+	if s.ruleOwnerTag == "" {
+		return tags
+	}
+	for _, tag := range tags {
+		if tag == s.ruleOwnerTag {
+			return tags
+		}
+	}
+	return append(append([]string{}, tags...), s.ruleOwnerTag)
+}
+
+// getOwnedRuleIDs returns the IDs of the rules of the observed security group owned by this manager, or nil if there
+// are no tags, in which case all the rules are owned. The owned rules are the rules carrying all the tags, and the
+// untagged rules whose description has the rule description prefix, which were created before the rules were owned
+// with tags. The IDs of the latter are also returned as the rules to adopt.
+func (s *Service) getOwnedRuleIDs(observed infrav1.SecurityGroupStatus, tags []string) (map[string]bool, []string, error) {
+	if len(tags) == 0 {
+		return nil, nil, nil
+	}
+	taggedRules, err := s.client.ListSecGroupRuleWithTags(rules.ListOpts{SecGroupID: observed.ID}, tags)
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing tagged rules of security group %s: %w", observed.ID, err)
+	}
+	owned := make(map[string]bool, len(taggedRules))
+	for _, rule := range taggedRules {
+		owned[rule.ID] = true
+	}
+	var legacyRuleIDs []string
+	if s.ruleDescriptionPrefix != "" {
+		for _, rule := range observed.Rules {
+			if !owned[rule.ID] && hasRuleDescriptionPrefix(rule, s.ruleDescriptionPrefix) {
+				owned[rule.ID] = true
+				legacyRuleIDs = append(legacyRuleIDs, rule.ID)
+			}
+		}
+	}
+	return owned, legacyRuleIDs, nil
+}
+
+// filterOwnedRules returns the rules whose ID is owned. All the rules are owned when owned is nil.
+func filterOwnedRules(rs []infrav1.SecurityGroupRuleStatus, owned map[string]bool) []infrav1.SecurityGroupRuleStatus {
+	if owned == nil {
+		return rs
+	}
+	var filtered []infrav1.SecurityGroupRuleStatus
+	for _, rule := range rs {
+		if owned[rule.ID] {
+			filtered = append(filtered, rule)
+		}
+	}
+	return filtered
+}
+
+// TODO: Support remote address groups. The gophercloud rules API, up to v1.14, has no RemoteAddressGroupID in
+// CreateOpts nor in SecGroupRule: the rules could be created with a custom CreateOptsBuilder, but the address
+// group of the observed rules couldn't be read, so they would never match the desired rules.
+func (s *Service) getRuleCreateOpts(securityGroupID string, r resolvedSecurityGroupRuleSpec) rules.CreateOpts {
 	dir := rules.RuleDirection(r.Direction)
 	proto := rules.RuleProtocol(r.Protocol)
+	if canonicalProtocol(r.Protocol, r.EtherType) == string(rules.ProtocolAny) {
+		// The protocol is omitted from the request, so that the rule matches any protocol.
+		proto = rules.ProtocolAny
+	}
 	etherType := rules.RuleEtherType(r.EtherType)
 
-	createOpts := rules.CreateOpts{
+	s.scope.Logger().V(6).Info("Creating rule", "description", r.Description, "direction", dir, "portRangeMin", r.PortRangeMin, "portRangeMax", r.PortRangeMax, "proto", proto, "etherType", etherType, "remoteGroupID", r.RemoteGroupID, "remoteIPPrefix", r.RemoteIPPrefix, "securityGroupID", securityGroupID)
+	return rules.CreateOpts{
 		Description:    r.Description,
 		Direction:      dir,
 		PortRangeMin:   r.PortRangeMin,
@@ -507,24 +2131,132 @@ func (s *Service) createRule(securityGroupID string, r resolvedSecurityGroupRule
 		RemoteIPPrefix: r.RemoteIPPrefix,
 		SecGroupID:     securityGroupID,
 	}
-	s.scope.Logger().V(6).Info("Creating rule", "description", r.Description, "direction", dir, "portRangeMin", r.PortRangeMin, "portRangeMax", r.PortRangeMax, "proto", proto, "etherType", etherType, "remoteGroupID", r.RemoteGroupID, "remoteIPPrefix", r.RemoteIPPrefix, "securityGroupID", securityGroupID)
-	rule, err := s.client.CreateSecGroupRule(createOpts)
+}
+
+// isSecurityGroupRuleLimitError returns whether err is the error returned by the cloud when creating a rule
+// would exceed the maximum number of rules of the security group.
+func isSecurityGroupRuleLimitError(err error) bool {
+	var body []byte
+	var errDefault400 gophercloud.ErrDefault400
+	var errDefault409 gophercloud.ErrDefault409
+	var errUnexpectedResponseCode gophercloud.ErrUnexpectedResponseCode
+	switch {
+	case errors.As(err, &errDefault400):
+		body = errDefault400.Body
+	case errors.As(err, &errDefault409):
+		body = errDefault409.Body
+	case errors.As(err, &errUnexpectedResponseCode):
+		body = errUnexpectedResponseCode.Body
+	default:
+		return false
+	}
+	return securityGroupRuleLimitPattern.Match(body)
+}
+
+// getMatchingRule returns the existing rule of the security group matching r. It is used when creating r
+// failed with conflictErr because the rule already exists, which is returned if no such rule is found.
+func (s *Service) getMatchingRule(securityGroupID string, r resolvedSecurityGroupRuleSpec, conflictErr error) (*infrav1.SecurityGroupRuleStatus, error) {
+	s.scope.Logger().V(4).Info("Rule already exists, adopting it", "securityGroupID", securityGroupID, "description", r.Description)
+	existingRules, err := s.getSecurityGroupRules(securityGroupID)
 	if err != nil {
-		return infrav1.SecurityGroupRuleStatus{}, err
+		return nil, err
+	}
+	for i := range existingRules {
+		if r.Matches(existingRules[i]) {
+			return &existingRules[i], nil
+		}
 	}
-	return convertOSSecGroupRuleToConfigSecGroupRule(*rule), nil
+	// Neutron doesn't tell rules apart by their description, so the conflicting rule may only differ from r by
+	// its description, e.g. when it was created before the managed rules were marked.
+	for i := range existingRules {
+		undescribed := r
+		undescribed.Description = pointer.StringDeref(existingRules[i].Description, "")
+		if undescribed.Matches(existingRules[i]) {
+			return &existingRules[i], nil
+		}
+	}
+	return nil, conflictErr
+}
+
+// getSecGroupProjectID returns the ID of the project the managed security groups are created in, or an empty
+// string if they are created in the project of the credentials.
+func getSecGroupProjectID(openStackCluster *infrav1.OpenStackCluster) string {
+	if openStackCluster.Spec.ManagedSecurityGroups != nil {
+		return openStackCluster.Spec.ManagedSecurityGroups.ProjectID
+	}
+	return ""
+}
+
+// getSecGroupNamePrefix returns the prefix of the names of the managed security groups of the cluster.
+func getSecGroupNamePrefix(openStackCluster *infrav1.OpenStackCluster) string {
+	if openStackCluster.Spec.ManagedSecurityGroups != nil && openStackCluster.Spec.ManagedSecurityGroups.NamePrefix != "" {
+		return openStackCluster.Spec.ManagedSecurityGroups.NamePrefix
+	}
+	return secGroupPrefix
+}
+
+func getSecControlPlaneGroupName(namePrefix, clusterName string) string {
+	return fmt.Sprintf("%s-cluster-%s-secgroup-%s", namePrefix, clusterName, controlPlaneSuffix)
 }
 
-func getSecControlPlaneGroupName(clusterName string) string {
-	return fmt.Sprintf("%s-cluster-%s-secgroup-%s", secGroupPrefix, clusterName, controlPlaneSuffix)
+func getSecWorkerGroupName(namePrefix, clusterName string) string {
+	return fmt.Sprintf("%s-cluster-%s-secgroup-%s", namePrefix, clusterName, workerSuffix)
 }
 
-func getSecWorkerGroupName(clusterName string) string {
-	return fmt.Sprintf("%s-cluster-%s-secgroup-%s", secGroupPrefix, clusterName, workerSuffix)
+func getSecNodeGroupName(namePrefix, clusterName string) string {
+	return fmt.Sprintf("%s-cluster-%s-secgroup-%s", namePrefix, clusterName, nodeSuffix)
 }
 
-func getSecBastionGroupName(clusterName string) string {
-	return fmt.Sprintf("%s-cluster-%s-secgroup-%s", secGroupPrefix, clusterName, bastionSuffix)
+func getSecAllNodesGroupName(namePrefix, clusterName string) string {
+	return fmt.Sprintf("%s-cluster-%s-secgroup-%s", namePrefix, clusterName, allNodesSuffix)
+}
+
+func getSecBastionGroupName(namePrefix, clusterName string) string {
+	return fmt.Sprintf("%s-cluster-%s-secgroup-%s", namePrefix, clusterName, bastionSuffix)
+}
+
+// getSecBastionReplicaGroupName returns the name of the security group of the bastion replica with the given index.
+// The first replica has the group of a single bastion.
+func getSecBastionReplicaGroupName(namePrefix, clusterName string, replica int) string {
+	return fmt.Sprintf("%s-cluster-%s-secgroup-%s", namePrefix, clusterName, getBastionReplicaSuffix(replica))
+}
+
+// getBastionReplicas returns the number of bastion replicas of the cluster, 0 when the bastion is disabled.
+func getBastionReplicas(openStackCluster *infrav1.OpenStackCluster) int {
+	if openStackCluster.Spec.Bastion == nil || !openStackCluster.Spec.Bastion.Enabled {
+		return 0
+	}
+	return pointer.IntDeref(openStackCluster.Spec.Bastion.Replicas, 1)
+}
+
+// getBastionReplicaSuffix returns the suffix of the security group of the bastion replica with the given index,
+// e.g. bastion-1. The first replica has the suffix of a single bastion.
+func getBastionReplicaSuffix(replica int) string {
+	if replica == 0 {
+		return bastionSuffix
+	}
+	return fmt.Sprintf("%s-%d", bastionSuffix, replica)
+}
+
+// getBastionReplicaIndex returns the index of the bastion replica whose security group has the given suffix, and
+// whether it is the suffix of the group of a bastion replica at all.
+func getBastionReplicaIndex(suffix string) (int, bool) {
+	if suffix == bastionSuffix {
+		return 0, true
+	}
+	index, ok := strings.CutPrefix(suffix, bastionSuffix+"-")
+	if !ok {
+		return 0, false
+	}
+	replica, err := strconv.Atoi(index)
+	if err != nil || replica < 1 {
+		return 0, false
+	}
+	return replica, true
+}
+
+func getSecShadowGroupName(namePrefix, clusterName string) string {
+	return fmt.Sprintf("%s-cluster-%s-secgroup-%s", namePrefix, clusterName, shadowSuffix)
 }
 
 func convertOSSecGroupToConfigSecGroup(osSecGroup groups.SecGroup) *infrav1.SecurityGroupStatus {
@@ -552,15 +2284,3 @@ func convertOSSecGroupRuleToConfigSecGroupRule(osSecGroupRule rules.SecGroupRule
 		RemoteIPPrefix: &osSecGroupRule.RemoteIPPrefix,
 	}
 }
-
-func isDuplicate(list []string, name string) bool {
-	if len(list) == 0 {
-		return false
-	}
-	for _, element := range list {
-		if element == name {
-			return true
-		}
-	}
-	return false
-}
@@ -0,0 +1,54 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+)
+
+// isAllNodesGroupMissingFromPorts returns true if a port of the given node security groups doesn't have the
+// allNodes security group. The machines created before the allNodes group was separated only have their node
+// group, so the allNodes rules are kept duplicated in the node groups until these machines are replaced.
+func (s *Service) isAllNodesGroupMissingFromPorts(allNodesGroupID string, nodeGroupIDs ...string) (bool, error) {
+	// The group was just created: no port can have it yet.
+	if strings.HasPrefix(allNodesGroupID, pendingRemoteGroupIDPrefix) {
+		return true, nil
+	}
+
+	checked := make(map[string]bool, len(nodeGroupIDs))
+	for _, id := range nodeGroupIDs {
+		if checked[id] || strings.HasPrefix(id, pendingRemoteGroupIDPrefix) {
+			continue
+		}
+		checked[id] = true
+
+		groupPorts, err := s.client.ListPort(ports.ListOpts{SecurityGroups: []string{id}})
+		if err != nil {
+			return false, err
+		}
+		for _, port := range groupPorts {
+			if !slices.Contains(port.SecurityGroups, allNodesGroupID) {
+				s.scope.Logger().V(4).Info("Port doesn't have the allNodes security group yet, keeping the allNodes rules in its group", "port", port.ID, "securityGroupID", id)
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/golang/mock/gomock"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/pointer"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/clients/mock"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
+)
+
+func TestGenerateDesiredSecGroupsSeparateAllNodesGroup(t *testing.T) {
+	secGroupNames := map[string]string{
+		controlPlaneSuffix: "k8s-cluster-mycluster-secgroup-controlplane",
+		workerSuffix:       "k8s-cluster-mycluster-secgroup-worker",
+		allNodesSuffix:     "k8s-cluster-mycluster-secgroup-allNodes",
+	}
+	expectListGroups := func(m *mock.MockNetworkClientMockRecorder, allNodesGroupID string) {
+		m.ListSecGroup(groups.ListOpts{Name: secGroupNames[controlPlaneSuffix]}).Return([]groups.SecGroup{{ID: "idControlPlane"}}, nil)
+		m.ListSecGroup(groups.ListOpts{Name: secGroupNames[workerSuffix]}).Return([]groups.SecGroup{{ID: "idWorker"}}, nil)
+		var allNodesGroups []groups.SecGroup
+		if allNodesGroupID != "" {
+			allNodesGroups = []groups.SecGroup{{ID: allNodesGroupID}}
+		}
+		m.ListSecGroup(groups.ListOpts{Name: secGroupNames[allNodesSuffix]}).Return(allNodesGroups, nil)
+	}
+
+	tests := []struct {
+		name           string
+		expect         func(m *mock.MockNetworkClientMockRecorder)
+		wantDuplicated bool
+	}{
+		{
+			name: "New cluster only has the rules in the allNodes group",
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				expectListGroups(m, "idAllNodes")
+				m.ListPort(ports.ListOpts{SecurityGroups: []string{"idControlPlane"}}).Return(nil, nil)
+				m.ListPort(ports.ListOpts{SecurityGroups: []string{"idWorker"}}).Return(nil, nil)
+			},
+		},
+		{
+			name: "Migrated cluster only has the rules in the allNodes group",
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				expectListGroups(m, "idAllNodes")
+				m.ListPort(ports.ListOpts{SecurityGroups: []string{"idControlPlane"}}).Return([]ports.Port{{ID: "idPort0", SecurityGroups: []string{"idControlPlane", "idAllNodes"}}}, nil)
+				m.ListPort(ports.ListOpts{SecurityGroups: []string{"idWorker"}}).Return([]ports.Port{{ID: "idPort1", SecurityGroups: []string{"idWorker", "idAllNodes"}}}, nil)
+			},
+		},
+		{
+			name: "Rules are kept duplicated until all the machines have the allNodes group",
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				expectListGroups(m, "idAllNodes")
+				m.ListPort(ports.ListOpts{SecurityGroups: []string{"idControlPlane"}}).Return([]ports.Port{{ID: "idPort0", SecurityGroups: []string{"idControlPlane", "idAllNodes"}}}, nil)
+				m.ListPort(ports.ListOpts{SecurityGroups: []string{"idWorker"}}).Return([]ports.Port{{ID: "idPort1", SecurityGroups: []string{"idWorker"}}}, nil)
+			},
+			wantDuplicated: true,
+		},
+		{
+			name: "Rules are kept duplicated while the allNodes group is not listable",
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				expectListGroups(m, "")
+			},
+			wantDuplicated: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+			tt.expect(mockScopeFactory.NetworkClient.EXPECT())
+
+			openStackCluster := &infrav1.OpenStackCluster{
+				Spec: infrav1.OpenStackClusterSpec{
+					ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{
+						SeparateAllNodesGroup: true,
+						AllNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
+							{
+								Name:         "BGP",
+								Description:  pointer.String("BGP"),
+								Direction:    "ingress",
+								Protocol:     pointer.String("tcp"),
+								PortRangeMin: pointer.Int(179),
+								PortRangeMax: pointer.Int(179),
+								RemoteManagedGroups: []infrav1.ManagedSecurityGroupName{
+									"controlplane",
+									"worker",
+								},
+							},
+						},
+					},
+				},
+			}
+			desiredSecGroups, err := s.generateDesiredSecGroups(openStackCluster, secGroupNames)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			hasBGPRules := func(group securityGroupSpec) bool {
+				for _, rule := range group.Rules {
+					if strings.HasPrefix(rule.Description, "BGP (") {
+						return true
+					}
+				}
+				return false
+			}
+			g.Expect(desiredSecGroups).To(HaveKey(allNodesSuffix))
+			g.Expect(desiredSecGroups[allNodesSuffix].Name).To(Equal(secGroupNames[allNodesSuffix]))
+			g.Expect(hasBGPRules(desiredSecGroups[allNodesSuffix])).To(BeTrue())
+			g.Expect(hasBGPRules(desiredSecGroups[controlPlaneSuffix])).To(Equal(tt.wantDuplicated))
+			g.Expect(hasBGPRules(desiredSecGroups[workerSuffix])).To(Equal(tt.wantDuplicated))
+		})
+	}
+}
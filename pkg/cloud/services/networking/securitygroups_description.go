@@ -0,0 +1,225 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+)
+
+const defaultSecGroupDescriptionTemplate = "Cluster API managed group"
+
+// maxSecGroupDescriptionLength is the maximum length of the description of a security group allowed by Neutron.
+const maxSecGroupDescriptionLength = 255
+
+// DescriptionFormat is the format of the descriptions generated for the managed security groups and rules.
+type DescriptionFormat string
+
+const (
+	// DescriptionFormatProse generates human readable descriptions.
+	DescriptionFormatProse DescriptionFormat = "prose"
+	// DescriptionFormatStructured generates space separated key=value descriptions, for log scraping.
+	DescriptionFormatStructured DescriptionFormat = "structured"
+)
+
+// securityGroupDescriptionData is the data available to the security group description template.
+type securityGroupDescriptionData struct {
+	// Cluster is the name of the cluster prefixed with its namespace, as used in the group names.
+	Cluster string
+	// Namespace is the namespace of the OpenStackCluster.
+	Namespace string
+	// Name is the name of the OpenStackCluster.
+	Name string
+	// Role is the role of the group: controlplane, worker, node, allNodes, bastion or shadow.
+	Role string
+	// ControlReference is the compliance control reference configured by the operator, e.g. CIS-5.2.
+	ControlReference string
+}
+
+type securityGroupDescription struct {
+	template         *template.Template
+	controlReference string
+	format           DescriptionFormat
+}
+
+// ManagedRuleDescriptionMarker starts the description of the rules created by this manager when the managed rules
+// are marked.
+const ManagedRuleDescriptionMarker = "cluster-api-managed"
+
+// ManagedRuleOwnerTag is the owner tag of the rules when the managed rules are marked and no owner tag is configured.
+const ManagedRuleOwnerTag = "cluster-api-managed"
+
+// maxTagLength is the maximum length of a Neutron tag.
+const maxTagLength = 60
+
+// securityGroupDescription returns the description of the managed security groups. The description template is
+// executed with the cluster metadata, the role of the group and the control reference.
+func (o Options) securityGroupDescription() (securityGroupDescription, error) {
+	switch o.SecurityGroupDescriptionFormat {
+	case DescriptionFormatProse, DescriptionFormatStructured:
+	default:
+		return securityGroupDescription{}, fmt.Errorf("invalid security group description format %q, must be %s or %s", o.SecurityGroupDescriptionFormat, DescriptionFormatProse, DescriptionFormatStructured)
+	}
+
+	tmpl, err := template.New("description").Parse(o.SecurityGroupDescriptionTemplate)
+	if err != nil {
+		return securityGroupDescription{}, fmt.Errorf("parsing security group description template: %w", err)
+	}
+	description := securityGroupDescription{
+		template:         tmpl,
+		controlReference: o.SecurityGroupControlReference,
+		format:           o.SecurityGroupDescriptionFormat,
+	}
+	return description, nil
+}
+
+// ruleOwnership returns the prefix prepended to the description of the rules created by this manager, and the tag
+// of the rules it owns. When the managed rules are marked, the prefix starts with ManagedRuleDescriptionMarker and
+// the owner tag defaults to ManagedRuleOwnerTag.
+func (o Options) ruleOwnership() (descriptionPrefix, ownerTag string) {
+	descriptionPrefix, ownerTag = o.SecurityGroupRuleDescriptionPrefix, o.SecurityGroupRuleOwnerTag
+	if o.SecurityGroupRuleManagedMarker {
+		descriptionPrefix = ManagedRuleDescriptionMarker + ": " + descriptionPrefix
+		if ownerTag == "" {
+			ownerTag = ManagedRuleOwnerTag
+		}
+	}
+	return descriptionPrefix, ownerTag
+}
+
+// validateRuleOwnership checks the ownership of the rules. Without owner tag, the rules of the clusters without tags
+// would all be owned, including the rules with another prefix.
+func (o Options) validateRuleOwnership() error {
+	descriptionPrefix, ownerTag := o.ruleOwnership()
+	if descriptionPrefix != "" && ownerTag == "" {
+		return fmt.Errorf("a security group rule owner tag is required with the rule description prefix %q", descriptionPrefix)
+	}
+	if len(ownerTag) > maxTagLength || strings.ContainsAny(ownerTag, ",/") {
+		return fmt.Errorf("invalid security group rule owner tag %q, must be at most %d characters without comma or slash", ownerTag, maxTagLength)
+	}
+	return nil
+}
+
+// formatKeyValues formats the given key and value pairs as space separated key=value, quoting the values
+// which would otherwise be ambiguous. Pairs with an empty value are omitted.
+func formatKeyValues(keyValues ...string) string {
+	var sb strings.Builder
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		key, value := keyValues[i], keyValues[i+1]
+		if value == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(key)
+		sb.WriteByte('=')
+		if strings.ContainsAny(value, " \t\"=") {
+			value = strconv.Quote(value)
+		}
+		sb.WriteString(value)
+	}
+	return sb.String()
+}
+
+// withRuleDescriptionFormat returns the rules with their description in the given format.
+func withRuleDescriptionFormat(rules []resolvedSecurityGroupRuleSpec, format DescriptionFormat) []resolvedSecurityGroupRuleSpec {
+	if format != DescriptionFormatStructured {
+		return rules
+	}
+
+	formattedRules := make([]resolvedSecurityGroupRuleSpec, len(rules))
+	for i, rule := range rules {
+		protocol := rule.Protocol
+		if protocol == "" {
+			protocol = "any"
+		}
+		var ports string
+		if rule.PortRangeMin != 0 || rule.PortRangeMax != 0 {
+			ports = fmt.Sprintf("%d-%d", rule.PortRangeMin, rule.PortRangeMax)
+		}
+		rule.Description = formatKeyValues(
+			"rule", rule.Description,
+			"direction", rule.Direction,
+			"ethertype", rule.EtherType,
+			"protocol", protocol,
+			"ports", ports,
+			"remote_ip_prefix", rule.RemoteIPPrefix,
+		)
+		formattedRules[i] = rule
+	}
+	return formattedRules
+}
+
+// withRuleDescriptionPrefix returns the rules with prefix prepended to their description.
+func withRuleDescriptionPrefix(rules []resolvedSecurityGroupRuleSpec, prefix string) []resolvedSecurityGroupRuleSpec {
+	if prefix == "" {
+		return rules
+	}
+
+	prefixedRules := make([]resolvedSecurityGroupRuleSpec, len(rules))
+	for i, rule := range rules {
+		rule.Description = prefix + rule.Description
+		prefixedRules[i] = rule
+	}
+	return prefixedRules
+}
+
+// hasRuleDescriptionPrefix returns true if the description of the rule starts with prefix.
+func hasRuleDescriptionPrefix(rule infrav1.SecurityGroupRuleStatus, prefix string) bool {
+	return rule.Description != nil && strings.HasPrefix(*rule.Description, prefix)
+}
+
+// render returns the description of the group with the given role, truncated to the length allowed by Neutron.
+func (d securityGroupDescription) render(openStackCluster *infrav1.OpenStackCluster, clusterName, role string) (string, error) {
+	if d.format == DescriptionFormatStructured {
+		return truncateSecGroupDescription(formatKeyValues(
+			"cluster", clusterName,
+			"namespace", openStackCluster.Namespace,
+			"name", openStackCluster.Name,
+			"role", role,
+			"control", d.controlReference,
+		)), nil
+	}
+
+	var sb strings.Builder
+	err := d.template.Execute(&sb, securityGroupDescriptionData{
+		Cluster:          clusterName,
+		Namespace:        openStackCluster.Namespace,
+		Name:             openStackCluster.Name,
+		Role:             role,
+		ControlReference: d.controlReference,
+	})
+	if err != nil {
+		return "", fmt.Errorf("rendering security group description: %w", err)
+	}
+	return truncateSecGroupDescription(sb.String()), nil
+}
+
+// truncateSecGroupDescription truncates the description to the characters allowed by Neutron, so that long cluster
+// names don't make the creation of the groups fail. The description is stable, so it doesn't drift once truncated.
+func truncateSecGroupDescription(description string) string {
+	runes := []rune(description)
+	if len(runes) <= maxSecGroupDescriptionLength {
+		return description
+	}
+	return string(runes[:maxSecGroupDescriptionLength])
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	capoerrors "sigs.k8s.io/cluster-api-provider-openstack/pkg/utils/errors"
+)
+
+// managedSecGroupNameInfix separates the names of the managed security groups from their suffix.
+const managedSecGroupNameInfix = "-secgroup-"
+
+// EnsureSecurityGroupRule ensures that the security group with the ID groupID has the rule, which is only created if
+// no rule of the group matches it. It returns the existing or created rule.
+//
+// The remoteManagedGroups of the rule are resolved to the managed security groups of the same cluster as the group,
+// which must then be a managed security group. A rule with several remoteManagedGroups stands for several Neutron
+// rules, and must be ensured once for each of them. Likewise, the remoteSecurityGroupFilter of the rule must match a
+// single group.
+//
+// The rule isn't marked as managed: the reconciliation of the managed security groups leaves it in place. The
+// requests to Neutron are made with ctx, and are aborted once it is done.
+func (s *Service) EnsureSecurityGroupRule(ctx context.Context, groupID string, rule infrav1.SecurityGroupRuleSpec) (infrav1.SecurityGroupRuleStatus, error) {
+	rules := []infrav1.SecurityGroupRuleSpec{rule}
+	if err := validateRules("rule", rules); err != nil {
+		return infrav1.SecurityGroupRuleStatus{}, err
+	}
+	if len(rule.RemoteManagedGroups) > 1 {
+		return infrav1.SecurityGroupRuleStatus{}, fmt.Errorf("rule[0] (%s): remoteManagedGroups must have a single group, got %d", rule.Name, len(rule.RemoteManagedGroups))
+	}
+	s = s.withClientContext(ctx)
+	remoteManagedGroups, err := s.getRemoteManagedGroupIDs(groupID, rule.RemoteManagedGroups)
+	if err != nil {
+		return infrav1.SecurityGroupRuleStatus{}, err
+	}
+	resolvedRules, err := getAllNodesRules("rule", remoteManagedGroups, s.GetSecurityGroups, rules)
+	if err != nil {
+		return infrav1.SecurityGroupRuleStatus{}, err
+	}
+	if rule.RemoteSecurityGroupFilter != nil && len(resolvedRules) != 1 {
+		return infrav1.SecurityGroupRuleStatus{}, fmt.Errorf("rule[0] (%s): remoteSecurityGroupFilter must match a single group, got %d", rule.Name, len(resolvedRules))
+	}
+	r := resolveSelfRemoteGroupID(canonicalizeRemoteIPPrefixes(resolvedRules), groupID)[0]
+
+	existingRules, err := s.getSecurityGroupRules(groupID)
+	if err != nil {
+		return infrav1.SecurityGroupRuleStatus{}, err
+	}
+	for i := range existingRules {
+		if r.Matches(existingRules[i]) {
+			s.scope.Logger().V(6).Info("Security group rule already exists", "securityGroupID", groupID, "ruleID", existingRules[i].ID)
+			return existingRules[i], nil
+		}
+	}
+
+	s.scope.Logger().V(6).Info("Creating security group rule", "securityGroupID", groupID, "rule", r)
+	created, err := s.createRule(ctx, groupID, r, nil)
+	if capoerrors.IsConflict(err) {
+		// The rule was created since we listed the rules of the group.
+		existingRule, err := s.getMatchingRule(groupID, r, err)
+		if err != nil {
+			return infrav1.SecurityGroupRuleStatus{}, err
+		}
+		return *existingRule, nil
+	}
+	if err != nil {
+		return infrav1.SecurityGroupRuleStatus{}, fmt.Errorf("creating rule of security group %s: %w", groupID, err)
+	}
+	return created, nil
+}
+
+// getRemoteManagedGroupIDs returns the IDs of the ruleRemoteManagedGroups of the cluster of the managed security
+// group with the ID groupID, keyed by suffix. The groups are found by name, the names of the managed security groups
+// of a cluster only differing by their suffix.
+func (s *Service) getRemoteManagedGroupIDs(groupID string, ruleRemoteManagedGroups []infrav1.ManagedSecurityGroupName) (map[string]string, error) {
+	if len(ruleRemoteManagedGroups) == 0 {
+		return nil, nil
+	}
+	group, err := s.client.GetSecGroup(groupID)
+	if err != nil {
+		return nil, fmt.Errorf("getting security group %s: %w", groupID, err)
+	}
+	i := strings.LastIndex(group.Name, managedSecGroupNameInfix)
+	if i < 0 {
+		return nil, fmt.Errorf("remoteManagedGroups can't be resolved: security group %s with id %s is not a managed security group", group.Name, groupID)
+	}
+
+	remoteManagedGroups := make(map[string]string, len(ruleRemoteManagedGroups))
+	for _, rg := range ruleRemoteManagedGroups {
+		name := group.Name[:i] + managedSecGroupNameInfix + rg.String()
+		remoteGroup, err := s.getOSSecurityGroupByName(name, group.ProjectID, group.Tags)
+		if err != nil {
+			return nil, err
+		}
+		if remoteGroup == nil {
+			return nil, fmt.Errorf("remoteManagedGroups: security group %s of %s not found", name, rg)
+		}
+		remoteManagedGroups[rg.String()] = remoteGroup.ID
+	}
+	return remoteManagedGroups, nil
+}
@@ -0,0 +1,200 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/golang/mock/gomock"
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/pointer"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/clients/mock"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
+)
+
+func TestEnsureSecurityGroupRule(t *testing.T) {
+	const (
+		controlPlaneName = "k8s-cluster-mycluster-secgroup-controlplane"
+		workerName       = "k8s-cluster-mycluster-secgroup-worker"
+	)
+
+	bgp := infrav1.SecurityGroupRuleSpec{
+		Name:         "BGP",
+		Description:  pointer.String("BGP"),
+		Direction:    "ingress",
+		EtherType:    pointer.String("IPv4"),
+		Protocol:     pointer.String("tcp"),
+		PortRangeMin: pointer.Int(179),
+		PortRangeMax: pointer.Int(179),
+	}
+	withRemoteIPPrefix := func(rule infrav1.SecurityGroupRuleSpec, prefix string) infrav1.SecurityGroupRuleSpec {
+		rule.RemoteIPPrefix = pointer.String(prefix)
+		return rule
+	}
+	withRemoteManagedGroups := func(rule infrav1.SecurityGroupRuleSpec, remoteManagedGroups ...infrav1.ManagedSecurityGroupName) infrav1.SecurityGroupRuleSpec {
+		rule.RemoteManagedGroups = remoteManagedGroups
+		return rule
+	}
+	bgpCreateOpts := func(remoteGroupID, remoteIPPrefix string) rules.CreateOpts {
+		return rules.CreateOpts{
+			Description:    "BGP",
+			Direction:      rules.DirIngress,
+			EtherType:      rules.EtherType4,
+			Protocol:       rules.ProtocolTCP,
+			PortRangeMin:   179,
+			PortRangeMax:   179,
+			RemoteGroupID:  remoteGroupID,
+			RemoteIPPrefix: remoteIPPrefix,
+			SecGroupID:     "idControlPlane",
+		}
+	}
+	bgpRule := func(id, remoteGroupID, remoteIPPrefix string) rules.SecGroupRule {
+		return rules.SecGroupRule{
+			ID:             id,
+			Description:    "BGP",
+			Direction:      "ingress",
+			EtherType:      "IPv4",
+			Protocol:       "tcp",
+			PortRangeMin:   179,
+			PortRangeMax:   179,
+			RemoteGroupID:  remoteGroupID,
+			RemoteIPPrefix: remoteIPPrefix,
+			SecGroupID:     "idControlPlane",
+		}
+	}
+
+	tests := []struct {
+		name       string
+		rule       infrav1.SecurityGroupRuleSpec
+		expect     func(m *mock.MockNetworkClientMockRecorder)
+		wantRuleID string
+		wantErr    bool
+	}{
+		{
+			name: "Matching rule is not created again",
+			rule: withRemoteIPPrefix(bgp, "10.0.0.1/24"),
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroupRule(rules.ListOpts{SecGroupID: "idControlPlane"}).Return([]rules.SecGroupRule{bgpRule("idRule", "", "10.0.0.0/24")}, nil)
+			},
+			wantRuleID: "idRule",
+		},
+		{
+			name: "Missing rule is created",
+			rule: withRemoteIPPrefix(bgp, "10.0.0.0/24"),
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroupRule(rules.ListOpts{SecGroupID: "idControlPlane"}).Return([]rules.SecGroupRule{bgpRule("idOther", "", "10.0.1.0/24")}, nil)
+				rule := bgpRule("idRule", "", "10.0.0.0/24")
+				m.CreateSecGroupRule(bgpCreateOpts("", "10.0.0.0/24")).Return(&rule, nil)
+			},
+			wantRuleID: "idRule",
+		},
+		{
+			name: "Remote managed group is resolved",
+			rule: withRemoteManagedGroups(bgp, "worker"),
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.GetSecGroup("idControlPlane").Return(&groups.SecGroup{ID: "idControlPlane", Name: controlPlaneName}, nil)
+				m.ListSecGroup(groups.ListOpts{Name: workerName}).Return([]groups.SecGroup{{ID: "idWorker", Name: workerName}}, nil)
+				m.ListSecGroupRule(rules.ListOpts{SecGroupID: "idControlPlane"}).Return(nil, nil)
+				// The description is suffixed with the name of the referenced managed group.
+				rule := bgpRule("idRule", "idWorker", "")
+				rule.Description = "BGP (worker)"
+				createOpts := bgpCreateOpts("idWorker", "")
+				createOpts.Description = "BGP (worker)"
+				m.CreateSecGroupRule(createOpts).Return(&rule, nil)
+			},
+			wantRuleID: "idRule",
+		},
+		{
+			name: "Rule created concurrently is returned",
+			rule: withRemoteIPPrefix(bgp, "10.0.0.0/24"),
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				gomock.InOrder(
+					m.ListSecGroupRule(rules.ListOpts{SecGroupID: "idControlPlane"}).Return(nil, nil),
+					m.CreateSecGroupRule(bgpCreateOpts("", "10.0.0.0/24")).Return(nil, gophercloud.ErrDefault409{}),
+					m.ListSecGroupRule(rules.ListOpts{SecGroupID: "idControlPlane"}).Return([]rules.SecGroupRule{bgpRule("idRule", "", "10.0.0.0/24")}, nil),
+				)
+			},
+			wantRuleID: "idRule",
+		},
+		{
+			name: "Remote managed group of a group which isn't managed is an error",
+			rule: withRemoteManagedGroups(bgp, "worker"),
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.GetSecGroup("idControlPlane").Return(&groups.SecGroup{ID: "idControlPlane", Name: "default"}, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name: "Missing remote managed group is an error",
+			rule: withRemoteManagedGroups(bgp, "bastion"),
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.GetSecGroup("idControlPlane").Return(&groups.SecGroup{ID: "idControlPlane", Name: controlPlaneName}, nil)
+				m.ListSecGroup(groups.ListOpts{Name: "k8s-cluster-mycluster-secgroup-bastion"}).Return(nil, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name:    "Several remote managed groups are an error",
+			rule:    withRemoteManagedGroups(bgp, "controlplane", "worker"),
+			expect:  func(m *mock.MockNetworkClientMockRecorder) {},
+			wantErr: true,
+		},
+		{
+			name:    "Several remotes are an error",
+			rule:    withRemoteManagedGroups(withRemoteIPPrefix(bgp, "10.0.0.0/24"), "worker"),
+			expect:  func(m *mock.MockNetworkClientMockRecorder) {},
+			wantErr: true,
+		},
+		{
+			name: "Missing direction is an error",
+			rule: func() infrav1.SecurityGroupRuleSpec {
+				rule := bgp
+				rule.Direction = ""
+				return rule
+			}(),
+			expect:  func(m *mock.MockNetworkClientMockRecorder) {},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+			tt.expect(mockScopeFactory.NetworkClient.EXPECT())
+
+			rule, err := s.EnsureSecurityGroupRule(context.TODO(), "idControlPlane", tt.rule)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(rule.ID).To(Equal(tt.wantRuleID))
+		})
+	}
+}
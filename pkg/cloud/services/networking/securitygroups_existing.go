@@ -0,0 +1,84 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"fmt"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/record"
+)
+
+// adoptedSecGroupTag is the tag of the pre-existing security groups adopted as managed security groups. It tells
+// them apart from the groups created by the controller once the cluster is deleted.
+const adoptedSecGroupTag = "capo-adopted"
+
+// adoptExistingSecurityGroups adopts the existingGroups of the managed security groups spec as the managed groups
+// of their role, given the names of the managed groups keyed by suffix. A group is tagged as adopted, then renamed
+// to the name of the managed group: it is then found, and reconciled, like the groups created by the controller.
+func (s *Service) adoptExistingSecurityGroups(openStackCluster *infrav1.OpenStackCluster, secGroupNames map[string]string) error {
+	for _, existing := range openStackCluster.Spec.ManagedSecurityGroups.ExistingGroups {
+		name, ok := secGroupNames[string(existing.Role)]
+		if !ok {
+			return fmt.Errorf("existing security group %s can't be adopted as the %s group, which is not a managed security group of the cluster", existing.ID, existing.Role)
+		}
+
+		group, err := s.client.GetSecGroup(existing.ID)
+		if err != nil {
+			return fmt.Errorf("getting existing security group %s: %w", existing.ID, err)
+		}
+
+		// The group is tagged first, so that it is never managed without being known as adopted.
+		if !hasAllTags(group.Tags, []string{adoptedSecGroupTag}) {
+			s.scope.Logger().V(6).Info("Tagging existing security group as adopted", "name", group.Name, "id", group.ID)
+			if err := s.client.AddAttributesTag("security-groups", group.ID, adoptedSecGroupTag); err != nil {
+				record.Warnf(openStackCluster, "FailedAdoptSecurityGroup", "Failed to tag security group %s with id %s as adopted: %v", group.Name, group.ID, err)
+				return err
+			}
+		}
+
+		if group.Name == name {
+			continue
+		}
+		managed, err := s.getOSSecurityGroupByName(name, getSecGroupProjectID(openStackCluster), openStackCluster.Spec.Tags)
+		if err != nil {
+			return err
+		}
+		if managed != nil {
+			return fmt.Errorf("existing security group %s can't be adopted as the %s group: security group %s already exists with id %s", existing.ID, existing.Role, name, managed.ID)
+		}
+
+		s.scope.Logger().Info("Adopting existing security group", "name", group.Name, "id", group.ID, "newName", name)
+		if _, err := s.client.UpdateSecGroup(group.ID, groups.UpdateOpts{Name: name}); err != nil {
+			record.Warnf(openStackCluster, "FailedAdoptSecurityGroup", "Failed to rename security group %s with id %s to %s: %v", group.Name, group.ID, name, err)
+			return err
+		}
+		record.Eventf(openStackCluster, "SuccessfulAdoptSecurityGroup", "Adopted security group %s with id %s as %s", group.Name, group.ID, name)
+	}
+	return nil
+}
+
+// isRetainedAdoptedSecGroup returns whether the group was adopted, and must be left in place when the cluster is
+// deleted.
+func isRetainedAdoptedSecGroup(openStackCluster *infrav1.OpenStackCluster, group *groups.SecGroup) bool {
+	if openStackCluster.Spec.ManagedSecurityGroups != nil && openStackCluster.Spec.ManagedSecurityGroups.DeleteAdoptedGroups {
+		return false
+	}
+	return hasAllTags(group.Tags, []string{adoptedSecGroupTag})
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/golang/mock/gomock"
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
+	. "github.com/onsi/gomega"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/clients/mock"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
+)
+
+func TestAdoptExistingSecurityGroups(t *testing.T) {
+	const (
+		controlPlaneName = "k8s-cluster-mycluster-secgroup-controlplane"
+		workerName       = "k8s-cluster-mycluster-secgroup-worker"
+	)
+	secGroupNames := map[string]string{
+		controlPlaneSuffix: controlPlaneName,
+		workerSuffix:       workerName,
+	}
+
+	tests := []struct {
+		name           string
+		existingGroups []infrav1.ExistingSecurityGroup
+		expect         func(m *mock.MockNetworkClientMockRecorder)
+		wantErr        bool
+	}{
+		{
+			name:   "Nothing is adopted without existing groups",
+			expect: func(m *mock.MockNetworkClientMockRecorder) {},
+		},
+		{
+			name:           "Existing group is tagged and renamed",
+			existingGroups: []infrav1.ExistingSecurityGroup{{Role: "controlplane", ID: "idExisting"}},
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.GetSecGroup("idExisting").Return(&groups.SecGroup{ID: "idExisting", Name: "terraform-controlplane"}, nil)
+				gomock.InOrder(
+					m.AddAttributesTag("security-groups", "idExisting", adoptedSecGroupTag).Return(nil),
+					m.ListSecGroup(groups.ListOpts{Name: controlPlaneName}).Return(nil, nil),
+					m.UpdateSecGroup("idExisting", groups.UpdateOpts{Name: controlPlaneName}).Return(&groups.SecGroup{}, nil),
+				)
+			},
+		},
+		{
+			name:           "Adopted group is left alone",
+			existingGroups: []infrav1.ExistingSecurityGroup{{Role: "controlplane", ID: "idExisting"}},
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.GetSecGroup("idExisting").Return(&groups.SecGroup{ID: "idExisting", Name: controlPlaneName, Tags: []string{adoptedSecGroupTag}}, nil)
+			},
+		},
+		{
+			name:           "Existing group isn't adopted when the managed group exists",
+			existingGroups: []infrav1.ExistingSecurityGroup{{Role: "worker", ID: "idExisting"}},
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.GetSecGroup("idExisting").Return(&groups.SecGroup{ID: "idExisting", Name: "terraform-worker"}, nil)
+				m.AddAttributesTag("security-groups", "idExisting", adoptedSecGroupTag).Return(nil)
+				m.ListSecGroup(groups.ListOpts{Name: workerName}).Return([]groups.SecGroup{{ID: "idWorker", Name: workerName}}, nil)
+			},
+			wantErr: true,
+		},
+		{
+			name:           "Existing group isn't adopted as a group which isn't managed",
+			existingGroups: []infrav1.ExistingSecurityGroup{{Role: "bastion", ID: "idExisting"}},
+			expect:         func(m *mock.MockNetworkClientMockRecorder) {},
+			wantErr:        true,
+		},
+		{
+			name:           "Missing existing group is an error",
+			existingGroups: []infrav1.ExistingSecurityGroup{{Role: "controlplane", ID: "idExisting"}},
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.GetSecGroup("idExisting").Return(nil, gophercloud.ErrDefault404{})
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+			tt.expect(mockScopeFactory.NetworkClient.EXPECT())
+
+			openStackCluster := &infrav1.OpenStackCluster{
+				Spec: infrav1.OpenStackClusterSpec{
+					ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{ExistingGroups: tt.existingGroups},
+				},
+			}
+			err = s.adoptExistingSecurityGroups(openStackCluster, secGroupNames)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestDeleteSecurityGroupsAdopted(t *testing.T) {
+	const (
+		controlPlaneName = "k8s-cluster-mycluster-secgroup-controlplane"
+		workerName       = "k8s-cluster-mycluster-secgroup-worker"
+	)
+
+	tests := []struct {
+		name                string
+		deleteAdoptedGroups bool
+		mockExpect          func(m *mock.MockNetworkClientMockRecorder)
+	}{
+		{
+			name: "Adopted group is left in place",
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Name: controlPlaneName}).Return([]groups.SecGroup{{ID: "idControlPlane", Name: controlPlaneName, Tags: []string{adoptedSecGroupTag}}}, nil)
+				m.ListSecGroup(groups.ListOpts{Name: workerName}).Return([]groups.SecGroup{{ID: "idWorker", Name: workerName}}, nil)
+				m.DeleteSecGroup("idWorker").Return(nil)
+			},
+		},
+		{
+			name:                "Adopted group is deleted",
+			deleteAdoptedGroups: true,
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Name: controlPlaneName}).Return([]groups.SecGroup{{ID: "idControlPlane", Name: controlPlaneName, Tags: []string{adoptedSecGroupTag}}}, nil)
+				m.DeleteSecGroup("idControlPlane").Return(nil)
+				m.ListSecGroup(groups.ListOpts{Name: workerName}).Return([]groups.SecGroup{{ID: "idWorker", Name: workerName}}, nil)
+				m.DeleteSecGroup("idWorker").Return(nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+
+			tt.mockExpect(mockScopeFactory.NetworkClient.EXPECT())
+			openStackCluster := &infrav1.OpenStackCluster{
+				Spec: infrav1.OpenStackClusterSpec{
+					ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{DeleteAdoptedGroups: tt.deleteAdoptedGroups},
+				},
+			}
+			g.Expect(s.DeleteSecurityGroups(context.TODO(), openStackCluster, "mycluster")).To(Succeed())
+		})
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/record"
+)
+
+// ForeignSecurityGroupPolicy is the policy applied to the security groups which have the tags of a cluster but
+// not the name of one of its managed security groups, e.g. the groups of a cluster which was renamed.
+type ForeignSecurityGroupPolicy string
+
+const (
+	// ForeignSecurityGroupIgnore leaves the foreign groups alone.
+	ForeignSecurityGroupIgnore ForeignSecurityGroupPolicy = "Ignore"
+	// ForeignSecurityGroupWarn emits a warning event for each foreign group.
+	ForeignSecurityGroupWarn ForeignSecurityGroupPolicy = "Warn"
+	// ForeignSecurityGroupAdopt renames a foreign group named like a managed group with the same role to the name
+	// of the managed group, if no group has that name yet. The other foreign groups are warned about.
+	ForeignSecurityGroupAdopt ForeignSecurityGroupPolicy = "Adopt"
+)
+
+// validate returns an error if the policy is unknown.
+func (p ForeignSecurityGroupPolicy) validate() error {
+	switch p {
+	case ForeignSecurityGroupIgnore, ForeignSecurityGroupWarn, ForeignSecurityGroupAdopt:
+		return nil
+	}
+	return fmt.Errorf("invalid foreign security group policy %q, must be one of %s, %s or %s", p,
+		ForeignSecurityGroupIgnore, ForeignSecurityGroupWarn, ForeignSecurityGroupAdopt)
+}
+
+// reconcileForeignSecurityGroups applies the foreign security group policy to the groups which have the tags of
+// the cluster but none of the given names of its managed security groups, keyed by suffix. Clusters without tags
+// have no foreign groups.
+func (s *Service) reconcileForeignSecurityGroups(openStackCluster *infrav1.OpenStackCluster, secGroupNames map[string]string) error {
+	if s.foreignSecGroupPolicy == ForeignSecurityGroupIgnore || len(openStackCluster.Spec.Tags) == 0 {
+		return nil
+	}
+
+	taggedGroups, err := s.client.ListSecGroup(groups.ListOpts{
+		Tags:      strings.Join(openStackCluster.Spec.Tags, ","),
+		ProjectID: getSecGroupProjectID(openStackCluster),
+	})
+	if err != nil {
+		return err
+	}
+
+	managedNames := make(map[string]bool, len(secGroupNames))
+	for _, name := range secGroupNames {
+		managedNames[name] = true
+	}
+
+	for i := range taggedGroups {
+		group := &taggedGroups[i]
+		if managedNames[group.Name] {
+			continue
+		}
+
+		if s.foreignSecGroupPolicy == ForeignSecurityGroupAdopt {
+			adopted, err := s.adoptForeignSecurityGroup(openStackCluster, group, secGroupNames)
+			if err != nil {
+				return err
+			}
+			if adopted {
+				continue
+			}
+		}
+
+		s.scope.Logger().Info("Security group has the tags of the cluster but is not managed", "name", group.Name, "id", group.ID)
+		record.Warnf(openStackCluster, "ForeignSecurityGroup", "Security group %s with id %s has the tags of the cluster but is not one of its managed security groups", group.Name, group.ID)
+	}
+	return nil
+}
+
+// adoptForeignSecurityGroup renames the foreign group to the name of the managed group with the same suffix, if
+// the name of the foreign group is compatible and no group has the name of the managed group yet.
+func (s *Service) adoptForeignSecurityGroup(openStackCluster *infrav1.OpenStackCluster, group *groups.SecGroup, secGroupNames map[string]string) (bool, error) {
+	suffix, ok := getSecGroupNameSuffix(group.Name, getSecGroupNamePrefix(openStackCluster), secGroupNames)
+	if !ok {
+		return false, nil
+	}
+	name := secGroupNames[suffix]
+
+	existing, err := s.getOSSecurityGroupByName(name, getSecGroupProjectID(openStackCluster), openStackCluster.Spec.Tags)
+	if err != nil {
+		return false, err
+	}
+	if existing != nil {
+		return false, nil
+	}
+
+	s.scope.Logger().Info("Adopting security group", "name", group.Name, "id", group.ID, "newName", name)
+	if _, err := s.client.UpdateSecGroup(group.ID, groups.UpdateOpts{Name: name}); err != nil {
+		record.Warnf(openStackCluster, "FailedAdoptSecurityGroup", "Failed to rename security group %s with id %s to %s: %v", group.Name, group.ID, name, err)
+		return false, err
+	}
+	record.Eventf(openStackCluster, "SuccessfulAdoptSecurityGroup", "Adopted security group %s with id %s as %s", group.Name, group.ID, name)
+	return true, nil
+}
+
+// getSecGroupNameSuffix returns the suffix of the managed security groups whose name has the same form as the
+// given name, i.e. <namePrefix>-cluster-<cluster name>-secgroup-<suffix>.
+func getSecGroupNameSuffix(name, namePrefix string, secGroupNames map[string]string) (string, bool) {
+	prefix := namePrefix + "-cluster-"
+	for suffix := range secGroupNames {
+		groupSuffix := "-secgroup-" + suffix
+		if strings.HasPrefix(name, prefix) && strings.HasSuffix(name, groupSuffix) && len(name) > len(prefix)+len(groupSuffix) {
+			return suffix, true
+		}
+	}
+	return "", false
+}
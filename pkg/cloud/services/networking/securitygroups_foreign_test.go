@@ -0,0 +1,163 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/golang/mock/gomock"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
+	. "github.com/onsi/gomega"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/clients/mock"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
+)
+
+func TestReconcileForeignSecurityGroups(t *testing.T) {
+	const (
+		controlPlaneName = "k8s-cluster-newname-secgroup-controlplane"
+		workerName       = "k8s-cluster-newname-secgroup-worker"
+	)
+	secGroupNames := map[string]string{
+		controlPlaneSuffix: controlPlaneName,
+		workerSuffix:       workerName,
+	}
+	tags := []string{"cluster-tag", "team-tag"}
+	managedGroup := groups.SecGroup{ID: "idControlPlane", Name: controlPlaneName, Tags: tags}
+
+	tests := []struct {
+		name   string
+		policy ForeignSecurityGroupPolicy
+		tags   []string
+		expect func(m *mock.MockNetworkClientMockRecorder)
+	}{
+		{
+			name:   "Ignore doesn't look for foreign groups",
+			policy: ForeignSecurityGroupIgnore,
+			tags:   tags,
+			expect: func(m *mock.MockNetworkClientMockRecorder) {},
+		},
+		{
+			name:   "Clusters without tags have no foreign groups",
+			policy: ForeignSecurityGroupAdopt,
+			expect: func(m *mock.MockNetworkClientMockRecorder) {},
+		},
+		{
+			name:   "Warn leaves the foreign group alone",
+			policy: ForeignSecurityGroupWarn,
+			tags:   tags,
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Tags: "cluster-tag,team-tag"}).Return([]groups.SecGroup{
+					managedGroup,
+					{ID: "idForeign", Name: "k8s-cluster-oldname-secgroup-worker", Tags: tags},
+				}, nil)
+			},
+		},
+		{
+			name:   "Adopt renames a name-compatible foreign group",
+			policy: ForeignSecurityGroupAdopt,
+			tags:   tags,
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Tags: "cluster-tag,team-tag"}).Return([]groups.SecGroup{
+					managedGroup,
+					{ID: "idForeign", Name: "k8s-cluster-oldname-secgroup-worker", Tags: tags},
+				}, nil)
+				m.ListSecGroup(groups.ListOpts{Name: workerName}).Return([]groups.SecGroup{}, nil)
+				m.UpdateSecGroup("idForeign", groups.UpdateOpts{Name: workerName}).Return(&groups.SecGroup{ID: "idForeign", Name: workerName}, nil)
+			},
+		},
+		{
+			name:   "Adopt leaves a name-compatible foreign group alone when the managed group exists",
+			policy: ForeignSecurityGroupAdopt,
+			tags:   tags,
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Tags: "cluster-tag,team-tag"}).Return([]groups.SecGroup{
+					managedGroup,
+					{ID: "idForeign", Name: "k8s-cluster-oldname-secgroup-controlplane", Tags: tags},
+				}, nil)
+				m.ListSecGroup(groups.ListOpts{Name: controlPlaneName}).Return([]groups.SecGroup{managedGroup}, nil)
+			},
+		},
+		{
+			name:   "Adopt leaves a foreign group with an incompatible name alone",
+			policy: ForeignSecurityGroupAdopt,
+			tags:   tags,
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Tags: "cluster-tag,team-tag"}).Return([]groups.SecGroup{
+					managedGroup,
+					{ID: "idForeign", Name: "k8s-cluster-oldname-secgroup-ingress", Tags: tags},
+				}, nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+			s.foreignSecGroupPolicy = tt.policy
+
+			tt.expect(mockScopeFactory.NetworkClient.EXPECT())
+
+			openStackCluster := &infrav1.OpenStackCluster{
+				Spec: infrav1.OpenStackClusterSpec{Tags: tt.tags},
+			}
+			g.Expect(s.reconcileForeignSecurityGroups(openStackCluster, secGroupNames)).To(Succeed())
+		})
+	}
+}
+
+func TestGetSecGroupNameSuffix(t *testing.T) {
+	secGroupNames := map[string]string{
+		controlPlaneSuffix: "k8s-cluster-newname-secgroup-controlplane",
+		workerSuffix:       "k8s-cluster-newname-secgroup-worker",
+	}
+
+	tests := []struct {
+		name       string
+		groupName  string
+		namePrefix string
+		wantSuffix string
+		wantOK     bool
+	}{
+		{name: "Control plane group of another cluster", groupName: "k8s-cluster-oldname-secgroup-controlplane", wantSuffix: controlPlaneSuffix, wantOK: true},
+		{name: "Worker group of a namespaced cluster", groupName: "k8s-cluster-default-oldname-secgroup-worker", wantSuffix: workerSuffix, wantOK: true},
+		{name: "Group of an unmanaged role", groupName: "k8s-cluster-oldname-secgroup-bastion"},
+		{name: "Group without cluster name", groupName: "k8s-cluster--secgroup-worker"},
+		{name: "Unrelated group", groupName: "my-worker"},
+		{name: "Worker group with the name prefix", groupName: "mgmt-a-cluster-oldname-secgroup-worker", namePrefix: "mgmt-a", wantSuffix: workerSuffix, wantOK: true},
+		{name: "Worker group without the name prefix", groupName: "k8s-cluster-oldname-secgroup-worker", namePrefix: "mgmt-a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			namePrefix := tt.namePrefix
+			if namePrefix == "" {
+				namePrefix = secGroupPrefix
+			}
+			suffix, ok := getSecGroupNameSuffix(tt.groupName, namePrefix, secGroupNames)
+			g.Expect(ok).To(Equal(tt.wantOK))
+			g.Expect(suffix).To(Equal(tt.wantSuffix))
+		})
+	}
+}
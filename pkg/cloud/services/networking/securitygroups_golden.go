@@ -0,0 +1,113 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"sort"
+
+	"sigs.k8s.io/yaml"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/record"
+)
+
+// SetGoldenRules sets the golden rules of the managed security groups, keyed by group suffix, e.g. worker. Each
+// value is a YAML list of rules, in which a remoteGroupID may be the suffix of a managed group or self. When
+// golden rules are set, ReconcileSecurityGroups warns about the groups whose generated rules diverge from them.
+func (s *Service) SetGoldenRules(goldenRules map[string]string) {
+	s.goldenRules = goldenRules
+}
+
+// checkGoldenRules compares the desired rules of the groups having golden rules with those golden rules, and
+// warns about the groups whose rules diverge. It returns the suffixes of the diverging groups. The groups
+// without golden rules, and the golden rules of groups which are not desired, are ignored.
+func (s *Service) checkGoldenRules(openStackCluster *infrav1.OpenStackCluster, desiredSecGroups map[string]securityGroupSpec, secGroupNames map[string]string) ([]string, error) {
+	if len(s.goldenRules) == 0 {
+		return nil, nil
+	}
+
+	// The golden rules reference the managed groups by suffix, as their IDs are only known once created.
+	suffixes := make(map[string]string, len(secGroupNames))
+	for suffix, name := range secGroupNames {
+		secGroup, err := s.getSecurityGroupByName(name, getSecGroupProjectID(openStackCluster), openStackCluster.Spec.Tags)
+		if err != nil {
+			return nil, err
+		}
+		suffixes[secGroup.ID] = suffix
+	}
+
+	var diverged []string
+	for suffix, desiredSecGroup := range desiredSecGroups {
+		value, ok := s.goldenRules[suffix]
+		if !ok {
+			continue
+		}
+
+		var goldenRules []resolvedSecurityGroupRuleSpec
+		if err := yaml.UnmarshalStrict([]byte(value), &goldenRules); err != nil {
+			record.Warnf(openStackCluster, "InvalidSecurityGroupGoldenRules", "Golden rules of security group %s are invalid: %v", desiredSecGroup.Name, err)
+			continue
+		}
+
+		generatedRules := make([]resolvedSecurityGroupRuleSpec, 0, len(desiredSecGroup.Rules))
+		for _, rule := range desiredSecGroup.Rules {
+			if remoteSuffix, ok := suffixes[rule.RemoteGroupID]; ok && rule.RemoteGroupID != "" {
+				rule.RemoteGroupID = remoteSuffix
+			}
+			generatedRules = append(generatedRules, rule)
+		}
+
+		missing, unexpected := diffGoldenRules(goldenRules, generatedRules)
+		if len(missing) == 0 && len(unexpected) == 0 {
+			continue
+		}
+
+		s.scope.Logger().Info("Generated security group rules diverge from the golden rules", "name", desiredSecGroup.Name, "missing", missing, "unexpected", unexpected)
+		record.Warnf(openStackCluster, "SecurityGroupRulesDiverged", "Generated rules of security group %s diverge from the golden rules: %d missing, %d unexpected", desiredSecGroup.Name, len(missing), len(unexpected))
+		diverged = append(diverged, suffix)
+	}
+	sort.Strings(diverged)
+	return diverged, nil
+}
+
+// diffGoldenRules returns the golden rules which are not generated, and the generated rules which are not golden.
+// Rules are compared as multisets, ignoring their origin.
+func diffGoldenRules(goldenRules, generatedRules []resolvedSecurityGroupRuleSpec) (missing, unexpected []resolvedSecurityGroupRuleSpec) {
+	remaining := make(map[resolvedSecurityGroupRuleSpec]int, len(generatedRules))
+	for _, rule := range generatedRules {
+		rule.Origin = ""
+		remaining[rule]++
+	}
+
+	for _, rule := range goldenRules {
+		rule.Origin = ""
+		if remaining[rule] > 0 {
+			remaining[rule]--
+			continue
+		}
+		missing = append(missing, rule)
+	}
+
+	for _, rule := range generatedRules {
+		rule.Origin = ""
+		if remaining[rule] > 0 {
+			remaining[rule]--
+			unexpected = append(unexpected, rule)
+		}
+	}
+	return missing, unexpected
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/golang/mock/gomock"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
+	. "github.com/onsi/gomega"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/clients/mock"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
+)
+
+func TestCheckGoldenRules(t *testing.T) {
+	secGroupNames := map[string]string{
+		controlPlaneSuffix: "k8s-cluster-mycluster-secgroup-controlplane",
+		workerSuffix:       "k8s-cluster-mycluster-secgroup-worker",
+	}
+	desiredSecGroups := map[string]securityGroupSpec{
+		workerSuffix: {
+			Name: secGroupNames[workerSuffix],
+			Rules: []resolvedSecurityGroupRuleSpec{
+				{
+					Description:   "Kubelet API",
+					Direction:     "ingress",
+					EtherType:     "IPv4",
+					PortRangeMin:  10250,
+					PortRangeMax:  10250,
+					Protocol:      "tcp",
+					RemoteGroupID: remoteGroupIDSelf,
+					Origin:        infrav1.SecurityGroupRuleOriginGeneral,
+				},
+				{
+					Description:   "Kubelet API",
+					Direction:     "ingress",
+					EtherType:     "IPv4",
+					PortRangeMin:  10250,
+					PortRangeMax:  10250,
+					Protocol:      "tcp",
+					RemoteGroupID: "idControlPlane",
+					Origin:        infrav1.SecurityGroupRuleOriginGeneral,
+				},
+			},
+		},
+	}
+	expectListGroups := func(m *mock.MockNetworkClientMockRecorder) {
+		m.ListSecGroup(groups.ListOpts{Name: secGroupNames[controlPlaneSuffix]}).Return([]groups.SecGroup{{ID: "idControlPlane"}}, nil)
+		m.ListSecGroup(groups.ListOpts{Name: secGroupNames[workerSuffix]}).Return([]groups.SecGroup{{ID: "idWorker"}}, nil)
+	}
+
+	tests := []struct {
+		name         string
+		goldenRules  map[string]string
+		expect       func(m *mock.MockNetworkClientMockRecorder)
+		wantDiverged []string
+	}{
+		{
+			name:   "Without golden rules nothing is checked",
+			expect: func(m *mock.MockNetworkClientMockRecorder) {},
+		},
+		{
+			name: "Generated rules match the golden rules",
+			goldenRules: map[string]string{
+				workerSuffix: `
+- {description: Kubelet API, direction: ingress, etherType: IPv4, portRangeMin: 10250, portRangeMax: 10250, protocol: tcp, remoteGroupID: controlplane}
+- {description: Kubelet API, direction: ingress, etherType: IPv4, portRangeMin: 10250, portRangeMax: 10250, protocol: tcp, remoteGroupID: self}
+`,
+			},
+			expect: expectListGroups,
+		},
+		{
+			name: "Generated rules diverge from the golden rules",
+			goldenRules: map[string]string{
+				workerSuffix: `
+- {description: Kubelet API, direction: ingress, etherType: IPv4, portRangeMin: 10250, portRangeMax: 10250, protocol: tcp, remoteGroupID: self}
+- {description: SSH, direction: ingress, etherType: IPv4, portRangeMin: 22, portRangeMax: 22, protocol: tcp}
+`,
+			},
+			expect:       expectListGroups,
+			wantDiverged: []string{workerSuffix},
+		},
+		{
+			name: "Golden rules of groups which are not desired are ignored",
+			goldenRules: map[string]string{
+				bastionSuffix: `
+- {description: SSH, direction: ingress, etherType: IPv4, portRangeMin: 22, portRangeMax: 22, protocol: tcp}
+`,
+			},
+			expect: expectListGroups,
+		},
+		{
+			name: "Invalid golden rules are not checked",
+			goldenRules: map[string]string{
+				workerSuffix: `- {description: SSH, port: 22}`,
+			},
+			expect: expectListGroups,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+			s.SetGoldenRules(tt.goldenRules)
+
+			tt.expect(mockScopeFactory.NetworkClient.EXPECT())
+
+			diverged, err := s.checkGoldenRules(&infrav1.OpenStackCluster{}, desiredSecGroups, secGroupNames)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(diverged).To(Equal(tt.wantDiverged))
+		})
+	}
+}
+
+func TestDiffGoldenRules(t *testing.T) {
+	g := NewWithT(t)
+
+	ssh := resolvedSecurityGroupRuleSpec{Description: "SSH", Direction: "ingress", EtherType: "IPv4", PortRangeMin: 22, PortRangeMax: 22, Protocol: "tcp"}
+	https := resolvedSecurityGroupRuleSpec{Description: "HTTPS", Direction: "ingress", EtherType: "IPv4", PortRangeMin: 443, PortRangeMax: 443, Protocol: "tcp"}
+	generatedSSH := ssh
+	generatedSSH.Origin = infrav1.SecurityGroupRuleOriginBastion
+
+	missing, unexpected := diffGoldenRules([]resolvedSecurityGroupRuleSpec{ssh, https}, []resolvedSecurityGroupRuleSpec{generatedSSH, generatedSSH})
+	g.Expect(missing).To(Equal([]resolvedSecurityGroupRuleSpec{https}))
+	g.Expect(unexpected).To(Equal([]resolvedSecurityGroupRuleSpec{ssh}))
+}
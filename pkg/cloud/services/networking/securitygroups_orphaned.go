@@ -0,0 +1,76 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/utils/pointer"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	capoerrors "sigs.k8s.io/cluster-api-provider-openstack/pkg/utils/errors"
+)
+
+// deleteOrphanedRules deletes the managed rules of the observed group whose remote group doesn't exist anymore, e.g.
+// because a managed group was deleted and recreated with a new ID. They match no traffic, and aren't always
+// desired rules to be replaced: they are deleted regardless of the desired rules. Only the rules owned with the
+// tags are deleted, if there are tags. It returns the remaining rules of the group.
+func (s *Service) deleteOrphanedRules(ctx context.Context, observed infrav1.SecurityGroupStatus, tags []string) ([]infrav1.SecurityGroupRuleStatus, error) {
+	// The existence of each remote group is only checked once.
+	remoteGroupExists := map[string]bool{observed.ID: true}
+	var orphanedRules []infrav1.SecurityGroupRuleStatus
+	for _, rule := range observed.Rules {
+		remoteGroupID := pointer.StringDeref(rule.RemoteGroupID, "")
+		if remoteGroupID == "" {
+			continue
+		}
+		exists, ok := remoteGroupExists[remoteGroupID]
+		if !ok {
+			_, err := s.client.GetSecGroup(remoteGroupID)
+			switch {
+			case err == nil:
+				exists = true
+			case capoerrors.IsNotFound(err):
+				exists = false
+			default:
+				return nil, fmt.Errorf("getting remote security group %s of rule %s: %w", remoteGroupID, rule.ID, err)
+			}
+			remoteGroupExists[remoteGroupID] = exists
+		}
+		if !exists {
+			orphanedRules = append(orphanedRules, rule)
+		}
+	}
+	if len(orphanedRules) == 0 {
+		return observed.Rules, nil
+	}
+
+	// The untagged rules with the rule description prefix are owned, but only adopted once the orphans are deleted.
+	ownedRuleIDs, _, err := s.getOwnedRuleIDs(observed, tags)
+	if err != nil {
+		return nil, err
+	}
+	orphanedRules = filterOwnedRules(orphanedRules, ownedRuleIDs)
+	for _, rule := range orphanedRules {
+		s.scope.Logger().Info("Deleting rule referencing a deleted security group", "name", observed.Name, "ID", rule.ID, "remoteGroupID", pointer.StringDeref(rule.RemoteGroupID, ""))
+		if err := s.deleteRule(ctx, observed, rule); err != nil {
+			return nil, err
+		}
+	}
+	return withoutRules(observed.Rules, orphanedRules), nil
+}
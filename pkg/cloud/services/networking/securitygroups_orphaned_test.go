@@ -0,0 +1,114 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/golang/mock/gomock"
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/pointer"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/clients/mock"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
+)
+
+func TestDeleteOrphanedRules(t *testing.T) {
+	ruleWithRemoteGroup := func(id, remoteGroupID string) infrav1.SecurityGroupRuleStatus {
+		return infrav1.SecurityGroupRuleStatus{
+			ID:            id,
+			Direction:     "ingress",
+			EtherType:     pointer.String("IPv4"),
+			RemoteGroupID: pointer.String(remoteGroupID),
+		}
+	}
+	ruleWithoutRemoteGroup := infrav1.SecurityGroupRuleStatus{ID: "idEgress", Direction: "egress", EtherType: pointer.String("IPv4")}
+
+	tests := []struct {
+		name      string
+		rules     []infrav1.SecurityGroupRuleStatus
+		tags      []string
+		expect    func(m *mock.MockNetworkClientMockRecorder)
+		wantRules []infrav1.SecurityGroupRuleStatus
+		wantErr   bool
+	}{
+		{
+			name:  "Rules referencing existing groups are kept",
+			rules: []infrav1.SecurityGroupRuleStatus{ruleWithRemoteGroup("idSelf", "idControlPlane"), ruleWithRemoteGroup("idFromWorker", "idWorker"), ruleWithoutRemoteGroup},
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.GetSecGroup("idWorker").Return(&groups.SecGroup{ID: "idWorker"}, nil)
+			},
+			wantRules: []infrav1.SecurityGroupRuleStatus{ruleWithRemoteGroup("idSelf", "idControlPlane"), ruleWithRemoteGroup("idFromWorker", "idWorker"), ruleWithoutRemoteGroup},
+		},
+		{
+			name:  "Rules referencing a deleted group are deleted",
+			rules: []infrav1.SecurityGroupRuleStatus{ruleWithRemoteGroup("idKubelet", "idOldWorker"), ruleWithRemoteGroup("idEtcd", "idOldWorker"), ruleWithoutRemoteGroup},
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.GetSecGroup("idOldWorker").Return(nil, gophercloud.ErrDefault404{})
+				m.DeleteSecGroupRule("idKubelet").Return(nil)
+				m.DeleteSecGroupRule("idEtcd").Return(nil)
+			},
+			wantRules: []infrav1.SecurityGroupRuleStatus{ruleWithoutRemoteGroup},
+		},
+		{
+			name:  "Only the owned rules referencing a deleted group are deleted",
+			rules: []infrav1.SecurityGroupRuleStatus{ruleWithRemoteGroup("idKubelet", "idOldWorker"), ruleWithRemoteGroup("idUnowned", "idOldWorker")},
+			tags:  []string{"tag1"},
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.GetSecGroup("idOldWorker").Return(nil, gophercloud.ErrDefault404{})
+				m.ListSecGroupRuleWithTags(rules.ListOpts{SecGroupID: "idControlPlane"}, []string{"tag1"}).Return([]rules.SecGroupRule{{ID: "idKubelet"}}, nil)
+				m.DeleteSecGroupRule("idKubelet").Return(nil)
+			},
+			wantRules: []infrav1.SecurityGroupRuleStatus{ruleWithRemoteGroup("idUnowned", "idOldWorker")},
+		},
+		{
+			name:  "Failure to get the remote group is an error",
+			rules: []infrav1.SecurityGroupRuleStatus{ruleWithRemoteGroup("idFromWorker", "idWorker")},
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.GetSecGroup("idWorker").Return(nil, gophercloud.ErrDefault500{})
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+			tt.expect(mockScopeFactory.NetworkClient.EXPECT())
+
+			observed := infrav1.SecurityGroupStatus{ID: "idControlPlane", Name: "k8s-cluster-mycluster-secgroup-controlplane", Rules: tt.rules}
+			remainingRules, err := s.deleteOrphanedRules(context.TODO(), observed, tt.tags)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(remainingRules).To(Equal(tt.wantRules))
+		})
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+)
+
+// SecurityGroupPlan is the change ReconcileSecurityGroups would make to the rules of a managed security group.
+type SecurityGroupPlan struct {
+	// Name is the name of the group.
+	Name string
+	// ID is the ID of the group, empty when the group doesn't exist yet.
+	ID string
+	// RulesToCreate are the rules which would be created. They have no ID. A rule referencing a group which
+	// doesn't exist yet has the remote group ID "pending:" followed by the suffix of that group.
+	RulesToCreate []infrav1.SecurityGroupRuleStatus
+	// RulesToDelete are the rules which would be deleted. The rules whose deletion is deferred by the rule
+	// deletion grace period, and, when the cluster has tags, the rules not carrying them, are not included.
+	RulesToDelete []infrav1.SecurityGroupRuleStatus
+}
+
+// SecurityGroupsPlan is the change ReconcileSecurityGroups would make to the managed security groups of a cluster,
+// keyed by the suffix of the name of each group, e.g. controlplane or worker.
+type SecurityGroupsPlan map[string]SecurityGroupPlan
+
+// HasChanges returns true if a rule of a group would be created or deleted.
+func (p SecurityGroupsPlan) HasChanges() bool {
+	for _, group := range p {
+		if len(group.RulesToCreate) > 0 || len(group.RulesToDelete) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanSecurityGroups returns the change ReconcileSecurityGroups would make to the rules of the managed security
+// groups of a cluster, without changing the groups or the status of the cluster. Unlike ReconcileSecurityGroups,
+// it compares the rules of every group with the desired rules, whether or not the spec changed, so the plan also
+// covers the drift of the groups. It returns a nil plan when the cluster has no managed security groups.
+// The requests to Neutron are made with ctx, and are aborted once it is done.
+func (s *Service) PlanSecurityGroups(ctx context.Context, openStackCluster *infrav1.OpenStackCluster, clusterName string) (SecurityGroupsPlan, error) {
+	if openStackCluster.Spec.ManagedSecurityGroups == nil {
+		return nil, nil
+	}
+
+	ctx, cancel, err := s.withSecurityGroupClientTimeout(ctx, openStackCluster)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+	s = s.withClientContext(ctx)
+
+	if err := validateAllNodesRules(openStackCluster.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules); err != nil {
+		return nil, err
+	}
+	if err := validateRules("shadowRules", openStackCluster.Spec.ManagedSecurityGroups.ShadowRules); err != nil {
+		return nil, err
+	}
+
+	secGroupNames, err := getManagedSecGroupNames(openStackCluster, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	desiredSecGroups, err := s.generateDesiredSecGroups(openStackCluster, secGroupNames)
+	if err != nil {
+		return nil, err
+	}
+	previousSecGroups := getPreviousSecGroups(openStackCluster)
+
+	plan := make(SecurityGroupsPlan, len(desiredSecGroups))
+	for k, desiredSecGroup := range desiredSecGroups {
+		observed, err := s.getSecurityGroupByName(desiredSecGroup.Name, getSecGroupProjectID(openStackCluster), openStackCluster.Spec.Tags)
+		if err != nil {
+			return nil, err
+		}
+		groupPlan := SecurityGroupPlan{Name: desiredSecGroup.Name, ID: observed.ID}
+
+		if observed.ID != "" {
+			observed.Rules, err = s.getSecurityGroupRules(observed.ID)
+			if err != nil {
+				return nil, err
+			}
+			if previous := previousSecGroups[k]; previous != nil {
+				observed.RulesPendingDeletion = previous.RulesPendingDeletion
+			}
+		} else {
+			// The rules of the group referencing the group itself reference it as any other group not created yet.
+			observed.ID = pendingRemoteGroupID(k)
+		}
+
+		diff := s.diffGroupRules(desiredSecGroup, *observed)
+		if groupPlan.ID != "" {
+			ownedRuleIDs, _, err := s.getOwnedRuleIDs(*observed, s.getRuleOwnershipTags(openStackCluster))
+			if err != nil {
+				return nil, err
+			}
+			diff.rulesToDelete = filterOwnedRules(diff.rulesToDelete, ownedRuleIDs)
+		}
+		groupPlan.RulesToDelete, _ = s.deferRuleDeletions(diff.rulesToDelete, observed.RulesPendingDeletion, len(diff.rulesToCreate) == 0)
+		for _, rule := range diff.rulesToCreate {
+			groupPlan.RulesToCreate = append(groupPlan.RulesToCreate, getPlannedRuleStatus(rule))
+		}
+		plan[k] = groupPlan
+	}
+	return plan, nil
+}
+
+// getPlannedRuleStatus returns the status the rule would have once created.
+func getPlannedRuleStatus(r resolvedSecurityGroupRuleSpec) infrav1.SecurityGroupRuleStatus {
+	status := convertOSSecGroupRuleToConfigSecGroupRule(rules.SecGroupRule{
+		Description:    r.Description,
+		Direction:      r.Direction,
+		EtherType:      r.EtherType,
+		PortRangeMin:   r.PortRangeMin,
+		PortRangeMax:   r.PortRangeMax,
+		Protocol:       r.Protocol,
+		RemoteGroupID:  r.RemoteGroupID,
+		RemoteIPPrefix: r.RemoteIPPrefix,
+	})
+	status.Origin = r.Origin
+	return status
+}
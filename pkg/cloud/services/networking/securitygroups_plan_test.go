@@ -0,0 +1,197 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/golang/mock/gomock"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
+	. "github.com/onsi/gomega"
+	"k8s.io/utils/pointer"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
+)
+
+func TestPlanSecurityGroups(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	controlPlaneGroup := groups.SecGroup{ID: "idControlPlane", Name: "k8s-cluster-mycluster-secgroup-controlplane"}
+	workerGroup := groups.SecGroup{ID: "idWorker", Name: "k8s-cluster-mycluster-secgroup-worker"}
+
+	// Planning only lists the groups and their rules: any other call fails the test.
+	observedRules := map[string][]rules.SecGroupRule{}
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	m.ListSecGroup(groups.ListOpts{Name: controlPlaneGroup.Name}).Return([]groups.SecGroup{controlPlaneGroup}, nil).AnyTimes()
+	m.ListSecGroup(groups.ListOpts{Name: workerGroup.Name}).Return([]groups.SecGroup{workerGroup}, nil).AnyTimes()
+	m.ListSecGroupRule(gomock.Any()).DoAndReturn(func(opts rules.ListOpts) ([]rules.SecGroupRule, error) {
+		return observedRules[opts.SecGroupID], nil
+	}).AnyTimes()
+
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{},
+		},
+	}
+	status := openStackCluster.Status.DeepCopy()
+
+	// The groups have no rules yet: all the desired rules would be created.
+	plan, err := s.PlanSecurityGroups(context.TODO(), openStackCluster, "mycluster")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(plan).To(HaveLen(2))
+	g.Expect(plan.HasChanges()).To(BeTrue())
+	for k, group := range map[string]groups.SecGroup{controlPlaneSuffix: controlPlaneGroup, workerSuffix: workerGroup} {
+		g.Expect(plan[k].Name).To(Equal(group.Name))
+		g.Expect(plan[k].ID).To(Equal(group.ID))
+		g.Expect(plan[k].RulesToCreate).NotTo(BeEmpty())
+		g.Expect(plan[k].RulesToDelete).To(BeEmpty())
+	}
+	g.Expect(openStackCluster.Status).To(Equal(*status))
+
+	// Once the planned rules exist, only the stale rule would be deleted.
+	for k, group := range map[string]groups.SecGroup{controlPlaneSuffix: controlPlaneGroup, workerSuffix: workerGroup} {
+		for i, rule := range plan[k].RulesToCreate {
+			observedRules[group.ID] = append(observedRules[group.ID], rules.SecGroupRule{
+				ID:             fmt.Sprintf("idRule%d", i),
+				SecGroupID:     group.ID,
+				Description:    pointer.StringDeref(rule.Description, ""),
+				Direction:      rule.Direction,
+				EtherType:      pointer.StringDeref(rule.EtherType, ""),
+				PortRangeMin:   pointer.IntDeref(rule.PortRangeMin, 0),
+				PortRangeMax:   pointer.IntDeref(rule.PortRangeMax, 0),
+				Protocol:       pointer.StringDeref(rule.Protocol, ""),
+				RemoteGroupID:  pointer.StringDeref(rule.RemoteGroupID, ""),
+				RemoteIPPrefix: pointer.StringDeref(rule.RemoteIPPrefix, ""),
+			})
+		}
+	}
+	staleRule := rules.SecGroupRule{ID: "idStale", SecGroupID: workerGroup.ID, Description: "Stale", Direction: "ingress", EtherType: "IPv4", Protocol: "tcp", PortRangeMin: 8080, PortRangeMax: 8080}
+	observedRules[workerGroup.ID] = append(observedRules[workerGroup.ID], staleRule)
+
+	plan, err = s.PlanSecurityGroups(context.TODO(), openStackCluster, "mycluster")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(plan[controlPlaneSuffix].RulesToCreate).To(BeEmpty())
+	g.Expect(plan[controlPlaneSuffix].RulesToDelete).To(BeEmpty())
+	g.Expect(plan[workerSuffix].RulesToCreate).To(BeEmpty())
+	g.Expect(plan[workerSuffix].RulesToDelete).To(Equal([]infrav1.SecurityGroupRuleStatus{convertOSSecGroupRuleToConfigSecGroupRule(staleRule)}))
+
+	// Planning again, with nothing changed, gives the same plan.
+	samePlan, err := s.PlanSecurityGroups(context.TODO(), openStackCluster, "mycluster")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(samePlan).To(Equal(plan))
+
+	observedRules[workerGroup.ID] = observedRules[workerGroup.ID][:len(observedRules[workerGroup.ID])-1]
+	plan, err = s.PlanSecurityGroups(context.TODO(), openStackCluster, "mycluster")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(plan.HasChanges()).To(BeFalse())
+}
+
+func TestPlanSecurityGroupsMissingGroup(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	controlPlaneGroup := groups.SecGroup{ID: "idControlPlane", Name: "k8s-cluster-mycluster-secgroup-controlplane"}
+
+	// The worker group doesn't exist: it isn't created, and its rules aren't listed.
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	m.ListSecGroup(groups.ListOpts{Name: controlPlaneGroup.Name}).Return([]groups.SecGroup{controlPlaneGroup}, nil).AnyTimes()
+	m.ListSecGroup(groups.ListOpts{Name: "k8s-cluster-mycluster-secgroup-worker"}).Return([]groups.SecGroup{}, nil).AnyTimes()
+	m.ListSecGroupRule(rules.ListOpts{SecGroupID: controlPlaneGroup.ID}).Return([]rules.SecGroupRule{}, nil)
+
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{},
+		},
+	}
+	plan, err := s.PlanSecurityGroups(context.TODO(), openStackCluster, "mycluster")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(plan[workerSuffix].ID).To(BeEmpty())
+	g.Expect(plan[workerSuffix].RulesToCreate).NotTo(BeEmpty())
+
+	// The rules referencing the worker group reference it by its pending ID.
+	var remoteGroupIDs []string
+	for _, rule := range plan[controlPlaneSuffix].RulesToCreate {
+		remoteGroupIDs = append(remoteGroupIDs, pointer.StringDeref(rule.RemoteGroupID, ""))
+	}
+	g.Expect(remoteGroupIDs).To(ContainElement(pendingRemoteGroupID(workerSuffix)))
+}
+
+func TestPlanSecurityGroupsUnmanaged(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	plan, err := s.PlanSecurityGroups(context.TODO(), &infrav1.OpenStackCluster{}, "mycluster")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(plan).To(BeNil())
+	g.Expect(plan.HasChanges()).To(BeFalse())
+}
+
+func TestPlanSecurityGroupsTagged(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	tags := []string{"cluster-tag"}
+	controlPlaneGroup := groups.SecGroup{ID: "idControlPlane", Name: "k8s-cluster-mycluster-secgroup-controlplane", Tags: tags}
+	workerGroup := groups.SecGroup{ID: "idWorker", Name: "k8s-cluster-mycluster-secgroup-worker", Tags: tags}
+	ownedRule := rules.SecGroupRule{ID: "idOwned", SecGroupID: workerGroup.ID, Description: "Stale", Direction: "ingress", EtherType: "IPv4", Protocol: "tcp", PortRangeMin: 8080, PortRangeMax: 8080}
+	foreignRule := rules.SecGroupRule{ID: "idForeign", SecGroupID: workerGroup.ID, Description: "Stale", Direction: "ingress", EtherType: "IPv4", Protocol: "tcp", PortRangeMin: 8081, PortRangeMax: 8081}
+
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	m.ListSecGroup(groups.ListOpts{Name: controlPlaneGroup.Name}).Return([]groups.SecGroup{controlPlaneGroup}, nil).AnyTimes()
+	m.ListSecGroup(groups.ListOpts{Name: workerGroup.Name}).Return([]groups.SecGroup{workerGroup}, nil).AnyTimes()
+	m.ListSecGroupRule(rules.ListOpts{SecGroupID: controlPlaneGroup.ID}).Return(nil, nil)
+	m.ListSecGroupRule(rules.ListOpts{SecGroupID: workerGroup.ID}).Return([]rules.SecGroupRule{ownedRule, foreignRule}, nil)
+	m.ListSecGroupRuleWithTags(rules.ListOpts{SecGroupID: controlPlaneGroup.ID}, tags).Return(nil, nil)
+	m.ListSecGroupRuleWithTags(rules.ListOpts{SecGroupID: workerGroup.ID}, tags).Return([]rules.SecGroupRule{ownedRule}, nil)
+
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{},
+			Tags:                  tags,
+		},
+	}
+	plan, err := s.PlanSecurityGroups(context.TODO(), openStackCluster, "mycluster")
+	g.Expect(err).NotTo(HaveOccurred())
+	// The rule without the tags of the cluster isn't deleted.
+	g.Expect(plan[workerSuffix].RulesToDelete).To(Equal([]infrav1.SecurityGroupRuleStatus{convertOSSecGroupRuleToConfigSecGroupRule(ownedRule)}))
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/record"
+)
+
+// SecurityGroupRuleProber checks that the traffic permitted by a rule of a managed security group reaches an
+// address. It returns an error describing why the traffic didn't reach the address. The probe is aborted once ctx
+// is done.
+type SecurityGroupRuleProber interface {
+	Probe(ctx context.Context, address string, rule infrav1.SecurityGroupRuleStatus) error
+}
+
+// tcpSecurityGroupRuleProber probes the TCP rules by connecting to the lowest port they permit.
+type tcpSecurityGroupRuleProber struct{}
+
+func (p tcpSecurityGroupRuleProber) Probe(ctx context.Context, address string, rule infrav1.SecurityGroupRuleStatus) error {
+	if rule.Protocol == nil || *rule.Protocol != "tcp" || rule.PortRangeMin == nil {
+		return fmt.Errorf("only the rules permitting TCP ports can be probed")
+	}
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(address, strconv.Itoa(*rule.PortRangeMin)))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// probeSecurityGroupRules probes the SSH rule of the bastion security group by connecting to the floating IP of the
+// bastion, and records the result in the status of the cluster. Nothing is probed unless the cluster opts in with
+// the SecurityGroupRuleProbeTimeoutAnnotation, nor when the cluster has no bastion reachable from the controller.
+// Nor is anything probed when SSH to the bastion is restricted to spec.bastion.sshAllowedCIDRs, as the controller
+// may rightly not be permitted. A rule which is not effective is warned about but doesn't fail the reconcile. The
+// probe is aborted once ctx is done.
+func (s *Service) probeSecurityGroupRules(ctx context.Context, openStackCluster *infrav1.OpenStackCluster) {
+	value, ok := openStackCluster.Annotations[infrav1.SecurityGroupRuleProbeTimeoutAnnotation]
+	if !ok || openStackCluster.Status.ManagedSecurityGroups == nil {
+		return
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		record.Warnf(openStackCluster, "InvalidSecurityGroupRuleProbeTimeout", "Invalid %s annotation %q: must be a positive duration", infrav1.SecurityGroupRuleProbeTimeoutAnnotation, value)
+		return
+	}
+
+	group := openStackCluster.Status.BastionSecurityGroup
+	bastion := openStackCluster.Status.Bastion
+	if group == nil || bastion == nil || bastion.FloatingIP == "" {
+		s.scope.Logger().V(4).Info("No bastion reachable from the controller, not probing the security group rules")
+		return
+	}
+
+	if openStackCluster.Spec.Bastion != nil && len(openStackCluster.Spec.Bastion.SSHAllowedCIDRs) > 0 {
+		s.scope.Logger().V(4).Info("SSH to the bastion is restricted, not probing the security group rules", "name", group.Name)
+		return
+	}
+
+	var rule *infrav1.SecurityGroupRuleStatus
+	for i := range group.Rules {
+		r := &group.Rules[i]
+		if r.Origin == infrav1.SecurityGroupRuleOriginBastion && r.Direction == "ingress" && r.Protocol != nil && *r.Protocol == "tcp" {
+			rule = r
+			break
+		}
+	}
+	if rule == nil {
+		s.scope.Logger().V(4).Info("Bastion security group has no SSH rule, not probing the security group rules", "name", group.Name)
+		return
+	}
+
+	probe := &infrav1.SecurityGroupRuleProbeStatus{
+		SecurityGroupName: group.Name,
+		RuleID:            rule.ID,
+		Address:           bastion.FloatingIP,
+		Effective:         true,
+		ProbeTime:         metav1.Time{Time: s.clock.Now()},
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := s.secGroupRuleProber.Probe(probeCtx, bastion.FloatingIP, *rule); err != nil {
+		probe.Effective = false
+		probe.Message = err.Error()
+		record.Warnf(openStackCluster, "SecurityGroupRuleNotEffective", "Rule %s of security group %s doesn't permit traffic to %s: %v", rule.ID, group.Name, bastion.FloatingIP, err)
+	}
+	s.scope.Logger().V(4).Info("Probed security group rule", "name", group.Name, "ruleID", rule.ID, "effective", probe.Effective)
+	openStackCluster.Status.ManagedSecurityGroups.RuleProbe = probe
+}
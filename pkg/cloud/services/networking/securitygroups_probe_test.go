@@ -0,0 +1,205 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/pointer"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
+)
+
+// fakeSecurityGroupRuleProber records the probes and fails them with err.
+type fakeSecurityGroupRuleProber struct {
+	err       error
+	addresses []string
+	ruleIDs   []string
+	deadlines []time.Time
+}
+
+func (p *fakeSecurityGroupRuleProber) Probe(ctx context.Context, address string, rule infrav1.SecurityGroupRuleStatus) error {
+	deadline, _ := ctx.Deadline()
+	p.deadlines = append(p.deadlines, deadline)
+	p.addresses = append(p.addresses, address)
+	p.ruleIDs = append(p.ruleIDs, rule.ID)
+	return p.err
+}
+
+func TestProbeSecurityGroupRules(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sshRule := infrav1.SecurityGroupRuleStatus{
+		ID:           "idSSH",
+		Direction:    "ingress",
+		Protocol:     pointer.String("tcp"),
+		PortRangeMin: pointer.Int(22),
+		PortRangeMax: pointer.Int(22),
+		Origin:       infrav1.SecurityGroupRuleOriginBastion,
+	}
+	defaultRule := infrav1.SecurityGroupRuleStatus{ID: "idEgress", Direction: "egress", Origin: infrav1.SecurityGroupRuleOriginDefault}
+	bastionGroup := &infrav1.SecurityGroupStatus{
+		Name:  "k8s-cluster-mycluster-secgroup-bastion",
+		ID:    "idBastion",
+		Rules: []infrav1.SecurityGroupRuleStatus{defaultRule, sshRule},
+	}
+
+	tests := []struct {
+		name          string
+		bastionGroup  *infrav1.SecurityGroupStatus
+		bastionSpec   *infrav1.Bastion
+		bastion       *infrav1.BastionStatus
+		annotations   map[string]string
+		probeErr      error
+		wantAddresses []string
+		wantProbe     *infrav1.SecurityGroupRuleProbeStatus
+	}{
+		{
+			name:          "Effective rule is recorded",
+			bastionGroup:  bastionGroup,
+			bastion:       &infrav1.BastionStatus{FloatingIP: "203.0.113.10"},
+			wantAddresses: []string{"203.0.113.10"},
+			wantProbe: &infrav1.SecurityGroupRuleProbeStatus{
+				SecurityGroupName: "k8s-cluster-mycluster-secgroup-bastion",
+				RuleID:            "idSSH",
+				Address:           "203.0.113.10",
+				Effective:         true,
+				ProbeTime:         metav1.Time{Time: now},
+			},
+		},
+		{
+			name:          "Ineffective rule is recorded",
+			bastionGroup:  bastionGroup,
+			bastion:       &infrav1.BastionStatus{FloatingIP: "203.0.113.10"},
+			probeErr:      errors.New("i/o timeout"),
+			wantAddresses: []string{"203.0.113.10"},
+			wantProbe: &infrav1.SecurityGroupRuleProbeStatus{
+				SecurityGroupName: "k8s-cluster-mycluster-secgroup-bastion",
+				RuleID:            "idSSH",
+				Address:           "203.0.113.10",
+				Message:           "i/o timeout",
+				ProbeTime:         metav1.Time{Time: now},
+			},
+		},
+		{
+			name:         "Cluster without the annotation is not probed",
+			bastionGroup: bastionGroup,
+			bastion:      &infrav1.BastionStatus{FloatingIP: "203.0.113.10"},
+			annotations:  map[string]string{},
+		},
+		{
+			name:         "Cluster with an invalid timeout is not probed",
+			bastionGroup: bastionGroup,
+			bastion:      &infrav1.BastionStatus{FloatingIP: "203.0.113.10"},
+			annotations:  map[string]string{infrav1.SecurityGroupRuleProbeTimeoutAnnotation: "soon"},
+		},
+		{
+			name:         "Bastion without floating IP is not probed",
+			bastionGroup: bastionGroup,
+			bastion:      &infrav1.BastionStatus{IP: "10.0.0.10"},
+		},
+		{
+			name:         "Bastion with restricted SSH is not probed",
+			bastionGroup: bastionGroup,
+			bastionSpec:  &infrav1.Bastion{Enabled: true, SSHAllowedCIDRs: []string{"192.0.2.0/24"}},
+			bastion:      &infrav1.BastionStatus{FloatingIP: "203.0.113.10"},
+		},
+		{
+			name:    "Cluster without bastion group is not probed",
+			bastion: &infrav1.BastionStatus{FloatingIP: "203.0.113.10"},
+		},
+		{
+			name:         "Bastion group without SSH rule is not probed",
+			bastionGroup: &infrav1.SecurityGroupStatus{Name: "k8s-cluster-mycluster-secgroup-bastion", Rules: []infrav1.SecurityGroupRuleStatus{defaultRule}},
+			bastion:      &infrav1.BastionStatus{FloatingIP: "203.0.113.10"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+			prober := &fakeSecurityGroupRuleProber{err: tt.probeErr}
+			s.secGroupRuleProber = prober
+			s.clock = testingclock.NewFakePassiveClock(now)
+
+			annotations := tt.annotations
+			if annotations == nil {
+				annotations = map[string]string{infrav1.SecurityGroupRuleProbeTimeoutAnnotation: "5s"}
+			}
+			openStackCluster := &infrav1.OpenStackCluster{
+				ObjectMeta: metav1.ObjectMeta{Annotations: annotations},
+				Spec:       infrav1.OpenStackClusterSpec{Bastion: tt.bastionSpec},
+				Status: infrav1.OpenStackClusterStatus{
+					BastionSecurityGroup:  tt.bastionGroup,
+					Bastion:               tt.bastion,
+					ManagedSecurityGroups: &infrav1.ManagedSecurityGroupsStatus{},
+				},
+			}
+			start := time.Now()
+			s.probeSecurityGroupRules(context.TODO(), openStackCluster)
+
+			g.Expect(prober.addresses).To(Equal(tt.wantAddresses))
+			// The probe times out after the timeout of the annotation.
+			for _, deadline := range prober.deadlines {
+				g.Expect(deadline).To(BeTemporally("~", start.Add(5*time.Second), time.Second))
+			}
+			g.Expect(openStackCluster.Status.ManagedSecurityGroups.RuleProbe).To(Equal(tt.wantProbe))
+		})
+	}
+}
+
+func TestTCPSecurityGroupRuleProber(t *testing.T) {
+	g := NewWithT(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	g.Expect(err).NotTo(HaveOccurred())
+	port := listener.Addr().(*net.TCPAddr).Port
+	g.Expect(listener.Close()).To(Succeed())
+
+	prober := tcpSecurityGroupRuleProber{}
+	rule := infrav1.SecurityGroupRuleStatus{Protocol: pointer.String("tcp"), PortRangeMin: pointer.Int(port), PortRangeMax: pointer.Int(port)}
+
+	// Nothing listens on the port anymore.
+	g.Expect(prober.Probe(context.TODO(), "127.0.0.1", rule)).NotTo(Succeed())
+
+	listener, err = net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	g.Expect(err).NotTo(HaveOccurred())
+	defer listener.Close()
+	g.Expect(prober.Probe(context.TODO(), "127.0.0.1", rule)).To(Succeed())
+
+	g.Expect(prober.Probe(context.TODO(), "127.0.0.1", infrav1.SecurityGroupRuleStatus{Protocol: pointer.String("icmp")})).NotTo(Succeed())
+
+	// The probe is aborted with the context.
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	g.Expect(prober.Probe(ctx, "127.0.0.1", rule)).To(MatchError(context.Canceled))
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/record"
+	capoerrors "sigs.k8s.io/cluster-api-provider-openstack/pkg/utils/errors"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/utils/names"
+)
+
+// SecurityGroupRemovalPolicy is the policy applied to the managed security groups of a cluster once
+// managedSecurityGroups is removed from its spec.
+type SecurityGroupRemovalPolicy string
+
+const (
+	// SecurityGroupRemovalLeave leaves the groups and their rules in place.
+	SecurityGroupRemovalLeave SecurityGroupRemovalPolicy = "Leave"
+	// SecurityGroupRemovalDelete deletes each group, and clears its status, once no port uses it anymore.
+	SecurityGroupRemovalDelete SecurityGroupRemovalPolicy = "Delete"
+)
+
+// validate returns an error if the policy is unknown.
+func (p SecurityGroupRemovalPolicy) validate() error {
+	switch p {
+	case SecurityGroupRemovalLeave, SecurityGroupRemovalDelete:
+		return nil
+	}
+	return fmt.Errorf("invalid security group removal policy %q, must be one of %s or %s", p,
+		SecurityGroupRemovalLeave, SecurityGroupRemovalDelete)
+}
+
+// ErrSecurityGroupInUse is returned while the managed security groups of a cluster whose spec doesn't have
+// managedSecurityGroups anymore can't be deleted because ports still use them. It is not a failure: the groups
+// are deleted once the machines have been replaced or their ports detached from the groups.
+var ErrSecurityGroupInUse = errors.New("security group is still in use")
+
+// reconcileRemovedSecurityGroups applies the security group removal policy to the managed security groups
+// reported in the status of a cluster whose spec doesn't have managedSecurityGroups anymore.
+func (s *Service) reconcileRemovedSecurityGroups(openStackCluster *infrav1.OpenStackCluster) error {
+	if s.secGroupRemovalPolicy != SecurityGroupRemovalDelete {
+		s.scope.Logger().V(4).Info("No need to reconcile security groups")
+		return nil
+	}
+
+	statuses := []**infrav1.SecurityGroupStatus{
+		&openStackCluster.Status.ShadowSecurityGroup,
+		&openStackCluster.Status.BastionSecurityGroup,
+		&openStackCluster.Status.WorkerSecurityGroup,
+		&openStackCluster.Status.NodeSecurityGroup,
+		&openStackCluster.Status.AllNodesSecurityGroup,
+		&openStackCluster.Status.ControlPlaneSecurityGroup,
+	}
+	// The groups of the bastion replicas after the first are listed by index, so a deleted group is cleared
+	// in place, and only removed from the list once the groups after it are deleted too.
+	additionalBastionSecGroups := make([]*infrav1.SecurityGroupStatus, len(openStackCluster.Status.AdditionalBastionSecurityGroups))
+	for i := range openStackCluster.Status.AdditionalBastionSecurityGroups {
+		if openStackCluster.Status.AdditionalBastionSecurityGroups[i].ID != "" {
+			additionalBastionSecGroups[i] = &openStackCluster.Status.AdditionalBastionSecurityGroups[i]
+		}
+		statuses = append(statuses, &additionalBastionSecGroups[i])
+	}
+	defer func() {
+		remaining := openStackCluster.Status.AdditionalBastionSecurityGroups
+		for i, group := range additionalBastionSecGroups {
+			if group == nil {
+				remaining[i] = infrav1.SecurityGroupStatus{}
+			}
+		}
+		for len(remaining) > 0 && remaining[len(remaining)-1].ID == "" {
+			remaining = remaining[:len(remaining)-1]
+		}
+		if len(remaining) == 0 {
+			remaining = nil
+		}
+		openStackCluster.Status.AdditionalBastionSecurityGroups = remaining
+	}()
+
+	// A group may be reported in several fields, e.g. the shared node group, but it is only deleted once.
+	var inUse []string
+	deletedIDs := make(map[string]bool)
+	for _, status := range statuses {
+		group := *status
+		if group == nil {
+			continue
+		}
+		if deleted, ok := deletedIDs[group.ID]; ok {
+			if deleted {
+				*status = nil
+			}
+			continue
+		}
+
+		// Deleting a group still used by the ports of the machines would strand them, or fail.
+		groupPorts, err := s.client.ListPort(ports.ListOpts{SecurityGroups: []string{group.ID}})
+		if err != nil {
+			return err
+		}
+		if len(groupPorts) > 0 {
+			s.scope.Logger().Info("Security group is still used by ports, not deleting it", "name", group.Name, "id", group.ID, "ports", len(groupPorts))
+			record.Warnf(openStackCluster, "SecurityGroupInUse", "Security group %s with id %s is still used by %d ports, it will be deleted once they don't use it anymore", group.Name, group.ID, len(groupPorts))
+			inUse = append(inUse, group.Name)
+			deletedIDs[group.ID] = false
+			continue
+		}
+
+		if err := s.client.DeleteSecGroup(group.ID); err != nil && !capoerrors.IsNotFound(err) {
+			record.Warnf(openStackCluster, "FailedDeleteSecurityGroup", "Failed to delete security group %s with id %s: %v", group.Name, group.ID, err)
+			return err
+		}
+		record.Eventf(openStackCluster, "SuccessfulDeleteSecurityGroup", "Deleted security group %s with id %s", group.Name, group.ID)
+		deletedIDs[group.ID] = true
+		*status = nil
+	}
+
+	if len(inUse) > 0 {
+		return fmt.Errorf("%w: %v", ErrSecurityGroupInUse, inUse)
+	}
+	return nil
+}
+
+// detachSecurityGroupFromClusterPorts removes the group from the ports created by the cluster, i.e. with the
+// description given by the controller to the ports of the cluster, so that it can be deleted. It returns the IDs
+// of the other ports using the group, which are left untouched.
+func (s *Service) detachSecurityGroupFromClusterPorts(openStackCluster *infrav1.OpenStackCluster, clusterName string, group *infrav1.SecurityGroupStatus) ([]string, error) {
+	groupPorts, err := s.client.ListPort(ports.ListOpts{SecurityGroups: []string{group.ID}})
+	if err != nil {
+		return nil, err
+	}
+
+	var blockingPortIDs []string
+	for _, port := range groupPorts {
+		if port.Description != names.GetDescription(clusterName) {
+			blockingPortIDs = append(blockingPortIDs, port.ID)
+			continue
+		}
+
+		securityGroups := make([]string, 0, len(port.SecurityGroups))
+		for _, id := range port.SecurityGroups {
+			if id != group.ID {
+				securityGroups = append(securityGroups, id)
+			}
+		}
+		s.scope.Logger().Info("Detaching security group from port", "name", group.Name, "id", group.ID, "port", port.ID)
+		if _, err := s.client.UpdatePort(port.ID, ports.UpdateOpts{SecurityGroups: &securityGroups}); err != nil && !capoerrors.IsNotFound(err) {
+			record.Warnf(openStackCluster, "FailedDetachSecurityGroup", "Failed to detach security group %s with id %s from port %s: %v", group.Name, group.ID, port.ID, err)
+			return nil, err
+		}
+		record.Eventf(openStackCluster, "SuccessfulDetachSecurityGroup", "Detached security group %s with id %s from port %s", group.Name, group.ID, port.ID)
+	}
+	sort.Strings(blockingPortIDs)
+	return blockingPortIDs, nil
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/golang/mock/gomock"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	. "github.com/onsi/gomega"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/clients/mock"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
+)
+
+func TestReconcileSecurityGroupsRemoved(t *testing.T) {
+	controlPlaneGroup := &infrav1.SecurityGroupStatus{ID: "idControlPlane", Name: "k8s-cluster-mycluster-secgroup-controlplane"}
+	workerGroup := &infrav1.SecurityGroupStatus{ID: "idWorker", Name: "k8s-cluster-mycluster-secgroup-worker"}
+
+	tests := []struct {
+		name             string
+		policy           SecurityGroupRemovalPolicy
+		expect           func(m *mock.MockNetworkClientMockRecorder)
+		wantErrInUse     bool
+		wantControlPlane *infrav1.SecurityGroupStatus
+		wantWorker       *infrav1.SecurityGroupStatus
+	}{
+		{
+			name:             "Leave keeps the groups",
+			policy:           SecurityGroupRemovalLeave,
+			expect:           func(m *mock.MockNetworkClientMockRecorder) {},
+			wantControlPlane: controlPlaneGroup,
+			wantWorker:       workerGroup,
+		},
+		{
+			name:   "Delete deletes the unused groups",
+			policy: SecurityGroupRemovalDelete,
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListPort(ports.ListOpts{SecurityGroups: []string{"idWorker"}}).Return(nil, nil)
+				m.DeleteSecGroup("idWorker").Return(nil)
+				m.ListPort(ports.ListOpts{SecurityGroups: []string{"idControlPlane"}}).Return(nil, nil)
+				m.DeleteSecGroup("idControlPlane").Return(nil)
+			},
+		},
+		{
+			name:   "Delete waits for the groups to be detached",
+			policy: SecurityGroupRemovalDelete,
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListPort(ports.ListOpts{SecurityGroups: []string{"idWorker"}}).Return([]ports.Port{{ID: "idPort"}}, nil)
+				m.ListPort(ports.ListOpts{SecurityGroups: []string{"idControlPlane"}}).Return(nil, nil)
+				m.DeleteSecGroup("idControlPlane").Return(nil)
+			},
+			wantErrInUse: true,
+			wantWorker:   workerGroup,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+			s.secGroupRemovalPolicy = tt.policy
+
+			tt.expect(mockScopeFactory.NetworkClient.EXPECT())
+
+			openStackCluster := &infrav1.OpenStackCluster{
+				Status: infrav1.OpenStackClusterStatus{
+					ControlPlaneSecurityGroup: controlPlaneGroup,
+					WorkerSecurityGroup:       workerGroup,
+					ManagedSecurityGroups:     &infrav1.ManagedSecurityGroupsStatus{},
+				},
+			}
+			err = s.ReconcileSecurityGroups(context.TODO(), openStackCluster, "mycluster")
+			if tt.wantErrInUse {
+				g.Expect(err).To(MatchError(ErrSecurityGroupInUse))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			g.Expect(openStackCluster.Status.ControlPlaneSecurityGroup).To(Equal(tt.wantControlPlane))
+			g.Expect(openStackCluster.Status.WorkerSecurityGroup).To(Equal(tt.wantWorker))
+			g.Expect(openStackCluster.Status.ManagedSecurityGroups).To(BeNil())
+			// Waiting for the groups to be detached is not a failure.
+			g.Expect(openStackCluster.Status.SecurityGroupReconcileFailures).To(BeZero())
+		})
+	}
+}
+
+func TestReconcileSecurityGroupsRemovedSharedNodeGroup(t *testing.T) {
+	tests := []struct {
+		name         string
+		expect       func(m *mock.MockNetworkClientMockRecorder)
+		wantErrInUse bool
+		wantRetained bool
+	}{
+		{
+			name: "Shared group is deleted once",
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListPort(ports.ListOpts{SecurityGroups: []string{"idNode"}}).Return(nil, nil)
+				m.DeleteSecGroup("idNode").Return(nil)
+			},
+		},
+		{
+			name: "Shared group in use is reported once",
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListPort(ports.ListOpts{SecurityGroups: []string{"idNode"}}).Return([]ports.Port{{ID: "idPort"}}, nil)
+			},
+			wantErrInUse: true,
+			wantRetained: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+			s.secGroupRemovalPolicy = SecurityGroupRemovalDelete
+
+			tt.expect(mockScopeFactory.NetworkClient.EXPECT())
+
+			// The shared node group is reported as the control plane and worker groups too.
+			nodeGroup := infrav1.SecurityGroupStatus{ID: "idNode", Name: "k8s-cluster-mycluster-secgroup-node"}
+			openStackCluster := &infrav1.OpenStackCluster{
+				Status: infrav1.OpenStackClusterStatus{
+					ControlPlaneSecurityGroup: nodeGroup.DeepCopy(),
+					WorkerSecurityGroup:       nodeGroup.DeepCopy(),
+					NodeSecurityGroup:         nodeGroup.DeepCopy(),
+					ManagedSecurityGroups:     &infrav1.ManagedSecurityGroupsStatus{},
+				},
+			}
+			err = s.ReconcileSecurityGroups(context.TODO(), openStackCluster, "mycluster")
+			if tt.wantErrInUse {
+				g.Expect(err).To(MatchError(ErrSecurityGroupInUse))
+				g.Expect(err.Error()).To(HaveSuffix("[k8s-cluster-mycluster-secgroup-node]"))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			if tt.wantRetained {
+				g.Expect(openStackCluster.Status.ControlPlaneSecurityGroup).To(Equal(&nodeGroup))
+				g.Expect(openStackCluster.Status.WorkerSecurityGroup).To(Equal(&nodeGroup))
+				g.Expect(openStackCluster.Status.NodeSecurityGroup).To(Equal(&nodeGroup))
+			} else {
+				g.Expect(openStackCluster.Status.ControlPlaneSecurityGroup).To(BeNil())
+				g.Expect(openStackCluster.Status.WorkerSecurityGroup).To(BeNil())
+				g.Expect(openStackCluster.Status.NodeSecurityGroup).To(BeNil())
+			}
+		})
+	}
+}
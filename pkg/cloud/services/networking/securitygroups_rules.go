@@ -16,6 +16,12 @@ limitations under the License.
 
 package networking
 
+import (
+	"strings"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+)
+
 var defaultRules = []resolvedSecurityGroupRuleSpec{
 	{
 		Direction:      "egress",
@@ -37,208 +43,311 @@ var defaultRules = []resolvedSecurityGroupRuleSpec{
 	},
 }
 
+// wellKnownPort is a port range of a Kubernetes component, along with the protocols it is served on.
+type wellKnownPort struct {
+	portRangeMin int
+	portRangeMax int
+	protocols    []string
+}
+
+// rules returns a copy of the rule permitting the port range for each protocol of the port.
+func (p wellKnownPort) rules(rule resolvedSecurityGroupRuleSpec) []resolvedSecurityGroupRuleSpec {
+	rules := make([]resolvedSecurityGroupRuleSpec, 0, len(p.protocols))
+	for _, protocol := range p.protocols {
+		rule.PortRangeMin = p.portRangeMin
+		rule.PortRangeMax = p.portRangeMax
+		rule.Protocol = protocol
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// wellKnownPorts are the ports of the Kubernetes components permitted by the rules of the managed security groups.
+type wellKnownPorts map[infrav1.WellKnownPortName]wellKnownPort
+
+// defaultWellKnownPorts are the standard ports of the Kubernetes components.
+var defaultWellKnownPorts = wellKnownPorts{
+	infrav1.WellKnownPortEtcd:          {portRangeMin: 2379, portRangeMax: 2380, protocols: []string{"tcp"}},
+	infrav1.WellKnownPortKubelet:       {portRangeMin: 10250, portRangeMax: 10250, protocols: []string{"tcp"}},
+	infrav1.WellKnownPortKubeAPIServer: {portRangeMin: 6443, portRangeMax: 6443, protocols: []string{"tcp"}},
+	infrav1.WellKnownPortSSH:           {portRangeMin: 22, portRangeMax: 22, protocols: []string{"tcp"}},
+	infrav1.WellKnownPortNodePorts:     {portRangeMin: 30000, portRangeMax: 32767, protocols: []string{"tcp", "udp"}},
+}
+
+// getWellKnownPorts returns the standard ports of the Kubernetes components, overridden by the well-known ports
+// of the cluster.
+func getWellKnownPorts(openStackCluster *infrav1.OpenStackCluster) wellKnownPorts {
+	ports := make(wellKnownPorts, len(defaultWellKnownPorts))
+	for name, port := range defaultWellKnownPorts {
+		ports[name] = port
+	}
+	if openStackCluster.Spec.ManagedSecurityGroups == nil {
+		return ports
+	}
+
+	for _, override := range openStackCluster.Spec.ManagedSecurityGroups.WellKnownPorts {
+		port, ok := ports[override.Name]
+		if !ok {
+			continue
+		}
+		port.portRangeMin = override.PortRangeMin
+		port.portRangeMax = override.PortRangeMin
+		if override.PortRangeMax != nil {
+			port.portRangeMax = *override.PortRangeMax
+		}
+		ports[override.Name] = port
+	}
+	return ports
+}
+
+// getEtherTypes returns the ether types of the rules permitting in-cluster traffic.
+// Dual-stack clusters need an IPv6 variant of each rule, as a rule only matches the traffic of its ether type.
+func getEtherTypes(dualStack bool) []string {
+	if dualStack {
+		return []string{"IPv4", "IPv6"}
+	}
+	return []string{"IPv4"}
+}
+
 // Permit traffic for etcd, kubelet.
-func getSGControlPlaneCommon(remoteGroupIDSelf, secWorkerGroupID string) []resolvedSecurityGroupRuleSpec {
-	return []resolvedSecurityGroupRuleSpec{
-		{
+func getSGControlPlaneCommon(ports wellKnownPorts, remoteGroupIDSelf, secWorkerGroupID string, dualStack bool) []resolvedSecurityGroupRuleSpec {
+	var rules []resolvedSecurityGroupRuleSpec
+	for _, etherType := range getEtherTypes(dualStack) {
+		rules = append(rules, ports[infrav1.WellKnownPortEtcd].rules(resolvedSecurityGroupRuleSpec{
 			Description:   "Etcd",
 			Direction:     "ingress",
-			EtherType:     "IPv4",
-			PortRangeMin:  2379,
-			PortRangeMax:  2380,
-			Protocol:      "tcp",
+			EtherType:     etherType,
 			RemoteGroupID: remoteGroupIDSelf,
-		},
-		{
-			// kubeadm says this is needed
+		})...)
+		// kubeadm says this is needed
+		rules = append(rules, ports[infrav1.WellKnownPortKubelet].rules(resolvedSecurityGroupRuleSpec{
 			Description:   "Kubelet API",
 			Direction:     "ingress",
-			EtherType:     "IPv4",
-			PortRangeMin:  10250,
-			PortRangeMax:  10250,
-			Protocol:      "tcp",
+			EtherType:     etherType,
 			RemoteGroupID: remoteGroupIDSelf,
-		},
-		{
-			// This is needed to support metrics-server deployments
+		})...)
+		// This is needed to support metrics-server deployments
+		rules = append(rules, ports[infrav1.WellKnownPortKubelet].rules(resolvedSecurityGroupRuleSpec{
 			Description:   "Kubelet API",
 			Direction:     "ingress",
-			EtherType:     "IPv4",
-			PortRangeMin:  10250,
-			PortRangeMax:  10250,
-			Protocol:      "tcp",
+			EtherType:     etherType,
 			RemoteGroupID: secWorkerGroupID,
-		},
+		})...)
 	}
+	return rules
 }
 
 // Permit traffic for kubelet.
-func getSGWorkerCommon(remoteGroupIDSelf, secControlPlaneGroupID string) []resolvedSecurityGroupRuleSpec {
-	return []resolvedSecurityGroupRuleSpec{
-		{
-			// This is needed to support metrics-server deployments
+func getSGWorkerCommon(ports wellKnownPorts, remoteGroupIDSelf, secControlPlaneGroupID string, dualStack bool) []resolvedSecurityGroupRuleSpec {
+	var rules []resolvedSecurityGroupRuleSpec
+	for _, etherType := range getEtherTypes(dualStack) {
+		// This is needed to support metrics-server deployments
+		rules = append(rules, ports[infrav1.WellKnownPortKubelet].rules(resolvedSecurityGroupRuleSpec{
 			Description:   "Kubelet API",
 			Direction:     "ingress",
-			EtherType:     "IPv4",
-			PortRangeMin:  10250,
-			PortRangeMax:  10250,
-			Protocol:      "tcp",
+			EtherType:     etherType,
 			RemoteGroupID: remoteGroupIDSelf,
-		},
-		{
+		})...)
+		rules = append(rules, ports[infrav1.WellKnownPortKubelet].rules(resolvedSecurityGroupRuleSpec{
 			Description:   "Kubelet API",
 			Direction:     "ingress",
-			EtherType:     "IPv4",
-			PortRangeMin:  10250,
-			PortRangeMax:  10250,
-			Protocol:      "tcp",
+			EtherType:     etherType,
 			RemoteGroupID: secControlPlaneGroupID,
-		},
+		})...)
 	}
+	return rules
 }
 
 // Permit traffic for ssh control plane.
-func getSGControlPlaneSSH(secBastionGroupID string) []resolvedSecurityGroupRuleSpec {
-	return []resolvedSecurityGroupRuleSpec{
-		{
+func getSGControlPlaneSSH(ports wellKnownPorts, secBastionGroupID string, dualStack bool) []resolvedSecurityGroupRuleSpec {
+	var rules []resolvedSecurityGroupRuleSpec
+	for _, etherType := range getEtherTypes(dualStack) {
+		rules = append(rules, ports[infrav1.WellKnownPortSSH].rules(resolvedSecurityGroupRuleSpec{
 			Description:   "SSH",
 			Direction:     "ingress",
-			EtherType:     "IPv4",
-			PortRangeMin:  22,
-			PortRangeMax:  22,
-			Protocol:      "tcp",
+			EtherType:     etherType,
 			RemoteGroupID: secBastionGroupID,
-		},
+		})...)
 	}
+	return rules
 }
 
 // Permit traffic for ssh worker.
-func getSGWorkerSSH(secBastionGroupID string) []resolvedSecurityGroupRuleSpec {
-	return []resolvedSecurityGroupRuleSpec{
-		{
+func getSGWorkerSSH(ports wellKnownPorts, secBastionGroupID string, dualStack bool) []resolvedSecurityGroupRuleSpec {
+	var rules []resolvedSecurityGroupRuleSpec
+	for _, etherType := range getEtherTypes(dualStack) {
+		rules = append(rules, ports[infrav1.WellKnownPortSSH].rules(resolvedSecurityGroupRuleSpec{
 			Description:   "SSH",
 			Direction:     "ingress",
-			EtherType:     "IPv4",
-			PortRangeMin:  22,
-			PortRangeMax:  22,
-			Protocol:      "tcp",
+			EtherType:     etherType,
 			RemoteGroupID: secBastionGroupID,
-		},
+		})...)
+	}
+	return rules
+}
+
+// Permit ssh to the bastion from the allowed CIDRs, or from anywhere if there are none.
+func getSGBastionSSH(ports wellKnownPorts, dualStack bool, allowedCIDRs []string) []resolvedSecurityGroupRuleSpec {
+	var rules []resolvedSecurityGroupRuleSpec
+	if len(allowedCIDRs) > 0 {
+		for _, cidr := range allowedCIDRs {
+			etherType := "IPv4"
+			if strings.Contains(cidr, ":") {
+				etherType = "IPv6"
+			}
+			rules = append(rules, ports[infrav1.WellKnownPortSSH].rules(resolvedSecurityGroupRuleSpec{
+				Description:    "SSH",
+				Direction:      "ingress",
+				EtherType:      etherType,
+				RemoteIPPrefix: cidr,
+			})...)
+		}
+		return rules
+	}
+	for _, etherType := range getEtherTypes(dualStack) {
+		rules = append(rules, ports[infrav1.WellKnownPortSSH].rules(resolvedSecurityGroupRuleSpec{
+			Description: "SSH",
+			Direction:   "ingress",
+			EtherType:   etherType,
+		})...)
 	}
+	return rules
 }
 
 // Allow all traffic, including from outside the cluster, to access the API.
-func getSGControlPlaneHTTPS() []resolvedSecurityGroupRuleSpec {
-	return []resolvedSecurityGroupRuleSpec{
-		{
-			Description:  "Kubernetes API",
-			Direction:    "ingress",
-			EtherType:    "IPv4",
-			PortRangeMin: 6443,
-			PortRangeMax: 6443,
-			Protocol:     "tcp",
-		},
-	}
-}
-
-// Allow all traffic, including from outside the cluster, to access node port services.
-func getSGWorkerNodePort() []resolvedSecurityGroupRuleSpec {
-	return []resolvedSecurityGroupRuleSpec{
-		{
-			Description:  "Node Port Services",
-			Direction:    "ingress",
-			EtherType:    "IPv4",
-			PortRangeMin: 30000,
-			PortRangeMax: 32767,
-			Protocol:     "tcp",
-		},
-		{
-			Description:  "Node Port Services",
-			Direction:    "ingress",
-			EtherType:    "IPv4",
-			PortRangeMin: 30000,
-			PortRangeMax: 32767,
-			Protocol:     "udp",
-		},
+func getSGControlPlaneHTTPS(ports wellKnownPorts, dualStack bool) []resolvedSecurityGroupRuleSpec {
+	var rules []resolvedSecurityGroupRuleSpec
+	for _, etherType := range getEtherTypes(dualStack) {
+		rules = append(rules, ports[infrav1.WellKnownPortKubeAPIServer].rules(resolvedSecurityGroupRuleSpec{
+			Description: "Kubernetes API",
+			Direction:   "ingress",
+			EtherType:   etherType,
+		})...)
 	}
+	return rules
 }
 
-// Permit all ingress from the cluster security groups.
-func getSGControlPlaneAllowAll(remoteGroupIDSelf, secWorkerGroupID string) []resolvedSecurityGroupRuleSpec {
-	return []resolvedSecurityGroupRuleSpec{
-		{
-			Description:   "In-cluster Ingress",
-			Direction:     "ingress",
-			EtherType:     "IPv4",
-			PortRangeMin:  0,
-			PortRangeMax:  0,
-			Protocol:      "",
-			RemoteGroupID: remoteGroupIDSelf,
-		},
-		{
-			Description:   "In-cluster Ingress",
-			Direction:     "ingress",
-			EtherType:     "IPv4",
-			PortRangeMin:  0,
-			PortRangeMax:  0,
-			Protocol:      "",
-			RemoteGroupID: secWorkerGroupID,
-		},
+// Allow the traffic of the allowed CIDRs, or all traffic including from outside the cluster if there are none,
+// to access node port services.
+func getSGWorkerNodePort(ports wellKnownPorts, dualStack bool, allowedCIDRs []string) []resolvedSecurityGroupRuleSpec {
+	var rules []resolvedSecurityGroupRuleSpec
+	if len(allowedCIDRs) > 0 {
+		for _, cidr := range allowedCIDRs {
+			etherType := "IPv4"
+			if strings.Contains(cidr, ":") {
+				etherType = "IPv6"
+			}
+			rules = append(rules, ports[infrav1.WellKnownPortNodePorts].rules(resolvedSecurityGroupRuleSpec{
+				Description:    "Node Port Services",
+				Direction:      "ingress",
+				EtherType:      etherType,
+				RemoteIPPrefix: cidr,
+			})...)
+		}
+		return rules
 	}
+	for _, etherType := range getEtherTypes(dualStack) {
+		rules = append(rules, ports[infrav1.WellKnownPortNodePorts].rules(resolvedSecurityGroupRuleSpec{
+			Description: "Node Port Services",
+			Direction:   "ingress",
+			EtherType:   etherType,
+		})...)
+	}
+	return rules
 }
 
 // Permit all ingress from the cluster security groups.
-func getSGWorkerAllowAll(remoteGroupIDSelf, secControlPlaneGroupID string) []resolvedSecurityGroupRuleSpec {
-	return []resolvedSecurityGroupRuleSpec{
-		{
-			Description:   "In-cluster Ingress",
-			Direction:     "ingress",
-			EtherType:     "IPv4",
-			PortRangeMin:  0,
-			PortRangeMax:  0,
-			Protocol:      "",
-			RemoteGroupID: remoteGroupIDSelf,
-		},
-		{
-			Description:   "In-cluster Ingress",
-			Direction:     "ingress",
-			EtherType:     "IPv4",
-			PortRangeMin:  0,
-			PortRangeMax:  0,
-			Protocol:      "",
-			RemoteGroupID: secControlPlaneGroupID,
-		},
+// Rules referencing a remote group only match the traffic of their ether type, so dual-stack clusters need
+// a rule per family.
+func getSGControlPlaneAllowAll(remoteGroupIDSelf, secWorkerGroupID string, dualStack bool) []resolvedSecurityGroupRuleSpec {
+	return getSGAllowAll(dualStack, remoteGroupIDSelf, secWorkerGroupID)
+}
+
+// Permit all ingress from the cluster security groups.
+func getSGWorkerAllowAll(remoteGroupIDSelf, secControlPlaneGroupID string, dualStack bool) []resolvedSecurityGroupRuleSpec {
+	return getSGAllowAll(dualStack, remoteGroupIDSelf, secControlPlaneGroupID)
+}
+
+func getSGAllowAll(dualStack bool, remoteGroupIDs ...string) []resolvedSecurityGroupRuleSpec {
+	etherTypes := getEtherTypes(dualStack)
+	rules := make([]resolvedSecurityGroupRuleSpec, 0, len(etherTypes)*len(remoteGroupIDs))
+	for _, etherType := range etherTypes {
+		for _, remoteGroupID := range remoteGroupIDs {
+			rules = append(rules, resolvedSecurityGroupRuleSpec{
+				Description:   "In-cluster Ingress",
+				Direction:     "ingress",
+				EtherType:     etherType,
+				PortRangeMin:  0,
+				PortRangeMax:  0,
+				Protocol:      "",
+				RemoteGroupID: remoteGroupID,
+			})
+		}
 	}
+	return rules
 }
 
 // Permit ports that defined in openStackCluster.Spec.APIServerLoadBalancer.AdditionalPorts.
-func getSGControlPlaneAdditionalPorts(ports []int) []resolvedSecurityGroupRuleSpec {
+func getSGControlPlaneAdditionalPorts(ports []int, dualStack bool) []resolvedSecurityGroupRuleSpec {
 	controlPlaneRules := []resolvedSecurityGroupRuleSpec{}
-
-	r := []resolvedSecurityGroupRuleSpec{
-		{
-			Description: "Additional ports",
-			Direction:   "ingress",
-			EtherType:   "IPv4",
-			Protocol:    "tcp",
-		},
-	}
-	for i, p := range ports {
-		r[i].PortRangeMin = p
-		r[i].PortRangeMax = p
-		controlPlaneRules = append(controlPlaneRules, r...)
+	for _, etherType := range getEtherTypes(dualStack) {
+		for _, p := range ports {
+			controlPlaneRules = append(controlPlaneRules, resolvedSecurityGroupRuleSpec{
+				Description:  "Additional ports",
+				Direction:    "ingress",
+				EtherType:    etherType,
+				PortRangeMin: p,
+				PortRangeMax: p,
+				Protocol:     "tcp",
+			})
+		}
 	}
 	return controlPlaneRules
 }
 
-func getSGControlPlaneGeneral(remoteGroupIDSelf, secWorkerGroupID string) []resolvedSecurityGroupRuleSpec {
+func getSGControlPlaneGeneral(ports wellKnownPorts, remoteGroupIDSelf, secWorkerGroupID string, dualStack bool) []resolvedSecurityGroupRuleSpec {
 	controlPlaneRules := []resolvedSecurityGroupRuleSpec{}
-	controlPlaneRules = append(controlPlaneRules, getSGControlPlaneCommon(remoteGroupIDSelf, secWorkerGroupID)...)
+	controlPlaneRules = append(controlPlaneRules, getSGControlPlaneCommon(ports, remoteGroupIDSelf, secWorkerGroupID, dualStack)...)
 	return controlPlaneRules
 }
 
-func getSGWorkerGeneral(remoteGroupIDSelf, secControlPlaneGroupID string) []resolvedSecurityGroupRuleSpec {
+func getSGWorkerGeneral(ports wellKnownPorts, remoteGroupIDSelf, secControlPlaneGroupID string, dualStack bool) []resolvedSecurityGroupRuleSpec {
 	workerRules := []resolvedSecurityGroupRuleSpec{}
-	workerRules = append(workerRules, getSGWorkerCommon(remoteGroupIDSelf, secControlPlaneGroupID)...)
+	workerRules = append(workerRules, getSGWorkerCommon(ports, remoteGroupIDSelf, secControlPlaneGroupID, dualStack)...)
 	return workerRules
 }
+
+// Permit the return traffic of the given rules.
+// Stateless security groups don't track connections, so the replies to traffic permitted by a rule are only
+// allowed by a rule in the opposite direction. The reply comes from the port the original traffic was sent to,
+// so the return rule can't be restricted to a port range.
+func getSGReturnTraffic(rules []resolvedSecurityGroupRuleSpec) []resolvedSecurityGroupRuleSpec {
+	returnRules := make([]resolvedSecurityGroupRuleSpec, 0, len(rules))
+	seen := make(map[resolvedSecurityGroupRuleSpec]bool, len(rules))
+	for _, rule := range rules {
+		r := resolvedSecurityGroupRuleSpec{
+			Description:    "Return traffic",
+			EtherType:      rule.EtherType,
+			Protocol:       rule.Protocol,
+			RemoteGroupID:  rule.RemoteGroupID,
+			RemoteIPPrefix: rule.RemoteIPPrefix,
+		}
+		if rule.Description != "" {
+			r.Description = "Return traffic for " + rule.Description
+		}
+		switch rule.Direction {
+		case "ingress":
+			r.Direction = "egress"
+		case "egress":
+			r.Direction = "ingress"
+		default:
+			continue
+		}
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+		returnRules = append(returnRules, r)
+	}
+	return returnRules
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"sort"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+)
+
+// setManagedSecGroupRulesStatus reports the desired rules of the observed managed security groups in the status of
+// the cluster, when it has the SecurityGroupRulesStatusAnnotation. The rules are compared with the rules the groups
+// were observed with, so no request is made.
+func (s *Service) setManagedSecGroupRulesStatus(openStackCluster *infrav1.OpenStackCluster, desiredSecGroups map[string]securityGroupSpec, observedSecGroups map[string]*infrav1.SecurityGroupStatus) {
+	if openStackCluster.Annotations[infrav1.SecurityGroupRulesStatusAnnotation] != "true" {
+		openStackCluster.Status.ManagedSecurityGroupRules = nil
+		return
+	}
+
+	rulesStatus := make([]infrav1.ManagedSecurityGroupRulesStatus, 0, len(desiredSecGroups))
+	for k, desiredSecGroup := range desiredSecGroups {
+		observed := observedSecGroups[k]
+		if observed == nil || observed.ID == "" {
+			continue
+		}
+		diff := s.diffGroupRules(desiredSecGroup, *observed)
+		groupRulesStatus := infrav1.ManagedSecurityGroupRulesStatus{
+			Name:         desiredSecGroup.Name,
+			DesiredRules: len(diff.desiredRules),
+		}
+		for _, rule := range diff.rulesToCreate {
+			groupRulesStatus.RulesPendingCreation = append(groupRulesStatus.RulesPendingCreation, getPlannedRuleStatus(rule))
+		}
+		rulesStatus = append(rulesStatus, groupRulesStatus)
+	}
+	sort.Slice(rulesStatus, func(i, j int) bool {
+		return rulesStatus[i].Name < rulesStatus[j].Name
+	})
+	openStackCluster.Status.ManagedSecurityGroupRules = rulesStatus
+}
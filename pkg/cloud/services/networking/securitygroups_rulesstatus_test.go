@@ -0,0 +1,89 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
+)
+
+func TestSetManagedSecGroupRulesStatus(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// The status is computed from the observed groups: any call to the client fails the test.
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	kubeAPI := resolvedSecurityGroupRuleSpec{Description: "Kubernetes API", Direction: "ingress", EtherType: "IPv4", PortRangeMin: 6443, PortRangeMax: 6443, Protocol: "tcp"}
+	fromWorker := resolvedSecurityGroupRuleSpec{Description: "Kubelet API", Direction: "ingress", EtherType: "IPv4", PortRangeMin: 10250, PortRangeMax: 10250, Protocol: "tcp", RemoteGroupID: "idWorker"}
+	fromBastion := resolvedSecurityGroupRuleSpec{Description: "SSH", Direction: "ingress", EtherType: "IPv4", PortRangeMin: 22, PortRangeMax: 22, Protocol: "tcp", RemoteGroupID: pendingRemoteGroupID(bastionSuffix)}
+	desiredSecGroups := map[string]securityGroupSpec{
+		controlPlaneSuffix: {Name: "k8s-cluster-mycluster-secgroup-controlplane", Rules: []resolvedSecurityGroupRuleSpec{kubeAPI, fromWorker}},
+		workerSuffix:       {Name: "k8s-cluster-mycluster-secgroup-worker", Rules: []resolvedSecurityGroupRuleSpec{fromBastion}},
+		bastionSuffix:      {Name: "k8s-cluster-mycluster-secgroup-bastion"},
+	}
+	observedSecGroups := map[string]*infrav1.SecurityGroupStatus{
+		controlPlaneSuffix: {ID: "idControlPlane", Name: "k8s-cluster-mycluster-secgroup-controlplane"},
+		workerSuffix:       {ID: "idWorker", Name: "k8s-cluster-mycluster-secgroup-worker"},
+	}
+
+	openStackCluster := &infrav1.OpenStackCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{infrav1.SecurityGroupRulesStatusAnnotation: "true"},
+		},
+	}
+
+	// The groups have no rules yet: all their desired rules are pending creation.
+	s.setManagedSecGroupRulesStatus(openStackCluster, desiredSecGroups, observedSecGroups)
+	rulesStatus := openStackCluster.Status.ManagedSecurityGroupRules
+	g.Expect(rulesStatus).To(HaveLen(2))
+	g.Expect(rulesStatus[0].Name).To(Equal("k8s-cluster-mycluster-secgroup-controlplane"))
+	g.Expect(rulesStatus[0].DesiredRules).To(Equal(2))
+	g.Expect(rulesStatus[0].RulesPendingCreation).To(HaveLen(2))
+	g.Expect(rulesStatus[1].Name).To(Equal("k8s-cluster-mycluster-secgroup-worker"))
+	g.Expect(rulesStatus[1].DesiredRules).To(Equal(1))
+	g.Expect(rulesStatus[1].RulesPendingCreation).To(HaveLen(1))
+	g.Expect(rulesStatus[1].RulesPendingCreation[0].RemoteGroupID).To(Equal(pointer.String(pendingRemoteGroupID(bastionSuffix))))
+
+	// Once the rules of the control plane group exist, only the rule referencing the bastion group is pending.
+	for i, rule := range rulesStatus[0].RulesPendingCreation {
+		rule.ID = []string{"idRule0", "idRule1"}[i]
+		observedSecGroups[controlPlaneSuffix].Rules = append(observedSecGroups[controlPlaneSuffix].Rules, rule)
+	}
+	s.setManagedSecGroupRulesStatus(openStackCluster, desiredSecGroups, observedSecGroups)
+	rulesStatus = openStackCluster.Status.ManagedSecurityGroupRules
+	g.Expect(rulesStatus).To(HaveLen(2))
+	g.Expect(rulesStatus[0].DesiredRules).To(Equal(2))
+	g.Expect(rulesStatus[0].RulesPendingCreation).To(BeEmpty())
+	g.Expect(rulesStatus[1].RulesPendingCreation).To(HaveLen(1))
+
+	// Without the annotation, the status isn't reported.
+	delete(openStackCluster.Annotations, infrav1.SecurityGroupRulesStatusAnnotation)
+	s.setManagedSecGroupRulesStatus(openStackCluster, desiredSecGroups, observedSecGroups)
+	g.Expect(openStackCluster.Status.ManagedSecurityGroupRules).To(BeNil())
+}
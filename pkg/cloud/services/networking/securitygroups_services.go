@@ -0,0 +1,112 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+)
+
+// SetLoadBalancerServices sets the Services of type LoadBalancer of the workload cluster. When the cluster
+// allows the load balancer Service traffic, ReconcileSecurityGroups opens the node ports of those Services
+// in the worker security group.
+func (s *Service) SetLoadBalancerServices(services []corev1.Service) {
+	s.loadBalancerServices = services
+}
+
+// getSGWorkerLoadBalancerServices permits the members and the health monitors of the load balancers created
+// for the given Services to reach the node ports of the Services. Octavia connects to the members from the
+// subnets of the cluster, so the rules are scoped to those subnets.
+func getSGWorkerLoadBalancerServices(openStackCluster *infrav1.OpenStackCluster, services []corev1.Service) []resolvedSecurityGroupRuleSpec {
+	if openStackCluster.Status.Network == nil {
+		return nil
+	}
+
+	type subnet struct {
+		cidr      string
+		etherType string
+	}
+	var subnets []subnet
+	for _, s := range openStackCluster.Status.Network.Subnets {
+		ip, _, err := net.ParseCIDR(s.CIDR)
+		if err != nil {
+			continue
+		}
+		etherType := "IPv6"
+		if ip.To4() != nil {
+			etherType = "IPv4"
+		}
+		subnets = append(subnets, subnet{cidr: s.CIDR, etherType: etherType})
+	}
+
+	// Sort the Services so the rules, and therefore their hash, don't depend on the order of the list.
+	loadBalancerServices := make([]corev1.Service, 0, len(services))
+	for _, service := range services {
+		if service.Spec.Type == corev1.ServiceTypeLoadBalancer {
+			loadBalancerServices = append(loadBalancerServices, service)
+		}
+	}
+	sort.Slice(loadBalancerServices, func(i, j int) bool {
+		if loadBalancerServices[i].Namespace != loadBalancerServices[j].Namespace {
+			return loadBalancerServices[i].Namespace < loadBalancerServices[j].Namespace
+		}
+		return loadBalancerServices[i].Name < loadBalancerServices[j].Name
+	})
+
+	rules := []resolvedSecurityGroupRuleSpec{}
+	addRule := func(description, protocol string, port int32) {
+		for _, subnet := range subnets {
+			rules = append(rules, resolvedSecurityGroupRuleSpec{
+				Description:    description,
+				Direction:      "ingress",
+				EtherType:      subnet.etherType,
+				PortRangeMin:   int(port),
+				PortRangeMax:   int(port),
+				Protocol:       protocol,
+				RemoteIPPrefix: subnet.cidr,
+			})
+		}
+	}
+
+	for _, service := range loadBalancerServices {
+		for _, port := range service.Spec.Ports {
+			// Node ports are not allocated when spec.allocateLoadBalancerNodePorts is false.
+			if port.NodePort == 0 {
+				continue
+			}
+			protocol := strings.ToLower(string(port.Protocol))
+			if protocol == "" {
+				protocol = "tcp"
+			}
+			portName := port.Name
+			if portName == "" {
+				portName = fmt.Sprint(port.Port)
+			}
+			addRule(fmt.Sprintf("LoadBalancer Service %s/%s port %s", service.Namespace, service.Name, portName), protocol, port.NodePort)
+		}
+		if service.Spec.HealthCheckNodePort != 0 {
+			addRule(fmt.Sprintf("LoadBalancer Service %s/%s health check", service.Namespace, service.Name), "tcp", service.Spec.HealthCheckNodePort)
+		}
+	}
+	return rules
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+)
+
+func TestGetSGWorkerLoadBalancerServices(t *testing.T) {
+	services := []corev1.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+			Spec: corev1.ServiceSpec{
+				Type: corev1.ServiceTypeLoadBalancer,
+				Ports: []corev1.ServicePort{
+					{Name: "https", Port: 443, NodePort: 30443, Protocol: corev1.ProtocolTCP},
+					{Port: 53, NodePort: 30053, Protocol: corev1.ProtocolUDP},
+				},
+				ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyLocal,
+				HealthCheckNodePort:   31000,
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "internal"},
+			Spec: corev1.ServiceSpec{
+				Type:  corev1.ServiceTypeNodePort,
+				Ports: []corev1.ServicePort{{Port: 80, NodePort: 30080, Protocol: corev1.ProtocolTCP}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "no-node-ports"},
+			Spec: corev1.ServiceSpec{
+				Type:  corev1.ServiceTypeLoadBalancer,
+				Ports: []corev1.ServicePort{{Port: 80, Protocol: corev1.ProtocolTCP}},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: "api"},
+			Spec: corev1.ServiceSpec{
+				Type:  corev1.ServiceTypeLoadBalancer,
+				Ports: []corev1.ServicePort{{Port: 8080, NodePort: 30808}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		subnets   []infrav1.Subnet
+		wantRules []resolvedSecurityGroupRuleSpec
+	}{
+		{
+			name:      "No subnets",
+			wantRules: []resolvedSecurityGroupRuleSpec{},
+		},
+		{
+			name:    "Rules are scoped to the cluster subnet",
+			subnets: []infrav1.Subnet{{CIDR: "10.0.0.0/24"}},
+			wantRules: []resolvedSecurityGroupRuleSpec{
+				{Description: "LoadBalancer Service apps/api port 8080", Direction: "ingress", EtherType: "IPv4", PortRangeMin: 30808, PortRangeMax: 30808, Protocol: "tcp", RemoteIPPrefix: "10.0.0.0/24"},
+				{Description: "LoadBalancer Service default/web port https", Direction: "ingress", EtherType: "IPv4", PortRangeMin: 30443, PortRangeMax: 30443, Protocol: "tcp", RemoteIPPrefix: "10.0.0.0/24"},
+				{Description: "LoadBalancer Service default/web port 53", Direction: "ingress", EtherType: "IPv4", PortRangeMin: 30053, PortRangeMax: 30053, Protocol: "udp", RemoteIPPrefix: "10.0.0.0/24"},
+				{Description: "LoadBalancer Service default/web health check", Direction: "ingress", EtherType: "IPv4", PortRangeMin: 31000, PortRangeMax: 31000, Protocol: "tcp", RemoteIPPrefix: "10.0.0.0/24"},
+			},
+		},
+		{
+			name:    "Rules are created for each subnet of a dual-stack cluster",
+			subnets: []infrav1.Subnet{{CIDR: "10.0.0.0/24"}, {CIDR: "2001:db8::/64"}},
+			wantRules: []resolvedSecurityGroupRuleSpec{
+				{Description: "LoadBalancer Service apps/api port 8080", Direction: "ingress", EtherType: "IPv4", PortRangeMin: 30808, PortRangeMax: 30808, Protocol: "tcp", RemoteIPPrefix: "10.0.0.0/24"},
+				{Description: "LoadBalancer Service apps/api port 8080", Direction: "ingress", EtherType: "IPv6", PortRangeMin: 30808, PortRangeMax: 30808, Protocol: "tcp", RemoteIPPrefix: "2001:db8::/64"},
+				{Description: "LoadBalancer Service default/web port https", Direction: "ingress", EtherType: "IPv4", PortRangeMin: 30443, PortRangeMax: 30443, Protocol: "tcp", RemoteIPPrefix: "10.0.0.0/24"},
+				{Description: "LoadBalancer Service default/web port https", Direction: "ingress", EtherType: "IPv6", PortRangeMin: 30443, PortRangeMax: 30443, Protocol: "tcp", RemoteIPPrefix: "2001:db8::/64"},
+				{Description: "LoadBalancer Service default/web port 53", Direction: "ingress", EtherType: "IPv4", PortRangeMin: 30053, PortRangeMax: 30053, Protocol: "udp", RemoteIPPrefix: "10.0.0.0/24"},
+				{Description: "LoadBalancer Service default/web port 53", Direction: "ingress", EtherType: "IPv6", PortRangeMin: 30053, PortRangeMax: 30053, Protocol: "udp", RemoteIPPrefix: "2001:db8::/64"},
+				{Description: "LoadBalancer Service default/web health check", Direction: "ingress", EtherType: "IPv4", PortRangeMin: 31000, PortRangeMax: 31000, Protocol: "tcp", RemoteIPPrefix: "10.0.0.0/24"},
+				{Description: "LoadBalancer Service default/web health check", Direction: "ingress", EtherType: "IPv6", PortRangeMin: 31000, PortRangeMax: 31000, Protocol: "tcp", RemoteIPPrefix: "2001:db8::/64"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			openStackCluster := &infrav1.OpenStackCluster{
+				Status: infrav1.OpenStackClusterStatus{
+					Network: &infrav1.NetworkStatusWithSubnets{Subnets: tt.subnets},
+				},
+			}
+			g.Expect(getSGWorkerLoadBalancerServices(openStackCluster, services)).To(Equal(tt.wantRules))
+		})
+	}
+}
@@ -16,19 +16,43 @@ limitations under the License.
 package networking
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"text/template"
+	"time"
 
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
 	"github.com/go-logr/logr/testr"
 	"github.com/golang/mock/gomock"
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/attributestags"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/quotas"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/rules"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/gophercloud/utils/openstack/clientconfig"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testingclock "k8s.io/utils/clock/testing"
 	"k8s.io/utils/pointer"
+	clusterv1 "sigs.k8s.io/cluster-api/api/v1beta1"
+	"sigs.k8s.io/cluster-api/util/conditions"
 
 	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/audit"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/clients"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/clients/mock"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/utils/names"
 )
 
 func TestValidateRemoteManagedGroups(t *testing.T) {
@@ -97,6 +121,17 @@ func TestValidateRemoteManagedGroups(t *testing.T) {
 	}
 }
 
+func TestValidateRemoteManagedGroupsDisabledBastion(t *testing.T) {
+	g := NewWithT(t)
+	remoteManagedGroups := map[string]string{
+		"self":         "self",
+		"controlplane": "1",
+		"worker":       "2",
+	}
+	err := validateRemoteManagedGroups(remoteManagedGroups, []infrav1.ManagedSecurityGroupName{"controlplane", "bastion"})
+	g.Expect(err).To(MatchError("remoteManagedGroups: the bastion security group is referenced, but the bastion is disabled"))
+}
+
 func TestGetAllNodesRules(t *testing.T) {
 	tests := []struct {
 		name                       string
@@ -120,6 +155,8 @@ func TestGetAllNodesRules(t *testing.T) {
 			},
 			allNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
 				{
+					Description:  pointer.String("SSH"),
+					Direction:    "ingress",
 					Protocol:     pointer.String("tcp"),
 					PortRangeMin: pointer.Int(22),
 					PortRangeMax: pointer.Int(22),
@@ -131,12 +168,16 @@ func TestGetAllNodesRules(t *testing.T) {
 			},
 			wantRules: []resolvedSecurityGroupRuleSpec{
 				{
+					Description:   "SSH (controlplane)",
+					Direction:     "ingress",
 					Protocol:      "tcp",
 					PortRangeMin:  22,
 					PortRangeMax:  22,
 					RemoteGroupID: "1",
 				},
 				{
+					Description:   "SSH (worker)",
+					Direction:     "ingress",
 					Protocol:      "tcp",
 					PortRangeMin:  22,
 					PortRangeMax:  22,
@@ -153,6 +194,7 @@ func TestGetAllNodesRules(t *testing.T) {
 			},
 			allNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
 				{
+					Direction:           "ingress",
 					Protocol:            pointer.String("tcp"),
 					PortRangeMin:        pointer.Int(22),
 					PortRangeMax:        pointer.Int(22),
@@ -161,6 +203,8 @@ func TestGetAllNodesRules(t *testing.T) {
 			},
 			wantRules: []resolvedSecurityGroupRuleSpec{
 				{
+					Description:   "(controlplane)",
+					Direction:     "ingress",
 					Protocol:      "tcp",
 					PortRangeMin:  22,
 					PortRangeMax:  22,
@@ -168,6 +212,66 @@ func TestGetAllNodesRules(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "Rules referencing the bastion reference all its replicas",
+			remoteManagedGroups: map[string]string{
+				"bastion":   "3",
+				"bastion-1": "4",
+			},
+			allNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
+				{
+					Description:         pointer.String("Metrics"),
+					Direction:           "ingress",
+					Protocol:            pointer.String("tcp"),
+					PortRangeMin:        pointer.Int(9100),
+					PortRangeMax:        pointer.Int(9100),
+					RemoteManagedGroups: []infrav1.ManagedSecurityGroupName{"bastion"},
+				},
+			},
+			wantRules: []resolvedSecurityGroupRuleSpec{
+				{
+					Description:   "Metrics (bastion)",
+					Direction:     "ingress",
+					Protocol:      "tcp",
+					PortRangeMin:  9100,
+					PortRangeMax:  9100,
+					RemoteGroupID: "3",
+				},
+				{
+					Description:   "Metrics (bastion-1)",
+					Direction:     "ingress",
+					Protocol:      "tcp",
+					PortRangeMin:  9100,
+					PortRangeMax:  9100,
+					RemoteGroupID: "4",
+				},
+			},
+		},
+		{
+			name: "Valid remoteIPPrefix in a rule",
+			remoteManagedGroups: map[string]string{
+				"controlplane": "1",
+				"worker":       "2",
+			},
+			allNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
+				{
+					Direction:      "egress",
+					Protocol:       pointer.String("tcp"),
+					PortRangeMin:   pointer.Int(443),
+					PortRangeMax:   pointer.Int(443),
+					RemoteIPPrefix: pointer.String("10.0.0.0/8"),
+				},
+			},
+			wantRules: []resolvedSecurityGroupRuleSpec{
+				{
+					Direction:      "egress",
+					Protocol:       "tcp",
+					PortRangeMin:   443,
+					PortRangeMax:   443,
+					RemoteIPPrefix: "10.0.0.0/8",
+				},
+			},
+		},
 		{
 			name: "Invalid allNodesSecurityGroupRules with wrong remoteManagedGroups",
 			remoteManagedGroups: map[string]string{
@@ -176,6 +280,7 @@ func TestGetAllNodesRules(t *testing.T) {
 			},
 			allNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
 				{
+					Direction:    "ingress",
 					Protocol:     pointer.String("tcp"),
 					PortRangeMin: pointer.Int(22),
 					PortRangeMax: pointer.Int(22),
@@ -196,6 +301,7 @@ func TestGetAllNodesRules(t *testing.T) {
 			},
 			allNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
 				{
+					Direction:    "ingress",
 					Protocol:     pointer.String("tcp"),
 					PortRangeMin: pointer.Int(22),
 					PortRangeMax: pointer.Int(22),
@@ -211,7 +317,7 @@ func TestGetAllNodesRules(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotRules, err := getAllNodesRules(tt.remoteManagedGroups, tt.allNodesSecurityGroupRules)
+			gotRules, err := getAllNodesRules("allNodesSecurityGroupRules", tt.remoteManagedGroups, nil, tt.allNodesSecurityGroupRules)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("getAllNodesRules() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -223,311 +329,5030 @@ func TestGetAllNodesRules(t *testing.T) {
 	}
 }
 
-func TestGenerateDesiredSecGroups(t *testing.T) {
-	mockCtrl := gomock.NewController(t)
-	defer mockCtrl.Finish()
-
-	secGroupNames := map[string]string{
-		"controlplane": "k8s-cluster-mycluster-secgroup-controlplane",
-		"worker":       "k8s-cluster-mycluster-secgroup-worker",
+func TestGetAllNodesRulesRemoteSecurityGroupFilter(t *testing.T) {
+	monitoringRule := func(filter infrav1.SecurityGroupFilter) infrav1.SecurityGroupRuleSpec {
+		return infrav1.SecurityGroupRuleSpec{
+			Name:                      "node-exporter",
+			Description:               pointer.String("Node exporter"),
+			Direction:                 "ingress",
+			Protocol:                  pointer.String("tcp"),
+			PortRangeMin:              pointer.Int(9100),
+			PortRangeMax:              pointer.Int(9100),
+			RemoteSecurityGroupFilter: &filter,
+		}
+	}
+	monitoringResolvedRule := func(remoteGroupID string) resolvedSecurityGroupRuleSpec {
+		return resolvedSecurityGroupRuleSpec{
+			Description:   "Node exporter",
+			Direction:     "ingress",
+			Protocol:      "tcp",
+			PortRangeMin:  9100,
+			PortRangeMax:  9100,
+			RemoteGroupID: remoteGroupID,
+		}
 	}
 
 	tests := []struct {
-		name             string
-		openStackCluster *infrav1.OpenStackCluster
-		mockExpect       func(m *mock.MockNetworkClientMockRecorder)
-		// We could also test the exact rules that are returned, but that'll be a lot data to write out.
-		// For now we just make sure that the number of rules is correct.
-		expectedNumberSecurityGroupRules int
-		wantErr                          bool
+		name       string
+		filter     infrav1.SecurityGroupFilter
+		expect     func(m *mock.MockNetworkClientMockRecorder)
+		wantRules  []resolvedSecurityGroupRuleSpec
+		wantErr    bool
+		wantErrMsg string
 	}{
 		{
-			name:                             "Valid openStackCluster with unmanaged securityGroups",
-			openStackCluster:                 &infrav1.OpenStackCluster{},
-			mockExpect:                       func(m *mock.MockNetworkClientMockRecorder) {},
-			expectedNumberSecurityGroupRules: 0,
-			wantErr:                          false,
+			name:   "Filter by name",
+			filter: infrav1.SecurityGroupFilter{Name: "monitoring"},
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Name: "monitoring"}).Return([]groups.SecGroup{{ID: "idMonitoring"}}, nil)
+			},
+			wantRules: []resolvedSecurityGroupRuleSpec{monitoringResolvedRule("idMonitoring")},
 		},
 		{
-			name: "Valid openStackCluster with securityGroups",
-			openStackCluster: &infrav1.OpenStackCluster{
-				Spec: infrav1.OpenStackClusterSpec{
-					ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{},
-				},
-			},
-			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
-				m.ListSecGroup(groups.ListOpts{Name: "k8s-cluster-mycluster-secgroup-controlplane"}).Return([]groups.SecGroup{
-					{
-						ID:   "0",
-						Name: "k8s-cluster-mycluster-secgroup-controlplane",
-					},
-				}, nil)
-				m.ListSecGroup(groups.ListOpts{Name: "k8s-cluster-mycluster-secgroup-worker"}).Return([]groups.SecGroup{
-					{
-						ID:   "1",
-						Name: "k8s-cluster-mycluster-secgroup-worker",
-					},
-				}, nil)
+			name:   "Filter matching several groups",
+			filter: infrav1.SecurityGroupFilter{FilterByNeutronTags: infrav1.FilterByNeutronTags{Tags: []infrav1.NeutronTag{"monitoring"}}},
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Tags: "monitoring"}).Return([]groups.SecGroup{{ID: "idPrometheus"}, {ID: "idAlertmanager"}}, nil)
 			},
-			expectedNumberSecurityGroupRules: 12,
-			wantErr:                          false,
+			wantRules: []resolvedSecurityGroupRuleSpec{monitoringResolvedRule("idPrometheus"), monitoringResolvedRule("idAlertmanager")},
 		},
 		{
-			name: "Valid openStackCluster with securityGroups and allNodesSecurityGroupRules",
-			openStackCluster: &infrav1.OpenStackCluster{
-				Spec: infrav1.OpenStackClusterSpec{
-					ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{
-						AllNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
-							{
-								Protocol:            pointer.String("tcp"),
-								PortRangeMin:        pointer.Int(22),
-								PortRangeMax:        pointer.Int(22),
-								RemoteManagedGroups: []infrav1.ManagedSecurityGroupName{"controlplane", "worker"},
-							},
-						},
-					},
-				},
-			},
-			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
-				m.ListSecGroup(groups.ListOpts{Name: "k8s-cluster-mycluster-secgroup-controlplane"}).Return([]groups.SecGroup{
-					{
-						ID:   "0",
-						Name: "k8s-cluster-mycluster-secgroup-controlplane",
-					},
-				}, nil)
-				m.ListSecGroup(groups.ListOpts{Name: "k8s-cluster-mycluster-secgroup-worker"}).Return([]groups.SecGroup{
-					{
-						ID:   "1",
-						Name: "k8s-cluster-mycluster-secgroup-worker",
-					},
-				}, nil)
-			},
-			expectedNumberSecurityGroupRules: 16,
-			wantErr:                          false,
+			name:      "Filter by ID",
+			filter:    infrav1.SecurityGroupFilter{ID: "idMonitoring"},
+			expect:    func(m *mock.MockNetworkClientMockRecorder) {},
+			wantRules: []resolvedSecurityGroupRuleSpec{monitoringResolvedRule("idMonitoring")},
 		},
 		{
-			name: "Valid openStackCluster with securityGroups with invalid allNodesSecurityGroupRules",
-			openStackCluster: &infrav1.OpenStackCluster{
-				Spec: infrav1.OpenStackClusterSpec{
-					ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{
-						AllNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
-							{
-								Protocol:            pointer.String("tcp"),
-								PortRangeMin:        pointer.Int(22),
-								PortRangeMax:        pointer.Int(22),
-								RemoteManagedGroups: []infrav1.ManagedSecurityGroupName{"controlplane", "worker", "unknownGroup"},
-							},
-						},
-					},
-				},
-			},
-			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
-				m.ListSecGroup(groups.ListOpts{Name: "k8s-cluster-mycluster-secgroup-controlplane"}).Return([]groups.SecGroup{
-					{
-						ID:   "0",
-						Name: "k8s-cluster-mycluster-secgroup-controlplane",
-					},
-				}, nil)
-				m.ListSecGroup(groups.ListOpts{Name: "k8s-cluster-mycluster-secgroup-worker"}).Return([]groups.SecGroup{
-					{
-						ID:   "1",
-						Name: "k8s-cluster-mycluster-secgroup-worker",
-					},
-				}, nil)
+			name:   "Filter matching no group",
+			filter: infrav1.SecurityGroupFilter{Name: "monitoring"},
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Name: "monitoring"}).Return(nil, nil)
 			},
-			wantErr: true,
+			wantErr:    true,
+			wantErrMsg: "allNodesSecurityGroupRules[0] (node-exporter): remoteSecurityGroupFilter: security group monitoring not found",
 		},
 	}
-
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			g := NewWithT(t)
-			log := testr.New(t)
-			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
 
-			s, err := NewService(scope.NewWithLogger(mockScopeFactory, log))
-			if err != nil {
-				t.Fatalf("Failed to create service: %v", err)
-			}
-			tt.mockExpect(mockScopeFactory.NetworkClient.EXPECT())
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+			tt.expect(mockScopeFactory.NetworkClient.EXPECT())
 
-			gotSecurityGroups, err := s.generateDesiredSecGroups(tt.openStackCluster, secGroupNames)
+			gotRules, err := getAllNodesRules("allNodesSecurityGroupRules", nil, s.GetSecurityGroups, []infrav1.SecurityGroupRuleSpec{monitoringRule(tt.filter)})
 			if tt.wantErr {
-				g.Expect(err).To(HaveOccurred())
-			} else {
-				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(err).To(MatchError(tt.wantErrMsg))
+				var notFoundErr *SecurityGroupNotFoundError
+				g.Expect(errors.As(err, &notFoundErr)).To(BeTrue())
+				return
 			}
-			var gotNumberSecurityGroupRules int
-			for _, secGroup := range gotSecurityGroups {
-				gotNumberSecurityGroupRules += len(secGroup.Rules)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(gotRules).To(Equal(tt.wantRules))
+		})
+	}
+}
+
+func TestResolvedSecurityGroupRuleSpecMatchesICMPv6(t *testing.T) {
+	rule := resolvedSecurityGroupRuleSpec{Direction: "ingress", EtherType: "IPv6", Protocol: "icmpv6", PortRangeMin: 2}
+	for _, protocol := range []string{"icmp", "icmpv6", "ipv6-icmp"} {
+		t.Run(protocol, func(t *testing.T) {
+			g := NewWithT(t)
+			// Neutron may report the protocol of an ICMPv6 rule under another of its names.
+			g.Expect(rule.Matches(infrav1.SecurityGroupRuleStatus{
+				Direction:    "ingress",
+				EtherType:    pointer.String("IPv6"),
+				Protocol:     pointer.String(protocol),
+				PortRangeMin: pointer.Int(2),
+			})).To(BeTrue())
+		})
+	}
+
+	g := NewWithT(t)
+	ipv4Rule := resolvedSecurityGroupRuleSpec{Direction: "ingress", EtherType: "IPv4", Protocol: "icmp", PortRangeMin: 3}
+	g.Expect(ipv4Rule.Matches(infrav1.SecurityGroupRuleStatus{
+		Direction:    "ingress",
+		EtherType:    pointer.String("IPv4"),
+		Protocol:     pointer.String("ipv6-icmp"),
+		PortRangeMin: pointer.Int(3),
+	})).To(BeFalse())
+}
+
+func TestCanonicalProtocol(t *testing.T) {
+	tests := []struct {
+		protocol  string
+		etherType string
+		want      string
+	}{
+		{protocol: "", want: ""},
+		{protocol: "any", want: ""},
+		{protocol: "ANY", want: ""},
+		{protocol: "tcp", want: "tcp"},
+		{protocol: "TCP", want: "tcp"},
+		{protocol: "6", want: "tcp"},
+		{protocol: "17", want: "udp"},
+		{protocol: "Udp", want: "udp"},
+		{protocol: "132", want: "sctp"},
+		{protocol: "1", etherType: "IPv4", want: "icmp"},
+		{protocol: "ICMP", etherType: "IPv4", want: "icmp"},
+		{protocol: "icmp", etherType: "IPv6", want: "ipv6-icmp"},
+		{protocol: "icmp", etherType: "ipv6", want: "ipv6-icmp"},
+		{protocol: "58", etherType: "IPv6", want: "ipv6-icmp"},
+		{protocol: "ICMPv6", etherType: "IPv6", want: "ipv6-icmp"},
+		// Protocols without a known name are compared by number.
+		{protocol: "200", want: "200"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.protocol+"/"+tt.etherType, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(canonicalProtocol(tt.protocol, tt.etherType)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestResolvedSecurityGroupRuleSpecMatchesNormalized(t *testing.T) {
+	observed := infrav1.SecurityGroupRuleStatus{
+		Direction:    "ingress",
+		EtherType:    pointer.String("IPv4"),
+		Protocol:     pointer.String("tcp"),
+		PortRangeMin: pointer.Int(443),
+		PortRangeMax: pointer.Int(443),
+	}
+	tests := []struct {
+		name string
+		rule resolvedSecurityGroupRuleSpec
+		want bool
+	}{
+		{
+			name: "Uppercase protocol",
+			rule: resolvedSecurityGroupRuleSpec{Direction: "ingress", EtherType: "IPv4", Protocol: "TCP", PortRangeMin: 443, PortRangeMax: 443},
+			want: true,
+		},
+		{
+			name: "Protocol number",
+			rule: resolvedSecurityGroupRuleSpec{Direction: "ingress", EtherType: "IPv4", Protocol: "6", PortRangeMin: 443, PortRangeMax: 443},
+			want: true,
+		},
+		{
+			name: "Direction and ether type in another case",
+			rule: resolvedSecurityGroupRuleSpec{Direction: "Ingress", EtherType: "ipv4", Protocol: "tcp", PortRangeMin: 443, PortRangeMax: 443},
+			want: true,
+		},
+		{
+			name: "Other protocol number",
+			rule: resolvedSecurityGroupRuleSpec{Direction: "ingress", EtherType: "IPv4", Protocol: "17", PortRangeMin: 443, PortRangeMax: 443},
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(tt.rule.Matches(observed)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestResolvedSecurityGroupRuleSpecMatchesAnyProtocol(t *testing.T) {
+	for _, protocol := range []string{"", "any"} {
+		t.Run(protocol, func(t *testing.T) {
+			g := NewWithT(t)
+			rule := resolvedSecurityGroupRuleSpec{Description: "In-cluster", Direction: "ingress", EtherType: "IPv4", Protocol: protocol, RemoteGroupID: "idWorker"}
+			observed := infrav1.SecurityGroupRuleStatus{
+				Description:   pointer.String("In-cluster"),
+				Direction:     "ingress",
+				EtherType:     pointer.String("IPv4"),
+				RemoteGroupID: pointer.String("idWorker"),
 			}
-			g.Expect(gotNumberSecurityGroupRules).To(Equal(tt.expectedNumberSecurityGroupRules))
+			// Neutron reports the rules matching any protocol without protocol.
+			g.Expect(rule.Matches(observed)).To(BeTrue())
+			observed.Protocol = pointer.String("")
+			g.Expect(rule.Matches(observed)).To(BeTrue())
+			observed.Protocol = pointer.String("tcp")
+			g.Expect(rule.Matches(observed)).To(BeFalse())
 		})
 	}
 }
 
-func TestReconcileGroupRules(t *testing.T) {
+func TestGetRuleCreateOptsAnyProtocol(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	NewWithT(t).Expect(err).NotTo(HaveOccurred())
+
+	for _, protocol := range []string{"", "any", "Any"} {
+		t.Run(protocol, func(t *testing.T) {
+			g := NewWithT(t)
+			createOpts := s.getRuleCreateOpts("idControlPlane", resolvedSecurityGroupRuleSpec{Direction: "ingress", EtherType: "IPv4", Protocol: protocol, RemoteGroupID: "idWorker"})
+			g.Expect(createOpts.Protocol).To(Equal(rules.ProtocolAny))
+
+			// The protocol is omitted from the request.
+			body, err := createOpts.ToSecGroupRuleCreateMap()
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(body["security_group_rule"]).NotTo(HaveKey("protocol"))
+		})
+	}
+}
+
+func TestResolvedSecurityGroupRuleSpecMatchesEtherType(t *testing.T) {
+	g := NewWithT(t)
+	rule := resolvedSecurityGroupRuleSpec{Description: "Etcd", Direction: "ingress", EtherType: "IPv4", Protocol: "tcp", PortRangeMin: 2379, PortRangeMax: 2380, RemoteGroupID: "idControlPlane"}
+	observed := infrav1.SecurityGroupRuleStatus{
+		Description:   pointer.String("Etcd"),
+		Direction:     "ingress",
+		EtherType:     pointer.String("IPv4"),
+		Protocol:      pointer.String("tcp"),
+		PortRangeMin:  pointer.Int(2379),
+		PortRangeMax:  pointer.Int(2380),
+		RemoteGroupID: pointer.String("idControlPlane"),
+	}
+	g.Expect(rule.Matches(observed)).To(BeTrue())
+
+	// The IPv6 variant of a rule of a dual-stack cluster is a distinct rule.
+	observed.EtherType = pointer.String("IPv6")
+	g.Expect(rule.Matches(observed)).To(BeFalse())
+	rule.EtherType = "IPv6"
+	g.Expect(rule.Matches(observed)).To(BeTrue())
+}
+
+func TestValidateAllNodesRules(t *testing.T) {
 	tests := []struct {
-		name             string
-		desiredSGSpecs   securityGroupSpec
-		observedSGStatus infrav1.SecurityGroupStatus
-		mockExpect       func(m *mock.MockNetworkClientMockRecorder)
-		wantSGStatus     infrav1.SecurityGroupStatus
+		name                       string
+		allNodesSecurityGroupRules []infrav1.SecurityGroupRuleSpec
+		wantErr                    string
 	}{
 		{
-			name:             "Empty desiredSGSpecs and observedSGStatus",
-			desiredSGSpecs:   securityGroupSpec{},
-			observedSGStatus: infrav1.SecurityGroupStatus{},
-			mockExpect:       func(m *mock.MockNetworkClientMockRecorder) {},
-			wantSGStatus:     infrav1.SecurityGroupStatus{},
+			name: "Valid directions",
+			allNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
+				{Name: "in", Direction: "ingress"},
+				{Name: "out", Direction: "egress"},
+			},
 		},
 		{
-			name: "Same desiredSGSpecs and observedSGStatus produces no changes",
-			desiredSGSpecs: securityGroupSpec{
-				Name: "k8s-cluster-mycluster-secgroup-controlplane",
-				Rules: []resolvedSecurityGroupRuleSpec{
-					{
-						Description:    "Allow SSH",
-						Direction:      "ingress",
-						EtherType:      "IPv4",
-						Protocol:       "tcp",
-						PortRangeMin:   22,
-						PortRangeMax:   22,
-						RemoteGroupID:  "1",
-						RemoteIPPrefix: "",
-					},
-				},
-			},
-			observedSGStatus: infrav1.SecurityGroupStatus{
-				ID:   "idSG",
-				Name: "k8s-cluster-mycluster-secgroup-controlplane",
-				Rules: []infrav1.SecurityGroupRuleStatus{
-					{
-						Description:    pointer.String("Allow SSH"),
-						Direction:      "ingress",
-						EtherType:      pointer.String("IPv4"),
-						ID:             "idSGRule",
-						Protocol:       pointer.String("tcp"),
-						PortRangeMin:   pointer.Int(22),
-						PortRangeMax:   pointer.Int(22),
-						RemoteGroupID:  pointer.String("1"),
-						RemoteIPPrefix: pointer.String(""),
-					},
-				},
+			name: "Missing direction",
+			allNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
+				{Name: "in", Direction: "ingress"},
+				{Name: "ssh"},
 			},
-			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {},
-			wantSGStatus: infrav1.SecurityGroupStatus{
-				ID:   "idSG",
-				Name: "k8s-cluster-mycluster-secgroup-controlplane",
-				Rules: []infrav1.SecurityGroupRuleStatus{
-					{
-						Description:    pointer.String("Allow SSH"),
-						Direction:      "ingress",
-						EtherType:      pointer.String("IPv4"),
-						ID:             "idSGRule",
-						Protocol:       pointer.String("tcp"),
-						PortRangeMin:   pointer.Int(22),
-						PortRangeMax:   pointer.Int(22),
-						RemoteGroupID:  pointer.String("1"),
-						RemoteIPPrefix: pointer.String(""),
-					},
-				},
+			wantErr: "allNodesSecurityGroupRules[1] (ssh): direction is required",
+		},
+		{
+			name: "Invalid direction",
+			allNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
+				{Name: "ssh", Direction: "inbound"},
 			},
+			wantErr: `allNodesSecurityGroupRules[0] (ssh): direction "inbound" is not valid, must be "ingress" or "egress"`,
 		},
 		{
-			name: "Different desiredSGSpecs and observedSGStatus produces changes",
-			desiredSGSpecs: securityGroupSpec{
-				Name: "k8s-cluster-mycluster-secgroup-controlplane",
-				Rules: []resolvedSecurityGroupRuleSpec{
-					{
-						Description:    "Allow SSH",
-						Direction:      "ingress",
-						EtherType:      "IPv4",
-						Protocol:       "tcp",
-						PortRangeMin:   22,
-						PortRangeMax:   22,
-						RemoteGroupID:  "1",
-						RemoteIPPrefix: "",
-					},
-				},
+			name: "Valid ICMP types and codes",
+			allNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
+				{Name: "pmtud", Direction: "ingress", Protocol: pointer.String("icmp"), PortRangeMin: pointer.Int(3), PortRangeMax: pointer.Int(4)},
+				{Name: "pmtud6", Direction: "ingress", Protocol: pointer.String("ipv6-icmp"), PortRangeMin: pointer.Int(2)},
+				{Name: "any", Direction: "ingress", Protocol: pointer.String("icmp")},
 			},
-			observedSGStatus: infrav1.SecurityGroupStatus{
-				ID:   "idSG",
-				Name: "k8s-cluster-mycluster-secgroup-controlplane",
-				Rules: []infrav1.SecurityGroupRuleStatus{
+		},
+		{
+			name: "ICMP type above 255",
+			allNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
+				{Name: "icmp", Direction: "ingress", Protocol: pointer.String("icmpv6"), PortRangeMin: pointer.Int(256)},
+			},
+			wantErr: "allNodesSecurityGroupRules[0] (icmp): ICMP type 256 is not valid, must be between 0 and 255",
+		},
+		{
+			name: "ICMP code above 255",
+			allNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
+				{Name: "icmp", Direction: "ingress", Protocol: pointer.String("icmp"), PortRangeMin: pointer.Int(3), PortRangeMax: pointer.Int(1000)},
+			},
+			wantErr: "allNodesSecurityGroupRules[0] (icmp): ICMP code 1000 is not valid, must be between 0 and 255",
+		},
+		{
+			name: "ICMP code without type",
+			allNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
+				{Name: "icmp", Direction: "ingress", Protocol: pointer.String("icmp"), PortRangeMax: pointer.Int(4)},
+			},
+			wantErr: "allNodesSecurityGroupRules[0] (icmp): ICMP code requires an ICMP type",
+		},
+		{
+			name: "Both remoteGroupID and remoteIPPrefix",
+			allNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
+				{Name: "in", Direction: "ingress", RemoteIPPrefix: pointer.String("10.0.0.0/8")},
+				{Name: "https", Direction: "ingress", RemoteGroupID: pointer.String("idSG"), RemoteIPPrefix: pointer.String("10.0.0.0/8")},
+			},
+			wantErr: "allNodesSecurityGroupRules[1] (https): only one of remoteGroupID, remoteIPPrefix, remoteManagedGroups and remoteSecurityGroupFilter can be set",
+		},
+		{
+			name: "Both remoteManagedGroups and remoteIPPrefix",
+			allNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
+				{Name: "ssh", Direction: "ingress", RemoteManagedGroups: []infrav1.ManagedSecurityGroupName{"worker"}, RemoteIPPrefix: pointer.String("10.0.0.0/8")},
+			},
+			wantErr: "allNodesSecurityGroupRules[0] (ssh): only one of remoteGroupID, remoteIPPrefix, remoteManagedGroups and remoteSecurityGroupFilter can be set",
+		},
+		{
+			name: "Port range above 255 is valid for TCP",
+			allNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
+				{Name: "http", Direction: "ingress", Protocol: pointer.String("tcp"), PortRangeMin: pointer.Int(8080), PortRangeMax: pointer.Int(8080)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			err := validateAllNodesRules(tt.allNodesSecurityGroupRules)
+			if tt.wantErr != "" {
+				g.Expect(err).To(MatchError(tt.wantErr))
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestReconcileSecurityGroupsMissingDirection(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{
+				AllNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
 					{
-						Description:    pointer.String("Allow SSH legacy"),
-						Direction:      "ingress",
-						EtherType:      pointer.String("IPv4"),
-						ID:             "idSGRuleLegacy",
-						Protocol:       pointer.String("tcp"),
-						PortRangeMin:   pointer.Int(222),
-						PortRangeMax:   pointer.Int(222),
-						RemoteGroupID:  pointer.String("2"),
-						RemoteIPPrefix: pointer.String(""),
+						Name:                "ssh",
+						Protocol:            pointer.String("tcp"),
+						RemoteManagedGroups: []infrav1.ManagedSecurityGroupName{"controlplane"},
 					},
 				},
 			},
-			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
-				m.DeleteSecGroupRule("idSGRuleLegacy").Return(nil)
-				m.CreateSecGroupRule(rules.CreateOpts{
-					SecGroupID:    "idSG",
-					Description:   "Allow SSH",
-					Direction:     "ingress",
+		},
+	}
+
+	// No OpenStack API call is expected: the rule must be rejected first.
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+	// Don't exhaust the attempts, which would wrap the error.
+	s.secGroupMaxReconcileAttempts = 2
+
+	err = s.ReconcileSecurityGroups(context.TODO(), openStackCluster, "mycluster")
+	g.Expect(err).To(MatchError("allNodesSecurityGroupRules[0] (ssh): direction is required"))
+}
+
+func TestReconcileSecurityGroupsMaxAttempts(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// The rule is rejected before any OpenStack API call, so every attempt fails.
+	invalidRules := &infrav1.ManagedSecurityGroups{
+		AllNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
+			{
+				Name:                "ssh",
+				Protocol:            pointer.String("tcp"),
+				RemoteManagedGroups: []infrav1.ManagedSecurityGroupName{"controlplane"},
+			},
+		},
+	}
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: invalidRules,
+		},
+	}
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+	s.secGroupMaxReconcileAttempts = 3
+
+	for attempt := 1; attempt < 3; attempt++ {
+		err = s.ReconcileSecurityGroups(context.TODO(), openStackCluster, "mycluster")
+		g.Expect(err).To(HaveOccurred())
+		g.Expect(errors.Is(err, ErrSecurityGroupReconcileAttemptsExhausted)).To(BeFalse(), "attempt %d", attempt)
+		g.Expect(openStackCluster.Status.SecurityGroupReconcileFailures).To(Equal(attempt))
+	}
+
+	err = s.ReconcileSecurityGroups(context.TODO(), openStackCluster, "mycluster")
+	g.Expect(errors.Is(err, ErrSecurityGroupReconcileAttemptsExhausted)).To(BeTrue())
+	g.Expect(err).To(MatchError(ContainSubstring("after 3 attempts: allNodesSecurityGroupRules[0] (ssh): direction is required")))
+	g.Expect(openStackCluster.Status.SecurityGroupReconcileFailures).To(Equal(3))
+
+	// A successful reconcile resets the failures.
+	openStackCluster.Spec.ManagedSecurityGroups = nil
+	g.Expect(s.ReconcileSecurityGroups(context.TODO(), openStackCluster, "mycluster")).To(Succeed())
+	g.Expect(openStackCluster.Status.SecurityGroupReconcileFailures).To(BeZero())
+}
+
+func TestWithReturnTrafficRules(t *testing.T) {
+	userRules := []resolvedSecurityGroupRuleSpec{
+		{
+			Description:   "SSH",
+			Direction:     "ingress",
+			EtherType:     "IPv4",
+			PortRangeMin:  22,
+			PortRangeMax:  22,
+			Protocol:      "tcp",
+			RemoteGroupID: "1",
+		},
+		{
+			Description:    "DNS",
+			Direction:      "egress",
+			EtherType:      "IPv4",
+			PortRangeMin:   53,
+			PortRangeMax:   53,
+			Protocol:       "udp",
+			RemoteIPPrefix: "10.0.0.0/24",
+		},
+		{
+			Description:   "SSH alternative port",
+			Direction:     "ingress",
+			EtherType:     "IPv4",
+			PortRangeMin:  2222,
+			PortRangeMax:  2222,
+			Protocol:      "tcp",
+			RemoteGroupID: "1",
+		},
+	}
+
+	tests := []struct {
+		name      string
+		group     securityGroupSpec
+		wantRules []resolvedSecurityGroupRuleSpec
+	}{
+		{
+			name: "Stateful group is unchanged",
+			group: securityGroupSpec{
+				Name:  "stateful",
+				Rules: defaultRules,
+			},
+			wantRules: defaultRules,
+		},
+		{
+			name: "Stateless group permits return traffic",
+			group: securityGroupSpec{
+				Name:      "stateless",
+				Rules:     defaultRules,
+				Stateless: true,
+			},
+			wantRules: append(append([]resolvedSecurityGroupRuleSpec{}, defaultRules...),
+				resolvedSecurityGroupRuleSpec{
+					Description:   "Return traffic for SSH",
+					Direction:     "egress",
 					EtherType:     "IPv4",
 					Protocol:      "tcp",
-					PortRangeMin:  22,
-					PortRangeMax:  22,
 					RemoteGroupID: "1",
-				}).Return(&rules.SecGroupRule{
-					ID:            "idSGRule",
-					Description:   "Allow SSH",
-					Direction:     "ingress",
+					Origin:        infrav1.SecurityGroupRuleOriginGeneral,
+				},
+				resolvedSecurityGroupRuleSpec{
+					Description:    "Return traffic for DNS",
+					Direction:      "ingress",
+					EtherType:      "IPv4",
+					Protocol:       "udp",
+					RemoteIPPrefix: "10.0.0.0/24",
+					Origin:         infrav1.SecurityGroupRuleOriginGeneral,
+				},
+				resolvedSecurityGroupRuleSpec{
+					Description:   "Return traffic for SSH alternative port",
+					Direction:     "egress",
 					EtherType:     "IPv4",
 					Protocol:      "tcp",
-					PortRangeMin:  22,
-					PortRangeMax:  22,
 					RemoteGroupID: "1",
-				}, nil)
-			},
-			wantSGStatus: infrav1.SecurityGroupStatus{
-				ID:   "idSG",
-				Name: "k8s-cluster-mycluster-secgroup-controlplane",
-				Rules: []infrav1.SecurityGroupRuleStatus{
-					{
-						Description:    pointer.String("Allow SSH"),
-						Direction:      "ingress",
-						EtherType:      pointer.String("IPv4"),
-						ID:             "idSGRule",
-						Protocol:       pointer.String("tcp"),
-						PortRangeMin:   pointer.Int(22),
-						PortRangeMax:   pointer.Int(22),
-						RemoteGroupID:  pointer.String("1"),
-						RemoteIPPrefix: pointer.String(""),
-					},
+					Origin:        infrav1.SecurityGroupRuleOriginGeneral,
 				},
-			},
+			),
 		},
 	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			got := withReturnTrafficRules(tt.group, userRules)
+			g.Expect(got.Rules).To(Equal(tt.wantRules))
+			g.Expect(got.Name).To(Equal(tt.group.Name))
+		})
+	}
+}
 
+func TestGetSGWorkerNodePort(t *testing.T) {
+	tests := []struct {
+		name                 string
+		dualStack            bool
+		allowedCIDRs         []string
+		wantEtherTypes       []string
+		wantRemoteIPPrefixes []string
+	}{
+		{
+			name:                 "Single-stack",
+			wantEtherTypes:       []string{"IPv4", "IPv4"},
+			wantRemoteIPPrefixes: []string{"", ""},
+		},
+		{
+			name:                 "Dual-stack",
+			dualStack:            true,
+			wantEtherTypes:       []string{"IPv4", "IPv4", "IPv6", "IPv6"},
+			wantRemoteIPPrefixes: []string{"", "", "", ""},
+		},
+		{
+			name:                 "Allowed CIDRs",
+			allowedCIDRs:         []string{"10.0.0.0/8", "2001:db8::/32"},
+			wantEtherTypes:       []string{"IPv4", "IPv4", "IPv6", "IPv6"},
+			wantRemoteIPPrefixes: []string{"10.0.0.0/8", "10.0.0.0/8", "2001:db8::/32", "2001:db8::/32"},
+		},
+		{
+			// The ether types follow the allowed CIDRs, rather than the stack of the cluster.
+			name:                 "Allowed CIDRs in a dual-stack cluster",
+			dualStack:            true,
+			allowedCIDRs:         []string{"10.0.0.0/8"},
+			wantEtherTypes:       []string{"IPv4", "IPv4"},
+			wantRemoteIPPrefixes: []string{"10.0.0.0/8", "10.0.0.0/8"},
+		},
+	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			g := NewWithT(t)
-			log := testr.New(t)
-			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			nodePortRules := getSGWorkerNodePort(defaultWellKnownPorts, tt.dualStack, tt.allowedCIDRs)
 
-			s, err := NewService(scope.NewWithLogger(mockScopeFactory, log))
-			if err != nil {
-				t.Fatalf("Failed to create service: %v", err)
+			etherTypes := make([]string, len(nodePortRules))
+			remoteIPPrefixes := make([]string, len(nodePortRules))
+			for i, rule := range nodePortRules {
+				etherTypes[i] = rule.EtherType
+				remoteIPPrefixes[i] = rule.RemoteIPPrefix
+				g.Expect(rule.PortRangeMin).To(Equal(30000))
+				g.Expect(rule.PortRangeMax).To(Equal(32767))
 			}
-			tt.mockExpect(mockScopeFactory.NetworkClient.EXPECT())
+			g.Expect(etherTypes).To(Equal(tt.wantEtherTypes))
+			g.Expect(remoteIPPrefixes).To(Equal(tt.wantRemoteIPPrefixes))
+		})
+	}
+}
 
-			sgStatus, err := s.reconcileGroupRules(tt.desiredSGSpecs, tt.observedSGStatus)
-			g.Expect(err).To(BeNil())
-			g.Expect(sgStatus).To(Equal(tt.wantSGStatus))
+func TestGenerateDesiredSecGroupsDualStack(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	secGroupNames := map[string]string{
+		controlPlaneSuffix: "k8s-cluster-mycluster-secgroup-controlplane",
+		workerSuffix:       "k8s-cluster-mycluster-secgroup-worker",
+		bastionSuffix:      "k8s-cluster-mycluster-secgroup-bastion",
+	}
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	m.ListSecGroup(groups.ListOpts{Name: secGroupNames[controlPlaneSuffix]}).Return([]groups.SecGroup{{ID: "idControlPlane"}}, nil)
+	m.ListSecGroup(groups.ListOpts{Name: secGroupNames[workerSuffix]}).Return([]groups.SecGroup{{ID: "idWorker"}}, nil)
+	m.ListSecGroup(groups.ListOpts{Name: secGroupNames[bastionSuffix]}).Return([]groups.SecGroup{{ID: "idBastion"}}, nil)
+
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{},
+			APIServerLoadBalancer: infrav1.APIServerLoadBalancer{
+				Enabled:         true,
+				AdditionalPorts: []int{8443},
+			},
+			Bastion: &infrav1.Bastion{Enabled: true},
+		},
+		Status: infrav1.OpenStackClusterStatus{
+			Network: &infrav1.NetworkStatusWithSubnets{
+				Subnets: []infrav1.Subnet{
+					{CIDR: "10.0.0.0/24"},
+					{CIDR: "2001:db8::/64"},
+				},
+			},
+		},
+	}
+	desiredSecGroups, err := s.generateDesiredSecGroups(openStackCluster, secGroupNames)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(desiredSecGroups).To(HaveLen(3))
+
+	for k, group := range desiredSecGroups {
+		ipv4Rules := map[resolvedSecurityGroupRuleSpec]bool{}
+		ipv6Rules := map[resolvedSecurityGroupRuleSpec]bool{}
+		for _, rule := range group.Rules {
+			etherType := rule.EtherType
+			rule.EtherType = ""
+			switch etherType {
+			case "IPv4":
+				ipv4Rules[rule] = true
+			case "IPv6":
+				ipv6Rules[rule] = true
+			}
+		}
+		// Every rule of the managed groups has an IPv6 variant.
+		g.Expect(ipv6Rules).To(Equal(ipv4Rules), "rules of the %s group", k)
+	}
+}
+
+func TestGetSecGroupRuleCounts(t *testing.T) {
+	g := NewWithT(t)
+	openStackCluster := &infrav1.OpenStackCluster{
+		Status: infrav1.OpenStackClusterStatus{
+			ControlPlaneSecurityGroup: &infrav1.SecurityGroupStatus{ID: "idControlPlane", Rules: make([]infrav1.SecurityGroupRuleStatus, 12)},
+			WorkerSecurityGroup:       &infrav1.SecurityGroupStatus{ID: "idWorker", Rules: make([]infrav1.SecurityGroupRuleStatus, 9)},
+			// The bastion group is not created yet.
+			BastionSecurityGroup: &infrav1.SecurityGroupStatus{Name: "k8s-cluster-mycluster-secgroup-bastion"},
+		},
+	}
+	g.Expect(getSecGroupRuleCounts(openStackCluster)).To(Equal(map[string]int{
+		controlPlaneSuffix: 12,
+		workerSuffix:       9,
+	}))
+	g.Expect(getSecGroupRuleCounts(&infrav1.OpenStackCluster{})).To(BeEmpty())
+}
+
+func TestIsDualStack(t *testing.T) {
+	tests := []struct {
+		name    string
+		subnets []infrav1.Subnet
+		want    bool
+	}{
+		{
+			name:    "IPv4 only",
+			subnets: []infrav1.Subnet{{CIDR: "10.0.0.0/24"}},
+		},
+		{
+			name:    "IPv6 only",
+			subnets: []infrav1.Subnet{{CIDR: "2001:db8::/64"}},
+		},
+		{
+			name:    "IPv4 and IPv6",
+			subnets: []infrav1.Subnet{{CIDR: "10.0.0.0/24"}, {CIDR: "2001:db8::/64"}},
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			openStackCluster := &infrav1.OpenStackCluster{
+				Status: infrav1.OpenStackClusterStatus{
+					Network: &infrav1.NetworkStatusWithSubnets{Subnets: tt.subnets},
+				},
+			}
+			g.Expect(isDualStack(openStackCluster)).To(Equal(tt.want))
 		})
 	}
 }
+
+func TestGenerateDesiredSecGroups(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	secGroupNames := map[string]string{
+		"controlplane": "k8s-cluster-mycluster-secgroup-controlplane",
+		"worker":       "k8s-cluster-mycluster-secgroup-worker",
+	}
+
+	tests := []struct {
+		name             string
+		openStackCluster *infrav1.OpenStackCluster
+		mockExpect       func(m *mock.MockNetworkClientMockRecorder)
+		// We could also test the exact rules that are returned, but that'll be a lot data to write out.
+		// For now we just make sure that the number of rules is correct.
+		expectedNumberSecurityGroupRules int
+		wantErr                          bool
+	}{
+		{
+			name:                             "Valid openStackCluster with unmanaged securityGroups",
+			openStackCluster:                 &infrav1.OpenStackCluster{},
+			mockExpect:                       func(m *mock.MockNetworkClientMockRecorder) {},
+			expectedNumberSecurityGroupRules: 0,
+			wantErr:                          false,
+		},
+		{
+			name: "Valid openStackCluster with securityGroups",
+			openStackCluster: &infrav1.OpenStackCluster{
+				Spec: infrav1.OpenStackClusterSpec{
+					ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{},
+				},
+			},
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Name: "k8s-cluster-mycluster-secgroup-controlplane"}).Return([]groups.SecGroup{
+					{
+						ID:   "0",
+						Name: "k8s-cluster-mycluster-secgroup-controlplane",
+					},
+				}, nil)
+				m.ListSecGroup(groups.ListOpts{Name: "k8s-cluster-mycluster-secgroup-worker"}).Return([]groups.SecGroup{
+					{
+						ID:   "1",
+						Name: "k8s-cluster-mycluster-secgroup-worker",
+					},
+				}, nil)
+			},
+			expectedNumberSecurityGroupRules: 12,
+			wantErr:                          false,
+		},
+		{
+			name: "Valid openStackCluster with securityGroups on a dual-stack network",
+			openStackCluster: &infrav1.OpenStackCluster{
+				Spec: infrav1.OpenStackClusterSpec{
+					ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{},
+				},
+				Status: infrav1.OpenStackClusterStatus{
+					Network: &infrav1.NetworkStatusWithSubnets{
+						Subnets: []infrav1.Subnet{
+							{CIDR: "10.0.0.0/24"},
+							{CIDR: "2001:db8::/64"},
+						},
+					},
+				},
+			},
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Name: "k8s-cluster-mycluster-secgroup-controlplane"}).Return([]groups.SecGroup{
+					{
+						ID:   "0",
+						Name: "k8s-cluster-mycluster-secgroup-controlplane",
+					},
+				}, nil)
+				m.ListSecGroup(groups.ListOpts{Name: "k8s-cluster-mycluster-secgroup-worker"}).Return([]groups.SecGroup{
+					{
+						ID:   "1",
+						Name: "k8s-cluster-mycluster-secgroup-worker",
+					},
+				}, nil)
+			},
+			expectedNumberSecurityGroupRules: 20,
+			wantErr:                          false,
+		},
+		{
+			name: "Valid openStackCluster with securityGroups and allNodesSecurityGroupRules",
+			openStackCluster: &infrav1.OpenStackCluster{
+				Spec: infrav1.OpenStackClusterSpec{
+					ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{
+						AllNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
+							{
+								Direction:           "ingress",
+								Protocol:            pointer.String("tcp"),
+								PortRangeMin:        pointer.Int(22),
+								PortRangeMax:        pointer.Int(22),
+								RemoteManagedGroups: []infrav1.ManagedSecurityGroupName{"controlplane", "worker"},
+							},
+						},
+					},
+				},
+			},
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Name: "k8s-cluster-mycluster-secgroup-controlplane"}).Return([]groups.SecGroup{
+					{
+						ID:   "0",
+						Name: "k8s-cluster-mycluster-secgroup-controlplane",
+					},
+				}, nil)
+				m.ListSecGroup(groups.ListOpts{Name: "k8s-cluster-mycluster-secgroup-worker"}).Return([]groups.SecGroup{
+					{
+						ID:   "1",
+						Name: "k8s-cluster-mycluster-secgroup-worker",
+					},
+				}, nil)
+			},
+			expectedNumberSecurityGroupRules: 16,
+			wantErr:                          false,
+		},
+		{
+			name: "Valid openStackCluster with securityGroups with invalid allNodesSecurityGroupRules",
+			openStackCluster: &infrav1.OpenStackCluster{
+				Spec: infrav1.OpenStackClusterSpec{
+					ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{
+						AllNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
+							{
+								Direction:           "ingress",
+								Protocol:            pointer.String("tcp"),
+								PortRangeMin:        pointer.Int(22),
+								PortRangeMax:        pointer.Int(22),
+								RemoteManagedGroups: []infrav1.ManagedSecurityGroupName{"controlplane", "worker", "unknownGroup"},
+							},
+						},
+					},
+				},
+			},
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Name: "k8s-cluster-mycluster-secgroup-controlplane"}).Return([]groups.SecGroup{
+					{
+						ID:   "0",
+						Name: "k8s-cluster-mycluster-secgroup-controlplane",
+					},
+				}, nil)
+				m.ListSecGroup(groups.ListOpts{Name: "k8s-cluster-mycluster-secgroup-worker"}).Return([]groups.SecGroup{
+					{
+						ID:   "1",
+						Name: "k8s-cluster-mycluster-secgroup-worker",
+					},
+				}, nil)
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			log := testr.New(t)
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, log), DefaultOptions())
+			if err != nil {
+				t.Fatalf("Failed to create service: %v", err)
+			}
+			tt.mockExpect(mockScopeFactory.NetworkClient.EXPECT())
+
+			gotSecurityGroups, err := s.generateDesiredSecGroups(tt.openStackCluster, secGroupNames)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+			var gotNumberSecurityGroupRules int
+			for _, secGroup := range gotSecurityGroups {
+				gotNumberSecurityGroupRules += len(secGroup.Rules)
+			}
+			g.Expect(gotNumberSecurityGroupRules).To(Equal(tt.expectedNumberSecurityGroupRules))
+		})
+	}
+}
+
+func TestReconcileGroupRules(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	etcdSelfRuleStatus := infrav1.SecurityGroupRuleStatus{
+		Description:    pointer.String("Etcd"),
+		Direction:      "ingress",
+		EtherType:      pointer.String("IPv4"),
+		ID:             "idSGRuleEtcd",
+		Protocol:       pointer.String("tcp"),
+		PortRangeMin:   pointer.Int(2379),
+		PortRangeMax:   pointer.Int(2380),
+		RemoteGroupID:  pointer.String("idSG"),
+		RemoteIPPrefix: pointer.String(""),
+	}
+
+	tests := []struct {
+		name             string
+		desiredSGSpecs   securityGroupSpec
+		observedSGStatus infrav1.SecurityGroupStatus
+		mockExpect       func(m *mock.MockNetworkClientMockRecorder)
+		wantSGStatus     infrav1.SecurityGroupStatus
+	}{
+		{
+			name:             "Empty desiredSGSpecs and observedSGStatus",
+			desiredSGSpecs:   securityGroupSpec{},
+			observedSGStatus: infrav1.SecurityGroupStatus{},
+			mockExpect:       func(m *mock.MockNetworkClientMockRecorder) {},
+			wantSGStatus:     infrav1.SecurityGroupStatus{},
+		},
+		{
+			name: "Same desiredSGSpecs and observedSGStatus produces no changes",
+			desiredSGSpecs: securityGroupSpec{
+				Name: "k8s-cluster-mycluster-secgroup-controlplane",
+				Rules: []resolvedSecurityGroupRuleSpec{
+					{
+						Description:    "Allow SSH",
+						Direction:      "ingress",
+						EtherType:      "IPv4",
+						Protocol:       "tcp",
+						PortRangeMin:   22,
+						PortRangeMax:   22,
+						RemoteGroupID:  "1",
+						RemoteIPPrefix: "",
+					},
+				},
+			},
+			observedSGStatus: infrav1.SecurityGroupStatus{
+				ID:   "idSG",
+				Name: "k8s-cluster-mycluster-secgroup-controlplane",
+				Rules: []infrav1.SecurityGroupRuleStatus{
+					{
+						Description:    pointer.String("Allow SSH"),
+						Direction:      "ingress",
+						EtherType:      pointer.String("IPv4"),
+						ID:             "idSGRule",
+						Protocol:       pointer.String("tcp"),
+						PortRangeMin:   pointer.Int(22),
+						PortRangeMax:   pointer.Int(22),
+						RemoteGroupID:  pointer.String("1"),
+						RemoteIPPrefix: pointer.String(""),
+					},
+				},
+			},
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {},
+			wantSGStatus: infrav1.SecurityGroupStatus{
+				ID:   "idSG",
+				Name: "k8s-cluster-mycluster-secgroup-controlplane",
+				Rules: []infrav1.SecurityGroupRuleStatus{
+					{
+						Description:    pointer.String("Allow SSH"),
+						Direction:      "ingress",
+						EtherType:      pointer.String("IPv4"),
+						ID:             "idSGRule",
+						Protocol:       pointer.String("tcp"),
+						PortRangeMin:   pointer.Int(22),
+						PortRangeMax:   pointer.Int(22),
+						RemoteGroupID:  pointer.String("1"),
+						RemoteIPPrefix: pointer.String(""),
+					},
+				},
+			},
+		},
+		{
+			name: "Different desiredSGSpecs and observedSGStatus produces changes",
+			desiredSGSpecs: securityGroupSpec{
+				Name: "k8s-cluster-mycluster-secgroup-controlplane",
+				Rules: []resolvedSecurityGroupRuleSpec{
+					{
+						Description:    "Allow SSH",
+						Direction:      "ingress",
+						EtherType:      "IPv4",
+						Protocol:       "tcp",
+						PortRangeMin:   22,
+						PortRangeMax:   22,
+						RemoteGroupID:  "1",
+						RemoteIPPrefix: "",
+					},
+				},
+			},
+			observedSGStatus: infrav1.SecurityGroupStatus{
+				ID:   "idSG",
+				Name: "k8s-cluster-mycluster-secgroup-controlplane",
+				Rules: []infrav1.SecurityGroupRuleStatus{
+					{
+						Description:    pointer.String("Allow SSH legacy"),
+						Direction:      "ingress",
+						EtherType:      pointer.String("IPv4"),
+						ID:             "idSGRuleLegacy",
+						Protocol:       pointer.String("tcp"),
+						PortRangeMin:   pointer.Int(222),
+						PortRangeMax:   pointer.Int(222),
+						RemoteGroupID:  pointer.String("2"),
+						RemoteIPPrefix: pointer.String(""),
+					},
+				},
+			},
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.DeleteSecGroupRule("idSGRuleLegacy").Return(nil)
+				m.CreateSecGroupRule(rules.CreateOpts{
+					SecGroupID:    "idSG",
+					Description:   "Allow SSH",
+					Direction:     "ingress",
+					EtherType:     "IPv4",
+					Protocol:      "tcp",
+					PortRangeMin:  22,
+					PortRangeMax:  22,
+					RemoteGroupID: "1",
+				}).Return(&rules.SecGroupRule{
+					ID:            "idSGRule",
+					Description:   "Allow SSH",
+					Direction:     "ingress",
+					EtherType:     "IPv4",
+					Protocol:      "tcp",
+					PortRangeMin:  22,
+					PortRangeMax:  22,
+					RemoteGroupID: "1",
+				}, nil)
+			},
+			wantSGStatus: infrav1.SecurityGroupStatus{
+				ID:   "idSG",
+				Name: "k8s-cluster-mycluster-secgroup-controlplane",
+				Rules: []infrav1.SecurityGroupRuleStatus{
+					{
+						Description:    pointer.String("Allow SSH"),
+						Direction:      "ingress",
+						EtherType:      pointer.String("IPv4"),
+						ID:             "idSGRule",
+						Protocol:       pointer.String("tcp"),
+						PortRangeMin:   pointer.Int(22),
+						PortRangeMax:   pointer.Int(22),
+						RemoteGroupID:  pointer.String("1"),
+						RemoteIPPrefix: pointer.String(""),
+					},
+				},
+			},
+		},
+		{
+			name: "Self keyword in RemoteGroupID matches the observed group ID",
+			desiredSGSpecs: securityGroupSpec{
+				Name: "k8s-cluster-mycluster-secgroup-controlplane",
+				Rules: []resolvedSecurityGroupRuleSpec{
+					{
+						Description:   "Etcd",
+						Direction:     "ingress",
+						EtherType:     "IPv4",
+						Protocol:      "tcp",
+						PortRangeMin:  2379,
+						PortRangeMax:  2380,
+						RemoteGroupID: remoteGroupIDSelf,
+					},
+				},
+			},
+			observedSGStatus: infrav1.SecurityGroupStatus{
+				ID:    "idSG",
+				Name:  "k8s-cluster-mycluster-secgroup-controlplane",
+				Rules: []infrav1.SecurityGroupRuleStatus{etcdSelfRuleStatus},
+			},
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {},
+			wantSGStatus: infrav1.SecurityGroupStatus{
+				ID:    "idSG",
+				Name:  "k8s-cluster-mycluster-secgroup-controlplane",
+				Rules: []infrav1.SecurityGroupRuleStatus{etcdSelfRuleStatus},
+			},
+		},
+		{
+			name: "Literal group ID in RemoteGroupID matches the observed group ID",
+			desiredSGSpecs: securityGroupSpec{
+				Name: "k8s-cluster-mycluster-secgroup-controlplane",
+				Rules: []resolvedSecurityGroupRuleSpec{
+					{
+						Description:   "Etcd",
+						Direction:     "ingress",
+						EtherType:     "IPv4",
+						Protocol:      "tcp",
+						PortRangeMin:  2379,
+						PortRangeMax:  2380,
+						RemoteGroupID: "idSG",
+					},
+				},
+			},
+			observedSGStatus: infrav1.SecurityGroupStatus{
+				ID:    "idSG",
+				Name:  "k8s-cluster-mycluster-secgroup-controlplane",
+				Rules: []infrav1.SecurityGroupRuleStatus{etcdSelfRuleStatus},
+			},
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {},
+			wantSGStatus: infrav1.SecurityGroupStatus{
+				ID:    "idSG",
+				Name:  "k8s-cluster-mycluster-secgroup-controlplane",
+				Rules: []infrav1.SecurityGroupRuleStatus{etcdSelfRuleStatus},
+			},
+		},
+		{
+			name: "Self keyword and literal group ID are the same rule",
+			desiredSGSpecs: securityGroupSpec{
+				Name: "k8s-cluster-mycluster-secgroup-controlplane",
+				Rules: []resolvedSecurityGroupRuleSpec{
+					{
+						Description:   "Etcd",
+						Direction:     "ingress",
+						EtherType:     "IPv4",
+						Protocol:      "tcp",
+						PortRangeMin:  2379,
+						PortRangeMax:  2380,
+						RemoteGroupID: remoteGroupIDSelf,
+					},
+					{
+						Description:   "Etcd",
+						Direction:     "ingress",
+						EtherType:     "IPv4",
+						Protocol:      "tcp",
+						PortRangeMin:  2379,
+						PortRangeMax:  2380,
+						RemoteGroupID: "idSG",
+					},
+				},
+			},
+			observedSGStatus: infrav1.SecurityGroupStatus{
+				ID:    "idSG",
+				Name:  "k8s-cluster-mycluster-secgroup-controlplane",
+				Rules: []infrav1.SecurityGroupRuleStatus{etcdSelfRuleStatus},
+			},
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {},
+			wantSGStatus: infrav1.SecurityGroupStatus{
+				ID:    "idSG",
+				Name:  "k8s-cluster-mycluster-secgroup-controlplane",
+				Rules: []infrav1.SecurityGroupRuleStatus{etcdSelfRuleStatus},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			log := testr.New(t)
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, log), DefaultOptions())
+			if err != nil {
+				t.Fatalf("Failed to create service: %v", err)
+			}
+			tt.mockExpect(mockScopeFactory.NetworkClient.EXPECT())
+
+			sgStatus, err := s.reconcileGroupRules(context.TODO(), tt.desiredSGSpecs, tt.observedSGStatus)
+			g.Expect(err).To(BeNil())
+			g.Expect(sgStatus).To(Equal(tt.wantSGStatus))
+		})
+	}
+}
+
+func TestGetSecurityGroupRules(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// More rules than a backend would embed in the group.
+	const numRules = 300
+	osRules := make([]rules.SecGroupRule, numRules)
+	for i := range osRules {
+		osRules[i] = rules.SecGroupRule{
+			ID:           fmt.Sprintf("rule%d", i),
+			Direction:    "ingress",
+			EtherType:    "IPv4",
+			Protocol:     "tcp",
+			PortRangeMin: 1000 + i,
+			PortRangeMax: 1000 + i,
+			SecGroupID:   "idSG",
+		}
+	}
+	mockScopeFactory.NetworkClient.EXPECT().ListSecGroupRule(rules.ListOpts{SecGroupID: "idSG"}).Return(osRules, nil)
+
+	secGroupRules, err := s.getSecurityGroupRules("idSG")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(secGroupRules).To(HaveLen(numRules))
+	g.Expect(secGroupRules[numRules-1].ID).To(Equal("rule299"))
+	g.Expect(*secGroupRules[numRules-1].PortRangeMin).To(Equal(1299))
+}
+
+type fakeAuditSink struct {
+	records []audit.Record
+	err     error
+}
+
+func (f *fakeAuditSink) Send(_ context.Context, record audit.Record) error {
+	f.records = append(f.records, record)
+	return f.err
+}
+
+func TestReconcileGroupRulesAudit(t *testing.T) {
+	auditTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	desired := securityGroupSpec{
+		Name: "k8s-cluster-mycluster-secgroup-controlplane",
+		Rules: []resolvedSecurityGroupRuleSpec{
+			{
+				Description:   "Allow SSH",
+				Direction:     "ingress",
+				EtherType:     "IPv4",
+				Protocol:      "tcp",
+				PortRangeMin:  22,
+				PortRangeMax:  22,
+				RemoteGroupID: "1",
+			},
+		},
+	}
+	observed := infrav1.SecurityGroupStatus{
+		ID:   "idSG",
+		Name: "k8s-cluster-mycluster-secgroup-controlplane",
+		Rules: []infrav1.SecurityGroupRuleStatus{
+			{
+				Description:    pointer.String("Allow SSH legacy"),
+				Direction:      "ingress",
+				EtherType:      pointer.String("IPv4"),
+				ID:             "idSGRuleLegacy",
+				Protocol:       pointer.String("tcp"),
+				PortRangeMin:   pointer.Int(222),
+				PortRangeMax:   pointer.Int(222),
+				RemoteGroupID:  pointer.String("2"),
+				RemoteIPPrefix: pointer.String(""),
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		sinkErr error
+	}{
+		{
+			name: "Rule changes are sent to the audit sink",
+		},
+		{
+			name:    "Audit failures don't fail the reconcile",
+			sinkErr: fmt.Errorf("audit sink unavailable"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+			sink := &fakeAuditSink{err: tt.sinkErr}
+			s.auditSink = sink
+			s.clock = testingclock.NewFakePassiveClock(auditTime)
+
+			m := mockScopeFactory.NetworkClient.EXPECT()
+			m.DeleteSecGroupRule("idSGRuleLegacy").Return(nil)
+			m.CreateSecGroupRule(gomock.Any()).Return(&rules.SecGroupRule{
+				ID:            "idSGRule",
+				Description:   "Allow SSH",
+				Direction:     "ingress",
+				EtherType:     "IPv4",
+				Protocol:      "tcp",
+				PortRangeMin:  22,
+				PortRangeMax:  22,
+				RemoteGroupID: "1",
+			}, nil)
+
+			_, err = s.reconcileGroupRules(context.TODO(), desired, observed)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			g.Expect(sink.records).To(HaveLen(2))
+			g.Expect(sink.records[0].Time).To(Equal(auditTime))
+			g.Expect(sink.records[0].Action).To(Equal(audit.ActionCreate))
+			g.Expect(sink.records[0].ID).To(Equal("idSGRule"))
+			g.Expect(sink.records[0].Parent).To(Equal("idSG"))
+			g.Expect(sink.records[1].Action).To(Equal(audit.ActionDelete))
+			g.Expect(sink.records[1].ResourceType).To(Equal("security-group-rule"))
+			g.Expect(sink.records[1].ID).To(Equal("idSGRuleLegacy"))
+			g.Expect(sink.records[1].Parent).To(Equal("idSG"))
+			g.Expect(sink.records[1].Resource).To(Equal(observed.Rules[0]))
+		})
+	}
+}
+
+func TestReconcileSecurityGroupsBastionOnlyChange(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	secGroupNames := map[string]string{
+		controlPlaneSuffix: "k8s-cluster-mycluster-secgroup-controlplane",
+		workerSuffix:       "k8s-cluster-mycluster-secgroup-worker",
+		bastionSuffix:      "k8s-cluster-mycluster-secgroup-bastion",
+	}
+	secGroupIDs := map[string]string{
+		controlPlaneSuffix: "idControlPlane",
+		workerSuffix:       "idWorker",
+		bastionSuffix:      "idBastion",
+	}
+
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	for k, name := range secGroupNames {
+		m.ListSecGroup(groups.ListOpts{Name: name}).Return([]groups.SecGroup{{ID: secGroupIDs[k], Name: name, Description: "Cluster API managed group"}}, nil).AnyTimes()
+	}
+
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{},
+			Bastion:               &infrav1.Bastion{Enabled: true},
+		},
+	}
+
+	// The control plane and worker groups were reconciled with the current spec, the bastion group wasn't.
+	desiredSecGroups, err := s.generateDesiredSecGroups(openStackCluster, secGroupNames)
+	g.Expect(err).NotTo(HaveOccurred())
+	_, rulesHashes, err := getChangedSecGroups(desiredSecGroups, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	controlPlaneSecurityGroup := &infrav1.SecurityGroupStatus{ID: "idControlPlane", Name: secGroupNames[controlPlaneSuffix], RulesHash: rulesHashes[controlPlaneSuffix]}
+	workerSecurityGroup := &infrav1.SecurityGroupStatus{ID: "idWorker", Name: secGroupNames[workerSuffix], RulesHash: rulesHashes[workerSuffix]}
+	openStackCluster.Status.ControlPlaneSecurityGroup = controlPlaneSecurityGroup
+	openStackCluster.Status.WorkerSecurityGroup = workerSecurityGroup
+	openStackCluster.Status.BastionSecurityGroup = &infrav1.SecurityGroupStatus{ID: "idBastion", Name: secGroupNames[bastionSuffix], RulesHash: "stale"}
+
+	// Only the rules of the bastion group are listed and created.
+	m.ListSecGroupRule(rules.ListOpts{SecGroupID: "idBastion"}).Return([]rules.SecGroupRule{}, nil)
+	m.CreateSecGroupRules(gomock.Any()).DoAndReturn(func(opts []rules.CreateOpts) ([]rules.SecGroupRule, error) {
+		g.Expect(opts).To(HaveLen(len(desiredSecGroups[bastionSuffix].Rules)))
+		created := make([]rules.SecGroupRule, len(opts))
+		for i, createOpts := range opts {
+			g.Expect(createOpts.SecGroupID).To(Equal("idBastion"))
+			created[i] = rules.SecGroupRule{
+				ID:         fmt.Sprintf("idRule%d", i),
+				SecGroupID: createOpts.SecGroupID,
+				Direction:  string(createOpts.Direction),
+				EtherType:  string(createOpts.EtherType),
+			}
+		}
+		return created, nil
+	})
+
+	err = s.ReconcileSecurityGroups(context.TODO(), openStackCluster, "mycluster")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	g.Expect(openStackCluster.Status.ControlPlaneSecurityGroup).To(Equal(controlPlaneSecurityGroup))
+	g.Expect(openStackCluster.Status.WorkerSecurityGroup).To(Equal(workerSecurityGroup))
+	g.Expect(openStackCluster.Status.BastionSecurityGroup.RulesHash).To(Equal(rulesHashes[bastionSuffix]))
+	g.Expect(openStackCluster.Status.BastionSecurityGroup.Rules).To(HaveLen(len(desiredSecGroups[bastionSuffix].Rules)))
+}
+
+func TestReconcileSecurityGroupsLastReconciledTime(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+	s.secGroupMaxReconcileAttempts = 2
+	previousTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fakeClock := testingclock.NewFakePassiveClock(previousTime.Add(time.Hour))
+	s.clock = fakeClock
+
+	secGroupNames := map[string]string{
+		controlPlaneSuffix: "k8s-cluster-mycluster-secgroup-controlplane",
+		workerSuffix:       "k8s-cluster-mycluster-secgroup-worker",
+		bastionSuffix:      "k8s-cluster-mycluster-secgroup-bastion",
+	}
+	secGroupIDs := map[string]string{
+		controlPlaneSuffix: "idControlPlane",
+		workerSuffix:       "idWorker",
+		bastionSuffix:      "idBastion",
+	}
+
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	for k, name := range secGroupNames {
+		m.ListSecGroup(groups.ListOpts{Name: name}).Return([]groups.SecGroup{{ID: secGroupIDs[k], Name: name, Description: "Cluster API managed group"}}, nil).AnyTimes()
+	}
+
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{},
+			Bastion:               &infrav1.Bastion{Enabled: true},
+		},
+		Status: infrav1.OpenStackClusterStatus{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroupsStatus{
+				LastReconciledTime: &metav1.Time{Time: previousTime},
+			},
+		},
+	}
+
+	// Only the bastion group needs to be reconciled.
+	desiredSecGroups, err := s.generateDesiredSecGroups(openStackCluster, secGroupNames)
+	g.Expect(err).NotTo(HaveOccurred())
+	_, rulesHashes, err := getChangedSecGroups(desiredSecGroups, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	openStackCluster.Status.ControlPlaneSecurityGroup = &infrav1.SecurityGroupStatus{ID: "idControlPlane", Name: secGroupNames[controlPlaneSuffix], RulesHash: rulesHashes[controlPlaneSuffix]}
+	openStackCluster.Status.WorkerSecurityGroup = &infrav1.SecurityGroupStatus{ID: "idWorker", Name: secGroupNames[workerSuffix], RulesHash: rulesHashes[workerSuffix]}
+	openStackCluster.Status.BastionSecurityGroup = &infrav1.SecurityGroupStatus{ID: "idBastion", Name: secGroupNames[bastionSuffix], RulesHash: "stale"}
+
+	// A failed reconcile doesn't update the timestamp.
+	m.ListSecGroupRule(rules.ListOpts{SecGroupID: "idBastion"}).Return(nil, gophercloud.ErrDefault500{})
+	g.Expect(s.ReconcileSecurityGroups(context.TODO(), openStackCluster, "mycluster")).NotTo(Succeed())
+	g.Expect(openStackCluster.Status.ManagedSecurityGroups.LastReconciledTime.Time).To(Equal(previousTime))
+
+	// A successful one does.
+	m.ListSecGroupRule(rules.ListOpts{SecGroupID: "idBastion"}).Return([]rules.SecGroupRule{}, nil)
+	m.CreateSecGroupRules(gomock.Any()).DoAndReturn(func(opts []rules.CreateOpts) ([]rules.SecGroupRule, error) {
+		created := make([]rules.SecGroupRule, len(opts))
+		for i, createOpts := range opts {
+			created[i] = rules.SecGroupRule{
+				ID:         fmt.Sprintf("idRule%d", i),
+				SecGroupID: createOpts.SecGroupID,
+				Direction:  string(createOpts.Direction),
+				EtherType:  string(createOpts.EtherType),
+			}
+		}
+		return created, nil
+	})
+	g.Expect(s.ReconcileSecurityGroups(context.TODO(), openStackCluster, "mycluster")).To(Succeed())
+	g.Expect(openStackCluster.Status.ManagedSecurityGroups.LastReconciledTime.Time).To(Equal(fakeClock.Now()))
+}
+
+func TestReconcileSecurityGroupsShadowGroup(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	secGroupNames := map[string]string{
+		controlPlaneSuffix: "k8s-cluster-mycluster-secgroup-controlplane",
+		workerSuffix:       "k8s-cluster-mycluster-secgroup-worker",
+		shadowSuffix:       "k8s-cluster-mycluster-secgroup-shadow",
+	}
+	secGroupIDs := map[string]string{
+		controlPlaneSuffix: "idControlPlane",
+		workerSuffix:       "idWorker",
+		shadowSuffix:       "idShadow",
+	}
+
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	for k, name := range secGroupNames {
+		m.ListSecGroup(groups.ListOpts{Name: name}).Return([]groups.SecGroup{{ID: secGroupIDs[k], Name: name, Description: "Cluster API managed group"}}, nil).AnyTimes()
+	}
+
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{
+				ShadowRules: []infrav1.SecurityGroupRuleSpec{
+					{
+						Name:                "https-from-workers",
+						Description:         pointer.String("HTTPS from workers"),
+						Direction:           "ingress",
+						EtherType:           pointer.String("IPv4"),
+						Protocol:            pointer.String("tcp"),
+						PortRangeMin:        pointer.Int(443),
+						PortRangeMax:        pointer.Int(443),
+						RemoteManagedGroups: []infrav1.ManagedSecurityGroupName{"worker"},
+					},
+				},
+			},
+		},
+	}
+
+	// The shadow rules are only desired in the shadow group.
+	desiredSecGroups, err := s.generateDesiredSecGroups(openStackCluster, secGroupNames)
+	g.Expect(err).NotTo(HaveOccurred())
+	shadowRule := resolvedSecurityGroupRuleSpec{
+		Description:   "HTTPS from workers (worker)",
+		Direction:     "ingress",
+		EtherType:     "IPv4",
+		PortRangeMin:  443,
+		PortRangeMax:  443,
+		Protocol:      "tcp",
+		RemoteGroupID: "idWorker",
+		Origin:        infrav1.SecurityGroupRuleOriginUser,
+	}
+	g.Expect(desiredSecGroups[shadowSuffix].Rules).To(Equal(append(withRuleOrigin(defaultRules, infrav1.SecurityGroupRuleOriginDefault), shadowRule)))
+	g.Expect(desiredSecGroups[controlPlaneSuffix].Rules).NotTo(ContainElement(shadowRule))
+	g.Expect(desiredSecGroups[workerSuffix].Rules).NotTo(ContainElement(shadowRule))
+
+	_, rulesHashes, err := getChangedSecGroups(desiredSecGroups, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	controlPlaneSecGroup := &infrav1.SecurityGroupStatus{ID: "idControlPlane", Name: secGroupNames[controlPlaneSuffix], RulesHash: rulesHashes[controlPlaneSuffix]}
+	workerSecGroup := &infrav1.SecurityGroupStatus{ID: "idWorker", Name: secGroupNames[workerSuffix], RulesHash: rulesHashes[workerSuffix]}
+	openStackCluster.Status.ControlPlaneSecurityGroup = controlPlaneSecGroup
+	openStackCluster.Status.WorkerSecurityGroup = workerSecGroup
+
+	// Only the shadow group is reconciled: the live groups are left alone.
+	m.ListSecGroupRule(rules.ListOpts{SecGroupID: "idShadow"}).Return([]rules.SecGroupRule{}, nil)
+	m.CreateSecGroupRules(gomock.Any()).DoAndReturn(func(opts []rules.CreateOpts) ([]rules.SecGroupRule, error) {
+		g.Expect(opts).To(HaveLen(len(desiredSecGroups[shadowSuffix].Rules)))
+		created := make([]rules.SecGroupRule, len(opts))
+		for i, createOpts := range opts {
+			g.Expect(createOpts.SecGroupID).To(Equal("idShadow"))
+			created[i] = rules.SecGroupRule{
+				ID:         fmt.Sprintf("idRule%d", i),
+				SecGroupID: createOpts.SecGroupID,
+				Direction:  string(createOpts.Direction),
+				EtherType:  string(createOpts.EtherType),
+			}
+		}
+		return created, nil
+	})
+	g.Expect(s.ReconcileSecurityGroups(context.TODO(), openStackCluster, "mycluster")).To(Succeed())
+	g.Expect(openStackCluster.Status.ShadowSecurityGroup).NotTo(BeNil())
+	g.Expect(openStackCluster.Status.ShadowSecurityGroup.ID).To(Equal("idShadow"))
+	g.Expect(openStackCluster.Status.ShadowSecurityGroup.RulesHash).To(Equal(rulesHashes[shadowSuffix]))
+	g.Expect(openStackCluster.Status.ControlPlaneSecurityGroup).To(BeIdenticalTo(controlPlaneSecGroup))
+	g.Expect(openStackCluster.Status.WorkerSecurityGroup).To(BeIdenticalTo(workerSecGroup))
+
+	// Emptying the shadow rules deletes the shadow group. No live group changed, so they are all checked for drift.
+	openStackCluster.Spec.ManagedSecurityGroups.ShadowRules = nil
+	m.DeleteSecGroup("idShadow").Return(nil)
+	for _, k := range []string{controlPlaneSuffix, workerSuffix} {
+		m.ListSecGroupRule(rules.ListOpts{SecGroupID: secGroupIDs[k]}).Return([]rules.SecGroupRule{}, nil)
+	}
+	m.CreateSecGroupRules(gomock.Any()).DoAndReturn(func(opts []rules.CreateOpts) ([]rules.SecGroupRule, error) {
+		created := make([]rules.SecGroupRule, len(opts))
+		for i, createOpts := range opts {
+			g.Expect(createOpts.SecGroupID).NotTo(Equal("idShadow"))
+			created[i] = rules.SecGroupRule{ID: fmt.Sprintf("idRule%d", i), SecGroupID: createOpts.SecGroupID}
+		}
+		return created, nil
+	}).AnyTimes()
+	g.Expect(s.ReconcileSecurityGroups(context.TODO(), openStackCluster, "mycluster")).To(Succeed())
+	g.Expect(openStackCluster.Status.ShadowSecurityGroup).To(BeNil())
+}
+
+func TestCreateSecurityGroupIfNotExistsDescription(t *testing.T) {
+	openStackCluster := &infrav1.OpenStackCluster{}
+	openStackCluster.Namespace = "default"
+	openStackCluster.Name = "mycluster"
+
+	const groupName = "k8s-cluster-default-mycluster-secgroup-controlplane"
+
+	tests := []struct {
+		name             string
+		template         string
+		controlReference string
+		format           DescriptionFormat
+		mockExpect       func(m *mock.MockNetworkClientMockRecorder, description string)
+		wantDescription  string
+	}{
+		{
+			name:            "Default description is applied at create",
+			template:        defaultSecGroupDescriptionTemplate,
+			wantDescription: "Cluster API managed group",
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder, description string) {
+				m.ListSecGroup(groups.ListOpts{Name: groupName}).Return([]groups.SecGroup{}, nil)
+				m.CreateSecGroup(groups.CreateOpts{Name: groupName, Description: description}).Return(&groups.SecGroup{ID: "idSG", Name: groupName}, nil)
+			},
+		},
+		{
+			name:             "Templated description is applied at create",
+			template:         "{{ .Role }} group of {{ .Namespace }}/{{ .Name }} ({{ .ControlReference }})",
+			controlReference: "CIS-5.2",
+			wantDescription:  "controlplane group of default/mycluster (CIS-5.2)",
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder, description string) {
+				m.ListSecGroup(groups.ListOpts{Name: groupName}).Return([]groups.SecGroup{}, nil)
+				m.CreateSecGroup(groups.CreateOpts{Name: groupName, Description: description}).Return(&groups.SecGroup{ID: "idSG", Name: groupName}, nil)
+			},
+		},
+		{
+			name:             "Long description is truncated",
+			template:         "{{ .Role }} group of {{ .Namespace }}/{{ .Name }} ({{ .ControlReference }})",
+			controlReference: strings.Repeat("x", 300),
+			wantDescription:  "controlplane group of default/mycluster (" + strings.Repeat("x", 255-len("controlplane group of default/mycluster (")),
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder, description string) {
+				m.ListSecGroup(groups.ListOpts{Name: groupName}).Return([]groups.SecGroup{}, nil)
+				m.CreateSecGroup(groups.CreateOpts{Name: groupName, Description: description}).Return(&groups.SecGroup{ID: "idSG", Name: groupName}, nil)
+			},
+		},
+		{
+			name:             "Drifted description is updated",
+			template:         "{{ .Cluster }} {{ .Role }} ({{ .ControlReference }})",
+			controlReference: "CIS-5.2",
+			wantDescription:  "default-mycluster controlplane (CIS-5.2)",
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder, description string) {
+				m.ListSecGroup(groups.ListOpts{Name: groupName}).Return([]groups.SecGroup{{ID: "idSG", Name: groupName, Description: "default-mycluster controlplane (CIS-5.1)"}}, nil)
+				m.UpdateSecGroup("idSG", groups.UpdateOpts{Description: &description}).Return(&groups.SecGroup{ID: "idSG", Name: groupName, Description: description}, nil)
+			},
+		},
+		{
+			name:             "Up to date description is not updated",
+			template:         "{{ .Cluster }} {{ .Role }} ({{ .ControlReference }})",
+			controlReference: "CIS-5.2",
+			wantDescription:  "default-mycluster controlplane (CIS-5.2)",
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder, description string) {
+				m.ListSecGroup(groups.ListOpts{Name: groupName}).Return([]groups.SecGroup{{ID: "idSG", Name: groupName, Description: description}}, nil)
+			},
+		},
+		{
+			name:             "Structured description is applied at create",
+			template:         defaultSecGroupDescriptionTemplate,
+			controlReference: "CIS 5.2",
+			format:           DescriptionFormatStructured,
+			wantDescription:  `cluster=default-mycluster namespace=default name=mycluster role=controlplane control="CIS 5.2"`,
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder, description string) {
+				m.ListSecGroup(groups.ListOpts{Name: groupName}).Return([]groups.SecGroup{}, nil)
+				m.CreateSecGroup(groups.CreateOpts{Name: groupName, Description: description}).Return(&groups.SecGroup{ID: "idSG", Name: groupName}, nil)
+			},
+		},
+		{
+			name:            "Up to date structured description is not updated",
+			template:        defaultSecGroupDescriptionTemplate,
+			format:          DescriptionFormatStructured,
+			wantDescription: "cluster=default-mycluster namespace=default name=mycluster role=controlplane",
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder, description string) {
+				m.ListSecGroup(groups.ListOpts{Name: groupName}).Return([]groups.SecGroup{{ID: "idSG", Name: groupName, Description: description}}, nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+			s.secGroupDescription = securityGroupDescription{
+				template:         template.Must(template.New("description").Parse(tt.template)),
+				controlReference: tt.controlReference,
+				format:           tt.format,
+			}
+
+			description, err := s.secGroupDescription.render(openStackCluster, "default-mycluster", controlPlaneSuffix)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(description).To(Equal(tt.wantDescription))
+
+			tt.mockExpect(mockScopeFactory.NetworkClient.EXPECT(), description)
+			g.Expect(s.createSecurityGroupIfNotExists(context.TODO(), openStackCluster, groupName, description, nil)).To(Succeed())
+		})
+	}
+}
+
+func TestCreateSecurityGroupIfNotExistsPropagation(t *testing.T) {
+	openStackCluster := &infrav1.OpenStackCluster{}
+	const groupName = "k8s-cluster-default-mycluster-secgroup-controlplane"
+	group := groups.SecGroup{ID: "idSG", Name: groupName, Description: defaultSecGroupDescriptionTemplate}
+
+	tests := []struct {
+		name       string
+		timeout    time.Duration
+		mockExpect func(m *mock.MockNetworkClientMockRecorder)
+		wantErr    bool
+	}{
+		{
+			name: "No wait without a timeout",
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Name: groupName}).Return([]groups.SecGroup{}, nil)
+				m.CreateSecGroup(gomock.Any()).Return(&group, nil)
+			},
+		},
+		{
+			name:    "Wait until the group is listable",
+			timeout: time.Second,
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Name: groupName}).Return([]groups.SecGroup{}, nil)
+				m.CreateSecGroup(gomock.Any()).Return(&group, nil)
+				gomock.InOrder(
+					m.ListSecGroup(groups.ListOpts{ID: "idSG"}).Return([]groups.SecGroup{}, nil).Times(2),
+					m.ListSecGroup(groups.ListOpts{ID: "idSG"}).Return([]groups.SecGroup{group}, nil),
+				)
+			},
+		},
+		{
+			name:    "Group never becomes listable",
+			timeout: 50 * time.Millisecond,
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Name: groupName}).Return([]groups.SecGroup{}, nil)
+				m.CreateSecGroup(gomock.Any()).Return(&group, nil)
+				m.ListSecGroup(groups.ListOpts{ID: "idSG"}).Return([]groups.SecGroup{}, nil).MinTimes(1)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+			s.secGroupPropagationTimeout = tt.timeout
+			s.secGroupPropagationInterval = 10 * time.Millisecond
+
+			tt.mockExpect(mockScopeFactory.NetworkClient.EXPECT())
+			err = s.createSecurityGroupIfNotExists(context.TODO(), openStackCluster, groupName, defaultSecGroupDescriptionTemplate, nil)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestCreateSecurityGroupIfNotExistsStateless(t *testing.T) {
+	openStackCluster := &infrav1.OpenStackCluster{}
+	const groupName = "k8s-cluster-default-mycluster-secgroup-worker"
+
+	tests := []struct {
+		name         string
+		stateless    *bool
+		wantStateful *bool
+	}{
+		{
+			name: "Statefulness is left to Neutron when unset",
+		},
+		{
+			name:         "Stateless group",
+			stateless:    pointer.Bool(true),
+			wantStateful: pointer.Bool(false),
+		},
+		{
+			name:         "Stateful group",
+			stateless:    pointer.Bool(false),
+			wantStateful: pointer.Bool(true),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+
+			m := mockScopeFactory.NetworkClient.EXPECT()
+			m.ListSecGroup(groups.ListOpts{Name: groupName}).Return([]groups.SecGroup{}, nil)
+			m.CreateSecGroup(groups.CreateOpts{Name: groupName, Description: "worker", Stateful: tt.wantStateful}).Return(&groups.SecGroup{ID: "idSG", Name: groupName}, nil)
+			g.Expect(s.createSecurityGroupIfNotExists(context.TODO(), openStackCluster, groupName, "worker", tt.stateless)).To(Succeed())
+		})
+	}
+}
+
+func TestCreateSecurityGroupIfNotExistsProjectID(t *testing.T) {
+	const (
+		groupName = "k8s-cluster-default-mycluster-secgroup-worker"
+		projectID = "delegated-project"
+	)
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{ProjectID: projectID},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		createErr  error
+		wantErr    bool
+		wantDenied bool
+	}{
+		{
+			name: "Group is created in the project",
+		},
+		{
+			name:       "Refused creation reports the project",
+			createErr:  gophercloud.ErrDefault403{},
+			wantErr:    true,
+			wantDenied: true,
+		},
+		{
+			name:      "Other errors are returned as is",
+			createErr: gophercloud.ErrDefault500{},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+
+			m := mockScopeFactory.NetworkClient.EXPECT()
+			m.ListSecGroup(groups.ListOpts{Name: groupName, ProjectID: projectID}).Return([]groups.SecGroup{}, nil)
+			if tt.createErr != nil {
+				m.CreateSecGroup(groups.CreateOpts{Name: groupName, Description: "worker", ProjectID: projectID}).Return(nil, tt.createErr)
+			} else {
+				m.CreateSecGroup(groups.CreateOpts{Name: groupName, Description: "worker", ProjectID: projectID}).Return(&groups.SecGroup{ID: "idSG", Name: groupName, ProjectID: projectID}, nil)
+			}
+
+			err = s.createSecurityGroupIfNotExists(context.TODO(), openStackCluster, groupName, "worker", nil)
+			if !tt.wantErr {
+				g.Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(HaveOccurred())
+			var denied *SecurityGroupProjectNotAccessibleError
+			g.Expect(errors.As(err, &denied)).To(Equal(tt.wantDenied))
+			if tt.wantDenied {
+				g.Expect(denied.ProjectID).To(Equal(projectID))
+			}
+		})
+	}
+}
+
+func TestValidateSecGroupProject(t *testing.T) {
+	const (
+		clientProjectID = "client-project"
+		projectID       = "delegated-project"
+	)
+
+	tests := []struct {
+		name       string
+		projectID  string
+		quotaErr   error
+		wantQuota  bool
+		wantErr    bool
+		wantDenied bool
+	}{
+		{
+			name: "Project of the credentials when unset",
+		},
+		{
+			name:      "Project of the credentials isn't checked",
+			projectID: clientProjectID,
+		},
+		{
+			name:      "Accessible project",
+			projectID: projectID,
+			wantQuota: true,
+		},
+		{
+			name:       "Forbidden project",
+			projectID:  projectID,
+			quotaErr:   gophercloud.ErrDefault403{},
+			wantQuota:  true,
+			wantErr:    true,
+			wantDenied: true,
+		},
+		{
+			name:      "Transient error isn't reported as forbidden",
+			projectID: projectID,
+			quotaErr:  gophercloud.ErrDefault503{},
+			wantQuota: true,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, clientProjectID)
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+
+			if tt.wantQuota {
+				mockScopeFactory.NetworkClient.EXPECT().GetQuota(tt.projectID).Return(&quotas.Quota{}, tt.quotaErr)
+			}
+
+			openStackCluster := &infrav1.OpenStackCluster{
+				Spec: infrav1.OpenStackClusterSpec{
+					ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{ProjectID: tt.projectID},
+				},
+			}
+			err = s.validateSecGroupProject(openStackCluster)
+			if !tt.wantErr {
+				g.Expect(err).NotTo(HaveOccurred())
+				return
+			}
+			g.Expect(err).To(HaveOccurred())
+			g.Expect(err.Error()).To(ContainSubstring(projectID))
+			var denied *SecurityGroupProjectNotAccessibleError
+			g.Expect(errors.As(err, &denied)).To(Equal(tt.wantDenied))
+		})
+	}
+}
+
+func TestCheckSecGroupStatefulness(t *testing.T) {
+	const (
+		bastionName = "k8s-cluster-mycluster-secgroup-bastion"
+		workerName  = "k8s-cluster-mycluster-secgroup-worker"
+	)
+	secGroupNames := map[string]string{
+		bastionSuffix: bastionName,
+		workerSuffix:  workerName,
+	}
+
+	tests := []struct {
+		name          string
+		stateless     *bool
+		mockExpect    func(m *mock.MockNetworkClientMockRecorder)
+		wantCondition bool
+	}{
+		{
+			name: "Groups are not checked when the statefulness is unset",
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+			},
+		},
+		{
+			name:      "Matching group",
+			stateless: pointer.Bool(true),
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Name: workerName}).Return([]groups.SecGroup{{ID: "idWorker", Name: workerName, Stateful: false}}, nil)
+			},
+		},
+		{
+			name:      "Stateful group when stateless is desired",
+			stateless: pointer.Bool(true),
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Name: workerName}).Return([]groups.SecGroup{{ID: "idWorker", Name: workerName, Stateful: true}}, nil)
+			},
+			wantCondition: true,
+		},
+		{
+			name:      "Stateless group when stateful is desired",
+			stateless: pointer.Bool(false),
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Name: workerName}).Return([]groups.SecGroup{{ID: "idWorker", Name: workerName, Stateful: false}}, nil)
+			},
+			wantCondition: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+
+			openStackCluster := &infrav1.OpenStackCluster{
+				Spec: infrav1.OpenStackClusterSpec{
+					ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{Stateless: tt.stateless},
+				},
+			}
+			// A condition left by a previous reconcile is removed once the groups match.
+			conditions.Set(openStackCluster, &clusterv1.Condition{
+				Type:   infrav1.SecurityGroupStatefulnessMismatchCondition,
+				Status: corev1.ConditionTrue,
+			})
+
+			tt.mockExpect(mockScopeFactory.NetworkClient.EXPECT())
+			g.Expect(s.checkSecGroupStatefulness(openStackCluster, secGroupNames)).To(Succeed())
+
+			condition := conditions.Get(openStackCluster, infrav1.SecurityGroupStatefulnessMismatchCondition)
+			if !tt.wantCondition {
+				g.Expect(condition).To(BeNil())
+				return
+			}
+			g.Expect(condition).NotTo(BeNil())
+			g.Expect(condition.Reason).To(Equal(infrav1.SecurityGroupRecreationRequiredReason))
+			g.Expect(condition.Message).To(ContainSubstring(workerName))
+			g.Expect(condition.Message).NotTo(ContainSubstring(bastionName))
+		})
+	}
+}
+
+func TestGetSecurityGroupByNameConflictPolicy(t *testing.T) {
+	const groupName = "k8s-cluster-default-mycluster-secgroup-controlplane"
+	clusterTags := []string{"capo-cluster"}
+
+	older := groups.SecGroup{ID: "idOlder", Name: groupName, CreatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tagged := groups.SecGroup{ID: "idTagged", Name: groupName, CreatedAt: time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC), Tags: []string{"capo-cluster", "other"}}
+
+	tests := []struct {
+		name    string
+		policy  SecurityGroupNameConflictPolicy
+		tags    []string
+		groups  []groups.SecGroup
+		wantID  string
+		wantErr bool
+	}{
+		{
+			name:    "Error policy fails",
+			policy:  SecurityGroupNameConflictError,
+			tags:    clusterTags,
+			groups:  []groups.SecGroup{tagged, older},
+			wantErr: true,
+		},
+		{
+			name:   "Oldest policy picks the oldest group",
+			policy: SecurityGroupNameConflictOldest,
+			tags:   clusterTags,
+			groups: []groups.SecGroup{tagged, older},
+			wantID: "idOlder",
+		},
+		{
+			name:   "Tagged policy picks the group with the cluster tags",
+			policy: SecurityGroupNameConflictTagged,
+			tags:   clusterTags,
+			groups: []groups.SecGroup{older, tagged},
+			wantID: "idTagged",
+		},
+		{
+			name:    "Tagged policy fails when no group has the cluster tags",
+			policy:  SecurityGroupNameConflictTagged,
+			tags:    []string{"another-cluster"},
+			groups:  []groups.SecGroup{older, tagged},
+			wantErr: true,
+		},
+		{
+			name:    "Tagged policy fails when the cluster has no tags",
+			policy:  SecurityGroupNameConflictTagged,
+			groups:  []groups.SecGroup{older, tagged},
+			wantErr: true,
+		},
+		{
+			name:   "Single group is used whatever the policy",
+			policy: SecurityGroupNameConflictError,
+			groups: []groups.SecGroup{tagged},
+			wantID: "idTagged",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+			s.secGroupNameConflictPolicy = tt.policy
+
+			mockScopeFactory.NetworkClient.EXPECT().ListSecGroup(groups.ListOpts{Name: groupName}).Return(tt.groups, nil)
+
+			group, err := s.getSecurityGroupByName(groupName, "", tt.tags)
+			if tt.wantErr {
+				g.Expect(err).To(MatchError(ErrSecurityGroupNotUnique))
+				g.Expect(err.Error()).To(ContainSubstring("idOlder, idTagged"))
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(group.ID).To(Equal(tt.wantID))
+		})
+	}
+}
+
+func TestReconcileGroupRulesConflict(t *testing.T) {
+	desired := securityGroupSpec{
+		Name: "k8s-cluster-mycluster-secgroup-controlplane",
+		Rules: []resolvedSecurityGroupRuleSpec{
+			{
+				Description:   "Allow SSH",
+				Direction:     "ingress",
+				EtherType:     "IPv4",
+				Protocol:      "tcp",
+				PortRangeMin:  22,
+				PortRangeMax:  22,
+				RemoteGroupID: "1",
+			},
+		},
+	}
+	observed := infrav1.SecurityGroupStatus{
+		ID:   "idSG",
+		Name: "k8s-cluster-mycluster-secgroup-controlplane",
+	}
+	existingRule := rules.SecGroupRule{
+		ID:            "idSGRule",
+		Description:   "Allow SSH",
+		Direction:     "ingress",
+		EtherType:     "IPv4",
+		Protocol:      "tcp",
+		PortRangeMin:  22,
+		PortRangeMax:  22,
+		RemoteGroupID: "1",
+		SecGroupID:    "idSG",
+	}
+
+	tests := []struct {
+		name         string
+		mockExpect   func(m *mock.MockNetworkClientMockRecorder)
+		wantSGStatus infrav1.SecurityGroupStatus
+		wantErr      bool
+	}{
+		{
+			name: "Existing rule is adopted on conflict",
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.CreateSecGroupRule(gomock.Any()).Return(nil, gophercloud.ErrDefault409{})
+				m.ListSecGroupRule(rules.ListOpts{SecGroupID: "idSG"}).Return([]rules.SecGroupRule{existingRule}, nil)
+			},
+			wantSGStatus: infrav1.SecurityGroupStatus{
+				ID:    "idSG",
+				Name:  "k8s-cluster-mycluster-secgroup-controlplane",
+				Rules: []infrav1.SecurityGroupRuleStatus{convertOSSecGroupRuleToConfigSecGroupRule(existingRule)},
+			},
+		},
+		{
+			name: "Existing rule with another description is adopted on conflict",
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				unmarkedRule := existingRule
+				unmarkedRule.Description = "SSH from the bastion"
+				m.CreateSecGroupRule(gomock.Any()).Return(nil, gophercloud.ErrDefault409{})
+				m.ListSecGroupRule(rules.ListOpts{SecGroupID: "idSG"}).Return([]rules.SecGroupRule{unmarkedRule}, nil)
+			},
+			wantSGStatus: infrav1.SecurityGroupStatus{
+				ID:   "idSG",
+				Name: "k8s-cluster-mycluster-secgroup-controlplane",
+				Rules: []infrav1.SecurityGroupRuleStatus{convertOSSecGroupRuleToConfigSecGroupRule(rules.SecGroupRule{
+					ID:            "idSGRule",
+					Description:   "SSH from the bastion",
+					Direction:     "ingress",
+					EtherType:     "IPv4",
+					Protocol:      "tcp",
+					PortRangeMin:  22,
+					PortRangeMax:  22,
+					RemoteGroupID: "1",
+					SecGroupID:    "idSG",
+				})},
+			},
+		},
+		{
+			name: "Conflict is returned if no matching rule exists",
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.CreateSecGroupRule(gomock.Any()).Return(nil, gophercloud.ErrDefault409{})
+				m.ListSecGroupRule(rules.ListOpts{SecGroupID: "idSG"}).Return([]rules.SecGroupRule{}, nil)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+			tt.mockExpect(mockScopeFactory.NetworkClient.EXPECT())
+
+			sgStatus, err := s.reconcileGroupRules(context.TODO(), desired, observed)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(sgStatus).To(Equal(tt.wantSGStatus))
+		})
+	}
+}
+
+func TestReconcileGroupRulesCreateBeforeDelete(t *testing.T) {
+	desired := securityGroupSpec{
+		Name: "k8s-cluster-mycluster-secgroup-controlplane",
+		Rules: []resolvedSecurityGroupRuleSpec{
+			{
+				Description:   "Allow SSH",
+				Direction:     "ingress",
+				EtherType:     "IPv4",
+				Protocol:      "tcp",
+				PortRangeMin:  22,
+				PortRangeMax:  22,
+				RemoteGroupID: "1",
+			},
+		},
+	}
+	newRule := rules.SecGroupRule{
+		ID:            "idSGRule",
+		Description:   "Allow SSH",
+		Direction:     "ingress",
+		EtherType:     "IPv4",
+		Protocol:      "tcp",
+		PortRangeMin:  22,
+		PortRangeMax:  22,
+		RemoteGroupID: "1",
+		SecGroupID:    "idSG",
+	}
+	oldRule := newRule
+	oldRule.ID = "idSGRuleOld"
+	oldRule.Description = "SSH"
+
+	tests := []struct {
+		name        string
+		oldRule     rules.SecGroupRule
+		gracePeriod time.Duration
+		mockExpect  func(m *mock.MockNetworkClientMockRecorder)
+		wantErr     bool
+	}{
+		{
+			name: "Replacement rule is created before the replaced rule is deleted",
+			oldRule: func() rules.SecGroupRule {
+				r := oldRule
+				r.PortRangeMin = 2222
+				r.PortRangeMax = 2222
+				return r
+			}(),
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				gomock.InOrder(
+					m.CreateSecGroupRule(gomock.Any()).Return(&newRule, nil),
+					m.DeleteSecGroupRule("idSGRuleOld").Return(nil),
+				)
+			},
+		},
+		{
+			name:    "Rule whose description changed is swapped once Neutron refuses the replacement",
+			oldRule: oldRule,
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				gomock.InOrder(
+					m.CreateSecGroupRule(gomock.Any()).Return(nil, gophercloud.ErrDefault409{}),
+					m.ListSecGroupRule(rules.ListOpts{SecGroupID: "idSG"}).Return([]rules.SecGroupRule{oldRule}, nil),
+					m.DeleteSecGroupRule("idSGRuleOld").Return(nil),
+					m.CreateSecGroupRule(gomock.Any()).Return(&newRule, nil),
+				)
+			},
+		},
+		{
+			name:        "Rule whose description changed is swapped regardless of the deletion grace period",
+			oldRule:     oldRule,
+			gracePeriod: time.Minute,
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				gomock.InOrder(
+					m.CreateSecGroupRule(gomock.Any()).Return(nil, gophercloud.ErrDefault409{}),
+					m.ListSecGroupRule(rules.ListOpts{SecGroupID: "idSG"}).Return([]rules.SecGroupRule{oldRule}, nil),
+					m.DeleteSecGroupRule("idSGRuleOld").Return(nil),
+					m.CreateSecGroupRule(gomock.Any()).Return(&newRule, nil),
+				)
+			},
+		},
+		{
+			name: "Replaced rule is kept when the replacement can't be created",
+			oldRule: func() rules.SecGroupRule {
+				r := oldRule
+				r.PortRangeMin = 2222
+				r.PortRangeMax = 2222
+				return r
+			}(),
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.CreateSecGroupRule(gomock.Any()).Return(nil, gophercloud.ErrDefault500{})
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+			s.secGroupRuleDeletionGracePeriod = tt.gracePeriod
+			tt.mockExpect(mockScopeFactory.NetworkClient.EXPECT())
+
+			observed := infrav1.SecurityGroupStatus{
+				ID:    "idSG",
+				Name:  "k8s-cluster-mycluster-secgroup-controlplane",
+				Rules: []infrav1.SecurityGroupRuleStatus{convertOSSecGroupRuleToConfigSecGroupRule(tt.oldRule)},
+			}
+			sgStatus, err := s.reconcileGroupRules(context.TODO(), desired, observed)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(sgStatus.Rules).To(Equal([]infrav1.SecurityGroupRuleStatus{convertOSSecGroupRuleToConfigSecGroupRule(newRule)}))
+			g.Expect(sgStatus.RulesPendingDeletion).To(BeEmpty())
+		})
+	}
+}
+
+func TestReconcileGroupRulesCanceled(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	desired := securityGroupSpec{
+		Name: "k8s-cluster-mycluster-secgroup-controlplane",
+		Rules: []resolvedSecurityGroupRuleSpec{
+			{Description: "Allow SSH", Direction: "ingress", EtherType: "IPv4", Protocol: "tcp", PortRangeMin: 22, PortRangeMax: 22},
+		},
+	}
+	observed := infrav1.SecurityGroupStatus{
+		ID:   "idSG",
+		Name: "k8s-cluster-mycluster-secgroup-controlplane",
+		Rules: []infrav1.SecurityGroupRuleStatus{
+			{ID: "idSGRuleOld", Description: pointer.String("Allow SSH"), Direction: "ingress", EtherType: pointer.String("IPv4"), Protocol: pointer.String("tcp"), PortRangeMin: pointer.Int(2222), PortRangeMax: pointer.Int(2222)},
+		},
+	}
+
+	// No rule is created nor deleted once the context is done.
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	_, err = s.reconcileGroupRules(ctx, desired, observed)
+	g.Expect(err).To(MatchError(context.Canceled))
+}
+
+func TestReconcileGroupRulesOwnerTag(t *testing.T) {
+	sshRule := func(description string, port int) infrav1.SecurityGroupRuleStatus {
+		return infrav1.SecurityGroupRuleStatus{
+			ID:             description,
+			Description:    pointer.String(description),
+			Direction:      "ingress",
+			EtherType:      pointer.String("IPv4"),
+			Protocol:       pointer.String("tcp"),
+			PortRangeMin:   pointer.Int(port),
+			PortRangeMax:   pointer.Int(port),
+			RemoteGroupID:  pointer.String(""),
+			RemoteIPPrefix: pointer.String(""),
+		}
+	}
+	// Both managers have reconciled the group, and each one has left a stale rule behind. The stale rule of manager
+	// a was created before the rules were owned with tags.
+	observed := infrav1.SecurityGroupStatus{
+		ID:   "idSG",
+		Name: "shared",
+		Rules: []infrav1.SecurityGroupRuleStatus{
+			sshRule("a: SSH", 22),
+			sshRule("a: SSH legacy", 222),
+			sshRule("b: SSH", 22),
+			sshRule("b: SSH legacy", 222),
+			sshRule("operator rule", 2222),
+		},
+	}
+
+	tests := []struct {
+		name       string
+		ownerTag   string
+		prefix     string
+		mockExpect func(m *mock.MockNetworkClientMockRecorder)
+		wantRules  []infrav1.SecurityGroupRuleStatus
+	}{
+		{
+			name:     "Manager a adopts its untagged rule, then deletes it",
+			ownerTag: "a",
+			prefix:   "a: ",
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroupRuleWithTags(rules.ListOpts{SecGroupID: "idSG"}, []string{"a"}).Return([]rules.SecGroupRule{{ID: "a: SSH"}}, nil)
+				m.ReplaceAllAttributesTags(secGroupRuleResource, "a: SSH legacy", attributestags.ReplaceAllOpts{Tags: []string{"a"}}).Return([]string{"a"}, nil)
+				m.DeleteSecGroupRule("a: SSH legacy").Return(nil)
+			},
+			wantRules: []infrav1.SecurityGroupRuleStatus{sshRule("a: SSH", 22)},
+		},
+		{
+			name:     "Manager b only deletes its own rules",
+			ownerTag: "b",
+			prefix:   "b: ",
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroupRuleWithTags(rules.ListOpts{SecGroupID: "idSG"}, []string{"b"}).Return([]rules.SecGroupRule{{ID: "b: SSH"}, {ID: "b: SSH legacy"}}, nil)
+				m.DeleteSecGroupRule("b: SSH legacy").Return(nil)
+			},
+			wantRules: []infrav1.SecurityGroupRuleStatus{sshRule("b: SSH", 22)},
+		},
+		{
+			name:     "Manager c creates its tagged rule",
+			ownerTag: "c",
+			prefix:   "c: ",
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroupRuleWithTags(rules.ListOpts{SecGroupID: "idSG"}, []string{"c"}).Return(nil, nil)
+				m.CreateSecGroupRule(rules.CreateOpts{
+					SecGroupID:   "idSG",
+					Description:  "c: SSH",
+					Direction:    "ingress",
+					EtherType:    "IPv4",
+					Protocol:     "tcp",
+					PortRangeMin: 22,
+					PortRangeMax: 22,
+				}).Return(&rules.SecGroupRule{
+					ID:           "c: SSH",
+					Description:  "c: SSH",
+					Direction:    "ingress",
+					EtherType:    "IPv4",
+					Protocol:     "tcp",
+					PortRangeMin: 22,
+					PortRangeMax: 22,
+				}, nil)
+				m.ReplaceAllAttributesTags(secGroupRuleResource, "c: SSH", attributestags.ReplaceAllOpts{Tags: []string{"c"}}).Return([]string{"c"}, nil)
+			},
+			wantRules: []infrav1.SecurityGroupRuleStatus{sshRule("c: SSH", 22)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+			s.ruleDescriptionPrefix = tt.prefix
+			s.ruleOwnerTag = tt.ownerTag
+			tt.mockExpect(mockScopeFactory.NetworkClient.EXPECT())
+
+			desired := securityGroupSpec{
+				Name: "shared",
+				Rules: []resolvedSecurityGroupRuleSpec{
+					{
+						Description:  "SSH",
+						Direction:    "ingress",
+						EtherType:    "IPv4",
+						Protocol:     "tcp",
+						PortRangeMin: 22,
+						PortRangeMax: 22,
+					},
+				},
+				RuleTags: s.getRuleOwnershipTags(&infrav1.OpenStackCluster{}),
+			}
+			sgStatus, err := s.reconcileGroupRules(context.TODO(), desired, observed)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(sgStatus.Rules).To(Equal(tt.wantRules))
+		})
+	}
+}
+
+func TestGetRuleOwnershipTags(t *testing.T) {
+	g := NewWithT(t)
+	s := &Service{}
+	openStackCluster := &infrav1.OpenStackCluster{Spec: infrav1.OpenStackClusterSpec{Tags: []string{"tag1", "tag2"}}}
+
+	g.Expect(s.getRuleOwnershipTags(openStackCluster)).To(Equal([]string{"tag1", "tag2"}))
+	s.ruleOwnerTag = "owner"
+	g.Expect(s.getRuleOwnershipTags(openStackCluster)).To(Equal([]string{"tag1", "tag2", "owner"}))
+	g.Expect(openStackCluster.Spec.Tags).To(Equal([]string{"tag1", "tag2"}))
+	s.ruleOwnerTag = "tag1"
+	g.Expect(s.getRuleOwnershipTags(openStackCluster)).To(Equal([]string{"tag1", "tag2"}))
+}
+
+func TestReconcileGroupRulesDescriptionFormat(t *testing.T) {
+	desired := securityGroupSpec{
+		Name: "worker",
+		Rules: []resolvedSecurityGroupRuleSpec{
+			{
+				Description:  "Node Port Services",
+				Direction:    "ingress",
+				EtherType:    "IPv4",
+				Protocol:     "tcp",
+				PortRangeMin: 30000,
+				PortRangeMax: 32767,
+			},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		format          DescriptionFormat
+		wantDescription string
+	}{
+		{
+			name:            "Prose",
+			format:          DescriptionFormatProse,
+			wantDescription: "Node Port Services",
+		},
+		{
+			name:            "Structured",
+			format:          DescriptionFormatStructured,
+			wantDescription: `rule="Node Port Services" direction=ingress ethertype=IPv4 protocol=tcp ports=30000-32767`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+			s.secGroupDescription.format = tt.format
+
+			mockScopeFactory.NetworkClient.EXPECT().CreateSecGroupRule(rules.CreateOpts{
+				SecGroupID:   "idSG",
+				Description:  tt.wantDescription,
+				Direction:    "ingress",
+				EtherType:    "IPv4",
+				Protocol:     "tcp",
+				PortRangeMin: 30000,
+				PortRangeMax: 32767,
+			}).Return(&rules.SecGroupRule{
+				ID:           "idRule",
+				Description:  tt.wantDescription,
+				Direction:    "ingress",
+				EtherType:    "IPv4",
+				Protocol:     "tcp",
+				PortRangeMin: 30000,
+				PortRangeMax: 32767,
+			}, nil)
+
+			created, err := s.reconcileGroupRules(context.TODO(), desired, infrav1.SecurityGroupStatus{ID: "idSG", Name: "worker"})
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(created.Rules).To(HaveLen(1))
+			g.Expect(*created.Rules[0].Description).To(Equal(tt.wantDescription))
+
+			// The created rule matches the desired rule, so the next reconcile doesn't touch it.
+			reconciled, err := s.reconcileGroupRules(context.TODO(), desired, created)
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(reconciled.Rules).To(Equal(created.Rules))
+		})
+	}
+}
+
+func TestCanonicalRemoteIPPrefix(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{prefix: "", want: ""},
+		{prefix: "10.0.0.0/24", want: "10.0.0.0/24"},
+		{prefix: "10.0.0.5/24", want: "10.0.0.0/24"},
+		{prefix: "10.0.0.5", want: "10.0.0.5/32"},
+		{prefix: "2001:db8::1/64", want: "2001:db8::/64"},
+		{prefix: "2001:db8::1", want: "2001:db8::1/128"},
+		{prefix: "not-a-prefix", want: "not-a-prefix"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(canonicalRemoteIPPrefix(tt.prefix)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestDedupeRules(t *testing.T) {
+	kubelet := resolvedSecurityGroupRuleSpec{
+		Description:   "Kubelet API",
+		Direction:     "ingress",
+		EtherType:     "IPv4",
+		Protocol:      "tcp",
+		PortRangeMin:  10250,
+		PortRangeMax:  10250,
+		RemoteGroupID: "idControlPlane",
+		Origin:        infrav1.SecurityGroupRuleOriginGeneral,
+	}
+	userKubelet := kubelet
+	userKubelet.Origin = infrav1.SecurityGroupRuleOriginUser
+	otherPort := kubelet
+	otherPort.PortRangeMin, otherPort.PortRangeMax = 10255, 10255
+	otherDescription := kubelet
+	otherDescription.Description = "Kubelet"
+	packetTooBig := resolvedSecurityGroupRuleSpec{Direction: "ingress", EtherType: "IPv6", Protocol: "icmpv6", PortRangeMin: 2}
+	ipv6ICMPPacketTooBig := packetTooBig
+	ipv6ICMPPacketTooBig.Protocol = "ipv6-icmp"
+	numericKubelet := kubelet
+	numericKubelet.Protocol = "6"
+
+	tests := []struct {
+		name  string
+		rules []resolvedSecurityGroupRuleSpec
+		want  []resolvedSecurityGroupRuleSpec
+	}{
+		{
+			name:  "Identical rules",
+			rules: []resolvedSecurityGroupRuleSpec{kubelet, otherPort, kubelet},
+			want:  []resolvedSecurityGroupRuleSpec{kubelet, otherPort},
+		},
+		{
+			name:  "Rules differing only by their origin keep the first origin",
+			rules: []resolvedSecurityGroupRuleSpec{kubelet, userKubelet},
+			want:  []resolvedSecurityGroupRuleSpec{kubelet},
+		},
+		{
+			name:  "Rules with another name of the same protocol",
+			rules: []resolvedSecurityGroupRuleSpec{packetTooBig, ipv6ICMPPacketTooBig},
+			want:  []resolvedSecurityGroupRuleSpec{packetTooBig},
+		},
+		{
+			name:  "Rules with the number of the same protocol",
+			rules: []resolvedSecurityGroupRuleSpec{kubelet, numericKubelet},
+			want:  []resolvedSecurityGroupRuleSpec{kubelet},
+		},
+		{
+			name:  "Rules with different descriptions are not duplicates",
+			rules: []resolvedSecurityGroupRuleSpec{kubelet, otherDescription},
+			want:  []resolvedSecurityGroupRuleSpec{kubelet, otherDescription},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(dedupeRules(tt.rules)).To(Equal(tt.want))
+		})
+	}
+}
+
+func TestReconcileGroupRulesDuplicateRules(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// An allNodes rule identical to a general rule, and a rule referencing the group by the self keyword
+	// identical to one referencing it by its ID.
+	kubelet := resolvedSecurityGroupRuleSpec{
+		Description:   "Kubelet API",
+		Direction:     "ingress",
+		EtherType:     "IPv4",
+		Protocol:      "tcp",
+		PortRangeMin:  10250,
+		PortRangeMax:  10250,
+		RemoteGroupID: remoteGroupIDSelf,
+		Origin:        infrav1.SecurityGroupRuleOriginGeneral,
+	}
+	userKubelet := kubelet
+	userKubelet.RemoteGroupID = "idSG"
+	userKubelet.Origin = infrav1.SecurityGroupRuleOriginUser
+	desired := securityGroupSpec{Name: "worker", Rules: []resolvedSecurityGroupRuleSpec{kubelet, userKubelet}}
+
+	// The rule is created once.
+	mockScopeFactory.NetworkClient.EXPECT().CreateSecGroupRule(gomock.Any()).DoAndReturn(func(opts rules.CreateOptsBuilder) (*rules.SecGroupRule, error) {
+		createOpts := opts.(rules.CreateOpts)
+		g.Expect(createOpts.RemoteGroupID).To(Equal("idSG"))
+		return &rules.SecGroupRule{ID: "idRule", SecGroupID: createOpts.SecGroupID, RemoteGroupID: createOpts.RemoteGroupID}, nil
+	})
+
+	sgStatus, err := s.reconcileGroupRules(context.TODO(), desired, infrav1.SecurityGroupStatus{ID: "idSG", Name: "worker"})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sgStatus.Rules).To(HaveLen(1))
+	g.Expect(sgStatus.Rules[0].Origin).To(Equal(infrav1.SecurityGroupRuleOriginGeneral))
+}
+
+func TestReconcileGroupRulesNonCanonicalRemoteIPPrefix(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	desired := securityGroupSpec{
+		Name: "worker",
+		Rules: []resolvedSecurityGroupRuleSpec{
+			{
+				Description:    "SSH from the office",
+				Direction:      "ingress",
+				EtherType:      "IPv4",
+				Protocol:       "tcp",
+				PortRangeMin:   22,
+				PortRangeMax:   22,
+				RemoteIPPrefix: "10.0.0.5/24",
+			},
+		},
+	}
+	// Neutron has stored the prefix with its host bits cleared.
+	observed := infrav1.SecurityGroupStatus{
+		ID:   "idSG",
+		Name: "worker",
+		Rules: []infrav1.SecurityGroupRuleStatus{
+			{
+				ID:             "idRule",
+				Description:    pointer.String("SSH from the office"),
+				Direction:      "ingress",
+				EtherType:      pointer.String("IPv4"),
+				Protocol:       pointer.String("tcp"),
+				PortRangeMin:   pointer.Int(22),
+				PortRangeMax:   pointer.Int(22),
+				RemoteGroupID:  pointer.String(""),
+				RemoteIPPrefix: pointer.String("10.0.0.0/24"),
+			},
+		},
+	}
+
+	// No rule is deleted nor created.
+	sgStatus, err := s.reconcileGroupRules(context.TODO(), desired, observed)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sgStatus.Rules).To(Equal(observed.Rules))
+}
+
+func TestReconcileGroupRulesStatusOrder(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	ruleStatus := func(id, description, direction, protocol string, port int) infrav1.SecurityGroupRuleStatus {
+		return infrav1.SecurityGroupRuleStatus{
+			ID:             id,
+			Description:    pointer.String(description),
+			Direction:      direction,
+			EtherType:      pointer.String("IPv4"),
+			Protocol:       pointer.String(protocol),
+			PortRangeMin:   pointer.Int(port),
+			PortRangeMax:   pointer.Int(port),
+			RemoteGroupID:  pointer.String(""),
+			RemoteIPPrefix: pointer.String(""),
+		}
+	}
+	ruleSpec := func(description, direction, protocol string, port int) resolvedSecurityGroupRuleSpec {
+		return resolvedSecurityGroupRuleSpec{
+			Description:  description,
+			Direction:    direction,
+			EtherType:    "IPv4",
+			Protocol:     protocol,
+			PortRangeMin: port,
+			PortRangeMax: port,
+		}
+	}
+
+	desired := securityGroupSpec{
+		Name: "worker",
+		Rules: []resolvedSecurityGroupRuleSpec{
+			ruleSpec("Kubelet API", "ingress", "tcp", 10250),
+			ruleSpec("DNS", "egress", "udp", 53),
+			ruleSpec("SSH", "ingress", "tcp", 22),
+			ruleSpec("Syslog", "ingress", "udp", 514),
+		},
+	}
+	observed := infrav1.SecurityGroupStatus{
+		ID:   "idSG",
+		Name: "worker",
+		Rules: []infrav1.SecurityGroupRuleStatus{
+			ruleStatus("idSyslog", "Syslog", "ingress", "udp", 514),
+			ruleStatus("idKubelet", "Kubelet API", "ingress", "tcp", 10250),
+			ruleStatus("idDNS", "DNS", "egress", "udp", 53),
+		},
+	}
+
+	mockScopeFactory.NetworkClient.EXPECT().CreateSecGroupRule(rules.CreateOpts{
+		SecGroupID:   "idSG",
+		Description:  "SSH",
+		Direction:    "ingress",
+		EtherType:    "IPv4",
+		Protocol:     "tcp",
+		PortRangeMin: 22,
+		PortRangeMax: 22,
+	}).Return(&rules.SecGroupRule{
+		ID:           "idSSH",
+		Description:  "SSH",
+		Direction:    "ingress",
+		EtherType:    "IPv4",
+		Protocol:     "tcp",
+		PortRangeMin: 22,
+		PortRangeMax: 22,
+	}, nil)
+
+	sgStatus, err := s.reconcileGroupRules(context.TODO(), desired, observed)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sgStatus.Rules).To(Equal([]infrav1.SecurityGroupRuleStatus{
+		ruleStatus("idDNS", "DNS", "egress", "udp", 53),
+		ruleStatus("idSSH", "SSH", "ingress", "tcp", 22),
+		ruleStatus("idKubelet", "Kubelet API", "ingress", "tcp", 10250),
+		ruleStatus("idSyslog", "Syslog", "ingress", "udp", 514),
+	}))
+}
+
+func TestReconcileGroupRulesOrphanAfterProtocolChange(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	ruleStatus := func(id, protocol string) infrav1.SecurityGroupRuleStatus {
+		return infrav1.SecurityGroupRuleStatus{
+			ID:             id,
+			Description:    pointer.String("Syslog"),
+			Direction:      "ingress",
+			EtherType:      pointer.String("IPv4"),
+			Protocol:       pointer.String(protocol),
+			PortRangeMin:   pointer.Int(514),
+			PortRangeMax:   pointer.Int(514),
+			RemoteGroupID:  pointer.String(""),
+			RemoteIPPrefix: pointer.String(""),
+		}
+	}
+	// The protocol of the rule was changed from tcp to udp.
+	desired := securityGroupSpec{
+		Name: "worker",
+		Rules: []resolvedSecurityGroupRuleSpec{
+			{
+				Description:  "Syslog",
+				Direction:    "ingress",
+				EtherType:    "IPv4",
+				Protocol:     "udp",
+				PortRangeMin: 514,
+				PortRangeMax: 514,
+			},
+		},
+	}
+	m := mockScopeFactory.NetworkClient.EXPECT()
+
+	// The first pass fails to delete the tcp rule, but still creates the udp rule.
+	m.DeleteSecGroupRule("idTCP").Return(gophercloud.ErrDefault500{})
+	m.CreateSecGroupRule(rules.CreateOpts{
+		SecGroupID:   "idSG",
+		Description:  "Syslog",
+		Direction:    "ingress",
+		EtherType:    "IPv4",
+		Protocol:     "udp",
+		PortRangeMin: 514,
+		PortRangeMax: 514,
+	}).Return(&rules.SecGroupRule{
+		ID:           "idUDP",
+		Description:  "Syslog",
+		Direction:    "ingress",
+		EtherType:    "IPv4",
+		Protocol:     "udp",
+		PortRangeMin: 514,
+		PortRangeMax: 514,
+	}, nil)
+
+	_, err = s.reconcileGroupRules(context.TODO(), desired, infrav1.SecurityGroupStatus{
+		ID:    "idSG",
+		Name:  "worker",
+		Rules: []infrav1.SecurityGroupRuleStatus{ruleStatus("idTCP", "tcp")},
+	})
+	g.Expect(err).To(MatchError(ContainSubstring("deleting rule idTCP of security group worker")))
+
+	// The follow-up pass observes both rules, and only removes the orphaned tcp rule.
+	m.DeleteSecGroupRule("idTCP").Return(nil)
+
+	sgStatus, err := s.reconcileGroupRules(context.TODO(), desired, infrav1.SecurityGroupStatus{
+		ID:    "idSG",
+		Name:  "worker",
+		Rules: []infrav1.SecurityGroupRuleStatus{ruleStatus("idTCP", "tcp"), ruleStatus("idUDP", "udp")},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sgStatus.Rules).To(Equal([]infrav1.SecurityGroupRuleStatus{ruleStatus("idUDP", "udp")}))
+}
+
+func TestReconcileGroupRulesLogsRuleDiff(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	var logs []string
+	logger := funcr.New(func(prefix, args string) {
+		logs = append(logs, args)
+	}, funcr.Options{Verbosity: 5})
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, logger), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	desired := securityGroupSpec{
+		Name: "worker",
+		Rules: []resolvedSecurityGroupRuleSpec{
+			{Description: "Syslog", Direction: "ingress", EtherType: "IPv4", Protocol: "udp", PortRangeMin: 514, PortRangeMax: 514},
+		},
+	}
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	m.CreateSecGroupRule(gomock.Any()).Return(&rules.SecGroupRule{ID: "idUDP", Description: "Syslog", Direction: "ingress", EtherType: "IPv4", Protocol: "udp", PortRangeMin: 514, PortRangeMax: 514}, nil)
+	m.DeleteSecGroupRule("idTCP").Return(nil)
+
+	_, err = s.reconcileGroupRules(context.TODO(), desired, infrav1.SecurityGroupStatus{
+		ID:   "idSG",
+		Name: "worker",
+		Rules: []infrav1.SecurityGroupRuleStatus{
+			{ID: "idTCP", Description: pointer.String("Syslog"), Direction: "ingress", EtherType: pointer.String("IPv4"), Protocol: pointer.String("tcp"), PortRangeMin: pointer.Int(514), PortRangeMax: pointer.Int(514)},
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Each rule is logged with the fields telling it apart.
+	g.Expect(logs).To(ContainElement(SatisfyAll(
+		ContainSubstring(`"msg"="Rule to create"`),
+		ContainSubstring(`"protocol"="udp"`),
+		ContainSubstring(`"portRangeMin"=514`),
+	)))
+	g.Expect(logs).To(ContainElement(SatisfyAll(
+		ContainSubstring(`"msg"="Rule to delete"`),
+		ContainSubstring(`"ID"="idTCP"`),
+		ContainSubstring(`"protocol"="tcp"`),
+	)))
+}
+
+func TestReconcileGroupRulesAlreadyDeleted(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	mockScopeFactory.NetworkClient.EXPECT().DeleteSecGroupRule("idOrphan").Return(gophercloud.ErrDefault404{})
+
+	sgStatus, err := s.reconcileGroupRules(context.TODO(), securityGroupSpec{Name: "worker"}, infrav1.SecurityGroupStatus{
+		ID:   "idSG",
+		Name: "worker",
+		Rules: []infrav1.SecurityGroupRuleStatus{
+			{
+				ID:           "idOrphan",
+				Description:  pointer.String("Orphan"),
+				Direction:    "ingress",
+				EtherType:    pointer.String("IPv4"),
+				Protocol:     pointer.String("tcp"),
+				PortRangeMin: pointer.Int(22),
+				PortRangeMax: pointer.Int(22),
+			},
+		},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sgStatus.Rules).To(BeEmpty())
+}
+
+func TestReconcileGroupRulesLimitExceeded(t *testing.T) {
+	desired := securityGroupSpec{
+		Name: "worker",
+		Rules: []resolvedSecurityGroupRuleSpec{
+			{Description: "SSH", Direction: "ingress", EtherType: "IPv4", Protocol: "tcp", PortRangeMin: 22, PortRangeMax: 22},
+			{Description: "HTTPS", Direction: "ingress", EtherType: "IPv4", Protocol: "tcp", PortRangeMin: 443, PortRangeMax: 443},
+		},
+		RuleTags: []string{"capi-a"},
+	}
+	observed := infrav1.SecurityGroupStatus{
+		ID:   "idSG",
+		Name: "worker",
+		Rules: []infrav1.SecurityGroupRuleStatus{
+			{
+				ID:          "idUnmanaged",
+				Description: pointer.String("Added by hand"),
+				Direction:   "egress",
+				EtherType:   pointer.String("IPv4"),
+			},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		maxRules       int
+		expect         func(m *mock.MockNetworkClientMockRecorder)
+		wantErrMessage string
+	}{
+		{
+			name:     "Cloud refuses to create more rules",
+			maxRules: 0,
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				ruleLimitErr := gophercloud.ErrDefault409{
+					ErrUnexpectedResponseCode: gophercloud.ErrUnexpectedResponseCode{
+						Actual: 409,
+						Body:   []byte(`{"NeutronError": {"message": "Maximum number of rules per security group reached"}}`),
+					},
+				}
+				// The bulk request is refused as a whole, then the rules are created one at a time.
+				m.CreateSecGroupRules(gomock.Any()).Return(nil, ruleLimitErr)
+				m.CreateSecGroupRule(gomock.Any()).Return(nil, ruleLimitErr)
+			},
+			wantErrMessage: "security group worker needs 2 rules, the cloud refused to create more",
+		},
+		{
+			name:           "Configured limit is exceeded by the desired and unmanaged rules",
+			maxRules:       2,
+			expect:         func(m *mock.MockNetworkClientMockRecorder) {},
+			wantErrMessage: "security group worker needs 3 rules, the limit is 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+			s.secGroupMaxRulesPerGroup = tt.maxRules
+			// The rule without the tag is not owned, but still counts towards the limit.
+			mockScopeFactory.NetworkClient.EXPECT().ListSecGroupRuleWithTags(rules.ListOpts{SecGroupID: "idSG"}, []string{"capi-a"}).Return(nil, nil)
+
+			tt.expect(mockScopeFactory.NetworkClient.EXPECT())
+
+			_, err = s.reconcileGroupRules(context.TODO(), desired, observed)
+			g.Expect(err).To(MatchError(ErrSecurityGroupRuleLimitExceeded))
+			g.Expect(err.Error()).To(ContainSubstring(tt.wantErrMessage))
+		})
+	}
+}
+
+func TestReconcileGroupRulesTagged(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	tags := []string{"cluster-tag"}
+	ssh := resolvedSecurityGroupRuleSpec{Description: "SSH", Direction: "ingress", EtherType: "IPv4", Protocol: "tcp", PortRangeMin: 22, PortRangeMax: 22}
+	https := resolvedSecurityGroupRuleSpec{Description: "HTTPS", Direction: "ingress", EtherType: "IPv4", Protocol: "tcp", PortRangeMin: 443, PortRangeMax: 443}
+	desired := securityGroupSpec{Name: "worker", Rules: []resolvedSecurityGroupRuleSpec{ssh, https}, RuleTags: tags}
+
+	staleRule := func(id string, port int) infrav1.SecurityGroupRuleStatus {
+		return infrav1.SecurityGroupRuleStatus{ID: id, Description: pointer.String("Stale"), Direction: "ingress", EtherType: pointer.String("IPv4"), Protocol: pointer.String("tcp"), PortRangeMin: pointer.Int(port), PortRangeMax: pointer.Int(port)}
+	}
+	observed := infrav1.SecurityGroupStatus{
+		ID:   "idSG",
+		Name: "worker",
+		Rules: []infrav1.SecurityGroupRuleStatus{
+			// The SSH rule exists, but was created before the rules were tagged.
+			{ID: "idSSH", Description: pointer.String("SSH"), Direction: "ingress", EtherType: pointer.String("IPv4"), Protocol: pointer.String("tcp"), PortRangeMin: pointer.Int(22), PortRangeMax: pointer.Int(22)},
+			staleRule("idOwned", 8080),
+			staleRule("idForeign", 8081),
+		},
+	}
+
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	m.ListSecGroupRuleWithTags(rules.ListOpts{SecGroupID: "idSG"}, tags).Return([]rules.SecGroupRule{{ID: "idOwned"}}, nil)
+	// The untagged SSH rule is adopted, and the HTTPS rule created with the tags.
+	m.ReplaceAllAttributesTags(secGroupRuleResource, "idSSH", attributestags.ReplaceAllOpts{Tags: tags}).Return(tags, nil)
+	m.CreateSecGroupRule(gomock.Any()).DoAndReturn(func(opts rules.CreateOptsBuilder) (*rules.SecGroupRule, error) {
+		createOpts := opts.(rules.CreateOpts)
+		g.Expect(createOpts.Description).To(Equal("HTTPS"))
+		return &rules.SecGroupRule{ID: "idHTTPS", SecGroupID: createOpts.SecGroupID, Description: createOpts.Description}, nil
+	})
+	m.ReplaceAllAttributesTags(secGroupRuleResource, "idHTTPS", attributestags.ReplaceAllOpts{Tags: tags}).Return(tags, nil)
+	// Only the stale rule carrying the tags is deleted.
+	m.DeleteSecGroupRule("idOwned").Return(nil)
+
+	sgStatus, err := s.reconcileGroupRules(context.TODO(), desired, observed)
+	g.Expect(err).NotTo(HaveOccurred())
+	var ruleIDs []string
+	for _, rule := range sgStatus.Rules {
+		ruleIDs = append(ruleIDs, rule.ID)
+	}
+	g.Expect(ruleIDs).To(ConsistOf("idSSH", "idHTTPS"))
+}
+
+func TestReconcileGroupRulesTagFailure(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	tags := []string{"cluster-tag"}
+	desired := securityGroupSpec{
+		Name:     "worker",
+		Rules:    []resolvedSecurityGroupRuleSpec{{Description: "SSH", Direction: "ingress", EtherType: "IPv4", Protocol: "tcp", PortRangeMin: 22, PortRangeMax: 22}},
+		RuleTags: tags,
+	}
+
+	// The rule is created, but can't be tagged: the error is returned, and the next pass adopts the rule.
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	m.ListSecGroupRuleWithTags(rules.ListOpts{SecGroupID: "idSG"}, tags).Return(nil, nil)
+	m.CreateSecGroupRule(gomock.Any()).Return(&rules.SecGroupRule{ID: "idSSH", SecGroupID: "idSG"}, nil)
+	m.ReplaceAllAttributesTags(secGroupRuleResource, "idSSH", attributestags.ReplaceAllOpts{Tags: tags}).Return(nil, gophercloud.ErrDefault500{})
+
+	_, err = s.reconcileGroupRules(context.TODO(), desired, infrav1.SecurityGroupStatus{ID: "idSG", Name: "worker"})
+	g.Expect(err).To(MatchError(ContainSubstring("tagging rule idSSH")))
+}
+
+func TestReconcileGroupRulesBulkCreate(t *testing.T) {
+	desired := securityGroupSpec{
+		Name: "worker",
+		Rules: []resolvedSecurityGroupRuleSpec{
+			{Description: "SSH", Direction: "ingress", EtherType: "IPv4", Protocol: "tcp", PortRangeMin: 22, PortRangeMax: 22},
+			{Description: "HTTPS", Direction: "ingress", EtherType: "IPv4", Protocol: "tcp", PortRangeMin: 443, PortRangeMax: 443},
+		},
+	}
+	observed := infrav1.SecurityGroupStatus{ID: "idSG", Name: "worker"}
+
+	createdRule := func(id string, opts rules.CreateOpts) rules.SecGroupRule {
+		return rules.SecGroupRule{
+			ID:           id,
+			SecGroupID:   opts.SecGroupID,
+			Description:  opts.Description,
+			Direction:    string(opts.Direction),
+			EtherType:    string(opts.EtherType),
+			Protocol:     string(opts.Protocol),
+			PortRangeMin: opts.PortRangeMin,
+			PortRangeMax: opts.PortRangeMax,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		expect      func(m *mock.MockNetworkClientMockRecorder)
+		wantErr     bool
+		wantRuleIDs []string
+	}{
+		{
+			name: "Rules are created with a single request",
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.CreateSecGroupRules(gomock.Any()).DoAndReturn(func(opts []rules.CreateOpts) ([]rules.SecGroupRule, error) {
+					return []rules.SecGroupRule{createdRule("idSSH", opts[0]), createdRule("idHTTPS", opts[1])}, nil
+				})
+			},
+			wantRuleIDs: []string{"idSSH", "idHTTPS"},
+		},
+		{
+			name: "Rules are created one at a time when the bulk request is refused",
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.CreateSecGroupRules(gomock.Any()).Return(nil, gophercloud.ErrDefault400{
+					ErrUnexpectedResponseCode: gophercloud.ErrUnexpectedResponseCode{Actual: 400},
+				})
+				m.CreateSecGroupRule(gomock.Any()).DoAndReturn(func(opts rules.CreateOptsBuilder) (*rules.SecGroupRule, error) {
+					createOpts := opts.(rules.CreateOpts)
+					rule := createdRule("id"+createOpts.Description, createOpts)
+					return &rule, nil
+				}).Times(2)
+			},
+			wantRuleIDs: []string{"idSSH", "idHTTPS"},
+		},
+		{
+			name: "Server error of the bulk request is returned",
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.CreateSecGroupRules(gomock.Any()).Return(nil, gophercloud.ErrDefault500{})
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+
+			tt.expect(mockScopeFactory.NetworkClient.EXPECT())
+
+			sgStatus, err := s.reconcileGroupRules(context.TODO(), desired, observed)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			ruleIDs := make([]string, len(sgStatus.Rules))
+			for i := range sgStatus.Rules {
+				ruleIDs[i] = sgStatus.Rules[i].ID
+			}
+			g.Expect(ruleIDs).To(ConsistOf(tt.wantRuleIDs))
+		})
+	}
+}
+
+func TestReconcileGroupRulesDeletionGracePeriod(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+	s.secGroupRuleDeletionGracePeriod = time.Minute
+	markedTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fakeClock := testingclock.NewFakePassiveClock(markedTime)
+	s.clock = fakeClock
+
+	desired := securityGroupSpec{
+		Name: "worker",
+		Rules: []resolvedSecurityGroupRuleSpec{
+			{Description: "HTTPS", Direction: "ingress", EtherType: "IPv4", Protocol: "tcp", PortRangeMin: 443, PortRangeMax: 443},
+		},
+	}
+	oldRule := infrav1.SecurityGroupRuleStatus{
+		ID:           "idOld",
+		Description:  pointer.String("HTTP"),
+		Direction:    "ingress",
+		EtherType:    pointer.String("IPv4"),
+		Protocol:     pointer.String("tcp"),
+		PortRangeMin: pointer.Int(80),
+		PortRangeMax: pointer.Int(80),
+	}
+	newRule := infrav1.SecurityGroupRuleStatus{
+		ID:             "idNew",
+		Description:    pointer.String("HTTPS"),
+		Direction:      "ingress",
+		EtherType:      pointer.String("IPv4"),
+		Protocol:       pointer.String("tcp"),
+		PortRangeMin:   pointer.Int(443),
+		PortRangeMax:   pointer.Int(443),
+		RemoteGroupID:  pointer.String(""),
+		RemoteIPPrefix: pointer.String(""),
+	}
+	wantPending := []infrav1.SecurityGroupRulePendingDeletion{{ID: "idOld", MarkedTime: metav1.Time{Time: markedTime}}}
+	m := mockScopeFactory.NetworkClient.EXPECT()
+
+	// The first pass creates the replacement rule and defers the deletion of the old one.
+	m.CreateSecGroupRule(gomock.Any()).Return(&rules.SecGroupRule{
+		ID:           "idNew",
+		Description:  "HTTPS",
+		Direction:    "ingress",
+		EtherType:    "IPv4",
+		Protocol:     "tcp",
+		PortRangeMin: 443,
+		PortRangeMax: 443,
+	}, nil)
+	sgStatus, err := s.reconcileGroupRules(context.TODO(), desired, infrav1.SecurityGroupStatus{ID: "idSG", Name: "worker", Rules: []infrav1.SecurityGroupRuleStatus{oldRule}})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sgStatus.Rules).To(Equal([]infrav1.SecurityGroupRuleStatus{newRule}))
+	g.Expect(sgStatus.RulesPendingDeletion).To(Equal(wantPending))
+
+	// Before the grace period elapsed, the deletion stays deferred and keeps its marked time.
+	fakeClock.SetTime(markedTime.Add(30 * time.Second))
+	observed := infrav1.SecurityGroupStatus{ID: "idSG", Name: "worker", Rules: []infrav1.SecurityGroupRuleStatus{oldRule, newRule}, RulesPendingDeletion: sgStatus.RulesPendingDeletion}
+	sgStatus, err = s.reconcileGroupRules(context.TODO(), desired, observed)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sgStatus.RulesPendingDeletion).To(Equal(wantPending))
+
+	// After it, the deletion is still deferred while the replacement rule is missing.
+	fakeClock.SetTime(markedTime.Add(time.Minute))
+	observed = infrav1.SecurityGroupStatus{ID: "idSG", Name: "worker", Rules: []infrav1.SecurityGroupRuleStatus{oldRule}, RulesPendingDeletion: sgStatus.RulesPendingDeletion}
+	m.CreateSecGroupRule(gomock.Any()).Return(&rules.SecGroupRule{
+		ID:           "idNew",
+		Description:  "HTTPS",
+		Direction:    "ingress",
+		EtherType:    "IPv4",
+		Protocol:     "tcp",
+		PortRangeMin: 443,
+		PortRangeMax: 443,
+	}, nil)
+	sgStatus, err = s.reconcileGroupRules(context.TODO(), desired, observed)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sgStatus.RulesPendingDeletion).To(Equal(wantPending))
+
+	// The rule is deleted by the next pass finding the replacement rule present.
+	observed = infrav1.SecurityGroupStatus{ID: "idSG", Name: "worker", Rules: []infrav1.SecurityGroupRuleStatus{oldRule, newRule}, RulesPendingDeletion: sgStatus.RulesPendingDeletion}
+	m.DeleteSecGroupRule("idOld").Return(nil)
+	sgStatus, err = s.reconcileGroupRules(context.TODO(), desired, observed)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sgStatus.Rules).To(Equal([]infrav1.SecurityGroupRuleStatus{newRule}))
+	g.Expect(sgStatus.RulesPendingDeletion).To(BeEmpty())
+}
+
+func TestGetChangedSecGroupsPendingDeletion(t *testing.T) {
+	g := NewWithT(t)
+
+	desiredSecGroups := map[string]securityGroupSpec{
+		controlPlaneSuffix: {Name: "k8s-cluster-mycluster-secgroup-controlplane", Rules: defaultRules},
+		workerSuffix:       {Name: "k8s-cluster-mycluster-secgroup-worker", Rules: defaultRules},
+	}
+	_, rulesHashes, err := getChangedSecGroups(desiredSecGroups, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	previousSecGroups := map[string]*infrav1.SecurityGroupStatus{
+		controlPlaneSuffix: {RulesHash: rulesHashes[controlPlaneSuffix]},
+		workerSuffix: {
+			RulesHash:            rulesHashes[workerSuffix],
+			RulesPendingDeletion: []infrav1.SecurityGroupRulePendingDeletion{{ID: "idOld"}},
+		},
+	}
+	changed, _, err := getChangedSecGroups(desiredSecGroups, previousSecGroups)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(changed).To(Equal(map[string]bool{workerSuffix: true}))
+}
+
+func TestWithSecurityGroupClientTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantTimeout time.Duration
+		wantErr     bool
+	}{
+		{
+			name: "No annotation",
+		},
+		{
+			name:        "Valid timeout",
+			annotations: map[string]string{infrav1.SecurityGroupClientTimeoutAnnotation: "2m"},
+			wantTimeout: 2 * time.Minute,
+		},
+		{
+			name:        "Invalid timeout",
+			annotations: map[string]string{infrav1.SecurityGroupClientTimeoutAnnotation: "two minutes"},
+			wantErr:     true,
+		},
+		{
+			name:        "Negative timeout",
+			annotations: map[string]string{infrav1.SecurityGroupClientTimeoutAnnotation: "-1s"},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+
+			openStackCluster := &infrav1.OpenStackCluster{}
+			openStackCluster.Annotations = tt.annotations
+			ctx, cancel, err := s.withSecurityGroupClientTimeout(context.TODO(), openStackCluster)
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+				return
+			}
+			g.Expect(err).NotTo(HaveOccurred())
+			deadline, ok := ctx.Deadline()
+			g.Expect(ok).To(Equal(tt.wantTimeout > 0))
+			if ok {
+				g.Expect(time.Until(deadline)).To(BeNumerically("~", tt.wantTimeout, time.Second))
+			}
+
+			// The context is done once released.
+			cancel()
+			if ok {
+				g.Expect(ctx.Err()).To(MatchError(context.Canceled))
+			}
+		})
+	}
+}
+
+func TestGenerateDesiredSecGroupsAllNodesRulesResolvedOnce(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	secGroupNames := map[string]string{
+		controlPlaneSuffix: "k8s-cluster-mycluster-secgroup-controlplane",
+		workerSuffix:       "k8s-cluster-mycluster-secgroup-worker",
+	}
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{
+				AllNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
+					{
+						Name:                "BGP",
+						Description:         pointer.String("BGP"),
+						Direction:           "ingress",
+						EtherType:           pointer.String("IPv4"),
+						Protocol:            pointer.String("tcp"),
+						PortRangeMin:        pointer.Int(179),
+						PortRangeMax:        pointer.Int(179),
+						RemoteManagedGroups: []infrav1.ManagedSecurityGroupName{"controlplane", "worker"},
+					},
+				},
+			},
+		},
+	}
+
+	// Each managed group is resolved to its ID exactly once.
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	m.ListSecGroup(groups.ListOpts{Name: secGroupNames[controlPlaneSuffix]}).Return([]groups.SecGroup{{ID: "idControlPlane", Name: secGroupNames[controlPlaneSuffix]}}, nil).Times(1)
+	m.ListSecGroup(groups.ListOpts{Name: secGroupNames[workerSuffix]}).Return([]groups.SecGroup{{ID: "idWorker", Name: secGroupNames[workerSuffix]}}, nil).Times(1)
+
+	desiredSecGroups, err := s.generateDesiredSecGroups(openStackCluster, secGroupNames)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	wantAllNodesRules := []resolvedSecurityGroupRuleSpec{
+		{Description: "BGP (controlplane)", Direction: "ingress", EtherType: "IPv4", Protocol: "tcp", PortRangeMin: 179, PortRangeMax: 179, RemoteGroupID: "idControlPlane", Origin: infrav1.SecurityGroupRuleOriginUser},
+		{Description: "BGP (worker)", Direction: "ingress", EtherType: "IPv4", Protocol: "tcp", PortRangeMin: 179, PortRangeMax: 179, RemoteGroupID: "idWorker", Origin: infrav1.SecurityGroupRuleOriginUser},
+	}
+	for _, k := range []string{controlPlaneSuffix, workerSuffix} {
+		var bgpRules []resolvedSecurityGroupRuleSpec
+		for _, rule := range desiredSecGroups[k].Rules {
+			if strings.HasPrefix(rule.Description, "BGP (") {
+				bgpRules = append(bgpRules, rule)
+			}
+		}
+		g.Expect(bgpRules).To(Equal(wantAllNodesRules), "allNodes rules of the %s group", k)
+	}
+}
+
+func TestGenerateDesiredSecGroupsRuleOrigin(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	secGroupNames := map[string]string{
+		controlPlaneSuffix: "k8s-cluster-mycluster-secgroup-controlplane",
+		workerSuffix:       "k8s-cluster-mycluster-secgroup-worker",
+		bastionSuffix:      "k8s-cluster-mycluster-secgroup-bastion",
+	}
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	m.ListSecGroup(groups.ListOpts{Name: secGroupNames[controlPlaneSuffix]}).Return([]groups.SecGroup{{ID: "idControlPlane"}}, nil)
+	m.ListSecGroup(groups.ListOpts{Name: secGroupNames[workerSuffix]}).Return([]groups.SecGroup{{ID: "idWorker"}}, nil)
+	m.ListSecGroup(groups.ListOpts{Name: secGroupNames[bastionSuffix]}).Return([]groups.SecGroup{{ID: "idBastion"}}, nil)
+
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{
+				AllNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
+					{
+						Name:                "BGP",
+						Description:         pointer.String("BGP"),
+						Direction:           "ingress",
+						EtherType:           pointer.String("IPv4"),
+						Protocol:            pointer.String("tcp"),
+						PortRangeMin:        pointer.Int(179),
+						PortRangeMax:        pointer.Int(179),
+						RemoteManagedGroups: []infrav1.ManagedSecurityGroupName{"worker"},
+					},
+				},
+			},
+			Bastion: &infrav1.Bastion{Enabled: true},
+		},
+	}
+	desiredSecGroups, err := s.generateDesiredSecGroups(openStackCluster, secGroupNames)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	wantOrigins := map[string]infrav1.SecurityGroupRuleOrigin{
+		"Full open":      infrav1.SecurityGroupRuleOriginDefault,
+		"Kubernetes API": infrav1.SecurityGroupRuleOriginGeneral,
+		"Kubelet API":    infrav1.SecurityGroupRuleOriginGeneral,
+		"BGP":            infrav1.SecurityGroupRuleOriginUser,
+		"SSH":            infrav1.SecurityGroupRuleOriginBastion,
+	}
+	for k, group := range desiredSecGroups {
+		for _, rule := range group.Rules {
+			g.Expect(rule.Origin).NotTo(BeEmpty(), "rule %q of the %s group", rule.Description, k)
+			if origin, ok := wantOrigins[rule.Description]; ok {
+				g.Expect(rule.Origin).To(Equal(origin), "rule %q of the %s group", rule.Description, k)
+			}
+		}
+	}
+}
+
+func TestReconcileGroupRulesOrigin(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	desired := securityGroupSpec{
+		Name: "worker",
+		Rules: []resolvedSecurityGroupRuleSpec{
+			{Description: "Full open", Direction: "egress", EtherType: "IPv4", Origin: infrav1.SecurityGroupRuleOriginDefault},
+			{Description: "BGP", Direction: "ingress", EtherType: "IPv4", Protocol: "tcp", PortRangeMin: 179, PortRangeMax: 179, Origin: infrav1.SecurityGroupRuleOriginUser},
+		},
+	}
+	observed := infrav1.SecurityGroupStatus{
+		ID:   "idSG",
+		Name: "worker",
+		Rules: []infrav1.SecurityGroupRuleStatus{
+			{
+				ID:             "idEgress",
+				Description:    pointer.String("Full open"),
+				Direction:      "egress",
+				EtherType:      pointer.String("IPv4"),
+				Protocol:       pointer.String(""),
+				PortRangeMin:   pointer.Int(0),
+				PortRangeMax:   pointer.Int(0),
+				RemoteGroupID:  pointer.String(""),
+				RemoteIPPrefix: pointer.String(""),
+			},
+		},
+	}
+
+	mockScopeFactory.NetworkClient.EXPECT().CreateSecGroupRule(gomock.Any()).Return(&rules.SecGroupRule{
+		ID:           "idBGP",
+		Description:  "BGP",
+		Direction:    "ingress",
+		EtherType:    "IPv4",
+		Protocol:     "tcp",
+		PortRangeMin: 179,
+		PortRangeMax: 179,
+	}, nil)
+
+	sgStatus, err := s.reconcileGroupRules(context.TODO(), desired, observed)
+	g.Expect(err).NotTo(HaveOccurred())
+	origins := map[string]infrav1.SecurityGroupRuleOrigin{}
+	for _, rule := range sgStatus.Rules {
+		origins[rule.ID] = rule.Origin
+	}
+	g.Expect(origins).To(Equal(map[string]infrav1.SecurityGroupRuleOrigin{
+		"idEgress": infrav1.SecurityGroupRuleOriginDefault,
+		"idBGP":    infrav1.SecurityGroupRuleOriginUser,
+	}))
+}
+
+func TestGenerateDesiredSecGroupsAllowAllDualStack(t *testing.T) {
+	secGroupNames := map[string]string{
+		controlPlaneSuffix: "k8s-cluster-mycluster-secgroup-controlplane",
+		workerSuffix:       "k8s-cluster-mycluster-secgroup-worker",
+	}
+	secGroupIDs := map[string]string{
+		controlPlaneSuffix: "idControlPlane",
+		workerSuffix:       "idWorker",
+	}
+
+	tests := []struct {
+		name           string
+		subnets        []infrav1.Subnet
+		wantEtherTypes []string
+	}{
+		{
+			name:           "IPv4 cluster",
+			subnets:        []infrav1.Subnet{{CIDR: "10.0.0.0/24"}},
+			wantEtherTypes: []string{"IPv4"},
+		},
+		{
+			name:           "Dual-stack cluster",
+			subnets:        []infrav1.Subnet{{CIDR: "10.0.0.0/24"}, {CIDR: "2001:db8::/64"}},
+			wantEtherTypes: []string{"IPv4", "IPv6"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+
+			m := mockScopeFactory.NetworkClient.EXPECT()
+			for k, name := range secGroupNames {
+				m.ListSecGroup(groups.ListOpts{Name: name}).Return([]groups.SecGroup{{ID: secGroupIDs[k], Name: name}}, nil)
+			}
+
+			openStackCluster := &infrav1.OpenStackCluster{
+				Spec: infrav1.OpenStackClusterSpec{
+					ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{AllowAllInClusterTraffic: true},
+				},
+				Status: infrav1.OpenStackClusterStatus{
+					Network: &infrav1.NetworkStatusWithSubnets{Subnets: tt.subnets},
+				},
+			}
+			desiredSecGroups, err := s.generateDesiredSecGroups(openStackCluster, secGroupNames)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			peers := map[string]string{
+				controlPlaneSuffix: secGroupIDs[workerSuffix],
+				workerSuffix:       secGroupIDs[controlPlaneSuffix],
+			}
+			for k, peerID := range peers {
+				var inClusterRules []resolvedSecurityGroupRuleSpec
+				for _, rule := range desiredSecGroups[k].Rules {
+					if rule.Description == "In-cluster Ingress" {
+						inClusterRules = append(inClusterRules, rule)
+					}
+				}
+				g.Expect(inClusterRules).To(HaveLen(2*len(tt.wantEtherTypes)), "group %s", k)
+				for _, etherType := range tt.wantEtherTypes {
+					for _, remoteGroupID := range []string{remoteGroupIDSelf, peerID} {
+						g.Expect(inClusterRules).To(ContainElement(resolvedSecurityGroupRuleSpec{
+							Description:   "In-cluster Ingress",
+							Direction:     "ingress",
+							EtherType:     etherType,
+							RemoteGroupID: remoteGroupID,
+							Origin:        infrav1.SecurityGroupRuleOriginGeneral,
+						}), "group %s", k)
+					}
+				}
+				// The egress is open for both families regardless of the cluster network.
+				g.Expect(desiredSecGroups[k].Rules).To(ContainElements(withRuleOrigin(defaultRules, infrav1.SecurityGroupRuleOriginDefault)), "group %s", k)
+			}
+		})
+	}
+}
+
+func TestGenerateDesiredSecGroupsExternallyManagedRouter(t *testing.T) {
+	secGroupNames := map[string]string{
+		controlPlaneSuffix: "k8s-cluster-mycluster-secgroup-controlplane",
+		workerSuffix:       "k8s-cluster-mycluster-secgroup-worker",
+	}
+	newCluster := func() *infrav1.OpenStackCluster {
+		return &infrav1.OpenStackCluster{
+			Spec: infrav1.OpenStackClusterSpec{
+				ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{
+					AllowLoadBalancerServiceTraffic: true,
+				},
+			},
+			Status: infrav1.OpenStackClusterStatus{
+				Network: &infrav1.NetworkStatusWithSubnets{
+					Subnets: []infrav1.Subnet{{CIDR: "10.6.0.0/24"}},
+				},
+			},
+		}
+	}
+	services := []corev1.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+			Spec: corev1.ServiceSpec{
+				Type:  corev1.ServiceTypeLoadBalancer,
+				Ports: []corev1.ServicePort{{Port: 443, NodePort: 30443, Protocol: corev1.ProtocolTCP}},
+			},
+		},
+	}
+
+	generate := func(g *WithT, openStackCluster *infrav1.OpenStackCluster) map[string]securityGroupSpec {
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+
+		mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+		s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+		g.Expect(err).NotTo(HaveOccurred())
+		s.SetLoadBalancerServices(services)
+
+		m := mockScopeFactory.NetworkClient.EXPECT()
+		m.ListSecGroup(groups.ListOpts{Name: secGroupNames[controlPlaneSuffix]}).Return([]groups.SecGroup{{ID: "idControlPlane"}}, nil)
+		m.ListSecGroup(groups.ListOpts{Name: secGroupNames[workerSuffix]}).Return([]groups.SecGroup{{ID: "idWorker"}}, nil)
+
+		desiredSecGroups, err := s.generateDesiredSecGroups(openStackCluster, secGroupNames)
+		g.Expect(err).NotTo(HaveOccurred())
+		return desiredSecGroups
+	}
+
+	g := NewWithT(t)
+
+	// The router is pre-existing, and its gateway is on a network the cluster knows nothing about.
+	externalRouterCluster := newCluster()
+	externalRouterCluster.Spec.Router = &infrav1.RouterFilter{Name: "shared-router"}
+	externalRouterCluster.Status.Router = &infrav1.Router{Name: "shared-router", ID: "idRouter", IPs: []string{"203.0.113.10"}}
+
+	desiredSecGroups := generate(g, externalRouterCluster)
+	g.Expect(desiredSecGroups).To(Equal(generate(g, newCluster())), "rules must not depend on the router")
+
+	// Source CIDRs are derived from the cluster subnets.
+	var remoteIPPrefixes []string
+	for _, rule := range desiredSecGroups[workerSuffix].Rules {
+		if rule.RemoteIPPrefix != "" {
+			remoteIPPrefixes = append(remoteIPPrefixes, rule.RemoteIPPrefix)
+		}
+	}
+	g.Expect(remoteIPPrefixes).To(Equal([]string{"10.6.0.0/24"}))
+}
+
+func TestCreateSecurityGroupIfNotExistsConcurrentTags(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const groupName = "k8s-cluster-mycluster-secgroup-worker"
+	// The group was listed by both reconciles before either edited its tags, and another tool tagged it since.
+	listedGroup := groups.SecGroup{ID: "idWorker", Name: groupName, Description: "worker", Tags: []string{"cluster"}}
+	var mu sync.Mutex
+	tags := map[string]bool{"cluster": true, "other-tool": true}
+
+	newService := func() *Service {
+		mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+		s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+		g.Expect(err).NotTo(HaveOccurred())
+
+		m := mockScopeFactory.NetworkClient.EXPECT()
+		m.ListSecGroup(groups.ListOpts{Name: groupName}).Return([]groups.SecGroup{listedGroup}, nil)
+		m.AddAttributesTag("security-groups", "idWorker", gomock.Any()).DoAndReturn(func(_, _, tag string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			tags[tag] = true
+			return nil
+		}).AnyTimes()
+		return s
+	}
+
+	// The reconciles have different tags, e.g. because the spec was edited in between.
+	clusters := []*infrav1.OpenStackCluster{
+		{Spec: infrav1.OpenStackClusterSpec{Tags: []string{"cluster", "team-a"}}},
+		{Spec: infrav1.OpenStackClusterSpec{Tags: []string{"cluster", "team-b", "env"}}},
+	}
+	services := []*Service{newService(), newService()}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(clusters))
+	for i := range clusters {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = services[i].createSecurityGroupIfNotExists(context.TODO(), clusters[i], groupName, "worker", nil)
+		}(i)
+	}
+	wg.Wait()
+
+	g.Expect(errs).To(HaveEach(BeNil()))
+	g.Expect(tags).To(Equal(map[string]bool{
+		"cluster":    true,
+		"other-tool": true,
+		"team-a":     true,
+		"team-b":     true,
+		"env":        true,
+	}))
+}
+
+func TestAddSecurityGroupTags(t *testing.T) {
+	tests := []struct {
+		name      string
+		tags      []string
+		groupTags []string
+		expect    func(m *mock.MockNetworkClientMockRecorder)
+	}{
+		{
+			name:   "Cluster without tags",
+			expect: func(m *mock.MockNetworkClientMockRecorder) {},
+		},
+		{
+			name:      "Group with all the tags",
+			tags:      []string{"a", "b"},
+			groupTags: []string{"b", "a", "c"},
+			expect:    func(m *mock.MockNetworkClientMockRecorder) {},
+		},
+		{
+			name:      "Only missing tags are added, other tags are kept",
+			tags:      []string{"a", "b"},
+			groupTags: []string{"a", "c"},
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.AddAttributesTag("security-groups", "idWorker", "b").Return(nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+
+			tt.expect(mockScopeFactory.NetworkClient.EXPECT())
+
+			openStackCluster := &infrav1.OpenStackCluster{Spec: infrav1.OpenStackClusterSpec{Tags: tt.tags}}
+			group := &groups.SecGroup{ID: "idWorker", Tags: tt.groupTags}
+			g.Expect(s.addSecurityGroupTags(openStackCluster, group)).To(Succeed())
+		})
+	}
+}
+
+// overlappingSecGroups returns the ListSecGroup of a client for which the filter with the tag tag-i matches the
+// groups id-i to id-(i+size-1), so that the groups matched by consecutive filters overlap.
+func overlappingSecGroups(size int) func(groups.ListOpts) ([]groups.SecGroup, error) {
+	return func(opts groups.ListOpts) ([]groups.SecGroup, error) {
+		var first int
+		if _, err := fmt.Sscanf(opts.Tags, "tag-%d", &first); err != nil {
+			return nil, err
+		}
+		secGroups := make([]groups.SecGroup, 0, size)
+		for i := first; i < first+size; i++ {
+			secGroups = append(secGroups, groups.SecGroup{ID: fmt.Sprintf("id-%d", i)})
+		}
+		return secGroups, nil
+	}
+}
+
+func overlappingSecGroupFilters(count int) []infrav1.SecurityGroupFilter {
+	filters := make([]infrav1.SecurityGroupFilter, 0, count)
+	for i := 0; i < count; i++ {
+		filters = append(filters, infrav1.SecurityGroupFilter{FilterByNeutronTags: infrav1.FilterByNeutronTags{Tags: []infrav1.NeutronTag{infrav1.NeutronTag(fmt.Sprintf("tag-%d", i))}}})
+	}
+	return filters
+}
+
+func TestGetSecurityGroupsOverlappingFilters(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	const (
+		filtersCount = 100
+		groupsSize   = 100
+	)
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+	mockScopeFactory.NetworkClient.EXPECT().ListSecGroup(gomock.Any()).DoAndReturn(overlappingSecGroups(groupsSize)).Times(filtersCount)
+
+	// Explicit IDs are de-duplicated along with the groups matched by the filters.
+	filters := append([]infrav1.SecurityGroupFilter{{ID: "id-50"}}, overlappingSecGroupFilters(filtersCount)...)
+	filters = append(filters, infrav1.SecurityGroupFilter{ID: "id-50"}, infrav1.SecurityGroupFilter{ID: "id-explicit"})
+
+	sgIDs, err := s.GetSecurityGroups(filters)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	want := []string{"id-50"}
+	for i := 0; i < filtersCount+groupsSize-1; i++ {
+		if i != 50 {
+			want = append(want, fmt.Sprintf("id-%d", i))
+		}
+	}
+	want = append(want, "id-explicit")
+	g.Expect(sgIDs).To(Equal(want))
+}
+
+func TestGetSecurityGroupsByTags(t *testing.T) {
+	filter := infrav1.SecurityGroupFilter{
+		FilterByNeutronTags: infrav1.FilterByNeutronTags{
+			Tags:    []infrav1.NeutronTag{"env-a", "capo"},
+			TagsAny: []infrav1.NeutronTag{"web", "db"},
+			NotTags: []infrav1.NeutronTag{"legacy"},
+		},
+	}
+	wantListOpts := groups.ListOpts{
+		ProjectID: "project-id",
+		Tags:      "env-a,capo",
+		TagsAny:   "web,db",
+		NotTags:   "legacy",
+	}
+
+	t.Run("groups matching the tags are returned", func(t *testing.T) {
+		g := NewWithT(t)
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "project-id")
+		s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+		g.Expect(err).NotTo(HaveOccurred())
+		mockScopeFactory.NetworkClient.EXPECT().ListSecGroup(wantListOpts).Return([]groups.SecGroup{{ID: "sg-web"}, {ID: "sg-db"}}, nil)
+
+		sgIDs, err := s.GetSecurityGroups([]infrav1.SecurityGroupFilter{filter})
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(sgIDs).To(Equal([]string{"sg-web", "sg-db"}))
+	})
+
+	t.Run("no group matches the tags", func(t *testing.T) {
+		g := NewWithT(t)
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "project-id")
+		s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+		g.Expect(err).NotTo(HaveOccurred())
+		mockScopeFactory.NetworkClient.EXPECT().ListSecGroup(wantListOpts).Return(nil, nil)
+
+		_, err = s.GetSecurityGroups([]infrav1.SecurityGroupFilter{filter})
+		g.Expect(err).To(MatchError(ContainSubstring("no security group found matching filter")))
+		g.Expect(err).To(MatchError(ContainSubstring("env-a")))
+		var notFoundErr *SecurityGroupNotFoundError
+		g.Expect(errors.As(err, &notFoundErr)).To(BeTrue())
+	})
+}
+
+func TestGetSecurityGroupsByDescription(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "project-id")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+	mockScopeFactory.NetworkClient.EXPECT().ListSecGroup(groups.ListOpts{Description: "dmz", ProjectID: "project-id"}).Return([]groups.SecGroup{{ID: "sg-dmz"}}, nil)
+
+	sgIDs, err := s.GetSecurityGroups([]infrav1.SecurityGroupFilter{{Description: "dmz"}})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sgIDs).To(Equal([]string{"sg-dmz"}))
+}
+
+func TestGetSecurityGroupsErrors(t *testing.T) {
+	filter := infrav1.SecurityGroupFilter{Name: "my-group"}
+
+	tests := []struct {
+		name         string
+		expect       func(m *mock.MockNetworkClientMockRecorder)
+		wantNotFound bool
+	}{
+		{
+			name: "Group not found",
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Name: "my-group", ProjectID: "project-id"}).Return(nil, nil)
+			},
+			wantNotFound: true,
+		},
+		{
+			name: "Neutron API failure",
+			expect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Name: "my-group", ProjectID: "project-id"}).Return(nil, gophercloud.ErrDefault500{})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "project-id")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+			tt.expect(mockScopeFactory.NetworkClient.EXPECT())
+
+			_, err = s.GetSecurityGroups([]infrav1.SecurityGroupFilter{filter})
+			g.Expect(err).To(HaveOccurred())
+			var notFoundErr *SecurityGroupNotFoundError
+			g.Expect(errors.As(err, &notFoundErr)).To(Equal(tt.wantNotFound))
+			if tt.wantNotFound {
+				g.Expect(notFoundErr.Filter).To(Equal(filter))
+				g.Expect(err).To(MatchError("security group my-group not found"))
+			}
+		})
+	}
+}
+
+// BenchmarkGetSecurityGroupsOverlappingFilters resolves filters matching many overlapping groups. The time per
+// operation grows linearly with the number of matched groups.
+func BenchmarkGetSecurityGroupsOverlappingFilters(b *testing.B) {
+	mockCtrl := gomock.NewController(b)
+	defer mockCtrl.Finish()
+
+	const (
+		filtersCount = 200
+		groupsSize   = 1000
+	)
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, logr.Discard()), DefaultOptions())
+	if err != nil {
+		b.Fatal(err)
+	}
+	mockScopeFactory.NetworkClient.EXPECT().ListSecGroup(gomock.Any()).DoAndReturn(overlappingSecGroups(groupsSize)).AnyTimes()
+	filters := overlappingSecGroupFilters(filtersCount)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := s.GetSecurityGroups(filters); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestGetWellKnownPorts(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(getWellKnownPorts(&infrav1.OpenStackCluster{})).To(Equal(defaultWellKnownPorts))
+
+	ports := getWellKnownPorts(&infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{
+				WellKnownPorts: []infrav1.WellKnownPort{
+					{Name: infrav1.WellKnownPortKubelet, PortRangeMin: 10255},
+					{Name: infrav1.WellKnownPortNodePorts, PortRangeMin: 20000, PortRangeMax: pointer.Int(22767)},
+				},
+			},
+		},
+	})
+	g.Expect(ports[infrav1.WellKnownPortKubelet]).To(Equal(wellKnownPort{portRangeMin: 10255, portRangeMax: 10255, protocols: []string{"tcp"}}))
+	g.Expect(ports[infrav1.WellKnownPortNodePorts]).To(Equal(wellKnownPort{portRangeMin: 20000, portRangeMax: 22767, protocols: []string{"tcp", "udp"}}))
+	g.Expect(ports[infrav1.WellKnownPortEtcd]).To(Equal(defaultWellKnownPorts[infrav1.WellKnownPortEtcd]))
+	// The defaults are not modified by the overrides.
+	g.Expect(defaultWellKnownPorts[infrav1.WellKnownPortKubelet].portRangeMin).To(Equal(10250))
+}
+
+func TestGenerateDesiredSecGroupsWellKnownPorts(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	secGroupNames := map[string]string{
+		controlPlaneSuffix: "k8s-cluster-mycluster-secgroup-controlplane",
+		workerSuffix:       "k8s-cluster-mycluster-secgroup-worker",
+	}
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	m.ListSecGroup(groups.ListOpts{Name: secGroupNames[controlPlaneSuffix]}).Return([]groups.SecGroup{{ID: "idControlPlane"}}, nil)
+	m.ListSecGroup(groups.ListOpts{Name: secGroupNames[workerSuffix]}).Return([]groups.SecGroup{{ID: "idWorker"}}, nil)
+
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{
+				WellKnownPorts: []infrav1.WellKnownPort{
+					{Name: infrav1.WellKnownPortKubelet, PortRangeMin: 10255},
+					{Name: infrav1.WellKnownPortNodePorts, PortRangeMin: 20000, PortRangeMax: pointer.Int(22767)},
+				},
+			},
+		},
+	}
+	desiredSecGroups, err := s.generateDesiredSecGroups(openStackCluster, secGroupNames)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	var kubeletRules, nodePortRules int
+	for k, group := range desiredSecGroups {
+		for _, rule := range group.Rules {
+			switch rule.Description {
+			case "Kubelet API":
+				kubeletRules++
+				g.Expect(rule.PortRangeMin).To(Equal(10255), "rule %q of the %s group", rule.Description, k)
+				g.Expect(rule.PortRangeMax).To(Equal(10255), "rule %q of the %s group", rule.Description, k)
+			case "Node Port Services":
+				nodePortRules++
+				g.Expect(k).To(Equal(workerSuffix))
+				g.Expect(rule.PortRangeMin).To(Equal(20000))
+				g.Expect(rule.PortRangeMax).To(Equal(22767))
+			case "Etcd":
+				g.Expect(rule.PortRangeMin).To(Equal(2379))
+				g.Expect(rule.PortRangeMax).To(Equal(2380))
+			}
+		}
+	}
+	// Both groups permit the kubelet of the control plane and of the workers.
+	g.Expect(kubeletRules).To(Equal(4))
+	// The worker group permits the node ports over TCP and UDP.
+	g.Expect(nodePortRules).To(Equal(2))
+}
+
+func TestReconcileSecurityGroupsContext(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	// The server holds the requests until they are aborted.
+	inFlight := make(chan struct{}, 1)
+	aborted := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inFlight <- struct{}{}
+		<-r.Context().Done()
+		aborted <- struct{}{}
+	}))
+	defer server.Close()
+
+	providerClient := &gophercloud.ProviderClient{
+		EndpointLocator: func(gophercloud.EndpointOpts) (string, error) { return server.URL + "/", nil },
+	}
+	networkClient, err := clients.NewNetworkClient(providerClient, &clientconfig.ClientOpts{})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+	s.client = networkClient
+
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.ReconcileSecurityGroups(ctx, openStackCluster, "mycluster")
+	}()
+
+	g.Eventually(inFlight).Should(Receive())
+	// The client of the service isn't bound to the context of the reconcile, which other callers don't share.
+	g.Expect(s.client).To(Equal(networkClient))
+
+	// Cancelling the context aborts the in-flight request to Neutron.
+	cancel()
+	g.Eventually(aborted).Should(Receive())
+	g.Eventually(errCh).Should(Receive(MatchError(context.Canceled)))
+}
+
+func TestReconcileSecurityGroupsPendingRemoteGroup(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	const (
+		controlPlaneName = "k8s-cluster-mycluster-secgroup-controlplane"
+		workerName       = "k8s-cluster-mycluster-secgroup-worker"
+	)
+	controlPlaneGroup := groups.SecGroup{ID: "idControlPlane", Name: controlPlaneName, Description: "Cluster API managed group"}
+	workerGroup := groups.SecGroup{ID: "idWorker", Name: workerName, Description: "Cluster API managed group"}
+
+	// The worker group is created by the first reconcile, but only listable from the second one.
+	workerListable := false
+	// The groups are reconciled concurrently.
+	var mu sync.Mutex
+	createdRules := map[string][]rules.SecGroupRule{}
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	m.ListSecGroup(groups.ListOpts{Name: controlPlaneName}).Return([]groups.SecGroup{controlPlaneGroup}, nil).AnyTimes()
+	m.ListSecGroup(groups.ListOpts{Name: workerName}).DoAndReturn(func(groups.ListOpts) ([]groups.SecGroup, error) {
+		if workerListable {
+			return []groups.SecGroup{workerGroup}, nil
+		}
+		return []groups.SecGroup{}, nil
+	}).AnyTimes()
+	m.CreateSecGroup(gomock.Any()).Return(&workerGroup, nil)
+	m.ListSecGroupRule(gomock.Any()).DoAndReturn(func(opts rules.ListOpts) ([]rules.SecGroupRule, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return createdRules[opts.SecGroupID], nil
+	}).AnyTimes()
+	createRule := func(createOpts rules.CreateOpts) rules.SecGroupRule {
+		mu.Lock()
+		defer mu.Unlock()
+		g.Expect(createOpts.RemoteGroupID).NotTo(HavePrefix(pendingRemoteGroupIDPrefix))
+		rule := rules.SecGroupRule{
+			ID:             fmt.Sprintf("idRule%d", len(createdRules[createOpts.SecGroupID])),
+			SecGroupID:     createOpts.SecGroupID,
+			Description:    createOpts.Description,
+			Direction:      string(createOpts.Direction),
+			EtherType:      string(createOpts.EtherType),
+			PortRangeMin:   createOpts.PortRangeMin,
+			PortRangeMax:   createOpts.PortRangeMax,
+			Protocol:       string(createOpts.Protocol),
+			RemoteGroupID:  createOpts.RemoteGroupID,
+			RemoteIPPrefix: createOpts.RemoteIPPrefix,
+		}
+		createdRules[createOpts.SecGroupID] = append(createdRules[createOpts.SecGroupID], rule)
+		return rule
+	}
+	m.CreateSecGroupRule(gomock.Any()).DoAndReturn(func(opts rules.CreateOptsBuilder) (*rules.SecGroupRule, error) {
+		rule := createRule(opts.(rules.CreateOpts))
+		return &rule, nil
+	}).AnyTimes()
+	m.CreateSecGroupRules(gomock.Any()).DoAndReturn(func(opts []rules.CreateOpts) ([]rules.SecGroupRule, error) {
+		created := make([]rules.SecGroupRule, len(opts))
+		for i := range opts {
+			created[i] = createRule(opts[i])
+		}
+		return created, nil
+	}).AnyTimes()
+
+	kubeletFromWorkers := func(groupRules []rules.SecGroupRule) bool {
+		for _, rule := range groupRules {
+			if rule.Description == "Kubelet API" && rule.RemoteGroupID == "idWorker" {
+				return true
+			}
+		}
+		return false
+	}
+
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{},
+		},
+	}
+
+	// The rules of the control plane group referencing the worker group are deferred.
+	err = s.ReconcileSecurityGroups(context.TODO(), openStackCluster, "mycluster")
+	g.Expect(err).To(MatchError(ErrSecurityGroupRemoteGroupPending))
+	g.Expect(openStackCluster.Status.SecurityGroupReconcileFailures).To(BeZero())
+	g.Expect(openStackCluster.Status.ManagedSecurityGroups).To(BeNil())
+	g.Expect(createdRules["idControlPlane"]).NotTo(BeEmpty())
+	g.Expect(kubeletFromWorkers(createdRules["idControlPlane"])).To(BeFalse())
+	for _, rule := range createdRules["idControlPlane"] {
+		if rule.Description == "Kubelet API" {
+			g.Expect(rule.RemoteGroupID).To(Equal("idControlPlane"))
+		}
+	}
+
+	// Once the worker group is listable, the deferred rules are created.
+	workerListable = true
+	g.Expect(s.ReconcileSecurityGroups(context.TODO(), openStackCluster, "mycluster")).To(Succeed())
+	g.Expect(kubeletFromWorkers(createdRules["idControlPlane"])).To(BeTrue())
+	g.Expect(openStackCluster.Status.ManagedSecurityGroups).NotTo(BeNil())
+	g.Expect(openStackCluster.Status.WorkerSecurityGroup.ID).To(Equal("idWorker"))
+}
+
+func TestGenerateDesiredSecGroupsSharedNodeGroup(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	m.ListSecGroup(groups.ListOpts{Name: "k8s-cluster-mycluster-secgroup-controlplane"}).Return([]groups.SecGroup{{ID: "idControlPlane"}}, nil)
+	m.ListSecGroup(groups.ListOpts{Name: "k8s-cluster-mycluster-secgroup-worker"}).Return([]groups.SecGroup{{ID: "idWorker"}}, nil)
+	m.ListSecGroup(groups.ListOpts{Name: "k8s-cluster-mycluster-secgroup-node"}).Return([]groups.SecGroup{{ID: "idNode"}}, nil)
+
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{
+				AllNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{
+					{
+						Name:                "Cilium health",
+						Direction:           "ingress",
+						Protocol:            pointer.String("tcp"),
+						PortRangeMin:        pointer.Int(4240),
+						PortRangeMax:        pointer.Int(4240),
+						RemoteManagedGroups: []infrav1.ManagedSecurityGroupName{"controlplane", "worker"},
+					},
+				},
+			},
+		},
+	}
+
+	separateSecGroups, err := s.generateDesiredSecGroups(openStackCluster, map[string]string{
+		controlPlaneSuffix: "k8s-cluster-mycluster-secgroup-controlplane",
+		workerSuffix:       "k8s-cluster-mycluster-secgroup-worker",
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+
+	openStackCluster.Spec.ManagedSecurityGroups.SharedNodeGroup = true
+	desiredSecGroups, err := s.generateDesiredSecGroups(openStackCluster, map[string]string{
+		nodeSuffix: "k8s-cluster-mycluster-secgroup-node",
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(desiredSecGroups).To(HaveLen(1))
+	g.Expect(desiredSecGroups).To(HaveKey(nodeSuffix))
+	nodeSecGroup := desiredSecGroups[nodeSuffix]
+	g.Expect(nodeSecGroup.Name).To(Equal("k8s-cluster-mycluster-secgroup-node"))
+
+	// The node group has the union of the control plane and worker rules, the rules between them being
+	// self-referencing, and each rule only once.
+	ruleKey := func(rule resolvedSecurityGroupRuleSpec) resolvedSecurityGroupRuleSpec {
+		if rule.RemoteGroupID == "idControlPlane" || rule.RemoteGroupID == "idWorker" {
+			rule.RemoteGroupID = remoteGroupIDSelf
+		}
+		rule.Origin = ""
+		return rule
+	}
+	wantRules := map[resolvedSecurityGroupRuleSpec]bool{}
+	for _, k := range []string{controlPlaneSuffix, workerSuffix} {
+		for _, rule := range separateSecGroups[k].Rules {
+			wantRules[ruleKey(rule)] = true
+		}
+	}
+	gotRules := map[resolvedSecurityGroupRuleSpec]bool{}
+	for _, rule := range nodeSecGroup.Rules {
+		g.Expect(rule.RemoteGroupID).NotTo(Equal("idNode"))
+		g.Expect(gotRules).NotTo(HaveKey(ruleKey(rule)), "rule %q is duplicated", rule.Description)
+		gotRules[ruleKey(rule)] = true
+	}
+	g.Expect(gotRules).To(Equal(wantRules))
+
+	var descriptions []string
+	for _, rule := range nodeSecGroup.Rules {
+		descriptions = append(descriptions, rule.Description)
+	}
+	g.Expect(descriptions).To(ContainElements("Kubernetes API", "Etcd", "Node Port Services", "Kubelet API"))
+}
+
+func TestReconcileSecurityGroupsSharedNodeGroup(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	nodeGroup := groups.SecGroup{ID: "idNode", Name: "k8s-cluster-mycluster-secgroup-node", Description: "Cluster API managed group"}
+
+	// Only the node group is reconciled, the control plane and worker groups are never listed.
+	// The groups are reconciled concurrently.
+	var mu sync.Mutex
+	createdRules := map[string][]rules.SecGroupRule{}
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	m.ListSecGroup(groups.ListOpts{Name: nodeGroup.Name}).Return([]groups.SecGroup{nodeGroup}, nil).AnyTimes()
+	m.ListSecGroupRule(gomock.Any()).DoAndReturn(func(opts rules.ListOpts) ([]rules.SecGroupRule, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return createdRules[opts.SecGroupID], nil
+	}).AnyTimes()
+	createRule := func(createOpts rules.CreateOpts) rules.SecGroupRule {
+		mu.Lock()
+		defer mu.Unlock()
+		rule := rules.SecGroupRule{
+			ID:             fmt.Sprintf("idRule%d", len(createdRules[createOpts.SecGroupID])),
+			SecGroupID:     createOpts.SecGroupID,
+			Description:    createOpts.Description,
+			Direction:      string(createOpts.Direction),
+			EtherType:      string(createOpts.EtherType),
+			PortRangeMin:   createOpts.PortRangeMin,
+			PortRangeMax:   createOpts.PortRangeMax,
+			Protocol:       string(createOpts.Protocol),
+			RemoteGroupID:  createOpts.RemoteGroupID,
+			RemoteIPPrefix: createOpts.RemoteIPPrefix,
+		}
+		createdRules[createOpts.SecGroupID] = append(createdRules[createOpts.SecGroupID], rule)
+		return rule
+	}
+	m.CreateSecGroupRule(gomock.Any()).DoAndReturn(func(opts rules.CreateOptsBuilder) (*rules.SecGroupRule, error) {
+		rule := createRule(opts.(rules.CreateOpts))
+		return &rule, nil
+	}).AnyTimes()
+	m.CreateSecGroupRules(gomock.Any()).DoAndReturn(func(opts []rules.CreateOpts) ([]rules.SecGroupRule, error) {
+		created := make([]rules.SecGroupRule, len(opts))
+		for i := range opts {
+			created[i] = createRule(opts[i])
+		}
+		return created, nil
+	}).AnyTimes()
+
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{SharedNodeGroup: true},
+		},
+	}
+	g.Expect(s.ReconcileSecurityGroups(context.TODO(), openStackCluster, "mycluster")).To(Succeed())
+
+	g.Expect(openStackCluster.Status.NodeSecurityGroup).NotTo(BeNil())
+	g.Expect(openStackCluster.Status.NodeSecurityGroup.ID).To(Equal("idNode"))
+	g.Expect(openStackCluster.Status.NodeSecurityGroup.Rules).To(HaveLen(len(createdRules["idNode"])))
+	// The node group is also reported as the control plane and worker groups, but its rules are only counted once.
+	g.Expect(openStackCluster.Status.ControlPlaneSecurityGroup).To(Equal(openStackCluster.Status.NodeSecurityGroup))
+	g.Expect(openStackCluster.Status.WorkerSecurityGroup).To(Equal(openStackCluster.Status.NodeSecurityGroup))
+	g.Expect(getSecGroupRuleCounts(openStackCluster)).To(Equal(map[string]int{nodeSuffix: len(createdRules["idNode"])}))
+	g.Expect(openStackCluster.Status.ManagedSecurityGroups).NotTo(BeNil())
+
+	var kubeletRules int
+	for _, rule := range createdRules["idNode"] {
+		if rule.Description == "Kubelet API" {
+			kubeletRules++
+			g.Expect(rule.RemoteGroupID).To(Equal("idNode"))
+		}
+	}
+	// The kubelet of the control plane and of the workers is permitted by a single rule.
+	g.Expect(kubeletRules).To(Equal(1))
+}
+
+func TestReconcileGroupRulesManagedMarker(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+	s.ruleDescriptionPrefix = ManagedRuleDescriptionMarker + ": "
+	s.ruleOwnerTag = ManagedRuleOwnerTag
+
+	sshRule := func(id string, description *string, remoteIPPrefix string) infrav1.SecurityGroupRuleStatus {
+		return infrav1.SecurityGroupRuleStatus{
+			ID:             id,
+			Description:    description,
+			Direction:      "ingress",
+			EtherType:      pointer.String("IPv4"),
+			Protocol:       pointer.String("tcp"),
+			PortRangeMin:   pointer.Int(22),
+			PortRangeMax:   pointer.Int(22),
+			RemoteGroupID:  pointer.String(""),
+			RemoteIPPrefix: pointer.String(remoteIPPrefix),
+		}
+	}
+	desired := securityGroupSpec{
+		Name: "k8s-cluster-mycluster-secgroup-worker",
+		Rules: []resolvedSecurityGroupRuleSpec{
+			{
+				Description:    "SSH",
+				Direction:      "ingress",
+				EtherType:      "IPv4",
+				Protocol:       "tcp",
+				PortRangeMin:   22,
+				PortRangeMax:   22,
+				RemoteIPPrefix: "10.0.0.0/24",
+			},
+		},
+		RuleTags: s.getRuleOwnershipTags(&infrav1.OpenStackCluster{}),
+	}
+	// The operator added rules to the group, one of them without description.
+	observed := infrav1.SecurityGroupStatus{
+		ID:   "idSG",
+		Name: "k8s-cluster-mycluster-secgroup-worker",
+		Rules: []infrav1.SecurityGroupRuleStatus{
+			sshRule("idManaged", pointer.String("cluster-api-managed: SSH"), "10.0.0.0/24"),
+			sshRule("idStale", pointer.String("cluster-api-managed: SSH"), "10.0.1.0/24"),
+			sshRule("idOffice", pointer.String("SSH from the office"), "192.0.2.0/24"),
+			sshRule("idUndescribed", nil, "198.51.100.0/24"),
+		},
+	}
+
+	// The marked rules were created before the rules were owned with tags: they are adopted, and only the stale
+	// one is deleted.
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	tags := []string{ManagedRuleOwnerTag}
+	m.ListSecGroupRuleWithTags(rules.ListOpts{SecGroupID: "idSG"}, tags).Return(nil, nil)
+	m.ReplaceAllAttributesTags(secGroupRuleResource, "idManaged", attributestags.ReplaceAllOpts{Tags: tags}).Return(tags, nil)
+	m.ReplaceAllAttributesTags(secGroupRuleResource, "idStale", attributestags.ReplaceAllOpts{Tags: tags}).Return(tags, nil)
+	m.DeleteSecGroupRule("idStale").Return(nil)
+
+	sgStatus, err := s.reconcileGroupRules(context.TODO(), desired, observed)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sgStatus.Rules).To(Equal([]infrav1.SecurityGroupRuleStatus{observed.Rules[0]}))
+}
+
+func TestDeleteSecurityGroupsNamePrefix(t *testing.T) {
+	tests := []struct {
+		name       string
+		namePrefix string
+		wantNames  []string
+	}{
+		{
+			name:      "Default prefix",
+			wantNames: []string{"k8s-cluster-mycluster-secgroup-controlplane", "k8s-cluster-mycluster-secgroup-worker"},
+		},
+		{
+			name:       "Custom prefix",
+			namePrefix: "mgmt-a",
+			wantNames:  []string{"mgmt-a-cluster-mycluster-secgroup-controlplane", "mgmt-a-cluster-mycluster-secgroup-worker"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+
+			m := mockScopeFactory.NetworkClient.EXPECT()
+			for _, name := range tt.wantNames {
+				m.ListSecGroup(groups.ListOpts{Name: name}).Return([]groups.SecGroup{{ID: "id-" + name, Name: name}}, nil)
+				m.DeleteSecGroup("id-" + name).Return(nil)
+			}
+
+			openStackCluster := &infrav1.OpenStackCluster{
+				Spec: infrav1.OpenStackClusterSpec{
+					ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{NamePrefix: tt.namePrefix},
+				},
+			}
+			g.Expect(s.DeleteSecurityGroups(context.TODO(), openStackCluster, "mycluster")).To(Succeed())
+		})
+	}
+}
+
+func TestDeleteSecurityGroupsInUse(t *testing.T) {
+	const (
+		controlPlaneName = "k8s-cluster-mycluster-secgroup-controlplane"
+		workerName       = "k8s-cluster-mycluster-secgroup-worker"
+	)
+	inUse := gophercloud.ErrDefault409{}
+	clusterPort := ports.Port{ID: "idClusterPort", Description: names.GetDescription("mycluster"), SecurityGroups: []string{"idOther", "idControlPlane"}}
+	foreignPort := ports.Port{ID: "idForeignPort", Description: "load balancer", SecurityGroups: []string{"idControlPlane"}}
+
+	tests := []struct {
+		name           string
+		retainOnDelete bool
+		mockExpect     func(m *mock.MockNetworkClientMockRecorder)
+	}{
+		{
+			name:           "Groups are retained",
+			retainOnDelete: true,
+			mockExpect:     func(m *mock.MockNetworkClientMockRecorder) {},
+		},
+		{
+			name: "Group used by the ports of the cluster is detached from them and deleted",
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Name: controlPlaneName}).Return([]groups.SecGroup{{ID: "idControlPlane", Name: controlPlaneName}}, nil)
+				gomock.InOrder(
+					m.DeleteSecGroup("idControlPlane").Return(inUse),
+					m.ListPort(ports.ListOpts{SecurityGroups: []string{"idControlPlane"}}).Return([]ports.Port{clusterPort}, nil),
+					m.UpdatePort("idClusterPort", ports.UpdateOpts{SecurityGroups: &[]string{"idOther"}}).Return(&ports.Port{}, nil),
+					m.DeleteSecGroup("idControlPlane").Return(nil),
+				)
+				m.ListSecGroup(groups.ListOpts{Name: workerName}).Return([]groups.SecGroup{{ID: "idWorker", Name: workerName}}, nil)
+				m.DeleteSecGroup("idWorker").Return(nil)
+			},
+		},
+		{
+			name: "Group used by other ports is left in place",
+			mockExpect: func(m *mock.MockNetworkClientMockRecorder) {
+				m.ListSecGroup(groups.ListOpts{Name: controlPlaneName}).Return([]groups.SecGroup{{ID: "idControlPlane", Name: controlPlaneName}}, nil)
+				gomock.InOrder(
+					m.DeleteSecGroup("idControlPlane").Return(inUse),
+					m.ListPort(ports.ListOpts{SecurityGroups: []string{"idControlPlane"}}).Return([]ports.Port{clusterPort, foreignPort}, nil),
+					m.UpdatePort("idClusterPort", ports.UpdateOpts{SecurityGroups: &[]string{"idOther"}}).Return(&ports.Port{}, nil),
+				)
+				// The deletion of the other groups isn't blocked.
+				m.ListSecGroup(groups.ListOpts{Name: workerName}).Return([]groups.SecGroup{{ID: "idWorker", Name: workerName}}, nil)
+				m.DeleteSecGroup("idWorker").Return(nil)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+
+			tt.mockExpect(mockScopeFactory.NetworkClient.EXPECT())
+			openStackCluster := &infrav1.OpenStackCluster{
+				Spec: infrav1.OpenStackClusterSpec{
+					ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{RetainOnDelete: tt.retainOnDelete},
+				},
+			}
+			g.Expect(s.DeleteSecurityGroups(context.TODO(), openStackCluster, "mycluster")).To(Succeed())
+		})
+	}
+}
+
+func TestReconcileSecurityGroupsNameTooLong(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// No security group is created.
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{NamePrefix: strings.Repeat("p", 161)},
+		},
+	}
+	err = s.ReconcileSecurityGroups(context.TODO(), openStackCluster, strings.Repeat("c", 64))
+	g.Expect(err).To(MatchError(ContainSubstring("longer than the 255 characters allowed")))
+}
+
+func TestReconcileSecurityGroupsPartialStatus(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{},
+		},
+	}
+	secGroupNames, err := getManagedSecGroupNames(openStackCluster, "mycluster")
+	g.Expect(err).NotTo(HaveOccurred())
+	secGroupIDs := map[string]string{controlPlaneSuffix: "idControlPlane", workerSuffix: "idWorker"}
+
+	// The rules of one of the groups can't be created.
+	reconciled, failed := controlPlaneSuffix, workerSuffix
+
+	// The groups are reconciled concurrently.
+	var mu sync.Mutex
+	createdRules := map[string][]rules.SecGroupRule{}
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	for k, name := range secGroupNames {
+		m.ListSecGroup(groups.ListOpts{Name: name}).Return([]groups.SecGroup{{ID: secGroupIDs[k], Name: name, Description: "Cluster API managed group"}}, nil).AnyTimes()
+	}
+	m.ListSecGroupRule(gomock.Any()).DoAndReturn(func(opts rules.ListOpts) ([]rules.SecGroupRule, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return createdRules[opts.SecGroupID], nil
+	}).AnyTimes()
+	m.CreateSecGroupRules(gomock.Any()).DoAndReturn(func(opts []rules.CreateOpts) ([]rules.SecGroupRule, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if opts[0].SecGroupID == secGroupIDs[failed] {
+			return nil, gophercloud.ErrDefault500{}
+		}
+		created := make([]rules.SecGroupRule, len(opts))
+		for i, createOpts := range opts {
+			created[i] = rules.SecGroupRule{
+				ID:          fmt.Sprintf("idRule%d", i),
+				SecGroupID:  createOpts.SecGroupID,
+				Description: createOpts.Description,
+				Direction:   string(createOpts.Direction),
+				EtherType:   string(createOpts.EtherType),
+			}
+		}
+		createdRules[opts[0].SecGroupID] = created
+		return created, nil
+	}).Times(2)
+
+	g.Expect(s.ReconcileSecurityGroups(context.TODO(), openStackCluster, "mycluster")).NotTo(Succeed())
+
+	// The group reconciled successfully is reported, the failed one is not.
+	statuses := map[string]*infrav1.SecurityGroupStatus{
+		controlPlaneSuffix: openStackCluster.Status.ControlPlaneSecurityGroup,
+		workerSuffix:       openStackCluster.Status.WorkerSecurityGroup,
+	}
+	g.Expect(statuses[reconciled]).NotTo(BeNil())
+	g.Expect(statuses[reconciled].ID).To(Equal(secGroupIDs[reconciled]))
+	g.Expect(statuses[reconciled].RulesHash).NotTo(BeEmpty())
+	g.Expect(statuses[reconciled].Rules).To(HaveLen(len(createdRules[secGroupIDs[reconciled]])))
+	g.Expect(statuses[failed]).To(BeNil())
+	g.Expect(openStackCluster.Status.BastionSecurityGroup).To(BeNil())
+	g.Expect(openStackCluster.Status.ManagedSecurityGroups).To(BeNil())
+}
+
+func TestGenerateDesiredSecGroupsDisableDefaultRules(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	secGroupNames := map[string]string{
+		controlPlaneSuffix: "k8s-cluster-mycluster-secgroup-controlplane",
+		workerSuffix:       "k8s-cluster-mycluster-secgroup-worker",
+		bastionSuffix:      "k8s-cluster-mycluster-secgroup-bastion",
+		shadowSuffix:       "k8s-cluster-mycluster-secgroup-shadow",
+	}
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	for k, name := range secGroupNames {
+		m.ListSecGroup(groups.ListOpts{Name: name}).Return([]groups.SecGroup{{ID: "id" + k}}, nil).AnyTimes()
+	}
+
+	httpsEgress := infrav1.SecurityGroupRuleSpec{
+		Name:           "HTTPS egress",
+		Direction:      "egress",
+		EtherType:      pointer.String("IPv4"),
+		Protocol:       pointer.String("tcp"),
+		PortRangeMin:   pointer.Int(443),
+		PortRangeMax:   pointer.Int(443),
+		RemoteIPPrefix: pointer.String("10.0.0.0/8"),
+	}
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			Bastion: &infrav1.Bastion{Enabled: true},
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{
+				DisableDefaultRules:        true,
+				AllNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{httpsEgress},
+				ShadowRules:                []infrav1.SecurityGroupRuleSpec{httpsEgress},
+			},
+		},
+	}
+	desiredSecGroups, err := s.generateDesiredSecGroups(openStackCluster, secGroupNames)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	origins := func(k string) map[infrav1.SecurityGroupRuleOrigin]int {
+		origins := map[infrav1.SecurityGroupRuleOrigin]int{}
+		for _, rule := range desiredSecGroups[k].Rules {
+			origins[rule.Origin]++
+		}
+		return origins
+	}
+	// The groups of the nodes only have the rules of the Kubernetes components and the user rules.
+	for _, k := range []string{controlPlaneSuffix, workerSuffix} {
+		g.Expect(origins(k)).NotTo(HaveKey(infrav1.SecurityGroupRuleOriginDefault), k)
+		g.Expect(origins(k)).To(HaveKey(infrav1.SecurityGroupRuleOriginGeneral), k)
+		g.Expect(origins(k)).To(HaveKeyWithValue(infrav1.SecurityGroupRuleOriginUser, 1), k)
+	}
+	g.Expect(origins(shadowSuffix)).To(Equal(map[infrav1.SecurityGroupRuleOrigin]int{infrav1.SecurityGroupRuleOriginUser: 1}))
+	// The bastion keeps its egress rules, no user rule being applied to it.
+	g.Expect(origins(bastionSuffix)).To(HaveKeyWithValue(infrav1.SecurityGroupRuleOriginDefault, len(defaultRules)))
+}
+
+func TestGenerateDesiredSecGroupsDisableWorkerIngressRules(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	secGroupNames := map[string]string{
+		controlPlaneSuffix: "k8s-cluster-mycluster-secgroup-controlplane",
+		workerSuffix:       "k8s-cluster-mycluster-secgroup-worker",
+		bastionSuffix:      "k8s-cluster-mycluster-secgroup-bastion",
+	}
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	for k, name := range secGroupNames {
+		m.ListSecGroup(groups.ListOpts{Name: name}).Return([]groups.SecGroup{{ID: "id" + k}}, nil).AnyTimes()
+	}
+
+	kubeletIngress := infrav1.SecurityGroupRuleSpec{
+		Name:                "Kubelet API",
+		Direction:           "ingress",
+		EtherType:           pointer.String("IPv4"),
+		Protocol:            pointer.String("tcp"),
+		PortRangeMin:        pointer.Int(10250),
+		PortRangeMax:        pointer.Int(10250),
+		RemoteManagedGroups: []infrav1.ManagedSecurityGroupName{"controlplane"},
+	}
+	for _, allowAll := range []bool{false, true} {
+		openStackCluster := &infrav1.OpenStackCluster{
+			Spec: infrav1.OpenStackClusterSpec{
+				Bastion: &infrav1.Bastion{Enabled: true},
+				ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{
+					DisableWorkerIngressRules:  true,
+					AllowAllInClusterTraffic:   allowAll,
+					AllNodesSecurityGroupRules: []infrav1.SecurityGroupRuleSpec{kubeletIngress},
+				},
+			},
+		}
+		desiredSecGroups, err := s.generateDesiredSecGroups(openStackCluster, secGroupNames)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		// The workers only have their egress rules and the user rules.
+		var workerIngress []resolvedSecurityGroupRuleSpec
+		for _, rule := range desiredSecGroups[workerSuffix].Rules {
+			if rule.Direction == "ingress" {
+				workerIngress = append(workerIngress, rule)
+			}
+		}
+		g.Expect(workerIngress).To(HaveLen(1), "allowAllInClusterTraffic=%t", allowAll)
+		g.Expect(workerIngress[0].Origin).To(Equal(infrav1.SecurityGroupRuleOriginUser))
+		g.Expect(desiredSecGroups[workerSuffix].Rules).To(ContainElements(withRuleOrigin(defaultRules, infrav1.SecurityGroupRuleOriginDefault)))
+
+		// The control plane keeps its ingress rules, including those from the workers.
+		var controlPlaneOrigins []infrav1.SecurityGroupRuleOrigin
+		for _, rule := range desiredSecGroups[controlPlaneSuffix].Rules {
+			if rule.Direction == "ingress" {
+				controlPlaneOrigins = append(controlPlaneOrigins, rule.Origin)
+			}
+		}
+		g.Expect(controlPlaneOrigins).To(ContainElements(infrav1.SecurityGroupRuleOriginGeneral, infrav1.SecurityGroupRuleOriginBastion))
+	}
+}
+
+func TestGenerateDesiredSecGroupsAllowAllWithAllNodesRules(t *testing.T) {
+	vxlan := infrav1.SecurityGroupRuleSpec{
+		Name:           "VXLAN",
+		Description:    pointer.String("VXLAN from the storage network"),
+		Direction:      "ingress",
+		EtherType:      pointer.String("IPv4"),
+		Protocol:       pointer.String("udp"),
+		PortRangeMin:   pointer.Int(4789),
+		PortRangeMax:   pointer.Int(4789),
+		RemoteIPPrefix: pointer.String("192.168.100.0/24"),
+	}
+
+	tests := []struct {
+		name                  string
+		managedSecurityGroups *infrav1.ManagedSecurityGroups
+		// allowAllGroups are the groups which must permit all the in-cluster traffic.
+		allowAllGroups []string
+		// allNodesGroups are the groups which must have the allNodes rules.
+		allNodesGroups []string
+	}{
+		{
+			name:                  "Control plane and worker groups",
+			managedSecurityGroups: &infrav1.ManagedSecurityGroups{},
+			allowAllGroups:        []string{controlPlaneSuffix, workerSuffix},
+			allNodesGroups:        []string{controlPlaneSuffix, workerSuffix},
+		},
+		{
+			name:                  "Shared node group",
+			managedSecurityGroups: &infrav1.ManagedSecurityGroups{SharedNodeGroup: true},
+			allowAllGroups:        []string{nodeSuffix},
+			allNodesGroups:        []string{nodeSuffix},
+		},
+		{
+			name:                  "Separate allNodes group",
+			managedSecurityGroups: &infrav1.ManagedSecurityGroups{SeparateAllNodesGroup: true},
+			allowAllGroups:        []string{controlPlaneSuffix, workerSuffix},
+			allNodesGroups:        []string{allNodesSuffix},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+
+			openStackCluster := &infrav1.OpenStackCluster{Spec: infrav1.OpenStackClusterSpec{ManagedSecurityGroups: tt.managedSecurityGroups}}
+			openStackCluster.Spec.ManagedSecurityGroups.AllowAllInClusterTraffic = true
+			openStackCluster.Spec.ManagedSecurityGroups.AllNodesSecurityGroupRules = []infrav1.SecurityGroupRuleSpec{vxlan}
+			secGroupNames, err := getManagedSecGroupNames(openStackCluster, "mycluster")
+			g.Expect(err).NotTo(HaveOccurred())
+
+			m := mockScopeFactory.NetworkClient.EXPECT()
+			for k, name := range secGroupNames {
+				m.ListSecGroup(groups.ListOpts{Name: name}).Return([]groups.SecGroup{{ID: "id" + k}}, nil).AnyTimes()
+			}
+			// The ports of the nodes all have the allNodes group.
+			m.ListPort(gomock.Any()).Return(nil, nil).AnyTimes()
+
+			desiredSecGroups, err := s.generateDesiredSecGroups(openStackCluster, secGroupNames)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			hasRule := func(k, description string) bool {
+				for _, rule := range desiredSecGroups[k].Rules {
+					if rule.Description == description {
+						return true
+					}
+				}
+				return false
+			}
+			for _, k := range tt.allowAllGroups {
+				g.Expect(hasRule(k, "In-cluster Ingress")).To(BeTrue(), "group %s permits all the in-cluster traffic", k)
+			}
+			for _, k := range tt.allNodesGroups {
+				g.Expect(hasRule(k, "VXLAN from the storage network")).To(BeTrue(), "group %s has the allNodes rules", k)
+			}
+		})
+	}
+}
+
+func TestGenerateDesiredSecGroupsBastionSSHAllowedCIDRs(t *testing.T) {
+	tests := []struct {
+		name            string
+		sshAllowedCIDRs []string
+		wantRules       []resolvedSecurityGroupRuleSpec
+	}{
+		{
+			name: "SSH is permitted from anywhere",
+			wantRules: []resolvedSecurityGroupRuleSpec{
+				{Description: "SSH", Direction: "ingress", EtherType: "IPv4", PortRangeMin: 22, PortRangeMax: 22, Protocol: "tcp", Origin: infrav1.SecurityGroupRuleOriginBastion},
+			},
+		},
+		{
+			name:            "SSH is restricted to the allowed CIDRs",
+			sshAllowedCIDRs: []string{"192.168.0.0/16", "2001:db8::/32"},
+			wantRules: []resolvedSecurityGroupRuleSpec{
+				{Description: "SSH", Direction: "ingress", EtherType: "IPv4", PortRangeMin: 22, PortRangeMax: 22, Protocol: "tcp", RemoteIPPrefix: "192.168.0.0/16", Origin: infrav1.SecurityGroupRuleOriginBastion},
+				{Description: "SSH", Direction: "ingress", EtherType: "IPv6", PortRangeMin: 22, PortRangeMax: 22, Protocol: "tcp", RemoteIPPrefix: "2001:db8::/32", Origin: infrav1.SecurityGroupRuleOriginBastion},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+
+			openStackCluster := &infrav1.OpenStackCluster{
+				Spec: infrav1.OpenStackClusterSpec{
+					ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{},
+					Bastion:               &infrav1.Bastion{Enabled: true, SSHAllowedCIDRs: tt.sshAllowedCIDRs},
+				},
+			}
+			secGroupNames, err := getManagedSecGroupNames(openStackCluster, "mycluster")
+			g.Expect(err).NotTo(HaveOccurred())
+
+			m := mockScopeFactory.NetworkClient.EXPECT()
+			for k, name := range secGroupNames {
+				m.ListSecGroup(groups.ListOpts{Name: name}).Return([]groups.SecGroup{{ID: "id" + k}}, nil).AnyTimes()
+			}
+
+			desiredSecGroups, err := s.generateDesiredSecGroups(openStackCluster, secGroupNames)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			var bastionRules []resolvedSecurityGroupRuleSpec
+			for _, rule := range desiredSecGroups[bastionSuffix].Rules {
+				if rule.Origin == infrav1.SecurityGroupRuleOriginBastion {
+					bastionRules = append(bastionRules, rule)
+				}
+			}
+			g.Expect(bastionRules).To(Equal(tt.wantRules))
+		})
+	}
+}
+
+func TestGenerateDesiredSecGroupsNodePortAllowedCIDRs(t *testing.T) {
+	tests := []struct {
+		name                 string
+		nodePortAllowedCIDRs []string
+		wantRules            []resolvedSecurityGroupRuleSpec
+	}{
+		{
+			name: "Node ports are permitted from anywhere",
+			wantRules: []resolvedSecurityGroupRuleSpec{
+				{Description: "Node Port Services", Direction: "ingress", EtherType: "IPv4", PortRangeMin: 30000, PortRangeMax: 32767, Protocol: "tcp", Origin: infrav1.SecurityGroupRuleOriginGeneral},
+				{Description: "Node Port Services", Direction: "ingress", EtherType: "IPv4", PortRangeMin: 30000, PortRangeMax: 32767, Protocol: "udp", Origin: infrav1.SecurityGroupRuleOriginGeneral},
+			},
+		},
+		{
+			name:                 "Node ports are restricted to the allowed CIDRs",
+			nodePortAllowedCIDRs: []string{"192.168.0.0/16", "2001:db8::/32"},
+			wantRules: []resolvedSecurityGroupRuleSpec{
+				{Description: "Node Port Services", Direction: "ingress", EtherType: "IPv4", PortRangeMin: 30000, PortRangeMax: 32767, Protocol: "tcp", RemoteIPPrefix: "192.168.0.0/16", Origin: infrav1.SecurityGroupRuleOriginGeneral},
+				{Description: "Node Port Services", Direction: "ingress", EtherType: "IPv4", PortRangeMin: 30000, PortRangeMax: 32767, Protocol: "udp", RemoteIPPrefix: "192.168.0.0/16", Origin: infrav1.SecurityGroupRuleOriginGeneral},
+				{Description: "Node Port Services", Direction: "ingress", EtherType: "IPv6", PortRangeMin: 30000, PortRangeMax: 32767, Protocol: "tcp", RemoteIPPrefix: "2001:db8::/32", Origin: infrav1.SecurityGroupRuleOriginGeneral},
+				{Description: "Node Port Services", Direction: "ingress", EtherType: "IPv6", PortRangeMin: 30000, PortRangeMax: 32767, Protocol: "udp", RemoteIPPrefix: "2001:db8::/32", Origin: infrav1.SecurityGroupRuleOriginGeneral},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+
+			mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+			s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+			g.Expect(err).NotTo(HaveOccurred())
+
+			openStackCluster := &infrav1.OpenStackCluster{
+				Spec: infrav1.OpenStackClusterSpec{
+					ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{NodePortAllowedCIDRs: tt.nodePortAllowedCIDRs},
+				},
+			}
+			secGroupNames, err := getManagedSecGroupNames(openStackCluster, "mycluster")
+			g.Expect(err).NotTo(HaveOccurred())
+
+			m := mockScopeFactory.NetworkClient.EXPECT()
+			for k, name := range secGroupNames {
+				m.ListSecGroup(groups.ListOpts{Name: name}).Return([]groups.SecGroup{{ID: "id" + k}}, nil).AnyTimes()
+			}
+
+			desiredSecGroups, err := s.generateDesiredSecGroups(openStackCluster, secGroupNames)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			var nodePortRules []resolvedSecurityGroupRuleSpec
+			for _, rule := range desiredSecGroups[workerSuffix].Rules {
+				if rule.Description == "Node Port Services" {
+					nodePortRules = append(nodePortRules, rule)
+				}
+			}
+			g.Expect(nodePortRules).To(Equal(tt.wantRules))
+
+			// The control plane doesn't expose the node ports.
+			for _, rule := range desiredSecGroups[controlPlaneSuffix].Rules {
+				g.Expect(rule.Description).NotTo(Equal("Node Port Services"))
+			}
+		})
+	}
+}
+
+func TestReconcileSecurityGroupsNotUniqueCondition(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	const (
+		controlPlaneName = "k8s-cluster-mycluster-secgroup-controlplane"
+		workerName       = "k8s-cluster-mycluster-secgroup-worker"
+	)
+	controlPlaneGroup := groups.SecGroup{ID: "idControlPlane", Name: controlPlaneName, Description: "Cluster API managed group"}
+	duplicateGroup := groups.SecGroup{ID: "idDuplicate", Name: controlPlaneName, Description: "Cluster API managed group"}
+	workerGroup := groups.SecGroup{ID: "idWorker", Name: workerName, Description: "Cluster API managed group"}
+
+	var mu sync.Mutex
+	controlPlaneGroups := []groups.SecGroup{controlPlaneGroup, duplicateGroup}
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	m.ListSecGroup(groups.ListOpts{Name: controlPlaneName}).DoAndReturn(func(groups.ListOpts) ([]groups.SecGroup, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return controlPlaneGroups, nil
+	}).AnyTimes()
+	m.ListSecGroup(groups.ListOpts{Name: workerName}).Return([]groups.SecGroup{workerGroup}, nil).AnyTimes()
+	m.ListSecGroupRule(gomock.Any()).Return(nil, nil).AnyTimes()
+	m.CreateSecGroupRules(gomock.Any()).DoAndReturn(func(opts []rules.CreateOpts) ([]rules.SecGroupRule, error) {
+		created := make([]rules.SecGroupRule, len(opts))
+		for i := range opts {
+			created[i] = rules.SecGroupRule{ID: fmt.Sprintf("idRule%d", i), SecGroupID: opts[i].SecGroupID}
+		}
+		return created, nil
+	}).AnyTimes()
+
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{},
+		},
+	}
+
+	// The duplicate groups are reported in a condition.
+	err = s.ReconcileSecurityGroups(context.TODO(), openStackCluster, "mycluster")
+	g.Expect(err).To(MatchError(ErrSecurityGroupNotUnique))
+	condition := conditions.Get(openStackCluster, infrav1.SecurityGroupNotUniqueCondition)
+	g.Expect(condition).NotTo(BeNil())
+	g.Expect(condition.Status).To(Equal(corev1.ConditionTrue))
+	g.Expect(condition.Reason).To(Equal(infrav1.DuplicateSecurityGroupNameReason))
+	g.Expect(condition.Message).To(ContainSubstring(controlPlaneName))
+	g.Expect(condition.Message).To(ContainSubstring("idControlPlane, idDuplicate"))
+
+	// The condition is removed once the duplicate is deleted.
+	mu.Lock()
+	controlPlaneGroups = []groups.SecGroup{controlPlaneGroup}
+	mu.Unlock()
+	g.Expect(s.ReconcileSecurityGroups(context.TODO(), openStackCluster, "mycluster")).To(Succeed())
+	g.Expect(conditions.Get(openStackCluster, infrav1.SecurityGroupNotUniqueCondition)).To(BeNil())
+}
+
+func TestGetBastionReplicaIndex(t *testing.T) {
+	tests := []struct {
+		suffix      string
+		wantReplica int
+		wantOK      bool
+	}{
+		{suffix: bastionSuffix, wantReplica: 0, wantOK: true},
+		{suffix: "bastion-1", wantReplica: 1, wantOK: true},
+		{suffix: "bastion-12", wantReplica: 12, wantOK: true},
+		{suffix: "bastion-0", wantOK: false},
+		{suffix: "bastion-x", wantOK: false},
+		{suffix: controlPlaneSuffix, wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.suffix, func(t *testing.T) {
+			g := NewWithT(t)
+			replica, ok := getBastionReplicaIndex(tt.suffix)
+			g.Expect(ok).To(Equal(tt.wantOK))
+			g.Expect(replica).To(Equal(tt.wantReplica))
+			if ok {
+				g.Expect(getBastionReplicaSuffix(replica)).To(Equal(tt.suffix))
+			}
+		})
+	}
+}
+
+func TestGenerateDesiredSecGroupsBastionReplicas(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{},
+			Bastion:               &infrav1.Bastion{Enabled: true, Replicas: pointer.Int(3)},
+		},
+	}
+	secGroupNames, err := getManagedSecGroupNames(openStackCluster, "mycluster")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(secGroupNames).To(HaveKeyWithValue(bastionSuffix, "k8s-cluster-mycluster-secgroup-bastion"))
+	g.Expect(secGroupNames).To(HaveKeyWithValue("bastion-1", "k8s-cluster-mycluster-secgroup-bastion-1"))
+	g.Expect(secGroupNames).To(HaveKeyWithValue("bastion-2", "k8s-cluster-mycluster-secgroup-bastion-2"))
+
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	for k, name := range secGroupNames {
+		m.ListSecGroup(groups.ListOpts{Name: name}).Return([]groups.SecGroup{{ID: "id" + k}}, nil).AnyTimes()
+	}
+
+	desiredSecGroups, err := s.generateDesiredSecGroups(openStackCluster, secGroupNames)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// Each replica has its own group, and SSH to the nodes is permitted from all of them.
+	for _, k := range []string{bastionSuffix, "bastion-1", "bastion-2"} {
+		g.Expect(desiredSecGroups).To(HaveKey(k))
+		for _, nodeSuffix := range []string{controlPlaneSuffix, workerSuffix} {
+			g.Expect(desiredSecGroups[nodeSuffix].Rules).To(ContainElement(SatisfyAll(
+				HaveField("PortRangeMin", 22),
+				HaveField("RemoteGroupID", "id"+k),
+			)), "SSH from %s to %s", k, nodeSuffix)
+		}
+	}
+}
+
+func TestDeleteSecurityGroupsBastionReplicas(t *testing.T) {
+	g := NewWithT(t)
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockScopeFactory := scope.NewMockScopeFactory(mockCtrl, "")
+	s, err := NewService(scope.NewWithLogger(mockScopeFactory, testr.New(t)), DefaultOptions())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	// The group of the third replica is deleted although the replica was removed from the spec.
+	wantNames := []string{
+		"k8s-cluster-mycluster-secgroup-controlplane",
+		"k8s-cluster-mycluster-secgroup-worker",
+		"k8s-cluster-mycluster-secgroup-bastion",
+		"k8s-cluster-mycluster-secgroup-bastion-1",
+		"k8s-cluster-mycluster-secgroup-bastion-2",
+	}
+	m := mockScopeFactory.NetworkClient.EXPECT()
+	for _, name := range wantNames {
+		m.ListSecGroup(groups.ListOpts{Name: name}).Return([]groups.SecGroup{{ID: "id-" + name, Name: name}}, nil)
+		m.DeleteSecGroup("id-" + name).Return(nil)
+	}
+
+	openStackCluster := &infrav1.OpenStackCluster{
+		Spec: infrav1.OpenStackClusterSpec{
+			ManagedSecurityGroups: &infrav1.ManagedSecurityGroups{},
+			Bastion:               &infrav1.Bastion{Enabled: true, Replicas: pointer.Int(2)},
+		},
+		Status: infrav1.OpenStackClusterStatus{
+			AdditionalBastionSecurityGroups: []infrav1.SecurityGroupStatus{
+				{ID: "id-k8s-cluster-mycluster-secgroup-bastion-1"},
+				{ID: "id-k8s-cluster-mycluster-secgroup-bastion-2"},
+			},
+		},
+	}
+	g.Expect(s.DeleteSecurityGroups(context.TODO(), openStackCluster, "mycluster")).To(Succeed())
+}
@@ -17,40 +17,213 @@ limitations under the License.
 package networking
 
 import (
+	"errors"
 	"fmt"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/attributestags"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/clock"
 
+	infrav1 "sigs.k8s.io/cluster-api-provider-openstack/api/v1beta1"
+	"sigs.k8s.io/cluster-api-provider-openstack/pkg/audit"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/clients"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/record"
 	"sigs.k8s.io/cluster-api-provider-openstack/pkg/scope"
 )
 
 const (
-	networkPrefix string = "k8s-clusterapi"
-	trunkResource string = "trunks"
-	portResource  string = "ports"
+	networkPrefix        string = "k8s-clusterapi"
+	trunkResource        string = "trunks"
+	portResource         string = "ports"
+	secGroupRuleResource string = "security-group-rules"
 )
 
 // Service interfaces with the OpenStack Networking API.
 // It will create a network related infrastructure for the cluster, like network, subnet, router, security groups.
 type Service struct {
-	scope  *scope.WithLogger
-	client clients.NetworkClient
+	scope               *scope.WithLogger
+	client              clients.NetworkClient
+	auditSink           audit.Sink
+	secGroupDescription securityGroupDescription
+	// ruleDescriptionPrefix is prepended to the description of the rules created by this manager. The untagged rules
+	// with the prefix are adopted.
+	ruleDescriptionPrefix string
+	// ruleOwnerTag is the tag of the rules owned by this manager in shared security groups, in addition to the
+	// tags of the cluster.
+	ruleOwnerTag string
+	// secGroupNameConflictPolicy is applied when several security groups have the name of a managed group.
+	secGroupNameConflictPolicy SecurityGroupNameConflictPolicy
+	// foreignSecGroupPolicy is applied to the groups with the tags of a cluster but not the name of a managed group.
+	foreignSecGroupPolicy ForeignSecurityGroupPolicy
+	// secGroupRemovalPolicy is applied to the managed groups once managedSecurityGroups is removed from the spec.
+	secGroupRemovalPolicy SecurityGroupRemovalPolicy
+	// secGroupPropagationTimeout is the time to wait for a created security group to be listable.
+	secGroupPropagationTimeout  time.Duration
+	secGroupPropagationInterval time.Duration
+	// secGroupRuleDeletionGracePeriod is the time the rules not desired anymore are retained before being deleted.
+	secGroupRuleDeletionGracePeriod time.Duration
+	// secGroupMaxReconcileAttempts is the number of consecutive failures after which the failure is terminal.
+	secGroupMaxReconcileAttempts int
+	// secGroupMaxRulesPerGroup is the maximum number of rules of a security group, zero if unknown.
+	secGroupMaxRulesPerGroup int
+	// secGroupRuleProber probes that a managed rule is effective after reconciling the groups of the clusters opting in.
+	secGroupRuleProber SecurityGroupRuleProber
+	// clock is the source of the reconcile timestamps.
+	clock clock.PassiveClock
+	// loadBalancerServices are the Services of type LoadBalancer of the workload cluster.
+	loadBalancerServices []corev1.Service
+	// goldenRules are the rules the generated rules of the managed groups are checked against, keyed by suffix.
+	goldenRules map[string]string
+	// floatingIPReuseTag is the tag of the floating IPs reused across clusters, empty if they aren't reused.
+	floatingIPReuseTag string
 }
 
-// NewService returns an instance of the networking service.
-func NewService(scope *scope.WithLogger) (*Service, error) {
+// Options are the options of the networking services, set from the flags of the manager.
+type Options struct {
+	// SecurityGroupDescriptionTemplate is the text/template of the description of the managed security groups,
+	// executed with the cluster metadata, the role of the group and SecurityGroupControlReference.
+	SecurityGroupDescriptionTemplate string
+	// SecurityGroupControlReference is a compliance control reference, e.g. CIS-5.2.
+	SecurityGroupControlReference string
+	// SecurityGroupDescriptionFormat is the format of the descriptions generated for the managed security groups
+	// and rules. The description template is ignored by the structured format.
+	SecurityGroupDescriptionFormat DescriptionFormat
+	// SecurityGroupRuleOwnerTag is the tag of the rules owned by this manager, in addition to the tags of the
+	// cluster. Only the owned rules are deleted, so several managers, or operators, can add rules to the same
+	// security group. All the rules are owned if there is no tag at all.
+	SecurityGroupRuleOwnerTag string
+	// SecurityGroupRuleDescriptionPrefix is prepended to the description of the rules created by this manager.
+	// The untagged rules with the prefix, created before the rules were owned with tags, are adopted by tagging
+	// them. It requires an owner tag.
+	SecurityGroupRuleDescriptionPrefix string
+	// SecurityGroupRuleManagedMarker starts the description of the rules created by this manager with
+	// ManagedRuleDescriptionMarker. The owner tag then defaults to ManagedRuleOwnerTag.
+	SecurityGroupRuleManagedMarker bool
+	// SecurityGroupNameConflictPolicy is applied when several security groups have the name of a managed group.
+	SecurityGroupNameConflictPolicy SecurityGroupNameConflictPolicy
+	// ForeignSecurityGroupPolicy is applied to the groups with the tags of a cluster but not the name of a managed
+	// group.
+	ForeignSecurityGroupPolicy ForeignSecurityGroupPolicy
+	// SecurityGroupRemovalPolicy is applied to the managed groups once managedSecurityGroups is removed from the spec.
+	SecurityGroupRemovalPolicy SecurityGroupRemovalPolicy
+	// SecurityGroupPropagationTimeout is the time to wait for a created security group to be listable before using
+	// it. No wait is done when zero.
+	SecurityGroupPropagationTimeout time.Duration
+	// SecurityGroupRuleDeletionGracePeriod is the time the rules not desired anymore are retained before being
+	// deleted. The rules are deleted immediately when zero.
+	SecurityGroupRuleDeletionGracePeriod time.Duration
+	// SecurityGroupMaxReconcileAttempts is the number of consecutive failed attempts to reconcile the security
+	// groups after which the failure is terminal.
+	SecurityGroupMaxReconcileAttempts int
+	// SecurityGroupMaxRulesPerGroup is the maximum number of rules of a security group allowed by the cloud. Neutron
+	// doesn't expose it, so it is checked before changing the rules of a group only when set. Zero if unknown.
+	SecurityGroupMaxRulesPerGroup int
+	// SecurityGroupClientRetryAttempts is the number of times the creation and deletion of security group rules,
+	// and the listing of security groups, are attempted when they fail with a transient server error, e.g. a 503
+	// during a maintenance of Neutron. The attempts are made with an exponential backoff.
+	SecurityGroupClientRetryAttempts int
+	// SecurityGroupListMaxResults is the maximum number of security groups a listing of security groups may return.
+	// The groups are then listed page by page, and the listing fails as soon as more groups match, rather than
+	// loading them all. Zero means unlimited.
+	SecurityGroupListMaxResults int
+	// FloatingIPReuseTag is the tag of the floating IPs reused across clusters, so that recreating clusters doesn't
+	// exhaust the floating IP quota. The floating IPs allocated without an explicit address are tagged with it, an
+	// unassigned floating IP with the tag is reused rather than a new one allocated, and the floating IPs with the
+	// tag are only disassociated rather than released once they aren't used anymore. Empty if they aren't reused.
+	FloatingIPReuseTag string
+	// AuditSink receives the changes of the managed security group rules. The records are discarded if nil.
+	AuditSink audit.Sink
+}
+
+// DefaultOptions returns the default options of the networking services.
+func DefaultOptions() Options {
+	return Options{
+		SecurityGroupDescriptionTemplate:  defaultSecGroupDescriptionTemplate,
+		SecurityGroupDescriptionFormat:    DescriptionFormatProse,
+		SecurityGroupNameConflictPolicy:   SecurityGroupNameConflictError,
+		ForeignSecurityGroupPolicy:        ForeignSecurityGroupIgnore,
+		SecurityGroupRemovalPolicy:        SecurityGroupRemovalLeave,
+		SecurityGroupMaxReconcileAttempts: 1,
+		SecurityGroupClientRetryAttempts:  1,
+		SecurityGroupListMaxResults:       1000,
+	}
+}
+
+// Validate returns an error if the options are invalid.
+func (o Options) Validate() error {
+	var errs []error
+	description, err := o.securityGroupDescription()
+	if err == nil {
+		// Fail early on templates referencing unknown fields.
+		_, err = description.render(&infrav1.OpenStackCluster{}, "", controlPlaneSuffix)
+	}
+	errs = append(errs, err, o.validateRuleOwnership())
+	errs = append(errs, o.SecurityGroupNameConflictPolicy.validate(), o.ForeignSecurityGroupPolicy.validate(), o.SecurityGroupRemovalPolicy.validate())
+	if o.SecurityGroupMaxReconcileAttempts < 1 {
+		errs = append(errs, fmt.Errorf("invalid maximum security group reconcile attempts %d, must be at least 1", o.SecurityGroupMaxReconcileAttempts))
+	}
+	if o.SecurityGroupMaxRulesPerGroup < 0 {
+		errs = append(errs, fmt.Errorf("invalid maximum number of rules per security group %d, must not be negative", o.SecurityGroupMaxRulesPerGroup))
+	}
+	if o.SecurityGroupClientRetryAttempts < 1 {
+		errs = append(errs, fmt.Errorf("invalid security group client retry attempts %d, must be at least 1", o.SecurityGroupClientRetryAttempts))
+	}
+	if o.SecurityGroupListMaxResults < 0 {
+		errs = append(errs, fmt.Errorf("invalid maximum number of listed security groups %d, must not be negative", o.SecurityGroupListMaxResults))
+	}
+	if strings.Contains(o.FloatingIPReuseTag, ",") {
+		errs = append(errs, fmt.Errorf("invalid floating IP reuse tag %q, must not contain a comma", o.FloatingIPReuseTag))
+	}
+	return errors.Join(errs...)
+}
+
+// NewService returns an instance of the networking service configured with the options.
+func NewService(scope *scope.WithLogger, options Options) (*Service, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	secGroupDescription, err := options.securityGroupDescription()
+	if err != nil {
+		return nil, err
+	}
+	ruleDescriptionPrefix, ruleOwnerTag := options.ruleOwnership()
+	auditSink := options.AuditSink
+	if auditSink == nil {
+		auditSink = audit.NewNoopSink()
+	}
+
 	networkClient, err := scope.NewNetworkClient()
 	if err != nil {
 		return nil, err
 	}
 
 	return &Service{
-		scope:  scope,
-		client: networkClient,
+		scope:     scope,
+		client:    clients.NetworkClientWithRetry(clients.NetworkClientWithSecGroupListLimit(networkClient, secGroupListPageSize, options.SecurityGroupListMaxResults), secGroupClientRetryBackoff(options.SecurityGroupClientRetryAttempts)),
+		auditSink: auditSink,
+
+		secGroupDescription:   secGroupDescription,
+		ruleDescriptionPrefix: ruleDescriptionPrefix,
+		ruleOwnerTag:          ruleOwnerTag,
+
+		secGroupNameConflictPolicy:  options.SecurityGroupNameConflictPolicy,
+		foreignSecGroupPolicy:       options.ForeignSecurityGroupPolicy,
+		secGroupRemovalPolicy:       options.SecurityGroupRemovalPolicy,
+		secGroupRuleProber:          tcpSecurityGroupRuleProber{},
+		secGroupPropagationTimeout:  options.SecurityGroupPropagationTimeout,
+		secGroupPropagationInterval: retryIntervalSecGroupPropagation,
+
+		secGroupRuleDeletionGracePeriod: options.SecurityGroupRuleDeletionGracePeriod,
+
+		secGroupMaxReconcileAttempts: options.SecurityGroupMaxReconcileAttempts,
+		secGroupMaxRulesPerGroup:     options.SecurityGroupMaxRulesPerGroup,
+		clock:                        clock.RealClock{},
+
+		floatingIPReuseTag: options.FloatingIPReuseTag,
 	}, nil
 }
 
@@ -0,0 +1,189 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package networking
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(o *Options)
+		wantErr bool
+	}{
+		{
+			name:   "Default options",
+			modify: func(*Options) {},
+		},
+		{
+			name:    "Description template with unknown field",
+			modify:  func(o *Options) { o.SecurityGroupDescriptionTemplate = "{{ .Unknown }}" },
+			wantErr: true,
+		},
+		{
+			name:    "Unparsable description template",
+			modify:  func(o *Options) { o.SecurityGroupDescriptionTemplate = "{{ .Role" },
+			wantErr: true,
+		},
+		{
+			name:    "Unknown description format",
+			modify:  func(o *Options) { o.SecurityGroupDescriptionFormat = "json" },
+			wantErr: true,
+		},
+		{
+			name: "Rule description prefix with owner tag",
+			modify: func(o *Options) {
+				o.SecurityGroupRuleDescriptionPrefix = "team-a/"
+				o.SecurityGroupRuleOwnerTag = "team-a"
+			},
+		},
+		{
+			name: "Rule description prefix with managed marker",
+			modify: func(o *Options) {
+				o.SecurityGroupRuleDescriptionPrefix, o.SecurityGroupRuleManagedMarker = "team-a/", true
+			},
+		},
+		{
+			name:    "Rule description prefix without owner tag",
+			modify:  func(o *Options) { o.SecurityGroupRuleDescriptionPrefix = "team-a/" },
+			wantErr: true,
+		},
+		{
+			name:    "Rule owner tag with comma",
+			modify:  func(o *Options) { o.SecurityGroupRuleOwnerTag = "team,a" },
+			wantErr: true,
+		},
+		{
+			name:    "Too long rule owner tag",
+			modify:  func(o *Options) { o.SecurityGroupRuleOwnerTag = strings.Repeat("a", 61) },
+			wantErr: true,
+		},
+		{
+			name:    "Unknown name conflict policy",
+			modify:  func(o *Options) { o.SecurityGroupNameConflictPolicy = "Newest" },
+			wantErr: true,
+		},
+		{
+			name:    "Unknown foreign security group policy",
+			modify:  func(o *Options) { o.ForeignSecurityGroupPolicy = "Delete" },
+			wantErr: true,
+		},
+		{
+			name:    "Unknown removal policy",
+			modify:  func(o *Options) { o.SecurityGroupRemovalPolicy = "Orphan" },
+			wantErr: true,
+		},
+		{
+			name:    "No reconcile attempt",
+			modify:  func(o *Options) { o.SecurityGroupMaxReconcileAttempts = 0 },
+			wantErr: true,
+		},
+		{
+			name:    "Negative maximum number of rules per group",
+			modify:  func(o *Options) { o.SecurityGroupMaxRulesPerGroup = -1 },
+			wantErr: true,
+		},
+		{
+			name:    "No client attempt",
+			modify:  func(o *Options) { o.SecurityGroupClientRetryAttempts = 0 },
+			wantErr: true,
+		},
+		{
+			name:    "Negative maximum number of listed groups",
+			modify:  func(o *Options) { o.SecurityGroupListMaxResults = -1 },
+			wantErr: true,
+		},
+		{
+			name:    "Floating IP reuse tag with comma",
+			modify:  func(o *Options) { o.FloatingIPReuseTag = "capo,reuse" },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			options := DefaultOptions()
+			tt.modify(&options)
+
+			err := options.Validate()
+			if tt.wantErr {
+				g.Expect(err).To(HaveOccurred())
+			} else {
+				g.Expect(err).NotTo(HaveOccurred())
+			}
+		})
+	}
+}
+
+func TestOptionsRuleOwnership(t *testing.T) {
+	tests := []struct {
+		name                  string
+		ownerTag              string
+		descriptionPrefix     string
+		marked                bool
+		wantDescriptionPrefix string
+		wantOwnerTag          string
+	}{
+		{
+			name:                  "Prefix with owner tag",
+			ownerTag:              "team-a",
+			descriptionPrefix:     "team-a/",
+			wantDescriptionPrefix: "team-a/",
+			wantOwnerTag:          "team-a",
+		},
+		{
+			name:                  "Marked prefix with owner tag",
+			ownerTag:              "team-a",
+			descriptionPrefix:     "team-a/",
+			marked:                true,
+			wantDescriptionPrefix: "cluster-api-managed: team-a/",
+			wantOwnerTag:          "team-a",
+		},
+		{
+			name:                  "Marked without owner tag",
+			marked:                true,
+			wantDescriptionPrefix: "cluster-api-managed: ",
+			wantOwnerTag:          ManagedRuleOwnerTag,
+		},
+		{
+			name: "Not marked without owner tag",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			options := DefaultOptions()
+			options.SecurityGroupRuleOwnerTag = tt.ownerTag
+			options.SecurityGroupRuleDescriptionPrefix = tt.descriptionPrefix
+			options.SecurityGroupRuleManagedMarker = tt.marked
+
+			descriptionPrefix, ownerTag := options.ruleOwnership()
+			g.Expect(descriptionPrefix).To(Equal(tt.wantDescriptionPrefix))
+			g.Expect(ownerTag).To(Equal(tt.wantOwnerTag))
+		})
+	}
+}
+
+func TestSecGroupClientRetryBackoff(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(secGroupClientRetryBackoff(1).Steps).To(Equal(1))
+	g.Expect(secGroupClientRetryBackoff(3).Steps).To(Equal(3))
+}
@@ -120,3 +120,32 @@ func RegisterAPIPrometheusMetrics() {
 		metrics.Registry.MustRegister(apiRequestPrometheusMetrics.Errors)
 	})
 }
+
+var securityGroupRulesPrometheusMetric = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "capo",
+		Name:      "managed_security_group_rules",
+		Help:      "Number of rules of a managed security group, to alert before the rule quota of the group is reached",
+	}, []string{"namespace", "cluster", "group"})
+
+var registerSecurityGroupPrometheusMetrics sync.Once
+
+func RegisterSecurityGroupPrometheusMetrics() {
+	registerSecurityGroupPrometheusMetrics.Do(func() {
+		metrics.Registry.MustRegister(securityGroupRulesPrometheusMetric)
+	})
+}
+
+// SetSecurityGroupRules records the number of rules of the managed security groups of a cluster, keyed by the
+// suffix of the groups. The groups of the cluster missing from rules, e.g. removed groups, are not reported anymore.
+func SetSecurityGroupRules(namespace, cluster string, rules map[string]int) {
+	DeleteSecurityGroupRules(namespace, cluster)
+	for group, n := range rules {
+		securityGroupRulesPrometheusMetric.WithLabelValues(namespace, cluster, group).Set(float64(n))
+	}
+}
+
+// DeleteSecurityGroupRules stops reporting the number of rules of the managed security groups of a cluster.
+func DeleteSecurityGroupRules(namespace, cluster string) {
+	securityGroupRulesPrometheusMetric.DeletePartialMatch(prometheus.Labels{"namespace": namespace, "cluster": cluster})
+}
@@ -0,0 +1,44 @@
+/*
+Copyright 2024 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSetSecurityGroupRules(t *testing.T) {
+	g := NewWithT(t)
+	defer securityGroupRulesPrometheusMetric.Reset()
+
+	SetSecurityGroupRules("ns", "cluster", map[string]int{"controlplane": 12, "worker": 9, "bastion": 3})
+	SetSecurityGroupRules("other-ns", "cluster", map[string]int{"controlplane": 5})
+	g.Expect(testutil.CollectAndCount(securityGroupRulesPrometheusMetric)).To(Equal(4))
+	g.Expect(testutil.ToFloat64(securityGroupRulesPrometheusMetric.WithLabelValues("ns", "cluster", "worker"))).To(Equal(9.0))
+
+	// The removed bastion group is not reported anymore.
+	SetSecurityGroupRules("ns", "cluster", map[string]int{"controlplane": 12, "worker": 10})
+	g.Expect(testutil.CollectAndCount(securityGroupRulesPrometheusMetric)).To(Equal(3))
+	g.Expect(testutil.ToFloat64(securityGroupRulesPrometheusMetric.WithLabelValues("ns", "cluster", "worker"))).To(Equal(10.0))
+
+	// Deleting a cluster doesn't affect the clusters of the same name in other namespaces.
+	DeleteSecurityGroupRules("ns", "cluster")
+	g.Expect(testutil.CollectAndCount(securityGroupRulesPrometheusMetric)).To(Equal(1))
+	g.Expect(testutil.ToFloat64(securityGroupRulesPrometheusMetric.WithLabelValues("other-ns", "cluster", "controlplane"))).To(Equal(5.0))
+}
@@ -72,6 +72,7 @@ func (f *providerScopeFactory) NewClientScopeFromMachine(ctx context.Context, ct
 	if err != nil {
 		return nil, err
 	}
+	setCloudRegion(&cloud, identityRef)
 
 	if caCert == nil {
 		caCert = defaultCACert
@@ -93,6 +94,7 @@ func (f *providerScopeFactory) NewClientScopeFromCluster(ctx context.Context, ct
 	if err != nil {
 		return nil, err
 	}
+	setCloudRegion(&cloud, &openStackCluster.Spec.IdentityRef)
 
 	if caCert == nil {
 		caCert = defaultCACert
@@ -330,6 +332,14 @@ func getCloudFromSecret(ctx context.Context, ctrlClient client.Client, secretNam
 	return clouds.Clouds[cloudName], caCert, nil
 }
 
+// setCloudRegion overrides the region of the clouds.yaml entry with the region of the identity reference, if set.
+// The region is used by all the service clients to select their endpoint in the service catalog.
+func setCloudRegion(cloud *clientconfig.Cloud, identityRef *infrav1.OpenStackIdentityReference) {
+	if identityRef.Region != "" {
+		cloud.RegionName = identityRef.Region
+	}
+}
+
 // getProjectIDFromAuthResult handles different auth mechanisms to retrieve the
 // current project id. Usually we use the Identity v3 Token mechanism that
 // returns the project id in the response to the initial auth request.
@@ -71,6 +71,22 @@ func IsInvalidError(err error) bool {
 	return false
 }
 
+func IsForbidden(err error) bool {
+	var errDefault403 gophercloud.ErrDefault403
+	if errors.As(err, &errDefault403) {
+		return true
+	}
+
+	var errUnexpectedResponseCode gophercloud.ErrUnexpectedResponseCode
+	if errors.As(err, &errUnexpectedResponseCode) {
+		if errUnexpectedResponseCode.Actual == http.StatusForbidden {
+			return true
+		}
+	}
+
+	return false
+}
+
 func IsConflict(err error) bool {
 	var errDefault409 gophercloud.ErrDefault409
 	if errors.As(err, &errDefault409) {
@@ -97,3 +113,13 @@ func IsNotImplementedError(err error) bool {
 
 	return false
 }
+
+// IsClientError returns true if the request was refused with a 4xx status code.
+func IsClientError(err error) bool {
+	var statusCodeError gophercloud.StatusCodeError
+	if errors.As(err, &statusCodeError) {
+		statusCode := statusCodeError.GetStatusCode()
+		return statusCode >= http.StatusBadRequest && statusCode < http.StatusInternalServerError
+	}
+	return false
+}